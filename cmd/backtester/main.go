@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+	"github.com/RuiHirano/fx-backtesting/pkg/statistics"
+	"github.com/RuiHirano/fx-backtesting/pkg/strategy"
+)
+
+// デフォルトの取引シンボルと取引サイズ
+const (
+	defaultSymbol    = "USDJPY"
+	defaultTradeSize = 1000.0
+)
+
+// StrategyConfig はCLIで実行する戦略とそのパラメーターです。
+// 各戦略は必要なパラメーターのみを使用します。
+type StrategyConfig struct {
+	Name         string  `json:"name"`
+	Symbol       string  `json:"symbol"`
+	Size         float64 `json:"size"`
+	FastPeriod   int     `json:"fast_period"`
+	SlowPeriod   int     `json:"slow_period"`
+	SignalPeriod int     `json:"signal_period"`
+	Period       int     `json:"period"`
+	Oversold     float64 `json:"oversold"`
+	Overbought   float64 `json:"overbought"`
+	Multiplier   float64 `json:"multiplier"`
+}
+
+// cliConfig は設定ファイルの内容です。バックテスト設定に strategy セクションを加えたものです。
+type cliConfig struct {
+	backtester.Config
+	Strategy StrategyConfig `json:"strategy"`
+}
+
+func main() {
+	dataPath := flag.String("data", "", "ローソク足データ(CSV)のパス")
+	configPath := flag.String("config", "", "設定ファイル(JSON)のパス")
+	format := flag.String("format", "text", "レポート形式 (text, json, jsonl, csv, html)")
+	outputPath := flag.String("output", "", "レポートの出力先ファイル (省略時は標準出力)")
+	seed := flag.Int64("seed", 0, "乱数シード (0の場合は実行ごとに異なるシード)")
+	quiet := flag.Bool("quiet", false, "進捗を標準エラー出力に表示しない")
+	envOverride := flag.Bool("env-override", false, "環境変数の値を設定ファイルの値より優先する")
+	logLevel := flag.String("log-level", "", "診断ログのレベル (debug, info, warn, error, off)。省略時は設定ファイルの値")
+	strict := flag.Bool("strict", false, "データに解析・検証できない行がある場合、読み飛ばさずにエラーにする")
+	stream := flag.Bool("stream", false, "データ全体のインデックスを構築せずに先頭から順に読み込む（時刻順に並んだ大きなファイル向け）")
+	flatTime := flag.String("flat-time", "", "毎日この時刻(UTC, HH:MM)をまたぐと全ポジションを決済する")
+
+	defaults := defaultStrategyConfig()
+	strategyName := flag.String("strategy", defaults.Name, "戦略 (ma, rsi, macd, bollinger)")
+	symbol := flag.String("symbol", defaults.Symbol, "取引シンボル")
+	size := flag.Float64("size", defaults.Size, "取引サイズ")
+	fastPeriod := flag.Int("fast", defaults.FastPeriod, "短期期間 (ma, macd)")
+	slowPeriod := flag.Int("slow", defaults.SlowPeriod, "長期期間 (ma, macd)")
+	signalPeriod := flag.Int("signal", defaults.SignalPeriod, "シグナル期間 (macd)")
+	period := flag.Int("period", defaults.Period, "期間 (rsi, bollinger)")
+	oversold := flag.Float64("oversold", defaults.Oversold, "売られすぎの閾値 (rsi)")
+	overbought := flag.Float64("overbought", defaults.Overbought, "買われすぎの閾値 (rsi)")
+	multiplier := flag.Float64("multiplier", defaults.Multiplier, "標準偏差の係数 (bollinger)")
+	flag.Parse()
+
+	reportFormat, err := statistics.ParseReportFormat(*format)
+	if err != nil {
+		log.Fatalf("Invalid format: %v", err)
+	}
+
+	config, err := loadConfig(*configPath, *envOverride)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if *dataPath != "" {
+		config.Market.DataProvider.FilePath = *dataPath
+	}
+	if *seed != 0 {
+		config.Backtest.Seed = *seed
+	}
+	if *logLevel != "" {
+		config.Visualizer.LogLevel = *logLevel
+	}
+	if *strict {
+		config.Market.DataProvider.StrictParsing = true
+	}
+	if *stream {
+		config.Market.DataProvider.Streaming = true
+	}
+	if *flatTime != "" {
+		config.Backtest.FlatTime = *flatTime
+	}
+
+	// 明示的に指定されたフラグのみ設定ファイルの値を上書きする
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "strategy":
+			config.Strategy.Name = *strategyName
+		case "symbol":
+			config.Strategy.Symbol = *symbol
+		case "size":
+			config.Strategy.Size = *size
+		case "fast":
+			config.Strategy.FastPeriod = *fastPeriod
+		case "slow":
+			config.Strategy.SlowPeriod = *slowPeriod
+		case "signal":
+			config.Strategy.SignalPeriod = *signalPeriod
+		case "period":
+			config.Strategy.Period = *period
+		case "oversold":
+			config.Strategy.Oversold = *oversold
+		case "overbought":
+			config.Strategy.Overbought = *overbought
+		case "multiplier":
+			config.Strategy.Multiplier = *multiplier
+		}
+	})
+
+	strat, err := newStrategy(config.Strategy)
+	if err != nil {
+		log.Fatalf("Invalid strategy: %v", err)
+	}
+
+	var progress func(backtester.Progress)
+	if !*quiet {
+		progress = newProgressPrinter(os.Stderr, progressInterval)
+	}
+
+	if err := runBacktestWithOutput(config.Config, strat, reportFormat, *outputPath, progress); err != nil {
+		log.Fatalf("Backtest failed: %v", err)
+	}
+}
+
+// defaultConfig はCLI用のデフォルト設定を返します。
+func defaultConfig() backtester.Config {
+	return backtester.Config{
+		Market: backtester.MarketConfig{
+			DataProvider: models.DataProviderConfig{
+				Format: "csv",
+			},
+		},
+		Broker: backtester.BrokerConfig{
+			InitialBalance: 100000.0,
+			Spread:         0.01,
+		},
+		Backtest:   backtester.BacktestConfig{},
+		Visualizer: models.DisabledVisualizerConfig(),
+	}
+}
+
+// defaultStrategyConfig はCLI用のデフォルトの戦略設定を返します。
+func defaultStrategyConfig() StrategyConfig {
+	return StrategyConfig{
+		Name:         "ma",
+		Symbol:       defaultSymbol,
+		Size:         defaultTradeSize,
+		FastPeriod:   5,
+		SlowPeriod:   20,
+		SignalPeriod: 9,
+		Period:       14,
+		Oversold:     30,
+		Overbought:   70,
+		Multiplier:   2,
+	}
+}
+
+// newStrategy は設定に従って戦略を作成します。
+func newStrategy(config StrategyConfig) (backtester.Strategy, error) {
+	switch config.Name {
+	case "ma":
+		return strategy.NewMovingAverageCross(config.Symbol, config.Size, config.FastPeriod, config.SlowPeriod)
+	case "rsi":
+		return strategy.NewRSIStrategy(config.Symbol, config.Period, config.Oversold, config.Overbought, config.Size)
+	case "macd":
+		return strategy.NewMACDStrategy(config.Symbol, config.FastPeriod, config.SlowPeriod, config.SignalPeriod, config.Size)
+	case "bollinger":
+		return strategy.NewBollingerStrategy(config.Symbol, config.Period, config.Multiplier, config.Size)
+	default:
+		return nil, fmt.Errorf("unknown strategy: %q", config.Name)
+	}
+}
+
+// 設定に使う環境変数名
+const (
+	envInitialBalance = "FXBT_INITIAL_BALANCE"
+	envSpread         = "FXBT_SPREAD"
+	envSymbol         = "FXBT_SYMBOL"
+	envDataFile       = "FXBT_DATA_FILE"
+)
+
+// loadConfig は設定を読み込みます。
+// デフォルト設定に環境変数を重ね、その上に設定ファイルの値を重ねます。
+// envOverride が true の場合は、設定ファイルよりも環境変数の値を優先します。
+func loadConfig(path string, envOverride bool) (cliConfig, error) {
+	config := cliConfig{
+		Config:   defaultConfig(),
+		Strategy: defaultStrategyConfig(),
+	}
+	if err := applyEnv(&config); err != nil {
+		return config, err
+	}
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if envOverride {
+		if err := applyEnv(&config); err != nil {
+			return config, err
+		}
+	}
+
+	return config, nil
+}
+
+// applyEnv は設定されている環境変数の値で設定を上書きします。
+func applyEnv(config *cliConfig) error {
+	if value, ok := os.LookupEnv(envInitialBalance); ok {
+		balance, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", envInitialBalance, err)
+		}
+		config.Broker.InitialBalance = balance
+	}
+	if value, ok := os.LookupEnv(envSpread); ok {
+		spread, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", envSpread, err)
+		}
+		config.Broker.Spread = spread
+	}
+	if value, ok := os.LookupEnv(envSymbol); ok {
+		config.Strategy.Symbol = value
+	}
+	if value, ok := os.LookupEnv(envDataFile); ok {
+		config.Market.DataProvider.FilePath = value
+	}
+	return nil
+}
+
+// progressInterval は進捗表示を更新する間隔です。
+const progressInterval = 500 * time.Millisecond
+
+// newProgressPrinter は進捗率と残り時間の目安を interval ごとに1行で上書き表示する関数を返します。
+// 最後のローソク足に達した時は間隔に関係なく表示し、改行します。
+func newProgressPrinter(w io.Writer, interval time.Duration) func(backtester.Progress) {
+	start := time.Now()
+	var lastPrinted time.Time
+	return func(p backtester.Progress) {
+		now := time.Now()
+		done := p.Total > 0 && p.Bar >= p.Total
+		if !done && now.Sub(lastPrinted) < interval {
+			return
+		}
+		lastPrinted = now
+
+		if p.Total <= 0 {
+			fmt.Fprintf(w, "\rprogress: %d bars (%s)", p.Bar, p.Time.Format(time.RFC3339))
+			return
+		}
+
+		eta := "--"
+		if p.Bar > 0 {
+			elapsed := now.Sub(start)
+			remaining := time.Duration(float64(elapsed) * float64(p.Total-p.Bar) / float64(p.Bar))
+			eta = remaining.Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "\rprogress: %5.1f%% (%d/%d) ETA %s", p.Percent(), p.Bar, p.Total, eta)
+		if done {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// benchmarkRecorder は戦略に渡したローソク足をバイアンドホールドとの比較と実行期間に集計します。
+// ローソク足は保持しないため、--stream で大きなデータを読み込んでもメモリ使用量は本数に依存しません。
+type benchmarkRecorder struct {
+	backtester.Strategy
+	benchmark *statistics.BenchmarkAccumulator
+}
+
+// OnBar は現在のローソク足を集計してから戦略を呼び出します。
+func (r *benchmarkRecorder) OnBar(bt *backtester.Backtester) error {
+	if recent := bt.GetRecentCandles(1); len(recent) > 0 {
+		r.benchmark.Add(*recent[0], bt.GetTradeHistory())
+	}
+	return r.Strategy.OnBar(bt)
+}
+
+// runBacktestWithOutput は戦略でバックテストを実行し、指定形式のレポートを出力します。
+// progress が nil でない場合は実行中の進捗を通知します。
+func runBacktestWithOutput(config backtester.Config, strat backtester.Strategy, format statistics.ReportFormat, outputPath string, progress func(backtester.Progress)) error {
+	bt, err := backtester.NewBacktester(config)
+	if err != nil {
+		return err
+	}
+	defer bt.Close()
+
+	ctx := context.Background()
+	if err := bt.Initialize(ctx); err != nil {
+		return err
+	}
+
+	// データ終端まで戦略を実行する（残りのポジションは Run が決済する）
+	recorder := &benchmarkRecorder{
+		Strategy:  strat,
+		benchmark: statistics.NewBenchmarkAccumulator(config.Broker.InitialBalance),
+	}
+	if _, err := bt.RunWithCallback(recorder, progress); err != nil {
+		return err
+	}
+
+	trades := bt.GetTradeHistory()
+	report := statistics.NewReport(trades, config.Broker.InitialBalance)
+	report.SetCalendar(config.Market.Calendar)
+	if n := recorder.benchmark.Bars(); n > 0 {
+		benchmark := recorder.benchmark.Benchmark(trades)
+		report.SetBenchmarkResult(&benchmark)
+		first, last := recorder.benchmark.Period()
+		report.SetRunPeriod(config.Market.Calendar.TradingDuration(first, last), n)
+	}
+
+	var out io.Writer = os.Stdout
+	if outputPath != "" {
+		file, err := os.Create(outputPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	_, err = io.WriteString(out, report.GenerateReport(format))
+	return err
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+	"github.com/RuiHirano/fx-backtesting/pkg/data"
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+	"github.com/RuiHirano/fx-backtesting/pkg/statistics"
+)
+
+// 設定ファイル読み込みテスト
+func TestCLI_LoadConfig(t *testing.T) {
+	t.Run("should return default config when path is empty", func(t *testing.T) {
+		config, err := loadConfig("", false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if config.Broker.InitialBalance != 100000.0 {
+			t.Errorf("Expected default initial balance, got %f", config.Broker.InitialBalance)
+		}
+	})
+
+	t.Run("should override defaults with file values", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		content := `{"broker": {"initial_balance": 5000, "spread": 0.02}}`
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		config, err := loadConfig(path, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if config.Broker.InitialBalance != 5000 {
+			t.Errorf("Expected initial balance 5000, got %f", config.Broker.InitialBalance)
+		}
+		if config.Market.DataProvider.Format != "csv" {
+			t.Errorf("Expected default format to be kept, got %s", config.Market.DataProvider.Format)
+		}
+		if config.Strategy.Name != "ma" {
+			t.Errorf("Expected default strategy to be kept, got %s", config.Strategy.Name)
+		}
+	})
+
+	t.Run("should read strategy section", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		content := `{"strategy": {"name": "rsi", "period": 7, "oversold": 25}}`
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		config, err := loadConfig(path, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if config.Strategy.Name != "rsi" || config.Strategy.Period != 7 || config.Strategy.Oversold != 25 {
+			t.Errorf("Unexpected strategy config: %+v", config.Strategy)
+		}
+		if config.Strategy.Overbought != 70 {
+			t.Errorf("Expected default overbought to be kept, got %f", config.Strategy.Overbought)
+		}
+	})
+
+	t.Run("should return error for missing file", func(t *testing.T) {
+		if _, err := loadConfig("./testdata/not_exists.json", false); err == nil {
+			t.Error("Expected error for missing config file")
+		}
+	})
+}
+
+// 環境変数からの設定読み込みテスト
+func TestCLI_LoadConfigFromEnv(t *testing.T) {
+	t.Run("should build config from environment without file", func(t *testing.T) {
+		t.Setenv("FXBT_INITIAL_BALANCE", "2500")
+		t.Setenv("FXBT_SPREAD", "0.5")
+		t.Setenv("FXBT_SYMBOL", "EURUSD")
+		t.Setenv("FXBT_DATA_FILE", "/data/eurusd.csv")
+
+		config, err := loadConfig("", false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if config.Broker.InitialBalance != 2500 || config.Broker.Spread != 0.5 {
+			t.Errorf("Unexpected broker config: %+v", config.Broker)
+		}
+		if config.Strategy.Symbol != "EURUSD" || config.Market.DataProvider.FilePath != "/data/eurusd.csv" {
+			t.Errorf("Unexpected symbol %q or data file %q", config.Strategy.Symbol, config.Market.DataProvider.FilePath)
+		}
+	})
+
+	t.Run("should prefer file values unless env override is set", func(t *testing.T) {
+		t.Setenv("FXBT_INITIAL_BALANCE", "2500")
+		t.Setenv("FXBT_SPREAD", "0.5")
+		path := filepath.Join(t.TempDir(), "config.json")
+		content := `{"broker": {"initial_balance": 5000}}`
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		config, err := loadConfig(path, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// ファイルに無い項目は環境変数の値が残る
+		if config.Broker.InitialBalance != 5000 || config.Broker.Spread != 0.5 {
+			t.Errorf("Unexpected broker config: %+v", config.Broker)
+		}
+
+		config, err = loadConfig(path, true)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if config.Broker.InitialBalance != 2500 {
+			t.Errorf("Expected env to override file, got %f", config.Broker.InitialBalance)
+		}
+	})
+
+	t.Run("should return error for invalid number", func(t *testing.T) {
+		t.Setenv("FXBT_SPREAD", "wide")
+		if _, err := loadConfig("", false); err == nil {
+			t.Error("Expected error for invalid FXBT_SPREAD")
+		}
+	})
+}
+
+// 戦略作成テスト
+func TestCLI_NewStrategy(t *testing.T) {
+	for _, name := range []string{"ma", "rsi", "macd", "bollinger"} {
+		t.Run(name, func(t *testing.T) {
+			config := defaultStrategyConfig()
+			config.Name = name
+			if _, err := newStrategy(config); err != nil {
+				t.Errorf("Unexpected error for %s: %v", name, err)
+			}
+		})
+	}
+
+	t.Run("should reject unknown strategy", func(t *testing.T) {
+		config := defaultStrategyConfig()
+		config.Name = "unknown"
+		if _, err := newStrategy(config); err == nil {
+			t.Error("Expected error for unknown strategy")
+		}
+	})
+
+	t.Run("should propagate invalid parameters", func(t *testing.T) {
+		config := defaultStrategyConfig()
+		config.FastPeriod = 30
+		if _, err := newStrategy(config); err == nil {
+			t.Error("Expected error when fast period is not shorter than slow period")
+		}
+	})
+}
+
+// 実行フローテスト
+func TestCLI_ExecutionFlow(t *testing.T) {
+	config := defaultConfig()
+	config.Market.DataProvider.FilePath = "../../pkg/backtester/testdata/sample.csv"
+
+	strat, err := newStrategy(defaultStrategyConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(t.TempDir(), "report.html")
+	var progress bytes.Buffer
+	printer := newProgressPrinter(&progress, time.Hour)
+	if err := runBacktestWithOutput(config, strat, statistics.FormatHTML, output, printer); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 最初の更新と最後のローソク足の進捗は間隔に関係なく表示される
+	if !strings.Contains(progress.String(), "(532/532)") || !strings.HasSuffix(progress.String(), "\n") {
+		t.Errorf("Expected final progress line, got %q", progress.String())
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<svg") {
+		t.Error("Expected HTML report with equity curve to be written")
+	}
+}
+
+// ストリーミング読み込みでのベンチマーク比較テスト
+func TestCLI_StreamingBenchmark(t *testing.T) {
+	const dataPath = "../../pkg/backtester/testdata/sample.csv"
+	config := defaultConfig()
+	config.Market.DataProvider.FilePath = dataPath
+	config.Market.DataProvider.Streaming = true
+
+	strat, err := newStrategy(defaultStrategyConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(t.TempDir(), "report.json")
+	if err := runBacktestWithOutput(config, strat, statistics.FormatJSON, output, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report statistics.JSONReport
+	if err := json.Unmarshal(content, &report); err != nil {
+		t.Fatalf("Expected valid JSON report, got %v", err)
+	}
+	if report.Benchmark == nil || len(report.Trades) == 0 {
+		t.Fatalf("Expected benchmark and trades in report, got %+v", report.Benchmark)
+	}
+
+	// ローソク足を全て読み込んで計算した比較と一致する
+	file, err := os.Open(dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	parser := data.NewCSVParser(file)
+	var candles []models.Candle
+	for {
+		candle, err := parser.Parse()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		candles = append(candles, *candle)
+	}
+	expected := statistics.CompareWithBenchmark(report.Trades, candles, config.Broker.InitialBalance)
+	for name, values := range map[string][2]float64{
+		"buy and hold return": {expected.BuyAndHoldReturn, float64(report.Benchmark.BuyAndHoldReturn)},
+		"strategy return":     {expected.StrategyReturn, float64(report.Benchmark.StrategyReturn)},
+		"correlation":         {expected.Correlation, float64(report.Benchmark.Correlation)},
+	} {
+		if math.Abs(values[0]-values[1]) > 1e-9 {
+			t.Errorf("Expected %s %f, got %f", name, values[0], values[1])
+		}
+	}
+}
+
+// 進捗表示テスト
+func TestCLI_ProgressPrinter(t *testing.T) {
+	t.Run("should print percentage and ETA", func(t *testing.T) {
+		var out bytes.Buffer
+		printer := newProgressPrinter(&out, 0)
+		printer(backtester.Progress{Bar: 50, Total: 200})
+		if !strings.Contains(out.String(), " 25.0% (50/200) ETA ") {
+			t.Errorf("Unexpected progress output: %q", out.String())
+		}
+	})
+
+	t.Run("should throttle updates by interval", func(t *testing.T) {
+		var out bytes.Buffer
+		printer := newProgressPrinter(&out, time.Hour)
+		printer(backtester.Progress{Bar: 1, Total: 3})
+		printer(backtester.Progress{Bar: 2, Total: 3})
+		if strings.Count(out.String(), "progress:") != 1 {
+			t.Errorf("Expected a single update, got %q", out.String())
+		}
+	})
+
+	t.Run("should print bar count when total is unknown", func(t *testing.T) {
+		var out bytes.Buffer
+		printer := newProgressPrinter(&out, 0)
+		printer(backtester.Progress{Bar: 10})
+		if !strings.Contains(out.String(), "progress: 10 bars") {
+			t.Errorf("Unexpected progress output: %q", out.String())
+		}
+	})
+}
@@ -163,7 +163,7 @@ func main() {
 	if err := bt.Initialize(ctx); err != nil {
 		log.Fatalf("Backtester初期化エラー: %v", err)
 	}
-	defer bt.Stop()
+	defer bt.Close()
 	
 	fmt.Printf("✅ BacktesterとVisualizer（ポート %d）が初期化されました\n", 8080)
 	fmt.Println("🌐 フロントエンドを開始するには:")
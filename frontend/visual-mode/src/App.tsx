@@ -4,9 +4,14 @@ import {
   type ChartOptions,
   ColorType,
   type DeepPartial,
+  type LineData,
   type Time,
 } from "lightweight-charts";
-import { CandlestickSeries, Chart } from "lightweight-charts-react-components";
+import {
+  CandlestickSeries,
+  Chart,
+  LineSeries,
+} from "lightweight-charts-react-components";
 import { useEffect, useRef, useState } from "react";
 import styled from "styled-components";
 
@@ -20,7 +25,17 @@ interface Trade {
 }
 
 interface WebSocketMessage {
-  type: "candle_update" | "trade_event" | "statistics_update" | "ping" | "pong";
+  type:
+    | "history"
+    | "candle_update"
+    | "candle_batch"
+    | "trade_event"
+    | "statistics_update"
+    | "indicator_update"
+    | "equity_update"
+    | "backtest_state"
+    | "ping"
+    | "pong";
   data?: any;
   timestamp?: string;
   message?: string;
@@ -60,11 +75,26 @@ const candleDataByTimeframeAtom = atom<Record<string, CandlestickData<Time>[]>>(
   "1w": [],
 });
 const tradesAtom = atom<Trade[]>([]);
+// Indicator overlay series keyed by indicator name (e.g. fast_ma, slow_ma)
+const indicatorsAtom = atom<Record<string, LineData<Time>[]>>({});
 const playbackStateAtom = atom<{ isPlaying: boolean; speed: number }>({
   isPlaying: false,
   speed: 1,
 });
 
+// appendCandles appends only candles newer than the last one, so replayed
+// messages after a reconnect don't duplicate bars already on the chart
+const appendCandles = (
+  existing: CandlestickData<Time>[],
+  incoming: CandlestickData<Time>[]
+): CandlestickData<Time>[] => {
+  const lastTime =
+    existing.length > 0 ? (existing[existing.length - 1].time as number) : -1;
+  const fresh = incoming.filter((candle) => (candle.time as number) > lastTime);
+  // Keep only the last 10000 candles
+  return [...existing, ...fresh].slice(-10000);
+};
+
 // Derived atom for current candle data
 const currentCandleDataAtom = atom<CandlestickData<Time>[]>((get) => {
   const timeframe = get(timeframeAtom);
@@ -221,12 +251,15 @@ const TimeframeLabel = styled.span`
 
 function App() {
   const ws = useRef<WebSocket | null>(null);
+  // Timestamp of the last received message, sent as ?since= on reconnect
+  const lastMessageTimestamp = useRef<string | null>(null);
 
   const [connectionState, setConnectionState] = useAtom(connectionStateAtom);
   const [timeframe, setTimeframe] = useAtom(timeframeAtom);
   const [candleDataByTimeframe, setCandleDataByTimeframe] = useAtom(candleDataByTimeframeAtom);
   const [currentCandleData] = useAtom(currentCandleDataAtom);
   const [trades, setTrades] = useAtom(tradesAtom);
+  const [indicators, setIndicators] = useAtom(indicatorsAtom);
   const [playbackState, setPlaybackState] = useAtom(playbackStateAtom);
   const [statistics, setStatistics] = useState<any>(null);
 
@@ -234,7 +267,14 @@ function App() {
     const connectWebSocket = () => {
       try {
         console.log("Attempting to connect to WebSocket...");
-        ws.current = new WebSocket("ws://localhost:8080/ws");
+        // Use wss when the dashboard itself is served over HTTPS
+        const scheme = window.location.protocol === "https:" ? "wss" : "ws";
+        // When served by the visualizer itself (StaticDir), connect back to the same host
+        const host = import.meta.env.DEV ? "localhost:8080" : window.location.host;
+        const since = lastMessageTimestamp.current
+          ? `?since=${encodeURIComponent(lastMessageTimestamp.current)}`
+          : "";
+        ws.current = new WebSocket(`${scheme}://${host}/ws${since}`);
 
         ws.current.onopen = () => {
           console.log("WebSocket connected successfully");
@@ -250,8 +290,34 @@ function App() {
           try {
             const message: WebSocketMessage = JSON.parse(event.data);
             console.log("Received message:", message);
+            if (message.timestamp) {
+              lastMessageTimestamp.current = message.timestamp;
+            }
 
             switch (message.type) {
+              case "history":
+                if (Array.isArray(message.data)) {
+                  const history: CandlestickData<Time>[] = message.data.map(
+                    (candle: any) => ({
+                      time: Math.floor(
+                        new Date(candle.timestamp || candle.time).getTime() /
+                          1000
+                      ) as Time,
+                      open: candle.open,
+                      high: candle.high,
+                      low: candle.low,
+                      close: candle.close,
+                    })
+                  );
+
+                  // Replace the chart with the server-side history on (re)connect
+                  setCandleDataByTimeframe((prev) => ({
+                    ...prev,
+                    [timeframe]: history.slice(-10000),
+                  }));
+                }
+                break;
+
               case "candle_update":
                 if (message.data) {
                   const candle = message.data;
@@ -267,15 +333,35 @@ function App() {
                     close: candle.close,
                   };
 
-                  setCandleDataByTimeframe((prev) => {
-                    const currentTimeframeData = prev[timeframe] || [];
-                    const updatedData = [...currentTimeframeData, formattedCandle];
-                    // Keep only the last 10000 candles
-                    return {
-                      ...prev,
-                      [timeframe]: updatedData.slice(-10000),
-                    };
-                  });
+                  setCandleDataByTimeframe((prev) => ({
+                    ...prev,
+                    [timeframe]: appendCandles(prev[timeframe] || [], [
+                      formattedCandle,
+                    ]),
+                  }));
+                }
+                break;
+
+              case "candle_batch":
+                if (Array.isArray(message.data)) {
+                  const batch: CandlestickData<Time>[] = message.data.map(
+                    (candle: any) => ({
+                      time: Math.floor(
+                        new Date(candle.timestamp || candle.time).getTime() /
+                          1000
+                      ) as Time,
+                      open: candle.open,
+                      high: candle.high,
+                      low: candle.low,
+                      close: candle.close,
+                    })
+                  );
+
+                  // Append the whole batch in a single state update
+                  setCandleDataByTimeframe((prev) => ({
+                    ...prev,
+                    [timeframe]: appendCandles(prev[timeframe] || [], batch),
+                  }));
                 }
                 break;
 
@@ -284,7 +370,7 @@ function App() {
                   const trade: Trade = {
                     id: message.data.id,
                     symbol: message.data.symbol,
-                    type: message.data.side === 0 ? "buy" : "sell",
+                    type: message.data.side === "Buy" ? "buy" : "sell",
                     amount: message.data.size,
                     price: message.data.entry_price || message.data.exit_price,
                     timestamp: message.timestamp || new Date().toISOString(),
@@ -293,12 +379,39 @@ function App() {
                 }
                 break;
 
+              case "indicator_update":
+                if (message.data?.name) {
+                  const { name, time, value } = message.data;
+                  const point: LineData<Time> = {
+                    time: Math.floor(new Date(time).getTime() / 1000) as Time,
+                    value,
+                  };
+                  setIndicators((prev) => ({
+                    ...prev,
+                    [name]: [...(prev[name] || []), point].slice(-10000),
+                  }));
+                }
+                break;
+
               case "statistics_update":
                 if (message.data) {
                   setStatistics(message.data);
                 }
                 break;
 
+              case "backtest_state":
+                // Idle (0) is broadcast after a reset: clear the chart and trades
+                if (message.data === 0) {
+                  setCandleDataByTimeframe((prev) => ({
+                    ...prev,
+                    [timeframe]: [],
+                  }));
+                  setTrades([]);
+                  setIndicators({});
+                  setPlaybackState((prev) => ({ ...prev, isPlaying: false }));
+                }
+                break;
+
               case "pong":
                 console.log("Received pong:", message.message);
                 break;
@@ -343,7 +456,14 @@ function App() {
         ws.current.close();
       }
     };
-  }, [setConnectionState, setTrades, setCandleDataByTimeframe, timeframe]);
+  }, [
+    setConnectionState,
+    setTrades,
+    setIndicators,
+    setCandleDataByTimeframe,
+    setPlaybackState,
+    timeframe,
+  ]);
 
   const handlePlayPause = () => {
     if (!connectionState.isConnected) return;
@@ -362,6 +482,34 @@ function App() {
     }
   };
 
+  const handleStep = () => {
+    if (!connectionState.isConnected || playbackState.isPlaying) return;
+
+    if (ws.current) {
+      const command = {
+        type: "step",
+        data: { count: 1 },
+        client_id: "react-client",
+        timestamp: new Date().toISOString(),
+      };
+      ws.current.send(JSON.stringify(command));
+    }
+  };
+
+  const handleReset = () => {
+    if (!connectionState.isConnected) return;
+
+    if (ws.current) {
+      const command = {
+        type: "reset",
+        data: {},
+        client_id: "react-client",
+        timestamp: new Date().toISOString(),
+      };
+      ws.current.send(JSON.stringify(command));
+    }
+  };
+
   const handleSpeedChange = (event: React.ChangeEvent<HTMLInputElement>) => {
     const speed = parseFloat(event.target.value);
     setPlaybackState((prev) => ({ ...prev, speed }));
@@ -447,6 +595,7 @@ function App() {
         {statistics && (
           <>
             <div>Balance: ${statistics.current_balance?.toFixed(2) || 0}</div>
+            <div>Floating PnL: ${statistics.unrealized_pnl?.toFixed(2) || 0}</div>
             <div>Total Trades: {statistics.total_trades || 0}</div>
             <div>Win Rate: {statistics.win_rate?.toFixed(1) || 0}%</div>
           </>
@@ -454,6 +603,9 @@ function App() {
       </StatusPanel>
       <Chart options={options} containerProps={{ style: { flexGrow: "1" } }}>
         <CandlestickSeries data={currentCandleData} />
+        {Object.entries(indicators).map(([name, data]) => (
+          <LineSeries key={name} data={data} />
+        ))}
       </Chart>
 
       <ControlPanel>
@@ -464,6 +616,20 @@ function App() {
           {playbackState.isPlaying ? "Pause" : "Play"}
         </PlayPauseButton>
 
+        <PlayPauseButton
+          onClick={handleStep}
+          disabled={!connectionState.isConnected || playbackState.isPlaying}
+        >
+          Step
+        </PlayPauseButton>
+
+        <PlayPauseButton
+          onClick={handleReset}
+          disabled={!connectionState.isConnected}
+        >
+          Reset
+        </PlayPauseButton>
+
         <SpeedLabel>Speed:</SpeedLabel>
         <SpeedSlider
           type="range"
@@ -4,12 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/RuiHirano/fx-backtesting/pkg/broker"
+	"github.com/RuiHirano/fx-backtesting/pkg/data"
+	"github.com/RuiHirano/fx-backtesting/pkg/instruments"
 	"github.com/RuiHirano/fx-backtesting/pkg/market"
 	"github.com/RuiHirano/fx-backtesting/pkg/models"
+	"github.com/RuiHirano/fx-backtesting/pkg/statistics"
 	"github.com/RuiHirano/fx-backtesting/pkg/visualizer"
 )
 
@@ -25,15 +33,125 @@ const (
 	BacktestStateError = models.BacktestStateError
 )
 
+var (
+	// ErrAlreadyInitialized は初期化済みの Backtester に対して Initialize を呼び出した場合のエラー
+	ErrAlreadyInitialized = errors.New("backtester already initialized")
+	// ErrStopped は Stop 済みの Backtester に対して Initialize を呼び出した場合のエラー（再実行には新しい Backtester を作成する）
+	ErrStopped = errors.New("backtester has been stopped; create a new Backtester to run again")
+	// ErrNotInitialized は Initialize 前の Backtester の操作・取得に対するエラー
+	ErrNotInitialized = errors.New("backtester not initialized")
+	// ErrNoCurrentCandle は現在のローソク足がない（データが空、または Close 済み）ため価格・時刻を取得できないことを表すエラー
+	ErrNoCurrentCandle = errors.New("no current candle")
+)
+
 // MarketConfig は市場に関する設定
 type MarketConfig struct {
 	DataProvider models.DataProviderConfig `json:"data_provider"`
+
+	// ローソク足キャッシュの本数と補充の閾値（0の場合は既定値。models.MarketConfig を参照）
+	CacheSize       int `json:"cache_size,omitempty"`
+	RefillThreshold int `json:"refill_threshold,omitempty"`
+
+	// 週末・休場日のローソク足を読み飛ばす取引カレンダー（nil の場合は全てのローソク足を使う）
+	Calendar *models.TradingCalendar `json:"calendar,omitempty"`
+}
+
+// marketConfig は models.MarketConfig に変換します。
+func (mc MarketConfig) marketConfig() models.MarketConfig {
+	return models.MarketConfig{
+		DataProvider:    mc.DataProvider,
+		Symbol:          "EURUSD", // デフォルト値
+		CacheSize:       mc.CacheSize,
+		RefillThreshold: mc.RefillThreshold,
+		Calendar:        mc.Calendar,
+	}
 }
 
 // BrokerConfig はブローカーに関する設定
 type BrokerConfig struct {
 	InitialBalance float64 `json:"initial_balance"`
 	Spread         float64 `json:"spread"`
+	// Config.Symbols のpipサイズで換算するスプレッド（指定したシンボルでは Spread より優先）
+	SpreadPips float64 `json:"spread_pips,omitempty"`
+
+	// 約定価格を不利な方向にずらすスリッページ（価格単位）。random の場合は BacktestConfig.Seed から再現できる乱数で0～Slippageを決める
+	Slippage     float64             `json:"slippage,omitempty"`
+	SlippageMode models.SlippageMode `json:"slippage_mode,omitempty"`
+
+	// 流動性の低いローソク足の扱い
+	MinVolumeToTrade         float64               `json:"min_volume_to_trade,omitempty"`
+	IlliquidPolicy           models.IlliquidPolicy `json:"illiquid_policy,omitempty"`
+	IlliquidSpreadMultiplier float64               `json:"illiquid_spread_multiplier,omitempty"`
+
+	// 注文を記録のみ行うペーパーモード
+	PaperMode bool `json:"paper_mode,omitempty"`
+
+	// 注文数量の最小・最大・刻みと、満たさない場合の扱い（models.BrokerConfig を参照）
+	MinLot    float64          `json:"min_lot,omitempty"`
+	MaxLot    float64          `json:"max_lot,omitempty"`
+	LotStep   float64          `json:"lot_step,omitempty"`
+	LotPolicy models.LotPolicy `json:"lot_policy,omitempty"`
+
+	// 同時保有ポジション数と建玉金額の上限（0の場合は無制限）
+	MaxOpenPositions int     `json:"max_open_positions,omitempty"`
+	MaxExposure      float64 `json:"max_exposure,omitempty"`
+
+	// 必要証拠金の計算に使うレバレッジ（0の場合は100倍、models.BrokerConfig を参照）
+	Leverage float64 `json:"leverage,omitempty"`
+
+	// 同じシンボルの注文を合算するか（hedging・netting、models.AccountMode を参照）
+	AccountMode models.AccountMode `json:"account_mode,omitempty"`
+
+	// 指値・逆指値注文の約定判定（close・touch・cross、models.FillModel を参照）
+	FillModel models.FillModel `json:"fill_model,omitempty"`
+
+	// ストップロス・テイクプロフィットの判定（close・intrabar、models.ProtectionModel を参照）
+	ProtectionModel models.ProtectionModel `json:"protection_model,omitempty"`
+
+	// 口座通貨と、決済通貨から口座通貨への換算レート（models.BrokerConfig を参照）
+	AccountCurrency string             `json:"account_currency,omitempty"`
+	ConversionRates map[string]float64 `json:"conversion_rates,omitempty"`
+	// 換算レートの価格データ（通貨ペアをキーとするCSVファイルのパス）。指定した場合は ConversionRates より優先する
+	ConversionFeeds map[string]string `json:"conversion_feeds,omitempty"`
+}
+
+// brokerConfig は models.BrokerConfig に変換します
+func (bc BrokerConfig) brokerConfig() models.BrokerConfig {
+	return models.BrokerConfig{
+		InitialBalance:           bc.InitialBalance,
+		Spread:                   bc.Spread,
+		SpreadPips:               bc.SpreadPips,
+		Slippage:                 bc.Slippage,
+		SlippageMode:             bc.SlippageMode,
+		MinVolumeToTrade:         bc.MinVolumeToTrade,
+		IlliquidPolicy:           bc.IlliquidPolicy,
+		IlliquidSpreadMultiplier: bc.IlliquidSpreadMultiplier,
+		PaperMode:                bc.PaperMode,
+		MinLot:                   bc.MinLot,
+		MaxLot:                   bc.MaxLot,
+		LotStep:                  bc.LotStep,
+		LotPolicy:                bc.LotPolicy,
+		MaxOpenPositions:         bc.MaxOpenPositions,
+		MaxExposure:              bc.MaxExposure,
+		Leverage:                 bc.Leverage,
+		AccountMode:              bc.AccountMode,
+		FillModel:                bc.FillModel,
+		ProtectionModel:          bc.ProtectionModel,
+		AccountCurrency:          bc.AccountCurrency,
+		ConversionRates:          bc.ConversionRates,
+	}
+}
+
+// rateSource は ConversionFeeds から換算レートの取得元を作成します。ConversionFeeds が空の場合は nil です。
+func (bc BrokerConfig) rateSource() broker.FeedRates {
+	if len(bc.ConversionFeeds) == 0 {
+		return nil
+	}
+	feeds := make(broker.FeedRates, len(bc.ConversionFeeds))
+	for pair, path := range bc.ConversionFeeds {
+		feeds[pair] = data.NewCSVProvider(models.DataProviderConfig{FilePath: path, Format: "csv"})
+	}
+	return feeds
 }
 
 // BacktestConfig はバックテスト実行に関する設定
@@ -41,6 +159,24 @@ type BacktestConfig struct {
 	StartTime *time.Time `json:"start_time,omitempty"`
 	EndTime   *time.Time `json:"end_time,omitempty"`
 	MaxSteps  *int       `json:"max_steps,omitempty"`
+	// Seed は Rand が返す乱数生成器と、random モードのスリッページに使う乱数生成器のシードです。
+	// 0の場合は実行ごとに異なるシードを使用します。
+	Seed int64 `json:"seed,omitempty"`
+	// FlatTime は毎日の取引終了時刻（UTC、"15:04" 形式）です。シミュレーション時刻がこの時刻をまたぐと
+	// 全ポジションを決済し、当日限り（models.DayOrder）の保留注文を取り消します。空の場合は無効です。
+	FlatTime string `json:"flat_time,omitempty"`
+}
+
+// flatTimeOfDay は FlatTime を0時からの経過時間に変換します。FlatTime が空の場合は false を返します。
+func (bc BacktestConfig) flatTimeOfDay() (time.Duration, bool, error) {
+	if bc.FlatTime == "" {
+		return 0, false, nil
+	}
+	t, err := time.Parse("15:04", bc.FlatTime)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid flat time %q: expected HH:MM", bc.FlatTime)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, true, nil
 }
 
 // Config はバックテスト全体の設定
@@ -49,6 +185,87 @@ type Config struct {
 	Broker     BrokerConfig              `json:"broker"`
 	Backtest   BacktestConfig            `json:"backtest"`
 	Visualizer models.VisualizerConfig   `json:"visualizer"`
+	// シンボルごとのpipサイズ・契約サイズ・決済通貨。指定したシンボルの数量はロット数として扱われ、
+	// ブローカーの損益・証拠金は契約サイズを掛けて計算される（未指定のシンボルは契約サイズ1）
+	Symbols map[string]models.SymbolSpec `json:"symbols,omitempty"`
+	// DefaultInstruments が true の場合、主要な通貨ペアの組み込み銘柄レジストリ（instruments.Default）を使う
+	DefaultInstruments bool `json:"default_instruments,omitempty"`
+	// 銘柄レジストリを読み込むJSONファイルのパス（instruments.Load の形式）。組み込みレジストリと同じシンボルは上書きする。
+	// 登録した最小サイズ・サイズ刻み・ティックサイズはブローカーの注文に適用される
+	InstrumentsFile string `json:"instruments_file,omitempty"`
+}
+
+// instrumentRegistry は DefaultInstruments・InstrumentsFile・Symbols の順に重ねて、ブローカーが参照する銘柄レジストリを作成します。
+// Symbols の指定は pipサイズ・契約サイズ・決済通貨を上書きし、レジストリの最小サイズ・サイズ刻み・ティックサイズは引き継ぎます。
+// いずれも指定されていない場合は nil です。
+func (c Config) instrumentRegistry() (*instruments.Registry, error) {
+	var registry *instruments.Registry
+	if c.DefaultInstruments {
+		registry = instruments.Default()
+	}
+	if c.InstrumentsFile != "" {
+		loaded, err := instruments.Load(c.InstrumentsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load instruments file: %w", err)
+		}
+		if registry == nil {
+			registry = loaded
+		} else {
+			registry.Merge(loaded)
+		}
+	}
+	if len(c.Symbols) == 0 {
+		return registry, nil
+	}
+	
+	if registry == nil {
+		registry = instruments.NewRegistry()
+	}
+	for symbol, spec := range c.Symbols {
+		instrument, _ := registry.Get(symbol)
+		instrument.Symbol = symbol
+		instrument.PipSize = spec.PipSize
+		instrument.ContractSize = spec.ContractSize
+		instrument.QuoteCurrency = spec.QuoteCurrency
+		// validateConfig で検証済みのため登録は失敗しない
+		registry.Register(instrument)
+	}
+	return registry, nil
+}
+
+// Strategy は Run で実行する売買戦略のインターフェースです。
+type Strategy interface {
+	// OnBar はローソク足ごとに、時間を進める前に呼び出されます。
+	OnBar(bt *Backtester) error
+}
+
+// Result はバックテストの実行結果です。
+type Result = models.BacktestResult
+
+// Progress は RunWithCallback で通知されるバックテストの進捗です。
+type Progress struct {
+	// Bar はこれまでに処理したローソク足の本数です。
+	Bar int
+	// Total は全ローソク足の本数です。データ提供元が本数を返せない場合は0です。
+	Total int
+	// Time は現在のシミュレーション時刻です。
+	Time time.Time
+}
+
+// Percent は進捗率（0〜100）を返します。全本数が不明な場合は0を返します。
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Bar) / float64(p.Total) * 100
+}
+
+// PositionSnapshot はダッシュボード向けのポジションの読み取り専用ビューです。
+type PositionSnapshot struct {
+	Position      models.Position `json:"position"`
+	CurrentPrice  float64         `json:"current_price"`
+	UnrealizedPnL float64         `json:"unrealized_pnl"`
+	AgeSeconds    float64         `json:"age_seconds"`
 }
 
 // Backtester はバックテスト実行とユーザーAPIを提供する統括コンポーネントです。
@@ -56,76 +273,154 @@ type Backtester struct {
 	config           Config
 	market           market.Market
 	broker           broker.Broker
+	// Config.DefaultInstruments・InstrumentsFile・Symbols から作成した銘柄レジストリ（未指定の場合は nil）
+	instruments      *instruments.Registry
 	visualizer       visualizer.Visualizer
 	initialized      bool
+	// Stop 済みかどうか（Visualizer・コントローラーを停止したため再初期化できない）
+	stopped          bool
+	// データ読み込みの失敗や戦略のエラーなど、実行を中断した致命的なエラー（Reset で解除）
+	errMutex         sync.RWMutex
+	err              error
 	statistics       *models.Statistics
+	// 保留注文の約定時に呼び出すユーザーの関数（OnOrderFilled で登録）
+	orderFilled      broker.OrderFilledFunc
+	// 最後に統計情報を Visualizer に送信した時刻（送信の間引きに使う）
+	lastStatisticsPush time.Time
+	// イベントログの記録先（SetEventRecorder で設定、nil の場合は記録しない）
+	events           atomic.Pointer[EventRecorder]
+	// 一括決済の間だけ設定する、イベントログに記録する決済理由（flat_time・end_of_backtest）
+	closeReason      string
+	// 証拠金維持率が100%を下回っている間は true（マージンコールを1回だけ記録する）
+	marginCalled     bool
+	// GetStatus で返す進捗のスナップショット（時間進行のたびに更新し、HTTP のハンドラーから並行して読む）
+	status           atomic.Pointer[models.BacktestStatus]
 	// バックテスト制御関連
 	backtestController *BacktestController
 	controlMutex     sync.RWMutex
+	// stepMutex は Forward による時間進行と Reset を排他します
+	stepMutex        sync.Mutex
 	ctx              context.Context
 	cancel           context.CancelFunc
+	// 注文IDの連番と、戦略などが使う乱数生成器
+	orderSeq         atomic.Uint64
+	seed             int64
+	rngSource        *countingSource
+	rng              *rand.Rand
+	// ブローカーのスリッページ用の乱数ソース（戦略の乱数とは独立した列）
+	slippageSource   *countingSource
+	// 毎日の取引終了時刻（0時からの経過時間、UTC）
+	flatTime         time.Duration
+	flatTimeEnabled  bool
+	// 診断ログの出力先（SetLogger で差し替え可能）
+	logger           atomic.Pointer[slog.Logger]
+	// 換算レートのフィード（診断ログの出力先を渡すために保持する）
+	feeds            broker.FeedRates
+	// 初期化前の取得の警告を1回だけ出力するためのフラグ
+	warnedUninitialized atomic.Bool
+	// Close の多重呼び出しを防ぎ、最初の結果を返す
+	closeOnce        sync.Once
+	closeErr         error
 }
 
 // BacktestController はバックテストのコントロールを管理
 type BacktestController struct {
-	bt              *Backtester
-	speedCh         chan float64
-	playCh          chan bool
-	state           models.BacktestControlState
-	mutex           sync.RWMutex
-	ctx             context.Context
-	cancel          context.CancelFunc
+	bt           *Backtester
+	speedCh      chan float64
+	playCh       chan bool
+	state        models.BacktestControlState
+	pendingSteps int // 一時停止中に進めてよい残りステップ数
+	wake         chan struct{} // 再生・ステップ・リセットのたびに閉じて作り直し、一時停止中の Forward を起こす
+	mutex        sync.RWMutex
+	ctx          context.Context
+	cancel       context.CancelFunc
+	done         chan struct{} // controlLoop の終了で閉じられる
 }
 
 // NewBacktester は新しいBacktesterを作成します。
 func NewBacktester(config Config) (*Backtester, error) {
 	// 設定の検証
+	if err := config.Market.DataProvider.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: market data provider config is invalid: %w", err)
+	}
 	if err := validateConfig(config); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+	registry, err := config.instrumentRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 
 	// Market作成
-	mkt := market.NewMarket(models.MarketConfig{
-		DataProvider: config.Market.DataProvider,
-		Symbol:       "EURUSD", // デフォルト値
-	})
+	mkt := market.NewMarket(config.Market.marketConfig())
+	
+	return newBacktester(config, mkt, registry), nil
+}
+
+// NewBacktesterWithProvider は指定したDataProviderからデータを読み込むBacktesterを作成します。
+// data.InMemoryProvider と組み合わせると、ファイルを使わずにバックテストできます。
+// config.Market.DataProvider は使用されません。
+func NewBacktesterWithProvider(config Config, provider data.DataProvider) (*Backtester, error) {
+	if provider == nil {
+		return nil, errors.New("invalid config: data provider is required")
+	}
+	if err := validateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	registry, err := config.instrumentRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 	
-	// Broker作成 (models.BrokerConfigに変換)
-	brokerConfig := models.BrokerConfig{
-		InitialBalance: config.Broker.InitialBalance,
-		Spread:         config.Broker.Spread,
+	return newBacktester(config, market.NewMarketWithProviderConfig(provider, config.Market.marketConfig()), registry), nil
+}
+
+// newBacktester は検証済みの設定とMarket、銘柄レジストリからBacktesterを組み立てます。
+func newBacktester(config Config, mkt market.Market, registry *instruments.Registry) *Backtester {
+	// Broker作成 (models.BrokerConfigに変換し、シンボルのメタデータを銘柄レジストリとして渡す)
+	bkr := broker.NewSimpleBrokerWithInstruments(config.Broker.brokerConfig(), mkt, registry)
+	feeds := config.Broker.rateSource()
+	if feeds != nil {
+		bkr.SetRateSource(feeds)
 	}
-	bkr := broker.NewSimpleBroker(brokerConfig, mkt)
 	
 	// コンテキストを作成
 	ctx, cancel := context.WithCancel(context.Background())
 	
+	seed := config.Backtest.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	
 	bt := &Backtester{
 		config:           config,
 		market:           mkt,
 		broker:           bkr,
+		instruments:      registry,
+		feeds:            feeds,
 		visualizer:       nil,
 		initialized:      false,
 		statistics:       models.NewStatistics(config.Broker.InitialBalance),
 		ctx:              ctx,
 		cancel:           cancel,
+		seed:             seed,
 	}
+	bt.flatTime, bt.flatTimeEnabled, _ = config.Backtest.flatTimeOfDay()
+	bt.resetRand()
+	bt.SetLogger(models.NewLogger(os.Stderr, config.Visualizer.LogLevel))
+	bkr.OnOrderFilled(bt.handleOrderFilled)
+	bkr.OnPositionClosed(bt.handlePositionClosed)
 	
 	// BacktestControllerを作成
 	if config.Visualizer.Enabled {
 		bt.backtestController = NewBacktestController(bt)
 	}
 	
-	return bt, nil
+	return bt
 }
 
-// validateConfig は設定の妥当性を検証します
+// validateConfig はデータ提供元以外の設定の妥当性を検証します
 func validateConfig(config Config) error {
-	// DataProvider設定の検証
-	if err := config.Market.DataProvider.Validate(); err != nil {
-		return fmt.Errorf("market data provider config is invalid: %w", err)
-	}
-	
 	// Broker設定の検証
 	if config.Broker.InitialBalance <= 0 {
 		return errors.New("broker initial balance must be positive")
@@ -133,6 +428,53 @@ func validateConfig(config Config) error {
 	if config.Broker.Spread < 0 {
 		return errors.New("broker spread must be non-negative")
 	}
+	brokerConfig := config.Broker.brokerConfig()
+	if err := brokerConfig.ValidateLots(); err != nil {
+		return fmt.Errorf("broker config is invalid: %w", err)
+	}
+	if err := brokerConfig.ValidateLimits(); err != nil {
+		return fmt.Errorf("broker config is invalid: %w", err)
+	}
+	if err := brokerConfig.ValidateLeverage(); err != nil {
+		return fmt.Errorf("broker config is invalid: %w", err)
+	}
+	if err := brokerConfig.ValidateAccountMode(); err != nil {
+		return fmt.Errorf("broker config is invalid: %w", err)
+	}
+	if err := brokerConfig.ValidateSlippage(); err != nil {
+		return fmt.Errorf("broker config is invalid: %w", err)
+	}
+	if err := brokerConfig.ValidateFillModel(); err != nil {
+		return fmt.Errorf("broker config is invalid: %w", err)
+	}
+	if err := brokerConfig.ValidateProtectionModel(); err != nil {
+		return fmt.Errorf("broker config is invalid: %w", err)
+	}
+	if err := brokerConfig.ValidateCurrency(); err != nil {
+		return fmt.Errorf("broker config is invalid: %w", err)
+	}
+	for pair, path := range config.Broker.ConversionFeeds {
+		if len(pair) != 6 || path == "" {
+			return fmt.Errorf("broker conversion feed for %q requires a currency pair and a file path", pair)
+		}
+	}
+	if config.Broker.SpreadPips < 0 {
+		return errors.New("broker spread pips must be non-negative")
+	}
+	
+	// シンボルのメタデータの検証
+	if err := models.ValidateSymbols(config.Symbols); err != nil {
+		return err
+	}
+	
+	// Market設定（キャッシュ）の検証
+	marketConfig := config.Market.marketConfig()
+	if err := marketConfig.ValidateCache(); err != nil {
+		return fmt.Errorf("market config is invalid: %w", err)
+	}
+	if err := marketConfig.Calendar.Validate(); err != nil {
+		return fmt.Errorf("market config is invalid: %w", err)
+	}
 	
 	// Backtest設定の検証
 	if err := validateBacktestConfig(config.Backtest); err != nil {
@@ -161,6 +503,11 @@ func validateBacktestConfig(config BacktestConfig) error {
 		return errors.New("max steps must be positive")
 	}
 	
+	// 取引終了時刻の形式チェック
+	if _, _, err := config.flatTimeOfDay(); err != nil {
+		return err
+	}
+	
 	return nil
 }
 
@@ -172,8 +519,27 @@ func NewBacktesterWithVisualizer(dataConfig models.DataProviderConfig, brokerCon
 			DataProvider: dataConfig,
 		},
 		Broker: BrokerConfig{
-			InitialBalance: brokerConfig.InitialBalance,
-			Spread:         brokerConfig.Spread,
+			InitialBalance:           brokerConfig.InitialBalance,
+			Spread:                   brokerConfig.Spread,
+			SpreadPips:               brokerConfig.SpreadPips,
+			Slippage:                 brokerConfig.Slippage,
+			SlippageMode:             brokerConfig.SlippageMode,
+			MinVolumeToTrade:         brokerConfig.MinVolumeToTrade,
+			IlliquidPolicy:           brokerConfig.IlliquidPolicy,
+			IlliquidSpreadMultiplier: brokerConfig.IlliquidSpreadMultiplier,
+			PaperMode:                brokerConfig.PaperMode,
+			MinLot:                   brokerConfig.MinLot,
+			MaxLot:                   brokerConfig.MaxLot,
+			LotStep:                  brokerConfig.LotStep,
+			LotPolicy:                brokerConfig.LotPolicy,
+			MaxOpenPositions:         brokerConfig.MaxOpenPositions,
+			MaxExposure:              brokerConfig.MaxExposure,
+			Leverage:                 brokerConfig.Leverage,
+			AccountMode:              brokerConfig.AccountMode,
+			FillModel:                brokerConfig.FillModel,
+			ProtectionModel:          brokerConfig.ProtectionModel,
+			AccountCurrency:          brokerConfig.AccountCurrency,
+			ConversionRates:          brokerConfig.ConversionRates,
 		},
 		Backtest:   BacktestConfig{}, // 空のBacktestConfig
 		Visualizer: visualizerConfig,
@@ -200,6 +566,8 @@ func NewBacktestController(bt *Backtester) *BacktestController {
 		state:   models.BacktestControlState{IsPlaying: false, Speed: 1.0, State: models.BacktestStateIdle},
 		ctx:     ctx,
 		cancel:  cancel,
+		done:    make(chan struct{}),
+		wake:    make(chan struct{}),
 	}
 	
 	// コントロールループを開始
@@ -210,13 +578,21 @@ func NewBacktestController(bt *Backtester) *BacktestController {
 
 // Initialize はBacktesterを初期化します。
 func (bt *Backtester) Initialize(ctx context.Context) error {
+	// 二重初期化は Visualizer の二重起動などにつながるため拒否する
+	if bt.initialized {
+		return ErrAlreadyInitialized
+	}
+	if bt.stopped {
+		return ErrStopped
+	}
+	
 	// VisualizerConfig検証
 	if err := bt.config.Visualizer.Validate(); err != nil {
 		return fmt.Errorf("invalid visualizer config: %w", err)
 	}
 	
-	// Visualizer初期化（有効な場合のみ）
-	if bt.config.Visualizer.Enabled {
+	// Visualizer初期化（有効な場合のみ。AttachVisualizer で共有の Visualizer に接続済みの場合は起動しない）
+	if bt.config.Visualizer.Enabled && bt.visualizer == nil {
 		if err := bt.initializeVisualizer(ctx); err != nil {
 			return fmt.Errorf("failed to initialize visualizer: %w", err)
 		}
@@ -225,11 +601,19 @@ func (bt *Backtester) Initialize(ctx context.Context) error {
 	// Market初期化
 	err := bt.market.Initialize(ctx)
 	if err != nil {
+		// 起動済みの Visualizer を残すと再度の Initialize でポートが衝突するため停止しておく
+		if bt.config.Visualizer.Enabled && bt.visualizer != nil {
+			bt.visualizer.Stop()
+			bt.visualizer = nil
+		}
 		return fmt.Errorf("failed to initialize market: %w", err)
 	}
 	
-	bt.ctx = ctx
+	// Cancel と呼び出し元のキャンセル・期限の両方で停止できるよう、派生コンテキストを保持する
+	bt.ctx, bt.cancel = context.WithCancel(ctx)
 	bt.initialized = true
+	bt.recordCandle()
+	bt.updateStatus()
 	
 	// Visualizerに状態変更を通知
 	if bt.visualizer != nil {
@@ -251,6 +635,20 @@ func (bt *Backtester) initializeVisualizer(ctx context.Context) error {
 		ClientTimeout:     bt.config.Visualizer.ClientTimeout,
 		BufferSize:        bt.config.Visualizer.BufferSize,
 		LogLevel:          bt.config.Visualizer.LogLevel,
+		HistorySize:       bt.config.Visualizer.HistorySize,
+		AllowedOrigins:    bt.config.Visualizer.AllowedOrigins,
+		CertFile:          bt.config.Visualizer.CertFile,
+		KeyFile:           bt.config.Visualizer.KeyFile,
+		StaticDir:         bt.config.Visualizer.StaticDir,
+		EnableCompression: bt.config.Visualizer.EnableCompression,
+		ReplayBufferSize:  bt.config.Visualizer.ReplayBufferSize,
+		EquityPoints:      bt.config.Visualizer.EquityPoints,
+		Logger:            bt.Logger(),
+	}
+	
+	if bt.config.Visualizer.BatchCandles {
+		vizConfig.BatchInterval = bt.config.Visualizer.FlushInterval
+		vizConfig.BatchSize = bt.config.Visualizer.BatchSize
 	}
 	
 	// Visualizer作成
@@ -261,6 +659,11 @@ func (bt *Backtester) initializeVisualizer(ctx context.Context) error {
 		bt.visualizer.SetBacktestController(bt.backtestController)
 	}
 	
+	// 接続時に直近のローソク足を送信できるようにする
+	bt.visualizer.SetHistoryProvider(bt)
+	// /status で進捗をポーリングできるようにする
+	bt.visualizer.SetStatusProvider(bt)
+	
 	// Visualizer開始
 	if err := bt.visualizer.Start(ctx, bt.config.Visualizer.Port); err != nil {
 		return fmt.Errorf("failed to start visualizer: %w", err)
@@ -269,8 +672,46 @@ func (bt *Backtester) initializeVisualizer(ctx context.Context) error {
 	return nil
 }
 
+// AttachVisualizer は起動済みの共有 Visualizer の sessionID のセッションにバックテストを接続します。
+// 複数の Backtester を別々のセッションに接続すると、1つのダッシュボードで各バックテストを監視・操作できます。
+// 接続したバックテストはダッシュボードから play されるまで進みません。Stop はセッションの登録を解除し、共有の Visualizer は停止しません。
+// Initialize の前に呼び出してください。専用の Visualizer を起動する Config.Visualizer.Enabled とは併用できません。
+func (bt *Backtester) AttachVisualizer(viz visualizer.Visualizer, sessionID string) error {
+	if bt.initialized {
+		return ErrAlreadyInitialized
+	}
+	if bt.config.Visualizer.Enabled || bt.visualizer != nil {
+		return errors.New("visualizer already configured for this backtester")
+	}
+	if viz == nil || !viz.IsRunning() {
+		return errors.New("visualizer must be running to attach")
+	}
+	
+	if bt.backtestController == nil {
+		bt.backtestController = NewBacktestController(bt)
+	}
+	session := viz.Session(sessionID)
+	session.SetBacktestController(bt.backtestController)
+	session.SetHistoryProvider(bt)
+	session.SetStatusProvider(bt)
+	bt.visualizer = session
+	return nil
+}
+
 // Stop はBacktesterとVisualizerを停止します。
+// 停止後の Backtester は再初期化できません（Initialize は ErrStopped を返す）。複数回呼び出しても安全です。
 func (bt *Backtester) Stop() error {
+	if bt.stopped {
+		return nil
+	}
+	
+	// Visualizer の停止に失敗しても、停止済みとして扱う
+	bt.initialized = false
+	bt.stopped = true
+	if bt.cancel != nil {
+		bt.cancel()
+	}
+	
 	// BacktestControllerを停止
 	if bt.backtestController != nil {
 		bt.backtestController.Stop()
@@ -288,33 +729,76 @@ func (bt *Backtester) Stop() error {
 		bt.visualizer.OnBacktestStateChange(models.BacktestStateStopped)
 	}
 	
-	bt.initialized = false
 	return nil
 }
 
+// Close はBacktesterが保持する全てのリソースを解放します。
+// Stop に加えてコンテキストをキャンセルし、コントロールループのゴルーチンの終了を待ってから
+// Market を閉じます（io.Closer を実装する DataProvider も閉じられる）。
+// 複数回呼び出しても安全で、2回目以降は最初の呼び出しの結果を返します。
+func (bt *Backtester) Close() error {
+	bt.closeOnce.Do(func() {
+		bt.closeErr = bt.close()
+	})
+	return bt.closeErr
+}
+
+// close は Close の本体。Visualizer → コントローラー → Market の順に停止する
+func (bt *Backtester) close() error {
+	var errs []error
+	
+	if err := bt.Stop(); err != nil {
+		errs = append(errs, err)
+	}
+	
+	if bt.backtestController != nil {
+		bt.backtestController.Stop()
+		bt.backtestController.Wait()
+	}
+	
+	// Forward・Reset の実行中に Market を閉じないよう排他する
+	bt.stepMutex.Lock()
+	err := bt.market.Close()
+	bt.stepMutex.Unlock()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to close market: %w", err))
+	}
+	
+	return errors.Join(errs...)
+}
+
 // Forward は時間を次のステップに進めます。
 func (bt *Backtester) Forward() bool {
 	if !bt.initialized {
 		return false
 	}
 	
+	// キャンセル済みの場合は進めない
+	if bt.IsCancelled() {
+		return false
+	}
+	
 	// コントロールモードが有効な場合のチェック
 	if bt.backtestController != nil {
-		// コントロールモードではコントローラーが再生状態の時のみ進む
-		for !bt.backtestController.IsRunning() {
-			// コンテキストのキャンセルをチェック
+		// コントロールモードではコントローラーが再生状態、またはステップ実行が残っている時のみ進む
+		for {
+			// 判定より先に通知チャネルを取得し、判定との間の再生・ステップ要求を取りこぼさない
+			wake := bt.backtestController.wakeChannel()
+			if bt.backtestController.acquireStep() {
+				break
+			}
+			
+			// バックテストが完全に終了した場合のチェック
+			if bt.market.IsFinished() {
+				return false
+			}
+			
+			// 一時停止中は状態変更かキャンセルまでポーリングせずに待機
 			select {
 			case <-bt.ctx.Done():
-				fmt.Println("Backtest interrupted by context cancellation")
+				bt.Logger().Info("backtest interrupted by context cancellation")
 				return false
-			default:
-				// 一時停止中は実際に待機
-				time.Sleep(100 * time.Millisecond)
-				
-				// バックテストが完全に終了した場合のチェック
-				if bt.market.IsFinished() {
-					return false
-				}
+			case <-wake:
 			}
 		}
 		
@@ -328,7 +812,7 @@ func (bt *Backtester) Forward() bool {
 			// 速度制御の待機中もコンテキストをチェック
 			select {
 			case <-bt.ctx.Done():
-				fmt.Println("Backtest interrupted during speed control")
+				bt.Logger().Info("backtest interrupted during speed control")
 				return false
 			case <-time.After(waitTime):
 				// 速度制御の待機終了
@@ -336,58 +820,409 @@ func (bt *Backtester) Forward() bool {
 		}
 	}
 	
+	bt.stepMutex.Lock()
+	defer bt.stepMutex.Unlock()
+	
 	// Market時間進行
+	previousTime := bt.market.GetCurrentTime()
 	hasNext := bt.market.Forward()
+	if hasNext {
+		bt.recordCandle()
+	}
+	
+	// データ読み込みの失敗による停止は正常終了と区別して記録する
+	if !hasNext {
+		if err := bt.market.Err(); err != nil {
+			bt.fail(err)
+		}
+	}
+	
+	// 取引終了時刻をまたいだ場合は、保留注文の約定判定より先にポジションを決済する
+	if hasNext && bt.flatTimeEnabled && bt.crossedFlatTime(previousTime, bt.market.GetCurrentTime()) {
+		bt.flattenSession()
+	}
 	
 	// Broker側のポジション価格更新
 	if hasNext {
 		bt.broker.UpdatePositions()
+		bt.checkMarginCall()
+		
+		// 取引の有無に関わらず、含み損益を含む有効証拠金を毎ステップ反映する
+		equity := bt.updateAccountStatistics()
+		bt.updateStatus()
 		
 		// Visualizerにローソク足データを通知
 		if bt.visualizer != nil {
 			candle := bt.market.GetCurrentCandle()
-			fmt.Printf("Current Candle: %v\n", candle)
 			if candle != nil {
+				// 毎ステップ通る経路のため、debug 以外では引数の組み立ても行わない
+				if logger := bt.Logger(); logger.Enabled(bt.ctx, slog.LevelDebug) {
+					logger.Debug("current candle", "time", candle.Timestamp, "close", candle.Close)
+				}
 				bt.visualizer.OnCandleUpdate(candle)
 			}
 			
-			// 統計情報の更新と通知
-			bt.statistics.UpdateBalance(bt.broker.GetBalance())
-			bt.visualizer.OnStatisticsUpdate(bt.statistics)
+			// 統計情報の通知（最後のローソク足では間引かずに送信する）
+			bt.publishStatistics(bt.market.IsFinished())
+			bt.visualizer.OnEquityUpdate(bt.market.GetCurrentTime(), equity)
 		}
 	}
 	
 	return hasNext
 }
 
-// IsFinished はバックテストが終了したかを確認します。
+// crossedFlatTime は previous より後、current 以前に取引終了時刻があるかを判定します。
+// データの欠落で複数日をまたいだ場合も1回として扱います。
+func (bt *Backtester) crossedFlatTime(previous, current time.Time) bool {
+	previous = previous.UTC()
+	boundary := time.Date(previous.Year(), previous.Month(), previous.Day(), 0, 0, 0, 0, time.UTC).Add(bt.flatTime)
+	if !boundary.After(previous) {
+		boundary = boundary.Add(24 * time.Hour)
+	}
+	return !current.Before(boundary)
+}
+
+// flattenSession は当日限りの保留注文を取り消し、全ポジションを現在価格で決済します。
+// 決済は ClosePosition と同じく通常の取引として取引履歴に記録されます。
+func (bt *Backtester) flattenSession() {
+	logger := bt.Logger()
+	for _, order := range bt.broker.GetPendingOrders() {
+		if !order.IsDayOrder() {
+			continue
+		}
+		if err := bt.broker.CancelOrder(order.ID); err != nil {
+			logger.Warn("failed to cancel day order at flat time", "order", order.ID, "error", err)
+			continue
+		}
+		bt.recordOrder(EventOrderCanceled, order, "flat_time")
+	}
+	
+	bt.closeReason = "flat_time"
+	defer func() { bt.closeReason = "" }()
+	positions := bt.broker.GetPositions()
+	for _, position := range positions {
+		if err := bt.ClosePosition(position.ID); err != nil {
+			logger.Warn("failed to close position at flat time", "position", position.ID, "error", err)
+		}
+	}
+	if len(positions) > 0 {
+		logger.Debug("closed positions at flat time", "time", bt.market.GetCurrentTime(), "positions", len(positions))
+	}
+}
+
+// updateAccountStatistics は統計情報の残高・有効証拠金・含み損益をブローカーの値で更新し、有効証拠金を返します。
+// 残高からは拘束中の証拠金が差し引かれているため、有効証拠金はブローカーの値（GetStatus と同じ）を使い、
+// 建玉の証拠金をドローダウンとして数えないようにします。
+func (bt *Backtester) updateAccountStatistics() float64 {
+	equity := bt.broker.GetEquity()
+	bt.statistics.UpdateAccount(bt.broker.GetBalance(), equity, bt.GetUnrealizedPnL())
+	return equity
+}
+
+// publishStatistics は統計情報を Visualizer に送信します。
+// 前回の送信から Visualizer 設定の StatisticsInterval が経過していない場合は、force でない限り送信を間引きます。
+func (bt *Backtester) publishStatistics(force bool) {
+	now := time.Now()
+	interval := bt.config.Visualizer.StatisticsInterval
+	if !force && interval > 0 && now.Sub(bt.lastStatisticsPush) < interval {
+		return
+	}
+	bt.lastStatisticsPush = now
+	bt.visualizer.OnStatisticsUpdate(bt.statistics)
+}
+
+// Reset はバックテストを最初のローソク足まで巻き戻し、残高・ポジション・取引履歴・統計情報を初期状態に戻します。
+// コントロールモードでは一時停止状態に戻り、Visualizer に Idle 状態を通知します。
+func (bt *Backtester) Reset() error {
+	if !bt.initialized {
+		return ErrNotInitialized
+	}
+	
+	bt.stepMutex.Lock()
+	defer bt.stepMutex.Unlock()
+	
+	if err := bt.market.Reset(bt.ctx); err != nil {
+		return fmt.Errorf("failed to reset market: %w", err)
+	}
+	bt.broker.Reset()
+	bt.statistics = models.NewStatistics(bt.config.Broker.InitialBalance)
+	bt.orderSeq.Store(0)
+	bt.resetRand()
+	bt.marginCalled = false
+	if recorder := bt.events.Load(); recorder != nil {
+		recorder.Reset()
+		bt.recordCandle()
+	}
+	bt.updateStatus()
+	bt.errMutex.Lock()
+	bt.err = nil
+	bt.errMutex.Unlock()
+	
+	if bt.backtestController != nil {
+		bt.backtestController.resetState()
+	}
+	
+	if bt.visualizer != nil {
+		bt.visualizer.OnBacktestStateChange(models.BacktestStateIdle)
+		bt.publishStatistics(true)
+	}
+	
+	return nil
+}
+
+// IsFinished はバックテストが終了したかを確認します。キャンセルされた場合も終了として扱います。
 func (bt *Backtester) IsFinished() bool {
 	if !bt.initialized {
 		return false
 	}
-	return bt.market.IsFinished()
+	return bt.market.IsFinished() || bt.IsCancelled()
+}
+
+// Err はバックテストを中断した致命的なエラーを返します。
+// データ読み込みの失敗で Forward が停止した場合や、Run が戦略のエラーで中断した場合に nil 以外を返し、
+// データ終端まで正常に進んだ場合や実行中は nil を返します。Reset で解除されます。
+func (bt *Backtester) Err() error {
+	bt.errMutex.RLock()
+	defer bt.errMutex.RUnlock()
+	return bt.err
+}
+
+// fail は致命的なエラーを記録し、Error 状態とエラー内容を Visualizer に通知します。最初のエラーのみ保持します。
+func (bt *Backtester) fail(err error) {
+	bt.errMutex.Lock()
+	if bt.err != nil {
+		bt.errMutex.Unlock()
+		return
+	}
+	bt.err = err
+	bt.errMutex.Unlock()
+	
+	bt.Logger().Error("backtest failed", "error", err)
+	if bt.visualizer != nil {
+		bt.visualizer.OnBacktestStateChange(models.BacktestStateError)
+		bt.visualizer.BroadcastMessage(visualizer.Message{
+			Type:      "error",
+			Data:      map[string]string{"error": err.Error()},
+			Timestamp: time.Now(),
+		})
+	}
+}
+
+// GetState は現在のバックテストの状態を返します。
+// Stop 後は Stopped、初期化前は Idle、致命的なエラー（Err を参照）で中断した場合は Error、キャンセル後は Stopped、
+// データ終端に達した場合は Completed を返します。それ以外はコントロールモードであればコントローラーの状態
+// （Idle・Running・Paused）、そうでなければ Running を返します。
+func (bt *Backtester) GetState() models.BacktestState {
+	switch {
+	case bt.stopped:
+		return models.BacktestStateStopped
+	case !bt.initialized:
+		return models.BacktestStateIdle
+	case bt.Err() != nil:
+		return models.BacktestStateError
+	case bt.IsCancelled():
+		return models.BacktestStateStopped
+	case bt.market.IsFinished():
+		return models.BacktestStateCompleted
+	case bt.backtestController != nil:
+		return bt.backtestController.GetState().State
+	default:
+		return models.BacktestStateRunning
+	}
+}
+
+// GetStatus は状態・シミュレーション時刻・完了率・残高・有効証拠金・保有ポジション数をまとめた進捗を返します。
+// 状態以外は時間を進めるたび（と Initialize・Reset・Run の終了時）に更新したスナップショットのため、
+// 実行中のゴルーチンとは別のゴルーチン（Visualizer の /status など）からも呼び出せます。
+// UpdatedAt が長時間更新されない場合は、一時停止中でなければ戦略やデータ読み込みが停滞しています。
+func (bt *Backtester) GetStatus() models.BacktestStatus {
+	var status models.BacktestStatus
+	if snapshot := bt.status.Load(); snapshot != nil {
+		status = *snapshot
+	}
+	status.State = bt.GetState().String()
+	return status
+}
+
+// updateStatus は GetStatus で返す進捗のスナップショットを現在の値で更新します。
+func (bt *Backtester) updateStatus() {
+	bar, total := bt.market.Progress()
+	status := &models.BacktestStatus{
+		CurrentTime:   bt.market.GetCurrentTime(),
+		Bar:           bar,
+		TotalBars:     total,
+		Balance:       bt.broker.GetBalance(),
+		Equity:        bt.broker.GetEquity(),
+		OpenPositions: len(bt.broker.GetPositions()),
+		UpdatedAt:     time.Now(),
+	}
+	if total > 0 {
+		status.PercentComplete = float64(bar) / float64(total) * 100
+	}
+	bt.status.Store(status)
+}
+
+// SetLogger は診断ログの出力先を設定します。nil を指定するとログを出力しません。
+// 既定では Visualizer 設定の LogLevel に従って標準エラー出力に出力します。
+// データ提供元（換算レートのフィードを含む）の読み込み時のログも同じロガーに出力します。
+// Visualizer やデータ提供元にも同じロガーを使わせる場合は Initialize の前に呼び出してください。
+func (bt *Backtester) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = models.NopLogger()
+	}
+	bt.logger.Store(logger)
+	if setter, ok := bt.market.(data.LoggerSetter); ok {
+		setter.SetLogger(logger)
+	}
+	for _, feed := range bt.feeds {
+		if setter, ok := feed.(data.LoggerSetter); ok {
+			setter.SetLogger(logger)
+		}
+	}
+}
+
+// Logger は診断ログの出力先を返します。戦略から独自のログを出力する際にも使えます。
+func (bt *Backtester) Logger() *slog.Logger {
+	return bt.logger.Load()
+}
+
+// IsCancelled は Cancel、または Initialize に渡したコンテキストのキャンセル・期限切れによって停止したかを確認します。
+func (bt *Backtester) IsCancelled() bool {
+	return bt.ctx != nil && bt.ctx.Err() != nil
 }
 
 // GetCurrentTime は現在の時刻を取得します。
+// 初期化前はゼロ値を返し、最初の1回だけ警告ログを出力します。エラーで区別する場合は CurrentTime を使います。
 func (bt *Backtester) GetCurrentTime() time.Time {
 	if !bt.initialized {
+		bt.warnNotInitialized("GetCurrentTime")
 		return time.Time{}
 	}
 	return bt.market.GetCurrentTime()
 }
 
+// CurrentTime は現在の時刻を取得します。
+// 初期化前は ErrNotInitialized、現在のローソク足がない場合は ErrNoCurrentCandle を返します。
+func (bt *Backtester) CurrentTime() (time.Time, error) {
+	candle, err := bt.currentCandle()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return candle.Timestamp, nil
+}
+
+// currentCandle は現在のローソク足を取得します。読み込みの失敗で市場が停止している場合は、その原因も含めて返します。
+func (bt *Backtester) currentCandle() (*models.Candle, error) {
+	if !bt.initialized {
+		return nil, ErrNotInitialized
+	}
+	candle := bt.market.GetCurrentCandle()
+	if candle == nil {
+		if err := bt.market.Err(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNoCurrentCandle, err)
+		}
+		return nil, ErrNoCurrentCandle
+	}
+	return candle, nil
+}
+
+// warnNotInitialized は初期化前に値を取得しようとしたことを最初の1回だけ警告ログに出力します。
+func (bt *Backtester) warnNotInitialized(method string) {
+	if bt.warnedUninitialized.CompareAndSwap(false, true) {
+		bt.Logger().Warn("backtester not initialized; returning zero value", "method", method)
+	}
+}
+
+// SetInitialTime はデータの最初のローソク足が指定時刻になるようシミュレーション時刻をずらします。
+// 取引開始後に時刻を変更すると建玉時刻と整合しなくなるため、初期化後・取引前にのみ呼び出せます。
+func (bt *Backtester) SetInitialTime(t time.Time) error {
+	if !bt.initialized {
+		return ErrNotInitialized
+	}
+	
+	if len(bt.broker.GetPositions()) > 0 || len(bt.broker.GetTradeHistory()) > 0 {
+		return errors.New("cannot set initial time after trading has started")
+	}
+	
+	if err := bt.market.SetStartTime(t); err != nil {
+		return fmt.Errorf("failed to set initial time: %w", err)
+	}
+	
+	return nil
+}
+
+// PublishIndicator はストラテジーのインジケーター値を現在時刻の点として Visualizer に送信します。
+// Visualizer が無効な場合は何もしません。
+func (bt *Backtester) PublishIndicator(name string, value float64) error {
+	if !bt.initialized {
+		return ErrNotInitialized
+	}
+	
+	if bt.visualizer == nil {
+		return nil
+	}
+	
+	return bt.visualizer.OnIndicatorUpdate(name, bt.market.GetCurrentTime(), value)
+}
+
 // GetCurrentPrice は指定シンボルの現在価格を取得します。
+// 初期化前は0を返し、最初の1回だけ警告ログを出力します。エラーで区別する場合は CurrentPrice を使います。
 func (bt *Backtester) GetCurrentPrice() float64 {
 	if !bt.initialized {
+		bt.warnNotInitialized("GetCurrentPrice")
 		return 0.0
 	}
 	return bt.market.GetCurrentPrice()
 }
 
+// CurrentPrice は現在価格（現在のローソク足の終値）を取得します。
+// 初期化前は ErrNotInitialized、現在のローソク足がない場合は ErrNoCurrentCandle を返すため、価格が0の場合と区別できます。
+func (bt *Backtester) CurrentPrice() (float64, error) {
+	candle, err := bt.currentCandle()
+	if err != nil {
+		return 0.0, err
+	}
+	return candle.Close, nil
+}
+
+// nextOrderID は "buy-SYMBOL-連番" 形式の注文IDを生成します。
+// 連番は1から始まり Reset で戻るため、同じ入力からは常に同じ注文IDが生成されます。
+func (bt *Backtester) nextOrderID(side, symbol string) string {
+	return fmt.Sprintf("%s-%s-%d", side, symbol, bt.orderSeq.Add(1))
+}
+
+// Rand は BacktestConfig.Seed で初期化された乱数生成器を返します。
+// モンテカルロ法など乱数を使う処理はこれを使うことで、同じシードから同じ結果を再現できます。
+// Reset で同じシードから初期化し直されます。並行して使用する場合は呼び出し側で排他してください。
+func (bt *Backtester) Rand() *rand.Rand {
+	return bt.rng
+}
+
+// resetRand はシードから乱数生成器を初期化し直します。
+// スリッページ用の生成器はこの Backtester のブローカー専用のため、RunBatch で並行実行しても共有されません。
+func (bt *Backtester) resetRand() {
+	bt.rngSource = newCountingSource(bt.seed)
+	bt.rng = rand.New(bt.rngSource)
+	bt.slippageSource = newCountingSource(slippageSeed(bt.seed))
+	bt.broker.SetRand(rand.New(bt.slippageSource))
+}
+
+// slippageSeed はスリッページ用の乱数生成器のシードを返します。
+// Rand の乱数を戦略が使ってもスリッページが変わらないよう、シードから別の列を導きます。
+func slippageSeed(seed int64) int64 {
+	return seed ^ 0x5DEECE66D
+}
+
 // Buy は買い注文を実行します。
 func (bt *Backtester) Buy(symbol string, size float64) error {
+	return bt.BuyWithProtection(symbol, size, 0, 0)
+}
+
+// BuyWithProtection はストップロス・テイクプロフィット価格付きの買い注文を実行します。
+// 0を指定した保護価格は設定されません。保護価格に達したポジションは Forward 時にブローカーが決済します。
+func (bt *Backtester) BuyWithProtection(symbol string, size, stopLoss, takeProfit float64) error {
 	if !bt.initialized {
-		return errors.New("backtester not initialized")
+		return ErrNotInitialized
 	}
 	
 	// 入力値検証
@@ -402,22 +1237,28 @@ func (bt *Backtester) Buy(symbol string, size float64) error {
 	}
 	
 	// 注文作成
-	orderID := fmt.Sprintf("buy-%s-%d", symbol, time.Now().UnixNano())
+	orderID := bt.nextOrderID("buy", symbol)
 	order := models.NewMarketOrder(orderID, symbol, models.Buy, size)
+	order.StopLoss = stopLoss
+	order.TakeProfit = takeProfit
 	
 	// Broker経由で注文実行
+	bt.recordOrder(EventOrderPlaced, order, "")
 	err := bt.broker.PlaceOrder(order)
 	if err != nil {
+		bt.recordOrder(EventOrderRejected, order, err.Error())
 		return err
 	}
+	bt.recordFill(order, bt.filledPosition(order))
 	
 	// Visualizerにトレードイベントを通知（ポジションオープン）
 	if bt.visualizer != nil {
 		// ダミーのトレードイベントを作成
 		trade := &models.Trade{
 			ID:         orderID,
+			Symbol:     symbol,
 			Side:       models.Buy,
-			Size:       size,
+			Size:       order.Size, // 数量の刻みで丸められた場合は約定した数量
 			EntryPrice: price,
 			ExitPrice:  0, // まだクローズされていない
 			PnL:        0,
@@ -428,7 +1269,7 @@ func (bt *Backtester) Buy(symbol string, size float64) error {
 		bt.visualizer.OnTradeEvent(trade)
 		
 		// 統計情報を更新
-		bt.statistics.UpdateBalance(bt.broker.GetBalance())
+		bt.updateAccountStatistics()
 	}
 	
 	return nil
@@ -436,8 +1277,14 @@ func (bt *Backtester) Buy(symbol string, size float64) error {
 
 // Sell は売り注文を実行します。
 func (bt *Backtester) Sell(symbol string, size float64) error {
+	return bt.SellWithProtection(symbol, size, 0, 0)
+}
+
+// SellWithProtection はストップロス・テイクプロフィット価格付きの売り注文を実行します。
+// 0を指定した保護価格は設定されません。保護価格に達したポジションは Forward 時にブローカーが決済します。
+func (bt *Backtester) SellWithProtection(symbol string, size, stopLoss, takeProfit float64) error {
 	if !bt.initialized {
-		return errors.New("backtester not initialized")
+		return ErrNotInitialized
 	}
 	
 	// 入力値検証
@@ -452,22 +1299,28 @@ func (bt *Backtester) Sell(symbol string, size float64) error {
 	}
 	
 	// 注文作成
-	orderID := fmt.Sprintf("sell-%s-%d", symbol, time.Now().UnixNano())
+	orderID := bt.nextOrderID("sell", symbol)
 	order := models.NewMarketOrder(orderID, symbol, models.Sell, size)
+	order.StopLoss = stopLoss
+	order.TakeProfit = takeProfit
 	
 	// Broker経由で注文実行
+	bt.recordOrder(EventOrderPlaced, order, "")
 	err := bt.broker.PlaceOrder(order)
 	if err != nil {
+		bt.recordOrder(EventOrderRejected, order, err.Error())
 		return err
 	}
+	bt.recordFill(order, bt.filledPosition(order))
 	
 	// Visualizerにトレードイベントを通知（ポジションオープン）
 	if bt.visualizer != nil {
 		// ダミーのトレードイベントを作成
 		trade := &models.Trade{
 			ID:         orderID,
+			Symbol:     symbol,
 			Side:       models.Sell,
-			Size:       size,
+			Size:       order.Size, // 数量の刻みで丸められた場合は約定した数量
 			EntryPrice: price,
 			ExitPrice:  0, // まだクローズされていない
 			PnL:        0,
@@ -478,7 +1331,7 @@ func (bt *Backtester) Sell(symbol string, size float64) error {
 		bt.visualizer.OnTradeEvent(trade)
 		
 		// 統計情報を更新
-		bt.statistics.UpdateBalance(bt.broker.GetBalance())
+		bt.updateAccountStatistics()
 	}
 	
 	return nil
@@ -492,58 +1345,133 @@ func (bt *Backtester) GetPositions() []*models.Position {
 	return bt.broker.GetPositions()
 }
 
+// GetPositionsSnapshot は現在価格・含み損益・保有時間を含むポジション一覧を取得します。
+// 保有時間はマーケット時刻を基準に計算します。
+func (bt *Backtester) GetPositionsSnapshot() []PositionSnapshot {
+	if !bt.initialized {
+		return []PositionSnapshot{}
+	}
+	
+	currentPrice := bt.market.GetCurrentPrice()
+	currentTime := bt.market.GetCurrentTime()
+	
+	positions := bt.broker.GetPositions()
+	snapshots := make([]PositionSnapshot, 0, len(positions))
+	for _, position := range positions {
+		// 呼び出し側からブローカー内部の状態を変更できないようコピーする
+		snapshot := *position
+		if currentPrice > 0 {
+			snapshot.CurrentPrice = currentPrice
+		}
+		
+		snapshots = append(snapshots, PositionSnapshot{
+			Position:      snapshot,
+			CurrentPrice:  snapshot.CurrentPrice,
+			UnrealizedPnL: bt.broker.ConvertToAccount(snapshot.Symbol, snapshot.UnrealizedPnL()*bt.contractSize(snapshot.Symbol)),
+			AgeSeconds:    currentTime.Sub(position.OpenTime).Seconds(),
+		})
+	}
+	
+	return snapshots
+}
+
+// GetUnrealizedPnL は保有中の全ポジションの含み損益の合計を現在価格で計算します。
+func (bt *Backtester) GetUnrealizedPnL() float64 {
+	total := 0.0
+	for _, snapshot := range bt.GetPositionsSnapshot() {
+		total += snapshot.UnrealizedPnL
+	}
+	return total
+}
+
+// GetSymbolSpec は銘柄レジストリ（Config.Symbols・DefaultInstruments・InstrumentsFile）に登録されたシンボルのメタデータを取得します（大文字小文字を区別しません）。
+func (bt *Backtester) GetSymbolSpec(symbol string) (models.SymbolSpec, bool) {
+	instrument, ok := bt.instruments.Get(symbol)
+	if !ok {
+		return models.SymbolSpec{}, false
+	}
+	return models.SymbolSpec{
+		PipSize:       instrument.PipSize,
+		ContractSize:  instrument.ContractSize,
+		QuoteCurrency: instrument.QuoteCurrency,
+	}, true
+}
+
+// contractSize は指定シンボルの契約サイズを返します。銘柄レジストリにない場合は1です。
+func (bt *Backtester) contractSize(symbol string) float64 {
+	if spec, ok := bt.GetSymbolSpec(symbol); ok {
+		return spec.ContractSize
+	}
+	return 1.0
+}
+
+// GetAveragePrice は指定したシンボル・売買方向の保有ポジションの平均エントリー価格と合計サイズを取得します。
+// 複数回に分けてエントリーした場合の平均建値として使えます。
+func (bt *Backtester) GetAveragePrice(symbol string, side models.OrderSide) (float64, float64) {
+	if !bt.initialized {
+		return 0, 0
+	}
+	return bt.broker.AveragePrice(symbol, side)
+}
+
+// PositionSizeForRisk は、symbol を entry で建てたポジションが stop で決済された場合の損失が
+// 有効証拠金の riskPct パーセント（1.0 = 1%）になる注文数量を返します。
+// 数量は Buy・Sell に渡す単位（銘柄レジストリに登録したシンボルではロット数）で、契約サイズと口座通貨への換算を考慮し、
+// 銘柄レジストリのサイズ刻みで切り捨てます。最小サイズに満たない場合や初期化前は0を返します。
+func (bt *Backtester) PositionSizeForRisk(symbol string, riskPct, entry, stop float64) float64 {
+	if !bt.initialized {
+		return 0
+	}
+	
+	units := models.PositionSizeForRisk(bt.broker.GetEquity(), riskPct, entry, stop, 0)
+	// 数量1あたりの価格変動額（契約サイズ分）を口座通貨に換算して割る
+	perSize := bt.broker.ConvertToAccount(symbol, bt.contractSize(symbol))
+	if perSize <= 0 {
+		return 0
+	}
+	return bt.instruments.NormalizeSize(symbol, units/perSize)
+}
+
+// GetRecentCandles は現在のローソク足を含む直近count件のローソク足を取得します。
+func (bt *Backtester) GetRecentCandles(count int) []*models.Candle {
+	if !bt.initialized {
+		return []*models.Candle{}
+	}
+	return bt.market.GetRecentCandles(count)
+}
+
 // GetBalance は現在の残高を取得します。
+// 初期化前は0を返し、最初の1回だけ警告ログを出力します。エラーで区別する場合は Balance を使います。
 func (bt *Backtester) GetBalance() float64 {
 	if !bt.initialized {
+		bt.warnNotInitialized("GetBalance")
 		return 0.0
 	}
 	return bt.broker.GetBalance()
 }
 
+// Balance は現在の残高を取得します。初期化前は ErrNotInitialized を返します。
+func (bt *Backtester) Balance() (float64, error) {
+	if !bt.initialized {
+		return 0.0, ErrNotInitialized
+	}
+	return bt.broker.GetBalance(), nil
+}
+
 // ClosePosition は指定されたポジションを決済します。
 func (bt *Backtester) ClosePosition(positionID string) error {
 	if !bt.initialized {
-		return errors.New("backtester not initialized")
-	}
-	
-	// ポジション情報を取得（クローズ前）
-	var position *models.Position
-	positions := bt.broker.GetPositions()
-	for _, p := range positions {
-		if p.ID == positionID {
-			position = p
-			break
-		}
-	}
-	
-	// ポジションをクローズ
-	err := bt.broker.ClosePosition(positionID)
-	if err != nil {
-		return err
-	}
-	
-	// Visualizerにトレードイベントを通知（ポジションクローズ）
-	if bt.visualizer != nil && position != nil {
-		// クローズ後のトレード履歴から最新のトレードを取得
-		tradeHistory := bt.broker.GetTradeHistory()
-		if len(tradeHistory) > 0 {
-			lastTrade := tradeHistory[len(tradeHistory)-1]
-			bt.visualizer.OnTradeEvent(lastTrade)
-			
-			// 統計情報を更新
-			bt.statistics.AddTrade(lastTrade.PnL)
-			bt.statistics.UpdateBalance(bt.broker.GetBalance())
-			bt.visualizer.OnStatisticsUpdate(bt.statistics)
-		}
+		return ErrNotInitialized
 	}
 	
-	return nil
+	// ポジションをクローズ（統計情報の更新と Visualizer への通知は handlePositionClosed で行う）
+	return bt.broker.ClosePosition(positionID)
 }
 
 // CloseAllPositions は全ポジションを決済します。
 func (bt *Backtester) CloseAllPositions() error {
 	if !bt.initialized {
-		return errors.New("backtester not initialized")
+		return ErrNotInitialized
 	}
 	
 	positions := bt.broker.GetPositions()
@@ -557,6 +1485,39 @@ func (bt *Backtester) CloseAllPositions() error {
 	return nil
 }
 
+// GetTrades は filter の条件を全て満たす取引を取引履歴の順（決済時刻順）に返します。
+// 取引履歴は決済時刻順に並んでいるため、From・To は二分探索で範囲を絞ってから残りの条件を判定します。
+func (bt *Backtester) GetTrades(filter models.TradeFilter) []*models.Trade {
+	trades := bt.GetTradeHistory()
+	start, end := 0, len(trades)
+	if !filter.From.IsZero() {
+		start = sort.Search(len(trades), func(i int) bool {
+			return !trades[i].CloseTime.Before(filter.From)
+		})
+	}
+	if !filter.To.IsZero() {
+		end = sort.Search(len(trades), func(i int) bool {
+			return !trades[i].CloseTime.Before(filter.To)
+		})
+	}
+	
+	matched := make([]*models.Trade, 0)
+	for i := start; i < end; i++ {
+		if filter.Matches(trades[i]) {
+			matched = append(matched, trades[i])
+		}
+	}
+	return matched
+}
+
+// GetTradeByID は指定IDの取引を取引履歴から取得します。部分決済の取引IDは "ポジションID-注文ID" 形式です。
+func (bt *Backtester) GetTradeByID(tradeID string) (*models.Trade, bool) {
+	if !bt.initialized {
+		return nil, false
+	}
+	return bt.broker.GetTrade(tradeID)
+}
+
 // GetTradeHistory は取引履歴を取得します。
 func (bt *Backtester) GetTradeHistory() []*models.Trade {
 	if !bt.initialized {
@@ -565,6 +1526,51 @@ func (bt *Backtester) GetTradeHistory() []*models.Trade {
 	return bt.broker.GetTradeHistory()
 }
 
+// GetPaperSignals はペーパーモードで記録された注文の一覧を取得します。
+func (bt *Backtester) GetPaperSignals() []broker.PaperSignal {
+	if !bt.initialized {
+		return []broker.PaperSignal{}
+	}
+	return bt.broker.GetPaperSignals()
+}
+
+// OnOrderFilled は指値・逆指値注文が約定した時に呼び出す関数を登録します。nil で登録を解除します。
+// 関数は Forward の中で約定した直後に呼び出されるため、例えばエントリーの約定を受けて
+// すぐに決済注文を発注できます。ペーパーモードではポジションを建てないため position は nil です。
+func (bt *Backtester) OnOrderFilled(fn func(order *models.Order, position *models.Position)) {
+	bt.orderFilled = fn
+}
+
+// filledPosition は成行注文の約定で建てた、またはネッティングで合算・一部決済したポジションを返します。ペーパーモードでは nil です。
+// ネッティングモードでちょうど決済された場合も nil です。
+func (bt *Backtester) filledPosition(order *models.Order) *models.Position {
+	for _, position := range bt.broker.GetPositions() {
+		if position.ID == "pos-"+order.ID {
+			return position
+		}
+	}
+	if bt.config.Broker.AccountMode == models.AccountNetting {
+		for _, position := range bt.broker.GetPositions() {
+			if position.Symbol == order.Symbol {
+				return position
+			}
+		}
+	}
+	return nil
+}
+
+// handleOrderFilled はブローカーからの約定通知を Visualizer と OnOrderFilled で登録された関数に伝えます。
+func (bt *Backtester) handleOrderFilled(order *models.Order, position *models.Position) {
+	bt.Logger().Debug("pending order filled", "order", order.ID, "price", order.ExecutedPrice)
+	bt.recordFill(order, position)
+	if bt.visualizer != nil {
+		bt.visualizer.OnOrderFilled(order, position)
+	}
+	if bt.orderFilled != nil {
+		bt.orderFilled(order, position)
+	}
+}
+
 // BacktestController のメソッド群
 
 // Play はバックテストを開始/再開
@@ -575,6 +1581,9 @@ func (bc *BacktestController) Play(speed float64) error {
 	bc.state.IsPlaying = true
 	bc.state.Speed = speed
 	bc.state.State = models.BacktestStateRunning
+	// 再生中はステップ実行を使わないため、未消化のステップは破棄する
+	bc.pendingSteps = 0
+	bc.notifyLocked()
 	
 	// 非ブロッキングで状態を送信
 	select {
@@ -587,7 +1596,7 @@ func (bc *BacktestController) Play(speed float64) error {
 	default:
 	}
 	
-	fmt.Printf("Backtest started with speed: %f\n", speed)
+	bc.bt.Logger().Info("backtest started", "speed", speed)
 	return nil
 }
 
@@ -605,10 +1614,76 @@ func (bc *BacktestController) Pause() error {
 	default:
 	}
 	
-	fmt.Printf("Backtest paused\n")
+	bc.bt.Logger().Info("backtest paused")
+	return nil
+}
+
+// Step は一時停止中に指定したステップ数だけ Forward を進め、その後再び一時停止する
+func (bc *BacktestController) Step(count int) error {
+	if count <= 0 {
+		count = 1
+	}
+	
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	
+	if bc.state.IsPlaying {
+		return errors.New("step is only available while paused")
+	}
+	
+	bc.pendingSteps += count
+	bc.notifyLocked()
+	bc.bt.Logger().Debug("backtest step requested", "count", count)
 	return nil
 }
 
+// Reset はバックテストを最初から実行し直せるよう巻き戻す
+func (bc *BacktestController) Reset() error {
+	bc.bt.Logger().Info("backtest reset requested")
+	return bc.bt.Reset()
+}
+
+// resetState はコントローラーを一時停止した初期状態に戻す（速度は維持）
+func (bc *BacktestController) resetState() {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	
+	bc.state.IsPlaying = false
+	bc.state.State = models.BacktestStateIdle
+	bc.pendingSteps = 0
+	bc.notifyLocked()
+}
+
+// wakeChannel は次の状態変更で閉じられるチャネルを返す
+func (bc *BacktestController) wakeChannel() <-chan struct{} {
+	bc.mutex.RLock()
+	defer bc.mutex.RUnlock()
+	return bc.wake
+}
+
+// notifyLocked は一時停止中の Forward を起こす。呼び出し側は bc.mutex を保持していること
+func (bc *BacktestController) notifyLocked() {
+	close(bc.wake)
+	bc.wake = make(chan struct{})
+}
+
+// acquireStep は Forward を進めてよいかを判定し、ステップ実行中であれば残りステップ数を消費する
+func (bc *BacktestController) acquireStep() bool {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	
+	if bc.state.IsPlaying {
+		return true
+	}
+	
+	if bc.pendingSteps > 0 {
+		bc.pendingSteps--
+		return true
+	}
+	
+	return false
+}
+
 // SetSpeed はバックテストの速度を設定
 func (bc *BacktestController) SetSpeed(speed float64) error {
 	bc.mutex.Lock()
@@ -622,7 +1697,7 @@ func (bc *BacktestController) SetSpeed(speed float64) error {
 	default:
 	}
 	
-	fmt.Printf("Backtest speed changed to: %f\n", speed)
+	bc.bt.Logger().Info("backtest speed changed", "speed", speed)
 	return nil
 }
 
@@ -642,6 +1717,8 @@ func (bc *BacktestController) IsRunning() bool {
 
 // controlLoop はコントロールループを実行
 func (bc *BacktestController) controlLoop() {
+	defer close(bc.done)
+	
 	for {
 		select {
 		case <-bc.ctx.Done():
@@ -649,16 +1726,14 @@ func (bc *BacktestController) controlLoop() {
 		case isPlaying := <-bc.playCh:
 			bc.bt.controlMutex.Lock()
 			if isPlaying {
-				fmt.Printf("Backtest control: Play\n")
+				bc.bt.Logger().Debug("backtest control: play")
 			} else {
-				fmt.Printf("Backtest control: Pause\n")
+				bc.bt.Logger().Debug("backtest control: pause")
 			}
 			bc.bt.controlMutex.Unlock()
 		case speed := <-bc.speedCh:
-			bc.bt.controlMutex.Lock()
-			bc.state.Speed = speed
-			bc.bt.controlMutex.Unlock()
-			fmt.Printf("Backtest control: Speed changed to %f\n", speed)
+			// state.Speed は Play・SetSpeed が bc.mutex の下で更新済み
+			bc.bt.Logger().Debug("backtest control: speed changed", "speed", speed)
 		}
 	}
 }
@@ -668,9 +1743,159 @@ func (bc *BacktestController) Stop() {
 	bc.cancel()
 }
 
-// Cancel はバックテストをキャンセルします
+// Wait はコントロールループのゴルーチンが終了するまで待つ（Stop の後に呼び出す）
+func (bc *BacktestController) Wait() {
+	<-bc.done
+}
+
+// Run は最後のローソク足まで戦略を実行し、残りのポジションを決済して結果を返します。
+// 事前に Initialize を呼び出しておく必要があります。
+// 途中でキャンセルされた場合はその時点で停止し、Cancelled を true にした部分的な結果を返します。
+// データの読み込みに失敗した場合は結果を返さずにエラーを返します（Err でも取得できる）。
+func (bt *Backtester) Run(strategy Strategy) (*Result, error) {
+	return bt.RunWithCallback(strategy, nil)
+}
+
+// RunWithCallback は Run と同様に戦略を実行し、各ローソク足の処理後に進捗を callback に通知します。
+// callback が nil の場合は Run と同じです。callback は Run と同じゴルーチンで呼び出されます。
+func (bt *Backtester) RunWithCallback(strategy Strategy, callback func(Progress)) (*Result, error) {
+	if !bt.initialized {
+		return nil, ErrNotInitialized
+	}
+	if strategy == nil {
+		return nil, errors.New("strategy is required")
+	}
+	
+	startTime := bt.GetCurrentTime()
+	
+	for !bt.IsFinished() {
+		if err := strategy.OnBar(bt); err != nil {
+			err = fmt.Errorf("strategy failed at %s: %w", bt.GetCurrentTime().Format(time.RFC3339), err)
+			bt.fail(err)
+			return nil, err
+		}
+		if callback != nil {
+			bar, total := bt.market.Progress()
+			callback(Progress{Bar: bar, Total: total, Time: bt.GetCurrentTime()})
+		}
+		if !bt.Forward() {
+			break
+		}
+	}
+	
+	// データの途中で読み込みに失敗した場合は、部分的な結果を成功として返さない
+	if err := bt.Err(); err != nil {
+		return nil, fmt.Errorf("backtest stopped at %s: %w", bt.GetCurrentTime().Format(time.RFC3339), err)
+	}
+	
+	bt.closeReason = "end_of_backtest"
+	err := bt.CloseAllPositions()
+	bt.closeReason = ""
+	bt.updateStatus()
+	if err != nil {
+		err = fmt.Errorf("failed to close positions: %w", err)
+		bt.fail(err)
+		return nil, err
+	}
+	
+	result := bt.buildResult(startTime, bt.GetCurrentTime())
+	result.Cancelled = bt.IsCancelled()
+	return result, nil
+}
+
+// buildResult は取引履歴から結果を集計します。期間はシミュレーション時刻で記録します。
+func (bt *Backtester) buildResult(startTime, endTime time.Time) *Result {
+	trades := bt.GetTradeHistory()
+	
+	result := models.NewBacktestResult(bt.config.Broker.InitialBalance)
+	for _, trade := range trades {
+		result.AddTrade(*trade)
+	}
+	result.Finalize()
+	result.StartTime = startTime
+	result.EndTime = endTime
+	result.Duration = endTime.Sub(startTime)
+	
+	calculator := statistics.NewCalculator(trades)
+	result.MaxDrawdown = calculator.CalculateMaxDrawdown()
+	result.SharpeRatio = calculator.CalculateSharpeRatio()
+	result.ProfitFactor = calculator.CalculateProfitFactor()
+	
+	return result
+}
+
+// Cancel はバックテストをキャンセルします。実行中の Run は次のローソク足に進む前に停止します。
 func (bt *Backtester) Cancel() {
 	if bt.cancel != nil {
 		bt.cancel()
 	}
-}
\ No newline at end of file
+}
+
+// RecostTrades は記録済みの取引を、エントリー・決済時刻の価格データから新しいスプレッドと手数料で再計算します。
+// 戦略を再実行せずにコスト感応度を確認するためのもので、元の取引は変更せず調整後のコピーを返します。
+// 約定価格はブローカーと同様に終値へスプレッドを加減して求め、取引に記録された契約サイズと口座通貨への換算レートで損益を計算します。
+// 手数料は1取引（往復）あたりの金額として損益から差し引き、Commission に記録します。スワップも損益から差し引きます。
+func RecostTrades(trades []*models.Trade, newSpread, newCommission float64, provider data.DataProvider) ([]*models.Trade, error) {
+	if provider == nil {
+		return nil, errors.New("data provider is required")
+	}
+	if newSpread < 0 {
+		return nil, errors.New("spread must be non-negative")
+	}
+	if newCommission < 0 {
+		return nil, errors.New("commission must be non-negative")
+	}
+	
+	ctx := context.Background()
+	recosted := make([]*models.Trade, 0, len(trades))
+	for _, trade := range trades {
+		if trade == nil {
+			continue
+		}
+		
+		entryMid, err := closePriceAt(ctx, provider, trade.OpenTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to price entry of trade %s: %w", trade.ID, err)
+		}
+		exitMid, err := closePriceAt(ctx, provider, trade.CloseTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to price exit of trade %s: %w", trade.ID, err)
+		}
+		
+		adjusted := *trade
+		if trade.Side == models.Buy {
+			adjusted.EntryPrice = entryMid + newSpread // Ask価格で買い
+			adjusted.ExitPrice = exitMid - newSpread   // Bid価格で売却
+		} else {
+			adjusted.EntryPrice = entryMid - newSpread // Bid価格で売り
+			adjusted.ExitPrice = exitMid + newSpread   // Ask価格で買戻し
+		}
+		units, rate := trade.Units(), trade.AccountRate()
+		adjusted.PnL = models.CalculatePnL(trade.Side, units, adjusted.EntryPrice, adjusted.ExitPrice)*rate - newCommission - trade.Swap
+		adjusted.SpreadCost = 2 * newSpread * units * rate
+		adjusted.SlippageCost = 0
+		adjusted.Commission = newCommission
+		
+		recosted = append(recosted, &adjusted)
+	}
+	
+	return recosted, nil
+}
+
+// closePriceAt は指定時刻時点で有効なローソク足の終値を取得します。
+func closePriceAt(ctx context.Context, provider data.DataProvider, t time.Time) (float64, error) {
+	index, err := provider.TimeToIndex(t)
+	if err != nil {
+		return 0, err
+	}
+	
+	candles, err := provider.GetCandlesByIndex(ctx, index, index)
+	if err != nil {
+		return 0, err
+	}
+	if len(candles) == 0 {
+		return 0, fmt.Errorf("no candle found at %v", t)
+	}
+	
+	return candles[0].Close, nil
+}
@@ -1,9 +1,21 @@
 package backtester
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/RuiHirano/fx-backtesting/pkg/data"
 	"github.com/RuiHirano/fx-backtesting/pkg/models"
 	"github.com/RuiHirano/fx-backtesting/pkg/visualizer"
 	"github.com/stretchr/testify/assert"
@@ -11,10 +23,12 @@ import (
 
 // MockVisualizer はテスト用のモックVisualizer
 type MockVisualizer struct {
-	candleUpdates    []*models.Candle
-	tradeEvents      []*models.Trade
+	candleUpdates     []*models.Candle
+	tradeEvents       []*models.Trade
 	statisticsUpdates []*models.Statistics
-	stateChanges     []VisualizerBacktestState
+	stateChanges      []VisualizerBacktestState
+	indicatorUpdates  []visualizer.IndicatorUpdate
+	orderFills        []visualizer.OrderFilled
 }
 
 // VisualizerBacktestState はVisualizer用のバックテスト状態
@@ -53,6 +67,20 @@ func (m *MockVisualizer) OnStatisticsUpdate(stats *models.Statistics) error {
 	return nil
 }
 
+func (m *MockVisualizer) OnEquityUpdate(timestamp time.Time, equity float64) error {
+	return nil
+}
+
+func (m *MockVisualizer) OnIndicatorUpdate(name string, timestamp time.Time, value float64) error {
+	m.indicatorUpdates = append(m.indicatorUpdates, visualizer.IndicatorUpdate{Name: name, Time: timestamp, Value: value})
+	return nil
+}
+
+func (m *MockVisualizer) OnOrderFilled(order *models.Order, position *models.Position) error {
+	m.orderFills = append(m.orderFills, visualizer.OrderFilled{Order: order, Position: position})
+	return nil
+}
+
 func (m *MockVisualizer) OnBacktestStateChange(state BacktestState) error {
 	m.stateChanges = append(m.stateChanges, VisualizerBacktestState(state))
 	return nil
@@ -99,6 +127,22 @@ func (m *MockVisualizer) GetBacktestController() models.BacktestController {
 	return nil
 }
 
+func (m *MockVisualizer) SetHistoryProvider(provider visualizer.HistoryProvider) {
+	// Do nothing in mock
+}
+
+func (m *MockVisualizer) SetStatusProvider(provider visualizer.StatusProvider) {
+	// Do nothing in mock
+}
+
+func (m *MockVisualizer) Session(id string) visualizer.Visualizer {
+	return m
+}
+
+func (m *MockVisualizer) SessionIDs() []string {
+	return nil
+}
+
 func (m *MockVisualizer) GetCandleUpdateCount() int {
 	return len(m.candleUpdates)
 }
@@ -198,6 +242,18 @@ func TestBacktesterWithVisualizer(t *testing.T) {
 			t.Error("Expected candle update notification after Forward")
 		}
 		
+		// インジケーター値は現在のシミュレーション時刻で通知される
+		if err := backtester.PublishIndicator("fast_ma", 1.2345); err != nil {
+			t.Errorf("Expected no error from PublishIndicator, got %v", err)
+		}
+		if len(mockVisualizer.indicatorUpdates) != 1 {
+			t.Fatalf("Expected 1 indicator update, got %d", len(mockVisualizer.indicatorUpdates))
+		}
+		update := mockVisualizer.indicatorUpdates[0]
+		assert.Equal(t, "fast_ma", update.Name)
+		assert.Equal(t, 1.2345, update.Value)
+		assert.True(t, backtester.GetCurrentTime().Equal(update.Time))
+		
 		// 最後のローソク足データを確認
 		lastCandle := mockVisualizer.GetLastCandle()
 		if lastCandle == nil {
@@ -252,6 +308,9 @@ func TestBacktesterWithVisualizer(t *testing.T) {
 		if mockVisualizer.GetTradeEventCount() == 0 {
 			t.Error("Expected trade event notification after Buy")
 		}
+		if trade := mockVisualizer.GetLastTrade(); trade == nil || trade.Symbol != "SAMPLE" {
+			t.Errorf("Expected trade event with symbol SAMPLE, got %+v", trade)
+		}
 		
 		// ポジション決済
 		positions := backtester.GetPositions()
@@ -546,10 +605,16 @@ func TestBacktester_PositionManagement(t *testing.T) {
 	}
 }
 
-// Backtester 統合テスト
-func TestBacktester_Integration(t *testing.T) {
+// Backtester ポジションスナップショットテスト
+func TestBacktester_GetPositionsSnapshot(t *testing.T) {
 	backtester := createTestBacktester(t)
 	
+	// 初期化前は空
+	if len(backtester.GetPositionsSnapshot()) != 0 {
+		t.Error("Expected empty snapshot before Initialize")
+	}
+	assert.Equal(t, 0.0, backtester.GetUnrealizedPnL())
+	
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	
@@ -558,138 +623,1856 @@ func TestBacktester_Integration(t *testing.T) {
 		t.Fatalf("Expected no error from Initialize, got %v", err)
 	}
 	
-	// 複数の時間ステップで取引実行
-	tradeCount := 0
-	maxSteps := 3
+	err = backtester.Buy("SAMPLE", 10000.0)
+	if err != nil {
+		t.Fatalf("Expected no error from Buy, got %v", err)
+	}
 	
-	for step := 0; step < maxSteps && !backtester.IsFinished(); step++ {
-		// 現在価格取得
-		price := backtester.GetCurrentPrice()
-		if price <= 0.0 {
-			t.Errorf("Expected positive price at step %d", step)
+	// 2ステップ（1分足で2分）進める
+	backtester.Forward()
+	backtester.Forward()
+	
+	snapshots := backtester.GetPositionsSnapshot()
+	if len(snapshots) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(snapshots))
+	}
+	
+	position := backtester.GetPositions()[0]
+	snapshot := snapshots[0]
+	assert.Equal(t, position.ID, snapshot.Position.ID)
+	assert.Equal(t, backtester.GetCurrentPrice(), snapshot.CurrentPrice)
+	assert.InDelta(t, position.UnrealizedPnL(), snapshot.UnrealizedPnL, 1e-9)
+	assert.InDelta(t, 120.0, snapshot.AgeSeconds, 1e-9)
+	
+	// 含み損益の合計は売買方向を考慮して現在価格から計算される
+	expected := (backtester.GetCurrentPrice() - position.EntryPrice) * position.Size
+	assert.InDelta(t, expected, backtester.GetUnrealizedPnL(), 1e-9)
+}
+
+// Backtester 統計情報の毎ステップ更新テスト
+func TestBacktester_StatisticsUpdates(t *testing.T) {
+	t.Run("should update equity every step without visualizer", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		err := backtester.Initialize(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
 		}
 		
-		// ステップごとに異なる取引パターン
-		switch step {
-		case 0:
-			// 買い注文
-			err = backtester.Buy("SAMPLE", 10000.0)
-			if err != nil {
-				t.Fatalf("Expected no error from Buy at step %d, got %v", step, err)
-			}
-			tradeCount++
-			
-		case 1:
-			// 売り注文
-			err = backtester.Sell("SAMPLE", 5000.0)
-			if err != nil {
-				t.Fatalf("Expected no error from Sell at step %d, got %v", step, err)
-			}
-			tradeCount++
-			
-		case 2:
-			// ポジション一部決済
-			positions := backtester.GetPositions()
-			if len(positions) > 0 {
-				err = backtester.ClosePosition(positions[0].ID)
-				if err != nil {
-					t.Fatalf("Expected no error from ClosePosition at step %d, got %v", step, err)
-				}
-			}
+		err = backtester.Buy("SAMPLE", 10000.0)
+		if err != nil {
+			t.Fatalf("Expected no error from Buy, got %v", err)
 		}
+		backtester.Forward()
+		backtester.Forward()
 		
-		// 時間進行
-		if step < maxSteps-1 {
-			hasNext := backtester.Forward()
-			if !hasNext {
-				break // データ終了
-			}
+		// 取引が発生しなくても含み損益が有効証拠金に反映される
+		stats := backtester.statistics
+		assert.InDelta(t, backtester.GetUnrealizedPnL(), stats.UnrealizedPnL, 1e-9)
+		assert.InDelta(t, backtester.broker.GetEquity(), stats.CurrentEquity, 1e-9)
+		// 残高から差し引かれた証拠金も有効証拠金に含まれる
+		assert.InDelta(t, backtester.GetBalance()+backtester.broker.GetUsedMargin()+backtester.GetUnrealizedPnL(), stats.CurrentEquity, 1e-9)
+		assert.InDelta(t, backtester.GetStatus().Equity, stats.CurrentEquity, 1e-9)
+	})
+	
+	t.Run("should not count used margin as drawdown when opening positions", func(t *testing.T) {
+		// スプレッド0の買いと売りで証拠金だけが残高から差し引かれる
+		backtester, err := NewBacktesterWithProvider(Config{Broker: BrokerConfig{InitialBalance: 10000.0}}, data.NewInMemoryProvider(candlesFromCloses([]float64{1.10, 1.10, 1.10})))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
 		}
-	}
+		backtester.visualizer = NewMockVisualizer()
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		assert.NoError(t, backtester.Buy("SAMPLE", 10000.0))
+		assert.NoError(t, backtester.Sell("SAMPLE", 5000.0))
+		assert.Greater(t, backtester.broker.GetUsedMargin(), 0.0)
+		
+		stats := backtester.statistics
+		assert.InDelta(t, 10000.0, stats.CurrentEquity, 1e-9)
+		assert.Equal(t, 0.0, stats.MaxDrawdown)
+		assert.Equal(t, 0.0, stats.MaxDrawdownPct)
+	})
 	
-	// 最終状態確認
-	finalPositions := backtester.GetPositions()
-	finalBalance := backtester.GetBalance()
+	t.Run("should count every close in statistics", func(t *testing.T) {
+		closes := []float64{1.10, 1.11, 1.10, 1.12, 1.11, 1.13, 1.12, 1.14, 1.13, 1.15, 1.14, 1.10, 1.10}
+		backtester, err := NewBacktesterWithProvider(Config{Broker: BrokerConfig{InitialBalance: 10000.0}}, data.NewInMemoryProvider(candlesFromCloses(closes)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		mockVisualizer := NewMockVisualizer()
+		backtester.visualizer = mockVisualizer
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		// CloseAllPositions による5往復（全て勝ち）
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, backtester.Buy("EURUSD", 1000.0))
+			backtester.Forward()
+			assert.NoError(t, backtester.CloseAllPositions())
+			backtester.Forward()
+		}
+		// ストップロスによる決済（負け）
+		assert.NoError(t, backtester.BuyWithProtection("EURUSD", 1000.0, 1.12, 0))
+		backtester.Forward()
+		
+		history := backtester.GetTradeHistory()
+		assert.Len(t, history, 6)
+		stats := backtester.statistics
+		assert.Equal(t, len(history), stats.TotalTrades)
+		assert.Equal(t, 5, stats.WinningTrades)
+		assert.Equal(t, 1, stats.LosingTrades)
+		// 建玉と決済のそれぞれで trade_event を通知する
+		assert.Equal(t, 2*len(history), mockVisualizer.GetTradeEventCount())
+	})
 	
-	// ログ出力（デバッグ用）
-	t.Logf("Final positions: %d", len(finalPositions))
-	t.Logf("Final balance: %f", finalBalance)
-	t.Logf("Trade count: %d", tradeCount)
+	t.Run("should send statistics every step when interval is zero", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		mockVisualizer := NewMockVisualizer()
+		backtester.visualizer = mockVisualizer
+		backtester.config.Visualizer.StatisticsInterval = 0
+		err := backtester.Initialize(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		before := mockVisualizer.GetStatisticsUpdateCount()
+		for i := 0; i < 5; i++ {
+			backtester.Forward()
+		}
+		assert.Equal(t, before+5, mockVisualizer.GetStatisticsUpdateCount())
+	})
 	
-	// 基本的な整合性確認
-	if finalBalance < 0 {
-		t.Error("Expected non-negative final balance")
-	}
+	t.Run("should throttle statistics by interval", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		mockVisualizer := NewMockVisualizer()
+		backtester.visualizer = mockVisualizer
+		backtester.config.Visualizer.StatisticsInterval = time.Hour
+		err := backtester.Initialize(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		before := mockVisualizer.GetStatisticsUpdateCount()
+		for i := 0; i < 5; i++ {
+			backtester.Forward()
+		}
+		assert.Equal(t, before+1, mockVisualizer.GetStatisticsUpdateCount())
+		
+		// 決済時は間引かずに送信される
+		err = backtester.Buy("SAMPLE", 10000.0)
+		if err != nil {
+			t.Fatalf("Expected no error from Buy, got %v", err)
+		}
+		backtester.Forward()
+		err = backtester.ClosePosition(backtester.GetPositions()[0].ID)
+		if err != nil {
+			t.Fatalf("Expected no error from ClosePosition, got %v", err)
+		}
+		assert.Equal(t, before+2, mockVisualizer.GetStatisticsUpdateCount())
+	})
 }
 
-// Backtester エラーハンドリングテスト
-func TestBacktester_ErrorHandling(t *testing.T) {
-	backtester := createTestBacktester(t)
+// Backtester 初期時刻設定テスト
+func TestBacktester_SetInitialTime(t *testing.T) {
+	anchor := time.Date(2030, 6, 3, 0, 0, 0, 0, time.UTC)
 	
+	t.Run("should fail before Initialize", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		if err := backtester.SetInitialTime(anchor); err == nil {
+			t.Error("Expected error before Initialize")
+		}
+	})
+	
+	t.Run("should anchor simulated clock and trade timestamps", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		
+		if err := backtester.Initialize(ctx); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		if err := backtester.SetInitialTime(time.Time{}); err == nil {
+			t.Error("Expected error for zero initial time")
+		}
+		
+		if err := backtester.SetInitialTime(anchor); err != nil {
+			t.Fatalf("Expected no error from SetInitialTime, got %v", err)
+		}
+		assert.True(t, anchor.Equal(backtester.GetCurrentTime()))
+		
+		err := backtester.Buy("SAMPLE", 10000.0)
+		if err != nil {
+			t.Fatalf("Expected no error from Buy, got %v", err)
+		}
+		
+		// 取引開始後は変更できない
+		if err := backtester.SetInitialTime(anchor.Add(time.Hour)); err == nil {
+			t.Error("Expected error after trading has started")
+		}
+		
+		// 1分足で1ステップ進めると時刻も1分進む
+		backtester.Forward()
+		assert.True(t, anchor.Add(time.Minute).Equal(backtester.GetCurrentTime()))
+		
+		if err := backtester.CloseAllPositions(); err != nil {
+			t.Fatalf("Expected no error from CloseAllPositions, got %v", err)
+		}
+		
+		trades := backtester.GetTradeHistory()
+		if len(trades) != 1 {
+			t.Fatalf("Expected 1 trade, got %d", len(trades))
+		}
+		assert.True(t, anchor.Equal(trades[0].OpenTime))
+		assert.True(t, anchor.Add(time.Minute).Equal(trades[0].CloseTime))
+	})
+}
+
+// BacktestController ステップ実行テスト
+func TestBacktestController_Step(t *testing.T) {
+	backtester := createTestBacktester(t)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	
-	err := backtester.Initialize(ctx)
-	if err != nil {
+	if err := backtester.Initialize(ctx); err != nil {
 		t.Fatalf("Expected no error from Initialize, got %v", err)
 	}
 	
-	// 無効なサイズでの注文
-	err = backtester.Buy("SAMPLE", 0.0)
-	if err == nil {
-		t.Error("Expected error for zero size order")
+	// コントロールモードを有効にする（一時停止状態で開始）
+	controller := NewBacktestController(backtester)
+	defer controller.Stop()
+	backtester.backtestController = controller
+	
+	startTime := backtester.GetCurrentTime()
+	
+	if err := controller.Step(2); err != nil {
+		t.Fatalf("Expected no error from Step, got %v", err)
 	}
+	assert.True(t, backtester.Forward())
+	assert.True(t, backtester.Forward())
+	assert.True(t, startTime.Add(2*time.Minute).Equal(backtester.GetCurrentTime()))
 	
-	err = backtester.Buy("SAMPLE", -1000.0)
-	if err == nil {
-		t.Error("Expected error for negative size order")
+	// ステップを使い切ると再び一時停止する
+	done := make(chan bool, 1)
+	go func() {
+		done <- backtester.Forward()
+	}()
+	
+	select {
+	case <-done:
+		t.Fatal("Expected Forward to block after steps are consumed")
+	case <-time.After(300 * time.Millisecond):
 	}
 	
-	// 残高不足での大きな注文
-	err = backtester.Buy("SAMPLE", 10000000.0) // 非常に大きな注文
-	if err == nil {
-		t.Error("Expected error for insufficient balance")
+	if err := controller.Step(0); err != nil {
+		t.Fatalf("Expected no error from Step, got %v", err)
 	}
+	select {
+	case hasNext := <-done:
+		assert.True(t, hasNext)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Forward to proceed after Step")
+	}
+	assert.True(t, startTime.Add(3*time.Minute).Equal(backtester.GetCurrentTime()))
+	assert.False(t, controller.GetState().IsPlaying)
 	
-	// 存在しないポジションのクローズ
-	err = backtester.ClosePosition("nonexistent-id")
-	if err == nil {
-		t.Error("Expected error for nonexistent position")
+	// 再生中はステップ実行できない
+	controller.Play(1.0)
+	assert.Error(t, controller.Step(1))
+}
+
+// BacktestController 再生・一時停止テスト
+func TestBacktestController_PlayPause(t *testing.T) {
+	backtester := createTestBacktester(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	if err := backtester.Initialize(ctx); err != nil {
+		t.Fatalf("Expected no error from Initialize, got %v", err)
 	}
 	
-	// 初期化前の操作エラー（新しいbacktesterで）
-	uninitializedBacktester := createTestBacktester(t)
+	controller := NewBacktestController(backtester)
+	defer controller.Stop()
+	backtester.backtestController = controller
 	
-	err = uninitializedBacktester.Buy("SAMPLE", 1000.0)
-	if err == nil {
-		t.Error("Expected error for uninitialized backtester")
+	forward := func() chan bool {
+		done := make(chan bool, 1)
+		go func() {
+			done <- backtester.Forward()
+		}()
+		return done
 	}
-}
-
-// ヘルパー関数: テスト用Backtester作成
-func createTestBacktester(_ *testing.T) *Backtester {
-	dataConfig := models.DataProviderConfig{
-		FilePath: "./testdata/sample.csv",
-		Format:   "csv",
+	
+	// 一時停止中は待機し、Play で即座に再開する（ポーリング間隔を待たない）
+	done := forward()
+	select {
+	case <-done:
+		t.Fatal("Expected Forward to block while paused")
+	case <-time.After(200 * time.Millisecond):
 	}
 	
-	brokerConfig := models.BrokerConfig{
-		InitialBalance: 10000.0,
-		Spread:         0.0001,
+	resumed := time.Now()
+	controller.Play(1000.0)
+	select {
+	case hasNext := <-done:
+		assert.True(t, hasNext)
+		assert.Less(t, time.Since(resumed), 50*time.Millisecond)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Forward to proceed after Play")
 	}
 	
-	config := Config{
-		Market: MarketConfig{
-			DataProvider: dataConfig,
-		},
-		Broker: BrokerConfig{
-			InitialBalance: brokerConfig.InitialBalance,
-			Spread:         brokerConfig.Spread,
-		},
+	// 再び一時停止すると次の Forward は待機し、キャンセルで false を返す
+	controller.Pause()
+	done = forward()
+	select {
+	case <-done:
+		t.Fatal("Expected Forward to block after Pause")
+	case <-time.After(100 * time.Millisecond):
 	}
-	backtester, err := NewBacktester(config)
-	if err != nil {
-		panic(err) // テスト用なのでpanicで良い
+	
+	cancel()
+	select {
+	case hasNext := <-done:
+		assert.False(t, hasNext)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Forward to return after cancel")
 	}
-	return backtester
-}
\ No newline at end of file
+}
+
+// Reset テスト
+func TestBacktester_Reset(t *testing.T) {
+	t.Run("should fail before Initialize", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		assert.Error(t, backtester.Reset())
+	})
+	
+	t.Run("should rewind market, broker and statistics", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		
+		if err := backtester.Initialize(ctx); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		startTime := backtester.GetCurrentTime()
+		startPrice := backtester.GetCurrentPrice()
+		
+		if err := backtester.Buy("SAMPLE", 10000.0); err != nil {
+			t.Fatalf("Expected no error from Buy, got %v", err)
+		}
+		for i := 0; i < 5; i++ {
+			backtester.Forward()
+		}
+		if err := backtester.CloseAllPositions(); err != nil {
+			t.Fatalf("Expected no error from CloseAllPositions, got %v", err)
+		}
+		if err := backtester.Buy("SAMPLE", 5000.0); err != nil {
+			t.Fatalf("Expected no error from Buy, got %v", err)
+		}
+		assert.Len(t, backtester.GetTradeHistory(), 1)
+		
+		if err := backtester.Reset(); err != nil {
+			t.Fatalf("Expected no error from Reset, got %v", err)
+		}
+		
+		assert.True(t, startTime.Equal(backtester.GetCurrentTime()))
+		assert.Equal(t, startPrice, backtester.GetCurrentPrice())
+		assert.Equal(t, 10000.0, backtester.GetBalance())
+		assert.Empty(t, backtester.GetPositions())
+		assert.Empty(t, backtester.GetTradeHistory())
+		assert.Equal(t, 0, backtester.statistics.TotalTrades)
+		assert.Equal(t, 10000.0, backtester.statistics.CurrentBalance)
+		
+		// リセット後も通常通り進められる
+		assert.True(t, backtester.Forward())
+		assert.True(t, startTime.Add(time.Minute).Equal(backtester.GetCurrentTime()))
+	})
+	
+	t.Run("should pause controller and return to idle", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		
+		if err := backtester.Initialize(ctx); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		controller := NewBacktestController(backtester)
+		defer controller.Stop()
+		backtester.backtestController = controller
+		
+		if err := controller.Step(3); err != nil {
+			t.Fatalf("Expected no error from Step, got %v", err)
+		}
+		controller.Play(2.0)
+		
+		if err := controller.Reset(); err != nil {
+			t.Fatalf("Expected no error from Reset, got %v", err)
+		}
+		
+		state := controller.GetState()
+		assert.False(t, state.IsPlaying)
+		assert.Equal(t, models.BacktestStateIdle, state.State)
+		assert.Equal(t, 2.0, state.Speed)
+		
+		// 保留中のステップも破棄されている
+		assert.False(t, controller.acquireStep())
+	})
+}
+
+// strategyFunc は関数をStrategyとして扱うテスト用アダプター
+type strategyFunc func(bt *Backtester) error
+
+func (f strategyFunc) OnBar(bt *Backtester) error {
+	return f(bt)
+}
+
+// Run テスト
+func TestBacktester_Run(t *testing.T) {
+	noop := strategyFunc(func(bt *Backtester) error { return nil })
+	
+	t.Run("should fail before Initialize", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		_, err := backtester.Run(noop)
+		assert.Error(t, err)
+	})
+	
+	t.Run("should call strategy on every bar and close remaining positions", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		startTime := backtester.GetCurrentTime()
+		
+		bars := 0
+		result, err := backtester.Run(strategyFunc(func(bt *Backtester) error {
+			bars++
+			if bars == 1 {
+				return bt.Buy("SAMPLE", 10000.0)
+			}
+			return nil
+		}))
+		if err != nil {
+			t.Fatalf("Expected no error from Run, got %v", err)
+		}
+		
+		// sample.csv の全532本で呼ばれる
+		assert.Equal(t, 532, bars)
+		assert.True(t, backtester.IsFinished())
+		assert.Empty(t, backtester.GetPositions())
+		assert.Equal(t, 1, result.TotalTrades)
+		assert.Equal(t, backtester.GetBalance(), result.FinalBalance)
+		assert.True(t, startTime.Equal(result.StartTime))
+		assert.Equal(t, 531*time.Minute, result.Duration)
+	})
+	
+	t.Run("should report progress with callback", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		progress := make([]Progress, 0)
+		_, err := backtester.RunWithCallback(noop, func(p Progress) {
+			progress = append(progress, p)
+		})
+		if err != nil {
+			t.Fatalf("Expected no error from RunWithCallback, got %v", err)
+		}
+		
+		assert.Len(t, progress, 532)
+		assert.Equal(t, Progress{Bar: 1, Total: 532, Time: progress[0].Time}, progress[0])
+		assert.Equal(t, 532, progress[531].Bar)
+		assert.InDelta(t, 100.0, progress[531].Percent(), 1e-9)
+		assert.Equal(t, 0.0, Progress{Bar: 10}.Percent())
+	})
+	
+	t.Run("should stop on cancel and return partial result", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		bars := 0
+		result, err := backtester.Run(strategyFunc(func(bt *Backtester) error {
+			bars++
+			if bars == 1 {
+				return bt.Buy("SAMPLE", 10000.0)
+			}
+			if bars == 10 {
+				bt.Cancel()
+			}
+			return nil
+		}))
+		if err != nil {
+			t.Fatalf("Expected no error from Run, got %v", err)
+		}
+		
+		assert.Equal(t, 10, bars)
+		assert.True(t, result.Cancelled)
+		assert.True(t, backtester.IsCancelled())
+		assert.True(t, backtester.IsFinished())
+		assert.False(t, backtester.Forward())
+		assert.Empty(t, backtester.GetPositions())
+		assert.Equal(t, 1, result.TotalTrades)
+		assert.Equal(t, 9*time.Minute, result.Duration)
+	})
+	
+	t.Run("should stop when context deadline passes", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := backtester.Initialize(ctx); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		bars := 0
+		result, err := backtester.Run(strategyFunc(func(bt *Backtester) error {
+			bars++
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		}))
+		if err != nil {
+			t.Fatalf("Expected no error from Run, got %v", err)
+		}
+		
+		assert.True(t, result.Cancelled)
+		assert.Less(t, bars, 532)
+	})
+	
+	t.Run("should stop on strategy error", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		_, err := backtester.Run(strategyFunc(func(bt *Backtester) error {
+			return errors.New("boom")
+		}))
+		assert.ErrorContains(t, err, "boom")
+		assert.False(t, backtester.IsFinished())
+	})
+}
+
+func TestBacktester_GetState(t *testing.T) {
+	ctx := context.Background()
+	noop := strategyFunc(func(bt *Backtester) error { return nil })
+	
+	t.Run("should derive state without controller", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		assert.Equal(t, BacktestStateIdle, backtester.GetState())
+		
+		assert.NoError(t, backtester.Initialize(ctx))
+		assert.Equal(t, BacktestStateRunning, backtester.GetState())
+		
+		_, err := backtester.Run(noop)
+		assert.NoError(t, err)
+		assert.Equal(t, BacktestStateCompleted, backtester.GetState())
+		
+		assert.NoError(t, backtester.Reset())
+		assert.Equal(t, BacktestStateRunning, backtester.GetState())
+		
+		assert.NoError(t, backtester.Stop())
+		assert.Equal(t, BacktestStateStopped, backtester.GetState())
+	})
+	
+	t.Run("should report error until reset", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		assert.NoError(t, backtester.Initialize(ctx))
+		
+		_, err := backtester.Run(strategyFunc(func(bt *Backtester) error {
+			return errors.New("boom")
+		}))
+		assert.Error(t, err)
+		assert.Equal(t, BacktestStateError, backtester.GetState())
+		
+		assert.NoError(t, backtester.Reset())
+		assert.Equal(t, BacktestStateRunning, backtester.GetState())
+	})
+	
+	t.Run("should report stopped after cancel", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		assert.NoError(t, backtester.Initialize(ctx))
+		backtester.Cancel()
+		assert.Equal(t, BacktestStateStopped, backtester.GetState())
+	})
+	
+	t.Run("should reflect controller state", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		assert.NoError(t, backtester.Initialize(ctx))
+		controller := NewBacktestController(backtester)
+		defer controller.Stop()
+		backtester.backtestController = controller
+		
+		assert.Equal(t, BacktestStateIdle, backtester.GetState())
+		controller.Play(1.0)
+		assert.Equal(t, BacktestStateRunning, backtester.GetState())
+		controller.Pause()
+		assert.Equal(t, BacktestStatePaused, backtester.GetState())
+	})
+}
+
+func TestBacktester_GetStatus(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	candles := []models.Candle{
+		*models.NewCandle(baseTime, 1.10, 1.10, 1.10, 1.10, 1000),
+		*models.NewCandle(baseTime.Add(time.Minute), 1.11, 1.11, 1.11, 1.11, 1000),
+		*models.NewCandle(baseTime.Add(2*time.Minute), 1.12, 1.12, 1.12, 1.12, 1000),
+		*models.NewCandle(baseTime.Add(3*time.Minute), 1.13, 1.13, 1.13, 1.13, 1000),
+	}
+	backtester, err := NewBacktesterWithProvider(Config{Broker: BrokerConfig{InitialBalance: 10000.0}}, data.NewInMemoryProvider(candles))
+	assert.NoError(t, err)
+	
+	status := backtester.GetStatus()
+	assert.Equal(t, "Idle", status.State)
+	assert.True(t, status.UpdatedAt.IsZero())
+	
+	assert.NoError(t, backtester.Initialize(context.Background()))
+	status = backtester.GetStatus()
+	assert.Equal(t, "Running", status.State)
+	assert.Equal(t, 1, status.Bar)
+	assert.Equal(t, 4, status.TotalBars)
+	assert.Equal(t, 25.0, status.PercentComplete)
+	assert.Equal(t, 10000.0, status.Balance)
+	assert.False(t, status.UpdatedAt.IsZero())
+	
+	// 約定はスナップショットに次の時間進行で反映される
+	assert.NoError(t, backtester.Buy("EURUSD", 1000.0))
+	assert.Equal(t, 0, backtester.GetStatus().OpenPositions)
+	assert.True(t, backtester.Forward())
+	status = backtester.GetStatus()
+	assert.True(t, baseTime.Add(time.Minute).Equal(status.CurrentTime))
+	assert.Equal(t, 50.0, status.PercentComplete)
+	assert.Equal(t, 1, status.OpenPositions)
+	assert.InDelta(t, 10000.0-11.0, status.Balance, 1e-9)
+	assert.InDelta(t, 10010.0, status.Equity, 1e-9)
+	
+	_, err = backtester.Run(strategyFunc(func(bt *Backtester) error { return nil }))
+	assert.NoError(t, err)
+	status = backtester.GetStatus()
+	assert.Equal(t, "Completed", status.State)
+	assert.Equal(t, 100.0, status.PercentComplete)
+	assert.Equal(t, 0, status.OpenPositions)
+	assert.InDelta(t, 10030.0, status.Balance, 1e-9)
+	
+	assert.NoError(t, backtester.Reset())
+	status = backtester.GetStatus()
+	assert.Equal(t, 1, status.Bar)
+	assert.Equal(t, 10000.0, status.Equity)
+}
+
+func TestBacktester_AttachVisualizer(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	candles := []models.Candle{
+		*models.NewCandle(baseTime, 1.10, 1.10, 1.10, 1.10, 1000),
+		*models.NewCandle(baseTime.Add(time.Minute), 1.11, 1.11, 1.11, 1.11, 1000),
+	}
+	newBacktester := func(t *testing.T, config Config) *Backtester {
+		config.Broker.InitialBalance = 10000.0
+		backtester, err := NewBacktesterWithProvider(config, data.NewInMemoryProvider(candles))
+		assert.NoError(t, err)
+		t.Cleanup(func() { backtester.Close() })
+		return backtester
+	}
+	
+	shared := visualizer.NewVisualizer(visualizer.DefaultConfig())
+	assert.Error(t, newBacktester(t, Config{}).AttachVisualizer(shared, "a"), "visualizer must be running")
+	assert.NoError(t, shared.Start(context.Background(), 8111))
+	defer shared.Stop()
+	
+	dedicatedConfig := models.DefaultVisualizerConfig()
+	dedicatedConfig.Enabled = true
+	dedicated := newBacktester(t, Config{Visualizer: dedicatedConfig})
+	assert.Error(t, dedicated.AttachVisualizer(shared, "a"), "cannot combine with a dedicated visualizer")
+	
+	// 接続中もダッシュボードは /sessions をポーリングしている（両方のセッションの進捗が見えるまで続ける）
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			resp, err := http.Get("http://localhost:8111/sessions")
+			if err != nil {
+				continue
+			}
+			var sessions []visualizer.SessionInfo
+			json.NewDecoder(resp.Body).Decode(&sessions)
+			resp.Body.Close()
+			if len(sessions) == 2 && sessions[0].Status != nil && sessions[1].Status != nil {
+				return
+			}
+		}
+	}()
+	
+	backtesterA, backtesterB := newBacktester(t, Config{}), newBacktester(t, Config{})
+	assert.NoError(t, backtesterA.AttachVisualizer(shared, "a"))
+	assert.NoError(t, backtesterB.AttachVisualizer(shared, "b"))
+	assert.Error(t, backtesterA.AttachVisualizer(shared, "c"), "already attached")
+	assert.Equal(t, []string{"a", "b"}, shared.SessionIDs())
+	<-polled
+	
+	assert.NoError(t, backtesterA.Initialize(context.Background()))
+	assert.NoError(t, backtesterB.Initialize(context.Background()))
+	assert.True(t, shared.IsRunning(), "attached backtesters do not start their own server")
+	
+	// 制御コマンドはセッションのバックテストにだけ届く
+	assert.NoError(t, shared.OnControlCommand(&visualizer.ControlCommand{Type: "play", SessionID: "a", Data: map[string]interface{}{}}))
+	assert.True(t, backtesterA.backtestController.GetState().IsPlaying)
+	assert.False(t, backtesterB.backtestController.GetState().IsPlaying)
+	
+	// Stop はセッションの登録だけを解除する
+	assert.NoError(t, backtesterA.Stop())
+	assert.Equal(t, []string{"b"}, shared.SessionIDs())
+	assert.True(t, shared.IsRunning())
+}
+
+// failingProvider は指定したインデックス以降の読み込みに失敗する DataProvider
+type failingProvider struct {
+	*data.InMemoryProvider
+	failFrom int
+}
+
+func (p *failingProvider) GetCandlesByIndex(ctx context.Context, startIndex, endIndex int) ([]models.Candle, error) {
+	if endIndex >= p.failFrom {
+		return nil, errors.New("disk error")
+	}
+	return p.InMemoryProvider.GetCandlesByIndex(ctx, startIndex, endIndex)
+}
+
+func TestBacktester_Err(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 0, 30)
+	for i := 0; i < 30; i++ {
+		candles = append(candles, *models.NewCandle(baseTime.Add(time.Duration(i)*time.Minute), 1.1, 1.1, 1.1, 1.1, 1000))
+	}
+	config := Config{
+		Market: MarketConfig{CacheSize: 10, RefillThreshold: 3},
+		Broker: BrokerConfig{InitialBalance: 10000.0},
+	}
+	noop := strategyFunc(func(bt *Backtester) error { return nil })
+	
+	t.Run("should fail run on data read error", func(t *testing.T) {
+		backtester, err := NewBacktesterWithProvider(config, &failingProvider{InMemoryProvider: data.NewInMemoryProvider(candles), failFrom: 10})
+		assert.NoError(t, err)
+		mockVisualizer := NewMockVisualizer()
+		backtester.visualizer = mockVisualizer
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		
+		result, err := backtester.Run(noop)
+		assert.Nil(t, result)
+		assert.ErrorContains(t, err, "disk error")
+		assert.ErrorContains(t, backtester.Err(), "disk error")
+		assert.Equal(t, BacktestStateError, backtester.GetState())
+		assert.Equal(t, VisualizerBacktestState(BacktestStateError), mockVisualizer.GetLastState())
+		
+		// Reset でエラーは解除される
+		assert.NoError(t, backtester.Reset())
+		assert.NoError(t, backtester.Err())
+		assert.Equal(t, BacktestStateRunning, backtester.GetState())
+	})
+	
+	t.Run("should record strategy error", func(t *testing.T) {
+		backtester, err := NewBacktesterWithProvider(config, data.NewInMemoryProvider(candles))
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		
+		_, err = backtester.Run(strategyFunc(func(bt *Backtester) error {
+			return errors.New("boom")
+		}))
+		assert.ErrorContains(t, err, "boom")
+		assert.Equal(t, err, backtester.Err())
+		assert.Equal(t, BacktestStateError, backtester.GetState())
+	})
+	
+	t.Run("should be nil after clean completion", func(t *testing.T) {
+		backtester, err := NewBacktesterWithProvider(config, data.NewInMemoryProvider(candles))
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		
+		_, err = backtester.Run(noop)
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Err())
+		assert.Equal(t, BacktestStateCompleted, backtester.GetState())
+	})
+}
+
+func TestBacktester_CurrentValues(t *testing.T) {
+	t.Run("should return ErrNotInitialized before Initialize", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		var buf bytes.Buffer
+		backtester.SetLogger(models.NewLogger(&buf, "warn"))
+		
+		_, err := backtester.CurrentPrice()
+		assert.ErrorIs(t, err, ErrNotInitialized)
+		_, err = backtester.CurrentTime()
+		assert.ErrorIs(t, err, ErrNotInitialized)
+		_, err = backtester.Balance()
+		assert.ErrorIs(t, err, ErrNotInitialized)
+		assert.ErrorIs(t, backtester.Buy("SAMPLE", 1000.0), ErrNotInitialized)
+		
+		// ゼロ値を返す取得は最初の1回だけ警告する
+		assert.Equal(t, 0.0, backtester.GetCurrentPrice())
+		assert.Equal(t, 0.0, backtester.GetBalance())
+		assert.True(t, backtester.GetCurrentTime().IsZero())
+		assert.Equal(t, 1, strings.Count(buf.String(), "backtester not initialized"))
+		assert.Contains(t, buf.String(), "GetCurrentPrice")
+	})
+	
+	t.Run("should match getters after Initialize", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		backtester.visualizer = NewMockVisualizer()
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		backtester.Forward()
+		
+		price, err := backtester.CurrentPrice()
+		assert.NoError(t, err)
+		assert.Equal(t, backtester.GetCurrentPrice(), price)
+		current, err := backtester.CurrentTime()
+		assert.NoError(t, err)
+		assert.Equal(t, backtester.GetCurrentTime(), current)
+		balance, err := backtester.Balance()
+		assert.NoError(t, err)
+		assert.Equal(t, 10000.0, balance)
+	})
+	
+	t.Run("should return ErrNoCurrentCandle without trading candles", func(t *testing.T) {
+		// 土曜日のローソク足だけのデータは週末スキップのカレンダーで全て除かれる
+		saturday := time.Date(2024, 1, 6, 9, 0, 0, 0, time.UTC)
+		candles := []models.Candle{*models.NewCandle(saturday, 1.1, 1.1, 1.1, 1.1, 1000)}
+		config := Config{
+			Market: MarketConfig{Calendar: &models.TradingCalendar{SkipWeekends: true}},
+			Broker: BrokerConfig{InitialBalance: 10000.0},
+		}
+		backtester, err := NewBacktesterWithProvider(config, data.NewInMemoryProvider(candles))
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		
+		_, err = backtester.CurrentPrice()
+		assert.ErrorIs(t, err, ErrNoCurrentCandle)
+		_, err = backtester.CurrentTime()
+		assert.ErrorIs(t, err, ErrNoCurrentCandle)
+		_, err = backtester.Balance()
+		assert.NoError(t, err)
+	})
+}
+
+func TestBacktester_GetTrades(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	candles := candlesFromCloses([]float64{1.10, 1.11, 1.12, 1.11, 1.10, 1.09})
+	backtester, err := NewBacktesterWithProvider(Config{Broker: BrokerConfig{InitialBalance: 10000.0}}, data.NewInMemoryProvider(candles))
+	assert.NoError(t, err)
+	
+	_, found := backtester.GetTradeByID("pos-buy-EURUSD-1")
+	assert.False(t, found, "not initialized")
+	assert.NoError(t, backtester.Initialize(context.Background()))
+	
+	// 09:00 買い → 09:01 決済（勝ち）、09:01 売り → 09:02 決済（負け）、09:03 買い → 09:05 決済（負け）
+	sides := map[int]func(string, float64) error{0: backtester.Buy, 1: backtester.Sell, 3: backtester.Buy}
+	_, err = backtester.Run(strategyFunc(func(bt *Backtester) error {
+		minute := int(bt.GetCurrentTime().Sub(baseTime) / time.Minute)
+		if minute == 1 || minute == 2 || minute == 5 {
+			if err := bt.CloseAllPositions(); err != nil {
+				return err
+			}
+		}
+		if open, ok := sides[minute]; ok {
+			return open("EURUSD", 1000.0)
+		}
+		return nil
+	}))
+	assert.NoError(t, err)
+	history := backtester.GetTradeHistory()
+	assert.Len(t, history, 3)
+	
+	t.Run("should return all trades for empty filter", func(t *testing.T) {
+		assert.Equal(t, history, backtester.GetTrades(models.TradeFilter{}))
+	})
+	
+	t.Run("should filter by side and result", func(t *testing.T) {
+		buy := models.Buy
+		buys := backtester.GetTrades(models.TradeFilter{Side: &buy})
+		assert.Equal(t, []*models.Trade{history[0], history[2]}, buys)
+		assert.Equal(t, []*models.Trade{history[0]}, backtester.GetTrades(models.TradeFilter{Result: models.TradeResultWin}))
+		assert.Equal(t, []*models.Trade{history[1], history[2]}, backtester.GetTrades(models.TradeFilter{Result: models.TradeResultLoss}))
+		assert.Empty(t, backtester.GetTrades(models.TradeFilter{Symbol: "USDJPY"}))
+	})
+	
+	t.Run("should filter by close time range", func(t *testing.T) {
+		from := baseTime.Add(2 * time.Minute)
+		assert.Equal(t, history[1:], backtester.GetTrades(models.TradeFilter{From: from}))
+		assert.Equal(t, history[:1], backtester.GetTrades(models.TradeFilter{To: from}))
+		assert.Equal(t, []*models.Trade{history[1]}, backtester.GetTrades(models.TradeFilter{From: from, To: from.Add(time.Minute)}))
+		assert.Empty(t, backtester.GetTrades(models.TradeFilter{From: baseTime.Add(time.Hour)}))
+	})
+	
+	t.Run("should find trade by id", func(t *testing.T) {
+		trade, found := backtester.GetTradeByID(history[1].ID)
+		assert.True(t, found)
+		assert.Same(t, history[1], trade)
+		_, found = backtester.GetTradeByID("unknown")
+		assert.False(t, found)
+	})
+}
+
+func TestBacktester_OnOrderFilled(t *testing.T) {
+	candles := candlesFromCloses([]float64{1.1000, 1.0950, 1.0900, 1.0880, 1.0850})
+	config := Config{Broker: BrokerConfig{InitialBalance: 10000.0}}
+	backtester, err := NewBacktesterWithProvider(config, data.NewInMemoryProvider(candles))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mockVisualizer := NewMockVisualizer()
+	backtester.visualizer = mockVisualizer
+	if err := backtester.Initialize(context.Background()); err != nil {
+		t.Fatalf("Expected no error from Initialize, got %v", err)
+	}
+	
+	// 約定を受けてすぐに反対売買の注文を出せる
+	var filled []*models.Order
+	var positions []*models.Position
+	backtester.OnOrderFilled(func(order *models.Order, position *models.Position) {
+		filled = append(filled, order)
+		positions = append(positions, position)
+		assert.Empty(t, backtester.broker.GetPendingOrders())
+		assert.NoError(t, backtester.Sell("TEST", order.Size))
+	})
+	
+	entry := models.NewLimitOrder("entry", "TEST", models.Buy, 1000.0, 1.0910)
+	assert.NoError(t, backtester.broker.PlaceOrder(entry))
+	
+	// 1.0950 では約定しない
+	backtester.Forward()
+	assert.Empty(t, filled)
+	
+	// 1.0900 で約定し、登録した関数と Visualizer に通知される
+	backtester.Forward()
+	if assert.Len(t, filled, 1) {
+		assert.Same(t, entry, filled[0])
+		assert.True(t, filled[0].IsExecuted())
+		assert.Equal(t, "pos-entry", positions[0].ID)
+	}
+	if assert.Len(t, mockVisualizer.orderFills, 1) {
+		assert.Equal(t, "entry", mockVisualizer.orderFills[0].Order.ID)
+		assert.Equal(t, "pos-entry", mockVisualizer.orderFills[0].Position.ID)
+	}
+	assert.Len(t, backtester.GetPositions(), 2)
+	
+	// 成行注文の約定では呼び出されない
+	backtester.Forward()
+	assert.Len(t, filled, 1)
+	
+	// nil で登録を解除しても Visualizer への通知は続く
+	backtester.OnOrderFilled(nil)
+	assert.NoError(t, backtester.broker.PlaceOrder(models.NewLimitOrder("second", "TEST", models.Buy, 1000.0, 1.0860)))
+	backtester.Forward()
+	assert.Len(t, filled, 1)
+	assert.Len(t, mockVisualizer.orderFills, 2)
+}
+
+func TestBacktester_Logger(t *testing.T) {
+	t.Run("should route diagnostics to configured logger", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		backtester.visualizer = NewMockVisualizer()
+		
+		var buf bytes.Buffer
+		backtester.SetLogger(models.NewLogger(&buf, "debug"))
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		backtester.Forward()
+		assert.Contains(t, buf.String(), "current candle")
+	})
+	
+	t.Run("should not log candles above debug level", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		backtester.visualizer = NewMockVisualizer()
+		
+		var buf bytes.Buffer
+		backtester.SetLogger(models.NewLogger(&buf, "info"))
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		for i := 0; i < 10; i++ {
+			backtester.Forward()
+		}
+		assert.Empty(t, buf.String())
+	})
+	
+	t.Run("should be silent with nil logger", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		backtester.visualizer = NewMockVisualizer()
+		backtester.SetLogger(nil)
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		assert.NotNil(t, backtester.Logger())
+		assert.False(t, backtester.Logger().Enabled(context.Background(), slog.LevelError))
+		assert.True(t, backtester.Forward())
+	})
+	
+	// 不正な行を含むデータファイルでは、データ提供元が読み飛ばしの警告を出力する
+	createMixedDataBacktester := func(t *testing.T, logLevel string) *Backtester {
+		path := filepath.Join(t.TempDir(), "mixed.csv")
+		content := "2024.01.01,09:00,1.1000,1.1010,1.0990,1.1005,1000\n" +
+			"2024.01.01,09:01,abc,1.1010,1.0990,1.1005,1000\n" +
+			"2024.01.01,09:02,1.1005,1.1015,1.0995,1.1010,1000\n"
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write data file: %v", err)
+		}
+		config := Config{
+			Market:     MarketConfig{DataProvider: models.DataProviderConfig{FilePath: path, Format: "csv"}},
+			Broker:     BrokerConfig{InitialBalance: 10000.0},
+			Visualizer: models.VisualizerConfig{LogLevel: logLevel},
+		}
+		backtester, err := NewBacktester(config)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return backtester
+	}
+	// captureDefaultLogger は slog のデフォルトロガーの出力を捕捉する
+	captureDefaultLogger := func(t *testing.T) *bytes.Buffer {
+		var buf bytes.Buffer
+		previous := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+		t.Cleanup(func() { slog.SetDefault(previous) })
+		return &buf
+	}
+	
+	t.Run("should route data provider diagnostics to configured logger", func(t *testing.T) {
+		defaultOutput := captureDefaultLogger(t)
+		backtester := createMixedDataBacktester(t, "")
+		var buf bytes.Buffer
+		backtester.SetLogger(models.NewLogger(&buf, "warn"))
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		assert.Contains(t, buf.String(), "skipped invalid rows")
+		assert.Empty(t, defaultOutput.String())
+	})
+	
+	t.Run("should silence data provider with nil logger or log level off", func(t *testing.T) {
+		defaultOutput := captureDefaultLogger(t)
+		
+		silenced := createMixedDataBacktester(t, "")
+		silenced.SetLogger(nil)
+		off := createMixedDataBacktester(t, models.LogLevelOff)
+		for _, backtester := range []*Backtester{silenced, off} {
+			if err := backtester.Initialize(context.Background()); err != nil {
+				t.Fatalf("Expected no error from Initialize, got %v", err)
+			}
+			assert.True(t, backtester.Forward())
+		}
+		assert.Empty(t, defaultOutput.String())
+	})
+}
+
+func TestNewBacktesterWithProvider(t *testing.T) {
+	config := Config{
+		Broker: BrokerConfig{
+			InitialBalance: 10000.0,
+			Spread:         0.0,
+		},
+	}
+	
+	t.Run("should reject nil provider", func(t *testing.T) {
+		_, err := NewBacktesterWithProvider(config, nil)
+		assert.Error(t, err)
+	})
+	
+	t.Run("should run on in-memory candles without data file", func(t *testing.T) {
+		candles := candlesFromCloses([]float64{1.1000, 1.1010, 1.1020, 1.1030, 1.1040})
+		
+		backtester, err := NewBacktesterWithProvider(config, data.NewInMemoryProvider(candles))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		bought := false
+		result, err := backtester.Run(strategyFunc(func(bt *Backtester) error {
+			if !bought {
+				bought = true
+				return bt.Buy("TEST", 10000.0)
+			}
+			return nil
+		}))
+		if err != nil {
+			t.Fatalf("Expected no error from Run, got %v", err)
+		}
+		
+		// 1.1000 で買い、最後のバー 1.1040 で決済
+		assert.Equal(t, 1, result.TotalTrades)
+		assert.InDelta(t, 10040.0, result.FinalBalance, 1e-6)
+		assert.Equal(t, 4*time.Minute, result.Duration)
+	})
+	
+	t.Run("should read all candles with a small market cache", func(t *testing.T) {
+		baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		candles := make([]models.Candle, 0, 25)
+		for i := 0; i < 25; i++ {
+			candles = append(candles, *models.NewCandle(baseTime.Add(time.Duration(i)*time.Minute), 1.1, 1.1, 1.1, 1.1, 1000))
+		}
+		
+		smallCache := config
+		smallCache.Market.CacheSize = 4
+		backtester, err := NewBacktesterWithProvider(smallCache, data.NewInMemoryProvider(candles))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		
+		result, err := backtester.Run(strategyFunc(func(bt *Backtester) error { return nil }))
+		if err != nil {
+			t.Fatalf("Expected no error from Run, got %v", err)
+		}
+		assert.Equal(t, 24*time.Minute, result.Duration)
+	})
+	
+	t.Run("should reject invalid market cache settings", func(t *testing.T) {
+		invalid := config
+		invalid.Market.CacheSize = 10
+		invalid.Market.RefillThreshold = 10
+		_, err := NewBacktesterWithProvider(invalid, data.NewInMemoryProvider(nil))
+		assert.ErrorContains(t, err, "refill threshold")
+	})
+	
+	t.Run("should apply broker lot settings", func(t *testing.T) {
+		baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		candles := []models.Candle{*models.NewCandle(baseTime, 1.1, 1.1, 1.1, 1.1, 1000)}
+		
+		invalid := config
+		invalid.Broker.MinLot = 200
+		invalid.Broker.MaxLot = 100
+		_, err := NewBacktesterWithProvider(invalid, data.NewInMemoryProvider(candles))
+		assert.ErrorContains(t, err, "min lot")
+		
+		lots := config
+		lots.Broker.LotStep = 100
+		lots.Broker.LotPolicy = models.LotRound
+		backtester, err := NewBacktesterWithProvider(lots, data.NewInMemoryProvider(candles))
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		assert.NoError(t, backtester.Buy("SAMPLE", 1050))
+		assert.Equal(t, 1000.0, backtester.GetPositions()[0].Size)
+	})
+	
+	t.Run("should apply broker account mode", func(t *testing.T) {
+		baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		candles := []models.Candle{*models.NewCandle(baseTime, 1.1, 1.1, 1.1, 1.1, 1000)}
+		
+		invalid := config
+		invalid.Broker.AccountMode = "mixed"
+		_, err := NewBacktesterWithProvider(invalid, data.NewInMemoryProvider(candles))
+		assert.ErrorContains(t, err, "account mode")
+		
+		netting := config
+		netting.Broker.AccountMode = models.AccountNetting
+		backtester, err := NewBacktesterWithProvider(netting, data.NewInMemoryProvider(candles))
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		assert.NoError(t, backtester.Buy("SAMPLE", 1000))
+		assert.NoError(t, backtester.Sell("SAMPLE", 1000))
+		assert.Empty(t, backtester.GetPositions())
+		assert.Len(t, backtester.GetTradeHistory(), 1)
+	})
+	
+	t.Run("should apply broker leverage", func(t *testing.T) {
+		baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		candles := []models.Candle{*models.NewCandle(baseTime, 2.0, 2.0, 2.0, 2.0, 1000)}
+		
+		invalid := config
+		invalid.Broker.Leverage = -10
+		_, err := NewBacktesterWithProvider(invalid, data.NewInMemoryProvider(candles))
+		assert.ErrorContains(t, err, "leverage")
+		
+		// 10倍では 1000 × 2.0 の建玉に 200 の証拠金が必要
+		leveraged := config
+		leveraged.Broker.Leverage = 10
+		backtester, err := NewBacktesterWithProvider(leveraged, data.NewInMemoryProvider(candles))
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		assert.NoError(t, backtester.Buy("SAMPLE", 1000))
+		assert.InDelta(t, 9800.0, backtester.GetBalance(), 1e-9)
+	})
+	
+	t.Run("should apply symbol metadata to spread, margin and PnL", func(t *testing.T) {
+		baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		candles := []models.Candle{
+			*models.NewCandle(baseTime, 150.0, 150.0, 150.0, 150.0, 1000),
+			*models.NewCandle(baseTime.Add(time.Minute), 151.0, 151.0, 151.0, 151.0, 1000),
+			*models.NewCandle(baseTime.Add(2*time.Minute), 151.0, 151.0, 151.0, 151.0, 1000),
+		}
+		
+		invalid := config
+		invalid.Symbols = map[string]models.SymbolSpec{"USDJPY": {PipSize: 0, ContractSize: 1000}}
+		_, err := NewBacktesterWithProvider(invalid, data.NewInMemoryProvider(candles))
+		assert.ErrorContains(t, err, "pip size")
+		
+		withSymbols := config
+		withSymbols.Broker.SpreadPips = 2
+		withSymbols.Symbols = map[string]models.SymbolSpec{
+			"USDJPY": {PipSize: 0.01, ContractSize: 1000, QuoteCurrency: "JPY"},
+		}
+		backtester, err := NewBacktesterWithProvider(withSymbols, data.NewInMemoryProvider(candles))
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		
+		spec, ok := backtester.GetSymbolSpec("usdjpy")
+		assert.True(t, ok)
+		assert.Equal(t, models.SymbolSpec{PipSize: 0.01, ContractSize: 1000, QuoteCurrency: "JPY"}, spec)
+		_, ok = backtester.GetSymbolSpec("EURUSD")
+		assert.False(t, ok)
+		
+		// 2pip のスプレッドは 0.02 円、1ロットの証拠金は 1000 通貨分
+		balance := backtester.GetBalance()
+		assert.NoError(t, backtester.Buy("USDJPY", 1))
+		position := backtester.GetPositions()[0]
+		assert.InDelta(t, 150.02, position.EntryPrice, 1e-9)
+		assert.InDelta(t, 1500.2, balance-backtester.GetBalance(), 1e-9)
+		
+		// 含み損益・確定損益は契約サイズを掛けて計算される
+		assert.True(t, backtester.Forward())
+		assert.InDelta(t, 980.0, backtester.GetUnrealizedPnL(), 1e-6)
+		assert.NoError(t, backtester.CloseAllPositions())
+		assert.InDelta(t, 960.0, backtester.GetTradeHistory()[0].PnL, 1e-6)
+	})
+	
+	t.Run("should load instruments file and apply its size constraints", func(t *testing.T) {
+		baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		candles := []models.Candle{
+			*models.NewCandle(baseTime, 150.0, 150.0, 150.0, 150.0, 1000),
+			*models.NewCandle(baseTime.Add(time.Minute), 151.0, 151.0, 151.0, 151.0, 1000),
+		}
+		
+		missing := config
+		missing.InstrumentsFile = "./testdata/missing.json"
+		_, err := NewBacktesterWithProvider(missing, data.NewInMemoryProvider(candles))
+		assert.ErrorContains(t, err, "failed to load instruments file")
+		
+		withFile := config
+		withFile.DefaultInstruments = true
+		withFile.InstrumentsFile = "./testdata/instruments.json"
+		backtester, err := NewBacktesterWithProvider(withFile, data.NewInMemoryProvider(candles))
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		
+		// 組み込みレジストリのシンボルも参照でき、同じシンボルはファイルの内容で上書きされる
+		_, ok := backtester.GetSymbolSpec("EURUSD")
+		assert.True(t, ok)
+		spec, ok := backtester.GetSymbolSpec("USDJPY")
+		assert.True(t, ok)
+		assert.Equal(t, models.SymbolSpec{PipSize: 0.01, ContractSize: 1000, QuoteCurrency: "JPY"}, spec)
+		
+		// 最小サイズ0.1未満・サイズ刻み0.1の倍数でない数量は拒否される
+		assert.ErrorIs(t, backtester.Buy("USDJPY", 0.05), models.ErrInvalidLotSize)
+		assert.ErrorIs(t, backtester.Buy("USDJPY", 0.25), models.ErrInvalidLotSize)
+		
+		// 損失が有効証拠金10000の1%（100）になる数量: 1円の逆行で1ロットあたり1000の損失 → 0.1ロット
+		assert.InDelta(t, 0.1, backtester.PositionSizeForRisk("USDJPY", 1.0, 150.0, 149.0), 1e-9)
+		// 0.15ロット相当はサイズ刻みで切り捨て、最小サイズ未満は0
+		assert.InDelta(t, 0.1, backtester.PositionSizeForRisk("USDJPY", 1.5, 150.0, 149.0), 1e-9)
+		assert.Equal(t, 0.0, backtester.PositionSizeForRisk("USDJPY", 0.5, 150.0, 149.0))
+		
+		assert.NoError(t, backtester.Buy("USDJPY", 0.1))
+		assert.Len(t, backtester.GetPositions(), 1)
+	})
+}
+
+func TestBacktester_Determinism(t *testing.T) {
+	// 買いと売りを建て、次のバーで決済する戦略で取引IDを集める
+	runTradeIDs := func(t *testing.T, backtester *Backtester) []string {
+		_, err := backtester.Run(strategyFunc(func(bt *Backtester) error {
+			if len(bt.GetPositions()) > 0 {
+				return bt.CloseAllPositions()
+			}
+			if err := bt.Buy("SAMPLE", 1000.0); err != nil {
+				return err
+			}
+			return bt.Sell("SAMPLE", 1000.0)
+		}))
+		if err != nil {
+			t.Fatalf("Expected no error from Run, got %v", err)
+		}
+		ids := make([]string, 0)
+		for _, trade := range backtester.GetTradeHistory() {
+			ids = append(ids, trade.ID)
+		}
+		return ids
+	}
+	newBacktester := func(t *testing.T, seed int64) *Backtester {
+		config := Config{
+			Market: MarketConfig{
+				DataProvider: models.DataProviderConfig{FilePath: "./testdata/sample.csv", Format: "csv"},
+			},
+			Broker:   BrokerConfig{InitialBalance: 10000.0, Spread: 0.0001},
+			Backtest: BacktestConfig{Seed: seed},
+		}
+		backtester, err := NewBacktester(config)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		return backtester
+	}
+	
+	t.Run("should generate sequential order IDs", func(t *testing.T) {
+		first := runTradeIDs(t, newBacktester(t, 1))
+		second := runTradeIDs(t, newBacktester(t, 1))
+		
+		assert.NotEmpty(t, first)
+		assert.Equal(t, first, second)
+		assert.Contains(t, first, "pos-buy-SAMPLE-1")
+		assert.Contains(t, first, "pos-sell-SAMPLE-2")
+	})
+	
+	t.Run("should reproduce random numbers from seed", func(t *testing.T) {
+		backtester := newBacktester(t, 42)
+		expected := rand.New(rand.NewSource(42))
+		first := []float64{backtester.Rand().Float64(), backtester.Rand().Float64()}
+		assert.Equal(t, []float64{expected.Float64(), expected.Float64()}, first)
+		
+		// Reset で注文IDの連番と乱数生成器が初期化される
+		assert.NoError(t, backtester.Buy("SAMPLE", 1000.0))
+		assert.NoError(t, backtester.Reset())
+		assert.Equal(t, first, []float64{backtester.Rand().Float64(), backtester.Rand().Float64()})
+		assert.NoError(t, backtester.Buy("SAMPLE", 1000.0))
+		assert.Equal(t, "pos-buy-SAMPLE-1", backtester.GetPositions()[0].ID)
+	})
+}
+
+// FlatTime テスト
+func TestBacktester_FlatTime(t *testing.T) {
+	// times の各時刻に価格 1.1 のローソク足を持つ、取引終了時刻 21:00 の初期化済み Backtester を作成する
+	newFlatBacktester := func(t *testing.T, times []time.Time) *Backtester {
+		candles := make([]models.Candle, 0, len(times))
+		for _, ts := range times {
+			candles = append(candles, *models.NewCandle(ts, 1.1, 1.1, 1.1, 1.1, 1000))
+		}
+		config := Config{
+			Broker:   BrokerConfig{InitialBalance: 10000.0},
+			Backtest: BacktestConfig{FlatTime: "21:00"},
+		}
+		backtester, err := NewBacktesterWithProvider(config, data.NewInMemoryProvider(candles))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := backtester.Initialize(context.Background()); err != nil {
+			t.Fatalf("Expected no error from Initialize, got %v", err)
+		}
+		return backtester
+	}
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	
+	t.Run("should close positions and cancel day orders when crossing flat time", func(t *testing.T) {
+		times := make([]time.Time, 0, 30)
+		for i := 0; i < 30; i++ {
+			times = append(times, day.Add(19*time.Hour+time.Duration(i)*time.Hour))
+		}
+		backtester := newFlatBacktester(t, times)
+		
+		assert.NoError(t, backtester.Buy("EURUSD", 1000.0))
+		dayOrder := models.NewLimitOrder("day-1", "EURUSD", models.Buy, 1000.0, 1.0)
+		dayOrder.TimeInForce = models.DayOrder
+		gtcOrder := models.NewLimitOrder("gtc-1", "EURUSD", models.Buy, 1000.0, 1.0)
+		assert.NoError(t, backtester.broker.PlaceOrder(dayOrder))
+		assert.NoError(t, backtester.broker.PlaceOrder(gtcOrder))
+		
+		// 20:00 ではまだ決済しない
+		backtester.Forward()
+		assert.Len(t, backtester.GetPositions(), 1)
+		
+		// 21:00 のローソク足で決済され、当日限りの注文だけが取り消される
+		backtester.Forward()
+		assert.Empty(t, backtester.GetPositions())
+		trades := backtester.GetTradeHistory()
+		if assert.Len(t, trades, 1) {
+			assert.Equal(t, "pos-buy-EURUSD-1", trades[0].ID)
+			assert.True(t, trades[0].CloseTime.Equal(day.Add(21*time.Hour)))
+		}
+		assert.True(t, dayOrder.IsCancelled())
+		pending := backtester.broker.GetPendingOrders()
+		if assert.Len(t, pending, 1) {
+			assert.Equal(t, "gtc-1", pending[0].ID)
+		}
+		
+		// 同じ日の 22:00 以降は決済せず、翌日の 21:00 に再び決済する
+		assert.NoError(t, backtester.Buy("EURUSD", 1000.0))
+		for i := 0; i < 23; i++ {
+			backtester.Forward()
+		}
+		assert.Len(t, backtester.GetPositions(), 1)
+		backtester.Forward()
+		assert.Empty(t, backtester.GetPositions())
+		assert.Len(t, backtester.GetTradeHistory(), 2)
+	})
+	
+	t.Run("should close once when data skips over flat time", func(t *testing.T) {
+		backtester := newFlatBacktester(t, []time.Time{day.Add(20 * time.Hour), day.Add(23 * time.Hour), day.Add(47 * time.Hour)})
+		assert.NoError(t, backtester.Buy("EURUSD", 1000.0))
+		backtester.Forward()
+		assert.Empty(t, backtester.GetPositions())
+		assert.Len(t, backtester.GetTradeHistory(), 1)
+		
+		// 23:00 から翌日 23:00 まで欠落していても翌日の取引終了時刻をまたいだとみなす
+		assert.NoError(t, backtester.Buy("EURUSD", 1000.0))
+		backtester.Forward()
+		assert.Empty(t, backtester.GetPositions())
+	})
+	
+	t.Run("should reject invalid flat time", func(t *testing.T) {
+		for _, value := range []string{"25:00", "9pm", "21:00:00"} {
+			_, err := NewBacktesterWithProvider(Config{
+				Broker:   BrokerConfig{InitialBalance: 10000.0},
+				Backtest: BacktestConfig{FlatTime: value},
+			}, data.NewInMemoryProvider(nil))
+			assert.ErrorContains(t, err, "invalid flat time", value)
+		}
+	})
+}
+
+// RecostTrades テスト
+func TestRecostTrades(t *testing.T) {
+	backtester := createTestBacktester(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	if err := backtester.Initialize(ctx); err != nil {
+		t.Fatalf("Expected no error from Initialize, got %v", err)
+	}
+	
+	// 買いと売りの取引を1つずつ記録する
+	if err := backtester.Buy("SAMPLE", 10000.0); err != nil {
+		t.Fatalf("Expected no error from Buy, got %v", err)
+	}
+	if err := backtester.Sell("SAMPLE", 5000.0); err != nil {
+		t.Fatalf("Expected no error from Sell, got %v", err)
+	}
+	backtester.Forward()
+	backtester.Forward()
+	if err := backtester.CloseAllPositions(); err != nil {
+		t.Fatalf("Expected no error from CloseAllPositions, got %v", err)
+	}
+	
+	trades := backtester.GetTradeHistory()
+	if len(trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %d", len(trades))
+	}
+	
+	provider := data.NewCSVProvider(models.DataProviderConfig{
+		FilePath: "./testdata/sample.csv",
+		Format:   "csv",
+	})
+	
+	t.Run("should reproduce original PnL with the original spread", func(t *testing.T) {
+		recosted, err := RecostTrades(trades, 0.0001, 0.0, provider)
+		if err != nil {
+			t.Fatalf("Expected no error from RecostTrades, got %v", err)
+		}
+		for i := range trades {
+			assert.InDelta(t, trades[i].PnL, recosted[i].PnL, 1e-9)
+			// エントリーと決済で0.0001ずつのスプレッドを支払っている
+			assert.InDelta(t, 2*0.0001*trades[i].Size, trades[i].SpreadCost, 1e-9)
+			assert.InDelta(t, trades[i].SpreadCost, recosted[i].SpreadCost, 1e-9)
+		}
+	})
+	
+	t.Run("should reduce net PnL by the extra spread and commission", func(t *testing.T) {
+		recosted, err := RecostTrades(trades, 0.0003, 2.5, provider)
+		if err != nil {
+			t.Fatalf("Expected no error from RecostTrades, got %v", err)
+		}
+		if len(recosted) != len(trades) {
+			t.Fatalf("Expected %d recosted trades, got %d", len(trades), len(recosted))
+		}
+		
+		for i, trade := range trades {
+			// エントリーと決済の両方で0.0002ずつ不利になる
+			expectedReduction := 2*0.0002*trade.Size + 2.5
+			assert.InDelta(t, trade.PnL-expectedReduction, recosted[i].PnL, 1e-9)
+			assert.Equal(t, trade.ID, recosted[i].ID)
+		}
+		
+		// 元の取引は変更されない
+		assert.NotEqual(t, trades[0].PnL, recosted[0].PnL)
+	})
+	
+	t.Run("should validate arguments", func(t *testing.T) {
+		_, err := RecostTrades(trades, 0.0001, 0.0, nil)
+		assert.Error(t, err)
+		
+		_, err = RecostTrades(trades, -0.0001, 0.0, provider)
+		assert.Error(t, err)
+	})
+	
+	t.Run("should apply contract size and account currency of the trade", func(t *testing.T) {
+		baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		candles := []models.Candle{
+			*models.NewCandle(baseTime, 1.10, 1.10, 1.10, 1.10, 1000),
+			*models.NewCandle(baseTime.Add(time.Minute), 1.12, 1.12, 1.12, 1.12, 1000),
+		}
+		provider := data.NewInMemoryProvider(candles)
+		config := Config{
+			Broker: BrokerConfig{
+				InitialBalance:  1000000.0,
+				Spread:          0.0001,
+				AccountCurrency: "JPY",
+				ConversionRates: map[string]float64{"USDJPY": 150.0},
+			},
+			Symbols: map[string]models.SymbolSpec{"EURUSD": {PipSize: 0.0001, ContractSize: 100000, QuoteCurrency: "USD"}},
+		}
+		backtester, err := NewBacktesterWithProvider(config, provider)
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		assert.NoError(t, backtester.Buy("EURUSD", 0.01))
+		backtester.Forward()
+		assert.NoError(t, backtester.CloseAllPositions())
+		
+		trades := backtester.GetTradeHistory()
+		if !assert.Len(t, trades, 1) {
+			return
+		}
+		// 1000通貨 × (0.02 - 0.0002) USD × 150
+		assert.InDelta(t, 1000*0.0198*150.0, trades[0].PnL, 1e-6)
+		
+		recosted, err := RecostTrades(trades, 0.0001, 0.0, provider)
+		assert.NoError(t, err)
+		assert.InDelta(t, trades[0].PnL, recosted[0].PnL, 1e-6)
+		assert.InDelta(t, trades[0].SpreadCost, recosted[0].SpreadCost, 1e-6)
+		
+		// スプレッドの増分 2 × 0.0002 × 1000通貨 = 0.4 USD = 60 JPY
+		recosted, err = RecostTrades(trades, 0.0003, 100.0, provider)
+		assert.NoError(t, err)
+		assert.InDelta(t, trades[0].PnL-60.0-100.0, recosted[0].PnL, 1e-6)
+	})
+}
+
+// Backtester 統合テスト
+func TestBacktester_Integration(t *testing.T) {
+	backtester := createTestBacktester(t)
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	err := backtester.Initialize(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error from Initialize, got %v", err)
+	}
+	
+	// 複数の時間ステップで取引実行
+	tradeCount := 0
+	maxSteps := 3
+	
+	for step := 0; step < maxSteps && !backtester.IsFinished(); step++ {
+		// 現在価格取得
+		price := backtester.GetCurrentPrice()
+		if price <= 0.0 {
+			t.Errorf("Expected positive price at step %d", step)
+		}
+		
+		// ステップごとに異なる取引パターン
+		switch step {
+		case 0:
+			// 買い注文
+			err = backtester.Buy("SAMPLE", 10000.0)
+			if err != nil {
+				t.Fatalf("Expected no error from Buy at step %d, got %v", step, err)
+			}
+			tradeCount++
+			
+		case 1:
+			// 売り注文
+			err = backtester.Sell("SAMPLE", 5000.0)
+			if err != nil {
+				t.Fatalf("Expected no error from Sell at step %d, got %v", step, err)
+			}
+			tradeCount++
+			
+		case 2:
+			// ポジション一部決済
+			positions := backtester.GetPositions()
+			if len(positions) > 0 {
+				err = backtester.ClosePosition(positions[0].ID)
+				if err != nil {
+					t.Fatalf("Expected no error from ClosePosition at step %d, got %v", step, err)
+				}
+			}
+		}
+		
+		// 時間進行
+		if step < maxSteps-1 {
+			hasNext := backtester.Forward()
+			if !hasNext {
+				break // データ終了
+			}
+		}
+	}
+	
+	// 最終状態確認
+	finalPositions := backtester.GetPositions()
+	finalBalance := backtester.GetBalance()
+	
+	// ログ出力（デバッグ用）
+	t.Logf("Final positions: %d", len(finalPositions))
+	t.Logf("Final balance: %f", finalBalance)
+	t.Logf("Trade count: %d", tradeCount)
+	
+	// 基本的な整合性確認
+	if finalBalance < 0 {
+		t.Error("Expected non-negative final balance")
+	}
+}
+
+// Backtester エラーハンドリングテスト
+func TestBacktester_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	
+	t.Run("should reject second Initialize without changing state", func(t *testing.T) {
+		bt := createTestBacktester(t)
+		assert.NoError(t, bt.Initialize(ctx))
+		assert.True(t, bt.Forward())
+		current := bt.GetCurrentTime()
+		
+		err := bt.Initialize(ctx)
+		assert.ErrorIs(t, err, ErrAlreadyInitialized)
+		assert.True(t, current.Equal(bt.GetCurrentTime()))
+		assert.True(t, bt.Forward())
+	})
+	
+	t.Run("should reject Initialize after Stop", func(t *testing.T) {
+		bt := createTestBacktester(t)
+		mockVisualizer := NewMockVisualizer()
+		bt.visualizer = mockVisualizer
+		assert.NoError(t, bt.Initialize(ctx))
+		
+		assert.NoError(t, bt.Stop())
+		assert.False(t, bt.Forward())
+		assert.ErrorIs(t, bt.Initialize(ctx), ErrStopped)
+		
+		// 2回目の Stop は何もしない
+		notified := mockVisualizer.GetStateChangeCount()
+		assert.NoError(t, bt.Stop())
+		assert.Equal(t, notified, mockVisualizer.GetStateChangeCount())
+	})
+	
+	t.Run("should reject Initialize after Stop before Initialize", func(t *testing.T) {
+		bt := createTestBacktester(t)
+		assert.NoError(t, bt.Stop())
+		assert.ErrorIs(t, bt.Initialize(ctx), ErrStopped)
+	})
+}
+
+func TestBacktester_Close(t *testing.T) {
+	ctx := context.Background()
+	
+	newVisualBacktester := func() *Backtester {
+		config := createTestBacktester(t).config
+		config.Visualizer = models.DefaultVisualizerConfig()
+		config.Visualizer.Port = 8105
+		bt, err := NewBacktester(config)
+		assert.NoError(t, err)
+		return bt
+	}
+	
+	t.Run("should release visualizer and controller goroutines", func(t *testing.T) {
+		baseline := runtime.NumGoroutine()
+		
+		// 同じポートで繰り返し起動できれば、サーバーも停止している
+		for i := 0; i < 3; i++ {
+			bt := newVisualBacktester()
+			assert.NoError(t, bt.Initialize(ctx))
+			assert.NoError(t, bt.Close())
+		}
+		
+		assertGoroutinesReleased(t, baseline)
+	})
+	
+	t.Run("should be safe to call multiple times", func(t *testing.T) {
+		bt := createTestBacktester(t)
+		assert.NoError(t, bt.Initialize(ctx))
+		assert.True(t, bt.Forward())
+		
+		assert.NoError(t, bt.Close())
+		assert.NoError(t, bt.Close())
+		assert.NoError(t, bt.Stop())
+		
+		assert.False(t, bt.Forward())
+		assert.True(t, bt.IsCancelled())
+		assert.ErrorIs(t, bt.Initialize(ctx), ErrStopped)
+	})
+	
+	t.Run("should release controller without Initialize", func(t *testing.T) {
+		baseline := runtime.NumGoroutine()
+		bt := newVisualBacktester()
+		assert.NoError(t, bt.Close())
+		assertGoroutinesReleased(t, baseline)
+	})
+}
+
+func TestBacktester_ErrorHandling(t *testing.T) {
+	backtester := createTestBacktester(t)
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	err := backtester.Initialize(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error from Initialize, got %v", err)
+	}
+	
+	// 無効なサイズでの注文
+	err = backtester.Buy("SAMPLE", 0.0)
+	if err == nil {
+		t.Error("Expected error for zero size order")
+	}
+	
+	err = backtester.Buy("SAMPLE", -1000.0)
+	if err == nil {
+		t.Error("Expected error for negative size order")
+	}
+	
+	// 残高不足での大きな注文
+	err = backtester.Buy("SAMPLE", 10000000.0) // 非常に大きな注文
+	if err == nil {
+		t.Error("Expected error for insufficient balance")
+	}
+	
+	// 存在しないポジションのクローズ
+	err = backtester.ClosePosition("nonexistent-id")
+	if err == nil {
+		t.Error("Expected error for nonexistent position")
+	}
+	
+	// 初期化前の操作エラー（新しいbacktesterで）
+	uninitializedBacktester := createTestBacktester(t)
+	
+	err = uninitializedBacktester.Buy("SAMPLE", 1000.0)
+	if err == nil {
+		t.Error("Expected error for uninitialized backtester")
+	}
+}
+
+// ヘルパー関数: ゴルーチン数が baseline 以下に戻るまで待つ
+// （assert.Eventually は条件判定用のゴルーチンを起動するため使わない）
+func assertGoroutinesReleased(t *testing.T, baseline int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > baseline {
+		t.Errorf("goroutines leaked: baseline %d, now %d", baseline, n)
+	}
+}
+
+// ヘルパー関数: 2024-01-01 09:00 から1分ごとの、終値のみ（始値・高値・安値も同値）のローソク足を作成
+func candlesFromCloses(closes []float64) []models.Candle {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 0, len(closes))
+	for i, price := range closes {
+		candles = append(candles, *models.NewCandle(baseTime.Add(time.Duration(i)*time.Minute), price, price, price, price, 1000))
+	}
+	return candles
+}
+
+// ヘルパー関数: テスト用Backtester作成
+func createTestBacktester(_ *testing.T) *Backtester {
+	dataConfig := models.DataProviderConfig{
+		FilePath: "./testdata/sample.csv",
+		Format:   "csv",
+	}
+	
+	brokerConfig := models.BrokerConfig{
+		InitialBalance: 10000.0,
+		Spread:         0.0001,
+	}
+	
+	config := Config{
+		Market: MarketConfig{
+			DataProvider: dataConfig,
+		},
+		Broker: BrokerConfig{
+			InitialBalance: brokerConfig.InitialBalance,
+			Spread:         brokerConfig.Spread,
+		},
+	}
+	backtester, err := NewBacktester(config)
+	if err != nil {
+		panic(err) // テスト用なのでpanicで良い
+	}
+	return backtester
+}
+
+func TestBacktester_AccountCurrency(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	candles := []models.Candle{
+		*models.NewCandle(baseTime, 1.10, 1.10, 1.10, 1.10, 1000),
+		*models.NewCandle(baseTime.Add(time.Minute), 1.11, 1.11, 1.11, 1.11, 1000),
+		*models.NewCandle(baseTime.Add(2*time.Minute), 1.12, 1.12, 1.12, 1.12, 1000),
+	}
+	// USDJPY は 150 から 140 に下落する
+	feedPath := filepath.Join(t.TempDir(), "usdjpy.csv")
+	feed := "2024.01.01,09:00,150.0,150.0,150.0,150.0,1000\n" +
+		"2024.01.01,09:01,145.0,145.0,145.0,145.0,1000\n" +
+		"2024.01.01,09:02,140.0,140.0,140.0,140.0,1000\n"
+	if err := os.WriteFile(feedPath, []byte(feed), 0o644); err != nil {
+		t.Fatalf("failed to write feed: %v", err)
+	}
+	
+	t.Run("should report PnL and balance in account currency", func(t *testing.T) {
+		config := Config{Broker: BrokerConfig{
+			InitialBalance:  1000000.0,
+			AccountCurrency: "JPY",
+			ConversionFeeds: map[string]string{"USDJPY": feedPath},
+		}}
+		backtester, err := NewBacktesterWithProvider(config, data.NewInMemoryProvider(candles))
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		
+		var unrealized float64
+		_, err = backtester.Run(strategyFunc(func(bt *Backtester) error {
+			switch bt.GetCurrentTime().Sub(baseTime) {
+			case 0:
+				return bt.Buy("EURUSD", 1000.0)
+			case time.Minute:
+				unrealized = bt.GetUnrealizedPnL()
+			}
+			return nil
+		}))
+		assert.NoError(t, err)
+		
+		// 含み益 10 USD × 145、決済益 20 USD × 140
+		assert.InDelta(t, 1450.0, unrealized, 1e-6)
+		history := backtester.GetTradeHistory()
+		if assert.Len(t, history, 1) {
+			assert.InDelta(t, 2800.0, history[0].PnL, 1e-6)
+		}
+		assert.InDelta(t, 1002800.0, backtester.GetBalance(), 1e-6)
+	})
+	
+	t.Run("should validate currency config", func(t *testing.T) {
+		invalid := []BrokerConfig{
+			{InitialBalance: 10000.0, AccountCurrency: "YEN!"},
+			{InitialBalance: 10000.0, ConversionRates: map[string]float64{"USDJPY": 0}},
+			{InitialBalance: 10000.0, ConversionFeeds: map[string]string{"USDJPY": ""}},
+		}
+		for _, broker := range invalid {
+			_, err := NewBacktesterWithProvider(Config{Broker: broker}, data.NewInMemoryProvider(candles))
+			assert.Error(t, err, "config %+v", broker)
+		}
+	})
+}
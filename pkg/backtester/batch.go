@@ -0,0 +1,100 @@
+package backtester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/data"
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// StrategyFactory は RunBatch の実行ごとに新しい戦略を生成する関数です。
+// index は configs 内の位置、config はその実行の設定です。戦略は実行ごとに別のインスタンスを返す必要があります。
+type StrategyFactory func(index int, config Config) (Strategy, error)
+
+// BatchResult は RunBatch の1回分の実行結果です。
+type BatchResult struct {
+	Index  int     // configs 内の位置
+	Config Config  // 実行に使った設定
+	Result *Result // 実行結果（Err が nil でない場合は nil）
+	Err    error   // 作成・初期化・実行のいずれかで発生したエラー
+}
+
+// RunBatch は configs の各設定で独立したバックテストを最大 concurrency 個並行して実行し、入力と同じ順で結果を返します。
+// 実行ごとに Backtester（Market・Broker）と戦略を新しく作成するため、実行間で状態は共有されません。
+// candles を指定した場合は各実行が data.InMemoryProvider でそのコピーを読み込み、nil の場合は各設定のデータファイルを読み込みます。
+// concurrency が0以下の場合は GOMAXPROCS を使います。Visualizer は各設定の値に関わらず無効にして実行します。
+// 1回の実行の失敗は対応する BatchResult.Err に記録され、他の実行は続行します。
+// ctx がキャンセルされた場合、開始前の実行は ctx のエラーで終了し、実行中のものは Cancelled の部分的な結果を返します。
+func RunBatch(ctx context.Context, configs []Config, candles []models.Candle, factory StrategyFactory, concurrency int) []BatchResult {
+	results := make([]BatchResult, len(configs))
+	if len(configs) == 0 {
+		return results
+	}
+
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	if concurrency > len(configs) {
+		concurrency = len(configs)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result, err := runOne(ctx, i, configs[i], candles, factory)
+				results[i] = BatchResult{Index: i, Config: configs[i], Result: result, Err: err}
+			}
+		}()
+	}
+
+	for i := range configs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// runOne は RunBatch の1回分のバックテストを実行します。
+func runOne(ctx context.Context, index int, config Config, candles []models.Candle, factory StrategyFactory) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if factory == nil {
+		return nil, errors.New("strategy factory is required")
+	}
+
+	config.Visualizer.Enabled = false
+
+	var bt *Backtester
+	var err error
+	if candles != nil {
+		bt, err = NewBacktesterWithProvider(config, data.NewInMemoryProvider(candles))
+	} else {
+		bt, err = NewBacktester(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer bt.Close()
+
+	strategy, err := factory(index, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create strategy: %w", err)
+	}
+
+	if err := bt.Initialize(ctx); err != nil {
+		return nil, fmt.Errorf("failed to initialize backtester: %w", err)
+	}
+
+	return bt.Run(strategy)
+}
@@ -0,0 +1,137 @@
+package backtester
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// 最初のローソク足で1回だけ買い、最後まで保有するテスト用戦略
+type buyOnceStrategy struct {
+	size   float64
+	bought bool
+}
+
+func (s *buyOnceStrategy) OnBar(bt *Backtester) error {
+	if s.bought {
+		return nil
+	}
+	s.bought = true
+	return bt.Buy("EURUSD", s.size)
+}
+
+func TestRunBatch(t *testing.T) {
+	closes := make([]float64, 50)
+	for i := range closes {
+		closes[i] = 1.1 + float64(i)*0.001
+	}
+	candles := candlesFromCloses(closes)
+	
+	configs := make([]Config, 0, 6)
+	for i := 0; i < 6; i++ {
+		configs = append(configs, Config{
+			Broker: BrokerConfig{InitialBalance: 10000.0, Spread: float64(i) * 0.0001},
+		})
+	}
+	factory := func(index int, config Config) (Strategy, error) {
+		return &buyOnceStrategy{size: 1000.0}, nil
+	}
+	
+	t.Run("should return results in input order", func(t *testing.T) {
+		results := RunBatch(context.Background(), configs, candles, factory, 3)
+		assert.Len(t, results, len(configs))
+		for i, r := range results {
+			assert.Equal(t, i, r.Index)
+			assert.NoError(t, r.Err)
+			assert.NotNil(t, r.Result)
+			assert.Equal(t, configs[i].Broker.Spread, r.Config.Broker.Spread)
+			assert.Equal(t, 1, r.Result.TotalTrades)
+			
+			// 1.1 で買って 1.149 で決済、往復のスプレッド分だけ損益が減る
+			expected := (0.049 - 2*configs[i].Broker.Spread) * 1000.0
+			assert.InDelta(t, expected, r.Result.TotalPnL, 1e-9, "run %d", i)
+		}
+	})
+	
+	t.Run("should match sequential execution", func(t *testing.T) {
+		sequential := RunBatch(context.Background(), configs, candles, factory, 1)
+		parallel := RunBatch(context.Background(), configs, candles, factory, 0)
+		for i := range configs {
+			assert.Equal(t, sequential[i].Result.TotalPnL, parallel[i].Result.TotalPnL)
+			assert.Equal(t, sequential[i].Result.FinalBalance, parallel[i].Result.FinalBalance)
+		}
+	})
+	
+	t.Run("should reproduce random slippage from each run's seed", func(t *testing.T) {
+		seeds := []int64{7, 8, 7, 8, 7, 8}
+		batch := make([]Config, 0, len(seeds))
+		for _, seed := range seeds {
+			batch = append(batch, Config{
+				Broker:   BrokerConfig{InitialBalance: 10000.0, Slippage: 0.001, SlippageMode: models.SlippageRandom},
+				Backtest: BacktestConfig{Seed: seed},
+			})
+		}
+		// 戦略が Rand を使ってもスリッページの乱数列は変わらない
+		results := RunBatch(context.Background(), batch, candles, func(index int, config Config) (Strategy, error) {
+			if index >= 2 {
+				return &buyOnceStrategy{size: 1000.0}, nil
+			}
+			return strategyFunc(func(bt *Backtester) error {
+				bt.Rand().Float64()
+				if len(bt.GetPositions()) > 0 {
+					return nil
+				}
+				return bt.Buy("EURUSD", 1000.0)
+			}), nil
+		}, 0)
+		
+		for i, r := range results {
+			assert.NoError(t, r.Err)
+			assert.Equal(t, results[i%2].Result.TotalPnL, r.Result.TotalPnL, "run %d", i)
+		}
+		assert.NotEqual(t, results[0].Result.TotalPnL, results[1].Result.TotalPnL)
+		assert.Less(t, results[0].Result.TotalPnL, 49.0)
+		assert.Greater(t, results[0].Result.TotalPnL, 49.0-2*0.001*1000.0)
+	})
+	
+	t.Run("should record per-run errors without stopping others", func(t *testing.T) {
+		batch := append([]Config{}, configs[:3]...)
+		batch[1].Broker.InitialBalance = 0
+		results := RunBatch(context.Background(), batch, candles, func(index int, config Config) (Strategy, error) {
+			if index == 2 {
+				return nil, errors.New("bad parameters")
+			}
+			return &buyOnceStrategy{size: 1000.0}, nil
+		}, 2)
+		
+		assert.NoError(t, results[0].Err)
+		assert.NotNil(t, results[0].Result)
+		assert.ErrorContains(t, results[1].Err, "initial balance")
+		assert.Nil(t, results[1].Result)
+		assert.ErrorContains(t, results[2].Err, "bad parameters")
+		
+		// 戦略のエラーも記録される
+		results = RunBatch(context.Background(), configs[:1], candles, func(index int, config Config) (Strategy, error) {
+			return strategyFunc(func(bt *Backtester) error { return errors.New("boom") }), nil
+		}, 1)
+		assert.ErrorContains(t, results[0].Err, "boom")
+	})
+	
+	t.Run("should not start runs after cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results := RunBatch(ctx, configs, candles, factory, 2)
+		for _, r := range results {
+			assert.ErrorIs(t, r.Err, context.Canceled)
+		}
+	})
+	
+	t.Run("should handle empty batch and missing factory", func(t *testing.T) {
+		assert.Empty(t, RunBatch(context.Background(), nil, candles, factory, 2))
+		results := RunBatch(context.Background(), configs[:1], candles, nil, 2)
+		assert.Error(t, results[0].Err)
+	})
+}
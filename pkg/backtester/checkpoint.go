@@ -0,0 +1,138 @@
+package backtester
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/broker"
+	"github.com/RuiHirano/fx-backtesting/pkg/market"
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// CheckpointVersion は SaveState が書き出すチェックポイント形式のバージョンです。
+// 形式を変更した場合は値を上げ、LoadState で古い形式を読み分けます。
+const CheckpointVersion = 1
+
+// checkpoint は SaveState / LoadState で読み書きするバックテスト状態です。
+type checkpoint struct {
+	Version    int                `json:"version"`
+	SavedAt    time.Time          `json:"saved_at"`
+	Market     market.State       `json:"market"`
+	Broker     broker.State       `json:"broker"`
+	Statistics *models.Statistics `json:"statistics"`
+	OrderSeq   uint64             `json:"order_seq"`
+	Seed       int64              `json:"seed"`
+	RandDraws  uint64             `json:"rand_draws"`
+	// SlippageDraws はスリッページ用の乱数の生成回数です（古いチェックポイントでは0）
+	SlippageDraws uint64          `json:"slippage_draws,omitempty"`
+}
+
+// SaveState はマーケットの位置・残高・ポジション・保留注文・取引履歴・統計情報・乱数列の位置を
+// JSON 形式のチェックポイントとして書き出します。LoadState で同じ時点から再開できます。
+func (bt *Backtester) SaveState(w io.Writer) error {
+	if !bt.initialized {
+		return ErrNotInitialized
+	}
+	
+	bt.stepMutex.Lock()
+	defer bt.stepMutex.Unlock()
+	
+	cp := checkpoint{
+		Version:    CheckpointVersion,
+		SavedAt:    time.Now(),
+		Market:     bt.market.State(),
+		Broker:     bt.broker.State(),
+		Statistics: bt.statistics,
+		OrderSeq:   bt.orderSeq.Load(),
+		Seed:       bt.seed,
+		RandDraws:  bt.rngSource.draws,
+		SlippageDraws: bt.slippageSource.draws,
+	}
+	if err := json.NewEncoder(w).Encode(cp); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LoadState は SaveState で書き出したチェックポイントを読み込み、保存時点の状態に戻します。
+// 同じデータ・設定で作成し、Initialize した Backtester に対して呼び出してください。
+// データが保存時点と一致しない場合はエラーを返します。
+// Rand が返す乱数生成器とスリッページ用の乱数生成器はそのまま使え、保存時点の続きから乱数を返します。
+func (bt *Backtester) LoadState(r io.Reader) error {
+	if !bt.initialized {
+		return ErrNotInitialized
+	}
+	
+	var cp checkpoint
+	if err := json.NewDecoder(r).Decode(&cp); err != nil {
+		return fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if cp.Version != CheckpointVersion {
+		return fmt.Errorf("unsupported checkpoint version: %d", cp.Version)
+	}
+	if cp.Statistics == nil {
+		return errors.New("checkpoint has no statistics")
+	}
+	
+	bt.stepMutex.Lock()
+	defer bt.stepMutex.Unlock()
+	
+	if err := bt.market.RestoreState(bt.ctx, cp.Market); err != nil {
+		return fmt.Errorf("failed to restore market: %w", err)
+	}
+	bt.broker.RestoreState(cp.Broker)
+	bt.statistics = cp.Statistics
+	bt.orderSeq.Store(cp.OrderSeq)
+	bt.seed = cp.Seed
+	bt.rngSource.restore(cp.Seed, cp.RandDraws)
+	bt.slippageSource.restore(slippageSeed(cp.Seed), cp.SlippageDraws)
+	bt.updateStatus()
+	
+	if bt.visualizer != nil {
+		bt.publishStatistics(true)
+	}
+	
+	return nil
+}
+
+// countingSource は生成回数を数える乱数ソースです。
+// math/rand のソースは内部状態を書き出せないため、シードと生成回数から乱数列の位置を復元します。
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+// newCountingSource はシードで初期化した countingSource を作成します。
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+// Int63 は rand.Source を実装します。
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+// Uint64 は rand.Source64 を実装します。
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+// Seed は rand.Source を実装します。生成回数も0に戻ります。
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
+
+// restore はシードから初期化し直し、draws 回分の乱数を読み捨てて保存時点の位置に戻します。
+func (s *countingSource) restore(seed int64, draws uint64) {
+	s.Seed(seed)
+	for s.draws < draws {
+		s.Int63()
+	}
+}
@@ -0,0 +1,165 @@
+package backtester
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// createCheckpointBacktester はシードを固定した初期化済みの Backtester を作成する
+func createCheckpointBacktester(t *testing.T) *Backtester {
+	config := Config{
+		Market: MarketConfig{
+			DataProvider: models.DataProviderConfig{
+				FilePath: "./testdata/sample.csv",
+				Format:   "csv",
+			},
+		},
+		Broker: BrokerConfig{
+			InitialBalance: 10000.0,
+			Spread:         0.0001,
+			Slippage:       0.0002,
+			SlippageMode:   models.SlippageRandom,
+		},
+		Backtest: BacktestConfig{
+			Seed: 42,
+		},
+	}
+	backtester, err := NewBacktester(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := backtester.Initialize(context.Background()); err != nil {
+		t.Fatalf("Expected no error from Initialize, got %v", err)
+	}
+	return backtester
+}
+
+// continueRun はチェックポイント以降の操作を再現し、乱数の値を返す
+func continueRun(t *testing.T, backtester *Backtester) []float64 {
+	draws := []float64{backtester.Rand().Float64(), backtester.Rand().Float64()}
+	
+	if err := backtester.Sell("SAMPLE", 5000.0); err != nil {
+		t.Fatalf("Expected no error from Sell, got %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		backtester.Forward()
+	}
+	if err := backtester.CloseAllPositions(); err != nil {
+		t.Fatalf("Expected no error from CloseAllPositions, got %v", err)
+	}
+	return draws
+}
+
+func TestBacktester_SaveLoadState(t *testing.T) {
+	t.Run("should resume from checkpoint with identical results", func(t *testing.T) {
+		original := createCheckpointBacktester(t)
+		
+		// 決済済み取引・保有ポジション・保留注文・乱数の消費がある状態を作る
+		for i := 0; i < 50; i++ {
+			original.Forward()
+		}
+		assert.NoError(t, original.Buy("SAMPLE", 10000.0))
+		for i := 0; i < 20; i++ {
+			original.Forward()
+		}
+		assert.NoError(t, original.ClosePosition(original.GetPositions()[0].ID))
+		assert.NoError(t, original.Buy("SAMPLE", 10000.0))
+		limit := models.NewLimitOrder("limit-1", "SAMPLE", models.Buy, 1000.0, original.GetCurrentPrice()*0.5)
+		assert.NoError(t, original.broker.PlaceOrder(limit))
+		original.Rand().Float64()
+		original.Forward()
+		
+		var buf bytes.Buffer
+		if err := original.SaveState(&buf); err != nil {
+			t.Fatalf("Expected no error from SaveState, got %v", err)
+		}
+		checkpoint := buf.Bytes()
+		savedTime := original.GetCurrentTime()
+		savedBalance := original.GetBalance()
+		savedTrades := original.statistics.TotalTrades
+		
+		expectedDraws := continueRun(t, original)
+		
+		resumed := createCheckpointBacktester(t)
+		if err := resumed.LoadState(bytes.NewReader(checkpoint)); err != nil {
+			t.Fatalf("Expected no error from LoadState, got %v", err)
+		}
+		
+		// 保存時点の状態が復元される
+		assert.True(t, savedTime.Equal(resumed.GetCurrentTime()))
+		assert.Equal(t, savedBalance, resumed.GetBalance())
+		assert.Len(t, resumed.GetPositions(), 1)
+		assert.Len(t, resumed.GetTradeHistory(), 1)
+		assert.Len(t, resumed.broker.GetPendingOrders(), 1)
+		assert.Equal(t, 1, savedTrades)
+		assert.Equal(t, savedTrades, resumed.statistics.TotalTrades)
+		
+		// 再開後も同じ操作で同じ結果・同じ注文ID・同じ乱数列になる
+		draws := continueRun(t, resumed)
+		assert.Equal(t, expectedDraws, draws)
+		assert.True(t, original.GetCurrentTime().Equal(resumed.GetCurrentTime()))
+		assert.InDelta(t, original.GetBalance(), resumed.GetBalance(), 1e-9)
+		assert.Equal(t, original.statistics.TotalTrades, resumed.statistics.TotalTrades)
+		
+		expected := original.GetTradeHistory()
+		actual := resumed.GetTradeHistory()
+		if assert.Len(t, actual, len(expected)) {
+			for i := range expected {
+				assert.Equal(t, expected[i].ID, actual[i].ID)
+				assert.InDelta(t, expected[i].PnL, actual[i].PnL, 1e-9)
+				assert.True(t, expected[i].CloseTime.Equal(actual[i].CloseTime))
+			}
+		}
+	})
+	
+	t.Run("should keep simulated time offset", func(t *testing.T) {
+		original := createCheckpointBacktester(t)
+		anchor := original.GetCurrentTime().AddDate(1, 0, 0)
+		assert.NoError(t, original.SetInitialTime(anchor))
+		original.Forward()
+		
+		var buf bytes.Buffer
+		assert.NoError(t, original.SaveState(&buf))
+		
+		resumed := createCheckpointBacktester(t)
+		assert.NoError(t, resumed.LoadState(&buf))
+		assert.True(t, original.GetCurrentTime().Equal(resumed.GetCurrentTime()))
+	})
+	
+	t.Run("should return error before Initialize", func(t *testing.T) {
+		backtester := createTestBacktester(t)
+		var buf bytes.Buffer
+		assert.Error(t, backtester.SaveState(&buf))
+		assert.Error(t, backtester.LoadState(&buf))
+	})
+	
+	t.Run("should reject unsupported version", func(t *testing.T) {
+		backtester := createCheckpointBacktester(t)
+		err := backtester.LoadState(bytes.NewReader([]byte(`{"version":99}`)))
+		assert.ErrorContains(t, err, "unsupported checkpoint version")
+	})
+	
+	t.Run("should reject checkpoint for different data", func(t *testing.T) {
+		original := createCheckpointBacktester(t)
+		original.Forward()
+		
+		var buf bytes.Buffer
+		assert.NoError(t, original.SaveState(&buf))
+		
+		// 保存時点のローソク足の時刻を改ざんする
+		var raw map[string]interface{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+		raw["market"].(map[string]interface{})["current_time"] = "2000-01-01T00:00:00Z"
+		tampered, err := json.Marshal(raw)
+		assert.NoError(t, err)
+		
+		resumed := createCheckpointBacktester(t)
+		err = resumed.LoadState(bytes.NewReader(tampered))
+		assert.ErrorContains(t, err, "does not match")
+	})
+}
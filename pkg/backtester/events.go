@@ -0,0 +1,210 @@
+package backtester
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/broker"
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// EventType はイベントログに記録するイベントの種類です。
+type EventType string
+
+const (
+	EventCandle         EventType = "candle"          // ローソク足が進んだ（Initialize・Reset 時の最初の足を含む）
+	EventOrderPlaced    EventType = "order_placed"    // 戦略が注文を発注した
+	EventOrderRejected  EventType = "order_rejected"  // 発注した注文がブローカーに拒否された
+	EventOrderFilled    EventType = "order_filled"    // 注文が約定した
+	EventOrderCanceled  EventType = "order_canceled"  // 保留注文が取り消された
+	EventPositionOpened EventType = "position_opened" // 約定で新しいポジションを建てた
+	EventPositionClosed EventType = "position_closed" // ポジションの全部または一部を決済した
+	EventMarginCall     EventType = "margin_call"     // 有効証拠金が拘束中の証拠金を下回った
+)
+
+// Event はイベントログの1件です。注文・ポジション・取引は記録時点のコピーで、後の変更の影響を受けません。
+type Event struct {
+	Seq      int              `json:"seq"`                // 記録順の通し番号（1から）
+	Type     EventType        `json:"type"`
+	Time     time.Time        `json:"time"`               // マーケット時刻
+	Candle   *models.Candle   `json:"candle,omitempty"`   // candle イベントの足
+	Order    *models.Order    `json:"order,omitempty"`    // 注文イベントの注文
+	Position *models.Position `json:"position,omitempty"` // 建てた・約定で変化したポジション
+	Trade    *models.Trade    `json:"trade,omitempty"`    // position_closed イベントで記録された取引
+	Reason   string           `json:"reason,omitempty"`   // 拒否・取消・決済の理由、マージンコールの証拠金維持率
+	Balance  float64          `json:"balance"`            // イベント直後の残高
+	Equity   float64          `json:"equity"`             // イベント直後の有効証拠金
+}
+
+// EventRecorder はバックテスト中のイベントを発生順に記録します。
+// Backtester.SetEventRecorder で設定すると、ローソク足の進行・発注・約定・ポジションの建玉と決済・マージンコールが記録されます。
+// 取引履歴と異なり、拒否された注文や決済理由も残るため、戦略がなぜその取引をしたかを後から再構成できます。
+type EventRecorder struct {
+	mutex  sync.RWMutex
+	events []Event
+}
+
+// NewEventRecorder は空の EventRecorder を作成します。
+func NewEventRecorder() *EventRecorder {
+	return &EventRecorder{events: make([]Event, 0)}
+}
+
+// Record はイベントに通し番号を付けて記録します。
+func (r *EventRecorder) Record(event Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	event.Seq = len(r.events) + 1
+	r.events = append(r.events, event)
+}
+
+// Events は記録したイベントのコピーを記録順に返します。
+func (r *EventRecorder) Events() []Event {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	events := make([]Event, len(r.events))
+	copy(events, r.events)
+	return events
+}
+
+// Reset は記録したイベントを全て破棄します。
+func (r *EventRecorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = make([]Event, 0)
+}
+
+// WriteJSON は記録したイベントを記録順のJSON配列として w に書き出します。ReadEvents で読み込めます。
+func (r *EventRecorder) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.Events())
+}
+
+// ReadEvents は WriteJSON で書き出したイベントログを読み込みます。
+// 通し番号が1から連続していない場合は、欠落・並び替えのあるログとしてエラーを返します。
+func ReadEvents(reader io.Reader) ([]Event, error) {
+	var events []Event
+	if err := json.NewDecoder(reader).Decode(&events); err != nil {
+		return nil, fmt.Errorf("failed to decode events: %w", err)
+	}
+	for i, event := range events {
+		if event.Seq != i+1 {
+			return nil, fmt.Errorf("event %d has seq %d: log is incomplete or out of order", i+1, event.Seq)
+		}
+	}
+	return events, nil
+}
+
+// Replay は events を記録順に handler に渡します。handler がエラーを返した場合はそこで停止し、そのエラーを返します。
+func Replay(events []Event, handler func(Event) error) error {
+	for _, event := range events {
+		if err := handler(event); err != nil {
+			return fmt.Errorf("replay stopped at event %d (%s): %w", event.Seq, event.Type, err)
+		}
+	}
+	return nil
+}
+
+// SetEventRecorder はイベントログの記録先を設定します。nil を指定すると記録を停止します。
+// 初期化済みの場合は、ログの起点として現在のローソク足を記録します。全ての足を記録するには Initialize の前に設定してください。
+// Reset は記録済みのイベントを破棄し、最初の足から記録し直します。
+func (bt *Backtester) SetEventRecorder(recorder *EventRecorder) {
+	bt.events.Store(recorder)
+	if bt.initialized {
+		bt.recordCandle()
+	}
+}
+
+// recordEvent はイベントログが設定されている場合に、マーケット時刻と残高・有効証拠金を付けてイベントを記録します。
+func (bt *Backtester) recordEvent(event Event) {
+	recorder := bt.events.Load()
+	if recorder == nil {
+		return
+	}
+	event.Time = bt.market.GetCurrentTime()
+	event.Balance = bt.broker.GetBalance()
+	event.Equity = bt.broker.GetEquity()
+	recorder.Record(event)
+}
+
+// recordCandle は現在のローソク足を candle イベントとして記録します。
+func (bt *Backtester) recordCandle() {
+	if bt.events.Load() == nil {
+		return
+	}
+	if candle := bt.market.GetCurrentCandle(); candle != nil {
+		copied := *candle
+		bt.recordEvent(Event{Type: EventCandle, Candle: &copied})
+	}
+}
+
+// recordOrder は注文のコピーを持つ注文イベントを記録します。
+func (bt *Backtester) recordOrder(eventType EventType, order *models.Order, reason string) {
+	if bt.events.Load() == nil {
+		return
+	}
+	copied := *order
+	bt.recordEvent(Event{Type: eventType, Order: &copied, Reason: reason})
+}
+
+// recordFill は約定した注文と、約定で変化したポジションを記録します。
+// ポジションIDが "pos-注文ID" の場合は約定で新しく建てたポジションとして position_opened も記録します。
+func (bt *Backtester) recordFill(order *models.Order, position *models.Position) {
+	if bt.events.Load() == nil {
+		return
+	}
+	copiedOrder := *order
+	event := Event{Type: EventOrderFilled, Order: &copiedOrder}
+	if position != nil {
+		copiedPosition := *position
+		event.Position = &copiedPosition
+	}
+	bt.recordEvent(event)
+	
+	if position != nil && position.ID == "pos-"+order.ID {
+		copiedPosition := *position
+		bt.recordEvent(Event{Type: EventPositionOpened, Order: &copiedOrder, Position: &copiedPosition})
+	}
+}
+
+// handlePositionClosed はブローカーからの決済通知を受けて、取引を統計情報に加えて Visualizer に通知し、position_closed イベントとして記録します。
+// ClosePosition に限らず、CloseAllPositions・ストップロス・テイクプロフィット・取引終了時刻・バックテスト終了時の決済も同じく数えます。
+// 取引終了時刻・バックテスト終了時の一括決済では、ブローカーの決済理由（manual）の代わりにその理由を記録します。
+func (bt *Backtester) handlePositionClosed(trade *models.Trade, reason broker.CloseReason) {
+	bt.statistics.AddTrade(trade.PnL)
+	bt.updateAccountStatistics()
+	if bt.visualizer != nil {
+		bt.visualizer.OnTradeEvent(trade)
+		bt.publishStatistics(true)
+	}
+	
+	if bt.events.Load() == nil {
+		return
+	}
+	recorded := string(reason)
+	if bt.closeReason != "" && reason == broker.CloseManual {
+		recorded = bt.closeReason
+	}
+	copied := *trade
+	bt.recordEvent(Event{Type: EventPositionClosed, Trade: &copied, Reason: recorded})
+}
+
+// checkMarginCall は有効証拠金が拘束中の証拠金を下回った（証拠金維持率が100%未満になった）時に margin_call を記録します。
+// 維持率が100%以上に戻るまでは同じマージンコールとして扱い、再記録しません。ポジションの強制決済は行いません。
+func (bt *Backtester) checkMarginCall() {
+	used := bt.broker.GetUsedMargin()
+	equity := bt.broker.GetEquity()
+	if used <= 0 || equity >= used {
+		bt.marginCalled = false
+		return
+	}
+	if bt.marginCalled {
+		return
+	}
+	bt.marginCalled = true
+	bt.Logger().Warn("margin call", "time", bt.market.GetCurrentTime(), "equity", equity, "used_margin", used)
+	bt.recordEvent(Event{Type: EventMarginCall, Reason: fmt.Sprintf("margin level %.1f%%", equity/used*100)})
+}
@@ -0,0 +1,203 @@
+package backtester
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/data"
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventRecorder(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("should number events and return a copy", func(t *testing.T) {
+		recorder := NewEventRecorder()
+		recorder.Record(Event{Type: EventCandle, Time: baseTime})
+		recorder.Record(Event{Type: EventOrderPlaced, Time: baseTime, Seq: 99})
+
+		events := recorder.Events()
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, 1, events[0].Seq)
+			assert.Equal(t, 2, events[1].Seq)
+		}
+		events[0].Type = EventMarginCall
+		assert.Equal(t, EventCandle, recorder.Events()[0].Type)
+
+		recorder.Reset()
+		assert.Empty(t, recorder.Events())
+	})
+
+	t.Run("should round trip through JSON", func(t *testing.T) {
+		recorder := NewEventRecorder()
+		order := models.NewMarketOrder("order-1", "EURUSD", models.Buy, 1000.0)
+		recorder.Record(Event{Type: EventCandle, Time: baseTime, Candle: models.NewCandle(baseTime, 1.10, 1.11, 1.09, 1.10, 1000)})
+		recorder.Record(Event{Type: EventOrderRejected, Time: baseTime, Order: order, Reason: "insufficient balance", Balance: 10000.0, Equity: 10000.0})
+
+		var buf bytes.Buffer
+		assert.NoError(t, recorder.WriteJSON(&buf))
+		events, err := ReadEvents(&buf)
+		assert.NoError(t, err)
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, 1.11, events[0].Candle.High)
+			assert.True(t, baseTime.Equal(events[0].Time))
+			assert.Equal(t, EventOrderRejected, events[1].Type)
+			assert.Equal(t, "order-1", events[1].Order.ID)
+			assert.Equal(t, models.Buy, events[1].Order.Side)
+			assert.Equal(t, "insufficient balance", events[1].Reason)
+			assert.Equal(t, 10000.0, events[1].Equity)
+		}
+	})
+
+	t.Run("should reject incomplete or reordered logs", func(t *testing.T) {
+		_, err := ReadEvents(bytes.NewBufferString(`[{"seq":1,"type":"candle"},{"seq":3,"type":"candle"}]`))
+		assert.Error(t, err)
+		_, err = ReadEvents(bytes.NewBufferString(`{"seq":1}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("should replay in order and stop at handler error", func(t *testing.T) {
+		events := []Event{{Seq: 1, Type: EventCandle}, {Seq: 2, Type: EventOrderPlaced}, {Seq: 3, Type: EventOrderFilled}}
+		stop := errors.New("stop")
+		var replayed []EventType
+		err := Replay(events, func(event Event) error {
+			replayed = append(replayed, event.Type)
+			if event.Type == EventOrderPlaced {
+				return stop
+			}
+			return nil
+		})
+		assert.True(t, errors.Is(err, stop))
+		assert.Equal(t, []EventType{EventCandle, EventOrderPlaced}, replayed)
+	})
+}
+
+func TestBacktester_EventRecorder(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	createEventBacktester := func(t *testing.T, balance float64, closes []float64) (*Backtester, *EventRecorder) {
+		backtester, err := NewBacktesterWithProvider(Config{Broker: BrokerConfig{InitialBalance: balance}}, data.NewInMemoryProvider(candlesFromCloses(closes)))
+		assert.NoError(t, err)
+		recorder := NewEventRecorder()
+		backtester.SetEventRecorder(recorder)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		return backtester, recorder
+	}
+	types := func(events []Event) []EventType {
+		result := make([]EventType, 0, len(events))
+		for _, event := range events {
+			result = append(result, event.Type)
+		}
+		return result
+	}
+
+	t.Run("should record candles, orders and positions in order", func(t *testing.T) {
+		backtester, recorder := createEventBacktester(t, 10000.0, []float64{1.10, 1.05, 1.10, 1.15})
+		_, err := backtester.Run(strategyFunc(func(bt *Backtester) error {
+			switch bt.GetCurrentTime().Sub(baseTime) {
+			case 0:
+				return bt.BuyWithProtection("EURUSD", 1000.0, 1.06, 0)
+			case time.Minute:
+				// 証拠金不足で拒否された注文も記録される
+				assert.Error(t, bt.Buy("EURUSD", 100000000.0))
+			case 2 * time.Minute:
+				return bt.Buy("EURUSD", 1000.0)
+			}
+			return nil
+		}))
+		assert.NoError(t, err)
+
+		events := recorder.Events()
+		assert.Equal(t, []EventType{
+			EventCandle, EventOrderPlaced, EventOrderFilled, EventPositionOpened,
+			EventCandle, EventPositionClosed,
+			EventOrderPlaced, EventOrderRejected,
+			EventCandle, EventOrderPlaced, EventOrderFilled, EventPositionOpened,
+			EventCandle, EventPositionClosed,
+		}, types(events))
+		if len(events) != 14 {
+			return
+		}
+
+		assert.True(t, baseTime.Equal(events[0].Time))
+		assert.Equal(t, 1.10, events[0].Candle.Close)
+		assert.Equal(t, models.Pending, events[1].Order.Status, "placed event keeps the order as submitted")
+		assert.Equal(t, models.Executed, events[2].Order.Status)
+		assert.Equal(t, 1.06, events[3].Position.StopLoss)
+
+		assert.Equal(t, "stop_loss", events[5].Reason)
+		assert.Equal(t, events[3].Position.ID, events[5].Trade.ID)
+		assert.True(t, baseTime.Add(time.Minute).Equal(events[5].Time))
+		assert.InDelta(t, 10000.0+events[5].Trade.PnL, events[5].Balance, 1e-9)
+
+		assert.Contains(t, events[7].Reason, "insufficient balance")
+		assert.Equal(t, "end_of_backtest", events[13].Reason)
+		for i, event := range events {
+			assert.Equal(t, i+1, event.Seq)
+		}
+	})
+
+	t.Run("should record flat time closes", func(t *testing.T) {
+		candles := []models.Candle{
+			*models.NewCandle(time.Date(2024, 1, 1, 20, 59, 0, 0, time.UTC), 1.10, 1.10, 1.10, 1.10, 1000),
+			*models.NewCandle(time.Date(2024, 1, 1, 21, 0, 0, 0, time.UTC), 1.11, 1.11, 1.11, 1.11, 1000),
+		}
+		config := Config{Broker: BrokerConfig{InitialBalance: 10000.0}, Backtest: BacktestConfig{FlatTime: "21:00"}}
+		backtester, err := NewBacktesterWithProvider(config, data.NewInMemoryProvider(candles))
+		assert.NoError(t, err)
+		assert.NoError(t, backtester.Initialize(context.Background()))
+		recorder := NewEventRecorder()
+		backtester.SetEventRecorder(recorder)
+
+		assert.NoError(t, backtester.Buy("EURUSD", 1000.0))
+		backtester.Forward()
+
+		events := recorder.Events()
+		assert.Equal(t, []EventType{
+			EventCandle, EventOrderPlaced, EventOrderFilled, EventPositionOpened, EventCandle, EventPositionClosed,
+		}, types(events), "recording starts from the current candle when set after Initialize")
+		assert.Equal(t, "flat_time", events[len(events)-1].Reason)
+	})
+
+	t.Run("should record a margin call once per breach", func(t *testing.T) {
+		// 証拠金 99 に対し、1.09 で含み損 90 となり有効証拠金 10 が拘束中の証拠金を下回る
+		backtester, recorder := createEventBacktester(t, 100.0, []float64{1.10, 1.09, 1.08, 1.12, 1.09})
+		assert.NoError(t, backtester.Buy("EURUSD", 9000.0))
+		for backtester.Forward() {
+		}
+
+		var calls []Event
+		for _, event := range recorder.Events() {
+			if event.Type == EventMarginCall {
+				calls = append(calls, event)
+			}
+		}
+		if assert.Len(t, calls, 2, "1.08 continues the first breach, 1.09 after recovery is a new one") {
+			assert.True(t, baseTime.Add(time.Minute).Equal(calls[0].Time))
+			assert.InDelta(t, 10.0, calls[0].Equity, 1e-6)
+			assert.Equal(t, "margin level 10.1%", calls[0].Reason)
+			assert.True(t, baseTime.Add(4*time.Minute).Equal(calls[1].Time))
+		}
+	})
+
+	t.Run("should restart the log on reset", func(t *testing.T) {
+		backtester, recorder := createEventBacktester(t, 10000.0, []float64{1.10, 1.11, 1.12})
+		assert.NoError(t, backtester.Buy("EURUSD", 1000.0))
+		backtester.Forward()
+		assert.NoError(t, backtester.Reset())
+
+		events := recorder.Events()
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, EventCandle, events[0].Type)
+			assert.Equal(t, 1, events[0].Seq)
+			assert.True(t, baseTime.Equal(events[0].Time))
+		}
+
+		backtester.SetEventRecorder(nil)
+		backtester.Forward()
+		assert.Len(t, recorder.Events(), 1)
+	})
+}
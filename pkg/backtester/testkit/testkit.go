@@ -0,0 +1,136 @@
+// Package testkit は戦略のバックテスト結果を期待値と照合するテストヘルパーを提供します。
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// DefaultTolerance は Fixture.Tolerance 未指定時の比較の許容誤差です。
+const DefaultTolerance = 1e-6
+
+// StrategyFactory は実行ごとに新しい戦略を生成する関数です。
+type StrategyFactory func() (backtester.Strategy, error)
+
+// Fixture はバックテストに使うデータと口座条件です。
+type Fixture struct {
+	DataPath       string  // ローソク足データ(CSV)のパス
+	InitialBalance float64 // 初期残高
+	Spread         float64 // スプレッド
+	Tolerance      float64 // 浮動小数点項目の許容誤差 (0の場合 DefaultTolerance)
+}
+
+// Run はフィクスチャで戦略をバックテストし、結果が期待値と許容誤差内で一致することを検証します。
+// 不一致の項目は全て期待値・実際値・差分付きで報告されます。検証後の結果を返します。
+func Run(t testing.TB, factory StrategyFactory, fixture Fixture, expected backtester.Result) *backtester.Result {
+	t.Helper()
+	
+	result, err := runBacktest(factory, fixture)
+	if err != nil {
+		t.Fatalf("testkit: %v", err)
+	}
+	
+	tolerance := fixture.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	
+	if diffs := Compare(&expected, result, tolerance); len(diffs) > 0 {
+		t.Errorf("testkit: backtest result mismatch (tolerance %g):\n  %s", tolerance, strings.Join(diffs, "\n  "))
+	}
+	
+	return result
+}
+
+// runBacktest はフィクスチャの設定でバックテストを1回実行します。
+func runBacktest(factory StrategyFactory, fixture Fixture) (*backtester.Result, error) {
+	strategy, err := factory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create strategy: %w", err)
+	}
+	
+	config := backtester.Config{
+		Market: backtester.MarketConfig{
+			DataProvider: models.DataProviderConfig{
+				FilePath: fixture.DataPath,
+				Format:   "csv",
+			},
+		},
+		Broker: backtester.BrokerConfig{
+			InitialBalance: fixture.InitialBalance,
+			Spread:         fixture.Spread,
+		},
+		Visualizer: models.DisabledVisualizerConfig(),
+	}
+	
+	bt, err := backtester.NewBacktester(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backtester: %w", err)
+	}
+	
+	if err := bt.Initialize(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to initialize backtester: %w", err)
+	}
+	defer bt.Stop()
+	
+	return bt.Run(strategy)
+}
+
+// Compare は期待値と実際の結果を比較し、不一致の項目を説明する文字列を返します。
+// 期待値の TradeHistory が空でない場合は、各取引の方向・サイズ・約定価格・損益も比較します。
+// 実行時刻に依存する期間やシャープレシオは比較しません。
+func Compare(expected, actual *backtester.Result, tolerance float64) []string {
+	var diffs []string
+	
+	diffFloat := func(name string, want, got float64) {
+		if math.Abs(got-want) > tolerance {
+			diffs = append(diffs, fmt.Sprintf("%s: got %.6f, want %.6f (diff %+.6f)", name, got, want, got-want))
+		}
+	}
+	diffInt := func(name string, want, got int) {
+		if got != want {
+			diffs = append(diffs, fmt.Sprintf("%s: got %d, want %d", name, got, want))
+		}
+	}
+	
+	diffFloat("InitialBalance", expected.InitialBalance, actual.InitialBalance)
+	diffFloat("FinalBalance", expected.FinalBalance, actual.FinalBalance)
+	diffFloat("TotalPnL", expected.TotalPnL, actual.TotalPnL)
+	diffInt("TotalTrades", expected.TotalTrades, actual.TotalTrades)
+	diffInt("WinningTrades", expected.WinningTrades, actual.WinningTrades)
+	diffInt("LosingTrades", expected.LosingTrades, actual.LosingTrades)
+	diffFloat("GrossProfit", expected.GrossProfit, actual.GrossProfit)
+	diffFloat("GrossLoss", expected.GrossLoss, actual.GrossLoss)
+	diffFloat("LargestWin", expected.LargestWin, actual.LargestWin)
+	diffFloat("LargestLoss", expected.LargestLoss, actual.LargestLoss)
+	diffFloat("MaxDrawdown", expected.MaxDrawdown, actual.MaxDrawdown)
+	diffFloat("ProfitFactor", expected.ProfitFactor, actual.ProfitFactor)
+	
+	if len(expected.TradeHistory) == 0 {
+		return diffs
+	}
+	
+	if len(expected.TradeHistory) != len(actual.TradeHistory) {
+		diffs = append(diffs, fmt.Sprintf("TradeHistory: got %d trades, want %d", len(actual.TradeHistory), len(expected.TradeHistory)))
+		return diffs
+	}
+	
+	for i := range expected.TradeHistory {
+		want, got := expected.TradeHistory[i], actual.TradeHistory[i]
+		if got.Side != want.Side {
+			diffs = append(diffs, fmt.Sprintf("TradeHistory[%d].Side: got %v, want %v", i, got.Side, want.Side))
+		}
+		diffFloat(fmt.Sprintf("TradeHistory[%d].Size", i), want.Size, got.Size)
+		diffFloat(fmt.Sprintf("TradeHistory[%d].EntryPrice", i), want.EntryPrice, got.EntryPrice)
+		diffFloat(fmt.Sprintf("TradeHistory[%d].ExitPrice", i), want.ExitPrice, got.ExitPrice)
+		diffFloat(fmt.Sprintf("TradeHistory[%d].PnL", i), want.PnL, got.PnL)
+	}
+	
+	return diffs
+}
@@ -0,0 +1,98 @@
+package testkit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+	"github.com/RuiHirano/fx-backtesting/pkg/strategy"
+)
+
+// maCrossFixture は MA(2)/MA(4) クロスが2回発生する12本の1分足
+var maCrossFixture = Fixture{
+	DataPath:       "./testdata/ma_cross.csv",
+	InitialBalance: 10000.0,
+	Spread:         0.0,
+}
+
+func newMACross() (backtester.Strategy, error) {
+	return strategy.NewMovingAverageCross("EURUSD", 10000.0, 2, 4)
+}
+
+// maCrossExpected は手計算で検証した期待結果
+//   - 09:04 終値1.1010で買い（短期1.1005 > 長期1.10025）、09:08 終値1.1000で決済（短期1.1010 < 長期1.10175）: 損益 -10
+//   - 09:10 終値1.1040で買い（短期1.1025 > 長期1.10175）、データ終了時に終値1.1060で決済: 損益 +20
+func maCrossExpected() backtester.Result {
+	return backtester.Result{
+		InitialBalance: 10000.0,
+		FinalBalance:   10010.0,
+		TotalPnL:       10.0,
+		TotalTrades:    2,
+		WinningTrades:  1,
+		LosingTrades:   1,
+		GrossProfit:    20.0,
+		GrossLoss:      10.0,
+		LargestWin:     20.0,
+		LargestLoss:    -10.0,
+		MaxDrawdown:    10.0,
+		ProfitFactor:   2.0,
+		TradeHistory: []models.Trade{
+			{Side: models.Buy, Size: 10000.0, EntryPrice: 1.1010, ExitPrice: 1.1000, PnL: -10.0},
+			{Side: models.Buy, Size: 10000.0, EntryPrice: 1.1040, ExitPrice: 1.1060, PnL: 20.0},
+		},
+	}
+}
+
+func TestRun_MovingAverageCross(t *testing.T) {
+	result := Run(t, newMACross, maCrossFixture, maCrossExpected())
+	
+	if result.Duration.Minutes() != 11 {
+		t.Errorf("Expected simulated duration of 11 minutes, got %v", result.Duration)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	expected := maCrossExpected()
+	
+	t.Run("should report no diffs within tolerance", func(t *testing.T) {
+		actual := maCrossExpected()
+		actual.FinalBalance += 1e-9
+		if diffs := Compare(&expected, &actual, DefaultTolerance); len(diffs) != 0 {
+			t.Errorf("Expected no diffs, got %v", diffs)
+		}
+	})
+	
+	t.Run("should describe every mismatched field", func(t *testing.T) {
+		actual := maCrossExpected()
+		actual.FinalBalance = 10020.0
+		actual.TotalTrades = 3
+		actual.TradeHistory[1].PnL = 30.0
+		
+		diffs := Compare(&expected, &actual, DefaultTolerance)
+		if len(diffs) != 3 {
+			t.Fatalf("Expected 3 diffs, got %v", diffs)
+		}
+		
+		joined := strings.Join(diffs, "\n")
+		for _, want := range []string{
+			"FinalBalance: got 10020.000000, want 10010.000000 (diff +10.000000)",
+			"TotalTrades: got 3, want 2",
+			"TradeHistory[1].PnL: got 30.000000, want 20.000000",
+		} {
+			if !strings.Contains(joined, want) {
+				t.Errorf("Expected diff %q in:\n%s", want, joined)
+			}
+		}
+	})
+	
+	t.Run("should report trade count mismatch", func(t *testing.T) {
+		actual := maCrossExpected()
+		actual.TradeHistory = actual.TradeHistory[:1]
+		
+		diffs := Compare(&expected, &actual, DefaultTolerance)
+		if len(diffs) != 1 || !strings.Contains(diffs[0], "got 1 trades, want 2") {
+			t.Errorf("Expected trade count diff, got %v", diffs)
+		}
+	})
+}
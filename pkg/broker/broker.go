@@ -3,22 +3,104 @@ package broker
 import (
 	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/RuiHirano/fx-backtesting/pkg/instruments"
 	"github.com/RuiHirano/fx-backtesting/pkg/market"
 	"github.com/RuiHirano/fx-backtesting/pkg/models"
 )
 
+// ErrIlliquidMarket は出来高が閾値未満のローソク足で注文が拒否された場合のエラーです。
+var ErrIlliquidMarket = errors.New("market is illiquid: candle volume below minimum")
+
+// ErrPositionLimit は約定すると MaxOpenPositions・MaxExposure を超える注文が拒否された場合のエラーです。
+var ErrPositionLimit = errors.New("position limit exceeded")
+
 // Broker はブローカー機能を提供するインターフェースです。
 type Broker interface {
 	PlaceOrder(order *models.Order) error
+	PlaceBracket(entry *models.Order, stopLoss, takeProfit float64) error
+	PreviewOrder(order *models.Order) (OrderPreview, error)
 	CancelOrder(orderID string) error
+	ModifyOrder(orderID string, newPrice, newSize float64) error
+	GetOrder(orderID string) (*models.Order, bool)
 	GetPendingOrders() []*models.Order
 	GetPositions() []*models.Position
+	AveragePrice(symbol string, side models.OrderSide) (float64, float64)
 	GetBalance() float64
+	GetEquity() float64
+	GetUsedMargin() float64
+	GetFreeMargin() float64
 	ClosePosition(positionID string) error
 	UpdatePositions()
 	ProcessPendingOrders()
 	GetTradeHistory() []*models.Trade
+	GetTrade(tradeID string) (*models.Trade, bool)
+	GetPaperSignals() []PaperSignal
+	OnOrderFilled(fn OrderFilledFunc)
+	OnPositionClosed(fn PositionClosedFunc)
+	SetRand(rng *rand.Rand)
+	SetRateSource(source RateSource)
+	ConvertToAccount(symbol string, amount float64) float64
+	State() State
+	RestoreState(state State)
+	Reset()
+}
+
+// OrderFilledFunc は保留注文が約定した時に呼び出される関数です。
+// position は約定で建てたポジションで、ペーパーモードではポジションを建てないため nil になります。
+type OrderFilledFunc func(order *models.Order, position *models.Position)
+
+// CloseReason はポジションが決済された理由です。
+type CloseReason string
+
+const (
+	CloseManual     CloseReason = "manual"      // ClosePosition による決済
+	CloseStopLoss   CloseReason = "stop_loss"   // ストップロス価格に達した
+	CloseTakeProfit CloseReason = "take_profit" // テイクプロフィット価格に達した
+	CloseNetting    CloseReason = "netting"     // ネッティングモードで反対方向の注文と相殺された
+)
+
+// PositionClosedFunc はポジションの全部または一部が決済された時に呼び出される関数です。
+// trade は決済で取引履歴に記録された取引です。
+type PositionClosedFunc func(trade *models.Trade, reason CloseReason)
+
+// State はブローカーの状態のスナップショットです。チェックポイントからの再開に使います。
+type State struct {
+	Balance       float64            `json:"balance"`
+	Positions     []*models.Position `json:"positions"`
+	PendingOrders []*models.Order    `json:"pending_orders"`
+	Orders        []*models.Order    `json:"orders,omitempty"`
+	TradeHistory  []*models.Trade    `json:"trade_history"`
+	PaperSignals  []PaperSignal      `json:"paper_signals"`
+}
+
+// OrderPreview は PreviewOrder による注文の約定見積もりです。
+type OrderPreview struct {
+	Size            float64 `json:"size"`              // 数量の最小・最大・刻みを適用した後の数量
+	ExecutionPrice  float64 `json:"execution_price"`   // スプレッドを適用した約定価格の見積もり
+	Spread          float64 `json:"spread"`            // 適用されるスプレッド（価格単位）
+	Slippage        float64 `json:"slippage,omitempty"` // 見込むスリッページ（価格単位、random モードでは上限値）
+	RequiredMargin  float64 `json:"required_margin"`   // 約定で新たに拘束される証拠金
+	FreeMargin      float64 `json:"free_margin"`       // 現在の余剰証拠金
+	FreeMarginAfter float64 `json:"free_margin_after"` // 約定後の余剰証拠金の見積もり（拒否される場合は FreeMargin - RequiredMargin）
+	Immediate       bool    `json:"immediate"`         // 成行注文として即座に約定するか（指値・逆指値は false）
+}
+
+// PaperSignal はペーパーモードで記録された「約定していたはずの」注文を表します。
+type PaperSignal struct {
+	OrderID          string           `json:"order_id"`
+	Symbol           string           `json:"symbol"`
+	Side             models.OrderSide `json:"side"`
+	Type             models.OrderType `json:"type"`
+	Size             float64          `json:"size"`
+	TheoreticalPrice float64          `json:"theoretical_price"`
+	RequiredMargin   float64          `json:"required_margin"`
+	Timestamp        time.Time        `json:"timestamp"`
 }
 
 // SimpleBroker はBrokerインターフェースの高度な実装です。
@@ -28,19 +110,353 @@ type SimpleBroker struct {
 	balance       float64
 	positions     map[string]*models.Position
 	pendingOrders map[string]*models.Order
+	orders        map[string]*models.Order
 	tradeHistory  []*models.Trade
+	trades        map[string]*models.Trade // 取引IDから取引履歴への索引
+	instruments   *instruments.Registry
+	paperSignals  []PaperSignal
+	orderFilled   OrderFilledFunc
+	positionClosed PositionClosedFunc
+	rng           *rand.Rand
+	rates         RateSource         // 口座通貨への換算レートの取得元
+	lastRates     map[string]float64 // シンボルごとに最後に得られた換算レート
+	ratesMutex    sync.Mutex         // 含み損益の参照と並行して lastRates を更新するための排他制御
 }
 
 // NewSimpleBroker は新しいSimpleBrokerを作成します。
 func NewSimpleBroker(config models.BrokerConfig, market market.Market) Broker {
+	return NewSimpleBrokerWithInstruments(config, market, nil)
+}
+
+// NewSimpleBrokerWithInstruments は銘柄レジストリを参照するSimpleBrokerを作成します。
+// 登録済みシンボルの取引では、サイズをロット数として契約サイズを掛けて損益・証拠金を計算し、
+// SpreadPipsが設定されている場合はpipサイズでスプレッドを価格に換算します。
+func NewSimpleBrokerWithInstruments(config models.BrokerConfig, market market.Market, registry *instruments.Registry) Broker {
+	var rates RateSource
+	if len(config.ConversionRates) > 0 {
+		rates = StaticRates(config.ConversionRates)
+	}
 	return &SimpleBroker{
 		config:        config,
 		market:        market,
 		balance:       config.InitialBalance,
 		positions:     make(map[string]*models.Position),
 		pendingOrders: make(map[string]*models.Order),
+		orders:        make(map[string]*models.Order),
 		tradeHistory:  make([]*models.Trade, 0),
+		trades:        make(map[string]*models.Trade),
+		instruments:   registry,
+		paperSignals:  make([]PaperSignal, 0),
+		rates:         rates,
+		lastRates:     make(map[string]float64),
+	}
+}
+
+// SetRand は random モードのスリッページに使う乱数生成器を設定します。
+// 同じシードの生成器を渡せば同じ約定価格が再現されます。生成器はこのブローカー専用にし、他と共有しないでください。
+func (b *SimpleBroker) SetRand(rng *rand.Rand) {
+	b.rng = rng
+}
+
+// slippage は約定1回分のスリッページを価格単位で返します。
+// random モードでは約定ごとに乱数生成器から0～Slippageの値を引きます（未設定の場合はシード1の生成器を使います）。
+func (b *SimpleBroker) slippage() float64 {
+	if b.config.Slippage <= 0 {
+		return 0
+	}
+	if b.config.SlippageMode != models.SlippageRandom {
+		return b.config.Slippage
+	}
+	if b.rng == nil {
+		b.rng = rand.New(rand.NewSource(1))
+	}
+	return b.rng.Float64() * b.config.Slippage
+}
+
+// spreadFor は指定シンボルに適用するスプレッドを価格単位で返します。
+// 流動性が低くwidenポリシーの場合は拡大したスプレッドを返します。
+func (b *SimpleBroker) spreadFor(symbol string) float64 {
+	spread := b.config.Spread
+	if b.config.SpreadPips > 0 {
+		if instrument, ok := b.instruments.Get(symbol); ok {
+			spread = b.config.SpreadPips * instrument.PipSize
+		}
+	}
+	
+	if b.isIlliquid() && b.config.IlliquidPolicy == models.IlliquidWiden {
+		multiplier := b.config.IlliquidSpreadMultiplier
+		if multiplier == 0 {
+			multiplier = 2.0
+		}
+		spread *= multiplier
+	}
+	
+	return spread
+}
+
+// isIlliquid は現在のローソク足の出来高がMinVolumeToTrade未満かを判定します。
+func (b *SimpleBroker) isIlliquid() bool {
+	if b.config.MinVolumeToTrade <= 0 {
+		return false
+	}
+	candle := b.market.GetCurrentCandle()
+	return candle != nil && candle.Volume < b.config.MinVolumeToTrade
+}
+
+// rejectsIlliquid は現在のローソク足で新規約定を見送るべきかを判定します。
+func (b *SimpleBroker) rejectsIlliquid() bool {
+	return b.isIlliquid() && b.config.IlliquidPolicy != models.IlliquidWiden
+}
+
+// exposure は保有中の全ポジションの建玉金額（数量 × 契約サイズ × 現在価格）の合計を口座通貨で返します。
+func (b *SimpleBroker) exposure() float64 {
+	total := 0.0
+	for _, position := range b.positions {
+		total += b.notional(position.Symbol, position.Size, position.CurrentPrice)
 	}
+	return total
+}
+
+// notional は数量 size を price で評価した建玉金額を口座通貨で返します。
+func (b *SimpleBroker) notional(symbol string, size, price float64) float64 {
+	return b.toAccount(symbol, size*b.contractSizeFor(symbol)*price)
+}
+
+// checkFreeMargin は必要証拠金が余剰証拠金 freeMargin 以下かを検証します。
+func checkFreeMargin(requiredMargin, freeMargin float64) error {
+	if freeMargin < requiredMargin {
+		return fmt.Errorf("insufficient balance: required margin %.2f exceeds free margin %.2f", requiredMargin, freeMargin)
+	}
+	return nil
+}
+
+// checkPositionLimits は注文を price で約定させた場合に MaxOpenPositions・MaxExposure を超えないかを検証します。
+func (b *SimpleBroker) checkPositionLimits(order *models.Order, price float64) error {
+	return b.checkLimits(order.ID, 1, b.notional(order.Symbol, order.Size, price))
+}
+
+// checkLimits はポジションが addedPositions 本、建玉金額が addedExposure 増える場合に上限を超えないかを検証します。
+func (b *SimpleBroker) checkLimits(orderID string, addedPositions int, addedExposure float64) error {
+	if addedPositions > 0 && b.config.MaxOpenPositions > 0 && len(b.positions)+addedPositions > b.config.MaxOpenPositions {
+		return fmt.Errorf("%w: order %s would exceed max open positions %d (open: %d)",
+			ErrPositionLimit, orderID, b.config.MaxOpenPositions, len(b.positions))
+	}
+	
+	if b.config.MaxExposure > 0 && addedExposure > 0 {
+		current := b.exposure()
+		if current+addedExposure > b.config.MaxExposure {
+			return fmt.Errorf("%w: order %s would raise exposure to %.2f, above max %.2f (current: %.2f)",
+				ErrPositionLimit, orderID, current+addedExposure, b.config.MaxExposure, current)
+		}
+	}
+	
+	return nil
+}
+
+// nettingPosition はネッティングモードで注文を合算する、指定シンボルの既存ポジションを返します。
+// ヘッジングモードの場合やポジションがない場合は nil です。
+func (b *SimpleBroker) nettingPosition(symbol string) *models.Position {
+	if b.config.AccountMode != models.AccountNetting {
+		return nil
+	}
+	for _, position := range b.GetPositions() {
+		if position.Symbol == symbol {
+			return position
+		}
+	}
+	return nil
+}
+
+// netOrder はネッティングモードで注文を既存ポジションに合算し、合算後のポジションを返します。
+// 同じ方向の注文は数量を加えて平均建値（数量加重）を更新し、反対方向の注文は注文数量分を executionPrice で決済します。
+// 注文数量がポジションを上回る場合は全て決済した上で、残りの数量で反対方向のポジションを建てます。
+// ちょうど決済された場合は nil を返します。証拠金・上限の検証に失敗した場合は何も変更しません。
+func (b *SimpleBroker) netOrder(position *models.Position, order *models.Order, executionPrice, currentPrice, spread, slip float64) (*models.Position, error) {
+	contractSize := b.contractSizeFor(order.Symbol)
+	
+	// 同じ方向: ポジションを積み増す
+	if order.Side == position.Side {
+		margin := b.marginFor(order.Symbol, order.Size, executionPrice)
+		if err := checkFreeMargin(margin, b.GetFreeMargin()); err != nil {
+			return nil, err
+		}
+		if err := b.checkLimits(order.ID, 0, b.notional(order.Symbol, order.Size, executionPrice)); err != nil {
+			return nil, err
+		}
+		
+		existingMargin := b.positionMargin(position)
+		total := position.Size + order.Size
+		position.EntryPrice = (position.EntryPrice*position.Size + executionPrice*order.Size) / total
+		position.EntrySpread = (position.EntrySpread*position.Size + spread*order.Size) / total
+		position.EntrySlippage = (position.EntrySlippage*position.Size + slip*order.Size) / total
+		position.Size = total
+		position.Margin = existingMargin + margin
+		position.CurrentPrice = currentPrice
+		if order.StopLoss > 0 {
+			position.StopLoss = order.StopLoss
+		}
+		if order.TakeProfit > 0 {
+			position.TakeProfit = order.TakeProfit
+		}
+		b.balance -= margin
+		return position, nil
+	}
+	
+	// 反対方向: 注文数量分を決済し、上回る分は反対方向に建てる
+	closing := order.Size
+	if closing > position.Size {
+		closing = position.Size
+	}
+	remaining := order.Size - closing
+	pnl := b.toAccount(order.Symbol, models.CalculatePnL(position.Side, closing*contractSize, position.EntryPrice, executionPrice))
+	released := b.positionMargin(position) * closing / position.Size
+	
+	var margin float64
+	if remaining > 0 {
+		// 決済で戻る証拠金と確定する損益を含めて、反転後のポジションの証拠金を確保できるか先に確認する
+		margin = b.marginFor(order.Symbol, remaining, executionPrice)
+		freeMargin := b.GetFreeMargin() - b.unrealizedPnL(position) + released + pnl
+		if err := checkFreeMargin(margin, freeMargin); err != nil {
+			return nil, err
+		}
+		addedExposure := b.notional(order.Symbol, remaining, executionPrice) - b.notional(order.Symbol, position.Size, position.CurrentPrice)
+		if err := b.checkLimits(order.ID, 0, addedExposure); err != nil {
+			return nil, err
+		}
+	}
+	
+	b.closePortion(position, order.ID, closing, executionPrice, spread, slip, pnl, released, CloseNetting)
+	if remaining == 0 {
+		if position.Size == 0 {
+			return nil, nil
+		}
+		position.CurrentPrice = currentPrice
+		return position, nil
+	}
+	
+	flipped := &models.Position{
+		ID:           fmt.Sprintf("pos-%s", order.ID),
+		Symbol:       order.Symbol,
+		Side:         order.Side,
+		Size:         remaining,
+		EntryPrice:   executionPrice,
+		CurrentPrice: currentPrice,
+		OpenTime:     b.market.GetCurrentTime(),
+		StopLoss:     order.StopLoss,
+		TakeProfit:   order.TakeProfit,
+		EntrySpread:  spread,
+		EntrySlippage: slip,
+		Margin:       margin,
+	}
+	b.positions[flipped.ID] = flipped
+	b.balance -= margin
+	return flipped, nil
+}
+
+// closePortion はポジションのうち size 分を closePrice で決済し、取引履歴に記録します。
+// 全て決済した場合はポジションを削除します。一部決済の取引IDは "ポジションID-注文ID" です。
+func (b *SimpleBroker) closePortion(position *models.Position, orderID string, size, closePrice, spread, slip, pnl, released float64, reason CloseReason) {
+	closed := *position
+	closed.Size = size
+	trade := models.NewTradeFromPosition(&closed, closePrice, pnl, b.market.GetCurrentTime())
+	trade.SpreadCost = b.toAccount(position.Symbol, (position.EntrySpread+spread)*size*b.contractSizeFor(position.Symbol))
+	trade.SlippageCost = b.toAccount(position.Symbol, (position.EntrySlippage+slip)*size*b.contractSizeFor(position.Symbol))
+	b.describeTrade(trade)
+	
+	b.balance += released + pnl
+	position.Margin = b.positionMargin(position) - released
+	position.Size -= size
+	if position.Size <= 0 {
+		position.Size = 0
+		delete(b.positions, position.ID)
+	} else {
+		trade.ID = fmt.Sprintf("%s-%s", position.ID, orderID)
+	}
+	b.recordTrade(trade)
+	b.notifyPositionClosed(trade, reason)
+}
+
+// positionMargin はポジションが拘束している証拠金を返します。
+// 証拠金を記録していない旧形式の状態から復元したポジションはエントリー価格から再計算します。
+func (b *SimpleBroker) positionMargin(position *models.Position) float64 {
+	if position.Margin == 0 {
+		return b.marginFor(position.Symbol, position.Size, position.EntryPrice)
+	}
+	return position.Margin
+}
+
+// unrealizedPnL はポジションの現在価格での含み損益を契約サイズを考慮して口座通貨で返します。
+func (b *SimpleBroker) unrealizedPnL(position *models.Position) float64 {
+	units := position.Size * b.contractSizeFor(position.Symbol)
+	return b.toAccount(position.Symbol, models.CalculatePnL(position.Side, units, position.EntryPrice, position.CurrentPrice))
+}
+
+// marginFor は数量 size の注文を price で約定させる場合の必要証拠金（建玉金額 ÷ レバレッジ）を口座通貨で返します。
+// レバレッジは BrokerConfig.Leverage（未指定の場合は1:100）です。
+// 売りも買いと同じく建玉金額に対する証拠金のみを拘束し、売却代金の受け取りや借入は扱いません。
+func (b *SimpleBroker) marginFor(symbol string, size, price float64) float64 {
+	return b.notional(symbol, size, price) / b.config.EffectiveLeverage()
+}
+
+// contractSizeFor は指定シンボルの契約サイズを返します。未登録の場合は1です。
+func (b *SimpleBroker) contractSizeFor(symbol string) float64 {
+	if instrument, ok := b.instruments.Get(symbol); ok {
+		return instrument.ContractSize
+	}
+	return 1.0
+}
+
+// describeTrade は取引に銘柄のpipサイズ・契約サイズと、損益の口座通貨への換算レートを記録します。
+// RecostTrades はこれらを使って、ブローカーと同じ計算で損益を再計算します。
+func (b *SimpleBroker) describeTrade(trade *models.Trade) {
+	trade.PipSize = b.pipSizeFor(trade.Symbol)
+	if instrument, ok := b.instruments.Get(trade.Symbol); ok {
+		trade.ContractSize = instrument.ContractSize
+	}
+	if b.config.AccountCurrency != "" {
+		trade.ConversionRate = b.accountRate(trade.Symbol)
+	}
+}
+
+// pipSizeFor は指定シンボルのpipサイズを返します。未登録の場合は0です。
+func (b *SimpleBroker) pipSizeFor(symbol string) float64 {
+	if instrument, ok := b.instruments.Get(symbol); ok {
+		return instrument.PipSize
+	}
+	return 0
+}
+
+// lotConfigFor は指定シンボルの注文数量に適用する制約を返します。
+// 銘柄レジストリに最小サイズ・サイズ刻みが登録されている場合は、それぞれ MinLot・LotStep の代わりに使います。
+func (b *SimpleBroker) lotConfigFor(symbol string) models.BrokerConfig {
+	config := b.config
+	if instrument, ok := b.instruments.Get(symbol); ok {
+		if instrument.MinSize > 0 {
+			config.MinLot = instrument.MinSize
+		}
+		if instrument.SizeStep > 0 {
+			config.LotStep = instrument.SizeStep
+		}
+	}
+	return config
+}
+
+// normalizeOrder は注文数量に lotConfigFor の制約を適用し、銘柄レジストリにティックサイズが登録されている場合は
+// 指値・逆指値・ストップロス・テイクプロフィットの価格をティックサイズの倍数に丸めます。
+func (b *SimpleBroker) normalizeOrder(order *models.Order) error {
+	size, err := b.lotConfigFor(order.Symbol).NormalizeSize(order.Size)
+	if err != nil {
+		return err
+	}
+	order.Size = size
+	
+	if instrument, ok := b.instruments.Get(order.Symbol); ok {
+		order.LimitPrice = instrument.RoundPrice(order.LimitPrice)
+		order.StopPrice = instrument.RoundPrice(order.StopPrice)
+		order.StopLoss = instrument.RoundPrice(order.StopLoss)
+		order.TakeProfit = instrument.RoundPrice(order.TakeProfit)
+	}
+	return nil
 }
 
 // PlaceOrder は注文を受け付け、種別に応じて即座に実行または保留状態にします。
@@ -50,15 +466,170 @@ func (b *SimpleBroker) PlaceOrder(order *models.Order) error {
 		return err
 	}
 
+	// 数量の最小・最大・刻みと価格の刻みを適用（LotPolicy が round の場合は order.Size を丸めた数量に置き換える）
+	if err := b.normalizeOrder(order); err != nil {
+		return err
+	}
+
+	// 口座通貨へ換算できないシンボルの注文は受け付けない
+	if err := b.checkConversionRate(order.Symbol); err != nil {
+		return err
+	}
+
 	// 注文種別に応じた処理
+	var err error
 	switch order.Type {
 	case models.MarketOrder:
-		return b.executeMarketOrder(order)
+		err = b.executeMarketOrder(order)
 	case models.LimitOrder, models.StopOrder:
-		return b.addPendingOrder(order)
+		err = b.addPendingOrder(order)
 	default:
-		return fmt.Errorf("unsupported order type: %v", order.Type)
+		err = fmt.Errorf("unsupported order type: %v", order.Type)
+	}
+	if err != nil {
+		return err
 	}
+
+	// 受け付けた注文は約定・キャンセル後も GetOrder で参照できるよう保持する
+	b.orders[order.ID] = order
+	return nil
+}
+
+// PlaceBracket はエントリー注文にストップロス・テイクプロフィットを付けて発注します。
+// 保護価格はエントリー注文の StopLoss・TakeProfit として約定時にポジションへ引き継がれ、
+// どちらか一方に達するとポジションごと決済されるため、もう一方も同時に無効になります（OCO）。
+// 約定前にエントリー注文がキャンセルされた場合、保護価格は何も作られません。
+// 保護価格は買いなら stopLoss < 基準価格 < takeProfit、売りならその逆である必要があります。
+// 基準価格は指値・逆指値注文ではその価格、成行注文では現在価格です。
+func (b *SimpleBroker) PlaceBracket(entry *models.Order, stopLoss, takeProfit float64) error {
+	if stopLoss <= 0 || takeProfit <= 0 {
+		return errors.New("bracket stop loss and take profit must be positive")
+	}
+
+	reference := entry.LimitPrice
+	switch entry.Type {
+	case models.MarketOrder:
+		reference = b.market.GetCurrentPrice()
+	case models.StopOrder:
+		reference = entry.StopPrice
+	}
+	if reference > 0 {
+		if entry.Side == models.Buy && !(stopLoss < reference && reference < takeProfit) {
+			return fmt.Errorf("buy bracket requires stop loss %.5f < entry %.5f < take profit %.5f", stopLoss, reference, takeProfit)
+		}
+		if entry.Side == models.Sell && !(takeProfit < reference && reference < stopLoss) {
+			return fmt.Errorf("sell bracket requires take profit %.5f < entry %.5f < stop loss %.5f", takeProfit, reference, stopLoss)
+		}
+	}
+
+	// 発注に失敗した場合はエントリー注文を元の状態に戻す
+	previousStopLoss, previousTakeProfit := entry.StopLoss, entry.TakeProfit
+	entry.StopLoss, entry.TakeProfit = stopLoss, takeProfit
+	if err := b.PlaceOrder(entry); err != nil {
+		entry.StopLoss, entry.TakeProfit = previousStopLoss, previousTakeProfit
+		return err
+	}
+	return nil
+}
+
+// PreviewOrder は注文を発注した場合の約定価格・必要証拠金・約定後の余剰証拠金を、副作用なしに見積もります。
+// 成行注文は現在価格で約定した場合を、指値・逆指値注文は指定価格に達した時点で（保有ポジションもその価格で評価して）約定した場合を見積もります。
+// 発注・約定が拒否される場合は PlaceOrder（保留注文では約定時）と同じエラーを、計算できた見積もりと共に返します。
+// order 自体は変更せず、注文履歴・ポジション・残高・ペーパーシグナルにも何も記録しません。
+func (b *SimpleBroker) PreviewOrder(order *models.Order) (OrderPreview, error) {
+	preview := OrderPreview{FreeMargin: b.GetFreeMargin()}
+	if order == nil {
+		return preview, errors.New("order is nil")
+	}
+	
+	trial := *order
+	if err := trial.Validate(); err != nil {
+		return preview, err
+	}
+	if err := b.normalizeOrder(&trial); err != nil {
+		return preview, err
+	}
+	size := trial.Size
+	preview.Size = size
+	
+	// 基準価格にスプレッドとスリッページを適用して約定価格を見積もる（random モードのスリッページは上限値で見込む）
+	reference := b.market.GetCurrentPrice()
+	switch trial.Type {
+	case models.MarketOrder:
+		preview.Immediate = true
+	case models.LimitOrder:
+		reference = trial.LimitPrice
+	case models.StopOrder:
+		reference = trial.StopPrice
+	default:
+		return preview, fmt.Errorf("unsupported order type: %v", trial.Type)
+	}
+	preview.Spread = b.spreadFor(trial.Symbol)
+	preview.Slippage = b.config.Slippage
+	preview.ExecutionPrice = reference + preview.Spread + preview.Slippage
+	if trial.Side == models.Sell {
+		preview.ExecutionPrice = reference - preview.Spread - preview.Slippage
+	}
+	
+	// ネッティングで反対方向の注文は、ポジションを上回る数量分だけ証拠金を拘束する
+	marginSize := size
+	if existing := b.nettingPosition(trial.Symbol); existing != nil && existing.Side != trial.Side {
+		marginSize = size - existing.Size
+		if marginSize < 0 {
+			marginSize = 0
+		}
+	}
+	preview.RequiredMargin = b.marginFor(trial.Symbol, marginSize, preview.ExecutionPrice)
+	preview.FreeMarginAfter = preview.FreeMargin - preview.RequiredMargin
+	
+	// 残高とポジションだけを写した作業用のブローカーで実際に約定させる
+	sim := b.previewBroker()
+	var err error
+	if trial.Type == models.MarketOrder {
+		err = sim.executeMarketOrder(&trial)
+	} else {
+		for _, position := range sim.positions {
+			position.CurrentPrice = reference
+		}
+		_, err = sim.executePendingOrder(&trial, reference, reference)
+	}
+	if err != nil {
+		return preview, err
+	}
+	
+	preview.FreeMarginAfter = sim.GetFreeMargin()
+	return preview, nil
+}
+
+// previewBroker は残高とポジションのコピーだけを持つ、PreviewOrder 用の作業用ブローカーを返します。
+// 乱数生成器を進めないよう、スリッページは常に上限値（fixed モード）で扱います。
+func (b *SimpleBroker) previewBroker() *SimpleBroker {
+	config := b.config
+	config.SlippageMode = models.SlippageFixed
+	sim := &SimpleBroker{
+		config:        config,
+		market:        b.market,
+		balance:       b.balance,
+		positions:     make(map[string]*models.Position, len(b.positions)),
+		pendingOrders: make(map[string]*models.Order),
+		orders:        make(map[string]*models.Order),
+		tradeHistory:  make([]*models.Trade, 0),
+		trades:        make(map[string]*models.Trade),
+		instruments:   b.instruments,
+		paperSignals:  make([]PaperSignal, 0),
+		rates:         b.rates,
+		lastRates:     make(map[string]float64, len(b.lastRates)),
+	}
+	b.ratesMutex.Lock()
+	for symbol, rate := range b.lastRates {
+		sim.lastRates[symbol] = rate
+	}
+	b.ratesMutex.Unlock()
+	for id, position := range b.positions {
+		copied := *position
+		sim.positions[id] = &copied
+	}
+	return sim
 }
 
 // executeMarketOrder は成行注文を即座に実行します。
@@ -69,20 +640,47 @@ func (b *SimpleBroker) executeMarketOrder(order *models.Order) error {
 		return fmt.Errorf("invalid price for symbol %s", order.Symbol)
 	}
 
-	// スプレッドを適用した実行価格を計算
+	// 流動性が低い場合は約定させない
+	if b.rejectsIlliquid() {
+		return ErrIlliquidMarket
+	}
+
+	// スプレッドとスリッページを適用した実行価格を計算
+	spread := b.spreadFor(order.Symbol)
+	slip := b.slippage()
 	var executionPrice float64
 	if order.Side == models.Buy {
-		executionPrice = currentPrice + b.config.Spread // Ask価格
+		executionPrice = currentPrice + spread + slip // Ask価格
 	} else {
-		executionPrice = currentPrice - b.config.Spread // Bid価格
+		executionPrice = currentPrice - spread - slip // Bid価格
+	}
+
+	// 必要証拠金を計算（売りも買いと同じ）
+	requiredMargin := b.marginFor(order.Symbol, order.Size, executionPrice)
+
+	// ペーパーモードでは記録のみ行う
+	if b.config.PaperMode {
+		b.recordPaperSignal(order, executionPrice, requiredMargin)
+		return nil
+	}
+
+	// ネッティングでは同じシンボルのポジションに合算する
+	if existing := b.nettingPosition(order.Symbol); existing != nil {
+		if _, err := b.netOrder(existing, order, executionPrice, currentPrice, spread, slip); err != nil {
+			return err
+		}
+		order.Execute(executionPrice)
+		return nil
 	}
 
-	// 必要証拠金を計算（1:100レバレッジを想定）
-	requiredMargin := (order.Size * executionPrice) / 100.0
+	// 余剰証拠金チェック（既存ポジションの拘束証拠金と含み損益を考慮する）
+	if err := checkFreeMargin(requiredMargin, b.GetFreeMargin()); err != nil {
+		return err
+	}
 
-	// 残高チェック
-	if b.balance < requiredMargin {
-		return errors.New("insufficient balance")
+	// ポジション数・建玉金額の上限チェック
+	if err := b.checkPositionLimits(order, executionPrice); err != nil {
+		return err
 	}
 
 	// ポジション作成
@@ -94,6 +692,11 @@ func (b *SimpleBroker) executeMarketOrder(order *models.Order) error {
 		EntryPrice:   executionPrice,
 		CurrentPrice: currentPrice,
 		OpenTime:     b.market.GetCurrentTime(),
+		StopLoss:     order.StopLoss,
+		TakeProfit:   order.TakeProfit,
+		EntrySpread:  spread,
+		EntrySlippage: slip,
+		Margin:       requiredMargin,
 	}
 
 	// ポジション保存
@@ -139,12 +742,65 @@ func (b *SimpleBroker) CancelOrder(orderID string) error {
 	return nil
 }
 
+// ModifyOrder は保留中の指値・逆指値注文の価格（指値注文は LimitPrice、逆指値注文は StopPrice）と数量を変更します。
+// 新しい値は PlaceOrder と同じ検証と数量の制約を通り、失敗した場合は注文を変更しません。
+// 作成時刻は変えないため、保留注文の処理順（GetPendingOrders の順）は変更前のまま維持されます。
+func (b *SimpleBroker) ModifyOrder(orderID string, newPrice, newSize float64) error {
+	order, exists := b.pendingOrders[orderID]
+	if !exists {
+		if order, known := b.orders[orderID]; known {
+			if order.IsExecuted() {
+				return fmt.Errorf("order already executed: %s", orderID)
+			}
+			if order.IsCancelled() {
+				return fmt.Errorf("order already cancelled: %s", orderID)
+			}
+		}
+		return fmt.Errorf("order not found: %s", orderID)
+	}
+
+	// 変更後の注文を検証してから反映する
+	modified := *order
+	modified.Size = newSize
+	switch modified.Type {
+	case models.LimitOrder:
+		modified.LimitPrice = newPrice
+	case models.StopOrder:
+		modified.StopPrice = newPrice
+	}
+	if err := modified.Validate(); err != nil {
+		return err
+	}
+	if err := b.normalizeOrder(&modified); err != nil {
+		return err
+	}
+
+	order.Size = modified.Size
+	order.LimitPrice = modified.LimitPrice
+	order.StopPrice = modified.StopPrice
+	return nil
+}
+
+// GetOrder は受け付けた注文を ID で取得します。保留中に加え、約定済み・キャンセル済みの注文も返します。
+// 拒否された注文は保持されないため、見つからない場合は false を返します。
+func (b *SimpleBroker) GetOrder(orderID string) (*models.Order, bool) {
+	order, exists := b.orders[orderID]
+	return order, exists
+}
+
 // GetPendingOrders は現在保留中の全注文を取得します。
 func (b *SimpleBroker) GetPendingOrders() []*models.Order {
 	orders := make([]*models.Order, 0, len(b.pendingOrders))
 	for _, order := range b.pendingOrders {
 		orders = append(orders, order)
 	}
+	// 実行ごとに結果が変わらないよう、作成時刻・ID順に並べる
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+		}
+		return orders[i].ID < orders[j].ID
+	})
 	return orders
 }
 
@@ -154,14 +810,65 @@ func (b *SimpleBroker) GetPositions() []*models.Position {
 	for _, position := range b.positions {
 		positions = append(positions, position)
 	}
+	// 実行ごとに決済順が変わらないよう、建玉時刻・ID順に並べる
+	sort.Slice(positions, func(i, j int) bool {
+		if !positions[i].OpenTime.Equal(positions[j].OpenTime) {
+			return positions[i].OpenTime.Before(positions[j].OpenTime)
+		}
+		return positions[i].ID < positions[j].ID
+	})
 	return positions
 }
 
+// AveragePrice は指定したシンボル・売買方向の保有ポジションについて、サイズ加重平均のエントリー価格と合計サイズを返します。
+// 該当するポジションが無い場合は 0, 0 を返します。
+func (b *SimpleBroker) AveragePrice(symbol string, side models.OrderSide) (float64, float64) {
+	totalSize := 0.0
+	notional := 0.0
+	for _, position := range b.positions {
+		if position.Symbol != symbol || position.Side != side {
+			continue
+		}
+		totalSize += position.Size
+		notional += position.EntryPrice * position.Size
+	}
+	
+	if totalSize == 0 {
+		return 0, 0
+	}
+	return notional / totalSize, totalSize
+}
+
 // GetBalance は現在の残高を取得します。
 func (b *SimpleBroker) GetBalance() float64 {
 	return b.balance
 }
 
+// GetEquity は有効証拠金（残高 + 拘束中の証拠金 + 含み損益）を返します。
+// 残高からは建玉の証拠金が差し引かれているため、拘束中の証拠金を足し戻して口座の評価額とします。
+func (b *SimpleBroker) GetEquity() float64 {
+	return b.GetFreeMargin() + b.GetUsedMargin()
+}
+
+// GetUsedMargin は保有中の全ポジションが拘束している証拠金の合計を返します。
+func (b *SimpleBroker) GetUsedMargin() float64 {
+	total := 0.0
+	for _, position := range b.positions {
+		total += b.positionMargin(position)
+	}
+	return total
+}
+
+// GetFreeMargin は新規注文に使える余剰証拠金（有効証拠金 - 拘束中の証拠金）を返します。
+// 含み損が出ている間は残高より小さくなり、新規注文の証拠金はこの額を超えられません。
+func (b *SimpleBroker) GetFreeMargin() float64 {
+	free := b.balance
+	for _, position := range b.positions {
+		free += b.unrealizedPnL(position)
+	}
+	return free
+}
+
 // ClosePosition はポジションをクローズします。
 func (b *SimpleBroker) ClosePosition(positionID string) error {
 	position, exists := b.positions[positionID]
@@ -175,35 +882,54 @@ func (b *SimpleBroker) ClosePosition(positionID string) error {
 		return fmt.Errorf("invalid price for symbol %s", position.Symbol)
 	}
 
-	// スプレッドを適用したクローズ価格を計算
+	b.closePositionAt(position, currentPrice, CloseManual)
+	return nil
+}
+
+// closePositionAt は price にスプレッドとスリッページを適用した価格でポジションを決済します。
+func (b *SimpleBroker) closePositionAt(position *models.Position, price float64, reason CloseReason) {
+	// スプレッドとスリッページを適用したクローズ価格を計算
+	spread := b.spreadFor(position.Symbol)
+	slip := b.slippage()
 	var closePrice float64
 	if position.Side == models.Buy {
-		closePrice = currentPrice - b.config.Spread // Bid価格で売却
+		closePrice = price - spread - slip // Bid価格で売却
 	} else {
-		closePrice = currentPrice + b.config.Spread // Ask価格で買戻し
+		closePrice = price + spread + slip // Ask価格で買戻し
 	}
 
-	// 損益計算
-	var pnl float64
-	if position.Side == models.Buy {
-		pnl = (closePrice - position.EntryPrice) * position.Size
-	} else {
-		pnl = (position.EntryPrice - closePrice) * position.Size
-	}
+	// 損益計算（売りはエントリー価格より安く買い戻すと利益、口座通貨に換算する）
+	units := position.Size * b.contractSizeFor(position.Symbol)
+	pnl := b.toAccount(position.Symbol, models.CalculatePnL(position.Side, units, position.EntryPrice, closePrice))
 
-	// 残高更新（証拠金を返却し、損益を反映）
-	requiredMargin := (position.EntryPrice * position.Size) / 100.0
-	b.balance += requiredMargin // 証拠金返却
-	b.balance += pnl            // 損益反映
+	// 残高更新（エントリー時に拘束した証拠金をそのまま返却し、損益を反映）
+	margin := b.positionMargin(position)
+	b.balance += margin // 証拠金返却
+	b.balance += pnl    // 損益反映
 
 	// 取引履歴を作成して保存
 	trade := models.NewTradeFromPosition(position, closePrice, pnl, b.market.GetCurrentTime())
-	b.tradeHistory = append(b.tradeHistory, trade)
+	trade.SpreadCost = b.toAccount(position.Symbol, (position.EntrySpread+spread)*units)
+	trade.SlippageCost = b.toAccount(position.Symbol, (position.EntrySlippage+slip)*units)
+	b.describeTrade(trade)
+	b.recordTrade(trade)
 
 	// ポジション削除
-	delete(b.positions, positionID)
+	delete(b.positions, position.ID)
+	b.notifyPositionClosed(trade, reason)
+}
 
-	return nil
+// notifyPositionClosed は OnPositionClosed で登録された関数に決済を通知します。
+func (b *SimpleBroker) notifyPositionClosed(trade *models.Trade, reason CloseReason) {
+	if b.positionClosed != nil {
+		b.positionClosed(trade, reason)
+	}
+}
+
+// recordTrade は取引を取引履歴に追加し、IDで引けるよう索引に登録します。
+func (b *SimpleBroker) recordTrade(trade *models.Trade) {
+	b.tradeHistory = append(b.tradeHistory, trade)
+	b.trades[trade.ID] = trade
 }
 
 // GetTradeHistory は取引履歴を取得します。
@@ -211,7 +937,123 @@ func (b *SimpleBroker) GetTradeHistory() []*models.Trade {
 	return b.tradeHistory
 }
 
-// UpdatePositions は全ポジションの現在価格を更新し、保留注文も処理します。
+// GetTrade は指定IDの取引を取引履歴から取得します。
+func (b *SimpleBroker) GetTrade(tradeID string) (*models.Trade, bool) {
+	trade, exists := b.trades[tradeID]
+	return trade, exists
+}
+
+// Reset は残高を初期残高に戻し、ポジション・保留注文・注文履歴・取引履歴・ペーパーシグナルを全て破棄します。
+func (b *SimpleBroker) Reset() {
+	b.balance = b.config.InitialBalance
+	b.positions = make(map[string]*models.Position)
+	b.pendingOrders = make(map[string]*models.Order)
+	b.orders = make(map[string]*models.Order)
+	b.tradeHistory = make([]*models.Trade, 0)
+	b.trades = make(map[string]*models.Trade)
+	b.paperSignals = make([]PaperSignal, 0)
+}
+
+// State は残高・ポジション・保留注文・注文履歴・取引履歴・ペーパーシグナルのコピーを返します。
+// ポジションと保留注文は GetPositions・GetPendingOrders と同じ順に並び、注文履歴には保留中の注文は含みません。
+func (b *SimpleBroker) State() State {
+	state := State{
+		Balance:       b.balance,
+		Positions:     make([]*models.Position, 0, len(b.positions)),
+		PendingOrders: make([]*models.Order, 0, len(b.pendingOrders)),
+		Orders:        make([]*models.Order, 0, len(b.orders)),
+		TradeHistory:  make([]*models.Trade, 0, len(b.tradeHistory)),
+		PaperSignals:  append([]PaperSignal{}, b.paperSignals...),
+	}
+	for _, position := range b.GetPositions() {
+		copied := *position
+		state.Positions = append(state.Positions, &copied)
+	}
+	for _, order := range b.GetPendingOrders() {
+		copied := *order
+		state.PendingOrders = append(state.PendingOrders, &copied)
+	}
+	for _, order := range b.orders {
+		if order.IsPending() {
+			continue
+		}
+		copied := *order
+		state.Orders = append(state.Orders, &copied)
+	}
+	sort.Slice(state.Orders, func(i, j int) bool {
+		if !state.Orders[i].CreatedAt.Equal(state.Orders[j].CreatedAt) {
+			return state.Orders[i].CreatedAt.Before(state.Orders[j].CreatedAt)
+		}
+		return state.Orders[i].ID < state.Orders[j].ID
+	})
+	for _, trade := range b.tradeHistory {
+		copied := *trade
+		state.TradeHistory = append(state.TradeHistory, &copied)
+	}
+	return state
+}
+
+// RestoreState は State で取得した状態に置き換えます。現在のポジション・保留注文などは破棄されます。
+func (b *SimpleBroker) RestoreState(state State) {
+	b.Reset()
+	b.balance = state.Balance
+	for _, position := range state.Positions {
+		copied := *position
+		b.positions[copied.ID] = &copied
+	}
+	for _, order := range state.Orders {
+		copied := *order
+		b.orders[copied.ID] = &copied
+	}
+	for _, order := range state.PendingOrders {
+		copied := *order
+		b.pendingOrders[copied.ID] = &copied
+		b.orders[copied.ID] = &copied
+	}
+	for _, trade := range state.TradeHistory {
+		copied := *trade
+		b.recordTrade(&copied)
+	}
+	b.paperSignals = append(b.paperSignals, state.PaperSignals...)
+}
+
+// GetPaperSignals はペーパーモードで記録された注文の一覧を取得します。
+func (b *SimpleBroker) GetPaperSignals() []PaperSignal {
+	return b.paperSignals
+}
+
+// recordPaperSignal は注文を理論約定価格で記録し、注文を約定状態にします。
+// 残高・ポジションは変更しません。
+func (b *SimpleBroker) recordPaperSignal(order *models.Order, executionPrice, requiredMargin float64) {
+	b.paperSignals = append(b.paperSignals, PaperSignal{
+		OrderID:          order.ID,
+		Symbol:           order.Symbol,
+		Side:             order.Side,
+		Type:             order.Type,
+		Size:             order.Size,
+		TheoreticalPrice: executionPrice,
+		RequiredMargin:   requiredMargin,
+		Timestamp:        b.market.GetCurrentTime(),
+	})
+	order.Execute(executionPrice)
+}
+
+// OnOrderFilled は保留注文（指値・逆指値）の約定時に呼び出す関数を登録します。nil で登録を解除します。
+// 関数は ProcessPendingOrders の中で、注文が保留リストから外れた後に呼び出されるため、
+// 関数内で新たな注文を発注できます（新たな保留注文の約定判定は次回の ProcessPendingOrders から）。
+// 登録は Reset・RestoreState 後も維持されます。
+func (b *SimpleBroker) OnOrderFilled(fn OrderFilledFunc) {
+	b.orderFilled = fn
+}
+
+// OnPositionClosed はポジションの決済時（一部決済を含む）に呼び出す関数を登録します。nil で登録を解除します。
+// 関数は取引履歴への記録とポジションの削除の後に、決済理由とともに呼び出されます。
+// 登録は Reset・RestoreState 後も維持されます。
+func (b *SimpleBroker) OnPositionClosed(fn PositionClosedFunc) {
+	b.positionClosed = fn
+}
+
+// UpdatePositions は全ポジションの現在価格を更新し、ストップロス・テイクプロフィットの決済と保留注文の処理も行います。
 func (b *SimpleBroker) UpdatePositions() {
 	// ポジション価格更新
 	for _, position := range b.positions {
@@ -221,15 +1063,82 @@ func (b *SimpleBroker) UpdatePositions() {
 		}
 	}
 	
+	// ストップロス・テイクプロフィットに達したポジションの決済
+	b.closeTriggeredPositions()
+	
 	// 保留注文の処理
 	b.ProcessPendingOrders()
 }
 
+// closeTriggeredPositions はストップロス・テイクプロフィットに達したポジションを決済します。
+// ProtectionModel が close（既定）の場合、決済価格は保護価格ではなく、通常の決済と同じく現在価格にスプレッドを適用した価格です。
+// intrabar の場合は現在のローソク足の高値・安値で判定し、intrabarProtectivePrice の価格にスプレッドを適用して決済します。
+func (b *SimpleBroker) closeTriggeredPositions() {
+	var candle *models.Candle
+	if b.config.ProtectionModel == models.ProtectionIntrabar {
+		candle = b.market.GetCurrentCandle()
+	}
+	
+	for _, position := range b.GetPositions() {
+		if candle != nil {
+			if price, reason, triggered := intrabarProtectivePrice(position, candle); triggered {
+				b.closePositionAt(position, price, reason)
+			}
+			continue
+		}
+		
+		var reason CloseReason
+		switch {
+		case position.ShouldStopLoss():
+			reason = CloseStopLoss
+		case position.ShouldTakeProfit():
+			reason = CloseTakeProfit
+		default:
+			continue
+		}
+		if currentPrice := b.market.GetCurrentPrice(); currentPrice > 0.0 {
+			b.closePositionAt(position, currentPrice, reason)
+		}
+	}
+}
+
+// intrabarProtectivePrice はローソク足の高値・安値でストップロス・テイクプロフィットを判定し、
+// スプレッド・スリッページを適用する前の決済価格と決済理由を返します。決済価格は保護価格で、
+// 始値の時点で既に保護価格を越えている（窓を開けた）場合は始値です。
+// 始値が両者の間にあり高値・安値が両方に触れた場合、足の中での順序は分からないため、
+// リスクを過小評価しないようストップロスが先に約定したものとします。
+func intrabarProtectivePrice(position *models.Position, candle *models.Candle) (float64, CloseReason, bool) {
+	stopLoss, takeProfit := position.StopLoss, position.TakeProfit
+	if position.IsLong() {
+		switch {
+		case stopLoss > 0 && candle.Open <= stopLoss:
+			return candle.Open, CloseStopLoss, true
+		case takeProfit > 0 && candle.Open >= takeProfit:
+			return candle.Open, CloseTakeProfit, true
+		case stopLoss > 0 && candle.Low <= stopLoss:
+			return stopLoss, CloseStopLoss, true
+		case takeProfit > 0 && candle.High >= takeProfit:
+			return takeProfit, CloseTakeProfit, true
+		}
+		return 0, "", false
+	}
+	
+	switch {
+	case stopLoss > 0 && candle.Open >= stopLoss:
+		return candle.Open, CloseStopLoss, true
+	case takeProfit > 0 && candle.Open <= takeProfit:
+		return candle.Open, CloseTakeProfit, true
+	case stopLoss > 0 && candle.High >= stopLoss:
+		return stopLoss, CloseStopLoss, true
+	case takeProfit > 0 && candle.Low <= takeProfit:
+		return takeProfit, CloseTakeProfit, true
+	}
+	return 0, "", false
+}
+
 // ProcessPendingOrders は保留中の注文を現在の市場価格と照らし合わせて約定処理します。
 func (b *SimpleBroker) ProcessPendingOrders() {
-	executedOrders := make([]string, 0)
-	
-	for orderID, order := range b.pendingOrders {
+	for _, order := range b.GetPendingOrders() {
 		if !order.IsPending() {
 			continue
 		}
@@ -239,58 +1148,132 @@ func (b *SimpleBroker) ProcessPendingOrders() {
 			continue
 		}
 		
+		// 流動性が低い場合は次のローソク足まで約定を見送る
+		if b.rejectsIlliquid() {
+			continue
+		}
+		
 		// 約定条件をチェック
-		shouldExecute := false
+		fillPrice, shouldExecute := b.pendingFillPrice(order, currentPrice)
 		
-		switch order.Type {
-		case models.LimitOrder:
-			if order.Side == models.Buy {
-				// 買い指値: 現在価格が指値価格以下になった時に約定
-				shouldExecute = currentPrice <= order.LimitPrice
-			} else {
-				// 売り指値: 現在価格が指値価格以上になった時に約定
-				shouldExecute = currentPrice >= order.LimitPrice
+		if shouldExecute {
+			position, err := b.executePendingOrder(order, fillPrice, currentPrice)
+			if err != nil {
+				continue
 			}
-		case models.StopOrder:
-			if order.Side == models.Buy {
-				// 買い逆指値: 現在価格が逆指値価格以上になった時に約定
-				shouldExecute = currentPrice >= order.StopPrice
-			} else {
-				// 売り逆指値: 現在価格が逆指値価格以下になった時に約定
-				shouldExecute = currentPrice <= order.StopPrice
+			
+			// 約定した注文を保留リストから削除してから通知する
+			delete(b.pendingOrders, order.ID)
+			if b.orderFilled != nil {
+				b.orderFilled(order, position)
 			}
 		}
-		
-		if shouldExecute {
-			if err := b.executePendingOrder(order, currentPrice); err == nil {
-				executedOrders = append(executedOrders, orderID)
-			}
+	}
+}
+
+// pendingFillPrice は保留注文が現在のローソク足で約定するかを FillModel に従って判定し、
+// スプレッド・スリッページを適用する前の約定価格を返します。
+func (b *SimpleBroker) pendingFillPrice(order *models.Order, currentPrice float64) (float64, bool) {
+	switch b.config.FillModel {
+	case models.FillOnTouch, models.FillOnCross:
+		candle := b.market.GetCurrentCandle()
+		if candle == nil {
+			return 0, false
 		}
+		return intrabarFillPrice(order, candle, b.config.FillModel == models.FillOnCross)
 	}
 	
-	// 約定した注文を保留リストから削除
-	for _, orderID := range executedOrders {
-		delete(b.pendingOrders, orderID)
+	switch order.Type {
+	case models.LimitOrder:
+		if order.Side == models.Buy {
+			// 買い指値: 現在価格が指値価格以下になった時に約定
+			return currentPrice, currentPrice <= order.LimitPrice
+		}
+		// 売り指値: 現在価格が指値価格以上になった時に約定
+		return currentPrice, currentPrice >= order.LimitPrice
+	case models.StopOrder:
+		if order.Side == models.Buy {
+			// 買い逆指値: 現在価格が逆指値価格以上になった時に約定
+			return currentPrice, currentPrice >= order.StopPrice
+		}
+		// 売り逆指値: 現在価格が逆指値価格以下になった時に約定
+		return currentPrice, currentPrice <= order.StopPrice
 	}
+	return 0, false
 }
 
-// executePendingOrder は保留注文を約定させます。
-func (b *SimpleBroker) executePendingOrder(order *models.Order, currentPrice float64) error {
-	// スプレッドを適用した実行価格を計算
+// intrabarFillPrice はローソク足の高値・安値で保留注文の約定を判定します。cross では注文価格に触れただけでは約定しません。
+// 約定価格は注文価格で、始値の時点で既に注文価格を越えている（窓を開けた）場合は始値です。
+// このため逆指値は窓開けで注文価格より不利に約定し、指値は始値で約定できた価格より有利にはなりません。
+func intrabarFillPrice(order *models.Order, candle *models.Candle, cross bool) (float64, bool) {
+	reachedBelow := func(price, level float64) bool {
+		if cross {
+			return price < level
+		}
+		return price <= level
+	}
+	reachedAbove := func(price, level float64) bool {
+		if cross {
+			return price > level
+		}
+		return price >= level
+	}
+	
+	switch order.Type {
+	case models.LimitOrder:
+		if order.Side == models.Buy {
+			return math.Min(candle.Open, order.LimitPrice), reachedBelow(candle.Low, order.LimitPrice)
+		}
+		return math.Max(candle.Open, order.LimitPrice), reachedAbove(candle.High, order.LimitPrice)
+	case models.StopOrder:
+		if order.Side == models.Buy {
+			return math.Max(candle.Open, order.StopPrice), reachedAbove(candle.High, order.StopPrice)
+		}
+		return math.Min(candle.Open, order.StopPrice), reachedBelow(candle.Low, order.StopPrice)
+	}
+	return 0, false
+}
+
+// executePendingOrder は保留注文を fillPrice で約定させ、建てたポジションを返します。ペーパーモードではポジションは nil です。
+// fillPrice は pendingFillPrice が返すスプレッド・スリッページ適用前の価格、currentPrice はポジションの現在価格に使う終値です。
+func (b *SimpleBroker) executePendingOrder(order *models.Order, fillPrice, currentPrice float64) (*models.Position, error) {
+	// スプレッドとスリッページを適用した実行価格を計算
+	spread := b.spreadFor(order.Symbol)
+	slip := b.slippage()
 	var executionPrice float64
 	if order.Side == models.Buy {
-		executionPrice = currentPrice + b.config.Spread // Ask価格
+		executionPrice = fillPrice + spread + slip // Ask価格
 	} else {
-		executionPrice = currentPrice - b.config.Spread // Bid価格
+		executionPrice = fillPrice - spread - slip // Bid価格
 	}
 	
-	// 必要証拠金を計算
-	requiredMargin := (order.Size * executionPrice) / 100.0
+	// 必要証拠金を計算（売りも買いと同じ）
+	requiredMargin := b.marginFor(order.Symbol, order.Size, executionPrice)
 	
-	// 残高チェック
-	if b.balance < requiredMargin {
-		// 証拠金不足の場合は約定させない
-		return errors.New("insufficient balance for pending order execution")
+	// ペーパーモードでは記録のみ行う
+	if b.config.PaperMode {
+		b.recordPaperSignal(order, executionPrice, requiredMargin)
+		return nil, nil
+	}
+	
+	// ネッティングでは同じシンボルのポジションに合算する
+	if existing := b.nettingPosition(order.Symbol); existing != nil {
+		position, err := b.netOrder(existing, order, executionPrice, currentPrice, spread, slip)
+		if err != nil {
+			return nil, err
+		}
+		order.Execute(executionPrice)
+		return position, nil
+	}
+	
+	// 余剰証拠金チェック（証拠金不足の場合は約定させない）
+	if err := checkFreeMargin(requiredMargin, b.GetFreeMargin()); err != nil {
+		return nil, fmt.Errorf("pending order execution: %w", err)
+	}
+	
+	// 上限に達している間は約定させず、保留のままにする
+	if err := b.checkPositionLimits(order, executionPrice); err != nil {
+		return nil, err
 	}
 	
 	// ポジション作成
@@ -302,6 +1285,11 @@ func (b *SimpleBroker) executePendingOrder(order *models.Order, currentPrice flo
 		EntryPrice:   executionPrice,
 		CurrentPrice: currentPrice,
 		OpenTime:     b.market.GetCurrentTime(),
+		StopLoss:     order.StopLoss,
+		TakeProfit:   order.TakeProfit,
+		EntrySpread:  spread,
+		EntrySlippage: slip,
+		Margin:       requiredMargin,
 	}
 	
 	// ポジション保存
@@ -313,5 +1301,5 @@ func (b *SimpleBroker) executePendingOrder(order *models.Order, currentPrice flo
 	// 注文を約定状態に更新
 	order.Execute(executionPrice)
 	
-	return nil
+	return position, nil
 }
\ No newline at end of file
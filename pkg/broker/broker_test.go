@@ -3,9 +3,13 @@ package broker
 import (
 	"context"
 	"fmt"
+	"math"
+	"math/rand"
 	"testing"
 	"time"
 
+	"github.com/RuiHirano/fx-backtesting/pkg/data"
+	"github.com/RuiHirano/fx-backtesting/pkg/instruments"
 	"github.com/RuiHirano/fx-backtesting/pkg/market"
 	"github.com/RuiHirano/fx-backtesting/pkg/models"
 	"github.com/stretchr/testify/assert"
@@ -310,6 +314,9 @@ func TestBroker_ClosePosition(t *testing.T) {
 		// 取引履歴が追加されていることを確認
 		trades := broker.GetTradeHistory()
 		assert.Len(t, trades, 1)
+		
+		// エントリーと決済で支払ったスプレッド（0.0001 × 2）がコストとして記録される
+		assert.InDelta(t, 2*0.0001*10000.0, trades[0].SpreadCost, 1e-9)
 	})
 	
 	t.Run("should return error for non-existent position", func(t *testing.T) {
@@ -543,4 +550,1429 @@ func TestBroker_Performance(t *testing.T) {
 		pendingOrders := broker.GetPendingOrders()
 		assert.GreaterOrEqual(t, len(pendingOrders), 0)
 	})
-}
\ No newline at end of file
+}
+
+// 銘柄レジストリ適用テスト
+func TestBroker_Instruments(t *testing.T) {
+	registry, err := instruments.Load("./testdata/instruments.json")
+	assert.NoError(t, err)
+	
+	_, mkt := createTestBroker(t)
+	brokerConfig := models.BrokerConfig{
+		InitialBalance: 10000.0,
+		Spread:         0.0001,
+		SpreadPips:     2.0,
+	}
+	broker := NewSimpleBrokerWithInstruments(brokerConfig, mkt, registry)
+	
+	t.Run("should apply pip size and contract size from registry", func(t *testing.T) {
+		currentPrice := mkt.GetCurrentPrice()
+		
+		// EURUSD: pipサイズ0.0001、契約サイズ1000
+		order := models.NewMarketOrder("inst-1", "EURUSD", models.Buy, 2.0)
+		err := broker.PlaceOrder(order)
+		assert.NoError(t, err)
+		
+		// 2pipsのスプレッドが価格に換算される
+		assert.InDelta(t, currentPrice+0.0002, order.ExecutedPrice, 1e-9)
+		
+		// 証拠金は契約サイズ込みの想定元本から計算される
+		expectedMargin := 2.0 * 1000.0 * order.ExecutedPrice / 100.0
+		assert.InDelta(t, 10000.0-expectedMargin, broker.GetBalance(), 1e-9)
+		
+		// 同一価格で決済すると往復4pips分の損失が契約サイズ分発生する
+		positions := broker.GetPositions()
+		assert.Len(t, positions, 1)
+		err = broker.ClosePosition(positions[0].ID)
+		assert.NoError(t, err)
+		
+		trades := broker.GetTradeHistory()
+		assert.Len(t, trades, 1)
+		assert.InDelta(t, -0.0004*2.0*1000.0, trades[0].PnL, 1e-9)
+		assert.InDelta(t, 10000.0-0.8, broker.GetBalance(), 1e-9)
+		
+		// 取引にpipサイズが記録され、往復のスプレッド分がpips単位の損益になる
+		assert.Equal(t, 0.0001, trades[0].PipSize)
+		assert.InDelta(t, -4.0, trades[0].PnLPips(), 1e-6)
+	})
+	
+	t.Run("should fall back to raw spread for unregistered symbol", func(t *testing.T) {
+		currentPrice := mkt.GetCurrentPrice()
+		
+		order := models.NewMarketOrder("inst-2", "XAUUSD", models.Buy, 10.0)
+		err := broker.PlaceOrder(order)
+		assert.NoError(t, err)
+		assert.InDelta(t, currentPrice+0.0001, order.ExecutedPrice, 1e-9)
+	})
+	
+	t.Run("should apply size constraints and tick size from registry", func(t *testing.T) {
+		// EURUSD: 最小サイズ0.1、サイズ刻み0.1、ティックサイズ0.00001
+		err := broker.PlaceOrder(models.NewMarketOrder("inst-3", "EURUSD", models.Buy, 0.05))
+		assert.ErrorIs(t, err, models.ErrInvalidLotSize)
+		err = broker.PlaceOrder(models.NewMarketOrder("inst-4", "EURUSD", models.Buy, 0.25))
+		assert.ErrorIs(t, err, models.ErrInvalidLotSize)
+		
+		// 指値・保護価格はティックサイズの倍数に丸められる
+		limitPrice := mkt.GetCurrentPrice() - 0.001234
+		order := models.NewLimitOrder("inst-5", "EURUSD", models.Buy, 0.3, limitPrice)
+		stopLoss := limitPrice - 0.002226
+		order.StopLoss = stopLoss
+		assert.NoError(t, broker.PlaceOrder(order))
+		assert.InDelta(t, math.Round(limitPrice/0.00001)*0.00001, order.LimitPrice, 1e-12)
+		assert.InDelta(t, math.Round(stopLoss/0.00001)*0.00001, order.StopLoss, 1e-12)
+		assert.NotEqual(t, limitPrice, order.LimitPrice)
+		assert.NoError(t, broker.CancelOrder(order.ID))
+		
+		// 未登録シンボルは BrokerConfig の制約のみ
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("inst-6", "XAUUSD", models.Buy, 0.05)))
+	})
+}
+
+// 低流動性ローソク足テスト
+func TestBroker_IlliquidCandles(t *testing.T) {
+	// sample.csvの最初のローソク足の出来高は1000、次は1010
+	newIlliquidBroker := func(policy models.IlliquidPolicy) (Broker, market.Market) {
+		_, mkt := createTestBroker(t)
+		brokerConfig := models.BrokerConfig{
+			InitialBalance:   10000.0,
+			Spread:           0.0001,
+			MinVolumeToTrade: 1005.0,
+			IlliquidPolicy:   policy,
+		}
+		return NewSimpleBroker(brokerConfig, mkt), mkt
+	}
+	
+	t.Run("should reject market order on low-volume candle", func(t *testing.T) {
+		broker, mkt := newIlliquidBroker(models.IlliquidReject)
+		
+		order := models.NewMarketOrder("illiquid-1", "EURUSD", models.Buy, 1000.0)
+		err := broker.PlaceOrder(order)
+		assert.ErrorIs(t, err, ErrIlliquidMarket)
+		assert.Len(t, broker.GetPositions(), 0)
+		assert.Equal(t, 10000.0, broker.GetBalance())
+		
+		// 出来高が閾値以上のローソク足では約定する
+		mkt.Forward()
+		order = models.NewMarketOrder("illiquid-2", "EURUSD", models.Buy, 1000.0)
+		assert.NoError(t, broker.PlaceOrder(order))
+		assert.Len(t, broker.GetPositions(), 1)
+	})
+	
+	t.Run("should defer pending order until liquid candle", func(t *testing.T) {
+		broker, mkt := newIlliquidBroker(models.IlliquidReject)
+		
+		order := models.NewLimitOrder("illiquid-3", "EURUSD", models.Buy, 1000.0, 2.0)
+		assert.NoError(t, broker.PlaceOrder(order))
+		
+		broker.ProcessPendingOrders()
+		assert.True(t, order.IsPending())
+		assert.Len(t, broker.GetPositions(), 0)
+		
+		mkt.Forward()
+		broker.ProcessPendingOrders()
+		assert.True(t, order.IsExecuted())
+		assert.Len(t, broker.GetPositions(), 1)
+	})
+	
+	t.Run("should widen spread on low-volume candle", func(t *testing.T) {
+		broker, mkt := newIlliquidBroker(models.IlliquidWiden)
+		currentPrice := mkt.GetCurrentPrice()
+		
+		order := models.NewMarketOrder("illiquid-4", "EURUSD", models.Buy, 1000.0)
+		assert.NoError(t, broker.PlaceOrder(order))
+		
+		// デフォルト倍率2倍のスプレッドで約定する
+		assert.InDelta(t, currentPrice+0.0002, order.ExecutedPrice, 1e-9)
+	})
+}
+
+// ペーパーモードテスト
+func TestBroker_Reset(t *testing.T) {
+	broker, _ := createTestBroker(t)
+	
+	buy := models.NewMarketOrder("reset-1", "EURUSD", models.Buy, 1000.0)
+	assert.NoError(t, broker.PlaceOrder(buy))
+	positions := broker.GetPositions()
+	assert.Len(t, positions, 1)
+	assert.NoError(t, broker.ClosePosition(positions[0].ID))
+	assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("reset-2", "EURUSD", models.Buy, 1000.0)))
+	assert.NoError(t, broker.PlaceOrder(models.NewLimitOrder("reset-3", "EURUSD", models.Buy, 1000.0, 0.5)))
+	assert.NotEqual(t, 10000.0, broker.GetBalance())
+	
+	broker.Reset()
+	
+	assert.Equal(t, 10000.0, broker.GetBalance())
+	assert.Empty(t, broker.GetPositions())
+	assert.Empty(t, broker.GetPendingOrders())
+	assert.Empty(t, broker.GetTradeHistory())
+	
+	// リセット後も通常通り注文できる
+	assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("reset-4", "EURUSD", models.Buy, 1000.0)))
+	assert.Len(t, broker.GetPositions(), 1)
+}
+
+func TestBroker_State(t *testing.T) {
+	original, mkt := createTestBroker(t)
+	
+	buy := models.NewMarketOrder("state-1", "EURUSD", models.Buy, 1000.0)
+	assert.NoError(t, original.PlaceOrder(buy))
+	assert.NoError(t, original.ClosePosition(original.GetPositions()[0].ID))
+	assert.NoError(t, original.PlaceOrder(models.NewMarketOrder("state-2", "EURUSD", models.Sell, 1000.0)))
+	assert.NoError(t, original.PlaceOrder(models.NewLimitOrder("state-3", "EURUSD", models.Buy, 1000.0, 0.5)))
+	
+	state := original.State()
+	assert.Equal(t, original.GetBalance(), state.Balance)
+	assert.Len(t, state.Positions, 1)
+	assert.Len(t, state.PendingOrders, 1)
+	assert.Len(t, state.Orders, 2)
+	assert.Len(t, state.TradeHistory, 1)
+	
+	// スナップショットは元のポジションと独立している
+	state.Positions[0].StopLoss = 9.9
+	assert.Equal(t, 0.0, original.GetPositions()[0].StopLoss)
+	state.Positions[0].StopLoss = 0
+	
+	restored := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, Spread: 0.0001}, mkt)
+	assert.NoError(t, restored.PlaceOrder(models.NewMarketOrder("discarded", "EURUSD", models.Buy, 1000.0)))
+	restored.RestoreState(state)
+	
+	assert.Equal(t, original.GetBalance(), restored.GetBalance())
+	assert.Equal(t, original.GetPositions(), restored.GetPositions())
+	assert.Equal(t, original.GetPendingOrders(), restored.GetPendingOrders())
+	assert.Equal(t, original.GetTradeHistory(), restored.GetTradeHistory())
+	_, found := restored.GetOrder("discarded")
+	assert.False(t, found)
+	for _, id := range []string{"state-1", "state-2", "state-3"} {
+		order, found := restored.GetOrder(id)
+		if assert.True(t, found, id) {
+			expected, _ := original.GetOrder(id)
+			assert.Equal(t, expected.Status, order.Status)
+		}
+	}
+	pending, _ := restored.GetOrder("state-3")
+	assert.Same(t, restored.GetPendingOrders()[0], pending)
+	
+	// 復元したポジションを通常通り決済できる
+	assert.NoError(t, restored.ClosePosition("pos-state-2"))
+	assert.Len(t, restored.GetTradeHistory(), 2)
+	assert.Len(t, original.GetPositions(), 1)
+}
+
+func TestBroker_PaperMode(t *testing.T) {
+	_, mkt := createTestBroker(t)
+	brokerConfig := models.BrokerConfig{
+		InitialBalance: 10000.0,
+		Spread:         0.0001,
+		PaperMode:      true,
+	}
+	broker := NewSimpleBroker(brokerConfig, mkt)
+	currentPrice := mkt.GetCurrentPrice()
+	
+	t.Run("should record market orders without affecting balance", func(t *testing.T) {
+		buy := models.NewMarketOrder("paper-1", "EURUSD", models.Buy, 1000.0)
+		assert.NoError(t, broker.PlaceOrder(buy))
+		sell := models.NewMarketOrder("paper-2", "EURUSD", models.Sell, 500.0)
+		assert.NoError(t, broker.PlaceOrder(sell))
+		
+		assert.Equal(t, 10000.0, broker.GetBalance())
+		assert.Len(t, broker.GetPositions(), 0)
+		
+		signals := broker.GetPaperSignals()
+		assert.Len(t, signals, 2)
+		assert.Equal(t, "paper-1", signals[0].OrderID)
+		assert.InDelta(t, currentPrice+0.0001, signals[0].TheoreticalPrice, 1e-9)
+		assert.InDelta(t, currentPrice-0.0001, signals[1].TheoreticalPrice, 1e-9)
+		assert.Equal(t, mkt.GetCurrentTime(), signals[0].Timestamp)
+	})
+	
+	t.Run("should record triggered pending orders", func(t *testing.T) {
+		order := models.NewLimitOrder("paper-3", "EURUSD", models.Buy, 1000.0, 2.0)
+		assert.NoError(t, broker.PlaceOrder(order))
+		broker.ProcessPendingOrders()
+		
+		assert.True(t, order.IsExecuted())
+		assert.Len(t, broker.GetPendingOrders(), 0)
+		assert.Len(t, broker.GetPositions(), 0)
+		assert.Equal(t, 10000.0, broker.GetBalance())
+		assert.Len(t, broker.GetPaperSignals(), 3)
+	})
+}
+
+// candlesFromCloses は 2024-01-01 09:00 から1分ごとの、終値のみ（始値・高値・安値も同値）のローソク足を作成する
+func candlesFromCloses(closes []float64) []models.Candle {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 0, len(closes))
+	for i, price := range closes {
+		candles = append(candles, *models.NewCandle(baseTime.Add(time.Duration(i)*time.Minute), price, price, price, price, 1000))
+	}
+	return candles
+}
+
+// createInMemoryBroker は終値のみのローソク足でスプレッド0のMarketとBrokerを作成する
+func createInMemoryBroker(t *testing.T, closes []float64) (Broker, market.Market) {
+	mkt := market.NewMarketWithProvider(data.NewInMemoryProvider(candlesFromCloses(closes)))
+	if err := mkt.Initialize(context.Background()); err != nil {
+		t.Fatalf("Failed to initialize market: %v", err)
+	}
+	return NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0}, mkt), mkt
+}
+
+func TestBroker_ProtectivePrices(t *testing.T) {
+	t.Run("should close long position at stop loss", func(t *testing.T) {
+		broker, mkt := createInMemoryBroker(t, []float64{1.10, 1.09, 1.08})
+		order := models.NewMarketOrder("sl-1", "EURUSD", models.Buy, 10000.0)
+		order.StopLoss = 1.085
+		assert.NoError(t, broker.PlaceOrder(order))
+		assert.Equal(t, 1.085, broker.GetPositions()[0].StopLoss)
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.Len(t, broker.GetPositions(), 1)
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.Empty(t, broker.GetPositions())
+		history := broker.GetTradeHistory()
+		assert.Len(t, history, 1)
+		// 保護価格ではなく現在価格 1.08 で決済される
+		assert.InDelta(t, 1.08, history[0].ExitPrice, 1e-9)
+		assert.InDelta(t, 9800.0, broker.GetBalance(), 1e-6)
+	})
+	
+	t.Run("should close short position at take profit", func(t *testing.T) {
+		broker, mkt := createInMemoryBroker(t, []float64{1.10, 1.08})
+		order := models.NewMarketOrder("tp-1", "EURUSD", models.Sell, 10000.0)
+		order.TakeProfit = 1.09
+		assert.NoError(t, broker.PlaceOrder(order))
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.Empty(t, broker.GetPositions())
+		assert.InDelta(t, 10200.0, broker.GetBalance(), 1e-6)
+	})
+	
+	t.Run("should carry protective prices from pending orders", func(t *testing.T) {
+		broker, mkt := createInMemoryBroker(t, []float64{1.10, 1.08, 1.12})
+		order := models.NewLimitOrder("limit-1", "EURUSD", models.Buy, 10000.0, 1.09)
+		order.TakeProfit = 1.11
+		assert.NoError(t, broker.PlaceOrder(order))
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		positions := broker.GetPositions()
+		assert.Len(t, positions, 1)
+		assert.Equal(t, 1.11, positions[0].TakeProfit)
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.Empty(t, broker.GetPositions())
+		assert.InDelta(t, 10400.0, broker.GetBalance(), 1e-6)
+	})
+}
+
+func TestBroker_AveragePrice(t *testing.T) {
+	broker, mkt := createInMemoryBroker(t, []float64{1.10, 1.13, 1.16})
+	
+	price, size := broker.AveragePrice("EURUSD", models.Buy)
+	assert.Equal(t, 0.0, price)
+	assert.Equal(t, 0.0, size)
+	
+	assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("avg-1", "EURUSD", models.Buy, 10000.0)))
+	mkt.Forward()
+	assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("avg-2", "EURUSD", models.Buy, 20000.0)))
+	assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("avg-3", "EURUSD", models.Sell, 5000.0)))
+	
+	// 買い: (1.10×10000 + 1.13×20000) / 30000
+	price, size = broker.AveragePrice("EURUSD", models.Buy)
+	assert.InDelta(t, 1.12, price, 1e-9)
+	assert.Equal(t, 30000.0, size)
+	
+	// 売りは別に集計される
+	price, size = broker.AveragePrice("EURUSD", models.Sell)
+	assert.InDelta(t, 1.13, price, 1e-9)
+	assert.Equal(t, 5000.0, size)
+	
+	price, size = broker.AveragePrice("USDJPY", models.Buy)
+	assert.Equal(t, 0.0, price)
+	assert.Equal(t, 0.0, size)
+}
+
+func TestBroker_LotSize(t *testing.T) {
+	_, mkt := createTestBroker(t)
+	lotConfig := models.BrokerConfig{
+		InitialBalance: 10000.0,
+		MinLot:         100.0,
+		MaxLot:         5000.0,
+		LotStep:        100.0,
+	}
+	
+	t.Run("should reject sizes outside lot constraints", func(t *testing.T) {
+		broker := NewSimpleBroker(lotConfig, mkt)
+		for _, size := range []float64{50.0, 150.0, 6000.0} {
+			order := models.NewMarketOrder("lot-reject", "EURUSD", models.Buy, size)
+			assert.ErrorIs(t, broker.PlaceOrder(order), models.ErrInvalidLotSize, "size %v", size)
+		}
+		limit := models.NewLimitOrder("lot-limit", "EURUSD", models.Buy, 150.0, 2.0)
+		assert.ErrorIs(t, broker.PlaceOrder(limit), models.ErrInvalidLotSize)
+		
+		assert.Len(t, broker.GetPositions(), 0)
+		assert.Len(t, broker.GetPendingOrders(), 0)
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("lot-ok", "EURUSD", models.Buy, 200.0)))
+		assert.Len(t, broker.GetPositions(), 1)
+	})
+	
+	t.Run("should round sizes when configured", func(t *testing.T) {
+		roundConfig := lotConfig
+		roundConfig.LotPolicy = models.LotRound
+		broker := NewSimpleBroker(roundConfig, mkt)
+		
+		order := models.NewMarketOrder("lot-round", "EURUSD", models.Buy, 250.0)
+		assert.NoError(t, broker.PlaceOrder(order))
+		assert.Equal(t, 200.0, order.Size)
+		
+		big := models.NewMarketOrder("lot-max", "EURUSD", models.Sell, 9000.0)
+		assert.NoError(t, broker.PlaceOrder(big))
+		
+		sizes := make([]float64, 0, 2)
+		for _, position := range broker.GetPositions() {
+			sizes = append(sizes, position.Size)
+		}
+		assert.ElementsMatch(t, []float64{200.0, 5000.0}, sizes)
+		
+		// 切り捨てると最小数量を下回る場合は拒否
+		assert.ErrorIs(t, broker.PlaceOrder(models.NewMarketOrder("lot-small", "EURUSD", models.Buy, 99.0)), models.ErrInvalidLotSize)
+	})
+}
+
+func TestBroker_PositionLimits(t *testing.T) {
+	t.Run("should reject orders above max open positions", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{1.10, 1.10})
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, MaxOpenPositions: 2}, mkt)
+		
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("limit-1", "EURUSD", models.Buy, 1000.0)))
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("limit-2", "EURUSD", models.Sell, 1000.0)))
+		err := broker.PlaceOrder(models.NewMarketOrder("limit-3", "EURUSD", models.Buy, 1000.0))
+		assert.ErrorIs(t, err, ErrPositionLimit)
+		assert.ErrorContains(t, err, "max open positions 2")
+		assert.Len(t, broker.GetPositions(), 2)
+		
+		// 決済して枠が空けば再び建てられる
+		assert.NoError(t, broker.ClosePosition(broker.GetPositions()[0].ID))
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("limit-4", "EURUSD", models.Buy, 1000.0)))
+	})
+	
+	t.Run("should reject orders above max exposure", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{2.0, 2.0})
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, MaxExposure: 5000.0}, mkt)
+		
+		// 1000 × 2.0 = 2000 ずつ建てると3本目で 6000 になり上限を超える
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("exp-1", "EURUSD", models.Buy, 1000.0)))
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("exp-2", "EURUSD", models.Buy, 1000.0)))
+		err := broker.PlaceOrder(models.NewMarketOrder("exp-3", "EURUSD", models.Buy, 1000.0))
+		assert.ErrorIs(t, err, ErrPositionLimit)
+		assert.ErrorContains(t, err, "exposure")
+		
+		// 上限内に収まる数量は受け付ける
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("exp-4", "EURUSD", models.Buy, 500.0)))
+	})
+	
+	t.Run("should keep pending orders until there is room", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{1.10, 1.10})
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, MaxOpenPositions: 1}, mkt)
+		
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("pending-1", "EURUSD", models.Buy, 1000.0)))
+		limit := models.NewLimitOrder("pending-2", "EURUSD", models.Buy, 1000.0, 2.0)
+		assert.NoError(t, broker.PlaceOrder(limit))
+		
+		broker.ProcessPendingOrders()
+		assert.False(t, limit.IsExecuted())
+		assert.Len(t, broker.GetPendingOrders(), 1)
+		
+		assert.NoError(t, broker.ClosePosition(broker.GetPositions()[0].ID))
+		broker.ProcessPendingOrders()
+		assert.True(t, limit.IsExecuted())
+		assert.Len(t, broker.GetPositions(), 1)
+	})
+}
+
+func TestBroker_Leverage(t *testing.T) {
+	t.Run("should use leverage for margin", func(t *testing.T) {
+		// 1000 × 2.0 = 2000 の建玉の証拠金は、既定の100倍で20、10倍で200
+		for _, tc := range []struct {
+			leverage float64
+			margin   float64
+		}{{0, 20.0}, {10, 200.0}} {
+			_, mkt := createInMemoryBroker(t, []float64{2.0, 2.0})
+			broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, Leverage: tc.leverage}, mkt)
+			
+			assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("lev-1", "EURUSD", models.Buy, 1000.0)))
+			assert.InDelta(t, tc.margin, broker.GetUsedMargin(), 1e-9)
+			assert.InDelta(t, 10000.0-tc.margin, broker.GetBalance(), 1e-9)
+		}
+	})
+	
+	t.Run("should check free margin with leverage", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{2.0, 2.0})
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, Leverage: 1}, mkt)
+		
+		// 1倍では建玉金額がそのまま証拠金になる: 6000 × 2.0 = 12000 は余剰証拠金10000を超える
+		err := broker.PlaceOrder(models.NewMarketOrder("lev-2", "EURUSD", models.Buy, 6000.0))
+		assert.ErrorContains(t, err, "insufficient balance")
+		
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("lev-3", "EURUSD", models.Buy, 4000.0)))
+		assert.InDelta(t, 2000.0, broker.GetFreeMargin(), 1e-9)
+		err = broker.PlaceOrder(models.NewMarketOrder("lev-4", "EURUSD", models.Buy, 1500.0))
+		assert.ErrorContains(t, err, "insufficient balance")
+		assert.Len(t, broker.GetPositions(), 1)
+	})
+}
+
+func TestBroker_GetOrder(t *testing.T) {
+	broker, _ := createInMemoryBroker(t, []float64{1.10, 1.05})
+	
+	market := models.NewMarketOrder("lookup-market", "EURUSD", models.Buy, 1000.0)
+	assert.NoError(t, broker.PlaceOrder(market))
+	order, found := broker.GetOrder("lookup-market")
+	assert.True(t, found)
+	assert.Equal(t, models.Executed, order.Status)
+	assert.Equal(t, market.ExecutedPrice, order.ExecutedPrice)
+	
+	limit := models.NewLimitOrder("lookup-limit", "EURUSD", models.Buy, 1000.0, 1.10)
+	assert.NoError(t, broker.PlaceOrder(limit))
+	order, found = broker.GetOrder("lookup-limit")
+	assert.True(t, found)
+	assert.Equal(t, models.Pending, order.Status)
+	
+	// 約定して保留リストから消えた後も、約定状態と約定価格を参照できる
+	broker.ProcessPendingOrders()
+	assert.Empty(t, broker.GetPendingOrders())
+	order, found = broker.GetOrder("lookup-limit")
+	assert.True(t, found)
+	assert.Equal(t, models.Executed, order.Status)
+	assert.InDelta(t, 1.10, order.ExecutedPrice, 1e-9)
+	
+	cancelled := models.NewLimitOrder("lookup-cancel", "EURUSD", models.Buy, 1000.0, 0.5)
+	assert.NoError(t, broker.PlaceOrder(cancelled))
+	assert.NoError(t, broker.CancelOrder("lookup-cancel"))
+	order, found = broker.GetOrder("lookup-cancel")
+	assert.True(t, found)
+	assert.Equal(t, models.Cancelled, order.Status)
+	
+	// 拒否された注文は保持しない
+	assert.Error(t, broker.PlaceOrder(models.NewMarketOrder("lookup-rejected", "EURUSD", models.Buy, 1e9)))
+	_, found = broker.GetOrder("lookup-rejected")
+	assert.False(t, found)
+	_, found = broker.GetOrder("unknown")
+	assert.False(t, found)
+	
+	broker.Reset()
+	_, found = broker.GetOrder("lookup-market")
+	assert.False(t, found)
+}
+
+func TestBroker_GetTrade(t *testing.T) {
+	broker, _ := createInMemoryBroker(t, []float64{1.10})
+	
+	assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("trade-1", "EURUSD", models.Buy, 1000.0)))
+	assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("trade-2", "EURUSD", models.Buy, 1000.0)))
+	assert.NoError(t, broker.ClosePosition("pos-trade-1"))
+	
+	trade, found := broker.GetTrade("pos-trade-1")
+	assert.True(t, found)
+	assert.Same(t, broker.GetTradeHistory()[0], trade)
+	_, found = broker.GetTrade("pos-trade-2")
+	assert.False(t, found, "open position has no trade yet")
+	
+	// 状態から復元した取引履歴も ID で引ける
+	state := broker.State()
+	broker.Reset()
+	_, found = broker.GetTrade("pos-trade-1")
+	assert.False(t, found)
+	broker.RestoreState(state)
+	trade, found = broker.GetTrade("pos-trade-1")
+	assert.True(t, found)
+	assert.Same(t, broker.GetTradeHistory()[0], trade)
+}
+
+func TestBroker_OnOrderFilled(t *testing.T) {
+	t.Run("should notify pending order fills", func(t *testing.T) {
+		broker, _ := createInMemoryBroker(t, []float64{1.10, 1.05})
+		
+		var filled []*models.Order
+		var positions []*models.Position
+		broker.OnOrderFilled(func(order *models.Order, position *models.Position) {
+			filled = append(filled, order)
+			positions = append(positions, position)
+			// 通知時点で保留リストから外れており、新たな注文を発注できる
+			assert.Empty(t, broker.GetPendingOrders())
+			assert.NoError(t, broker.PlaceOrder(models.NewLimitOrder("take-profit", "EURUSD", models.Sell, order.Size, 1.20)))
+		})
+		
+		// 成行注文では呼び出されない
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("fill-market", "EURUSD", models.Buy, 1000.0)))
+		assert.Empty(t, filled)
+		
+		limit := models.NewLimitOrder("fill-limit", "EURUSD", models.Buy, 1000.0, 1.10)
+		assert.NoError(t, broker.PlaceOrder(limit))
+		broker.ProcessPendingOrders()
+		
+		if assert.Len(t, filled, 1) {
+			assert.Same(t, limit, filled[0])
+			assert.Equal(t, "pos-fill-limit", positions[0].ID)
+			assert.InDelta(t, limit.ExecutedPrice, positions[0].EntryPrice, 1e-9)
+		}
+		
+		// 通知中に発注した注文は次回の処理まで約定判定されない
+		pending := broker.GetPendingOrders()
+		if assert.Len(t, pending, 1) {
+			assert.Equal(t, "take-profit", pending[0].ID)
+		}
+		broker.ProcessPendingOrders()
+		assert.Len(t, filled, 1)
+	})
+	
+	t.Run("should pass nil position in paper mode", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{1.10})
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, PaperMode: true}, mkt)
+		
+		var positions []*models.Position
+		broker.OnOrderFilled(func(order *models.Order, position *models.Position) {
+			positions = append(positions, position)
+		})
+		assert.NoError(t, broker.PlaceOrder(models.NewLimitOrder("paper-limit", "EURUSD", models.Buy, 1000.0, 1.20)))
+		broker.ProcessPendingOrders()
+		
+		if assert.Len(t, positions, 1) {
+			assert.Nil(t, positions[0])
+		}
+	})
+	
+	t.Run("should keep the hook after reset", func(t *testing.T) {
+		broker, _ := createInMemoryBroker(t, []float64{1.10})
+		
+		count := 0
+		broker.OnOrderFilled(func(*models.Order, *models.Position) { count++ })
+		broker.Reset()
+		assert.NoError(t, broker.PlaceOrder(models.NewLimitOrder("reset-limit", "EURUSD", models.Buy, 1000.0, 1.20)))
+		broker.ProcessPendingOrders()
+		assert.Equal(t, 1, count)
+		
+		broker.OnOrderFilled(nil)
+		assert.NoError(t, broker.PlaceOrder(models.NewLimitOrder("reset-limit-2", "EURUSD", models.Buy, 1000.0, 1.20)))
+		broker.ProcessPendingOrders()
+		assert.Equal(t, 1, count)
+	})
+}
+
+func TestBroker_OnPositionClosed(t *testing.T) {
+	type closed struct {
+		tradeID string
+		reason  CloseReason
+	}
+	record := func(broker Broker) *[]closed {
+		var notified []closed
+		broker.OnPositionClosed(func(trade *models.Trade, reason CloseReason) {
+			// 通知時点で取引履歴に記録済み
+			_, exists := broker.GetTrade(trade.ID)
+			assert.True(t, exists)
+			notified = append(notified, closed{trade.ID, reason})
+		})
+		return &notified
+	}
+	
+	t.Run("should notify manual, stop loss and take profit closes", func(t *testing.T) {
+		broker, mkt := createInMemoryBroker(t, []float64{1.10, 1.05, 1.10})
+		notified := record(broker)
+		
+		stop := models.NewMarketOrder("stop", "EURUSD", models.Buy, 1000.0)
+		stop.StopLoss = 1.06
+		assert.NoError(t, broker.PlaceOrder(stop))
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("manual", "EURUSD", models.Buy, 1000.0)))
+		assert.NoError(t, broker.ClosePosition("pos-manual"))
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		target := models.NewMarketOrder("target", "EURUSD", models.Buy, 1000.0)
+		target.TakeProfit = 1.08
+		assert.NoError(t, broker.PlaceOrder(target))
+		mkt.Forward()
+		broker.UpdatePositions()
+		
+		assert.Equal(t, []closed{
+			{"pos-manual", CloseManual},
+			{"pos-stop", CloseStopLoss},
+			{"pos-target", CloseTakeProfit},
+		}, *notified)
+	})
+	
+	t.Run("should notify intrabar closes with the triggered side", func(t *testing.T) {
+		baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		mkt := market.NewMarketWithProvider(data.NewInMemoryProvider([]models.Candle{
+			*models.NewCandle(baseTime, 1.10, 1.10, 1.10, 1.10, 1000),
+			*models.NewCandle(baseTime.Add(time.Minute), 1.10, 1.12, 1.09, 1.10, 1000),
+		}))
+		if err := mkt.Initialize(context.Background()); err != nil {
+			t.Fatalf("Failed to initialize market: %v", err)
+		}
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, ProtectionModel: models.ProtectionIntrabar}, mkt)
+		notified := record(broker)
+		
+		long := models.NewMarketOrder("long", "EURUSD", models.Buy, 1000.0)
+		long.TakeProfit = 1.115
+		assert.NoError(t, broker.PlaceOrder(long))
+		short := models.NewMarketOrder("short", "EURUSD", models.Sell, 1000.0)
+		short.StopLoss = 1.115
+		assert.NoError(t, broker.PlaceOrder(short))
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.ElementsMatch(t, []closed{
+			{"pos-long", CloseTakeProfit},
+			{"pos-short", CloseStopLoss},
+		}, *notified)
+	})
+	
+	t.Run("should notify partial and full closes by netting", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{1.10})
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, AccountMode: models.AccountNetting}, mkt)
+		notified := record(broker)
+		
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-1", "EURUSD", models.Buy, 1000.0)))
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-2", "EURUSD", models.Sell, 400.0)))
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-3", "EURUSD", models.Sell, 600.0)))
+		assert.Equal(t, []closed{
+			{"pos-net-1-net-2", CloseNetting},
+			{"pos-net-1", CloseNetting},
+		}, *notified)
+	})
+	
+	t.Run("should keep the hook after reset and stop notifying after nil", func(t *testing.T) {
+		broker, _ := createInMemoryBroker(t, []float64{1.10})
+		notified := record(broker)
+		broker.Reset()
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("reset-1", "EURUSD", models.Buy, 1000.0)))
+		assert.NoError(t, broker.ClosePosition("pos-reset-1"))
+		assert.Len(t, *notified, 1)
+		
+		broker.OnPositionClosed(nil)
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("reset-2", "EURUSD", models.Buy, 1000.0)))
+		assert.NoError(t, broker.ClosePosition("pos-reset-2"))
+		assert.Len(t, *notified, 1)
+	})
+}
+
+func TestBroker_ModifyOrder(t *testing.T) {
+	t.Run("should update pending limit and stop orders in place", func(t *testing.T) {
+		broker, _ := createInMemoryBroker(t, []float64{1.10})
+		
+		limit := models.NewLimitOrder("modify-limit", "EURUSD", models.Buy, 1000.0, 1.00)
+		stop := models.NewStopOrder("modify-stop", "EURUSD", models.Buy, 1000.0, 1.20)
+		assert.NoError(t, broker.PlaceOrder(limit))
+		assert.NoError(t, broker.PlaceOrder(stop))
+		
+		assert.NoError(t, broker.ModifyOrder("modify-limit", 1.05, 2000.0))
+		assert.Equal(t, 1.05, limit.LimitPrice)
+		assert.Equal(t, 2000.0, limit.Size)
+		assert.NoError(t, broker.ModifyOrder("modify-stop", 1.15, 500.0))
+		assert.Equal(t, 1.15, stop.StopPrice)
+		assert.Equal(t, 0.0, stop.LimitPrice)
+		assert.Equal(t, 500.0, stop.Size)
+		
+		// 処理順は変更前のまま維持される
+		pending := broker.GetPendingOrders()
+		assert.Same(t, limit, pending[0])
+		assert.Same(t, stop, pending[1])
+		
+		// 価格を追いかけると約定する
+		assert.NoError(t, broker.ModifyOrder("modify-limit", 1.10, 2000.0))
+		broker.ProcessPendingOrders()
+		assert.True(t, limit.IsExecuted())
+		assert.Equal(t, 2000.0, broker.GetPositions()[0].Size)
+	})
+	
+	t.Run("should reject invalid values without changing the order", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{1.10})
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, MinLot: 100, LotStep: 100}, mkt)
+		
+		limit := models.NewLimitOrder("modify-invalid", "EURUSD", models.Buy, 1000.0, 1.00)
+		assert.NoError(t, broker.PlaceOrder(limit))
+		
+		assert.Error(t, broker.ModifyOrder("modify-invalid", 0, 1000.0))
+		assert.Error(t, broker.ModifyOrder("modify-invalid", 1.05, -1))
+		assert.ErrorIs(t, broker.ModifyOrder("modify-invalid", 1.05, 150.0), models.ErrInvalidLotSize)
+		assert.Equal(t, 1.00, limit.LimitPrice)
+		assert.Equal(t, 1000.0, limit.Size)
+	})
+	
+	t.Run("should reject executed, cancelled and unknown orders", func(t *testing.T) {
+		broker, _ := createInMemoryBroker(t, []float64{1.10})
+		
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("modify-market", "EURUSD", models.Buy, 1000.0)))
+		assert.ErrorContains(t, broker.ModifyOrder("modify-market", 1.05, 1000.0), "already executed")
+		
+		assert.NoError(t, broker.PlaceOrder(models.NewLimitOrder("modify-cancel", "EURUSD", models.Buy, 1000.0, 1.00)))
+		assert.NoError(t, broker.CancelOrder("modify-cancel"))
+		assert.ErrorContains(t, broker.ModifyOrder("modify-cancel", 1.05, 1000.0), "already cancelled")
+		
+		assert.ErrorContains(t, broker.ModifyOrder("unknown", 1.05, 1000.0), "order not found")
+	})
+}
+
+func TestBroker_PlaceBracket(t *testing.T) {
+	t.Run("should protect the position once the entry fills", func(t *testing.T) {
+		broker, mkt := createInMemoryBroker(t, []float64{1.10, 1.08, 1.12, 1.05})
+		entry := models.NewLimitOrder("bracket-1", "EURUSD", models.Buy, 10000.0, 1.09)
+		assert.NoError(t, broker.PlaceBracket(entry, 1.07, 1.11))
+		
+		// 約定前はポジションを作らない
+		assert.Empty(t, broker.GetPositions())
+		assert.Len(t, broker.GetPendingOrders(), 1)
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		positions := broker.GetPositions()
+		if assert.Len(t, positions, 1) {
+			assert.Equal(t, 1.07, positions[0].StopLoss)
+			assert.Equal(t, 1.11, positions[0].TakeProfit)
+		}
+		
+		// テイクプロフィットで決済されると、ストップロスも同時に無効になる
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.Empty(t, broker.GetPositions())
+		assert.Len(t, broker.GetTradeHistory(), 1)
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.Len(t, broker.GetTradeHistory(), 1)
+		assert.Empty(t, broker.GetPendingOrders())
+	})
+	
+	t.Run("should protect a market entry immediately", func(t *testing.T) {
+		broker, mkt := createInMemoryBroker(t, []float64{1.10, 1.08})
+		entry := models.NewMarketOrder("bracket-market", "EURUSD", models.Sell, 10000.0)
+		assert.NoError(t, broker.PlaceBracket(entry, 1.12, 1.09))
+		assert.Equal(t, 1.12, broker.GetPositions()[0].StopLoss)
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.Empty(t, broker.GetPositions())
+		assert.InDelta(t, 10200.0, broker.GetBalance(), 1e-6)
+	})
+	
+	t.Run("should create nothing when the entry is cancelled", func(t *testing.T) {
+		broker, mkt := createInMemoryBroker(t, []float64{1.10, 1.08, 1.05})
+		entry := models.NewLimitOrder("bracket-cancel", "EURUSD", models.Buy, 10000.0, 1.09)
+		assert.NoError(t, broker.PlaceBracket(entry, 1.07, 1.11))
+		assert.NoError(t, broker.CancelOrder("bracket-cancel"))
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.Empty(t, broker.GetPositions())
+		assert.Empty(t, broker.GetPendingOrders())
+		assert.Empty(t, broker.GetTradeHistory())
+	})
+	
+	t.Run("should reject protective prices on the wrong side", func(t *testing.T) {
+		broker, _ := createInMemoryBroker(t, []float64{1.10})
+		
+		buy := models.NewLimitOrder("bracket-buy", "EURUSD", models.Buy, 10000.0, 1.09)
+		assert.Error(t, broker.PlaceBracket(buy, 1.10, 1.11))
+		assert.Error(t, broker.PlaceBracket(buy, 1.07, 1.08))
+		assert.Error(t, broker.PlaceBracket(buy, 0, 1.11))
+		
+		sell := models.NewStopOrder("bracket-sell", "EURUSD", models.Sell, 10000.0, 1.08)
+		assert.Error(t, broker.PlaceBracket(sell, 1.07, 1.06))
+		
+		// 発注に失敗した場合は保護価格を設定しない
+		rejected := models.NewMarketOrder("bracket-rejected", "EURUSD", models.Buy, 1e9)
+		assert.Error(t, broker.PlaceBracket(rejected, 1.05, 1.15))
+		assert.Equal(t, 0.0, rejected.StopLoss)
+		assert.Equal(t, 0.0, rejected.TakeProfit)
+		assert.Empty(t, broker.GetPendingOrders())
+		assert.Empty(t, broker.GetPositions())
+	})
+}
+
+func TestBroker_ShortAccounting(t *testing.T) {
+	t.Run("should lock the same margin as a long and apply PnL with inverted sign", func(t *testing.T) {
+		broker, mkt := createInMemoryBroker(t, []float64{1.10, 1.05, 1.15})
+		
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("short-1", "EURUSD", models.Sell, 10000.0)))
+		position := broker.GetPositions()[0]
+		// 売却代金は加算されず、建玉金額の 1% のみ拘束される
+		assert.InDelta(t, 110.0, position.Margin, 1e-9)
+		assert.InDelta(t, 10000.0-110.0, broker.GetBalance(), 1e-9)
+		
+		// 下落で利益
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.NoError(t, broker.ClosePosition(position.ID))
+		assert.InDelta(t, 500.0, broker.GetTradeHistory()[0].PnL, 1e-9)
+		assert.InDelta(t, 10500.0, broker.GetBalance(), 1e-9)
+		
+		// 上昇で損失
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("short-2", "EURUSD", models.Sell, 10000.0)))
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.NoError(t, broker.ClosePosition("pos-short-2"))
+		assert.InDelta(t, -1000.0, broker.GetTradeHistory()[1].PnL, 1e-9)
+		assert.InDelta(t, 9500.0, broker.GetBalance(), 1e-9)
+	})
+	
+	t.Run("should return exactly the margin locked at entry with spread", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{1.10, 1.08})
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, Spread: 0.001}, mkt)
+		
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("short-spread", "EURUSD", models.Sell, 10000.0)))
+		position := broker.GetPositions()[0]
+		// Bid価格 1.099 で約定し、その価格で証拠金を計算する
+		assert.InDelta(t, 1.099, position.EntryPrice, 1e-9)
+		assert.InDelta(t, 109.9, position.Margin, 1e-9)
+		
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.NoError(t, broker.ClosePosition(position.ID))
+		
+		// Ask価格 1.081 で買い戻し、残高の増減は損益と一致する
+		trade := broker.GetTradeHistory()[0]
+		assert.InDelta(t, 1.081, trade.ExitPrice, 1e-9)
+		assert.InDelta(t, 180.0, trade.PnL, 1e-9)
+		assert.InDelta(t, 10000.0+trade.PnL, broker.GetBalance(), 1e-9)
+	})
+	
+	t.Run("should reject a short when most of the balance is committed", func(t *testing.T) {
+		broker, _ := createInMemoryBroker(t, []float64{1.00})
+		
+		// 買いで 9000 を拘束し、残り 1000
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("long-large", "EURUSD", models.Buy, 900000.0)))
+		assert.InDelta(t, 1000.0, broker.GetBalance(), 1e-9)
+		
+		// 残高を超える証拠金が必要な売りは拒否され、残高は変わらない
+		err := broker.PlaceOrder(models.NewMarketOrder("short-large", "EURUSD", models.Sell, 200000.0))
+		assert.ErrorContains(t, err, "insufficient balance")
+		assert.InDelta(t, 1000.0, broker.GetBalance(), 1e-9)
+		
+		// 残高内に収まる売りは建てられ、全て決済すると初期残高に戻る
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("short-small", "EURUSD", models.Sell, 100000.0)))
+		assert.InDelta(t, 0.0, broker.GetBalance(), 1e-9)
+		for _, position := range broker.GetPositions() {
+			assert.NoError(t, broker.ClosePosition(position.ID))
+		}
+		assert.InDelta(t, 10000.0, broker.GetBalance(), 1e-9)
+	})
+	
+	t.Run("should keep balance consistent across repeated shorts", func(t *testing.T) {
+		closes := []float64{1.10, 1.12, 1.09, 1.11, 1.08, 1.10, 1.13, 1.07}
+		_, mkt := createInMemoryBroker(t, closes)
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, Spread: 0.0002}, mkt)
+		
+		for i := 0; i < len(closes)-1; i++ {
+			assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder(fmt.Sprintf("short-%d", i), "EURUSD", models.Sell, 10000.0)))
+			mkt.Forward()
+			broker.UpdatePositions()
+			assert.NoError(t, broker.ClosePosition(fmt.Sprintf("pos-short-%d", i)))
+		}
+		
+		// 証拠金の拘束・返却で残高がずれず、損益の合計だけ変化する
+		total := 0.0
+		for _, trade := range broker.GetTradeHistory() {
+			total += trade.PnL
+		}
+		assert.InDelta(t, 10000.0+total, broker.GetBalance(), 1e-9)
+	})
+}
+
+func TestBroker_Netting(t *testing.T) {
+	createNettingBroker := func(t *testing.T, closes []float64, config models.BrokerConfig) (Broker, market.Market) {
+		_, mkt := createInMemoryBroker(t, closes)
+		config.InitialBalance = 10000.0
+		config.AccountMode = models.AccountNetting
+		return NewSimpleBroker(config, mkt), mkt
+	}
+	
+	t.Run("should combine, reduce, flip and close a single position", func(t *testing.T) {
+		broker, mkt := createNettingBroker(t, []float64{1.10, 1.20}, models.BrokerConfig{})
+		
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-1", "EURUSD", models.Buy, 1000.0)))
+		mkt.Forward()
+		broker.UpdatePositions()
+		
+		// 同じ方向は数量加重の平均建値で1つのポジションに合算される
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-2", "EURUSD", models.Buy, 1000.0)))
+		positions := broker.GetPositions()
+		if assert.Len(t, positions, 1) {
+			assert.Equal(t, "pos-net-1", positions[0].ID)
+			assert.Equal(t, 2000.0, positions[0].Size)
+			assert.InDelta(t, 1.15, positions[0].EntryPrice, 1e-9)
+			assert.InDelta(t, 23.0, positions[0].Margin, 1e-9)
+		}
+		assert.InDelta(t, 10000.0-23.0, broker.GetBalance(), 1e-9)
+		
+		// 反対方向は一部決済となり、拘束した証拠金を比例して返却する
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-3", "EURUSD", models.Sell, 500.0)))
+		positions = broker.GetPositions()
+		if assert.Len(t, positions, 1) {
+			assert.Equal(t, 1500.0, positions[0].Size)
+			assert.InDelta(t, 1.15, positions[0].EntryPrice, 1e-9)
+			assert.InDelta(t, 17.25, positions[0].Margin, 1e-9)
+		}
+		history := broker.GetTradeHistory()
+		if assert.Len(t, history, 1) {
+			assert.Equal(t, "pos-net-1-net-3", history[0].ID)
+			assert.Equal(t, 500.0, history[0].Size)
+			assert.InDelta(t, 25.0, history[0].PnL, 1e-9)
+		}
+		assert.InDelta(t, 10000.0-17.25+25.0, broker.GetBalance(), 1e-9)
+		
+		// ポジションを上回る反対方向の注文は、全て決済した上で残りで反転する
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-4", "EURUSD", models.Sell, 2500.0)))
+		positions = broker.GetPositions()
+		if assert.Len(t, positions, 1) {
+			assert.Equal(t, "pos-net-4", positions[0].ID)
+			assert.Equal(t, models.Sell, positions[0].Side)
+			assert.Equal(t, 1000.0, positions[0].Size)
+			assert.InDelta(t, 1.20, positions[0].EntryPrice, 1e-9)
+			assert.InDelta(t, 12.0, positions[0].Margin, 1e-9)
+		}
+		history = broker.GetTradeHistory()
+		if assert.Len(t, history, 2) {
+			assert.Equal(t, "pos-net-1", history[1].ID)
+			assert.Equal(t, 1500.0, history[1].Size)
+			assert.InDelta(t, 75.0, history[1].PnL, 1e-9)
+		}
+		
+		// 同じ数量の反対方向の注文でちょうど決済され、残高は初期残高と損益の合計の和になる
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-5", "EURUSD", models.Buy, 1000.0)))
+		assert.Empty(t, broker.GetPositions())
+		assert.Len(t, broker.GetTradeHistory(), 3)
+		assert.InDelta(t, 10000.0+25.0+75.0, broker.GetBalance(), 1e-9)
+	})
+	
+	t.Run("should keep independent positions in hedging mode", func(t *testing.T) {
+		broker, _ := createInMemoryBroker(t, []float64{1.10})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("hedge-1", "EURUSD", models.Buy, 1000.0)))
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("hedge-2", "EURUSD", models.Sell, 1000.0)))
+		assert.Len(t, broker.GetPositions(), 2)
+	})
+	
+	t.Run("should net pending order fills", func(t *testing.T) {
+		broker, mkt := createNettingBroker(t, []float64{1.10, 1.05}, models.BrokerConfig{})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-entry", "EURUSD", models.Buy, 1000.0)))
+		assert.NoError(t, broker.PlaceOrder(models.NewLimitOrder("net-limit", "EURUSD", models.Buy, 1000.0, 1.06)))
+		
+		var filled *models.Position
+		broker.OnOrderFilled(func(order *models.Order, position *models.Position) {
+			filled = position
+		})
+		mkt.Forward()
+		broker.UpdatePositions()
+		
+		positions := broker.GetPositions()
+		if assert.Len(t, positions, 1) {
+			assert.Same(t, positions[0], filled)
+			assert.Equal(t, 2000.0, positions[0].Size)
+			assert.InDelta(t, 1.075, positions[0].EntryPrice, 1e-9)
+		}
+	})
+	
+	t.Run("should leave the position unchanged when a flip lacks margin", func(t *testing.T) {
+		broker, _ := createNettingBroker(t, []float64{1.00}, models.BrokerConfig{})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-large", "EURUSD", models.Buy, 500000.0)))
+		
+		// 決済で 5000 が戻っても、反転後の 1500000 の証拠金 15000 には足りない
+		err := broker.PlaceOrder(models.NewMarketOrder("net-flip", "EURUSD", models.Sell, 2000000.0))
+		assert.ErrorContains(t, err, "insufficient balance")
+		positions := broker.GetPositions()
+		if assert.Len(t, positions, 1) {
+			assert.Equal(t, 500000.0, positions[0].Size)
+			assert.Equal(t, models.Buy, positions[0].Side)
+		}
+		assert.InDelta(t, 5000.0, broker.GetBalance(), 1e-9)
+		assert.Empty(t, broker.GetTradeHistory())
+	})
+	
+	t.Run("should not count a combined position twice against max open positions", func(t *testing.T) {
+		broker, _ := createNettingBroker(t, []float64{1.10}, models.BrokerConfig{MaxOpenPositions: 1})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-max-1", "EURUSD", models.Buy, 1000.0)))
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-max-2", "EURUSD", models.Buy, 1000.0)))
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("net-max-3", "EURUSD", models.Sell, 3000.0)))
+		assert.Len(t, broker.GetPositions(), 1)
+		
+		// 別のシンボルは新しいポジションとして数える
+		err := broker.PlaceOrder(models.NewMarketOrder("net-max-4", "GBPUSD", models.Buy, 1000.0))
+		assert.ErrorIs(t, err, ErrPositionLimit)
+	})
+}
+
+func TestBroker_FreeMargin(t *testing.T) {
+	t.Run("should report equity, used margin and free margin", func(t *testing.T) {
+		broker, mkt := createInMemoryBroker(t, []float64{1.00, 0.99, 1.01})
+		assert.InDelta(t, 10000.0, broker.GetFreeMargin(), 1e-9)
+		assert.InDelta(t, 10000.0, broker.GetEquity(), 1e-9)
+		assert.Zero(t, broker.GetUsedMargin())
+		
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("margin-1", "EURUSD", models.Buy, 500000.0)))
+		assert.InDelta(t, 5000.0, broker.GetUsedMargin(), 1e-9)
+		assert.InDelta(t, 5000.0, broker.GetFreeMargin(), 1e-9)
+		assert.InDelta(t, 10000.0, broker.GetEquity(), 1e-9)
+		
+		// 含み損は余剰証拠金と有効証拠金を減らす
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.InDelta(t, 5000.0, broker.GetUsedMargin(), 1e-9)
+		assert.InDelta(t, 0.0, broker.GetFreeMargin(), 1e-6)
+		assert.InDelta(t, 5000.0, broker.GetEquity(), 1e-6)
+		
+		// 含み益は余剰証拠金を増やす
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.InDelta(t, 10000.0, broker.GetFreeMargin(), 1e-6)
+		assert.InDelta(t, 15000.0, broker.GetEquity(), 1e-6)
+	})
+	
+	t.Run("should reject market orders exceeding free margin despite balance", func(t *testing.T) {
+		broker, mkt := createInMemoryBroker(t, []float64{1.00, 0.99})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("margin-2", "EURUSD", models.Buy, 400000.0)))
+		
+		// 残高 6000 に対し、含み損 4000 で余剰証拠金は 2000
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.InDelta(t, 6000.0, broker.GetBalance(), 1e-9)
+		assert.InDelta(t, 2000.0, broker.GetFreeMargin(), 1e-6)
+		
+		// 証拠金 2970 は残高内だが余剰証拠金を超える
+		order := models.NewMarketOrder("margin-3", "EURUSD", models.Sell, 300000.0)
+		err := broker.PlaceOrder(order)
+		assert.ErrorContains(t, err, "insufficient balance")
+		assert.ErrorContains(t, err, "free margin")
+		assert.True(t, order.IsPending())
+		assert.Len(t, broker.GetPositions(), 1)
+		
+		// 余剰証拠金内の注文は受け付ける
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("margin-4", "EURUSD", models.Sell, 100000.0)))
+		assert.Len(t, broker.GetPositions(), 2)
+	})
+	
+	t.Run("should keep pending orders unfilled while free margin is insufficient", func(t *testing.T) {
+		broker, mkt := createInMemoryBroker(t, []float64{1.00, 0.99, 0.98})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("margin-5", "EURUSD", models.Buy, 400000.0)))
+		limit := models.NewLimitOrder("margin-6", "EURUSD", models.Buy, 300000.0, 0.99)
+		assert.NoError(t, broker.PlaceOrder(limit))
+		
+		// 指値に達しても余剰証拠金 2000 では証拠金 2970 を確保できない
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.True(t, limit.IsPending())
+		assert.Len(t, broker.GetPositions(), 1)
+	})
+}
+
+func TestBroker_PreviewOrder(t *testing.T) {
+	t.Run("should match the result of placing a market order without side effects", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{1.10, 1.08})
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, Spread: 0.001}, mkt)
+		
+		order := models.NewMarketOrder("preview-1", "EURUSD", models.Buy, 10000.0)
+		preview, err := broker.PreviewOrder(order)
+		assert.NoError(t, err)
+		assert.True(t, preview.Immediate)
+		assert.Equal(t, 10000.0, preview.Size)
+		assert.InDelta(t, 1.101, preview.ExecutionPrice, 1e-9)
+		assert.InDelta(t, 0.001, preview.Spread, 1e-9)
+		assert.InDelta(t, 110.1, preview.RequiredMargin, 1e-9)
+		assert.InDelta(t, 10000.0, preview.FreeMargin, 1e-9)
+		
+		// 何も記録されていない
+		assert.True(t, order.IsPending())
+		assert.Empty(t, broker.GetPositions())
+		assert.InDelta(t, 10000.0, broker.GetBalance(), 1e-9)
+		_, found := broker.GetOrder("preview-1")
+		assert.False(t, found)
+		
+		// 実際に発注した結果と一致する
+		assert.NoError(t, broker.PlaceOrder(order))
+		assert.InDelta(t, preview.ExecutionPrice, order.ExecutedPrice, 1e-9)
+		assert.InDelta(t, preview.RequiredMargin, broker.GetUsedMargin(), 1e-9)
+		assert.InDelta(t, preview.FreeMarginAfter, broker.GetFreeMargin(), 1e-9)
+	})
+	
+	t.Run("should return the rejection reason with the estimate", func(t *testing.T) {
+		broker, _ := createInMemoryBroker(t, []float64{1.00, 1.01})
+		
+		preview, err := broker.PreviewOrder(models.NewMarketOrder("preview-2", "EURUSD", models.Buy, 2000000.0))
+		assert.ErrorContains(t, err, "insufficient balance")
+		assert.InDelta(t, 20000.0, preview.RequiredMargin, 1e-9)
+		assert.InDelta(t, -10000.0, preview.FreeMarginAfter, 1e-9)
+		
+		_, err = broker.PreviewOrder(models.NewMarketOrder("preview-3", "EURUSD", models.Buy, 0))
+		assert.Error(t, err)
+		
+		// 上限超過も PlaceOrder と同じエラーになる
+		_, mkt := createInMemoryBroker(t, []float64{1.00, 1.01})
+		limited := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, MaxOpenPositions: 1}, mkt)
+		assert.NoError(t, limited.PlaceOrder(models.NewMarketOrder("preview-4", "EURUSD", models.Buy, 1000.0)))
+		_, err = limited.PreviewOrder(models.NewMarketOrder("preview-5", "EURUSD", models.Buy, 1000.0))
+		assert.ErrorIs(t, err, ErrPositionLimit)
+	})
+	
+	t.Run("should estimate pending orders at their trigger price", func(t *testing.T) {
+		broker, _ := createInMemoryBroker(t, []float64{1.00, 0.99})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("preview-6", "EURUSD", models.Buy, 400000.0)))
+		
+		// 0.99 では既存ポジションの含み損 4000 を差し引いた余剰証拠金 2000 で約定を判断する
+		limit := models.NewLimitOrder("preview-7", "EURUSD", models.Buy, 100000.0, 0.99)
+		preview, err := broker.PreviewOrder(limit)
+		assert.NoError(t, err)
+		assert.False(t, preview.Immediate)
+		assert.InDelta(t, 0.99, preview.ExecutionPrice, 1e-9)
+		assert.InDelta(t, 990.0, preview.RequiredMargin, 1e-9)
+		assert.InDelta(t, 6000.0, preview.FreeMargin, 1e-9)
+		assert.InDelta(t, 2000.0-990.0, preview.FreeMarginAfter, 1e-6)
+		assert.Empty(t, broker.GetPendingOrders())
+		
+		_, err = broker.PreviewOrder(models.NewLimitOrder("preview-8", "EURUSD", models.Buy, 300000.0, 0.99))
+		assert.ErrorContains(t, err, "insufficient balance")
+	})
+	
+	t.Run("should only require margin for the flipped size in netting mode", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{1.00, 1.01})
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, AccountMode: models.AccountNetting}, mkt)
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("preview-9", "EURUSD", models.Buy, 1000.0)))
+		
+		preview, err := broker.PreviewOrder(models.NewMarketOrder("preview-10", "EURUSD", models.Sell, 1500.0))
+		assert.NoError(t, err)
+		assert.InDelta(t, 5.0, preview.RequiredMargin, 1e-9)
+		// 買い 1000 を決済して証拠金 10 が戻り、売り 500 に証拠金 5 を拘束する
+		assert.InDelta(t, 10000.0-5.0, preview.FreeMarginAfter, 1e-9)
+		assert.Len(t, broker.GetPositions(), 1)
+		assert.Equal(t, models.Buy, broker.GetPositions()[0].Side)
+	})
+}
+
+func TestBroker_Slippage(t *testing.T) {
+	t.Run("should apply fixed slippage adversely and record its cost", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{1.10, 1.10})
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, Spread: 0.0001, Slippage: 0.0002}, mkt)
+		
+		buy := models.NewMarketOrder("slip-1", "EURUSD", models.Buy, 10000.0)
+		assert.NoError(t, broker.PlaceOrder(buy))
+		assert.InDelta(t, 1.1003, buy.ExecutedPrice, 1e-9)
+		
+		position := broker.GetPositions()[0]
+		assert.NoError(t, broker.ClosePosition(position.ID))
+		trades := broker.GetTradeHistory()
+		assert.Len(t, trades, 1)
+		assert.InDelta(t, 1.0997, trades[0].ExitPrice, 1e-9)
+		assert.InDelta(t, 2*0.0001*10000.0, trades[0].SpreadCost, 1e-9)
+		assert.InDelta(t, 2*0.0002*10000.0, trades[0].SlippageCost, 1e-9)
+		assert.InDelta(t, -trades[0].TotalCost(), trades[0].PnL, 1e-9)
+		
+		sell := models.NewMarketOrder("slip-2", "EURUSD", models.Sell, 10000.0)
+		assert.NoError(t, broker.PlaceOrder(sell))
+		assert.InDelta(t, 1.0997, sell.ExecutedPrice, 1e-9)
+	})
+	
+	t.Run("should reproduce random slippage from the same seed", func(t *testing.T) {
+		fills := func(seed int64) []float64 {
+			_, mkt := createInMemoryBroker(t, []float64{1.10, 1.10})
+			broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, Slippage: 0.001, SlippageMode: models.SlippageRandom}, mkt)
+			broker.SetRand(rand.New(rand.NewSource(seed)))
+			prices := make([]float64, 0, 5)
+			for i := 0; i < 5; i++ {
+				order := models.NewMarketOrder(fmt.Sprintf("rand-%d", i), "EURUSD", models.Buy, 1000.0)
+				assert.NoError(t, broker.PlaceOrder(order))
+				assert.GreaterOrEqual(t, order.ExecutedPrice, 1.10)
+				assert.LessOrEqual(t, order.ExecutedPrice, 1.101)
+				prices = append(prices, order.ExecutedPrice)
+			}
+			return prices
+		}
+		
+		first := fills(42)
+		assert.Equal(t, first, fills(42))
+		assert.NotEqual(t, first, fills(7))
+		
+		distinct := map[float64]bool{}
+		for _, price := range first {
+			distinct[price] = true
+		}
+		assert.Greater(t, len(distinct), 1, "約定ごとに異なるスリッページになる")
+	})
+	
+	t.Run("should preview the maximum slippage without consuming the generator", func(t *testing.T) {
+		_, mkt := createInMemoryBroker(t, []float64{1.10, 1.10})
+		config := models.BrokerConfig{InitialBalance: 10000.0, Slippage: 0.001, SlippageMode: models.SlippageRandom}
+		previewed := NewSimpleBroker(config, mkt)
+		previewed.SetRand(rand.New(rand.NewSource(1)))
+		plain := NewSimpleBroker(config, mkt)
+		plain.SetRand(rand.New(rand.NewSource(1)))
+		
+		preview, err := previewed.PreviewOrder(models.NewMarketOrder("preview-slip", "EURUSD", models.Buy, 1000.0))
+		assert.NoError(t, err)
+		assert.InDelta(t, 0.001, preview.Slippage, 1e-9)
+		assert.InDelta(t, 1.101, preview.ExecutionPrice, 1e-9)
+		
+		a := models.NewMarketOrder("slip-a", "EURUSD", models.Buy, 1000.0)
+		b := models.NewMarketOrder("slip-b", "EURUSD", models.Buy, 1000.0)
+		assert.NoError(t, previewed.PlaceOrder(a))
+		assert.NoError(t, plain.PlaceOrder(b))
+		assert.Equal(t, b.ExecutedPrice, a.ExecutedPrice)
+	})
+}
+
+func TestBroker_FillModel(t *testing.T) {
+	// 1.10 の横ばい → 1.08〜1.12 の値幅で終値 1.10 → 1.15 に窓を開けて上昇
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	candles := []models.Candle{
+		*models.NewCandle(baseTime, 1.10, 1.10, 1.10, 1.10, 1000),
+		*models.NewCandle(baseTime.Add(time.Minute), 1.10, 1.12, 1.08, 1.10, 1000),
+		*models.NewCandle(baseTime.Add(2*time.Minute), 1.15, 1.16, 1.14, 1.15, 1000),
+	}
+	createFillBroker := func(t *testing.T, fillModel models.FillModel) (Broker, market.Market) {
+		mkt := market.NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		if err := mkt.Initialize(context.Background()); err != nil {
+			t.Fatalf("Failed to initialize market: %v", err)
+		}
+		return NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, FillModel: fillModel}, mkt), mkt
+	}
+	// 各注文を1本目で発注し、2本目・3本目で約定を判定して約定価格（未約定は0）を返す
+	fills := func(t *testing.T, fillModel models.FillModel, orders ...*models.Order) map[string][]float64 {
+		broker, mkt := createFillBroker(t, fillModel)
+		for _, order := range orders {
+			assert.NoError(t, broker.PlaceOrder(order))
+		}
+		result := make(map[string][]float64)
+		for bar := 1; bar <= 2; bar++ {
+			mkt.Forward()
+			broker.ProcessPendingOrders()
+			for _, order := range orders {
+				filled, _ := broker.GetOrder(order.ID)
+				if filled.Status == models.Executed && len(result[order.ID]) == 0 {
+					result[order.ID] = []float64{float64(bar), filled.ExecutedPrice}
+				}
+			}
+		}
+		return result
+	}
+	newOrders := func() []*models.Order {
+		return []*models.Order{
+			models.NewLimitOrder("buy-limit", "EURUSD", models.Buy, 1000.0, 1.09),
+			models.NewLimitOrder("buy-limit-low", "EURUSD", models.Buy, 1000.0, 1.08),
+			models.NewLimitOrder("sell-limit", "EURUSD", models.Sell, 1000.0, 1.12),
+			models.NewStopOrder("sell-stop", "EURUSD", models.Sell, 1000.0, 1.09),
+			models.NewStopOrder("buy-stop", "EURUSD", models.Buy, 1000.0, 1.13),
+		}
+	}
+	
+	t.Run("should only see the close by default", func(t *testing.T) {
+		result := fills(t, "", newOrders()...)
+		assert.NotContains(t, result, "buy-limit", "close 1.10 never reaches 1.09")
+		assert.NotContains(t, result, "sell-stop")
+		// 窓を開けた終値 1.15 で約定する
+		assert.Equal(t, []float64{2, 1.15}, result["buy-stop"])
+	})
+	
+	t.Run("should fill at order price when high or low touches it", func(t *testing.T) {
+		result := fills(t, models.FillOnTouch, newOrders()...)
+		assert.Equal(t, []float64{1, 1.09}, result["buy-limit"])
+		assert.Equal(t, []float64{1, 1.08}, result["buy-limit-low"])
+		assert.Equal(t, []float64{1, 1.12}, result["sell-limit"])
+		assert.Equal(t, []float64{1, 1.09}, result["sell-stop"])
+		// 始値 1.15 が逆指値 1.13 を越えているため、不利な始値で約定する
+		assert.Equal(t, []float64{2, 1.15}, result["buy-stop"])
+	})
+	
+	t.Run("should require price to trade through in cross mode", func(t *testing.T) {
+		result := fills(t, models.FillOnCross, newOrders()...)
+		assert.Equal(t, []float64{1, 1.09}, result["buy-limit"])
+		assert.NotContains(t, result, "buy-limit-low", "low 1.08 only touches the limit")
+		// 高値 1.12 は指値に触れただけで約定せず、次の足で始値 1.15 で約定する
+		assert.Equal(t, []float64{2, 1.15}, result["sell-limit"])
+		assert.Equal(t, []float64{2, 1.15}, result["buy-stop"])
+	})
+	
+	t.Run("should fill limit at the open after a favourable gap", func(t *testing.T) {
+		result := fills(t, models.FillOnTouch, models.NewLimitOrder("sell-limit-gap", "EURUSD", models.Sell, 1000.0, 1.13))
+		assert.Equal(t, []float64{2, 1.15}, result["sell-limit-gap"])
+	})
+	
+	t.Run("should apply spread on top of the fill price", func(t *testing.T) {
+		_, mkt := createFillBroker(t, models.FillOnTouch)
+		broker := NewSimpleBroker(models.BrokerConfig{InitialBalance: 10000.0, Spread: 0.0001, FillModel: models.FillOnTouch}, mkt)
+		order := models.NewLimitOrder("spread-limit", "EURUSD", models.Buy, 1000.0, 1.09)
+		assert.NoError(t, broker.PlaceOrder(order))
+		mkt.Forward()
+		broker.ProcessPendingOrders()
+		assert.Equal(t, models.Executed, order.Status)
+		assert.InDelta(t, 1.0901, order.ExecutedPrice, 1e-9)
+		if positions := broker.GetPositions(); assert.Len(t, positions, 1) {
+			assert.Equal(t, 1.10, positions[0].CurrentPrice)
+		}
+	})
+}
+
+func TestBroker_ProtectionModel(t *testing.T) {
+	// 1.10 の横ばい → 1.08〜1.12 の値幅で終値 1.10 → 1.05 に窓を開けて下落
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	candles := []models.Candle{
+		*models.NewCandle(baseTime, 1.10, 1.10, 1.10, 1.10, 1000),
+		*models.NewCandle(baseTime.Add(time.Minute), 1.10, 1.12, 1.08, 1.10, 1000),
+		*models.NewCandle(baseTime.Add(2*time.Minute), 1.05, 1.06, 1.04, 1.05, 1000),
+	}
+	// 1本目で保護価格付きの成行注文を約定させ、2本目・3本目で決済を判定して決済した足と決済価格（未決済は nil）を返す
+	exit := func(t *testing.T, config models.BrokerConfig, side models.OrderSide, stopLoss, takeProfit float64) []float64 {
+		mkt := market.NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		if err := mkt.Initialize(context.Background()); err != nil {
+			t.Fatalf("Failed to initialize market: %v", err)
+		}
+		config.InitialBalance = 10000.0
+		broker := NewSimpleBroker(config, mkt)
+		order := models.NewMarketOrder("protected", "EURUSD", side, 1000.0)
+		order.StopLoss, order.TakeProfit = stopLoss, takeProfit
+		assert.NoError(t, broker.PlaceOrder(order))
+		
+		for bar := 1; bar <= 2; bar++ {
+			mkt.Forward()
+			broker.UpdatePositions()
+			if history := broker.GetTradeHistory(); len(history) > 0 {
+				return []float64{float64(bar), history[0].ExitPrice}
+			}
+		}
+		return nil
+	}
+	intrabar := models.BrokerConfig{ProtectionModel: models.ProtectionIntrabar}
+	
+	t.Run("should only see the close by default", func(t *testing.T) {
+		// 2本目の終値 1.10 は保護価格に届かず、ストップロス 1.09 を大きく下回る3本目の終値 1.05 で決済する
+		assert.Equal(t, []float64{2, 1.05}, exit(t, models.BrokerConfig{}, models.Buy, 1.09, 1.11))
+		assert.Nil(t, exit(t, models.BrokerConfig{}, models.Sell, 1.11, 1.04), "close 1.05 never reaches take profit")
+	})
+	
+	t.Run("should close at protective price when high or low touches it", func(t *testing.T) {
+		assert.Equal(t, []float64{1, 1.09}, exit(t, intrabar, models.Buy, 1.09, 1.13))
+		assert.Equal(t, []float64{1, 1.11}, exit(t, intrabar, models.Buy, 1.07, 1.11))
+		assert.Equal(t, []float64{1, 1.08}, exit(t, intrabar, models.Sell, 1.13, 1.08), "low exactly touches take profit")
+	})
+	
+	t.Run("should assume stop loss first when both are touched", func(t *testing.T) {
+		assert.Equal(t, []float64{1, 1.09}, exit(t, intrabar, models.Buy, 1.09, 1.11))
+		assert.Equal(t, []float64{1, 1.11}, exit(t, intrabar, models.Sell, 1.11, 1.09))
+	})
+	
+	t.Run("should close at the open after a gap", func(t *testing.T) {
+		// ストップロスは不利な始値、テイクプロフィットは有利な始値で決済する
+		assert.Equal(t, []float64{2, 1.05}, exit(t, intrabar, models.Buy, 1.07, 1.13))
+		assert.Equal(t, []float64{2, 1.05}, exit(t, intrabar, models.Sell, 1.13, 1.07))
+	})
+	
+	t.Run("should apply spread on top of the protective price", func(t *testing.T) {
+		config := models.BrokerConfig{Spread: 0.0001, ProtectionModel: models.ProtectionIntrabar}
+		result := exit(t, config, models.Buy, 1.09, 1.13)
+		if assert.Len(t, result, 2) {
+			assert.InDelta(t, 1.0899, result[1], 1e-9)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/data"
+)
+
+// ErrNoConversionRate は決済通貨から口座通貨への換算レートが得られない場合のエラーです。
+var ErrNoConversionRate = errors.New("no conversion rate to account currency")
+
+// RateSource は通貨の換算レートを提供します。
+// Rate は時刻 t に from 通貨1単位が to 通貨の何単位になるかを返します。
+type RateSource interface {
+	Rate(from, to string, t time.Time) (float64, error)
+}
+
+// StaticRates は通貨ペア（"USDJPY" など）をキーとする固定の換算レートです。
+// USDJPY が 150 の場合、USD→JPY は 150、JPY→USD は 1/150 として時刻に関係なく使います。
+type StaticRates map[string]float64
+
+// Rate は from から to への換算レートを返します。ペアが逆向きに登録されている場合は逆数を返します。
+func (r StaticRates) Rate(from, to string, t time.Time) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1.0, nil
+	}
+	for pair, rate := range r {
+		if rate <= 0 {
+			continue
+		}
+		switch strings.ToUpper(pair) {
+		case from + to:
+			return rate, nil
+		case to + from:
+			return 1.0 / rate, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s to %s", ErrNoConversionRate, from, to)
+}
+
+// FeedRates は通貨ペアをキーとする価格データから換算レートを求めます。
+// レートは時刻 t 時点で有効なローソク足（t 以前で最も新しいもの）の終値です。
+type FeedRates map[string]data.DataProvider
+
+// Rate は from から to への換算レートを返します。ペアが逆向きに登録されている場合は逆数を返します。
+func (r FeedRates) Rate(from, to string, t time.Time) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1.0, nil
+	}
+	for pair, provider := range r {
+		switch strings.ToUpper(pair) {
+		case from + to:
+			return closeAt(provider, t)
+		case to + from:
+			rate, err := closeAt(provider, t)
+			if err != nil {
+				return 0, err
+			}
+			return 1.0 / rate, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s to %s", ErrNoConversionRate, from, to)
+}
+
+// closeAt は時刻 t 時点で有効なローソク足の終値を返します。
+func closeAt(provider data.DataProvider, t time.Time) (float64, error) {
+	index, err := provider.TimeToIndex(t)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrNoConversionRate, err)
+	}
+	candles, err := provider.GetCandlesByIndex(context.Background(), index, index)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrNoConversionRate, err)
+	}
+	if len(candles) == 0 || candles[0].Close <= 0 {
+		return 0, fmt.Errorf("%w: no candle at %v", ErrNoConversionRate, t)
+	}
+	return candles[0].Close, nil
+}
+
+// currencies は指定シンボルの基軸通貨と決済通貨を返します。
+// 決済通貨は銘柄メタデータの QuoteCurrency を優先し、6文字のシンボルは前半3文字を基軸通貨、後半3文字を決済通貨とみなします。
+func (b *SimpleBroker) currencies(symbol string) (base, quote string) {
+	symbol = strings.ToUpper(symbol)
+	if len(symbol) == 6 {
+		base, quote = symbol[:3], symbol[3:]
+	}
+	if instrument, ok := b.instruments.Get(symbol); ok && instrument.QuoteCurrency != "" {
+		quote = strings.ToUpper(instrument.QuoteCurrency)
+	}
+	return base, quote
+}
+
+// conversionRate は指定シンボルの決済通貨1単位を口座通貨に換算するレートを返します。
+// AccountCurrency が未設定の場合と、決済通貨が口座通貨と同じ・不明の場合は1です。
+// 基軸通貨が口座通貨の場合（EUR口座での EURUSD など）は現在価格の逆数を、それ以外はレートソースを使います。
+func (b *SimpleBroker) conversionRate(symbol string) (float64, error) {
+	account := strings.ToUpper(b.config.AccountCurrency)
+	base, quote := b.currencies(symbol)
+	if account == "" || quote == "" || quote == account {
+		return 1.0, nil
+	}
+
+	if base == account {
+		if price := b.market.GetCurrentPrice(); price > 0 {
+			return 1.0 / price, nil
+		}
+	}
+	if b.rates == nil {
+		return 0, fmt.Errorf("%w: %s to %s", ErrNoConversionRate, quote, account)
+	}
+	return b.rates.Rate(quote, account, b.market.GetCurrentTime())
+}
+
+// checkConversionRate は注文を受け付ける前に、指定シンボルの換算レートが得られるかを検証します。
+func (b *SimpleBroker) checkConversionRate(symbol string) error {
+	rate, err := b.conversionRate(symbol)
+	if err != nil {
+		return err
+	}
+	b.ratesMutex.Lock()
+	b.lastRates[strings.ToUpper(symbol)] = rate
+	b.ratesMutex.Unlock()
+	return nil
+}
+
+// toAccount は指定シンボルの決済通貨建ての金額を口座通貨建てに換算します。
+func (b *SimpleBroker) toAccount(symbol string, amount float64) float64 {
+	if b.config.AccountCurrency == "" || amount == 0 {
+		return amount
+	}
+	return amount * b.accountRate(symbol)
+}
+
+// accountRate は toAccount が使う、指定シンボルの決済通貨1単位あたりの口座通貨の額を返します。
+// レートが一時的に得られない場合（換算用データの終端以降など）は最後に得られたレートを、一度も得られていない場合は1を返します。
+func (b *SimpleBroker) accountRate(symbol string) float64 {
+	key := strings.ToUpper(symbol)
+	rate, err := b.conversionRate(symbol)
+	b.ratesMutex.Lock()
+	defer b.ratesMutex.Unlock()
+	if err != nil {
+		last, ok := b.lastRates[key]
+		if !ok {
+			return 1.0
+		}
+		return last
+	}
+	b.lastRates[key] = rate
+	return rate
+}
+
+// ConvertToAccount は指定シンボルの決済通貨建ての金額を、現在の換算レートで口座通貨建てに換算します。
+// AccountCurrency が未設定の場合や換算レートが一度も得られていない場合はそのまま返します。
+func (b *SimpleBroker) ConvertToAccount(symbol string, amount float64) float64 {
+	return b.toAccount(symbol, amount)
+}
+
+// SetRateSource は口座通貨への換算レートの取得元を設定します。
+// BrokerConfig.ConversionRates が設定されている場合、作成時にはそれを StaticRates として使います。
+func (b *SimpleBroker) SetRateSource(source RateSource) {
+	b.rates = source
+}
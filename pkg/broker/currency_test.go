@@ -0,0 +1,171 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/data"
+	"github.com/RuiHirano/fx-backtesting/pkg/market"
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticRates_Rate(t *testing.T) {
+	rates := StaticRates{"USDJPY": 150.0, "eurusd": 1.10}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		from, to string
+		expected float64
+	}{
+		{"registered pair", "USD", "JPY", 150.0},
+		{"inverse pair", "JPY", "USD", 1.0 / 150.0},
+		{"lower case pair", "eur", "usd", 1.10},
+		{"same currency", "JPY", "JPY", 1.0},
+	}
+	for _, tt := range tests {
+		t.Run("should return rate for "+tt.name, func(t *testing.T) {
+			rate, err := rates.Rate(tt.from, tt.to, now)
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.expected, rate, 1e-12)
+		})
+	}
+
+	t.Run("should return error for unknown pair", func(t *testing.T) {
+		_, err := rates.Rate("GBP", "JPY", now)
+		assert.True(t, errors.Is(err, ErrNoConversionRate))
+	})
+}
+
+func TestFeedRates_Rate(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	feed := data.NewInMemoryProvider([]models.Candle{
+		*models.NewCandle(baseTime, 150.0, 150.0, 150.0, 150.0, 1000),
+		*models.NewCandle(baseTime.Add(time.Hour), 151.0, 152.0, 150.0, 152.0, 1000),
+	})
+	rates := FeedRates{"USDJPY": feed}
+
+	t.Run("should use the close of the latest candle at or before t", func(t *testing.T) {
+		rate, err := rates.Rate("USD", "JPY", baseTime.Add(30*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 150.0, rate)
+
+		rate, err = rates.Rate("JPY", "USD", baseTime.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.InDelta(t, 1.0/152.0, rate, 1e-12)
+	})
+
+	t.Run("should return error after the end of the feed or for unknown pair", func(t *testing.T) {
+		_, err := rates.Rate("USD", "JPY", baseTime.Add(2*time.Hour))
+		assert.True(t, errors.Is(err, ErrNoConversionRate))
+
+		_, err = rates.Rate("EUR", "JPY", baseTime)
+		assert.True(t, errors.Is(err, ErrNoConversionRate))
+	})
+}
+
+func TestBroker_AccountCurrency(t *testing.T) {
+	// EURUSD 1.10 → 1.12
+	createCurrencyBroker := func(t *testing.T, config models.BrokerConfig) (Broker, market.Market) {
+		baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		candles := []models.Candle{
+			*models.NewCandle(baseTime, 1.10, 1.10, 1.10, 1.10, 1000),
+			*models.NewCandle(baseTime.Add(time.Hour), 1.12, 1.12, 1.12, 1.12, 1000),
+		}
+		mkt := market.NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		if err := mkt.Initialize(context.Background()); err != nil {
+			t.Fatalf("Failed to initialize market: %v", err)
+		}
+		return NewSimpleBroker(config, mkt), mkt
+	}
+
+	t.Run("should convert PnL and margin from quote currency to account currency", func(t *testing.T) {
+		broker, mkt := createCurrencyBroker(t, models.BrokerConfig{
+			InitialBalance:  1000000.0,
+			AccountCurrency: "JPY",
+			ConversionRates: map[string]float64{"USDJPY": 150.0},
+		})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("order-1", "EURUSD", models.Buy, 1000.0)))
+		// 証拠金 1000 × 1.10 / 100 = 11 USD = 1650 JPY
+		assert.InDelta(t, 1650.0, broker.GetUsedMargin(), 1e-9)
+		assert.InDelta(t, 1000000.0-1650.0, broker.GetBalance(), 1e-9)
+
+		// 含み益 1000 × 0.02 = 20 USD = 3000 JPY
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.InDelta(t, 1003000.0, broker.GetEquity(), 1e-6)
+		assert.InDelta(t, 3000.0, broker.ConvertToAccount("EURUSD", 20.0), 1e-9)
+
+		positions := broker.GetPositions()
+		assert.NoError(t, broker.ClosePosition(positions[0].ID))
+		trade := broker.GetTradeHistory()[0]
+		assert.InDelta(t, 3000.0, trade.PnL, 1e-6)
+		assert.InDelta(t, 1003000.0, broker.GetBalance(), 1e-6)
+	})
+
+	t.Run("should convert spread cost to account currency", func(t *testing.T) {
+		broker, mkt := createCurrencyBroker(t, models.BrokerConfig{
+			InitialBalance:  1000000.0,
+			Spread:          0.0001,
+			AccountCurrency: "JPY",
+			ConversionRates: map[string]float64{"USDJPY": 150.0},
+		})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("order-1", "EURUSD", models.Buy, 1000.0)))
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.NoError(t, broker.ClosePosition(broker.GetPositions()[0].ID))
+
+		// 往復 0.0002 × 1000 = 0.2 USD = 30 JPY
+		trade := broker.GetTradeHistory()[0]
+		assert.InDelta(t, 30.0, trade.SpreadCost, 1e-6)
+		assert.InDelta(t, (0.02-0.0002)*1000*150.0, trade.PnL, 1e-6)
+	})
+
+	t.Run("should use the traded price when the base currency is the account currency", func(t *testing.T) {
+		broker, mkt := createCurrencyBroker(t, models.BrokerConfig{InitialBalance: 10000.0, AccountCurrency: "EUR"})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("order-1", "EURUSD", models.Buy, 1000.0)))
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.NoError(t, broker.ClosePosition(broker.GetPositions()[0].ID))
+
+		// 20 USD を決済時の EURUSD 1.12 で EUR に換算する
+		assert.InDelta(t, 20.0/1.12, broker.GetTradeHistory()[0].PnL, 1e-9)
+	})
+
+	t.Run("should convert at the rate of the feed at close time", func(t *testing.T) {
+		broker, mkt := createCurrencyBroker(t, models.BrokerConfig{InitialBalance: 1000000.0, AccountCurrency: "JPY"})
+		baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		broker.SetRateSource(FeedRates{"USDJPY": data.NewInMemoryProvider([]models.Candle{
+			*models.NewCandle(baseTime, 150.0, 150.0, 150.0, 150.0, 1000),
+			*models.NewCandle(baseTime.Add(time.Hour), 140.0, 140.0, 140.0, 140.0, 1000),
+		})})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("order-1", "EURUSD", models.Buy, 1000.0)))
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.NoError(t, broker.ClosePosition(broker.GetPositions()[0].ID))
+
+		assert.InDelta(t, 20.0*140.0, broker.GetTradeHistory()[0].PnL, 1e-6)
+	})
+
+	t.Run("should reject orders without a conversion rate", func(t *testing.T) {
+		broker, _ := createCurrencyBroker(t, models.BrokerConfig{InitialBalance: 1000000.0, AccountCurrency: "JPY"})
+		order := models.NewMarketOrder("order-1", "EURUSD", models.Buy, 1000.0)
+		err := broker.PlaceOrder(order)
+		assert.True(t, errors.Is(err, ErrNoConversionRate))
+		assert.Empty(t, broker.GetPositions())
+		_, exists := broker.GetOrder(order.ID)
+		assert.False(t, exists)
+	})
+
+	t.Run("should not convert when quote currency is the account currency", func(t *testing.T) {
+		broker, mkt := createCurrencyBroker(t, models.BrokerConfig{InitialBalance: 10000.0, AccountCurrency: "USD"})
+		assert.NoError(t, broker.PlaceOrder(models.NewMarketOrder("order-1", "EURUSD", models.Buy, 1000.0)))
+		mkt.Forward()
+		broker.UpdatePositions()
+		assert.NoError(t, broker.ClosePosition(broker.GetPositions()[0].ID))
+		assert.InDelta(t, 20.0, broker.GetTradeHistory()[0].PnL, 1e-9)
+	})
+}
@@ -0,0 +1,159 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// InMemoryProvider はメモリ上のローソク足スライスからデータを提供します。
+// ファイルを使わずに、作成した価格系列でバックテストや戦略のテストを行うためのものです。
+type InMemoryProvider struct {
+	candles []models.Candle
+}
+
+// NewInMemoryProvider は新しいInMemoryProviderを作成します。
+// 渡されたスライスはコピーされ、時刻順に並べ替えられます。
+func NewInMemoryProvider(candles []models.Candle) *InMemoryProvider {
+	sorted := make([]models.Candle, len(candles))
+	copy(sorted, candles)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+	
+	return &InMemoryProvider{candles: sorted}
+}
+
+// Len はローソク足の本数を返します。
+func (p *InMemoryProvider) Len() int {
+	return len(p.candles)
+}
+
+// TimeToIndex は時刻をインデックスに変換します。一致する時刻がない場合は直前のローソク足のインデックスを返します。
+func (p *InMemoryProvider) TimeToIndex(t time.Time) (int, error) {
+	if len(p.candles) == 0 {
+		return -1, errors.New("no data available")
+	}
+	
+	if t.After(p.candles[len(p.candles)-1].Timestamp) {
+		return -1, errors.New("time after last available data")
+	}
+	
+	// t より後の最初のローソク足の1つ前
+	index := sort.Search(len(p.candles), func(i int) bool {
+		return p.candles[i].Timestamp.After(t)
+	}) - 1
+	if index < 0 {
+		return 0, nil
+	}
+	return index, nil
+}
+
+// IndexToTime はインデックスを時刻に変換します。
+func (p *InMemoryProvider) IndexToTime(index int) (time.Time, error) {
+	if index < 0 || index >= len(p.candles) {
+		return time.Time{}, ErrIndexOutOfRange
+	}
+	return p.candles[index].Timestamp, nil
+}
+
+// GetCandlesByTime は指定された時間範囲のローソク足データを取得します。
+func (p *InMemoryProvider) GetCandlesByTime(ctx context.Context, startTime, endTime time.Time) ([]models.Candle, error) {
+	if startTime.After(endTime) {
+		return nil, errors.New("start time must be before end time")
+	}
+	
+	startIndex, err := p.TimeToIndex(startTime)
+	if err != nil {
+		return nil, err
+	}
+	
+	endIndex, err := p.TimeToIndex(endTime)
+	if err != nil {
+		return nil, err
+	}
+	
+	return p.GetCandlesByIndex(ctx, startIndex, endIndex)
+}
+
+// GetCandlesByIndex は指定されたインデックス範囲のローソク足データを取得します。
+// 終端を超える範囲はデータ末尾までに切り詰めます。
+func (p *InMemoryProvider) GetCandlesByIndex(ctx context.Context, startIndex, endIndex int) ([]models.Candle, error) {
+	if startIndex > endIndex {
+		return nil, errors.New("start index must be less than or equal to end index")
+	}
+	
+	if startIndex < 0 || startIndex >= len(p.candles) {
+		return nil, ErrIndexOutOfRange
+	}
+	
+	if endIndex >= len(p.candles) {
+		endIndex = len(p.candles) - 1
+	}
+	
+	candles := make([]models.Candle, endIndex-startIndex+1)
+	copy(candles, p.candles[startIndex:endIndex+1])
+	return candles, nil
+}
+
+// GetPrevCandlesByTime は基準時刻より前のローソク足データを取得します。
+func (p *InMemoryProvider) GetPrevCandlesByTime(ctx context.Context, baseTime time.Time, count int) ([]models.Candle, error) {
+	if count <= 0 {
+		return []models.Candle{}, nil
+	}
+	
+	baseIndex, err := p.TimeToIndex(baseTime)
+	if err != nil {
+		return nil, err
+	}
+	
+	return p.GetPrevCandlesByIndex(ctx, baseIndex, count)
+}
+
+// GetPrevCandlesByIndex は基準インデックスより前のローソク足データを取得します。
+func (p *InMemoryProvider) GetPrevCandlesByIndex(ctx context.Context, baseIndex int, count int) ([]models.Candle, error) {
+	if baseIndex < 0 || baseIndex >= len(p.candles) {
+		return nil, errors.New("base index out of range")
+	}
+	
+	if count <= 0 || baseIndex == 0 {
+		return []models.Candle{}, nil
+	}
+	
+	startIndex := baseIndex - count
+	if startIndex < 0 {
+		startIndex = 0
+	}
+	
+	return p.GetCandlesByIndex(ctx, startIndex, baseIndex-1)
+}
+
+// GetNextCandlesByTime は基準時刻より後のローソク足データを取得します。
+func (p *InMemoryProvider) GetNextCandlesByTime(ctx context.Context, baseTime time.Time, count int) ([]models.Candle, error) {
+	if count <= 0 {
+		return []models.Candle{}, nil
+	}
+	
+	baseIndex, err := p.TimeToIndex(baseTime)
+	if err != nil {
+		return nil, err
+	}
+	
+	return p.GetNextCandlesByIndex(ctx, baseIndex, count)
+}
+
+// GetNextCandlesByIndex は基準インデックスより後のローソク足データを取得します。
+func (p *InMemoryProvider) GetNextCandlesByIndex(ctx context.Context, baseIndex int, count int) ([]models.Candle, error) {
+	if baseIndex < 0 || baseIndex >= len(p.candles) {
+		return nil, errors.New("base index out of range")
+	}
+	
+	if count <= 0 || baseIndex+1 >= len(p.candles) {
+		return []models.Candle{}, nil
+	}
+	
+	return p.GetCandlesByIndex(ctx, baseIndex+1, baseIndex+count)
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// createMemoryCandles は1分足を count 本、逆順で作成する
+func createMemoryCandles(baseTime time.Time, count int) []models.Candle {
+	candles := make([]models.Candle, 0, count)
+	for i := count - 1; i >= 0; i-- {
+		price := 100.0 + float64(i)
+		candles = append(candles, *models.NewCandle(baseTime.Add(time.Duration(i)*time.Minute), price, price+0.5, price-0.5, price, 1000))
+	}
+	return candles
+}
+
+func TestInMemoryProvider_ImplementsDataProvider(t *testing.T) {
+	var _ DataProvider = NewInMemoryProvider(nil)
+}
+
+func TestInMemoryProvider_TimeIndexConversion(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	provider := NewInMemoryProvider(createMemoryCandles(baseTime, 10))
+	
+	if provider.Len() != 10 {
+		t.Fatalf("Len() = %d, want 10", provider.Len())
+	}
+	
+	tests := []struct {
+		name      string
+		time      time.Time
+		wantIndex int
+		wantErr   bool
+	}{
+		{name: "exact time", time: baseTime.Add(3 * time.Minute), wantIndex: 3},
+		{name: "between candles returns previous", time: baseTime.Add(3*time.Minute + 30*time.Second), wantIndex: 3},
+		{name: "before first candle", time: baseTime.Add(-time.Hour), wantIndex: 0},
+		{name: "after last candle", time: baseTime.Add(time.Hour), wantErr: true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.TimeToIndex(tt.time)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TimeToIndex() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.wantIndex {
+				t.Errorf("TimeToIndex() = %d, want %d", got, tt.wantIndex)
+			}
+		})
+	}
+	
+	got, err := provider.IndexToTime(9)
+	if err != nil || !got.Equal(baseTime.Add(9*time.Minute)) {
+		t.Errorf("IndexToTime(9) = %v, %v", got, err)
+	}
+	if _, err := provider.IndexToTime(10); err == nil {
+		t.Error("Expected error for out of range index")
+	}
+}
+
+func TestInMemoryProvider_GetCandles(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	input := createMemoryCandles(baseTime, 10)
+	provider := NewInMemoryProvider(input)
+	ctx := context.Background()
+	
+	t.Run("should return candles sorted by time", func(t *testing.T) {
+		candles, err := provider.GetCandlesByIndex(ctx, 0, 2)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(candles) != 3 || candles[0].Close != 100.0 || candles[2].Close != 102.0 {
+			t.Errorf("Unexpected candles: %+v", candles)
+		}
+	})
+	
+	t.Run("should clamp end index and reject invalid ranges", func(t *testing.T) {
+		candles, err := provider.GetCandlesByIndex(ctx, 8, 500)
+		if err != nil || len(candles) != 2 {
+			t.Errorf("Expected 2 candles, got %d (%v)", len(candles), err)
+		}
+		if _, err := provider.GetCandlesByIndex(ctx, 10, 12); err == nil {
+			t.Error("Expected error for start index out of range")
+		}
+		if _, err := provider.GetCandlesByIndex(ctx, 5, 2); err == nil {
+			t.Error("Expected error for reversed range")
+		}
+	})
+	
+	t.Run("should get candles by time", func(t *testing.T) {
+		candles, err := provider.GetCandlesByTime(ctx, baseTime.Add(2*time.Minute), baseTime.Add(4*time.Minute))
+		if err != nil || len(candles) != 3 {
+			t.Errorf("Expected 3 candles, got %d (%v)", len(candles), err)
+		}
+	})
+	
+	t.Run("should get previous and next candles", func(t *testing.T) {
+		prev, err := provider.GetPrevCandlesByIndex(ctx, 5, 3)
+		if err != nil || len(prev) != 3 || prev[2].Close != 104.0 {
+			t.Errorf("Unexpected previous candles: %+v (%v)", prev, err)
+		}
+		next, err := provider.GetNextCandlesByTime(ctx, baseTime.Add(7*time.Minute), 5)
+		if err != nil || len(next) != 2 || next[0].Close != 108.0 {
+			t.Errorf("Unexpected next candles: %+v (%v)", next, err)
+		}
+		if empty, _ := provider.GetPrevCandlesByIndex(ctx, 0, 3); len(empty) != 0 {
+			t.Errorf("Expected no candles before index 0, got %d", len(empty))
+		}
+	})
+	
+	t.Run("should not share memory with caller", func(t *testing.T) {
+		input[0].Close = -1
+		candles, _ := provider.GetCandlesByIndex(ctx, 9, 9)
+		candles[0].Close = -2
+		again, _ := provider.GetCandlesByIndex(ctx, 9, 9)
+		if again[0].Close != 109.0 {
+			t.Errorf("Expected provider data to be unchanged, got %f", again[0].Close)
+		}
+	})
+}
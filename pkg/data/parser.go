@@ -2,6 +2,7 @@ package data
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
@@ -14,6 +15,7 @@ import (
 // CSVParser はCSVファイルを解析します。
 type CSVParser struct {
 	reader *csv.Reader
+	line   int
 }
 
 // NewCSVParser は新しいCSVParserを作成します。
@@ -28,8 +30,13 @@ func NewCSVParser(reader io.Reader) *CSVParser {
 func (p *CSVParser) Parse() (*models.Candle, error) {
 	record, err := p.reader.Read()
 	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			p.line = parseErr.StartLine
+		}
 		return nil, err
 	}
+	p.line, _ = p.reader.FieldPos(0)
 	
 	// ヘッダー行をスキップ
 	if strings.Contains(record[0], "timestamp") {
@@ -74,4 +81,9 @@ func (p *CSVParser) Parse() (*models.Candle, error) {
 	}
 	
 	return models.NewCandle(timestamp, open, high, low, close, volume), nil
-}
\ No newline at end of file
+}
+
+// Line は直前に読み込んだレコードのファイル上の行番号（1始まり）を返します。
+func (p *CSVParser) Line() int {
+	return p.line
+}
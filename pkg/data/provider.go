@@ -3,7 +3,9 @@ package data
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
@@ -13,11 +15,44 @@ import (
 	"github.com/RuiHirano/fx-backtesting/pkg/models"
 )
 
+// ErrMaxRowsExceeded はデータが設定された行数の上限を超えた場合のエラーです。
+var ErrMaxRowsExceeded = errors.New("data exceeds max rows limit")
+
+// ErrDuplicateTimestamp は DuplicatePolicy が error の場合に、同じ時刻のローソク足が複数あったことを表すエラーです。
+var ErrDuplicateTimestamp = errors.New("duplicate timestamp in data file")
+
+// ErrIndexOutOfRange は指定したインデックスがデータの範囲外であることを表すエラーです。
+// 開始インデックスがデータ末尾を超えた場合も返すため、読み込みの終端判定に使えます。
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+// ErrInvalidRow は StrictParsing が有効な場合に、解析・検証に失敗した行があったことを表すエラーです。
+var ErrInvalidRow = errors.New("invalid row in data file")
+
+// ParseWarning はインデックス構築時に読み飛ばした行と、その理由を表します。
+type ParseWarning struct {
+	Line   int    `json:"line"`   // ファイル上の行番号（1始まり）
+	Reason string `json:"reason"` // 解析・検証エラーの内容
+}
+
+// String は "line N: 理由" 形式の文字列を返します。
+func (w ParseWarning) String() string {
+	return fmt.Sprintf("line %d: %s", w.Line, w.Reason)
+}
+
 // CandleIndex は軽量インデックスエントリです。
 type CandleIndex struct {
 	Timestamp  time.Time
 	FileOffset int64
 	LineNumber int
+	SourceLine int  // ファイル上の行番号（1始まり）
+	Filled     bool // FillInterval による補完。LineNumber は補完元（直前）のローソク足を指す
+}
+
+// Gap はデータ内で想定間隔より空いた区間を表します。
+type Gap struct {
+	Start   time.Time `json:"start"`   // 区間直前のローソク足の時刻
+	End     time.Time `json:"end"`     // 区間直後のローソク足の時刻
+	Missing int       `json:"missing"` // 想定間隔で並んでいれば存在したはずのローソク足の本数
 }
 
 // DataProvider はデータ提供者のインターフェースです。
@@ -39,11 +74,26 @@ type DataProvider interface {
 	GetNextCandlesByIndex(ctx context.Context, baseIndex int, count int) ([]models.Candle, error)
 }
 
+// Sized は全ローソク足の本数を返せるデータ提供者が実装するインターフェースです。
+// 進捗表示など、総数が分かると便利な場面で使われます。
+type Sized interface {
+	Len() int
+}
+
+// LoggerSetter は診断ログの出力先を差し替えられるデータ提供者が実装するインターフェースです。
+// Market や Backtester は設定されたロガーをこのインターフェースを通してデータ提供者に渡します。
+type LoggerSetter interface {
+	SetLogger(logger *slog.Logger)
+}
+
 // CSVProvider はCSVファイルからデータを提供します。
 type CSVProvider struct {
-	Config  models.DataProviderConfig
-	index   []CandleIndex
-	indexed bool
+	Config    models.DataProviderConfig
+	index     []CandleIndex
+	indexed   bool
+	truncated bool
+	warnings  []ParseWarning
+	logger    *slog.Logger
 }
 
 // NewCSVProvider は新しいCSVProviderを作成します。
@@ -55,8 +105,34 @@ func NewCSVProvider(config models.DataProviderConfig) *CSVProvider {
 	}
 }
 
+// SetLogger は不正な行の読み飛ばしなど、読み込み時の診断ログの出力先を設定します。nil を指定するとログを出力しません。
+// 未設定の場合は slog のデフォルトロガーに出力します。読み込みを始める前に呼び出してください。
+func (p *CSVProvider) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = models.NopLogger()
+	}
+	p.logger = logger
+}
+
+// log は診断ログの出力先を返します。
+func (p *CSVProvider) log() *slog.Logger {
+	if p.logger == nil {
+		return slog.Default()
+	}
+	return p.logger
+}
+
+// indexCancelCheckInterval はインデックス構築中に ctx のキャンセルを確認する行数の間隔です。
+const indexCancelCheckInterval = 1024
+
 // buildIndex はファイルをスキャンして軽量インデックスを構築します。
 func (p *CSVProvider) buildIndex() error {
+	return p.buildIndexContext(context.Background())
+}
+
+// buildIndexContext は buildIndex と同じくインデックスを構築し、ctx がキャンセルされた場合は途中で ctx.Err() を返します。
+// 途中で止めた場合はインデックスを空に戻し、次の読み込みで最初から構築し直します。
+func (p *CSVProvider) buildIndexContext(ctx context.Context) error {
 	if p.indexed {
 		return nil
 	}
@@ -74,46 +150,214 @@ func (p *CSVProvider) buildIndex() error {
 
 	parser := NewCSVParser(file)
 	p.index = make([]CandleIndex, 0)
+	p.warnings = make([]ParseWarning, 0)
 	lineNumber := 0
 
-	for {
-		candle, err := parser.Parse()
-		if err != nil {
-			if err == io.EOF {
-				break
+	for rows := 0; ; rows++ {
+		if rows%indexCancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				p.index = make([]CandleIndex, 0)
+				p.warnings = make([]ParseWarning, 0)
+				p.truncated = false
+				return err
 			}
-			lineNumber++
-			continue
+		}
+
+		candle, err := parser.Parse()
+		if err == io.EOF {
+			break
 		}
 
 		// バリデーション
-		if err := candle.Validate(); err != nil {
+		if err == nil {
+			err = candle.Validate()
+		}
+		if err != nil {
+			if p.Config.StrictParsing {
+				p.index = make([]CandleIndex, 0)
+				return fmt.Errorf("%w: %s line %d: %v", ErrInvalidRow, p.Config.FilePath, parser.Line(), err)
+			}
+			p.skipRow(parser.Line(), err)
 			lineNumber++
 			continue
 		}
 
-		// バリデーション済みのデータを追加
+		// 行数の上限チェック
+		if p.Config.MaxRows > 0 && len(p.index) >= p.Config.MaxRows {
+			if p.Config.MaxRowsPolicy == models.MaxRowsError {
+				p.index = make([]CandleIndex, 0)
+				return fmt.Errorf("%w: %d rows in %s", ErrMaxRowsExceeded, p.Config.MaxRows, p.Config.FilePath)
+			}
+			p.truncated = true
+			p.log().Warn("data file exceeds max rows; remaining rows are ignored", "file", p.Config.FilePath, "max_rows", p.Config.MaxRows)
+			break
+		}
 
 		// インデックスに追加（ファイルオフセットは簡単化）
 		p.index = append(p.index, CandleIndex{
 			Timestamp:  candle.Timestamp,
 			FileOffset: int64(lineNumber),
 			LineNumber: lineNumber,
+			SourceLine: parser.Line(),
 		})
 
 		lineNumber++
 	}
 
-	// 時刻順でソート
-	sort.Slice(p.index, func(i, j int) bool {
+	// 時刻順でソート（同じ時刻の行はファイル内の順を保つ）
+	sort.SliceStable(p.index, func(i, j int) bool {
 		return p.index[i].Timestamp.Before(p.index[j].Timestamp)
 	})
 
+	if err := p.removeDuplicates(); err != nil {
+		p.index = make([]CandleIndex, 0)
+		return err
+	}
+
+	if p.Config.FillInterval > 0 {
+		p.fillGaps(p.Config.FillInterval)
+	}
+
+	if len(p.warnings) > 0 {
+		p.log().Warn("skipped invalid rows in data file", "file", p.Config.FilePath, "skipped", len(p.warnings), "first", p.warnings[0].String())
+	}
+
 	p.indexed = true
 	return nil
 }
 
-// TimeToIndex は時刻をインデックスに変換します。
+// removeDuplicates は時刻順に並んだインデックスから、DuplicatePolicy に従って同じ時刻の行を1つにまとめます。
+// 取り除いた行は Warnings に記録されます。
+func (p *CSVProvider) removeDuplicates() error {
+	deduped := make([]CandleIndex, 0, len(p.index))
+	var dropped []ParseWarning
+	for i := 0; i < len(p.index); {
+		// 同じ時刻の行の範囲 [i, j)
+		j := i + 1
+		for j < len(p.index) && p.index[j].Timestamp.Equal(p.index[i].Timestamp) {
+			j++
+		}
+
+		kept := i
+		if j-i > 1 {
+			switch p.Config.DuplicatePolicy {
+			case models.DuplicateError:
+				return fmt.Errorf("%w: %s at %s (lines %d and %d)", ErrDuplicateTimestamp,
+					p.Config.FilePath, p.index[i].Timestamp.Format(time.RFC3339), p.index[i].SourceLine, p.index[i+1].SourceLine)
+			case models.DuplicateKeepLast:
+				kept = j - 1
+			}
+			for k := i; k < j; k++ {
+				if k != kept {
+					dropped = append(dropped, ParseWarning{
+						Line:   p.index[k].SourceLine,
+						Reason: fmt.Sprintf("duplicate timestamp %s (kept line %d)", p.index[k].Timestamp.Format(time.RFC3339), p.index[kept].SourceLine),
+					})
+				}
+			}
+		}
+		deduped = append(deduped, p.index[kept])
+		i = j
+	}
+
+	if len(dropped) > 0 {
+		p.warnings = append(p.warnings, dropped...)
+		sort.SliceStable(p.warnings, func(i, j int) bool {
+			return p.warnings[i].Line < p.warnings[j].Line
+		})
+	}
+	p.index = deduped
+	return nil
+}
+
+// fillGaps は interval より空いた区間に、直前のローソク足を補完元とするインデックスを挿入します。
+func (p *CSVProvider) fillGaps(interval time.Duration) {
+	filled := make([]CandleIndex, 0, len(p.index))
+	for i, entry := range p.index {
+		if i > 0 {
+			prev := p.index[i-1]
+			for t := prev.Timestamp.Add(interval); t.Before(entry.Timestamp); t = t.Add(interval) {
+				filled = append(filled, CandleIndex{
+					Timestamp:  t,
+					FileOffset: prev.FileOffset,
+					LineNumber: prev.LineNumber,
+					SourceLine: prev.SourceLine,
+					Filled:     true,
+				})
+			}
+		}
+		filled = append(filled, entry)
+	}
+	p.index = filled
+}
+
+// DetectGaps はデータ内で expectedInterval より空いた区間を時刻順に返します。
+// 週末や配信の停止など、ローソク足が欠けている箇所の確認に使います。
+// FillInterval で補完した場合も、補完前の元データの区間を返します。
+// expectedInterval が0以下の場合、またはインデックスの構築に失敗した場合は nil を返します。
+func (p *CSVProvider) DetectGaps(expectedInterval time.Duration) []Gap {
+	if expectedInterval <= 0 {
+		return nil
+	}
+	if err := p.buildIndex(); err != nil {
+		return nil
+	}
+
+	var gaps []Gap
+	var prev *CandleIndex
+	for i := range p.index {
+		entry := &p.index[i]
+		if entry.Filled {
+			continue
+		}
+		if prev != nil {
+			if diff := entry.Timestamp.Sub(prev.Timestamp); diff > expectedInterval {
+				missing := int(diff/expectedInterval) - 1
+				if diff%expectedInterval != 0 {
+					missing++
+				}
+				gaps = append(gaps, Gap{Start: prev.Timestamp, End: entry.Timestamp, Missing: missing})
+			}
+		}
+		prev = entry
+	}
+	return gaps
+}
+
+// skipRow は解析・検証に失敗して読み飛ばした行を記録します。
+func (p *CSVProvider) skipRow(line int, err error) {
+	p.warnings = append(p.warnings, ParseWarning{Line: line, Reason: err.Error()})
+	p.log().Debug("skipping invalid row", "file", p.Config.FilePath, "line", line, "error", err)
+}
+
+// SkippedRows はインデックス構築時に解析・検証の失敗、または時刻の重複により読み飛ばした行数を返します。
+// ヘッダー行は含みません。インデックス構築前は0です。
+func (p *CSVProvider) SkippedRows() int {
+	return len(p.warnings)
+}
+
+// Warnings はインデックス構築時に読み飛ばした行の行番号と理由を、ファイル内の順に返します。
+// インデックス構築前は空です。
+func (p *CSVProvider) Warnings() []ParseWarning {
+	return append([]ParseWarning{}, p.warnings...)
+}
+
+// Truncated は行数の上限によりデータの読み込みが打ち切られたかを返します。
+func (p *CSVProvider) Truncated() bool {
+	return p.truncated
+}
+
+// Len はローソク足の本数を返します。インデックスの構築に失敗した場合は0を返します。
+func (p *CSVProvider) Len() int {
+	if err := p.buildIndex(); err != nil {
+		return 0
+	}
+	return len(p.index)
+}
+
+// TimeToIndex は時刻をインデックスに変換します。一致する時刻がない場合は直前のローソク足のインデックスを返します。
+// 同じ時刻の行はインデックス構築時に DuplicatePolicy に従って1つにまとめられるため、
+// 一致する時刻のインデックスは常に1つに定まります。
 func (p *CSVProvider) TimeToIndex(t time.Time) (int, error) {
 	if err := p.buildIndex(); err != nil {
 		return -1, err
@@ -160,7 +404,7 @@ func (p *CSVProvider) IndexToTime(index int) (time.Time, error) {
 	}
 
 	if index < 0 || index >= len(p.index) {
-		return time.Time{}, errors.New("index out of range")
+		return time.Time{}, ErrIndexOutOfRange
 	}
 
 	return p.index[index].Timestamp, nil
@@ -196,7 +440,7 @@ func (p *CSVProvider) GetCandlesByIndex(ctx context.Context, startIndex, endInde
 	}
 
 	if startIndex < 0 || endIndex >= len(p.index) {
-		return nil, errors.New("index out of range")
+		return nil, ErrIndexOutOfRange
 	}
 
 	candles := make([]models.Candle, 0, endIndex-startIndex+1)
@@ -204,7 +448,8 @@ func (p *CSVProvider) GetCandlesByIndex(ctx context.Context, startIndex, endInde
 	for i := startIndex; i <= endIndex; i++ {
 		candle, err := p.getCandleAtIndex(i)
 		if err != nil {
-			continue
+			// インデックス済みの行が読めないのはファイルの変更・削除などの読み込み障害
+			return nil, fmt.Errorf("failed to read candle at index %d: %w", i, err)
 		}
 		candles = append(candles, *candle)
 	}
@@ -294,10 +539,90 @@ func (p *CSVProvider) GetNextCandlesByIndex(ctx context.Context, baseIndex int,
 	return p.GetCandlesByIndex(ctx, startIndex, endIndex)
 }
 
+// Stream は全てのローソク足をインデックス順（時刻順）に1本ずつ送るチャネルと、読み込みのエラーを送るチャネルを返します。
+// ローソク足を1つのスライスにまとめないため、メモリに保持するのは軽量インデックスと送信中の1本だけです。
+// ファイルは先頭から1回の走査で読み込みます（時刻順に並んでいないファイルでは、前の行に戻るたびに開き直します）。
+// 全て送るか失敗するとローソク足のチャネルを閉じ、続けてエラーのチャネルを閉じます。エラーは最大1つで、正常に終わった場合は送りません。
+// ctx がキャンセルされた場合は ctx.Err() を送って終了します。
+// インデックスが未構築の場合は送信を始める前にゴルーチン内で構築するため、呼び出しはすぐに戻り、構築中のキャンセルも ctx に従います。
+// チャネルが閉じるまでは、同じ CSVProvider の他のメソッドを呼び出さないでください。
+func (p *CSVProvider) Stream(ctx context.Context) (<-chan models.Candle, <-chan error) {
+	candles := make(chan models.Candle)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(candles)
+		if err := p.buildIndexContext(ctx); err != nil {
+			errc <- err
+			return
+		}
+		if err := p.streamCandles(ctx, candles); err != nil {
+			errc <- err
+		}
+	}()
+	return candles, errc
+}
+
+// streamCandles はインデックス順にファイルを読み進めてローソク足を out に送ります。
+func (p *CSVProvider) streamCandles(ctx context.Context, out chan<- models.Candle) error {
+	var file *os.File
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	var parser *CSVParser
+	var candle *models.Candle
+	var parseErr error
+	line := -1 // 最後に解析した行（LineNumber と同じく0始まり）
+	for i, entry := range p.index {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// 前の行に戻る場合は先頭から読み直す（補完したローソク足は直前の行を再利用する）
+		if parser == nil || entry.LineNumber < line {
+			if file != nil {
+				file.Close()
+			}
+			var err error
+			file, err = os.Open(p.Config.FilePath)
+			if err != nil {
+				return fmt.Errorf("failed to read candle at index %d: %w", i, err)
+			}
+			parser = NewCSVParser(file)
+			line = -1
+		}
+		for line < entry.LineNumber {
+			candle, parseErr = parser.Parse()
+			if parseErr == io.EOF {
+				return fmt.Errorf("failed to read candle at index %d: candle not found", i)
+			}
+			line++
+		}
+		if parseErr != nil {
+			return fmt.Errorf("failed to read candle at index %d: %w", i, parseErr)
+		}
+
+		next := *candle
+		if entry.Filled {
+			next = *flatCandle(entry.Timestamp, candle.Close)
+		}
+		select {
+		case out <- next:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
 // getCandleAtIndex は指定されたインデックスのローソク足データを取得します。
 func (p *CSVProvider) getCandleAtIndex(index int) (*models.Candle, error) {
 	if index < 0 || index >= len(p.index) {
-		return nil, errors.New("index out of range")
+		return nil, ErrIndexOutOfRange
 	}
 
 	file, err := os.Open(p.Config.FilePath)
@@ -308,21 +633,29 @@ func (p *CSVProvider) getCandleAtIndex(index int) (*models.Candle, error) {
 
 	parser := NewCSVParser(file)
 	
-	// 指定されたライン番号まで読み飛ばす
+	// 指定されたライン番号まで読み飛ばす（途中の不正な行はインデックス構築時と同様に1行として数える）
 	targetLine := p.index[index].LineNumber
 	for i := 0; i <= targetLine; i++ {
 		candle, err := parser.Parse()
-		if err != nil {
-			return nil, err
+		if err == io.EOF {
+			break
 		}
 		if i == targetLine {
-			return candle, nil
+			if err == nil && p.index[index].Filled {
+				candle = flatCandle(p.index[index].Timestamp, candle.Close)
+			}
+			return candle, err
 		}
 	}
 
 	return nil, errors.New("candle not found")
 }
 
+// flatCandle は四本値が全て price で出来高0の補完用ローソク足を作成します。
+func flatCandle(timestamp time.Time, price float64) *models.Candle {
+	return models.NewCandle(timestamp, price, price, price, price, 0)
+}
+
 // extractSymbolFromFilename はファイル名からシンボルを推測します。
 func (p *CSVProvider) extractSymbolFromFilename(filename string) string {
 	base := filepath.Base(filename)
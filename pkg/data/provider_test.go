@@ -1,7 +1,13 @@
 package data
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -637,4 +643,537 @@ func TestCSVProvider_DataConsistency(t *testing.T) {
 				candlesByTime[lastIdx].Timestamp, candlesByIndex[lastIdx].Timestamp)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestCSVProvider_MaxRows(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should truncate index at MaxRows", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{
+			FilePath: "testdata/sample.csv",
+			Format:   "csv",
+			MaxRows:  3,
+		})
+
+		candles, err := provider.GetCandlesByIndex(ctx, 0, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(candles) != 3 {
+			t.Errorf("Expected 3 candles, got %d", len(candles))
+		}
+
+		// 4本目以降はインデックスに含まれない
+		if _, err := provider.IndexToTime(3); err == nil {
+			t.Error("Expected index 3 to be out of range")
+		}
+		if !provider.Truncated() {
+			t.Error("Expected provider to report truncation")
+		}
+	})
+
+	t.Run("should return error when policy is error", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{
+			FilePath:      "testdata/sample.csv",
+			Format:        "csv",
+			MaxRows:       3,
+			MaxRowsPolicy: models.MaxRowsError,
+		})
+
+		_, err := provider.GetCandlesByIndex(ctx, 0, 2)
+		if !errors.Is(err, ErrMaxRowsExceeded) {
+			t.Errorf("Expected ErrMaxRowsExceeded, got %v", err)
+		}
+	})
+
+	t.Run("should not truncate when rows are within the limit", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{
+			FilePath: "testdata/sample.csv",
+			Format:   "csv",
+			MaxRows:  1000,
+		})
+
+		if _, err := provider.IndexToTime(0); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if provider.Truncated() {
+			t.Error("Expected no truncation within the limit")
+		}
+	})
+}
+
+func TestCSVProvider_SkippedRows(t *testing.T) {
+	t.Run("should count rows that fail parsing or validation", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{
+			FilePath: "testdata/mixed.csv",
+			Format:   "csv",
+		})
+		if got := provider.SkippedRows(); got != 0 {
+			t.Errorf("SkippedRows() before indexing = %d, want 0", got)
+		}
+
+		candles, err := provider.GetCandlesByIndex(context.Background(), 0, provider.Len()-1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(candles) != 3 {
+			t.Fatalf("Expected 3 valid candles, got %d", len(candles))
+		}
+		for _, candle := range candles {
+			if err := candle.Validate(); err != nil {
+				t.Errorf("Expected only valid candles, got %v", err)
+			}
+		}
+		if got := provider.SkippedRows(); got != 4 {
+			t.Errorf("SkippedRows() = %d, want 4", got)
+		}
+	})
+
+	t.Run("should report zero for clean data", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{
+			FilePath: "testdata/sample.csv",
+			Format:   "csv",
+		})
+		if _, err := provider.IndexToTime(0); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got := provider.SkippedRows(); got != 0 {
+			t.Errorf("SkippedRows() = %d, want 0", got)
+		}
+	})
+}
+
+func TestCSVProvider_Warnings(t *testing.T) {
+	t.Run("should report line numbers and reasons of skipped rows", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{
+			FilePath: "testdata/mixed.csv",
+			Format:   "csv",
+		})
+		if len(provider.Warnings()) != 0 {
+			t.Errorf("Expected no warnings before indexing, got %v", provider.Warnings())
+		}
+		if _, err := provider.IndexToTime(0); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		warnings := provider.Warnings()
+		expected := []struct {
+			line   int
+			reason string
+		}{
+			{2, "must be greater than or equal to low"},
+			{3, "must be greater than or equal to open"},
+			{5, "invalid open price"},
+			{6, "must be non-negative"},
+		}
+		if len(warnings) != len(expected) {
+			t.Fatalf("Expected %d warnings, got %v", len(expected), warnings)
+		}
+		for i, want := range expected {
+			if warnings[i].Line != want.line {
+				t.Errorf("warnings[%d].Line = %d, want %d", i, warnings[i].Line, want.line)
+			}
+			if !strings.Contains(warnings[i].Reason, want.reason) {
+				t.Errorf("warnings[%d].Reason = %q, want to contain %q", i, warnings[i].Reason, want.reason)
+			}
+		}
+		if got := warnings[0].String(); !strings.HasPrefix(got, "line 2: ") {
+			t.Errorf("String() = %q, want prefix %q", got, "line 2: ")
+		}
+	})
+
+	t.Run("should count header line in line numbers", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{
+			FilePath: "testdata/invalid.csv",
+			Format:   "csv",
+		})
+		provider.IndexToTime(0)
+
+		warnings := provider.Warnings()
+		if len(warnings) != 1 || warnings[0].Line != 2 {
+			t.Errorf("Expected one warning at line 2, got %v", warnings)
+		}
+	})
+
+	t.Run("should fail fast with StrictParsing", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{
+			FilePath:      "testdata/mixed.csv",
+			Format:        "csv",
+			StrictParsing: true,
+		})
+
+		_, err := provider.GetCandlesByIndex(context.Background(), 0, 10)
+		if !errors.Is(err, ErrInvalidRow) {
+			t.Fatalf("Expected ErrInvalidRow, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("Expected error to contain line number, got %v", err)
+		}
+		if provider.Len() != 0 {
+			t.Errorf("Len() = %d, want 0 after strict failure", provider.Len())
+		}
+	})
+
+	t.Run("should accept clean data with StrictParsing", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{
+			FilePath:      "testdata/sample.csv",
+			Format:        "csv",
+			StrictParsing: true,
+		})
+		if got := provider.Len(); got != 480 {
+			t.Errorf("Len() = %d, want 480", got)
+		}
+	})
+}
+
+// captureDefaultLogger は slog のデフォルトロガーの出力を捕捉する
+func captureDefaultLogger(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestCSVProvider_Logger(t *testing.T) {
+	config := models.DataProviderConfig{FilePath: "testdata/mixed.csv", Format: "csv"}
+
+	t.Run("should log skipped rows to default logger when unset", func(t *testing.T) {
+		defaultOutput := captureDefaultLogger(t)
+		provider := NewCSVProvider(config)
+		if _, err := provider.IndexToTime(0); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(defaultOutput.String(), "skipped invalid rows") {
+			t.Errorf("Expected warning on default logger, got %q", defaultOutput.String())
+		}
+	})
+
+	t.Run("should log skipped rows to configured logger", func(t *testing.T) {
+		defaultOutput := captureDefaultLogger(t)
+		var buf bytes.Buffer
+		provider := NewCSVProvider(config)
+		provider.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		if _, err := provider.IndexToTime(0); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(buf.String(), "skipped invalid rows") || !strings.Contains(buf.String(), "skipping invalid row") {
+			t.Errorf("Expected warning and debug logs on configured logger, got %q", buf.String())
+		}
+		if defaultOutput.Len() != 0 {
+			t.Errorf("Expected nothing on default logger, got %q", defaultOutput.String())
+		}
+	})
+
+	t.Run("should be silent with nil logger", func(t *testing.T) {
+		defaultOutput := captureDefaultLogger(t)
+		provider := NewCSVProvider(config)
+		provider.SetLogger(nil)
+		if _, err := provider.IndexToTime(0); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if defaultOutput.Len() != 0 {
+			t.Errorf("Expected no logs, got %q", defaultOutput.String())
+		}
+	})
+}
+
+func TestCSVProvider_DuplicateTimestamps(t *testing.T) {
+	ctx := context.Background()
+	dupTime := time.Date(2024, 1, 1, 9, 1, 0, 0, time.UTC)
+
+	newProvider := func(policy models.DuplicatePolicy) *CSVProvider {
+		return NewCSVProvider(models.DataProviderConfig{
+			FilePath:        "testdata/duplicates.csv",
+			Format:          "csv",
+			DuplicatePolicy: policy,
+		})
+	}
+
+	t.Run("should keep first row by default", func(t *testing.T) {
+		provider := newProvider("")
+		if got := provider.Len(); got != 4 {
+			t.Fatalf("Len() = %d, want 4", got)
+		}
+
+		index, err := provider.TimeToIndex(dupTime)
+		if err != nil || index != 1 {
+			t.Fatalf("TimeToIndex() = %d, %v, want 1", index, err)
+		}
+		candles, err := provider.GetCandlesByIndex(ctx, 1, 1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if candles[0].Close != 1.1010 {
+			t.Errorf("Expected first row (close 1.1010), got close %f", candles[0].Close)
+		}
+
+		warnings := provider.Warnings()
+		if len(warnings) != 1 || warnings[0].Line != 4 || !strings.Contains(warnings[0].Reason, "kept line 2") {
+			t.Errorf("Expected duplicate warning for line 4, got %v", warnings)
+		}
+	})
+
+	t.Run("should keep last row", func(t *testing.T) {
+		provider := newProvider(models.DuplicateKeepLast)
+		candles, err := provider.GetCandlesByIndex(ctx, 0, provider.Len()-1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(candles) != 4 {
+			t.Fatalf("Expected 4 candles, got %d", len(candles))
+		}
+		if candles[1].Close != 1.1020 || candles[1].Volume != 2000 {
+			t.Errorf("Expected last row (close 1.1020), got %+v", candles[1])
+		}
+
+		warnings := provider.Warnings()
+		if len(warnings) != 1 || warnings[0].Line != 2 {
+			t.Errorf("Expected duplicate warning for line 2, got %v", warnings)
+		}
+	})
+
+	t.Run("should return error when policy is error", func(t *testing.T) {
+		provider := newProvider(models.DuplicateError)
+		_, err := provider.GetCandlesByIndex(ctx, 0, 10)
+		if !errors.Is(err, ErrDuplicateTimestamp) {
+			t.Fatalf("Expected ErrDuplicateTimestamp, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "lines 2 and 4") {
+			t.Errorf("Expected error to contain line numbers, got %v", err)
+		}
+		if provider.Len() != 0 {
+			t.Errorf("Len() = %d, want 0", provider.Len())
+		}
+	})
+
+	t.Run("should return same count for time and index queries", func(t *testing.T) {
+		provider := newProvider("")
+		start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		end := time.Date(2024, 1, 1, 9, 2, 0, 0, time.UTC)
+
+		byTime, err := provider.GetCandlesByTime(ctx, start, end)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		byIndex, err := provider.GetCandlesByIndex(ctx, 0, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(byTime) != 3 || len(byIndex) != 3 {
+			t.Errorf("Expected 3 candles from both queries, got %d by time and %d by index", len(byTime), len(byIndex))
+		}
+	})
+}
+
+func TestCSVProvider_DetectGaps(t *testing.T) {
+	base := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	expected := []Gap{
+		{Start: base.Add(1 * time.Minute), End: base.Add(4 * time.Minute), Missing: 2},
+		{Start: base.Add(5 * time.Minute), End: base.Add(8 * time.Minute), Missing: 2},
+	}
+
+	t.Run("should report gaps larger than the expected interval", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{
+			FilePath: "testdata/gaps.csv",
+			Format:   "csv",
+		})
+
+		gaps := provider.DetectGaps(time.Minute)
+		if len(gaps) != len(expected) {
+			t.Fatalf("Expected %d gaps, got %v", len(expected), gaps)
+		}
+		for i, want := range expected {
+			if !gaps[i].Start.Equal(want.Start) || !gaps[i].End.Equal(want.End) || gaps[i].Missing != want.Missing {
+				t.Errorf("gaps[%d] = %+v, want %+v", i, gaps[i], want)
+			}
+		}
+
+		if gaps := provider.DetectGaps(5 * time.Minute); len(gaps) != 0 {
+			t.Errorf("Expected no gaps for 5 minute interval, got %v", gaps)
+		}
+		if gaps := provider.DetectGaps(0); gaps != nil {
+			t.Errorf("Expected nil for non-positive interval, got %v", gaps)
+		}
+	})
+
+	t.Run("should forward-fill gaps with flat candles", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{
+			FilePath:     "testdata/gaps.csv",
+			Format:       "csv",
+			FillInterval: time.Minute,
+		})
+
+		candles, err := provider.GetCandlesByIndex(context.Background(), 0, provider.Len()-1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(candles) != 9 {
+			t.Fatalf("Expected 9 candles after filling, got %d", len(candles))
+		}
+		for i, candle := range candles {
+			if !candle.Timestamp.Equal(base.Add(time.Duration(i) * time.Minute)) {
+				t.Errorf("candles[%d].Timestamp = %v, want %v", i, candle.Timestamp, base.Add(time.Duration(i)*time.Minute))
+			}
+		}
+
+		// 09:02・09:03 は 09:01 の終値の横ばい
+		filled := candles[2]
+		if filled.Open != 1.1010 || filled.High != 1.1010 || filled.Low != 1.1010 || filled.Close != 1.1010 || filled.Volume != 0 {
+			t.Errorf("Expected flat candle at previous close, got %+v", filled)
+		}
+		if candles[4].Close != 1.1015 || candles[4].Volume != 1000 {
+			t.Errorf("Expected original candle at 09:04, got %+v", candles[4])
+		}
+
+		// 補完後も元データの区間が報告される
+		if gaps := provider.DetectGaps(time.Minute); len(gaps) != 2 {
+			t.Errorf("Expected 2 gaps in original data, got %v", gaps)
+		}
+	})
+}
+
+func TestCSVProvider_Len(t *testing.T) {
+	provider := NewCSVProvider(models.DataProviderConfig{
+		FilePath: "testdata/sample.csv",
+		Format:   "csv",
+		MaxRows:  3,
+	})
+	if got := provider.Len(); got != 3 {
+		t.Errorf("Len() = %d, want 3", got)
+	}
+
+	missing := NewCSVProvider(models.DataProviderConfig{
+		FilePath: "testdata/not_exists.csv",
+		Format:   "csv",
+	})
+	if got := missing.Len(); got != 0 {
+		t.Errorf("Len() = %d, want 0 for missing file", got)
+	}
+}
+
+func TestCSVProvider_ReadFailure(t *testing.T) {
+	src, err := os.ReadFile("testdata/sample.csv")
+	if err != nil {
+		t.Fatalf("failed to read sample data: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "sample.csv")
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+
+	provider := NewCSVProvider(models.DataProviderConfig{FilePath: path, Format: "csv"})
+	total := provider.Len()
+	if total == 0 {
+		t.Fatal("expected sample data to be indexed")
+	}
+
+	// データ末尾を超える開始インデックスは終端として判別できる
+	if _, err := provider.GetCandlesByIndex(context.Background(), total, total+10); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("expected ErrIndexOutOfRange, got %v", err)
+	}
+
+	// インデックス構築後にファイルが消えた場合は空の結果ではなくエラーになる
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove data file: %v", err)
+	}
+	candles, err := provider.GetCandlesByIndex(context.Background(), 0, 2)
+	if err == nil {
+		t.Fatalf("expected read error, got %d candles", len(candles))
+	}
+	if errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("read failure must not be reported as out of range: %v", err)
+	}
+}
+
+// drainStream はローソク足のチャネルを読み切り、エラーのチャネルの値と合わせて返します。
+func drainStream(candles <-chan models.Candle, errc <-chan error) ([]models.Candle, error) {
+	var all []models.Candle
+	for candle := range candles {
+		all = append(all, candle)
+	}
+	return all, <-errc
+}
+
+func TestCSVProvider_Stream(t *testing.T) {
+	ctx := context.Background()
+
+	configs := []struct {
+		name   string
+		config models.DataProviderConfig
+	}{
+		{"sample", models.DataProviderConfig{FilePath: "testdata/sample.csv", Format: "csv"}},
+		{"filled gaps", models.DataProviderConfig{FilePath: "testdata/gaps.csv", Format: "csv", FillInterval: time.Minute}},
+		// keep-last では 09:01 がファイル後方の行になり、前の行に戻って読み直す
+		{"unsorted duplicates", models.DataProviderConfig{FilePath: "testdata/duplicates.csv", Format: "csv", DuplicatePolicy: models.DuplicateKeepLast}},
+	}
+	for _, tt := range configs {
+		t.Run("should stream the same candles as GetCandlesByIndex for "+tt.name, func(t *testing.T) {
+			provider := NewCSVProvider(tt.config)
+			expected, err := provider.GetCandlesByIndex(ctx, 0, provider.Len()-1)
+			if err != nil {
+				t.Fatalf("GetCandlesByIndex() error = %v", err)
+			}
+
+			got, err := drainStream(provider.Stream(ctx))
+			if err != nil {
+				t.Fatalf("Stream() error = %v", err)
+			}
+			if len(got) != len(expected) {
+				t.Fatalf("Expected %d candles, got %d", len(expected), len(got))
+			}
+			for i := range expected {
+				if got[i] != expected[i] {
+					t.Fatalf("candles[%d] = %+v, want %+v", i, got[i], expected[i])
+				}
+			}
+		})
+	}
+
+	t.Run("should stop when context is cancelled", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{FilePath: "testdata/sample.csv", Format: "csv"})
+		cancelCtx, cancel := context.WithCancel(ctx)
+		candles, errc := provider.Stream(cancelCtx)
+
+		if _, ok := <-candles; !ok {
+			t.Fatal("Expected first candle before cancel")
+		}
+		cancel()
+		got, err := drainStream(candles, errc)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if len(got) > 1 {
+			t.Errorf("Expected at most one candle after cancel, got %d", len(got))
+		}
+	})
+
+	t.Run("should stop building the index when context is cancelled", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{FilePath: "testdata/sample.csv", Format: "csv"})
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		got, err := drainStream(provider.Stream(cancelCtx))
+		if !errors.Is(err, context.Canceled) || len(got) != 0 {
+			t.Errorf("Expected context.Canceled and no candles, got %d candles (%v)", len(got), err)
+		}
+		if provider.indexed || len(provider.index) != 0 {
+			t.Errorf("Expected index to be discarded after cancel, got %d entries", len(provider.index))
+		}
+
+		// 次の読み込みでは最初から構築し直す
+		got, err = drainStream(provider.Stream(ctx))
+		if err != nil || len(got) != 480 {
+			t.Errorf("Expected 480 candles after rebuilding the index, got %d (%v)", len(got), err)
+		}
+	})
+
+	t.Run("should return error for missing file", func(t *testing.T) {
+		provider := NewCSVProvider(models.DataProviderConfig{FilePath: "testdata/not_exists.csv", Format: "csv"})
+		got, err := drainStream(provider.Stream(ctx))
+		if err == nil || len(got) != 0 {
+			t.Errorf("Expected file error and no candles, got %d candles (%v)", len(got), err)
+		}
+	})
+}
@@ -0,0 +1,372 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// ErrUnsortedData はストリーミング読み込みで、ファイル内のローソク足が時刻順に並んでいなかったことを表すエラーです。
+// 時刻順でないファイルはストリーミングを無効にして（インデックスを構築して）読み込んでください。
+var ErrUnsortedData = errors.New("data file is not sorted by time")
+
+// StreamingCSVProvider はCSVファイルを先頭から順に読み込むデータ提供者です。
+// CSVProvider と異なり、ファイル全体のインデックスを事前に構築しないため、大きなファイルでも起動が速くメモリをほとんど使いません。
+// Market のように先頭から順にインデックスを進める読み込みはストリームから直接返し、
+// 読み込み済みの位置より前のインデックスが要求された場合はファイルを先頭から読み直します。
+// TimeToIndex などのランダムアクセスが要求された場合のみ、内部の CSVProvider でインデックスを構築します。
+//
+// ファイルは時刻順に並んでいる必要があります（前の行より古い時刻の行があると ErrUnsortedData）。
+// 連続する同じ時刻の行は DuplicatePolicy に従って1つにまとめ、FillInterval による補完も CSVProvider と同じ結果になります。
+// 総数を知るにはファイル全体の読み込みが必要なため、Sized は実装しません（Market.Progress の総数は0）。
+type StreamingCSVProvider struct {
+	Config models.DataProviderConfig
+
+	file     *os.File
+	parser   *CSVParser
+	next     int        // 次に返すローソク足のインデックス
+	rows     int        // 読み込んだ有効な行数（MaxRows の判定に使用）
+	ahead    *streamRow // 先読みした行（同じ時刻の行をまとめるため）
+	upcoming *streamRow // 次に返す行（FillInterval の補完を挟むため）
+	last     *models.Candle
+	done     bool
+	err      error
+
+	warnings  []ParseWarning
+	truncated bool
+
+	indexed *CSVProvider // ランダムアクセスが要求された場合に構築する
+	logger  *slog.Logger
+}
+
+// streamRow は読み込んだローソク足と、ファイル上の行番号です。
+type streamRow struct {
+	candle *models.Candle
+	line   int
+}
+
+// NewStreamingCSVProvider は新しいStreamingCSVProviderを作成します。ファイルは最初の読み込み時に開きます。
+func NewStreamingCSVProvider(config models.DataProviderConfig) *StreamingCSVProvider {
+	return &StreamingCSVProvider{
+		Config: config,
+	}
+}
+
+// SetLogger は不正な行の読み飛ばしなど、読み込み時の診断ログの出力先を設定します。nil を指定するとログを出力しません。
+// 未設定の場合は slog のデフォルトロガーに出力します。ランダムアクセス用に構築する CSVProvider にも同じロガーを使います。
+func (p *StreamingCSVProvider) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = models.NopLogger()
+	}
+	p.logger = logger
+	if p.indexed != nil {
+		p.indexed.SetLogger(logger)
+	}
+}
+
+// log は診断ログの出力先を返します。
+func (p *StreamingCSVProvider) log() *slog.Logger {
+	if p.logger == nil {
+		return slog.Default()
+	}
+	return p.logger
+}
+
+// open はファイルを先頭から読み直せるよう開き直し、読み込みの状態を初期化します。
+func (p *StreamingCSVProvider) open() error {
+	p.closeFile()
+
+	if _, err := os.Stat(p.Config.FilePath); os.IsNotExist(err) {
+		return errors.New("file not found: " + p.Config.FilePath)
+	}
+
+	file, err := os.Open(p.Config.FilePath)
+	if err != nil {
+		return err
+	}
+
+	p.file = file
+	p.parser = NewCSVParser(file)
+	p.next = 0
+	p.rows = 0
+	p.ahead = nil
+	p.upcoming = nil
+	p.last = nil
+	p.done = false
+	p.err = nil
+	p.warnings = make([]ParseWarning, 0)
+	p.truncated = false
+	return nil
+}
+
+// closeFile は開いているファイルを閉じます。
+func (p *StreamingCSVProvider) closeFile() error {
+	if p.file == nil {
+		return nil
+	}
+	err := p.file.Close()
+	p.file = nil
+	p.parser = nil
+	return err
+}
+
+// Close は開いているファイルを閉じます。Market.Close から呼ばれます。
+func (p *StreamingCSVProvider) Close() error {
+	return p.closeFile()
+}
+
+// readRow は次の有効な行を返します。解析・検証に失敗した行は CSVProvider と同様に読み飛ばすか、StrictParsing の場合はエラーにします。
+// ファイル末尾、または MaxRows で打ち切った場合は io.EOF を返します。
+func (p *StreamingCSVProvider) readRow() (*streamRow, error) {
+	for {
+		candle, err := p.parser.Parse()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+
+		// バリデーション
+		if err == nil {
+			err = candle.Validate()
+		}
+		if err != nil {
+			if p.Config.StrictParsing {
+				return nil, fmt.Errorf("%w: %s line %d: %v", ErrInvalidRow, p.Config.FilePath, p.parser.Line(), err)
+			}
+			p.warnings = append(p.warnings, ParseWarning{Line: p.parser.Line(), Reason: err.Error()})
+			p.log().Debug("skipping invalid row", "file", p.Config.FilePath, "line", p.parser.Line(), "error", err)
+			continue
+		}
+
+		// 行数の上限チェック
+		if p.Config.MaxRows > 0 && p.rows >= p.Config.MaxRows {
+			if p.Config.MaxRowsPolicy == models.MaxRowsError {
+				return nil, fmt.Errorf("%w: %d rows in %s", ErrMaxRowsExceeded, p.Config.MaxRows, p.Config.FilePath)
+			}
+			p.truncated = true
+			p.log().Warn("data file exceeds max rows; remaining rows are ignored", "file", p.Config.FilePath, "max_rows", p.Config.MaxRows)
+			return nil, io.EOF
+		}
+
+		p.rows++
+		return &streamRow{candle: candle, line: p.parser.Line()}, nil
+	}
+}
+
+// readUnique は次の時刻のローソク足を返します。連続する同じ時刻の行は DuplicatePolicy に従って1つにまとめます。
+func (p *StreamingCSVProvider) readUnique() (*streamRow, error) {
+	current := p.ahead
+	p.ahead = nil
+	if current == nil {
+		row, err := p.readRow()
+		if err != nil {
+			return nil, err
+		}
+		current = row
+	}
+
+	for {
+		row, err := p.readRow()
+		if err == io.EOF {
+			return current, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		timestamp := current.candle.Timestamp
+		if row.candle.Timestamp.Before(timestamp) {
+			return nil, fmt.Errorf("%w: %s line %d at %s is before line %d at %s", ErrUnsortedData, p.Config.FilePath,
+				row.line, row.candle.Timestamp.Format(time.RFC3339), current.line, timestamp.Format(time.RFC3339))
+		}
+		if !row.candle.Timestamp.Equal(timestamp) {
+			p.ahead = row
+			return current, nil
+		}
+
+		// 同じ時刻の行
+		dropped, kept := row, current
+		switch p.Config.DuplicatePolicy {
+		case models.DuplicateError:
+			return nil, fmt.Errorf("%w: %s at %s (lines %d and %d)", ErrDuplicateTimestamp,
+				p.Config.FilePath, timestamp.Format(time.RFC3339), current.line, row.line)
+		case models.DuplicateKeepLast:
+			dropped, kept = current, row
+		}
+		p.warnings = append(p.warnings, ParseWarning{
+			Line:   dropped.line,
+			Reason: fmt.Sprintf("duplicate timestamp %s (kept line %d)", timestamp.Format(time.RFC3339), kept.line),
+		})
+		current = kept
+	}
+}
+
+// readNext は次のインデックスのローソク足を返します。FillInterval より空いた区間には直前の終値の横ばいのローソク足を挟みます。
+func (p *StreamingCSVProvider) readNext() (*models.Candle, error) {
+	if p.upcoming == nil {
+		row, err := p.readUnique()
+		if err != nil {
+			return nil, err
+		}
+		p.upcoming = row
+	}
+
+	candle := p.upcoming.candle
+	if p.Config.FillInterval > 0 && p.last != nil {
+		if t := p.last.Timestamp.Add(p.Config.FillInterval); t.Before(candle.Timestamp) {
+			candle = flatCandle(t, p.last.Close)
+		} else {
+			p.upcoming = nil
+		}
+	} else {
+		p.upcoming = nil
+	}
+
+	p.last = candle
+	p.next++
+	return candle, nil
+}
+
+// advance は次のローソク足を読み込みます。ファイル末尾に達した場合は io.EOF を返し、以降の呼び出しも同じ結果を返します。
+func (p *StreamingCSVProvider) advance() (*models.Candle, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.done {
+		return nil, io.EOF
+	}
+
+	candle, err := p.readNext()
+	if err == io.EOF {
+		p.done = true
+		if len(p.warnings) > 0 {
+			p.log().Warn("skipped invalid rows in data file", "file", p.Config.FilePath, "skipped", len(p.warnings), "first", p.warnings[0].String())
+		}
+		return nil, io.EOF
+	}
+	if err != nil {
+		p.err = err
+		return nil, err
+	}
+	return candle, nil
+}
+
+// GetCandlesByIndex は指定されたインデックス範囲のローソク足データを取得します。
+// 読み込み済みの位置以降はストリームから順に読み込み、それより前の位置はファイルを先頭から読み直します。
+func (p *StreamingCSVProvider) GetCandlesByIndex(ctx context.Context, startIndex, endIndex int) ([]models.Candle, error) {
+	if startIndex > endIndex {
+		return nil, errors.New("start index must be less than or equal to end index")
+	}
+	if startIndex < 0 {
+		return nil, ErrIndexOutOfRange
+	}
+
+	if p.parser == nil || startIndex < p.next {
+		if err := p.open(); err != nil {
+			return nil, err
+		}
+	}
+
+	// 開始位置まで読み飛ばす
+	for p.next < startIndex {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, err := p.advance(); err == io.EOF {
+			return nil, ErrIndexOutOfRange
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	candles := make([]models.Candle, 0)
+	for p.next <= endIndex {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		candle, err := p.advance()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, *candle)
+	}
+
+	// 開始インデックスがデータ末尾を超えた場合は CSVProvider と同じく範囲外
+	if len(candles) == 0 {
+		return nil, ErrIndexOutOfRange
+	}
+	return candles, nil
+}
+
+// index はランダムアクセス用の CSVProvider を返します。初回の呼び出しで作成し、インデックスは CSVProvider が構築します。
+func (p *StreamingCSVProvider) index() *CSVProvider {
+	if p.indexed == nil {
+		p.log().Debug("building index for random access on streaming provider", "file", p.Config.FilePath)
+		p.indexed = NewCSVProvider(p.Config)
+		p.indexed.logger = p.logger
+	}
+	return p.indexed
+}
+
+// Indexed はランダムアクセスのためにインデックスを構築したかを返します。
+func (p *StreamingCSVProvider) Indexed() bool {
+	return p.indexed != nil && p.indexed.indexed
+}
+
+// Warnings はこれまでに読み込んだ範囲で読み飛ばした行の行番号と理由を返します。
+func (p *StreamingCSVProvider) Warnings() []ParseWarning {
+	return append([]ParseWarning{}, p.warnings...)
+}
+
+// SkippedRows はこれまでに読み込んだ範囲で読み飛ばした行数を返します。
+func (p *StreamingCSVProvider) SkippedRows() int {
+	return len(p.warnings)
+}
+
+// Truncated は行数の上限によりデータの読み込みが打ち切られたかを返します。
+func (p *StreamingCSVProvider) Truncated() bool {
+	return p.truncated
+}
+
+// TimeToIndex は時刻をインデックスに変換します。インデックスを構築して CSVProvider.TimeToIndex と同じ結果を返します。
+func (p *StreamingCSVProvider) TimeToIndex(t time.Time) (int, error) {
+	return p.index().TimeToIndex(t)
+}
+
+// IndexToTime はインデックスを時刻に変換します。インデックスを構築して CSVProvider.IndexToTime と同じ結果を返します。
+func (p *StreamingCSVProvider) IndexToTime(index int) (time.Time, error) {
+	return p.index().IndexToTime(index)
+}
+
+// GetCandlesByTime は指定された時間範囲のローソク足データを取得します。時刻の検索にはインデックスを構築します。
+func (p *StreamingCSVProvider) GetCandlesByTime(ctx context.Context, startTime, endTime time.Time) ([]models.Candle, error) {
+	return p.index().GetCandlesByTime(ctx, startTime, endTime)
+}
+
+// GetPrevCandlesByTime は基準時刻より前のローソク足データを取得します。時刻の検索にはインデックスを構築します。
+func (p *StreamingCSVProvider) GetPrevCandlesByTime(ctx context.Context, baseTime time.Time, count int) ([]models.Candle, error) {
+	return p.index().GetPrevCandlesByTime(ctx, baseTime, count)
+}
+
+// GetPrevCandlesByIndex は基準インデックスより前のローソク足データを取得します。範囲の判定にはインデックスを構築します。
+func (p *StreamingCSVProvider) GetPrevCandlesByIndex(ctx context.Context, baseIndex int, count int) ([]models.Candle, error) {
+	return p.index().GetPrevCandlesByIndex(ctx, baseIndex, count)
+}
+
+// GetNextCandlesByTime は基準時刻より後のローソク足データを取得します。時刻の検索にはインデックスを構築します。
+func (p *StreamingCSVProvider) GetNextCandlesByTime(ctx context.Context, baseTime time.Time, count int) ([]models.Candle, error) {
+	return p.index().GetNextCandlesByTime(ctx, baseTime, count)
+}
+
+// GetNextCandlesByIndex は基準インデックスより後のローソク足データを取得します。範囲の判定にはインデックスを構築します。
+func (p *StreamingCSVProvider) GetNextCandlesByIndex(ctx context.Context, baseIndex int, count int) ([]models.Candle, error) {
+	return p.index().GetNextCandlesByIndex(ctx, baseIndex, count)
+}
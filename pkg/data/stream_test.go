@@ -0,0 +1,269 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// writeStreamData はテスト用のCSVファイルを一時ディレクトリに作成します。
+func writeStreamData(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stream.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+	return path
+}
+
+// readAllInChunks は Market と同じく先頭から size 本ずつ（Sized の場合は末尾までに切り詰めて）、範囲外になるまで読み込みます。
+func readAllInChunks(t *testing.T, provider DataProvider, size int) []models.Candle {
+	t.Helper()
+	var all []models.Candle
+	for start := 0; ; start += size {
+		end := start + size - 1
+		if sized, ok := provider.(Sized); ok && end >= sized.Len() {
+			if start >= sized.Len() {
+				return all
+			}
+			end = sized.Len() - 1
+		}
+		candles, err := provider.GetCandlesByIndex(context.Background(), start, end)
+		if errors.Is(err, ErrIndexOutOfRange) {
+			return all
+		}
+		if err != nil {
+			t.Fatalf("GetCandlesByIndex(%d) error = %v", start, err)
+		}
+		all = append(all, candles...)
+	}
+}
+
+func TestStreamingCSVProvider_Sequential(t *testing.T) {
+	config := models.DataProviderConfig{FilePath: "testdata/sample.csv", Format: "csv"}
+	expected := readAllInChunks(t, NewCSVProvider(config), 1000)
+
+	provider := NewStreamingCSVProvider(config)
+	defer provider.Close()
+
+	t.Run("should return the same candles as the indexed provider", func(t *testing.T) {
+		got := readAllInChunks(t, provider, 64)
+		if len(got) != len(expected) {
+			t.Fatalf("Expected %d candles, got %d", len(expected), len(got))
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Fatalf("candles[%d] = %+v, want %+v", i, got[i], expected[i])
+			}
+		}
+		if provider.Indexed() {
+			t.Error("Expected sequential reads not to build an index")
+		}
+	})
+
+	t.Run("should rewind when an earlier index is requested", func(t *testing.T) {
+		candles, err := provider.GetCandlesByIndex(context.Background(), 2, 3)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(candles) != 2 || candles[0] != expected[2] || candles[1] != expected[3] {
+			t.Errorf("Expected candles 2-3 after rewind, got %+v", candles)
+		}
+		if provider.Indexed() {
+			t.Error("Expected rewind not to build an index")
+		}
+	})
+
+	t.Run("should build index only for random access", func(t *testing.T) {
+		index, err := provider.TimeToIndex(expected[10].Timestamp)
+		if err != nil || index != 10 {
+			t.Fatalf("TimeToIndex() = %d, %v, want 10", index, err)
+		}
+		if !provider.Indexed() {
+			t.Error("Expected TimeToIndex to build an index")
+		}
+		next, err := provider.GetNextCandlesByIndex(context.Background(), 10, 2)
+		if err != nil || len(next) != 2 || next[0] != expected[11] {
+			t.Errorf("GetNextCandlesByIndex() = %+v, %v", next, err)
+		}
+	})
+
+	t.Run("should return error for invalid range", func(t *testing.T) {
+		if _, err := provider.GetCandlesByIndex(context.Background(), 5, 4); err == nil {
+			t.Error("Expected error when start index is after end index")
+		}
+		if _, err := provider.GetCandlesByIndex(context.Background(), -1, 4); !errors.Is(err, ErrIndexOutOfRange) {
+			t.Errorf("Expected ErrIndexOutOfRange for negative index, got %v", err)
+		}
+	})
+}
+
+func TestStreamingCSVProvider_Rows(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should skip invalid rows and record warnings", func(t *testing.T) {
+		provider := NewStreamingCSVProvider(models.DataProviderConfig{FilePath: "testdata/mixed.csv", Format: "csv"})
+		candles, err := provider.GetCandlesByIndex(ctx, 0, 10)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(candles) != 3 {
+			t.Fatalf("Expected 3 valid candles, got %d", len(candles))
+		}
+		warnings := provider.Warnings()
+		if len(warnings) != 4 || warnings[0].Line != 2 || warnings[3].Line != 6 {
+			t.Errorf("Expected warnings for lines 2, 3, 5, 6, got %v", warnings)
+		}
+	})
+
+	t.Run("should log skipped rows to configured logger", func(t *testing.T) {
+		defaultOutput := captureDefaultLogger(t)
+		var buf bytes.Buffer
+		provider := NewStreamingCSVProvider(models.DataProviderConfig{FilePath: "testdata/mixed.csv", Format: "csv"})
+		provider.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+		if _, err := provider.GetCandlesByIndex(ctx, 0, 10); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(buf.String(), "skipped invalid rows") {
+			t.Errorf("Expected warning on configured logger, got %q", buf.String())
+		}
+
+		// ランダムアクセス用に構築する CSVProvider も同じロガーを使う
+		buf.Reset()
+		if _, err := provider.GetPrevCandlesByIndex(ctx, 2, 1); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !provider.Indexed() || !strings.Contains(buf.String(), "skipped invalid rows") {
+			t.Errorf("Expected indexed provider to log on configured logger, got %q", buf.String())
+		}
+		if defaultOutput.Len() != 0 {
+			t.Errorf("Expected nothing on default logger, got %q", defaultOutput.String())
+		}
+	})
+
+	t.Run("should return error for invalid row when strict", func(t *testing.T) {
+		provider := NewStreamingCSVProvider(models.DataProviderConfig{FilePath: "testdata/mixed.csv", Format: "csv", StrictParsing: true})
+		if _, err := provider.GetCandlesByIndex(ctx, 0, 10); !errors.Is(err, ErrInvalidRow) {
+			t.Errorf("Expected ErrInvalidRow, got %v", err)
+		}
+	})
+
+	t.Run("should apply max rows", func(t *testing.T) {
+		provider := NewStreamingCSVProvider(models.DataProviderConfig{FilePath: "testdata/sample.csv", Format: "csv", MaxRows: 3})
+		candles, err := provider.GetCandlesByIndex(ctx, 0, 10)
+		if err != nil || len(candles) != 3 || !provider.Truncated() {
+			t.Errorf("Expected 3 candles and truncated, got %d (%v), truncated %v", len(candles), err, provider.Truncated())
+		}
+
+		strict := NewStreamingCSVProvider(models.DataProviderConfig{FilePath: "testdata/sample.csv", Format: "csv", MaxRows: 3, MaxRowsPolicy: models.MaxRowsError})
+		if _, err := strict.GetCandlesByIndex(ctx, 0, 10); !errors.Is(err, ErrMaxRowsExceeded) {
+			t.Errorf("Expected ErrMaxRowsExceeded, got %v", err)
+		}
+	})
+
+	t.Run("should return error for missing file", func(t *testing.T) {
+		provider := NewStreamingCSVProvider(models.DataProviderConfig{FilePath: "testdata/not_exists.csv", Format: "csv"})
+		if _, err := provider.GetCandlesByIndex(ctx, 0, 10); err == nil || errors.Is(err, ErrIndexOutOfRange) {
+			t.Errorf("Expected file error, got %v", err)
+		}
+	})
+}
+
+func TestStreamingCSVProvider_Duplicates(t *testing.T) {
+	ctx := context.Background()
+	path := writeStreamData(t, "2024.01.01,09:00,1.1000,1.1010,1.0990,1.1005,1000\n"+
+		"2024.01.01,09:01,1.1005,1.1015,1.0995,1.1010,1000\n"+
+		"2024.01.01,09:01,1.1005,1.1025,1.0995,1.1020,2000\n"+
+		"2024.01.01,09:02,1.1010,1.1020,1.1000,1.1015,1000\n")
+
+	tests := []struct {
+		name        string
+		policy      models.DuplicatePolicy
+		wantClose   float64
+		wantWarning int
+	}{
+		{"keep first", "", 1.1010, 3},
+		{"keep last", models.DuplicateKeepLast, 1.1020, 2},
+	}
+	for _, tt := range tests {
+		t.Run("should "+tt.name, func(t *testing.T) {
+			provider := NewStreamingCSVProvider(models.DataProviderConfig{FilePath: path, Format: "csv", DuplicatePolicy: tt.policy})
+			candles, err := provider.GetCandlesByIndex(ctx, 0, 10)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(candles) != 3 || candles[1].Close != tt.wantClose {
+				t.Errorf("Expected 3 candles with close %f at 09:01, got %+v", tt.wantClose, candles)
+			}
+			warnings := provider.Warnings()
+			if len(warnings) != 1 || warnings[0].Line != tt.wantWarning {
+				t.Errorf("Expected duplicate warning for line %d, got %v", tt.wantWarning, warnings)
+			}
+		})
+	}
+
+	t.Run("should return error when policy is error", func(t *testing.T) {
+		provider := NewStreamingCSVProvider(models.DataProviderConfig{FilePath: path, Format: "csv", DuplicatePolicy: models.DuplicateError})
+		if _, err := provider.GetCandlesByIndex(ctx, 0, 10); !errors.Is(err, ErrDuplicateTimestamp) {
+			t.Errorf("Expected ErrDuplicateTimestamp, got %v", err)
+		}
+	})
+
+	t.Run("should return error for unsorted data", func(t *testing.T) {
+		provider := NewStreamingCSVProvider(models.DataProviderConfig{FilePath: "testdata/duplicates.csv", Format: "csv"})
+		_, err := provider.GetCandlesByIndex(ctx, 0, 10)
+		if !errors.Is(err, ErrUnsortedData) {
+			t.Fatalf("Expected ErrUnsortedData, got %v", err)
+		}
+		// エラーは以降の読み込みでも返る
+		if _, err := provider.GetCandlesByIndex(ctx, 10, 20); !errors.Is(err, ErrUnsortedData) {
+			t.Errorf("Expected sticky ErrUnsortedData, got %v", err)
+		}
+	})
+}
+
+func TestStreamingCSVProvider_FillInterval(t *testing.T) {
+	config := models.DataProviderConfig{FilePath: "testdata/gaps.csv", Format: "csv", FillInterval: time.Minute}
+	expected := readAllInChunks(t, NewCSVProvider(config), 100)
+	got := readAllInChunks(t, NewStreamingCSVProvider(config), 2)
+
+	if len(got) != 9 || len(got) != len(expected) {
+		t.Fatalf("Expected 9 candles after filling, got %d (indexed %d)", len(got), len(expected))
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("candles[%d] = %+v, want %+v", i, got[i], expected[i])
+		}
+	}
+}
+
+func TestStreamingCSVProvider_Close(t *testing.T) {
+	path := writeStreamData(t, "2024.01.01,09:00,1.1000,1.1010,1.0990,1.1005,1000\n")
+	provider := NewStreamingCSVProvider(models.DataProviderConfig{FilePath: path, Format: "csv"})
+	var _ io.Closer = provider
+
+	if _, err := provider.GetCandlesByIndex(context.Background(), 0, 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := provider.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err := provider.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+
+	// 閉じた後も先頭から読み直せる
+	candles, err := provider.GetCandlesByIndex(context.Background(), 0, 0)
+	if err != nil || len(candles) != 1 {
+		t.Errorf("Expected to reopen after Close, got %d candles (%v)", len(candles), err)
+	}
+}
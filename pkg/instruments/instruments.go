@@ -0,0 +1,169 @@
+package instruments
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+//go:embed default_instruments.json
+var defaultInstrumentsJSON []byte
+
+// Instrument は通貨ペアなどの銘柄メタデータを表します。
+type Instrument struct {
+	Symbol        string  `json:"symbol,omitempty"`
+	PipSize       float64 `json:"pip_size"`
+	ContractSize  float64 `json:"contract_size"`
+	MinSize       float64 `json:"min_size"`
+	SizeStep      float64 `json:"size_step"`
+	TickSize      float64 `json:"tick_size"`
+	QuoteCurrency string  `json:"quote_currency"`
+}
+
+// Validate は銘柄メタデータの妥当性を検証します。
+func (i *Instrument) Validate() error {
+	if i.PipSize <= 0 {
+		return errors.New("pip size must be positive")
+	}
+
+	if i.ContractSize <= 0 {
+		return errors.New("contract size must be positive")
+	}
+
+	if i.MinSize < 0 || i.SizeStep < 0 || i.TickSize < 0 {
+		return errors.New("min size, size step and tick size must be non-negative")
+	}
+
+	return nil
+}
+
+// NormalizeSize はサイズを取引単位に切り捨て、最小サイズ未満の場合は0を返します。
+func (i *Instrument) NormalizeSize(size float64) float64 {
+	if i.SizeStep > 0 {
+		// 浮動小数点誤差で1ステップ分切り捨てないよう微小値を加える
+		size = math.Floor(size/i.SizeStep+1e-9) * i.SizeStep
+	}
+
+	if size < i.MinSize {
+		return 0
+	}
+
+	return size
+}
+
+// RoundPrice は価格をティックサイズの倍数に丸めます。ティックサイズが0の場合はそのまま返します。
+func (i *Instrument) RoundPrice(price float64) float64 {
+	if i.TickSize <= 0 {
+		return price
+	}
+	return math.Round(price/i.TickSize) * i.TickSize
+}
+
+// Registry はシンボルから銘柄メタデータを引くレジストリです。
+type Registry struct {
+	instruments map[string]Instrument
+}
+
+// NewRegistry は空のRegistryを作成します。
+func NewRegistry() *Registry {
+	return &Registry{
+		instruments: make(map[string]Instrument),
+	}
+}
+
+// Default は主要な通貨ペアを登録済みのRegistryを返します。
+func Default() *Registry {
+	registry, err := LoadFromReader(bytes.NewReader(defaultInstrumentsJSON))
+	if err != nil {
+		// 埋め込みデータが不正な場合はビルド時の不具合
+		panic(fmt.Sprintf("invalid default instruments: %v", err))
+	}
+	return registry
+}
+
+// Load はJSONファイルからRegistryを読み込みます。
+func Load(path string) (*Registry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return LoadFromReader(file)
+}
+
+// LoadFromReader はシンボルをキーとするJSONオブジェクトからRegistryを読み込みます。
+func LoadFromReader(r io.Reader) (*Registry, error) {
+	var raw map[string]Instrument
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse instruments: %w", err)
+	}
+
+	registry := NewRegistry()
+	for symbol, instrument := range raw {
+		instrument.Symbol = symbol
+		if err := registry.Register(instrument); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+// Register は銘柄を登録します。同じシンボルが既にある場合は上書きします。
+func (r *Registry) Register(instrument Instrument) error {
+	symbol := strings.ToUpper(strings.TrimSpace(instrument.Symbol))
+	if symbol == "" {
+		return errors.New("symbol is required")
+	}
+
+	if err := instrument.Validate(); err != nil {
+		return fmt.Errorf("invalid instrument %s: %w", symbol, err)
+	}
+
+	instrument.Symbol = symbol
+	r.instruments[symbol] = instrument
+	return nil
+}
+
+// Merge は other に登録された銘柄を追加します。同じシンボルは other の内容で上書きします。
+func (r *Registry) Merge(other *Registry) {
+	if other == nil {
+		return
+	}
+	for symbol, instrument := range other.instruments {
+		r.instruments[symbol] = instrument
+	}
+}
+
+// Get は指定シンボルの銘柄メタデータを取得します。
+func (r *Registry) Get(symbol string) (Instrument, bool) {
+	if r == nil {
+		return Instrument{}, false
+	}
+	instrument, ok := r.instruments[strings.ToUpper(symbol)]
+	return instrument, ok
+}
+
+// Len は登録済みのシンボル数を返します。
+func (r *Registry) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.instruments)
+}
+
+// NormalizeSize は指定シンボルの取引単位でサイズを正規化します。未登録の場合はそのまま返します。
+func (r *Registry) NormalizeSize(symbol string, size float64) float64 {
+	instrument, ok := r.Get(symbol)
+	if !ok {
+		return size
+	}
+	return instrument.NormalizeSize(size)
+}
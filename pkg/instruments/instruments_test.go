@@ -0,0 +1,97 @@
+package instruments
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// レジストリ読み込みテスト
+func TestRegistry_Load(t *testing.T) {
+	t.Run("should load instruments from JSON file", func(t *testing.T) {
+		registry, err := Load("./testdata/instruments.json")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, registry.Len())
+
+		eurusd, ok := registry.Get("EURUSD")
+		assert.True(t, ok)
+		assert.Equal(t, "EURUSD", eurusd.Symbol)
+		assert.Equal(t, 0.0001, eurusd.PipSize)
+		assert.Equal(t, 1000.0, eurusd.ContractSize)
+		assert.Equal(t, "USD", eurusd.QuoteCurrency)
+
+		// シンボルは大文字に正規化される
+		usdjpy, ok := registry.Get("usdjpy")
+		assert.True(t, ok)
+		assert.Equal(t, "USDJPY", usdjpy.Symbol)
+		assert.Equal(t, 0.01, usdjpy.PipSize)
+	})
+
+	t.Run("should return error for missing file", func(t *testing.T) {
+		_, err := Load("./testdata/not_exists.json")
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject invalid instrument", func(t *testing.T) {
+		_, err := LoadFromReader(strings.NewReader(`{"EURUSD": {"pip_size": 0, "contract_size": 1000}}`))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "pip size must be positive")
+	})
+}
+
+// デフォルトレジストリテスト
+func TestRegistry_Default(t *testing.T) {
+	registry := Default()
+
+	for _, symbol := range []string{"EURUSD", "GBPUSD", "USDJPY", "EURJPY"} {
+		_, ok := registry.Get(symbol)
+		assert.True(t, ok, "default registry should contain %s", symbol)
+	}
+
+	usdjpy, _ := registry.Get("USDJPY")
+	assert.Equal(t, 0.01, usdjpy.PipSize)
+	assert.Equal(t, "JPY", usdjpy.QuoteCurrency)
+
+	_, ok := registry.Get("UNKNOWN")
+	assert.False(t, ok)
+}
+
+// サイズ正規化テスト
+func TestRegistry_NormalizeSize(t *testing.T) {
+	registry, err := Load("./testdata/instruments.json")
+	assert.NoError(t, err)
+
+	assert.InDelta(t, 1.2, registry.NormalizeSize("EURUSD", 1.25), 1e-9) // ステップで切り捨て
+	assert.Equal(t, 0.0, registry.NormalizeSize("EURUSD", 0.05))         // 最小サイズ未満
+	assert.Equal(t, 1.234, registry.NormalizeSize("UNKNOWN", 1.234))     // 未登録はそのまま
+}
+
+// 価格丸めテスト
+func TestInstrument_RoundPrice(t *testing.T) {
+	registry, err := Load("./testdata/instruments.json")
+	assert.NoError(t, err)
+
+	usdjpy, _ := registry.Get("USDJPY")
+	assert.InDelta(t, 150.124, usdjpy.RoundPrice(150.1237), 1e-9) // ティックサイズ0.001の倍数に丸める
+	assert.InDelta(t, 150.123, usdjpy.RoundPrice(150.1231), 1e-9)
+
+	noTick := Instrument{PipSize: 0.01, ContractSize: 1000}
+	assert.Equal(t, 150.1237, noTick.RoundPrice(150.1237)) // ティックサイズ0はそのまま
+}
+
+// レジストリ統合テスト
+func TestRegistry_Merge(t *testing.T) {
+	registry := Default()
+	loaded, err := Load("./testdata/instruments.json")
+	assert.NoError(t, err)
+
+	registry.Merge(loaded)
+	eurusd, _ := registry.Get("EURUSD")
+	assert.Equal(t, 1000.0, eurusd.ContractSize) // 同じシンボルは上書き
+	_, ok := registry.Get("GBPUSD")
+	assert.True(t, ok) // 他のシンボルは残る
+
+	registry.Merge(nil)
+	assert.Equal(t, Default().Len(), registry.Len())
+}
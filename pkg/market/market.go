@@ -2,6 +2,10 @@ package market
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -17,7 +21,23 @@ type Market interface {
 	GetCurrentTime() time.Time
 	GetCurrentCandle() *models.Candle
 	GetPrevCandles(startTime time.Time, index int) []*models.Candle
+	GetRecentCandles(count int) []*models.Candle
+	SetStartTime(startTime time.Time) error
+	Reset(ctx context.Context) error
 	IsFinished() bool
+	Progress() (int, int)
+	Err() error
+	State() State
+	RestoreState(ctx context.Context, state State) error
+	Close() error
+}
+
+// State is a snapshot of the market position, used to checkpoint and resume a backtest.
+type State struct {
+	Index       int           `json:"index"`
+	TimeOffset  time.Duration `json:"time_offset"`
+	Finished    bool          `json:"finished"`
+	CurrentTime time.Time     `json:"current_time"`
 }
 
 // MarketImpl implements the Market interface.
@@ -29,22 +49,62 @@ type MarketImpl struct {
 	refillThreshold int
 	finished        bool
 	initialized     bool
+	closed          bool
+	err             error
 	mu              sync.Mutex
 	lastIndexFetched int
+	timeOffset      time.Duration
+	// calendar drops candles outside trading days; sourceIndexes holds the provider index of each cached candle when set
+	calendar        *models.TradingCalendar
+	sourceIndexes   []int
 }
 
-// NewMarket creates a new MarketImpl with default cache settings.
+// NewMarket creates a new MarketImpl that reads the configured data file,
+// using the cache settings from marketConfig (defaults when zero).
+// When DataProvider.Streaming is set, candles are read lazily with data.StreamingCSVProvider
+// instead of indexing the whole file up front.
 func NewMarket(marketConfig models.MarketConfig) *MarketImpl {
-	provider := data.NewCSVProvider(marketConfig.DataProvider)
-	cacheSize := 500
-	refillThreshold := 100
-	
-	return &MarketImpl{
+	if marketConfig.DataProvider.Streaming {
+		return NewMarketWithProviderConfig(data.NewStreamingCSVProvider(marketConfig.DataProvider), marketConfig)
+	}
+	return NewMarketWithProviderConfig(data.NewCSVProvider(marketConfig.DataProvider), marketConfig)
+}
+
+// NewMarketWithProvider creates a new MarketImpl with default cache settings that reads candles from the given provider.
+func NewMarketWithProvider(provider data.DataProvider) *MarketImpl {
+	return NewMarketWithProviderConfig(provider, models.MarketConfig{})
+}
+
+// NewMarketWithProviderConfig creates a new MarketImpl that reads candles from the given provider,
+// using the cache settings from marketConfig (defaults when zero). marketConfig.DataProvider is ignored.
+// When marketConfig.Calendar is set, candles on non-trading days (judged by their original timestamps)
+// are skipped, so Forward moves straight from Friday's last candle to Monday's first.
+// When marketConfig.Logger is set, it is passed to providers that implement data.LoggerSetter.
+func NewMarketWithProviderConfig(provider data.DataProvider, marketConfig models.MarketConfig) *MarketImpl {
+	cacheSize, refillThreshold := marketConfig.CacheSettings()
+
+	m := &MarketImpl{
 		provider:        provider,
 		cacheSize:       cacheSize,
 		refillThreshold: refillThreshold,
 		currentIndex:    -1, // Start before the first element
 		candleCache:     make([]*models.Candle, 0, cacheSize),
+		calendar:        marketConfig.Calendar,
+	}
+	if marketConfig.Logger != nil {
+		m.SetLogger(marketConfig.Logger)
+	}
+	return m
+}
+
+// SetLogger passes the diagnostic logger to the provider when it implements data.LoggerSetter.
+// A nil logger silences the provider. Call it before Initialize.
+func (m *MarketImpl) SetLogger(logger *slog.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if setter, ok := m.provider.(data.LoggerSetter); ok {
+		setter.SetLogger(logger)
 	}
 }
 
@@ -53,20 +113,56 @@ func (m *MarketImpl) Initialize(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.closed {
+		return errors.New("market is closed")
+	}
 	if m.initialized {
 		return nil
 	}
 
-	candles, err := m.provider.GetCandlesByIndex(ctx, 0, m.cacheSize-1)
+	return m.loadInitialCache(ctx)
+}
+
+// Reset rewinds the market to the first candle by reloading the cache from index 0.
+// The time offset set by SetStartTime is kept.
+func (m *MarketImpl) Reset(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.initialized {
+		return errors.New("market not initialized")
+	}
+
+	m.candleCache = make([]*models.Candle, 0, m.cacheSize)
+	m.sourceIndexes = nil
+	m.currentIndex = -1
+	m.finished = false
+	m.initialized = false
+	m.err = nil
+
+	return m.loadInitialCache(ctx)
+}
+
+// loadInitialCache fills the cache starting from index 0. The caller must hold m.mu.
+func (m *MarketImpl) loadInitialCache(ctx context.Context) error {
+	candles, err := m.provider.GetCandlesByIndex(ctx, 0, m.clampEndIndex(m.cacheSize-1))
 	if err != nil {
 		return err
 	}
 
-	for i := range candles {
-		m.candleCache = append(m.candleCache, &candles[i])
-	}
+	m.appendCandles(0, candles)
+	m.lastIndexFetched = len(candles) - 1
 
-	m.lastIndexFetched = len(m.candleCache) - 1
+	// Every candle of the first chunk may fall on non-trading days
+	for len(m.candleCache) == 0 && len(candles) > 0 {
+		more, err := m.loadMore(ctx)
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+	}
 
 	if len(m.candleCache) == 0 {
 		m.finished = true
@@ -87,16 +183,22 @@ func (m *MarketImpl) Forward() bool {
 		return false
 	}
 
+	return m.forward()
+}
+
+// forward advances the current index, refilling the cache as needed. The caller must hold m.mu.
+func (m *MarketImpl) forward() bool {
 	// Check if we need to refill the cache
 	if len(m.candleCache)-m.currentIndex <= m.refillThreshold {
-		startIndex := m.lastIndexFetched + 1
-		endIndex := startIndex + m.cacheSize - 1
-		newCandles, err := m.provider.GetCandlesByIndex(context.Background(), startIndex, endIndex)
-		if err == nil && len(newCandles) > 0 {
-			for i := range newCandles {
-				m.candleCache = append(m.candleCache, &newCandles[i])
-			}
-			m.lastIndexFetched = endIndex
+		more, err := m.loadMore(context.Background())
+		// With a calendar a whole chunk may be skipped, so keep reading until the next candle is cached
+		for err == nil && more && m.currentIndex+1 >= len(m.candleCache) {
+			more, err = m.loadMore(context.Background())
+		}
+		if err != nil {
+			m.err = err
+			m.finished = true
+			return false
 		}
 	}
 
@@ -109,6 +211,157 @@ func (m *MarketImpl) Forward() bool {
 	return true
 }
 
+// loadMore reads the next chunk after lastIndexFetched into the cache.
+// It returns false at the end of the data; an error other than data.ErrIndexOutOfRange is a read failure.
+// The caller must hold m.mu.
+func (m *MarketImpl) loadMore(ctx context.Context) (bool, error) {
+	startIndex := m.lastIndexFetched + 1
+	endIndex := m.clampEndIndex(startIndex + m.cacheSize - 1)
+	if endIndex < startIndex {
+		return false, nil
+	}
+	newCandles, err := m.provider.GetCandlesByIndex(ctx, startIndex, endIndex)
+	if errors.Is(err, data.ErrIndexOutOfRange) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load candles %d-%d: %w", startIndex, endIndex, err)
+	}
+	if len(newCandles) == 0 {
+		return false, nil
+	}
+
+	m.appendCandles(startIndex, newCandles)
+	m.lastIndexFetched = endIndex
+	return true, nil
+}
+
+// clampEndIndex limits endIndex to the last candle when the provider implements data.Sized,
+// so data shorter than the cache size can be read from providers that reject an end index past the data.
+func (m *MarketImpl) clampEndIndex(endIndex int) int {
+	if sized, ok := m.provider.(data.Sized); ok {
+		if last := sized.Len() - 1; endIndex > last {
+			return last
+		}
+	}
+	return endIndex
+}
+
+// appendCandles adds candles read from the provider starting at startIndex to the cache,
+// skipping those on non-trading days of the calendar. The caller must hold m.mu.
+func (m *MarketImpl) appendCandles(startIndex int, candles []models.Candle) {
+	for i := range candles {
+		if m.calendar != nil {
+			if !m.calendar.IsTradingTime(candles[i].Timestamp) {
+				continue
+			}
+			m.sourceIndexes = append(m.sourceIndexes, startIndex+i)
+		}
+		m.candleCache = append(m.candleCache, m.shiftCandle(&candles[i]))
+	}
+}
+
+// State returns the current position of the market.
+func (m *MarketImpl) State() State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := State{
+		Index:      m.currentIndex,
+		TimeOffset: m.timeOffset,
+		Finished:   m.finished,
+	}
+	if m.currentIndex >= 0 && m.currentIndex < len(m.candleCache) {
+		state.CurrentTime = m.candleCache[m.currentIndex].Timestamp
+	}
+	return state
+}
+
+// RestoreState reloads the cache from the first candle with the saved time offset and
+// advances to the saved index, so the cache looks exactly as it did when the state was taken.
+// It returns an error if the data does not reach the saved index or the candle time differs.
+func (m *MarketImpl) RestoreState(ctx context.Context, state State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.initialized {
+		return errors.New("market not initialized")
+	}
+
+	m.candleCache = make([]*models.Candle, 0, m.cacheSize)
+	m.sourceIndexes = nil
+	m.currentIndex = -1
+	m.finished = false
+	m.initialized = false
+	m.err = nil
+	m.timeOffset = state.TimeOffset
+
+	if err := m.loadInitialCache(ctx); err != nil {
+		return err
+	}
+
+	for m.currentIndex < state.Index {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !m.forward() {
+			if m.err != nil {
+				return m.err
+			}
+			return fmt.Errorf("market data ends before saved index %d", state.Index)
+		}
+	}
+	if m.currentIndex != state.Index {
+		return fmt.Errorf("invalid saved index %d", state.Index)
+	}
+
+	if !state.CurrentTime.IsZero() && !m.candleCache[m.currentIndex].Timestamp.Equal(state.CurrentTime) {
+		return fmt.Errorf("market data does not match saved state: candle %d is at %s, expected %s",
+			state.Index, m.candleCache[m.currentIndex].Timestamp, state.CurrentTime)
+	}
+	m.finished = state.Finished
+
+	return nil
+}
+
+// SetStartTime anchors the simulated clock so that the first candle is at startTime.
+// All cached and subsequently loaded candles are shifted by the same offset.
+func (m *MarketImpl) SetStartTime(startTime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.initialized {
+		return errors.New("market not initialized")
+	}
+	if startTime.IsZero() {
+		return errors.New("start time must not be zero")
+	}
+	if len(m.candleCache) == 0 {
+		return errors.New("no candle data to anchor")
+	}
+
+	delta := startTime.Sub(m.candleCache[0].Timestamp)
+	for i, candle := range m.candleCache {
+		// Copy so the provider's data is left untouched
+		shifted := *candle
+		shifted.Timestamp = candle.Timestamp.Add(delta)
+		m.candleCache[i] = &shifted
+	}
+	m.timeOffset += delta
+
+	return nil
+}
+
+// shiftCandle applies the configured time offset to a freshly loaded candle.
+func (m *MarketImpl) shiftCandle(candle *models.Candle) *models.Candle {
+	if m.timeOffset == 0 {
+		return candle
+	}
+	shifted := *candle
+	shifted.Timestamp = candle.Timestamp.Add(m.timeOffset)
+	return &shifted
+}
+
 // GetCurrentPrice returns the closing price of the current candle.
 func (m *MarketImpl) GetCurrentPrice() float64 {
 	m.mu.Lock()
@@ -172,9 +425,80 @@ func (m *MarketImpl) GetPrevCandles(startTime time.Time, index int) []*models.Ca
 	return m.candleCache[startIndex:index]
 }
 
+// GetRecentCandles returns up to count candles ending at (and including) the current candle.
+func (m *MarketImpl) GetRecentCandles(count int) []*models.Candle {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.initialized || count <= 0 || m.currentIndex >= len(m.candleCache) {
+		return []*models.Candle{}
+	}
+
+	endIndex := m.currentIndex + 1
+	startIndex := endIndex - count
+	if startIndex < 0 {
+		startIndex = 0
+	}
+
+	// Copy so callers cannot observe later cache mutations
+	recent := make([]*models.Candle, endIndex-startIndex)
+	copy(recent, m.candleCache[startIndex:endIndex])
+	return recent
+}
+
 // IsFinished returns true if the market simulation has ended.
 func (m *MarketImpl) IsFinished() bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	return m.finished
+}
+
+// Err returns the error that stopped the market, or nil if it finished normally or is still running.
+// When a candle read fails during Forward, the market is finished and Forward returns false as at the end of data.
+func (m *MarketImpl) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Close releases the candle cache and closes the provider if it implements io.Closer.
+// The market is finished afterwards and cannot be initialized again. Calling Close more than once is safe.
+func (m *MarketImpl) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+
+	m.candleCache = nil
+	m.sourceIndexes = nil
+	m.currentIndex = -1
+	m.finished = true
+	m.initialized = false
+
+	if closer, ok := m.provider.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return fmt.Errorf("failed to close data provider: %w", err)
+		}
+	}
+	return nil
+}
+
+// Progress returns the number of candles reached so far (including the current one)
+// and the total number of candles. The total is 0 when the provider does not implement data.Sized.
+// Candles skipped by the trading calendar count as reached once a later candle is current.
+func (m *MarketImpl) Progress() (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total := 0
+	if sized, ok := m.provider.(data.Sized); ok {
+		total = sized.Len()
+	}
+	if m.calendar != nil && m.currentIndex >= 0 && m.currentIndex < len(m.sourceIndexes) {
+		return m.sourceIndexes[m.currentIndex] + 1, total
+	}
+	return m.currentIndex + 1, total
 }
\ No newline at end of file
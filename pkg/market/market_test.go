@@ -1,11 +1,16 @@
 package market
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/RuiHirano/fx-backtesting/pkg/data"
 	"github.com/RuiHirano/fx-backtesting/pkg/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -246,4 +251,505 @@ func TestMarket_GetPrevCandles(t *testing.T) {
 		prevCandles = market.GetPrevCandles(startTime, 100)
 		assert.Empty(t, prevCandles)
 	})
-}
\ No newline at end of file
+}
+
+func TestMarket_SetStartTime(t *testing.T) {
+	baseTime := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	anchor := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	newMarket := func(count int) (*MarketImpl, []models.Candle) {
+		mockProvider := new(MockDataProvider)
+		candles := make([]models.Candle, count)
+		for i := 0; i < count; i++ {
+			candles[i] = models.Candle{Timestamp: baseTime.Add(time.Duration(i) * time.Minute)}
+		}
+		mockProvider.On("GetCandlesByIndex", mock.Anything, 0, 499).Return(candles, nil)
+		mockProvider.On("GetCandlesByIndex", mock.Anything, mock.Anything, mock.Anything).Return([]models.Candle{}, nil).Maybe()
+
+		market := NewMarket(models.MarketConfig{})
+		market.provider = mockProvider
+		return market, candles
+	}
+
+	t.Run("ANCHOR-001: Shift clock to the given start time", func(t *testing.T) {
+		market, candles := newMarket(5)
+		market.Initialize(context.Background())
+
+		assert.NoError(t, market.SetStartTime(anchor))
+		assert.Equal(t, anchor, market.GetCurrentTime())
+
+		market.Forward()
+		assert.Equal(t, anchor.Add(time.Minute), market.GetCurrentTime())
+		assert.Equal(t, anchor.Add(time.Minute), market.GetCurrentCandle().Timestamp)
+
+		// Provider data is left untouched
+		assert.Equal(t, baseTime, candles[0].Timestamp)
+	})
+
+	t.Run("ANCHOR-002: Invalid start time or uninitialized market", func(t *testing.T) {
+		market, _ := newMarket(5)
+		assert.Error(t, market.SetStartTime(anchor))
+
+		market.Initialize(context.Background())
+		assert.Error(t, market.SetStartTime(time.Time{}))
+	})
+}
+
+func TestMarket_Reset(t *testing.T) {
+	mockProvider := new(MockDataProvider)
+	baseTime := time.Date(2025, 7, 11, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 20)
+	for i := 0; i < 20; i++ {
+		candles[i] = models.Candle{Timestamp: baseTime.Add(time.Duration(i) * time.Minute), Close: float64(i)}
+	}
+	mockProvider.On("GetCandlesByIndex", mock.Anything, 0, 499).Return(candles, nil)
+	mockProvider.On("GetCandlesByIndex", mock.Anything, mock.Anything, mock.Anything).Return([]models.Candle{}, nil).Maybe()
+
+	t.Run("RESET-001: Rewind to the first candle after reaching the end", func(t *testing.T) {
+		market := NewMarket(models.MarketConfig{})
+		market.provider = mockProvider
+		assert.NoError(t, market.Initialize(context.Background()))
+
+		for market.Forward() {
+		}
+		assert.True(t, market.IsFinished())
+
+		assert.NoError(t, market.Reset(context.Background()))
+		assert.False(t, market.IsFinished())
+		assert.Equal(t, baseTime, market.GetCurrentTime())
+		assert.Equal(t, 0.0, market.GetCurrentPrice())
+		assert.True(t, market.Forward())
+		assert.Equal(t, 1.0, market.GetCurrentPrice())
+	})
+
+	t.Run("RESET-002: Keep the anchored start time", func(t *testing.T) {
+		market := NewMarket(models.MarketConfig{})
+		market.provider = mockProvider
+		assert.NoError(t, market.Initialize(context.Background()))
+
+		anchor := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		assert.NoError(t, market.SetStartTime(anchor))
+		market.Forward()
+
+		assert.NoError(t, market.Reset(context.Background()))
+		assert.Equal(t, anchor, market.GetCurrentTime())
+	})
+
+	t.Run("RESET-003: Reset before Initialize", func(t *testing.T) {
+		market := NewMarket(models.MarketConfig{})
+		market.provider = mockProvider
+		assert.Error(t, market.Reset(context.Background()))
+	})
+}
+
+func TestMarket_GetRecentCandles(t *testing.T) {
+	mockProvider := new(MockDataProvider)
+	baseTime := time.Date(2025, 7, 11, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 20)
+	for i := 0; i < 20; i++ {
+		candles[i] = models.Candle{Timestamp: baseTime.Add(time.Duration(i) * time.Minute)}
+	}
+	mockProvider.On("GetCandlesByIndex", mock.Anything, 0, 499).Return(candles, nil)
+	mockProvider.On("GetCandlesByIndex", mock.Anything, mock.Anything, mock.Anything).Return([]models.Candle{}, nil).Maybe()
+
+	market := NewMarket(models.MarketConfig{})
+	market.provider = mockProvider
+	market.Initialize(context.Background())
+
+	for i := 0; i < 10; i++ {
+		market.Forward()
+	}
+
+	t.Run("RECENT-001: Get last 3 candles including current", func(t *testing.T) {
+		recent := market.GetRecentCandles(3)
+		assert.Len(t, recent, 3)
+		assert.Equal(t, baseTime.Add(8*time.Minute), recent[0].Timestamp)
+		assert.Equal(t, baseTime.Add(10*time.Minute), recent[2].Timestamp)
+	})
+
+	t.Run("RECENT-002: Count larger than available history", func(t *testing.T) {
+		recent := market.GetRecentCandles(100)
+		assert.Len(t, recent, 11)
+		assert.Equal(t, baseTime, recent[0].Timestamp)
+	})
+
+	t.Run("RECENT-003: Non-positive count", func(t *testing.T) {
+		assert.Empty(t, market.GetRecentCandles(0))
+		assert.Empty(t, market.GetRecentCandles(-1))
+	})
+}
+
+func TestMarket_Progress(t *testing.T) {
+	baseTime := time.Date(2025, 7, 11, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 5)
+	for i := range candles {
+		candles[i] = models.Candle{Timestamp: baseTime.Add(time.Duration(i) * time.Minute), Close: 1.0}
+	}
+
+	t.Run("PROGRESS-001: Sized provider reports total", func(t *testing.T) {
+		market := NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		bar, total := market.Progress()
+		assert.Equal(t, 0, bar)
+		assert.Equal(t, 5, total)
+
+		market.Initialize(context.Background())
+		market.Forward()
+		bar, total = market.Progress()
+		assert.Equal(t, 2, bar)
+		assert.Equal(t, 5, total)
+
+		for market.Forward() {
+		}
+		bar, _ = market.Progress()
+		assert.Equal(t, 5, bar)
+	})
+
+	t.Run("PROGRESS-002: Provider without length", func(t *testing.T) {
+		mockProvider := new(MockDataProvider)
+		mockProvider.On("GetCandlesByIndex", mock.Anything, 0, 499).Return(candles, nil)
+		mockProvider.On("GetCandlesByIndex", mock.Anything, mock.Anything, mock.Anything).Return([]models.Candle{}, nil).Maybe()
+
+		market := NewMarketWithProvider(mockProvider)
+		market.Initialize(context.Background())
+		bar, total := market.Progress()
+		assert.Equal(t, 1, bar)
+		assert.Equal(t, 0, total)
+	})
+}
+
+func TestMarket_RestoreState(t *testing.T) {
+	baseTime := time.Date(2025, 7, 11, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 20)
+	for i := range candles {
+		candles[i] = models.Candle{Timestamp: baseTime.Add(time.Duration(i) * time.Minute), Close: float64(i)}
+	}
+
+	t.Run("STATE-001: Restore the saved position and time offset", func(t *testing.T) {
+		market := NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		assert.NoError(t, market.Initialize(context.Background()))
+		anchor := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		assert.NoError(t, market.SetStartTime(anchor))
+		for i := 0; i < 7; i++ {
+			market.Forward()
+		}
+		state := market.State()
+		assert.Equal(t, 7, state.Index)
+		assert.Equal(t, anchor.Add(7*time.Minute), state.CurrentTime)
+
+		restored := NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		assert.NoError(t, restored.Initialize(context.Background()))
+		assert.NoError(t, restored.RestoreState(context.Background(), state))
+		assert.Equal(t, anchor.Add(7*time.Minute), restored.GetCurrentTime())
+		assert.Equal(t, 7.0, restored.GetCurrentPrice())
+		assert.Len(t, restored.GetRecentCandles(100), 8)
+		assert.True(t, restored.Forward())
+		assert.Equal(t, 8.0, restored.GetCurrentPrice())
+	})
+
+	t.Run("STATE-002: Restore a finished market", func(t *testing.T) {
+		market := NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		assert.NoError(t, market.Initialize(context.Background()))
+		for market.Forward() {
+		}
+
+		restored := NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		assert.NoError(t, restored.Initialize(context.Background()))
+		assert.NoError(t, restored.RestoreState(context.Background(), market.State()))
+		assert.True(t, restored.IsFinished())
+		assert.False(t, restored.Forward())
+	})
+
+	t.Run("STATE-003: Data does not match the saved state", func(t *testing.T) {
+		market := NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		assert.NoError(t, market.Initialize(context.Background()))
+
+		assert.Error(t, market.RestoreState(context.Background(), State{Index: 50}))
+		assert.Error(t, market.RestoreState(context.Background(), State{Index: 3, CurrentTime: baseTime}))
+	})
+
+	t.Run("STATE-004: Restore before Initialize", func(t *testing.T) {
+		market := NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		assert.Error(t, market.RestoreState(context.Background(), State{}))
+	})
+}
+
+func TestMarket_CacheSettings(t *testing.T) {
+	baseTime := time.Date(2025, 7, 11, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 25)
+	for i := range candles {
+		candles[i] = models.Candle{Timestamp: baseTime.Add(time.Duration(i) * time.Minute), Close: float64(i)}
+	}
+
+	t.Run("CACHE-001: Load and refill with the configured cache size", func(t *testing.T) {
+		mockProvider := new(MockDataProvider)
+		mockProvider.On("GetCandlesByIndex", mock.Anything, 0, 9).Return(candles[0:10], nil).Once()
+		mockProvider.On("GetCandlesByIndex", mock.Anything, 10, 19).Return(candles[10:20], nil).Once()
+		mockProvider.On("GetCandlesByIndex", mock.Anything, 20, 29).Return(candles[20:25], nil).Once()
+		mockProvider.On("GetCandlesByIndex", mock.Anything, mock.Anything, mock.Anything).Return([]models.Candle{}, nil).Maybe()
+
+		market := NewMarketWithProviderConfig(mockProvider, models.MarketConfig{CacheSize: 10, RefillThreshold: 3})
+		assert.NoError(t, market.Initialize(context.Background()))
+
+		steps := 0
+		for market.Forward() {
+			steps++
+		}
+		assert.Equal(t, 24, steps)
+		assert.Equal(t, 24.0, market.GetCurrentPrice())
+		mockProvider.AssertExpectations(t)
+	})
+
+	t.Run("CACHE-003: Load data shorter than the cache from a provider that rejects ranges past the end", func(t *testing.T) {
+		// CSVProvider returns an error for an end index past the data, so the market limits the range with data.Sized
+		path := filepath.Join(t.TempDir(), "short.csv")
+		content := "2024.01.01,09:00,1.1,1.1,1.1,1.1,1000\n2024.01.01,09:01,1.2,1.2,1.2,1.2,1000\n2024.01.01,09:02,1.3,1.3,1.3,1.3,1000\n"
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		provider := data.NewCSVProvider(models.DataProviderConfig{FilePath: path, Format: "csv"})
+		market := NewMarketWithProviderConfig(provider, models.MarketConfig{CacheSize: 10, RefillThreshold: 3})
+		assert.NoError(t, market.Initialize(context.Background()))
+
+		steps := 0
+		for market.Forward() {
+			steps++
+		}
+		assert.NoError(t, market.Err())
+		assert.Equal(t, 2, steps)
+		assert.Equal(t, 1.3, market.GetCurrentPrice())
+	})
+
+	t.Run("CACHE-002: Default cache settings", func(t *testing.T) {
+		market := NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		assert.Equal(t, models.DefaultCacheSize, market.cacheSize)
+		assert.Equal(t, models.DefaultRefillThreshold, market.refillThreshold)
+	})
+}
+
+// closableProvider counts Close calls on an in-memory provider.
+type closableProvider struct {
+	*data.InMemoryProvider
+	closeCount int
+	closeErr   error
+}
+
+func (p *closableProvider) Close() error {
+	p.closeCount++
+	return p.closeErr
+}
+
+func TestMarket_Close(t *testing.T) {
+	baseTime := time.Date(2025, 7, 11, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 5)
+	for i := range candles {
+		candles[i] = models.Candle{Timestamp: baseTime.Add(time.Duration(i) * time.Minute), Close: 1.0}
+	}
+
+	t.Run("CLOSE-001: Closes provider once and finishes market", func(t *testing.T) {
+		provider := &closableProvider{InMemoryProvider: data.NewInMemoryProvider(candles)}
+		market := NewMarketWithProvider(provider)
+		assert.NoError(t, market.Initialize(context.Background()))
+
+		assert.NoError(t, market.Close())
+		assert.NoError(t, market.Close())
+		assert.Equal(t, 1, provider.closeCount)
+
+		assert.True(t, market.IsFinished())
+		assert.False(t, market.Forward())
+		assert.Nil(t, market.GetCurrentCandle())
+		assert.Error(t, market.Initialize(context.Background()))
+	})
+
+	t.Run("CLOSE-002: Provider without Close", func(t *testing.T) {
+		market := NewMarketWithProvider(data.NewInMemoryProvider(candles))
+		assert.NoError(t, market.Initialize(context.Background()))
+		assert.NoError(t, market.Close())
+	})
+
+	t.Run("CLOSE-003: Provider close error is returned", func(t *testing.T) {
+		provider := &closableProvider{InMemoryProvider: data.NewInMemoryProvider(candles), closeErr: errors.New("boom")}
+		market := NewMarketWithProvider(provider)
+		assert.ErrorContains(t, market.Close(), "boom")
+	})
+}
+
+func TestMarket_Logger(t *testing.T) {
+	// One of the three rows has an unparsable open price, so the provider logs a warning when indexing
+	path := filepath.Join(t.TempDir(), "mixed.csv")
+	content := "2024.01.01,09:00,1.1000,1.1010,1.0990,1.1005,1000\n" +
+		"2024.01.01,09:01,abc,1.1010,1.0990,1.1005,1000\n" +
+		"2024.01.01,09:02,1.1005,1.1015,1.0995,1.1010,1000\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	dataConfig := models.DataProviderConfig{FilePath: path, Format: "csv"}
+
+	t.Run("LOG-001: Logger from the market config reaches the provider", func(t *testing.T) {
+		for _, streaming := range []bool{false, true} {
+			var buf bytes.Buffer
+			config := dataConfig
+			config.Streaming = streaming
+			market := NewMarket(models.MarketConfig{DataProvider: config, Logger: slog.New(slog.NewTextHandler(&buf, nil))})
+			assert.NoError(t, market.Initialize(context.Background()))
+			assert.Contains(t, buf.String(), "skipped invalid rows", "streaming=%v", streaming)
+		}
+	})
+
+	t.Run("LOG-002: SetLogger replaces the provider logger", func(t *testing.T) {
+		var configured, replaced bytes.Buffer
+		market := NewMarket(models.MarketConfig{DataProvider: dataConfig, Logger: slog.New(slog.NewTextHandler(&configured, nil))})
+		market.SetLogger(slog.New(slog.NewTextHandler(&replaced, nil)))
+		assert.NoError(t, market.Initialize(context.Background()))
+		assert.Empty(t, configured.String())
+		assert.Contains(t, replaced.String(), "skipped invalid rows")
+	})
+
+	t.Run("LOG-003: Providers without a logger are accepted", func(t *testing.T) {
+		market := NewMarketWithProvider(data.NewInMemoryProvider([]models.Candle{{Close: 1.0}}))
+		market.SetLogger(nil)
+		assert.NoError(t, market.Initialize(context.Background()))
+	})
+}
+
+func TestMarket_Err(t *testing.T) {
+	baseTime := time.Date(2025, 7, 11, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 25)
+	for i := range candles {
+		candles[i] = models.Candle{Timestamp: baseTime.Add(time.Duration(i) * time.Minute), Close: float64(i)}
+	}
+
+	t.Run("ERR-001: Read failure stops the market with an error", func(t *testing.T) {
+		readErr := errors.New("disk error")
+		mockProvider := new(MockDataProvider)
+		mockProvider.On("GetCandlesByIndex", mock.Anything, 0, 9).Return(candles[0:10], nil)
+		mockProvider.On("GetCandlesByIndex", mock.Anything, 10, 19).Return(nil, readErr)
+
+		market := NewMarketWithProviderConfig(mockProvider, models.MarketConfig{CacheSize: 10, RefillThreshold: 3})
+		assert.NoError(t, market.Initialize(context.Background()))
+
+		steps := 0
+		for market.Forward() {
+			steps++
+		}
+		// The refill is attempted when 3 candles remain, so the market stops at index 7
+		assert.Equal(t, 7, steps)
+		assert.True(t, market.IsFinished())
+		assert.ErrorIs(t, market.Err(), readErr)
+
+		// Reset clears the error
+		assert.NoError(t, market.Reset(context.Background()))
+		assert.NoError(t, market.Err())
+	})
+
+	t.Run("ERR-002: End of data is not an error", func(t *testing.T) {
+		market := NewMarketWithProviderConfig(data.NewInMemoryProvider(candles), models.MarketConfig{CacheSize: 10, RefillThreshold: 3})
+		assert.NoError(t, market.Initialize(context.Background()))
+		for market.Forward() {
+		}
+		assert.True(t, market.IsFinished())
+		assert.Equal(t, 24.0, market.GetCurrentPrice())
+		assert.NoError(t, market.Err())
+	})
+}
+
+func TestMarket_Streaming(t *testing.T) {
+	newConfig := func(streaming bool) models.MarketConfig {
+		return models.MarketConfig{
+			DataProvider: models.DataProviderConfig{
+				FilePath:  "../data/testdata/sample.csv",
+				Format:    "csv",
+				Streaming: streaming,
+			},
+			CacheSize:       50,
+			RefillThreshold: 10,
+		}
+	}
+	run := func(market *MarketImpl) []models.Candle {
+		var candles []models.Candle
+		for ok := true; ok; ok = market.Forward() {
+			candles = append(candles, *market.GetCurrentCandle())
+		}
+		return candles
+	}
+
+	t.Run("STREAM-001: Streaming market replays the same candles", func(t *testing.T) {
+		indexed := NewMarket(newConfig(false))
+		assert.NoError(t, indexed.Initialize(context.Background()))
+		expected := run(indexed)
+
+		streaming := NewMarket(newConfig(true))
+		assert.IsType(t, &data.StreamingCSVProvider{}, streaming.provider)
+		assert.NoError(t, streaming.Initialize(context.Background()))
+		assert.Equal(t, expected, run(streaming))
+		assert.NoError(t, streaming.Err())
+		assert.False(t, streaming.provider.(*data.StreamingCSVProvider).Indexed())
+
+		// The total is unknown without reading the whole file
+		bar, total := streaming.Progress()
+		assert.Equal(t, len(expected), bar)
+		assert.Equal(t, 0, total)
+
+		// Reset rewinds the stream
+		assert.NoError(t, streaming.Reset(context.Background()))
+		assert.Equal(t, expected, run(streaming))
+		assert.NoError(t, streaming.Close())
+	})
+}
+
+func TestMarket_Calendar(t *testing.T) {
+	// Hourly candles from Friday 2025-07-11 through Tuesday 2025-07-15, with Monday as a holiday
+	friday := time.Date(2025, 7, 11, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 5*24)
+	for i := range candles {
+		candles[i] = models.Candle{Timestamp: friday.Add(time.Duration(i) * time.Hour), Close: 1.0 + float64(i)*0.0001}
+	}
+	config := models.MarketConfig{
+		CacheSize:       10,
+		RefillThreshold: 3,
+		Calendar:        &models.TradingCalendar{SkipWeekends: true, Holidays: []string{"2025-07-14"}},
+	}
+	run := func(market *MarketImpl) []time.Time {
+		var times []time.Time
+		for ok := true; ok; ok = market.Forward() {
+			times = append(times, market.GetCurrentTime())
+		}
+		return times
+	}
+
+	t.Run("CAL-001: Weekend and holiday candles are skipped", func(t *testing.T) {
+		market := NewMarketWithProviderConfig(data.NewInMemoryProvider(candles), config)
+		assert.NoError(t, market.Initialize(context.Background()))
+		times := run(market)
+
+		assert.Len(t, times, 48)
+		assert.True(t, times[23].Equal(friday.Add(23*time.Hour)))
+		assert.True(t, times[24].Equal(friday.Add(4*24*time.Hour)), "Friday is followed by Tuesday")
+		assert.NoError(t, market.Err())
+
+		bar, total := market.Progress()
+		assert.Equal(t, 120, bar)
+		assert.Equal(t, 120, total)
+	})
+
+	t.Run("CAL-002: First chunk entirely on non-trading days", func(t *testing.T) {
+		market := NewMarketWithProviderConfig(data.NewInMemoryProvider(candles[24:]), config)
+		assert.NoError(t, market.Initialize(context.Background()))
+		assert.True(t, market.GetCurrentTime().Equal(friday.Add(4*24*time.Hour)))
+		assert.Len(t, run(market), 24)
+
+		// Data with no trading candles at all finishes immediately
+		weekend := NewMarketWithProviderConfig(data.NewInMemoryProvider(candles[24:72]), config)
+		assert.NoError(t, weekend.Initialize(context.Background()))
+		assert.True(t, weekend.IsFinished())
+		assert.Nil(t, weekend.GetCurrentCandle())
+	})
+
+	t.Run("CAL-003: State restores across skipped candles", func(t *testing.T) {
+		market := NewMarketWithProviderConfig(data.NewInMemoryProvider(candles), config)
+		assert.NoError(t, market.Initialize(context.Background()))
+		for i := 0; i < 30; i++ {
+			market.Forward()
+		}
+		state := market.State()
+
+		restored := NewMarketWithProviderConfig(data.NewInMemoryProvider(candles), config)
+		assert.NoError(t, restored.Initialize(context.Background()))
+		assert.NoError(t, restored.RestoreState(context.Background(), state))
+		assert.Equal(t, market.GetCurrentCandle(), restored.GetCurrentCandle())
+	})
+}
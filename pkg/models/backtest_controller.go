@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // BacktestState はバックテストの状態を表す
 type BacktestState int
 
@@ -39,10 +41,25 @@ type BacktestControlState struct {
 	State     BacktestState `json:"state"`
 }
 
+// BacktestStatus はHTTPのポーリングなどで参照するバックテストの進捗のスナップショット
+type BacktestStatus struct {
+	State           string    `json:"state"`            // BacktestState の文字列表現
+	CurrentTime     time.Time `json:"current_time"`     // 現在のシミュレーション時刻
+	Bar             int       `json:"bar"`              // 処理済みのローソク足の本数
+	TotalBars       int       `json:"total_bars"`       // 全ローソク足の本数（不明な場合は0）
+	PercentComplete float64   `json:"percent_complete"` // 完了率（%、全本数が不明な場合は0）
+	Balance         float64   `json:"balance"`
+	Equity          float64   `json:"equity"` // 含み損益を含む有効証拠金
+	OpenPositions   int       `json:"open_positions"`
+	UpdatedAt       time.Time `json:"updated_at"` // 最後にスナップショットを更新した実時刻（停滞の検出に使う）
+}
+
 // BacktestController はバックテストの制御を管理するインターフェース
 type BacktestController interface {
 	Play(speed float64) error
 	Pause() error
+	Step(count int) error
+	Reset() error
 	SetSpeed(speed float64) error
 	GetState() BacktestControlState
 	IsRunning() bool
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// HolidayLayout は TradingCalendar.Holidays の日付の形式です。
+const HolidayLayout = "2006-01-02"
+
+// TradingCalendar は取引が行われない期間（週末・休場日）を表します。
+// 日付の判定は UTC で行います。nil の TradingCalendar は全ての時刻を取引時間として扱います。
+type TradingCalendar struct {
+	SkipWeekends bool     `json:"skip_weekends,omitempty"` // 土曜日・日曜日（UTC）を休場とする
+	Holidays     []string `json:"holidays,omitempty"`      // 休場日（"2006-01-02" 形式、UTC）
+}
+
+// Validate は休場日の形式を検証します。
+func (c *TradingCalendar) Validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, holiday := range c.Holidays {
+		if _, err := time.Parse(HolidayLayout, holiday); err != nil {
+			return fmt.Errorf("invalid holiday %q: expected YYYY-MM-DD", holiday)
+		}
+	}
+	return nil
+}
+
+// IsTradingDay は t（UTC）の日付が取引日かを判定します。
+func (c *TradingCalendar) IsTradingDay(t time.Time) bool {
+	if c == nil {
+		return true
+	}
+	t = t.UTC()
+	if c.SkipWeekends && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return false
+	}
+	date := t.Format(HolidayLayout)
+	for _, holiday := range c.Holidays {
+		if holiday == date {
+			return false
+		}
+	}
+	return true
+}
+
+// IsTradingTime は t が取引時間内かを判定します。現在は日単位で判定するため IsTradingDay と同じです。
+func (c *TradingCalendar) IsTradingTime(t time.Time) bool {
+	return c.IsTradingDay(t)
+}
+
+// TradingDuration は start から end までのうち、取引日に含まれる時間を返します。
+// 金曜から月曜に保有した取引では週末の48時間が除かれます。end が start より前の場合は0を返します。
+func (c *TradingCalendar) TradingDuration(start, end time.Time) time.Duration {
+	if !end.After(start) {
+		return 0
+	}
+	if c == nil {
+		return end.Sub(start)
+	}
+
+	var total time.Duration
+	start, end = start.UTC(), end.UTC()
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	for day.Before(end) {
+		next := day.AddDate(0, 0, 1)
+		if c.IsTradingDay(day) {
+			from, to := day, next
+			if start.After(from) {
+				from = start
+			}
+			if end.Before(to) {
+				to = end
+			}
+			total += to.Sub(from)
+		}
+		day = next
+	}
+	return total
+}
+
+// TradingDaysPerYear は年率換算に使う1年あたりの取引日数を返します。
+// 週末を除く場合は 52週 × 5日 = 260日、それ以外は365日とし、平日の休場日がある場合は
+// 休場日を含む年の数で割った1年あたりの休場日数を差し引きます。
+func (c *TradingCalendar) TradingDaysPerYear() float64 {
+	if c == nil {
+		return 365.0
+	}
+
+	days := 365.0
+	if c.SkipWeekends {
+		days = 260.0
+	}
+
+	years := make(map[int]bool)
+	holidays := make(map[string]bool)
+	for _, holiday := range c.Holidays {
+		date, err := time.Parse(HolidayLayout, holiday)
+		if err != nil {
+			continue
+		}
+		if c.SkipWeekends && (date.Weekday() == time.Saturday || date.Weekday() == time.Sunday) {
+			continue
+		}
+		years[date.Year()] = true
+		holidays[holiday] = true
+	}
+	if len(holidays) > 0 {
+		days -= float64(len(holidays)) / float64(len(years))
+	}
+	return days
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTradingCalendar_IsTradingDay(t *testing.T) {
+	calendar := &TradingCalendar{SkipWeekends: true, Holidays: []string{"2024-01-01"}}
+
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected bool
+	}{
+		{"holiday", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), false},
+		{"weekday", time.Date(2024, 1, 5, 23, 59, 0, 0, time.UTC), true},
+		{"saturday", time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC), false},
+		{"sunday", time.Date(2024, 1, 7, 22, 0, 0, 0, time.UTC), false},
+		{"sunday in UTC", time.Date(2024, 1, 8, 7, 0, 0, 0, time.FixedZone("JST", 9*60*60)), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := calendar.IsTradingDay(test.time); got != test.expected {
+				t.Errorf("IsTradingDay(%v) = %v, want %v", test.time, got, test.expected)
+			}
+		})
+	}
+
+	var none *TradingCalendar
+	if !none.IsTradingDay(time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Expected nil calendar to treat every day as a trading day")
+	}
+}
+
+func TestTradingCalendar_TradingDuration(t *testing.T) {
+	friday := time.Date(2024, 1, 5, 20, 0, 0, 0, time.UTC)
+	monday := time.Date(2024, 1, 8, 4, 0, 0, 0, time.UTC)
+
+	t.Run("should exclude weekend", func(t *testing.T) {
+		calendar := &TradingCalendar{SkipWeekends: true}
+		if got := calendar.TradingDuration(friday, monday); got != 8*time.Hour {
+			t.Errorf("Expected 8h, got %v", got)
+		}
+	})
+
+	t.Run("should exclude holidays", func(t *testing.T) {
+		calendar := &TradingCalendar{SkipWeekends: true, Holidays: []string{"2024-01-08"}}
+		if got := calendar.TradingDuration(friday, monday); got != 4*time.Hour {
+			t.Errorf("Expected 4h, got %v", got)
+		}
+	})
+
+	t.Run("should return elapsed time without calendar", func(t *testing.T) {
+		var calendar *TradingCalendar
+		if got := calendar.TradingDuration(friday, monday); got != 56*time.Hour {
+			t.Errorf("Expected 56h, got %v", got)
+		}
+	})
+
+	t.Run("should return zero for reversed range", func(t *testing.T) {
+		calendar := &TradingCalendar{SkipWeekends: true}
+		if got := calendar.TradingDuration(monday, friday); got != 0 {
+			t.Errorf("Expected 0, got %v", got)
+		}
+	})
+}
+
+func TestTradingCalendar_TradingDaysPerYear(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar *TradingCalendar
+		expected float64
+	}{
+		{"nil calendar", nil, 365},
+		{"weekends", &TradingCalendar{SkipWeekends: true}, 260},
+		// 土曜日の休場日は週末として既に除かれている
+		{"weekday holidays", &TradingCalendar{SkipWeekends: true, Holidays: []string{"2024-01-01", "2024-12-25", "2024-01-06"}}, 258},
+		{"holidays over two years", &TradingCalendar{Holidays: []string{"2024-01-01", "2025-01-01"}}, 364},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.calendar.TradingDaysPerYear(); got != test.expected {
+				t.Errorf("TradingDaysPerYear() = %v, want %v", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestTradingCalendar_Validate(t *testing.T) {
+	var none *TradingCalendar
+	if err := none.Validate(); err != nil {
+		t.Errorf("Expected nil calendar to be valid, got %v", err)
+	}
+
+	valid := &TradingCalendar{SkipWeekends: true, Holidays: []string{"2024-12-25"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid calendar, got %v", err)
+	}
+
+	invalid := &TradingCalendar{Holidays: []string{"2024/12/25"}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected error for invalid holiday format")
+	}
+}
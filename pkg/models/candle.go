@@ -3,6 +3,7 @@ package models
 import (
 	"errors"
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -29,17 +30,31 @@ func NewCandle(timestamp time.Time, open, high, low, close, volume float64) *Can
 }
 
 // Validate はローソク足データの妥当性を検証します。
+// 時刻が設定されていること、価格が正であること、高値・安値が始値・終値を含む範囲であること、
+// 出来高が負でないことを確認し、違反した値を含むエラーを返します。
 func (c *Candle) Validate() error {
-	if c.High < c.Low {
-		return errors.New("high price must be greater than or equal to low price")
+	if c.Timestamp.IsZero() {
+		return errors.New("timestamp must be set")
 	}
 	
 	if c.Open <= 0 || c.High <= 0 || c.Low <= 0 || c.Close <= 0 {
-		return errors.New("prices must be positive")
+		return fmt.Errorf("prices must be positive: open=%g high=%g low=%g close=%g", c.Open, c.High, c.Low, c.Close)
+	}
+	
+	if c.High < c.Low {
+		return fmt.Errorf("high %g must be greater than or equal to low %g", c.High, c.Low)
+	}
+	
+	if c.High < math.Max(c.Open, c.Close) {
+		return fmt.Errorf("high %g must be greater than or equal to open %g and close %g", c.High, c.Open, c.Close)
+	}
+	
+	if c.Low > math.Min(c.Open, c.Close) {
+		return fmt.Errorf("low %g must be less than or equal to open %g and close %g", c.Low, c.Open, c.Close)
 	}
 	
 	if c.Volume < 0 {
-		return errors.New("volume must be non-negative")
+		return fmt.Errorf("volume %g must be non-negative", c.Volume)
 	}
 	
 	return nil
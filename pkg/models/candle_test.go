@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -61,6 +62,34 @@ func TestCandle_Validate(t *testing.T) {
 	if err := candle.Validate(); err == nil {
 		t.Error("Expected error for negative volume")
 	}
+	
+	// 異常なケース - 高値が始値・終値より低い
+	candle = NewCandle(timestamp, 1.1000, 1.1010, 1.0990, 1.1020, 1000.0)
+	if err := candle.Validate(); err == nil || !strings.Contains(err.Error(), "high 1.101") {
+		t.Errorf("Expected error when close is above high, got %v", err)
+	}
+	candle = NewCandle(timestamp, 1.1020, 1.1010, 1.0990, 1.1005, 1000.0)
+	if err := candle.Validate(); err == nil {
+		t.Error("Expected error when open is above high")
+	}
+	
+	// 異常なケース - 安値が始値・終値より高い
+	candle = NewCandle(timestamp, 1.0980, 1.1010, 1.0990, 1.1005, 1000.0)
+	if err := candle.Validate(); err == nil || !strings.Contains(err.Error(), "low 1.099") {
+		t.Errorf("Expected error when open is below low, got %v", err)
+	}
+	
+	// 異常なケース - 時刻が未設定
+	candle = NewCandle(time.Time{}, 1.1000, 1.1010, 1.0990, 1.1005, 1000.0)
+	if err := candle.Validate(); err == nil {
+		t.Error("Expected error for zero timestamp")
+	}
+	
+	// 境界値 - 四本値が全て同じ
+	candle = NewCandle(timestamp, 1.1000, 1.1000, 1.1000, 1.1000, 0)
+	if err := candle.Validate(); err != nil {
+		t.Errorf("Expected no error for flat candle, got %v", err)
+	}
 }
 
 func TestCandle_IsValidOHLC(t *testing.T) {
@@ -2,31 +2,295 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"strings"
+	"time"
 )
 
 // Config はバックテスト全体の設定を管理します。
 type Config struct {
-	Market MarketConfig `json:"market"`
-	Broker BrokerConfig `json:"broker"`
+	Market  MarketConfig          `json:"market"`
+	Broker  BrokerConfig          `json:"broker"`
+	Symbols map[string]SymbolSpec `json:"symbols,omitempty"` // シンボルごとのメタデータ（未指定のシンボルはpipサイズ・契約サイズを考慮しない）
+}
+
+// SymbolSpec は銘柄ごとの価格・数量の単位です。
+// ブローカーの損益・証拠金・pip指定のスプレッドの計算と、pip単位の統計に使われます。
+type SymbolSpec struct {
+	PipSize       float64 `json:"pip_size"`                 // 1pipの価格幅（EURUSD は 0.0001、USDJPY は 0.01）
+	ContractSize  float64 `json:"contract_size"`            // 数量1（1ロット）あたりの通貨量
+	QuoteCurrency string  `json:"quote_currency,omitempty"` // 損益が表される決済通貨
+}
+
+// Validate はSymbolSpecの妥当性を検証します。
+func (s SymbolSpec) Validate() error {
+	if s.PipSize <= 0 {
+		return errors.New("pip size must be positive")
+	}
+	if s.ContractSize <= 0 {
+		return errors.New("contract size must be positive")
+	}
+	return nil
+}
+
+// ValidateSymbols はシンボルごとのメタデータの妥当性を検証します。
+func ValidateSymbols(symbols map[string]SymbolSpec) error {
+	for symbol, spec := range symbols {
+		if strings.TrimSpace(symbol) == "" {
+			return errors.New("symbol spec requires a symbol")
+		}
+		if err := spec.Validate(); err != nil {
+			return fmt.Errorf("invalid symbol spec %s: %w", symbol, err)
+		}
+	}
+	return nil
 }
 
 // MarketConfig は市場データに関する設定です。
 type MarketConfig struct {
 	DataProvider DataProviderConfig `json:"data_provider"`
 	Symbol       string             `json:"symbol"`
+
+	// ローソク足キャッシュの本数と、未処理の残りがこの本数以下になったら次を読み込む閾値（0の場合は既定値）
+	CacheSize       int `json:"cache_size,omitempty"`
+	RefillThreshold int `json:"refill_threshold,omitempty"`
+
+	// 取引が行われない期間のローソク足を読み飛ばすための取引カレンダー（nil の場合は全て読み込む）
+	Calendar *TradingCalendar `json:"calendar,omitempty"`
+
+	// データ提供元の診断ログの出力先（nil の場合はデータ提供元の既定。CSVProvider などは slog のデフォルトロガーに出力する）
+	Logger *slog.Logger `json:"-"`
+}
+
+// キャッシュ設定の既定値
+const (
+	DefaultCacheSize       = 500
+	DefaultRefillThreshold = 100
+)
+
+// CacheSettings は0を既定値に置き換えたキャッシュの本数と補充の閾値を返します。
+// 閾値が未指定でキャッシュの本数のみ指定された場合は、既定値とキャッシュの本数の1/5のうち小さい方（最低1）を使います。
+func (mc MarketConfig) CacheSettings() (cacheSize, refillThreshold int) {
+	cacheSize = mc.CacheSize
+	if cacheSize == 0 {
+		cacheSize = DefaultCacheSize
+	}
+	
+	refillThreshold = mc.RefillThreshold
+	if refillThreshold == 0 {
+		refillThreshold = DefaultRefillThreshold
+		if cacheSize/5 < refillThreshold {
+			refillThreshold = cacheSize / 5
+		}
+		if refillThreshold < 1 {
+			refillThreshold = 1
+		}
+	}
+	return cacheSize, refillThreshold
+}
+
+// ValidateCache はキャッシュの本数と補充の閾値の妥当性を検証します。
+func (mc *MarketConfig) ValidateCache() error {
+	if mc.CacheSize < 0 {
+		return errors.New("cache size must be non-negative")
+	}
+	if mc.RefillThreshold < 0 {
+		return errors.New("refill threshold must be non-negative")
+	}
+	
+	cacheSize, refillThreshold := mc.CacheSettings()
+	if refillThreshold >= cacheSize {
+		return fmt.Errorf("refill threshold (%d) must be less than cache size (%d)", refillThreshold, cacheSize)
+	}
+	
+	return nil
 }
 
 // DataProviderConfig はデータソースに関する設定です。
 type DataProviderConfig struct {
 	FilePath string `json:"file_path"`
 	Format   string `json:"format"`
+
+	// 読み込む行数の上限（0の場合は無制限）
+	MaxRows       int           `json:"max_rows,omitempty"`
+	MaxRowsPolicy MaxRowsPolicy `json:"max_rows_policy,omitempty"`
+
+	// 解析・検証に失敗した行がある場合、読み飛ばさずにエラーとする
+	StrictParsing bool `json:"strict_parsing,omitempty"`
+
+	// 同じ時刻のローソク足が複数ある場合の扱い（空の場合は keep-first）
+	DuplicatePolicy DuplicatePolicy `json:"duplicate_policy,omitempty"`
+
+	// 0より大きい場合、この間隔より空いた区間を直前の終値の横ばいのローソク足（出来高0）で補完する
+	FillInterval time.Duration `json:"fill_interval,omitempty"`
+
+	// ファイル全体のインデックスを事前に構築せず、先頭から順に読み込む（時刻順に並んだ大きなファイル向け）
+	Streaming bool `json:"streaming,omitempty"`
 }
 
+// MaxRowsPolicy は行数の上限を超えた場合の扱いを表します。
+type MaxRowsPolicy string
+
+const (
+	MaxRowsTruncate MaxRowsPolicy = "truncate" // 上限で読み込みを打ち切り警告を出す
+	MaxRowsError    MaxRowsPolicy = "error"    // エラーとして扱う
+)
+
+// DuplicatePolicy は同じ時刻のローソク足が複数ある場合の扱いを表します。
+type DuplicatePolicy string
+
+const (
+	DuplicateKeepFirst DuplicatePolicy = "keep-first" // ファイル内で最初に現れた行を残す
+	DuplicateKeepLast  DuplicatePolicy = "keep-last"  // ファイル内で最後に現れた行を残す
+	DuplicateError     DuplicatePolicy = "error"      // エラーとして扱う
+)
+
 // BrokerConfig はブローカーに関する設定です。
 type BrokerConfig struct {
 	InitialBalance float64 `json:"initial_balance"`
 	Spread         float64 `json:"spread"`
+	SpreadPips     float64 `json:"spread_pips,omitempty"` // 銘柄レジストリのpipサイズで換算するスプレッド
+
+	// 約定価格を不利な方向にずらすスリッページ（価格単位）。random の場合は約定ごとに0～Slippageの一様乱数
+	Slippage     float64      `json:"slippage,omitempty"`
+	SlippageMode SlippageMode `json:"slippage_mode,omitempty"`
+
+	// 流動性の低いローソク足の扱い（MinVolumeToTradeが0の場合は無効）
+	MinVolumeToTrade         float64        `json:"min_volume_to_trade,omitempty"`
+	IlliquidPolicy           IlliquidPolicy `json:"illiquid_policy,omitempty"`
+	IlliquidSpreadMultiplier float64        `json:"illiquid_spread_multiplier,omitempty"` // widen時のスプレッド倍率（0の場合は2倍）
+
+	// PaperModeが有効な場合、注文は理論約定価格とともに記録されるだけで残高・ポジションに影響しない
+	PaperMode bool `json:"paper_mode,omitempty"`
+
+	// 注文数量の最小・最大・刻み（0の場合は制約なし）。制約を満たさない数量の扱いは LotPolicy で指定する
+	MinLot    float64   `json:"min_lot,omitempty"`
+	MaxLot    float64   `json:"max_lot,omitempty"`
+	LotStep   float64   `json:"lot_step,omitempty"`
+	LotPolicy LotPolicy `json:"lot_policy,omitempty"`
+
+	// 同時に保有できるポジション数と、建玉の合計金額（数量 × 契約サイズ × 価格）の上限（0の場合は無制限）
+	MaxOpenPositions int     `json:"max_open_positions,omitempty"`
+	MaxExposure      float64 `json:"max_exposure,omitempty"`
+
+	// 必要証拠金の計算に使うレバレッジ（証拠金 = 建玉金額 ÷ Leverage、0の場合は DefaultLeverage）
+	Leverage float64 `json:"leverage,omitempty"`
+
+	// 同じシンボルの注文をポジションごとに分けるか（hedging、既定）、1つのポジションに合算するか（netting）
+	AccountMode AccountMode `json:"account_mode,omitempty"`
+
+	// 指値・逆指値注文の約定判定を終値で行うか（close、既定）、ローソク足の高値・安値で行うか（touch・cross）
+	FillModel FillModel `json:"fill_model,omitempty"`
+
+	// ストップロス・テイクプロフィットの判定を終値で行うか（close、既定）、ローソク足の高値・安値で行うか（intrabar）
+	ProtectionModel ProtectionModel `json:"protection_model,omitempty"`
+
+	// 口座通貨（"JPY" など）。設定した場合、損益・証拠金・建玉金額・残高は決済通貨から口座通貨に換算する（空の場合は換算しない）
+	AccountCurrency string `json:"account_currency,omitempty"`
+	// 口座通貨への固定の換算レート（"USDJPY": 150 など、通貨ペアをキーとする。逆向きのペアは逆数で使う）
+	ConversionRates map[string]float64 `json:"conversion_rates,omitempty"`
+}
+
+// DefaultLeverage は BrokerConfig.Leverage を指定しない場合のレバレッジです。
+const DefaultLeverage = 100.0
+
+// EffectiveLeverage は証拠金の計算に使うレバレッジを返します。Leverage が0の場合は DefaultLeverage です。
+func (bc BrokerConfig) EffectiveLeverage() float64 {
+	if bc.Leverage == 0 {
+		return DefaultLeverage
+	}
+	return bc.Leverage
+}
+
+// IlliquidPolicy は出来高が閾値未満のローソク足での注文の扱いを表します。
+type IlliquidPolicy string
+
+const (
+	IlliquidReject IlliquidPolicy = "reject" // 成行注文を拒否し、保留注文の約定を見送る
+	IlliquidWiden  IlliquidPolicy = "widen"  // スプレッドを拡大して約定させる
+)
+
+// SlippageMode はスリッページの決め方を表します。
+type SlippageMode string
+
+const (
+	SlippageFixed  SlippageMode = "fixed"  // 常に Slippage だけずらす（既定）
+	SlippageRandom SlippageMode = "random" // 約定ごとに0～Slippageの一様乱数だけずらす（乱数はシードから再現できる）
+)
+
+// LotPolicy は MinLot・MaxLot・LotStep を満たさない注文数量の扱いを表します。
+type LotPolicy string
+
+const (
+	LotReject LotPolicy = "reject" // 注文を拒否する（既定）
+	LotRound  LotPolicy = "round"  // LotStep の倍数に切り捨て、MaxLot を超える数量は MaxLot に抑える
+)
+
+// AccountMode は同じシンボルの注文によるポジションの扱いを表します。
+type AccountMode string
+
+const (
+	AccountHedging AccountMode = "hedging" // 注文ごとに独立したポジションを建てる（既定）
+	AccountNetting AccountMode = "netting" // シンボルごとに1つのポジションに合算し、反対方向の注文で減らす・反転させる
+)
+
+// FillModel は指値・逆指値注文の約定判定と約定価格の決め方を表します。
+type FillModel string
+
+const (
+	FillOnClose FillModel = "close" // 終値が注文価格に達したら終値で約定する（既定）
+	FillOnTouch FillModel = "touch" // 高値・安値が注文価格に触れたら注文価格で約定する（始値が注文価格を越えていれば始値）
+	FillOnCross FillModel = "cross" // 高値・安値が注文価格を越えたら約定する。触れただけでは約定しない。価格は touch と同じ
+)
+
+// ProtectionModel はストップロス・テイクプロフィットの判定と決済価格の決め方を表します。
+type ProtectionModel string
+
+const (
+	ProtectionOnClose  ProtectionModel = "close"    // 終値が保護価格に達したら終値で決済する（既定）
+	ProtectionIntrabar ProtectionModel = "intrabar" // 高値・安値が保護価格に触れたら保護価格で決済する。両方に触れた場合はストップロスを優先する
+)
+
+// ErrInvalidLotSize は注文数量が MinLot・MaxLot・LotStep の制約を満たさないことを表すエラーです。
+var ErrInvalidLotSize = errors.New("invalid lot size")
+
+// lotTolerance は数量が LotStep の倍数かを判定する際の許容誤差（刻み幅に対する比率）です。
+const lotTolerance = 1e-9
+
+// NormalizeSize は MinLot・MaxLot・LotStep に従って注文数量を検証し、約定させる数量を返します。
+// LotPolicy が round の場合は MaxLot を超える数量を MaxLot に抑えてから LotStep の倍数に切り捨てます
+// （リスクを増やさないよう切り上げはしない）。丸めた結果が MinLot 未満または0になる場合や、
+// reject の場合に制約を満たさない数量はエラー（ErrInvalidLotSize）を返します。
+func (bc BrokerConfig) NormalizeSize(size float64) (float64, error) {
+	round := bc.LotPolicy == LotRound
+	
+	if bc.MaxLot > 0 && size > bc.MaxLot {
+		if !round {
+			return 0, fmt.Errorf("%w: size %g exceeds max lot %g", ErrInvalidLotSize, size, bc.MaxLot)
+		}
+		size = bc.MaxLot
+	}
+	
+	if bc.LotStep > 0 {
+		steps := size / bc.LotStep
+		nearest := math.Round(steps)
+		switch {
+		case math.Abs(steps-nearest) <= lotTolerance*math.Max(1, nearest):
+			// 浮動小数点の誤差を除いて刻みに揃える
+			size = nearest * bc.LotStep
+		case round:
+			size = math.Floor(steps) * bc.LotStep
+		default:
+			return 0, fmt.Errorf("%w: size %g is not a multiple of lot step %g", ErrInvalidLotSize, size, bc.LotStep)
+		}
+	}
+	
+	if size <= 0 || (bc.MinLot > 0 && size < bc.MinLot) {
+		return 0, fmt.Errorf("%w: size %g is below min lot %g", ErrInvalidLotSize, size, bc.MinLot)
+	}
+	return size, nil
 }
 
 // NewDefaultConfig はデフォルト設定を生成します。
@@ -55,7 +319,7 @@ func (c *Config) Validate() error {
 		return err
 	}
 	
-	return nil
+	return ValidateSymbols(c.Symbols)
 }
 
 // Validate はMarketConfigの妥当性を検証します。
@@ -68,7 +332,11 @@ func (mc *MarketConfig) Validate() error {
 		return errors.New("symbol is required")
 	}
 	
-	return nil
+	if err := mc.Calendar.Validate(); err != nil {
+		return err
+	}
+	
+	return mc.ValidateCache()
 }
 
 // Validate はDataProviderConfigの妥当性を検証します。
@@ -81,6 +349,26 @@ func (dpc *DataProviderConfig) Validate() error {
 		return errors.New("format must be 'csv' or 'json'")
 	}
 	
+	if dpc.MaxRows < 0 {
+		return errors.New("max rows must be non-negative")
+	}
+	
+	switch dpc.MaxRowsPolicy {
+	case "", MaxRowsTruncate, MaxRowsError:
+	default:
+		return fmt.Errorf("invalid max rows policy: %s", dpc.MaxRowsPolicy)
+	}
+	
+	switch dpc.DuplicatePolicy {
+	case "", DuplicateKeepFirst, DuplicateKeepLast, DuplicateError:
+	default:
+		return fmt.Errorf("invalid duplicate policy: %s", dpc.DuplicatePolicy)
+	}
+	
+	if dpc.FillInterval < 0 {
+		return errors.New("fill interval must be non-negative")
+	}
+	
 	return nil
 }
 
@@ -94,5 +382,164 @@ func (bc *BrokerConfig) Validate() error {
 		return errors.New("spread must be non-negative")
 	}
 	
+	if bc.SpreadPips < 0 {
+		return errors.New("spread pips must be non-negative")
+	}
+	
+	if bc.MinVolumeToTrade < 0 {
+		return errors.New("min volume to trade must be non-negative")
+	}
+	
+	if err := bc.ValidateSlippage(); err != nil {
+		return err
+	}
+	
+	switch bc.IlliquidPolicy {
+	case "", IlliquidReject, IlliquidWiden:
+	default:
+		return fmt.Errorf("invalid illiquid policy: %s", bc.IlliquidPolicy)
+	}
+	
+	if bc.IlliquidSpreadMultiplier != 0 && bc.IlliquidSpreadMultiplier < 1 {
+		return errors.New("illiquid spread multiplier must be at least 1")
+	}
+	
+	if err := bc.ValidateLots(); err != nil {
+		return err
+	}
+	
+	if err := bc.ValidateLimits(); err != nil {
+		return err
+	}
+	
+	if err := bc.ValidateLeverage(); err != nil {
+		return err
+	}
+	
+	if err := bc.ValidateFillModel(); err != nil {
+		return err
+	}
+	
+	if err := bc.ValidateProtectionModel(); err != nil {
+		return err
+	}
+	
+	if err := bc.ValidateCurrency(); err != nil {
+		return err
+	}
+	
+	return bc.ValidateAccountMode()
+}
+
+// ValidateCurrency は口座通貨（AccountCurrency）と換算レート（ConversionRates）の妥当性を検証します。
+func (bc *BrokerConfig) ValidateCurrency() error {
+	if bc.AccountCurrency != "" && !isCurrencyCode(bc.AccountCurrency) {
+		return fmt.Errorf("invalid account currency: %s", bc.AccountCurrency)
+	}
+	for pair, rate := range bc.ConversionRates {
+		if len(pair) != 6 || !isCurrencyCode(pair[:3]) || !isCurrencyCode(pair[3:]) {
+			return fmt.Errorf("invalid conversion rate pair: %s", pair)
+		}
+		if rate <= 0 {
+			return fmt.Errorf("conversion rate for %s must be positive", pair)
+		}
+	}
+	return nil
+}
+
+// isCurrencyCode は code が3文字のアルファベットの通貨コードかを判定します。
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateProtectionModel はストップロス・テイクプロフィットの判定方法（ProtectionModel）の妥当性を検証します。
+func (bc *BrokerConfig) ValidateProtectionModel() error {
+	switch bc.ProtectionModel {
+	case "", ProtectionOnClose, ProtectionIntrabar:
+		return nil
+	default:
+		return fmt.Errorf("invalid protection model: %s", bc.ProtectionModel)
+	}
+}
+
+// ValidateFillModel は約定判定の方法（FillModel）の妥当性を検証します。
+func (bc *BrokerConfig) ValidateFillModel() error {
+	switch bc.FillModel {
+	case "", FillOnClose, FillOnTouch, FillOnCross:
+		return nil
+	default:
+		return fmt.Errorf("invalid fill model: %s", bc.FillModel)
+	}
+}
+
+// ValidateAccountMode は口座の種類（AccountMode）の妥当性を検証します。
+func (bc *BrokerConfig) ValidateAccountMode() error {
+	switch bc.AccountMode {
+	case "", AccountHedging, AccountNetting:
+		return nil
+	default:
+		return fmt.Errorf("invalid account mode: %s", bc.AccountMode)
+	}
+}
+
+// ValidateSlippage はスリッページ（Slippage・SlippageMode）の妥当性を検証します。
+func (bc *BrokerConfig) ValidateSlippage() error {
+	if bc.Slippage < 0 {
+		return errors.New("slippage must be non-negative")
+	}
+	
+	switch bc.SlippageMode {
+	case "", SlippageFixed, SlippageRandom:
+		return nil
+	default:
+		return fmt.Errorf("invalid slippage mode: %s", bc.SlippageMode)
+	}
+}
+
+// ValidateLimits はポジション数・建玉金額の上限（MaxOpenPositions・MaxExposure）の妥当性を検証します。
+func (bc *BrokerConfig) ValidateLimits() error {
+	if bc.MaxOpenPositions < 0 {
+		return errors.New("max open positions must be non-negative")
+	}
+	
+	if bc.MaxExposure < 0 {
+		return errors.New("max exposure must be non-negative")
+	}
+	
+	return nil
+}
+
+// ValidateLeverage はレバレッジ（Leverage）の妥当性を検証します。0は DefaultLeverage を表します。
+func (bc *BrokerConfig) ValidateLeverage() error {
+	if bc.Leverage < 0 || math.IsNaN(bc.Leverage) || math.IsInf(bc.Leverage, 0) {
+		return fmt.Errorf("leverage must be a positive finite number: %v", bc.Leverage)
+	}
+	return nil
+}
+
+// ValidateLots は注文数量の制約（MinLot・MaxLot・LotStep・LotPolicy）の妥当性を検証します。
+func (bc *BrokerConfig) ValidateLots() error {
+	if bc.MinLot < 0 || bc.MaxLot < 0 || bc.LotStep < 0 {
+		return errors.New("min lot, max lot and lot step must be non-negative")
+	}
+	
+	if bc.MaxLot > 0 && bc.MinLot > bc.MaxLot {
+		return errors.New("min lot must not exceed max lot")
+	}
+	
+	switch bc.LotPolicy {
+	case "", LotReject, LotRound:
+	default:
+		return fmt.Errorf("invalid lot policy: %s", bc.LotPolicy)
+	}
+	
 	return nil
 }
\ No newline at end of file
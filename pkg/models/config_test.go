@@ -1,6 +1,11 @@
 package models
 
-import "testing"
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
 
 // Config構造体のテスト
 func TestConfig_NewDefaultConfig(t *testing.T) {
@@ -45,6 +50,32 @@ func TestConfig_Validate(t *testing.T) {
 	if err := config.Validate(); err == nil {
 		t.Error("Expected error for negative initial balance")
 	}
+	
+	// シンボルのメタデータ
+	config = NewDefaultConfig()
+	config.Market.DataProvider.FilePath = "./testdata/sample.csv"
+	config.Symbols = map[string]SymbolSpec{
+		"EURUSD": {PipSize: 0.0001, ContractSize: 100000, QuoteCurrency: "USD"},
+		"USDJPY": {PipSize: 0.01, ContractSize: 100000, QuoteCurrency: "JPY"},
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no error for valid symbol specs, got %v", err)
+	}
+	
+	config.Symbols["USDJPY"] = SymbolSpec{PipSize: 0.01}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for zero contract size")
+	}
+	
+	config.Symbols = map[string]SymbolSpec{"GBPUSD": {PipSize: -0.0001, ContractSize: 100000}}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative pip size")
+	}
+	
+	config.Symbols = map[string]SymbolSpec{" ": {PipSize: 0.0001, ContractSize: 100000}}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for empty symbol")
+	}
 }
 
 func TestMarketConfig_Validate(t *testing.T) {
@@ -66,6 +97,66 @@ func TestMarketConfig_Validate(t *testing.T) {
 	if err := config.Validate(); err == nil {
 		t.Error("Expected error for empty symbol")
 	}
+	
+	// キャッシュ設定
+	config.Symbol = "EURUSD"
+	config.CacheSize = 50
+	config.RefillThreshold = 10
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no error for valid cache settings, got %v", err)
+	}
+	config.RefillThreshold = 50
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error when refill threshold is not less than cache size")
+	}
+	config.RefillThreshold = -1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative refill threshold")
+	}
+	config.CacheSize = -1
+	config.RefillThreshold = 0
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative cache size")
+	}
+	config.CacheSize = 1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for cache size too small to refill")
+	}
+	
+	// 取引カレンダー
+	config.CacheSize = 0
+	config.Calendar = &TradingCalendar{SkipWeekends: true, Holidays: []string{"2024-12-25"}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no error for valid calendar, got %v", err)
+	}
+	config.Calendar.Holidays = []string{"12/25/2024"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for invalid holiday")
+	}
+}
+
+func TestMarketConfig_CacheSettings(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          MarketConfig
+		cacheSize       int
+		refillThreshold int
+	}{
+		{"defaults", MarketConfig{}, DefaultCacheSize, DefaultRefillThreshold},
+		{"explicit", MarketConfig{CacheSize: 1000, RefillThreshold: 300}, 1000, 300},
+		{"threshold scales with small cache", MarketConfig{CacheSize: 50}, 50, 10},
+		{"threshold at least one", MarketConfig{CacheSize: 3}, 3, 1},
+		{"large cache keeps default threshold", MarketConfig{CacheSize: 5000}, 5000, DefaultRefillThreshold},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheSize, refillThreshold := tt.config.CacheSettings()
+			if cacheSize != tt.cacheSize || refillThreshold != tt.refillThreshold {
+				t.Errorf("CacheSettings() = (%d, %d), want (%d, %d)", cacheSize, refillThreshold, tt.cacheSize, tt.refillThreshold)
+			}
+		})
+	}
 }
 
 func TestDataProviderConfig_Validate(t *testing.T) {
@@ -91,6 +182,26 @@ func TestDataProviderConfig_Validate(t *testing.T) {
 	if err := config.Validate(); err == nil {
 		t.Error("Expected error for invalid format")
 	}
+	
+	// 重複時刻の扱い
+	config.Format = "csv"
+	for _, policy := range []DuplicatePolicy{"", DuplicateKeepFirst, DuplicateKeepLast, DuplicateError} {
+		config.DuplicatePolicy = policy
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no error for duplicate policy %q, got %v", policy, err)
+		}
+	}
+	config.DuplicatePolicy = "merge"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for invalid duplicate policy")
+	}
+	
+	// 異常なケース - 負の補完間隔
+	config.DuplicatePolicy = ""
+	config.FillInterval = -time.Minute
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative fill interval")
+	}
 }
 
 func TestBrokerConfig_Validate(t *testing.T) {
@@ -116,4 +227,179 @@ func TestBrokerConfig_Validate(t *testing.T) {
 	if err := config.Validate(); err == nil {
 		t.Error("Expected error for negative spread")
 	}
+	
+	// 数量の制約
+	config.Spread = 0.0001
+	config.MinLot, config.MaxLot, config.LotStep, config.LotPolicy = 0.01, 100, 0.01, LotRound
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no error for valid lot settings, got %v", err)
+	}
+	config.MinLot = 200
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for min lot above max lot")
+	}
+	config.MinLot, config.LotStep = 0.01, -0.01
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative lot step")
+	}
+	config.LotStep, config.LotPolicy = 0.01, "truncate"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for invalid lot policy")
+	}
+	
+	// ポジション数・建玉金額の上限
+	config.LotPolicy = LotRound
+	config.MaxOpenPositions, config.MaxExposure = 5, 100000.0
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no error for valid position limits, got %v", err)
+	}
+	config.MaxOpenPositions = -1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative max open positions")
+	}
+	config.MaxOpenPositions, config.MaxExposure = 5, -1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative max exposure")
+	}
+	
+	// レバレッジ（0は既定の100倍）
+	config.MaxExposure = 0
+	for _, leverage := range []float64{0, 1, 25, 500} {
+		config.Leverage = leverage
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no error for leverage %v, got %v", leverage, err)
+		}
+	}
+	for _, leverage := range []float64{-1, math.Inf(1), math.NaN()} {
+		config.Leverage = leverage
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected error for leverage %v", leverage)
+		}
+	}
+	config.Leverage = 0
+	if got := config.EffectiveLeverage(); got != DefaultLeverage {
+		t.Errorf("Expected default leverage %v, got %v", DefaultLeverage, got)
+	}
+	config.Leverage = 25
+	if got := config.EffectiveLeverage(); got != 25 {
+		t.Errorf("Expected leverage 25, got %v", got)
+	}
+	
+	// 口座の種類
+	config.Leverage = 0
+	for _, mode := range []AccountMode{"", AccountHedging, AccountNetting} {
+		config.AccountMode = mode
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no error for account mode %q, got %v", mode, err)
+		}
+	}
+	config.AccountMode = "mixed"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for invalid account mode")
+	}
+	
+	// スリッページ
+	config.AccountMode = ""
+	for _, mode := range []SlippageMode{"", SlippageFixed, SlippageRandom} {
+		config.Slippage, config.SlippageMode = 0.0002, mode
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no error for slippage mode %q, got %v", mode, err)
+		}
+	}
+	config.SlippageMode = "gaussian"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for invalid slippage mode")
+	}
+	config.Slippage, config.SlippageMode = -0.0001, SlippageFixed
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative slippage")
+	}
+	
+	// 約定判定
+	config.Slippage = 0
+	for _, model := range []FillModel{"", FillOnClose, FillOnTouch, FillOnCross} {
+		config.FillModel = model
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no error for fill model %q, got %v", model, err)
+		}
+	}
+	config.FillModel = "mid"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for invalid fill model")
+	}
+	
+	// 保護価格の判定
+	config.FillModel = ""
+	for _, model := range []ProtectionModel{"", ProtectionOnClose, ProtectionIntrabar} {
+		config.ProtectionModel = model
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no error for protection model %q, got %v", model, err)
+		}
+	}
+	config.ProtectionModel = "tick"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for invalid protection model")
+	}
+	
+	// 口座通貨と換算レート
+	config.ProtectionModel = ""
+	config.AccountCurrency, config.ConversionRates = "JPY", map[string]float64{"USDJPY": 150.0, "eurjpy": 160.0}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no error for account currency, got %v", err)
+	}
+	for _, currency := range []string{"JP", "YEN!", "1PY"} {
+		config.AccountCurrency = currency
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected error for account currency %q", currency)
+		}
+	}
+	config.AccountCurrency = "JPY"
+	for _, rates := range []map[string]float64{{"USD/JPY": 150.0}, {"USDJPY": 0}, {"USDJPY": -150.0}} {
+		config.ConversionRates = rates
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected error for conversion rates %v", rates)
+		}
+	}
+}
+
+func TestBrokerConfig_NormalizeSize(t *testing.T) {
+	reject := BrokerConfig{MinLot: 0.01, MaxLot: 100, LotStep: 0.01}
+	round := reject
+	round.LotPolicy = LotRound
+	
+	tests := []struct {
+		name     string
+		config   BrokerConfig
+		size     float64
+		expected float64
+		hasError bool
+	}{
+		{"no constraints", BrokerConfig{}, 0.123456, 0.123456, false},
+		{"on step", reject, 0.05, 0.05, false},
+		{"float error within step", reject, 0.1 + 0.2, 0.3, false},
+		{"reject off step", reject, 0.015, 0, true},
+		{"reject below min", reject, 0.001, 0, true},
+		{"reject above max", reject, 150, 0, true},
+		{"round down to step", round, 0.019, 0.01, false},
+		{"round clamps to max", round, 150, 100, false},
+		{"round below min", round, 0.009, 0, true},
+		{"round to zero without min", BrokerConfig{LotStep: 1, LotPolicy: LotRound}, 0.5, 0, true},
+	}
+	
+	for _, test := range tests {
+		size, err := test.config.NormalizeSize(test.size)
+		if test.hasError {
+			if !errors.Is(err, ErrInvalidLotSize) {
+				t.Errorf("%s: expected ErrInvalidLotSize, got %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", test.name, err)
+			continue
+		}
+		if math.Abs(size-test.expected) > 1e-12 {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, size)
+		}
+	}
 }
\ No newline at end of file
@@ -1,7 +1,9 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -49,6 +51,38 @@ func (os OrderSide) String() string {
 	}
 }
 
+// MarshalJSON はOrderSideを "Buy" / "Sell" の文字列としてJSONに変換します。
+func (os OrderSide) MarshalJSON() ([]byte, error) {
+	if os != Buy && os != Sell {
+		return nil, fmt.Errorf("invalid order side: %d", int(os))
+	}
+	return json.Marshal(os.String())
+}
+
+// UnmarshalJSON は文字列（大文字小文字を区別しない）または従来の整数値からOrderSideを復元します。
+func (os *OrderSide) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		side, err := ParseOrderSide(s)
+		if err != nil {
+			return err
+		}
+		*os = side
+		return nil
+	}
+	
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid order side: %s", data)
+	}
+	side := OrderSide(n)
+	if side != Buy && side != Sell {
+		return fmt.Errorf("invalid order side: %d", n)
+	}
+	*os = side
+	return nil
+}
+
 // OrderStatus は注文状態を表します。
 type OrderStatus int
 
@@ -75,19 +109,32 @@ func (os OrderStatus) String() string {
 	}
 }
 
+// TimeInForce は保留注文の有効期限を表します。
+type TimeInForce string
+
+const (
+	GoodTillCancel TimeInForce = "GTC" // キャンセルされるまで有効（既定）
+	DayOrder       TimeInForce = "DAY" // 当日限り。取引終了時刻（BacktestConfig.FlatTime）で取り消される
+)
+
 // Order は取引注文を表します。
 type Order struct {
-	ID          string      `json:"id"`
-	Type        OrderType   `json:"type"`
-	Symbol      string      `json:"symbol"`
-	Side        OrderSide   `json:"side"`
-	Size        float64     `json:"size"`
-	LimitPrice  float64     `json:"limit_price,omitempty"`
-	StopPrice   float64     `json:"stop_price,omitempty"`
-	Status      OrderStatus `json:"status"`
-	CreatedAt   time.Time   `json:"created_at"`
-	ExecutedAt  time.Time   `json:"executed_at,omitempty"`
-	ExecutedPrice float64   `json:"executed_price,omitempty"`
+	ID            string      `json:"id"`
+	Type          OrderType   `json:"type"`
+	Symbol        string      `json:"symbol"`
+	Side          OrderSide   `json:"side"`
+	Size          float64     `json:"size"`
+	LimitPrice    float64     `json:"limit_price,omitempty"`
+	StopPrice     float64     `json:"stop_price,omitempty"`
+	Status        OrderStatus `json:"status"`
+	CreatedAt     time.Time   `json:"created_at"`
+	ExecutedAt    time.Time   `json:"executed_at,omitempty"`
+	ExecutedPrice float64     `json:"executed_price,omitempty"`
+	// StopLoss・TakeProfit は約定時にポジションへ引き継がれる保護価格です。0は設定しないことを表します。
+	StopLoss   float64 `json:"stop_loss,omitempty"`
+	TakeProfit float64 `json:"take_profit,omitempty"`
+	// TimeInForce は保留注文の有効期限です。空の場合は GoodTillCancel として扱います。
+	TimeInForce TimeInForce `json:"time_in_force,omitempty"`
 }
 
 // NewMarketOrder は成行注文を作成します。
@@ -141,6 +188,16 @@ func (o *Order) Validate() error {
 		return errors.New("symbol is required")
 	}
 	
+	if o.StopLoss < 0 || o.TakeProfit < 0 {
+		return errors.New("stop loss and take profit must not be negative")
+	}
+	
+	switch o.TimeInForce {
+	case "", GoodTillCancel, DayOrder:
+	default:
+		return fmt.Errorf("invalid time in force: %s", o.TimeInForce)
+	}
+	
 	switch o.Type {
 	case LimitOrder:
 		if o.LimitPrice <= 0 {
@@ -170,6 +227,11 @@ func (o *Order) IsStop() bool {
 	return o.Type == StopOrder
 }
 
+// IsDayOrder は当日限りの注文かどうかを判定します。
+func (o *Order) IsDayOrder() bool {
+	return o.TimeInForce == DayOrder
+}
+
 // IsPending は保留中の注文かどうかを判定します。
 func (o *Order) IsPending() bool {
 	return o.Status == Pending
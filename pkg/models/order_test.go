@@ -1,6 +1,9 @@
 package models
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+)
 
 // Order構造体のテスト
 func TestOrder_NewMarketOrder(t *testing.T) {
@@ -65,6 +68,29 @@ func TestOrder_Validate(t *testing.T) {
 	if err := order.Validate(); err == nil {
 		t.Error("Expected error for limit order with zero price")
 	}
+	
+	// 異常なケース - 負のストップロス
+	order = NewMarketOrder("test-123", "EURUSD", Buy, 10000.0)
+	order.StopLoss = -1.0
+	if err := order.Validate(); err == nil {
+		t.Error("Expected error for negative stop loss")
+	}
+	
+	// 有効期限
+	order = NewLimitOrder("test-123", "EURUSD", Sell, 10000.0, 1.1000)
+	for _, tif := range []TimeInForce{"", GoodTillCancel, DayOrder} {
+		order.TimeInForce = tif
+		if err := order.Validate(); err != nil {
+			t.Errorf("Expected no error for time in force %q, got %v", tif, err)
+		}
+	}
+	if !order.IsDayOrder() {
+		t.Error("Expected DAY order to be a day order")
+	}
+	order.TimeInForce = "IOC"
+	if err := order.Validate(); err == nil {
+		t.Error("Expected error for invalid time in force")
+	}
 }
 
 func TestOrder_IsMarket(t *testing.T) {
@@ -124,4 +150,52 @@ func TestOrderSide_String(t *testing.T) {
 			t.Errorf("Expected %s, got %s", test.expected, test.orderSide.String())
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestOrderSide_JSON(t *testing.T) {
+	// 文字列として出力される
+	data, err := json.Marshal(Sell)
+	if err != nil || string(data) != `"Sell"` {
+		t.Errorf("Expected \"Sell\", got %s (%v)", data, err)
+	}
+	if _, err := json.Marshal(OrderSide(999)); err == nil {
+		t.Error("Expected error for unknown side")
+	}
+	
+	// 注文・ポジションの side も文字列になる
+	data, _ = json.Marshal(NewPosition("pos-1", "EURUSD", Buy, 1000.0, 1.1000))
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	if fields["side"] != "Buy" {
+		t.Errorf("Expected position side \"Buy\", got %v", fields["side"])
+	}
+	
+	// 文字列と従来の整数値の両方を受け付ける
+	tests := []struct {
+		input    string
+		expected OrderSide
+		hasError bool
+	}{
+		{`"Buy"`, Buy, false},
+		{`"sell"`, Sell, false},
+		{`0`, Buy, false},
+		{`1`, Sell, false},
+		{`"Long"`, Buy, true},
+		{`2`, Buy, true},
+		{`true`, Buy, true},
+	}
+	
+	for _, test := range tests {
+		var side OrderSide
+		err := json.Unmarshal([]byte(test.input), &side)
+		if test.hasError {
+			if err == nil {
+				t.Errorf("Expected error for input %s, got nil", test.input)
+			}
+			continue
+		}
+		if err != nil || side != test.expected {
+			t.Errorf("Expected %v for input %s, got %v (%v)", test.expected, test.input, side, err)
+		}
+	}
+}
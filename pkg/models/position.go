@@ -14,6 +14,11 @@ type Position struct {
 	OpenTime     time.Time `json:"open_time"`
 	StopLoss     float64   `json:"stop_loss,omitempty"`
 	TakeProfit   float64   `json:"take_profit,omitempty"`
+	EntrySpread  float64   `json:"entry_spread,omitempty"` // エントリー時に支払ったスプレッド（価格単位）
+	EntrySlippage float64  `json:"entry_slippage,omitempty"` // エントリー時のスリッページ（価格単位）
+	// Margin はエントリー時に残高から拘束した証拠金です。買い・売りとも同じ計算で、決済時にこの額がそのまま返却されます。
+	// 売りポジションでも売却代金は残高に加算されず、損益は決済時に CalculatePnL の符号で反映されます。
+	Margin       float64   `json:"margin,omitempty"`
 }
 
 // NewPosition は新しいポジションを作成します。
@@ -38,11 +43,12 @@ func (p *Position) UpdatePrice(currentPrice float64) {
 
 // calculatePnL は損益を計算します。
 func (p *Position) calculatePnL() {
-	if p.Side == Buy {
-		p.PnL = (p.CurrentPrice - p.EntryPrice) * p.Size
-	} else {
-		p.PnL = (p.EntryPrice - p.CurrentPrice) * p.Size
-	}
+	p.PnL = p.UnrealizedPnL()
+}
+
+// UnrealizedPnL は現在価格に基づく売買方向を考慮した含み損益を返します。
+func (p *Position) UnrealizedPnL() float64 {
+	return CalculatePnL(p.Side, p.Size, p.EntryPrice, p.CurrentPrice)
 }
 
 // IsLong は買いポジションかどうかを判定します。
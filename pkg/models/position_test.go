@@ -67,6 +67,16 @@ func TestPosition_UpdatePrice(t *testing.T) {
 	assertFloatEqual(t, expectedPnL, position.PnL, "Sell position PnL")
 }
 
+func TestPosition_UnrealizedPnL(t *testing.T) {
+	position := NewPosition("pos-123", "EURUSD", Buy, 10000.0, 1.1000)
+	position.CurrentPrice = 1.1050
+	assertFloatEqual(t, 50.0, position.UnrealizedPnL(), "Buy position unrealized PnL")
+	
+	// 売りポジションは価格上昇で損失
+	position.Side = Sell
+	assertFloatEqual(t, -50.0, position.UnrealizedPnL(), "Sell position unrealized PnL")
+}
+
 func TestPosition_IsLong(t *testing.T) {
 	buyPosition := NewPosition("pos-123", "EURUSD", Buy, 10000.0, 1.1000)
 	if !buyPosition.IsLong() {
@@ -10,12 +10,14 @@ type BacktestResult struct {
 	StartTime time.Time     `json:"start_time"`
 	EndTime   time.Time     `json:"end_time"`
 	Duration  time.Duration `json:"duration"`
+	Cancelled bool          `json:"cancelled,omitempty"` // キャンセルにより途中で打ち切られた場合 true
 	
 	// 残高情報
 	InitialBalance float64 `json:"initial_balance"`
 	FinalBalance   float64 `json:"final_balance"`
 	TotalPnL       float64 `json:"total_pnl"`
 	TotalReturn    float64 `json:"total_return_percent"`
+	TotalCost      float64 `json:"total_cost"` // スプレッドなどの取引コスト合計（TotalPnL に含まれる）
 	
 	// 取引統計
 	TotalTrades   int     `json:"total_trades"`
@@ -77,12 +79,13 @@ func (br *BacktestResult) updateStatistics() {
 		return
 	}
 	
-	var totalPnL, grossProfit, grossLoss float64
+	var totalPnL, totalCost, grossProfit, grossLoss float64
 	var winningTrades, losingTrades int
 	var largestWin, largestLoss float64
 
 	for _, trade := range br.TradeHistory {
 		totalPnL += trade.PnL
+		totalCost += trade.TotalCost()
 		
 		if trade.IsWinning() {
 			winningTrades++
@@ -102,6 +105,7 @@ func (br *BacktestResult) updateStatistics() {
 	br.TotalPnL = totalPnL
 	br.FinalBalance = br.InitialBalance + totalPnL
 	br.TotalReturn = (totalPnL / br.InitialBalance) * 100
+	br.TotalCost = totalCost
 	
 	br.WinningTrades = winningTrades
 	br.LosingTrades = losingTrades
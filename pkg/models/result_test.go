@@ -24,7 +24,7 @@ func TestBacktestResult_AddTrade(t *testing.T) {
 	result := NewBacktestResult(10000.0)
 	
 	position := NewPosition("pos-123", "EURUSD", Buy, 10000.0, 1.1000)
-	trade := NewTradeFromPosition(position, 1.1010)
+	trade := closeTrade(position, 1.1010)
 	
 	result.AddTrade(*trade)
 	
@@ -50,12 +50,13 @@ func TestBacktestResult_UpdateStatistics(t *testing.T) {
 	
 	// 勝ち取引を追加
 	position1 := NewPosition("pos-1", "EURUSD", Buy, 10000.0, 1.1000)
-	trade1 := NewTradeFromPosition(position1, 1.1010)
+	trade1 := closeTrade(position1, 1.1010)
 	result.AddTrade(*trade1)
 	
 	// 負け取引を追加
 	position2 := NewPosition("pos-2", "EURUSD", Buy, 10000.0, 1.1020)
-	trade2 := NewTradeFromPosition(position2, 1.1000)
+	trade2 := closeTrade(position2, 1.1000)
+	trade2.SpreadCost = 2.0
 	result.AddTrade(*trade2)
 	
 	// 統計値の確認
@@ -85,6 +86,11 @@ func TestBacktestResult_UpdateStatistics(t *testing.T) {
 	if result.FinalBalance != expectedFinalBalance {
 		t.Errorf("Expected final balance %f, got %f", expectedFinalBalance, result.FinalBalance)
 	}
+	
+	// 取引コストはスプレッドコストの合計
+	if result.TotalCost != 2.0 {
+		t.Errorf("Expected total cost 2.0, got %f", result.TotalCost)
+	}
 }
 
 func TestBacktestResult_Finalize(t *testing.T) {
@@ -108,7 +114,7 @@ func TestBacktestResult_GetSummary(t *testing.T) {
 	result := NewBacktestResult(10000.0)
 	
 	position := NewPosition("pos-123", "EURUSD", Buy, 10000.0, 1.1000)
-	trade := NewTradeFromPosition(position, 1.1010)
+	trade := closeTrade(position, 1.1010)
 	result.AddTrade(*trade)
 	
 	summary := result.GetSummary()
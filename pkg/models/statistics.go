@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Statistics はバックテストの統計情報を表す構造体
 type Statistics struct {
@@ -14,6 +17,8 @@ type Statistics struct {
 	// 収益統計
 	InitialBalance   float64   `json:"initial_balance"`
 	CurrentBalance   float64   `json:"current_balance"`
+	CurrentEquity    float64   `json:"current_equity"`
+	UnrealizedPnL    float64   `json:"unrealized_pnl"`
 	TotalProfit      float64   `json:"total_profit"`
 	TotalLoss        float64   `json:"total_loss"`
 	NetProfit        float64   `json:"net_profit"`
@@ -35,6 +40,9 @@ type Statistics struct {
 	
 	// 更新時刻
 	LastUpdated      time.Time `json:"last_updated"`
+	
+	// ドローダウン計算用のピーク資産
+	peakEquity       float64
 }
 
 // NewStatistics は新しい統計情報を作成
@@ -43,17 +51,59 @@ func NewStatistics(initialBalance float64) *Statistics {
 		StartTime:        time.Now(),
 		InitialBalance:   initialBalance,
 		CurrentBalance:   initialBalance,
+		CurrentEquity:    initialBalance,
 		LastUpdated:      time.Now(),
+		peakEquity:       initialBalance,
 	}
 }
 
 // UpdateBalance は残高を更新
+// 含み損益がない前提で有効証拠金も残高に合わせる
 func (s *Statistics) UpdateBalance(newBalance float64) {
-	s.CurrentBalance = newBalance
+	s.UpdateAccount(newBalance, newBalance, 0)
+}
+
+// UpdateAccount は残高・有効証拠金・含み損益を同時に更新
+// 残高から拘束中の証拠金が差し引かれている場合、有効証拠金は残高と含み損益の和にならないため含み損益も受け取る
+func (s *Statistics) UpdateAccount(balance, equity, unrealizedPnL float64) {
+	s.CurrentBalance = balance
 	s.NetProfit = s.CurrentBalance - s.InitialBalance
+	s.CurrentEquity = equity
+	s.UnrealizedPnL = unrealizedPnL
+	s.updateDrawdown()
 	s.LastUpdated = time.Now()
 }
 
+// updateDrawdown はピーク資産からの最大ドローダウンを更新
+func (s *Statistics) updateDrawdown() {
+	if s.peakEquity < s.InitialBalance {
+		s.peakEquity = s.InitialBalance
+	}
+	if s.CurrentEquity > s.peakEquity {
+		s.peakEquity = s.CurrentEquity
+	}
+	
+	drawdown := s.peakEquity - s.CurrentEquity
+	if drawdown > s.MaxDrawdown {
+		s.MaxDrawdown = drawdown
+		if s.peakEquity > 0 {
+			s.MaxDrawdownPct = drawdown / s.peakEquity * 100
+		}
+	}
+}
+
+// CurrentDrawdown はピーク資産からの現在のドローダウンを返す
+func (s *Statistics) CurrentDrawdown() float64 {
+	peak := s.peakEquity
+	if peak < s.InitialBalance {
+		peak = s.InitialBalance
+	}
+	if s.CurrentEquity >= peak {
+		return 0
+	}
+	return peak - s.CurrentEquity
+}
+
 // AddTrade は取引を統計に追加
 func (s *Statistics) AddTrade(profit float64) {
 	s.TotalTrades++
@@ -94,4 +144,102 @@ func (s *Statistics) calculateMetrics() {
 	if s.TotalTrades > 0 {
 		s.AverageProfit = s.NetProfit / float64(s.TotalTrades)
 	}
-}
\ No newline at end of file
+}
+
+// statisticsJSON は Statistics の JSON 表現
+type statisticsJSON struct {
+	StartTime            time.Time `json:"start_time"`
+	EndTime              time.Time `json:"end_time"`
+	TotalTrades          int       `json:"total_trades"`
+	WinningTrades        int       `json:"winning_trades"`
+	LosingTrades         int       `json:"losing_trades"`
+	InitialBalance       float64   `json:"initial_balance"`
+	CurrentBalance       float64   `json:"current_balance"`
+	CurrentEquity        float64   `json:"current_equity"`
+	UnrealizedPnL        float64   `json:"unrealized_pnl"`
+	TotalProfit          float64   `json:"total_profit"`
+	TotalLoss            float64   `json:"total_loss"`
+	NetProfit            float64   `json:"net_profit"`
+	WinRate              float64   `json:"win_rate"`
+	ProfitFactor         float64   `json:"profit_factor"`
+	Drawdown             float64   `json:"drawdown"`
+	MaxDrawdown          float64   `json:"max_drawdown"`
+	MaxDrawdownPct       float64   `json:"max_drawdown_pct"`
+	AverageWin           float64   `json:"average_win"`
+	AverageLoss          float64   `json:"average_loss"`
+	AverageProfit        float64   `json:"average_profit"`
+	MaxConsecutiveWins   int       `json:"max_consecutive_wins"`
+	MaxConsecutiveLosses int       `json:"max_consecutive_losses"`
+	LastUpdated          time.Time `json:"last_updated"`
+}
+
+// MarshalJSON は勝率などの派生値を含めて JSON に変換
+func (s *Statistics) MarshalJSON() ([]byte, error) {
+	// 勝率はフィールドの状態に関わらず取引数から導出する
+	winRate := 0.0
+	if s.TotalTrades > 0 {
+		winRate = float64(s.WinningTrades) / float64(s.TotalTrades) * 100
+	}
+	
+	return json.Marshal(statisticsJSON{
+		StartTime:            s.StartTime,
+		EndTime:              s.EndTime,
+		TotalTrades:          s.TotalTrades,
+		WinningTrades:        s.WinningTrades,
+		LosingTrades:         s.LosingTrades,
+		InitialBalance:       s.InitialBalance,
+		CurrentBalance:       s.CurrentBalance,
+		CurrentEquity:        s.CurrentEquity,
+		UnrealizedPnL:        s.UnrealizedPnL,
+		TotalProfit:          s.TotalProfit,
+		TotalLoss:            s.TotalLoss,
+		NetProfit:            s.NetProfit,
+		WinRate:              winRate,
+		ProfitFactor:         s.ProfitFactor,
+		Drawdown:             s.CurrentDrawdown(),
+		MaxDrawdown:          s.MaxDrawdown,
+		MaxDrawdownPct:       s.MaxDrawdownPct,
+		AverageWin:           s.AverageWin,
+		AverageLoss:          s.AverageLoss,
+		AverageProfit:        s.AverageProfit,
+		MaxConsecutiveWins:   s.MaxConsecutiveWins,
+		MaxConsecutiveLosses: s.MaxConsecutiveLosses,
+		LastUpdated:          s.LastUpdated,
+	})
+}
+
+// UnmarshalJSON は MarshalJSON の形式の JSON から統計情報を復元
+// ピーク資産は現在の有効証拠金とドローダウンから復元する
+func (s *Statistics) UnmarshalJSON(data []byte) error {
+	var v statisticsJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	
+	*s = Statistics{
+		StartTime:            v.StartTime,
+		EndTime:              v.EndTime,
+		TotalTrades:          v.TotalTrades,
+		WinningTrades:        v.WinningTrades,
+		LosingTrades:         v.LosingTrades,
+		InitialBalance:       v.InitialBalance,
+		CurrentBalance:       v.CurrentBalance,
+		CurrentEquity:        v.CurrentEquity,
+		UnrealizedPnL:        v.UnrealizedPnL,
+		TotalProfit:          v.TotalProfit,
+		TotalLoss:            v.TotalLoss,
+		NetProfit:            v.NetProfit,
+		WinRate:              v.WinRate,
+		ProfitFactor:         v.ProfitFactor,
+		MaxDrawdown:          v.MaxDrawdown,
+		MaxDrawdownPct:       v.MaxDrawdownPct,
+		AverageWin:           v.AverageWin,
+		AverageLoss:          v.AverageLoss,
+		AverageProfit:        v.AverageProfit,
+		MaxConsecutiveWins:   v.MaxConsecutiveWins,
+		MaxConsecutiveLosses: v.MaxConsecutiveLosses,
+		LastUpdated:          v.LastUpdated,
+		peakEquity:           v.CurrentEquity + v.Drawdown,
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"math"
 	"testing"
 	"time"
@@ -177,4 +178,104 @@ func TestLastUpdated(t *testing.T) {
 			t.Error("Expected last updated time to be updated")
 		}
 	})
-}
\ No newline at end of file
+}
+
+// TestStatisticsMarshalJSON は統計情報の JSON シリアライズをテスト
+func TestStatisticsMarshalJSON(t *testing.T) {
+	t.Run("should serialize balance, equity, trades, win rate and drawdown", func(t *testing.T) {
+		stats := NewStatistics(10000.0)
+		
+		stats.AddTrade(300.0)
+		stats.UpdateBalance(10300.0)
+		stats.AddTrade(-100.0)
+		stats.UpdateBalance(10200.0)
+		stats.AddTrade(200.0)
+		// 含み損を抱えた状態でピーク(10300)から200のドローダウン
+		stats.UpdateAccount(10400.0, 10100.0, -300.0)
+		
+		data, err := json.Marshal(stats)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		
+		expected := map[string]float64{
+			"current_balance":  10400.0,
+			"current_equity":   10100.0,
+			"unrealized_pnl":   -300.0,
+			"total_trades":     3,
+			"winning_trades":   2,
+			"losing_trades":    1,
+			"win_rate":         200.0 / 3.0,
+			"drawdown":         200.0,
+			"max_drawdown":     200.0,
+			"max_drawdown_pct": 200.0 / 10300.0 * 100,
+			"net_profit":       400.0,
+		}
+		for key, want := range expected {
+			value, ok := decoded[key].(float64)
+			if !ok {
+				t.Errorf("Expected field %s to be present, got %v", key, decoded[key])
+				continue
+			}
+			if abs(value-want) > 1e-9 {
+				t.Errorf("Expected %s to be %f, got %f", key, want, value)
+			}
+		}
+	})
+	
+	t.Run("should derive win rate from trade counts", func(t *testing.T) {
+		stats := &Statistics{TotalTrades: 4, WinningTrades: 1}
+		
+		data, err := json.Marshal(stats)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		
+		if decoded["win_rate"] != 25.0 {
+			t.Errorf("Expected win rate 25, got %v", decoded["win_rate"])
+		}
+	})
+}
+
+func TestStatisticsUnmarshalJSON(t *testing.T) {
+	t.Run("should restore statistics and peak equity", func(t *testing.T) {
+		stats := NewStatistics(10000.0)
+		stats.AddTrade(300.0)
+		stats.UpdateBalance(10300.0)
+		stats.UpdateAccount(10300.0, 10100.0, -200.0)
+		
+		data, err := json.Marshal(stats)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		var restored Statistics
+		if err := json.Unmarshal(data, &restored); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		if restored.TotalTrades != 1 || restored.CurrentEquity != 10100.0 || restored.MaxDrawdown != 200.0 {
+			t.Errorf("Expected restored fields to match, got %+v", restored)
+		}
+		if restored.CurrentDrawdown() != 200.0 {
+			t.Errorf("Expected current drawdown 200, got %f", restored.CurrentDrawdown())
+		}
+		
+		// ピーク資産(10300)が復元されているため、続きのドローダウンも同じになる
+		stats.UpdateAccount(10300.0, 10000.0, -300.0)
+		restored.UpdateAccount(10300.0, 10000.0, -300.0)
+		if restored.MaxDrawdown != stats.MaxDrawdown || restored.MaxDrawdownPct != stats.MaxDrawdownPct {
+			t.Errorf("Expected max drawdown %f, got %f", stats.MaxDrawdown, restored.MaxDrawdown)
+		}
+	})
+}
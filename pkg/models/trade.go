@@ -1,7 +1,10 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 )
 
@@ -29,8 +32,10 @@ func (ts TradeStatus) String() string {
 }
 
 // Trade は完了した取引を表します。
+// JSON表現は MarshalJSON を参照してください。
 type Trade struct {
 	ID         string        `json:"id"`
+	Symbol     string        `json:"symbol"`
 	Side       OrderSide     `json:"side"`
 	Size       float64       `json:"size"`
 	EntryPrice float64       `json:"entry_price"`
@@ -40,12 +45,152 @@ type Trade struct {
 	OpenTime   time.Time     `json:"open_time"`
 	CloseTime  time.Time     `json:"close_time"`
 	Duration   time.Duration `json:"duration"`
+	SpreadCost float64       `json:"spread_cost"` // エントリーと決済で支払ったスプレッドの金額（PnLに含まれる）
+	SlippageCost float64     `json:"slippage_cost"` // エントリーと決済のスリッページの金額（PnLに含まれる）
+	Commission float64       `json:"commission"`  // 支払った手数料の金額（PnLに含まれる、ブローカーは計上しないため RecostTrades などで設定）
+	Swap       float64       `json:"swap"`        // 支払ったスワップの金額（受け取った場合は負、PnLに含まれる）
+	PipSize    float64       `json:"pip_size"`    // 1pipの価格幅（銘柄メタデータがないシンボルは0）
+	ContractSize   float64   `json:"contract_size"`   // 数量1あたりの通貨量（銘柄メタデータがないシンボルは0で、1として扱う）
+	ConversionRate float64   `json:"conversion_rate"` // 決済通貨から口座通貨への換算レート（口座通貨を設定していない場合は0で、1として扱う）
+}
+
+// tradeJSON は Trade のJSON表現です。Visualizer のトレードイベントと statistics の JSON・JSON Lines レポート
+// （取引一覧・ベスト/ワースト取引・損益寄与上位取引）で同じ形になります。
+//
+//	id, symbol       : 文字列
+//	side             : "Buy" / "Sell"（読み込み時は従来の整数値も受け付ける）
+//	size, entry_price, exit_price, pnl : 数値
+//	status           : "Open" / "Closed" / "Canceled"
+//	open_time        : RFC3339 形式の時刻
+//	close_time       : RFC3339 形式の時刻（未決済の場合は省略）
+//	duration_hours   : 保有時間（時間単位）
+//	spread_cost      : 支払ったスプレッドの金額（pnl に含まれる）
+//	slippage_cost    : スリッページの金額（pnl に含まれる、0の場合は省略）
+//	commission       : 手数料の金額（pnl に含まれる、0の場合は省略）
+//	swap             : 支払ったスワップの金額（受け取った場合は負、pnl に含まれる、0の場合は省略）
+//	pip_size         : 1pipの価格幅（銘柄メタデータがない場合は省略）
+//	contract_size    : 数量1あたりの通貨量（銘柄メタデータがない場合は省略）
+//	conversion_rate  : 決済通貨から口座通貨への換算レート（口座通貨を設定していない場合は省略）
+//	pnl_pips         : pips単位の損益（pip_size がない場合は省略、出力のみ）
+type tradeJSON struct {
+	ID            string    `json:"id"`
+	Symbol        string    `json:"symbol"`
+	Side          OrderSide `json:"side"`
+	Size          float64   `json:"size"`
+	EntryPrice    float64   `json:"entry_price"`
+	ExitPrice     float64   `json:"exit_price"`
+	PnL           float64   `json:"pnl"`
+	Status        string    `json:"status"`
+	OpenTime      string    `json:"open_time"`
+	CloseTime     string    `json:"close_time,omitempty"`
+	DurationHours float64   `json:"duration_hours"`
+	SpreadCost    float64   `json:"spread_cost"`
+	SlippageCost  float64   `json:"slippage_cost,omitempty"`
+	Commission    float64   `json:"commission,omitempty"`
+	Swap          float64   `json:"swap,omitempty"`
+	PipSize       float64   `json:"pip_size,omitempty"`
+	PnLPips       float64   `json:"pnl_pips,omitempty"`
+	ContractSize  float64   `json:"contract_size,omitempty"`
+	ConversionRate float64  `json:"conversion_rate,omitempty"`
+}
+
+// MarshalJSON は Trade を tradeJSON の形式でJSONに変換します。
+func (t Trade) MarshalJSON() ([]byte, error) {
+	v := tradeJSON{
+		ID:            t.ID,
+		Symbol:        t.Symbol,
+		Side:          t.Side,
+		Size:          t.Size,
+		EntryPrice:    t.EntryPrice,
+		ExitPrice:     t.ExitPrice,
+		PnL:           t.PnL,
+		Status:        t.Status.String(),
+		OpenTime:      t.OpenTime.Format(time.RFC3339Nano),
+		DurationHours: t.GetDurationHours(),
+		SpreadCost:    t.SpreadCost,
+		SlippageCost:  t.SlippageCost,
+		Commission:    t.Commission,
+		Swap:          t.Swap,
+		PipSize:       t.PipSize,
+		PnLPips:       t.PnLPips(),
+		ContractSize:  t.ContractSize,
+		ConversionRate: t.ConversionRate,
+	}
+	if !t.CloseTime.IsZero() {
+		v.CloseTime = t.CloseTime.Format(time.RFC3339Nano)
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON は MarshalJSON の形式のJSONから Trade を復元します。
+func (t *Trade) UnmarshalJSON(data []byte) error {
+	var v tradeJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	
+	status, err := ParseTradeStatus(v.Status)
+	if err != nil {
+		return err
+	}
+	openTime, err := time.Parse(time.RFC3339Nano, v.OpenTime)
+	if err != nil {
+		return fmt.Errorf("invalid open_time: %w", err)
+	}
+	var closeTime time.Time
+	if v.CloseTime != "" {
+		if closeTime, err = time.Parse(time.RFC3339Nano, v.CloseTime); err != nil {
+			return fmt.Errorf("invalid close_time: %w", err)
+		}
+	}
+	
+	*t = Trade{
+		ID:         v.ID,
+		Symbol:     v.Symbol,
+		Side:       v.Side,
+		Size:       v.Size,
+		EntryPrice: v.EntryPrice,
+		ExitPrice:  v.ExitPrice,
+		PnL:        v.PnL,
+		Status:     status,
+		OpenTime:   openTime,
+		CloseTime:  closeTime,
+		Duration:   time.Duration(v.DurationHours * float64(time.Hour)),
+		SpreadCost: v.SpreadCost,
+		SlippageCost: v.SlippageCost,
+		Commission: v.Commission,
+		Swap:       v.Swap,
+		PipSize:    v.PipSize,
+		ContractSize:   v.ContractSize,
+		ConversionRate: v.ConversionRate,
+	}
+	if !closeTime.IsZero() {
+		t.Duration = closeTime.Sub(openTime)
+	}
+	return nil
+}
+
+// Units は数量を ContractSize で通貨量に換算した値を返します。ContractSize が0の場合は数量をそのまま返します。
+func (t *Trade) Units() float64 {
+	if t.ContractSize > 0 {
+		return t.Size * t.ContractSize
+	}
+	return t.Size
+}
+
+// AccountRate は決済通貨建ての金額を口座通貨建てに換算するレートを返します。ConversionRate が0の場合は1です。
+func (t *Trade) AccountRate() float64 {
+	if t.ConversionRate > 0 {
+		return t.ConversionRate
+	}
+	return 1.0
 }
 
 // NewTradeFromPosition はポジションから取引履歴を作成します。
 func NewTradeFromPosition(position *Position, exitPrice float64, pnl float64, closeTime time.Time) *Trade {
 	return &Trade{
 		ID:         position.ID,
+		Symbol:     position.Symbol,
 		Side:       position.Side,
 		Size:       position.Size,
 		EntryPrice: position.EntryPrice,
@@ -58,14 +203,35 @@ func NewTradeFromPosition(position *Position, exitPrice float64, pnl float64, cl
 	}
 }
 
-// calculateTradePnL は取引の損益を計算します。
-func calculateTradePnL(side OrderSide, size, entryPrice, exitPrice float64) float64 {
+// CalculatePnL は売買方向を考慮して、entryPrice で建てた size 単位を exitPrice で決済した場合の損益を計算します。
+// 含み損益・決済損益の計算はすべてこの関数を使います。
+func CalculatePnL(side OrderSide, size, entryPrice, exitPrice float64) float64 {
 	if side == Buy {
 		return (exitPrice - entryPrice) * size
 	}
 	return (entryPrice - exitPrice) * size
 }
 
+// PositionSizeForRisk は、entry で建てたポジションが stop で決済された場合の損失が
+// 残高 balance の riskPct パーセント（1.0 = 1%）になる数量を返します。数量は CalculatePnL と同じ単位です。
+// leverage が正の場合は、建玉の金額（数量 × entry）が balance × leverage を超えないよう数量を抑えます（0以下は上限なし）。
+// 残高・リスク・価格が正でない場合や、entry と stop が同じ場合は 0 を返します。
+func PositionSizeForRisk(balance, riskPct, entry, stop, leverage float64) float64 {
+	if balance <= 0 || riskPct <= 0 || entry <= 0 || stop <= 0 {
+		return 0
+	}
+	stopDistance := math.Abs(entry - stop)
+	if stopDistance == 0 {
+		return 0
+	}
+	
+	size := balance * riskPct / 100.0 / stopDistance
+	if leverage > 0 {
+		size = math.Min(size, balance*leverage/entry)
+	}
+	return size
+}
+
 // IsWinning は勝ち取引かどうかを判定します。
 func (t *Trade) IsWinning() bool {
 	return t.PnL > 0
@@ -89,15 +255,78 @@ func (t *Trade) GetPnLPercentage() float64 {
 	return (t.PnL / (t.EntryPrice * t.Size)) * 100
 }
 
+// PnLPips は建値から決済価格までの値動きを売買方向を考慮してpips単位で返します。
+// 価格差から計算するため、スプレッドを含む約定価格ベースの損益で、数量や契約サイズには依存しません。
+// PipSize が設定されていない取引と未決済の取引は0を返します。
+func (t *Trade) PnLPips() float64 {
+	if t.PipSize <= 0 || t.Status != TradeClosed {
+		return 0
+	}
+	return CalculatePnL(t.Side, 1, t.EntryPrice, t.ExitPrice) / t.PipSize
+}
+
+// TotalCost は取引コスト（スプレッド・スリッページ・手数料・スワップ）の合計を返します。
+func (t *Trade) TotalCost() float64 {
+	return t.SpreadCost + t.SlippageCost + t.Commission + t.Swap
+}
+
 // GetDurationHours は取引時間を時間単位で返します。
 func (t *Trade) GetDurationHours() float64 {
 	return t.Duration.Hours()
 }
 
+// TradeResult は TradeFilter で絞り込む取引の結果です。
+type TradeResult string
+
+const (
+	TradeResultAny       TradeResult = ""          // 結果で絞り込まない
+	TradeResultWin       TradeResult = "win"       // 勝ち取引（PnL > 0）
+	TradeResultLoss      TradeResult = "loss"      // 負け取引（PnL < 0）
+	TradeResultBreakeven TradeResult = "breakeven" // 損益なし（PnL == 0）
+)
+
+// TradeFilter は取引履歴の絞り込み条件です。ゼロ値のフィールドは条件に含めず、指定した条件を全て満たす取引が対象になります。
+type TradeFilter struct {
+	Symbol string      // シンボル（大文字小文字を区別しない）
+	Side   *OrderSide  // 売買方向
+	From   time.Time   // 決済時刻がこの時刻以降
+	To     time.Time   // 決済時刻がこの時刻より前
+	Result TradeResult // 勝ち・負け・損益なし
+}
+
+// Matches は取引が全ての条件を満たすかを判定します。
+func (f TradeFilter) Matches(trade *Trade) bool {
+	if trade == nil {
+		return false
+	}
+	if f.Symbol != "" && !strings.EqualFold(trade.Symbol, f.Symbol) {
+		return false
+	}
+	if f.Side != nil && trade.Side != *f.Side {
+		return false
+	}
+	if !f.From.IsZero() && trade.CloseTime.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !trade.CloseTime.Before(f.To) {
+		return false
+	}
+	switch f.Result {
+	case TradeResultWin:
+		return trade.IsWinning()
+	case TradeResultLoss:
+		return trade.IsLosing()
+	case TradeResultBreakeven:
+		return trade.IsBreakeven()
+	}
+	return true
+}
+
 // ToCSVRecord はCSV形式の文字列スライスに変換します。
 func (t *Trade) ToCSVRecord() []string {
 	return []string{
 		t.ID,
+		t.Symbol,
 		t.Side.String(),
 		fmt.Sprintf("%.2f", t.Size),
 		fmt.Sprintf("%.5f", t.EntryPrice),
@@ -1,14 +1,24 @@
 package models
 
-import "testing"
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
 
+// closeTrade はテスト用に現在時刻でポジションを決済した取引を作成します。
+func closeTrade(position *Position, exitPrice float64) *Trade {
+	pnl := CalculatePnL(position.Side, position.Size, position.EntryPrice, exitPrice)
+	return NewTradeFromPosition(position, exitPrice, pnl, time.Now())
+}
 
 // Trade構造体のテスト
 func TestTrade_NewTradeFromPosition(t *testing.T) {
 	position := NewPosition("pos-123", "EURUSD", Buy, 10000.0, 1.1000)
 	exitPrice := 1.1010
 	
-	trade := NewTradeFromPosition(position, exitPrice)
+	trade := NewTradeFromPosition(position, exitPrice, CalculatePnL(position.Side, position.Size, position.EntryPrice, exitPrice), time.Now())
 	
 	if trade.ID != position.ID {
 		t.Errorf("Expected ID %s, got %s", position.ID, trade.ID)
@@ -46,19 +56,19 @@ func TestTrade_IsWinning(t *testing.T) {
 	position := NewPosition("pos-123", "EURUSD", Buy, 10000.0, 1.1000)
 	
 	// 勝ち取引
-	trade := NewTradeFromPosition(position, 1.1010)
+	trade := closeTrade(position, 1.1010)
 	if !trade.IsWinning() {
 		t.Error("Expected winning trade")
 	}
 	
 	// 負け取引
-	trade = NewTradeFromPosition(position, 1.0990)
+	trade = closeTrade(position, 1.0990)
 	if !trade.IsLosing() {
 		t.Error("Expected losing trade")
 	}
 	
 	// 引き分け
-	trade = NewTradeFromPosition(position, 1.1000)
+	trade = closeTrade(position, 1.1000)
 	if !trade.IsBreakeven() {
 		t.Error("Expected breakeven trade")
 	}
@@ -68,13 +78,13 @@ func TestTrade_IsLosing(t *testing.T) {
 	position := NewPosition("pos-123", "EURUSD", Buy, 10000.0, 1.1000)
 	
 	// 負け取引
-	trade := NewTradeFromPosition(position, 1.0990)
+	trade := closeTrade(position, 1.0990)
 	if !trade.IsLosing() {
 		t.Error("Expected losing trade")
 	}
 	
 	// 勝ち取引
-	trade = NewTradeFromPosition(position, 1.1010)
+	trade = closeTrade(position, 1.1010)
 	if trade.IsLosing() {
 		t.Error("Expected winning trade, not losing")
 	}
@@ -84,13 +94,13 @@ func TestTrade_IsBreakeven(t *testing.T) {
 	position := NewPosition("pos-123", "EURUSD", Buy, 10000.0, 1.1000)
 	
 	// 引き分け取引
-	trade := NewTradeFromPosition(position, 1.1000)
+	trade := closeTrade(position, 1.1000)
 	if !trade.IsBreakeven() {
 		t.Error("Expected breakeven trade")
 	}
 	
 	// 勝ち取引
-	trade = NewTradeFromPosition(position, 1.1010)
+	trade = closeTrade(position, 1.1010)
 	if trade.IsBreakeven() {
 		t.Error("Expected winning trade, not breakeven")
 	}
@@ -98,7 +108,7 @@ func TestTrade_IsBreakeven(t *testing.T) {
 
 func TestTrade_GetPnLPercentage(t *testing.T) {
 	position := NewPosition("pos-123", "EURUSD", Buy, 10000.0, 1.1000)
-	trade := NewTradeFromPosition(position, 1.1010)
+	trade := closeTrade(position, 1.1010)
 	
 	expectedPercentage := ((1.1010 - 1.1000) / 1.1000) * 100
 	actualPercentage := trade.GetPnLPercentage()
@@ -106,9 +116,31 @@ func TestTrade_GetPnLPercentage(t *testing.T) {
 	assertFloatEqual(t, expectedPercentage, actualPercentage, "Trade PnL percentage")
 }
 
+func TestTrade_PnLPips(t *testing.T) {
+	// 買い: 1.1000 → 1.1025 で +25pips
+	buy := closeTrade(NewPosition("pos-1", "EURUSD", Buy, 10000.0, 1.1000), 1.1025)
+	buy.PipSize = 0.0001
+	assertFloatEqual(t, 25.0, buy.PnLPips(), "Buy trade PnL pips")
+	
+	// 売り: 150.00 → 150.30 で -30pips（数量・契約サイズに依存しない）
+	sell := closeTrade(NewPosition("pos-2", "USDJPY", Sell, 3.0, 150.00), 150.30)
+	sell.PipSize = 0.01
+	assertFloatEqual(t, -30.0, sell.PnLPips(), "Sell trade PnL pips")
+	
+	// pipサイズがない取引・未決済の取引は0
+	noPip := closeTrade(NewPosition("pos-3", "EURUSD", Buy, 10000.0, 1.1000), 1.1025)
+	if noPip.PnLPips() != 0 {
+		t.Errorf("Expected 0 pips without pip size, got %f", noPip.PnLPips())
+	}
+	buy.Status = TradeOpen
+	if buy.PnLPips() != 0 {
+		t.Errorf("Expected 0 pips for open trade, got %f", buy.PnLPips())
+	}
+}
+
 func TestTrade_GetDurationHours(t *testing.T) {
 	position := NewPosition("pos-123", "EURUSD", Buy, 10000.0, 1.1000)
-	trade := NewTradeFromPosition(position, 1.1010)
+	trade := closeTrade(position, 1.1010)
 	
 	// 取引時間は非常に短いはずなので、0以上であることを確認
 	if trade.GetDurationHours() < 0 {
@@ -118,7 +150,7 @@ func TestTrade_GetDurationHours(t *testing.T) {
 
 func TestTrade_ToCSVRecord(t *testing.T) {
 	position := NewPosition("pos-123", "EURUSD", Buy, 10000.0, 1.1000)
-	trade := NewTradeFromPosition(position, 1.1010)
+	trade := closeTrade(position, 1.1010)
 	
 	record := trade.ToCSVRecord()
 	
@@ -140,6 +172,92 @@ func TestTrade_ToCSVRecord(t *testing.T) {
 	}
 }
 
+func TestTrade_JSON(t *testing.T) {
+	openTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	trade := &Trade{
+		ID:         "sell-EURUSD-1",
+		Symbol:     "EURUSD",
+		Side:       Sell,
+		Size:       1000.0,
+		EntryPrice: 1.1000,
+		ExitPrice:  1.0990,
+		PnL:        1.0,
+		Status:     TradeClosed,
+		OpenTime:   openTime,
+		CloseTime:  openTime.Add(90 * time.Minute),
+		Duration:   90 * time.Minute,
+		SpreadCost: 0.2,
+		SlippageCost: 0.1,
+		Commission: 0.5,
+		Swap:       -0.3,
+		PipSize:    0.0001,
+	}
+	
+	data, err := json.Marshal(trade)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	expected := map[string]interface{}{
+		"id":             "sell-EURUSD-1",
+		"symbol":         "EURUSD",
+		"side":           "Sell",
+		"status":         "Closed",
+		"open_time":      "2024-01-01T09:00:00Z",
+		"close_time":     "2024-01-01T10:30:00Z",
+		"duration_hours": 1.5,
+		"spread_cost":    0.2,
+		"slippage_cost":  0.1,
+		"commission":     0.5,
+		"swap":           -0.3,
+		"pip_size":       0.0001,
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, fields[key])
+		}
+	}
+	if pips, ok := fields["pnl_pips"].(float64); !ok || math.Abs(pips-10.0) > 1e-6 {
+		t.Errorf("Expected pnl_pips to be 10, got %v", fields["pnl_pips"])
+	}
+	
+	// 往復で元の値に戻る
+	var decoded Trade
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if decoded != *trade {
+		t.Errorf("Expected round-trip %+v, got %+v", *trade, decoded)
+	}
+	
+	// 未決済の取引は close_time を省略する
+	trade.CloseTime = time.Time{}
+	trade.Status = TradeOpen
+	data, _ = json.Marshal(trade)
+	fields = map[string]interface{}{}
+	json.Unmarshal(data, &fields)
+	if _, ok := fields["close_time"]; ok {
+		t.Errorf("Expected close_time to be omitted for open trade, got %s", data)
+	}
+	if _, ok := fields["pnl_pips"]; ok {
+		t.Errorf("Expected pnl_pips to be omitted for open trade, got %s", data)
+	}
+	
+	// 従来の整数値の side も読み込める
+	if err := json.Unmarshal([]byte(`{"id":"x","side":1,"status":"Open","open_time":"2024-01-01T09:00:00Z"}`), &decoded); err != nil || decoded.Side != Sell {
+		t.Errorf("Expected legacy integer side to decode as Sell, got %v (%v)", decoded.Side, err)
+	}
+	
+	// 不正な side はエラー
+	if err := json.Unmarshal([]byte(`{"id":"x","side":"Long","status":"Open","open_time":"2024-01-01T09:00:00Z"}`), &decoded); err == nil {
+		t.Error("Expected error for invalid side")
+	}
+}
+
 func TestTradeStatus_String(t *testing.T) {
 	tests := []struct {
 		status   TradeStatus
@@ -158,14 +276,87 @@ func TestTradeStatus_String(t *testing.T) {
 	}
 }
 
-func TestCalculateTradePnL(t *testing.T) {
+func TestCalculatePnL(t *testing.T) {
 	// 買い取引のテスト
-	buyPnL := calculateTradePnL(Buy, 10000.0, 1.1000, 1.1010)
+	buyPnL := CalculatePnL(Buy, 10000.0, 1.1000, 1.1010)
 	expectedBuyPnL := (1.1010 - 1.1000) * 10000.0
 	assertFloatEqual(t, expectedBuyPnL, buyPnL, "Buy trade PnL")
 	
 	// 売り取引のテスト
-	sellPnL := calculateTradePnL(Sell, 10000.0, 1.1000, 1.0990)
+	sellPnL := CalculatePnL(Sell, 10000.0, 1.1000, 1.0990)
 	expectedSellPnL := (1.1000 - 1.0990) * 10000.0
 	assertFloatEqual(t, expectedSellPnL, sellPnL, "Sell trade PnL")
-}
\ No newline at end of file
+}
+
+func TestTradeFilter_Matches(t *testing.T) {
+	closeTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	trade := &Trade{Symbol: "EURUSD", Side: Sell, PnL: 25.0, CloseTime: closeTime}
+	buy, sell := Buy, Sell
+	
+	tests := []struct {
+		name     string
+		filter   TradeFilter
+		expected bool
+	}{
+		{"empty filter", TradeFilter{}, true},
+		{"symbol ignores case", TradeFilter{Symbol: "eurusd"}, true},
+		{"other symbol", TradeFilter{Symbol: "USDJPY"}, false},
+		{"side sell", TradeFilter{Side: &sell}, true},
+		// Buy はゼロ値だが、ポインタで指定すれば条件になる
+		{"side buy", TradeFilter{Side: &buy}, false},
+		{"from inclusive", TradeFilter{From: closeTime}, true},
+		{"to exclusive", TradeFilter{To: closeTime}, false},
+		{"within range", TradeFilter{From: closeTime.Add(-time.Hour), To: closeTime.Add(time.Hour)}, true},
+		{"win", TradeFilter{Result: TradeResultWin}, true},
+		{"loss", TradeFilter{Result: TradeResultLoss}, false},
+		{"breakeven", TradeFilter{Result: TradeResultBreakeven}, false},
+		{"all conditions", TradeFilter{Symbol: "EURUSD", Side: &sell, From: closeTime, Result: TradeResultWin}, true},
+	}
+	
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.filter.Matches(trade); got != test.expected {
+				t.Errorf("Matches() = %v, want %v", got, test.expected)
+			}
+		})
+	}
+	
+	if (TradeFilter{}).Matches(nil) {
+		t.Error("Expected nil trade not to match")
+	}
+}
+
+func TestPositionSizeForRisk(t *testing.T) {
+	// 残高10000の1%（100）を、50pips（0.0050）の損切り幅で失う数量
+	size := PositionSizeForRisk(10000.0, 1.0, 1.1000, 1.0950, 0)
+	assertFloatEqual(t, 20000.0, size, "Buy size")
+	assertFloatEqual(t, -100.0, CalculatePnL(Buy, size, 1.1000, 1.0950), "Loss at stop")
+	
+	// 売りは損切りが建値より上
+	size = PositionSizeForRisk(10000.0, 1.0, 1.1000, 1.1050, 0)
+	assertFloatEqual(t, 20000.0, size, "Sell size")
+	assertFloatEqual(t, -100.0, CalculatePnL(Sell, size, 1.1000, 1.1050), "Loss at stop")
+	
+	// レバレッジ上限: 10000 × 1 / 1.1 ≒ 9090.9 に抑えられる
+	size = PositionSizeForRisk(10000.0, 1.0, 1.1000, 1.0950, 1.0)
+	assertFloatEqual(t, 10000.0/1.1, size, "Size capped by leverage")
+	
+	// 上限に達しない場合はリスクによる数量のまま
+	size = PositionSizeForRisk(10000.0, 1.0, 1.1000, 1.0950, 100.0)
+	assertFloatEqual(t, 20000.0, size, "Size below leverage cap")
+	
+	// 不正な入力は0
+	invalid := [][5]float64{
+		{0, 1.0, 1.1, 1.09, 0},
+		{10000.0, 0, 1.1, 1.09, 0},
+		{10000.0, -1.0, 1.1, 1.09, 0},
+		{10000.0, 1.0, 0, 1.09, 0},
+		{10000.0, 1.0, 1.1, 0, 0},
+		{10000.0, 1.0, 1.1, 1.1, 0},
+	}
+	for _, args := range invalid {
+		if got := PositionSizeForRisk(args[0], args[1], args[2], args[3], args[4]); got != 0 {
+			t.Errorf("PositionSizeForRisk(%v) = %v, want 0", args, got)
+		}
+	}
+}
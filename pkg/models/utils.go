@@ -2,6 +2,8 @@ package models
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
 	"strings"
 	"time"
 )
@@ -23,6 +25,20 @@ func ParseOrderSide(s string) (OrderSide, error) {
 	}
 }
 
+// ParseTradeStatus は文字列からTradeStatusに変換します。
+func ParseTradeStatus(s string) (TradeStatus, error) {
+	switch strings.ToLower(s) {
+	case "open":
+		return TradeOpen, nil
+	case "closed":
+		return TradeClosed, nil
+	case "canceled", "cancelled":
+		return TradeCanceled, nil
+	default:
+		return TradeOpen, fmt.Errorf("invalid trade status: %s", s)
+	}
+}
+
 // ParseOrderType は文字列からOrderTypeに変換します。
 func ParseOrderType(s string) (OrderType, error) {
 	switch strings.ToLower(s) {
@@ -56,4 +72,41 @@ func (ve ValidationError) Error() string {
 // ValidateStruct は構造体全体のバリデーションを行います。
 func ValidateStruct(v Validator) error {
 	return v.Validate()
-}
\ No newline at end of file
+}
+
+// LogLevelOff はログ出力を無効にするログレベルです。
+const LogLevelOff = "off"
+
+// ParseLogLevel は文字列から slog.Level に変換します。空文字列は info として扱います。
+func ParseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level: %s", s)
+	}
+}
+
+// NewLogger は指定したログレベル以上を w にテキスト形式で出力するロガーを作成します。
+// level が "off" の場合は何も出力しないロガーを、不正な値の場合は info レベルのロガーを返します。
+func NewLogger(w io.Writer, level string) *slog.Logger {
+	if strings.ToLower(level) == LogLevelOff {
+		return NopLogger()
+	}
+	lvl, err := ParseLogLevel(level)
+	if err != nil {
+		lvl = slog.LevelInfo
+	}
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: lvl}))
+}
+
+// NopLogger は何も出力しないロガーを返します。
+func NopLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
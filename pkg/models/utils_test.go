@@ -1,6 +1,9 @@
 package models
 
 import (
+	"bytes"
+	"context"
+	"log/slog"
 	"strings"
 	"testing"
 )
@@ -58,17 +61,17 @@ func TestParseOrderType(t *testing.T) {
 		expected OrderType
 		hasError bool
 	}{
-		{"market", Market, false},
-		{"Market", Market, false},
-		{"MARKET", Market, false},
-		{"limit", Limit, false},
-		{"Limit", Limit, false},
-		{"LIMIT", Limit, false},
-		{"stop", Stop, false},
-		{"Stop", Stop, false},
-		{"STOP", Stop, false},
-		{"invalid", Market, true},
-		{"", Market, true},
+		{"market", MarketOrder, false},
+		{"Market", MarketOrder, false},
+		{"MARKET", MarketOrder, false},
+		{"limit", LimitOrder, false},
+		{"Limit", LimitOrder, false},
+		{"LIMIT", LimitOrder, false},
+		{"stop", StopOrder, false},
+		{"Stop", StopOrder, false},
+		{"STOP", StopOrder, false},
+		{"invalid", MarketOrder, true},
+		{"", MarketOrder, true},
 	}
 	
 	for _, test := range tests {
@@ -121,4 +124,93 @@ func TestValidateStruct(t *testing.T) {
 	if err := ValidateStruct(&config); err == nil {
 		t.Error("Expected error for invalid config")
 	}
-}
\ No newline at end of file
+}
+
+func TestParseTradeStatus(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected TradeStatus
+		hasError bool
+	}{
+		{"open", TradeOpen, false},
+		{"Closed", TradeClosed, false},
+		{"CANCELED", TradeCanceled, false},
+		{"cancelled", TradeCanceled, false},
+		{"pending", TradeOpen, true},
+	}
+	
+	for _, test := range tests {
+		result, err := ParseTradeStatus(test.input)
+		if test.hasError {
+			if err == nil {
+				t.Errorf("Expected error for input '%s', got nil", test.input)
+			}
+			continue
+		}
+		if err != nil || result != test.expected {
+			t.Errorf("Expected %v for input '%s', got %v (%v)", test.expected, test.input, result, err)
+		}
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected slog.Level
+		hasError bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"warning", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", slog.LevelInfo, true},
+	}
+	
+	for _, test := range tests {
+		result, err := ParseLogLevel(test.input)
+		
+		if test.hasError {
+			if err == nil {
+				t.Errorf("Expected error for input '%s', got nil", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected no error for input '%s', got %v", test.input, err)
+		}
+		if result != test.expected {
+			t.Errorf("Expected %v for input '%s', got %v", test.expected, test.input, result)
+		}
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	
+	// warn 以上のみ出力される
+	logger := NewLogger(&buf, "warn")
+	logger.Info("hidden")
+	logger.Warn("shown", "key", 1)
+	
+	output := buf.String()
+	if strings.Contains(output, "hidden") {
+		t.Errorf("Expected info message to be filtered, got %q", output)
+	}
+	if !strings.Contains(output, "shown") || !strings.Contains(output, "key=1") {
+		t.Errorf("Expected warn message with attributes, got %q", output)
+	}
+	
+	// off では何も出力されない
+	buf.Reset()
+	NewLogger(&buf, LogLevelOff).Error("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output for off level, got %q", buf.String())
+	}
+	
+	// NopLogger はどのレベルも無効
+	if NopLogger().Enabled(context.Background(), slog.LevelError) {
+		t.Error("Expected NopLogger to disable all levels")
+	}
+}
@@ -16,11 +16,25 @@ type VisualizerConfig struct {
 	MaxClients        int           `json:"max_clients"`        // 最大クライアント数
 	HeartbeatInterval time.Duration `json:"heartbeat_interval"` // ハートビート間隔
 	ClientTimeout     time.Duration `json:"client_timeout"`     // クライアントタイムアウト
+	AllowedOrigins    []string      `json:"allowed_origins"`    // 接続を許可するOrigin ("*"で全て許可)
+
+	// TLS設定 (両方指定するとwssで配信)
+	CertFile string `json:"cert_file"` // 証明書ファイルパス
+	KeyFile  string `json:"key_file"`  // 秘密鍵ファイルパス
+
+	// フロントエンド配信設定
+	StaticDir string `json:"static_dir"` // "/" で配信するビルド済みフロントエンドのディレクトリ (空の場合は配信しない)
 
 	// データ処理設定
-	BufferSize    int           `json:"buffer_size"`    // バッファサイズ
-	BatchSize     int           `json:"batch_size"`     // バッチサイズ
-	FlushInterval time.Duration `json:"flush_interval"` // フラッシュ間隔
+	BufferSize         int           `json:"buffer_size"`         // バッファサイズ
+	BatchCandles       bool          `json:"batch_candles"`       // ローソク足を candle_batch にまとめて送信するかどうか
+	BatchSize          int           `json:"batch_size"`          // バッチサイズ (到達した時点で送信)
+	FlushInterval      time.Duration `json:"flush_interval"`      // フラッシュ間隔
+	EnableCompression  bool          `json:"enable_compression"`  // WebSocketのメッセージ圧縮 (permessage-deflate) を有効にするかどうか
+	HistorySize        int           `json:"history_size"`        // 接続時に送信する過去ローソク足の本数
+	EquityPoints       int           `json:"equity_points"`       // equity_update で送信するエクイティ系列の最大点数 (0で無効)
+	ReplayBufferSize   int           `json:"replay_buffer_size"`  // 再接続時にリプレイする直近メッセージ数 (0で無効)
+	StatisticsInterval time.Duration `json:"statistics_interval"` // statistics_update の最小送信間隔 (0で毎ステップ送信)
 
 	// ログ設定
 	LogLevel      string `json:"log_level"`      // ログレベル
@@ -31,19 +45,24 @@ type VisualizerConfig struct {
 // DefaultVisualizerConfig はデフォルトのVisualizer設定を返します
 func DefaultVisualizerConfig() VisualizerConfig {
 	return VisualizerConfig{
-		Enabled:           true,
-		Port:              8080,
-		ReadTimeout:       60 * time.Second,
-		WriteTimeout:      10 * time.Second,
-		MaxClients:        100,
-		HeartbeatInterval: 30 * time.Second,
-		ClientTimeout:     90 * time.Second,
-		BufferSize:        1024,
-		BatchSize:         100,
-		FlushInterval:     1 * time.Second,
-		LogLevel:          "info",
-		LogFile:           "",
-		EnableMetrics:     false,
+		Enabled:            true,
+		Port:               8080,
+		ReadTimeout:        60 * time.Second,
+		WriteTimeout:       10 * time.Second,
+		MaxClients:         100,
+		HeartbeatInterval:  30 * time.Second,
+		ClientTimeout:      90 * time.Second,
+		AllowedOrigins:     []string{"*"},
+		BufferSize:         1024,
+		BatchSize:          100,
+		FlushInterval:      1 * time.Second,
+		HistorySize:        200,
+		EquityPoints:       500,
+		ReplayBufferSize:   1000,
+		StatisticsInterval: 250 * time.Millisecond,
+		LogLevel:           "info",
+		LogFile:            "",
+		EnableMetrics:      false,
 	}
 }
 
@@ -84,6 +103,54 @@ func (vc *VisualizerConfig) Validate() error {
 		}
 	}
 
+	if (vc.CertFile == "") != (vc.KeyFile == "") {
+		return &ValidationError{
+			Field:   "CertFile",
+			Value:   vc.CertFile,
+			Message: "cert file and key file must be set together",
+		}
+	}
+
+	if vc.HistorySize < 0 {
+		return &ValidationError{
+			Field:   "HistorySize",
+			Value:   vc.HistorySize,
+			Message: "history size must be non-negative",
+		}
+	}
+
+	if vc.EquityPoints < 0 {
+		return &ValidationError{
+			Field:   "EquityPoints",
+			Value:   vc.EquityPoints,
+			Message: "equity points must be non-negative",
+		}
+	}
+
+	if vc.ReplayBufferSize < 0 {
+		return &ValidationError{
+			Field:   "ReplayBufferSize",
+			Value:   vc.ReplayBufferSize,
+			Message: "replay buffer size must be non-negative",
+		}
+	}
+
+	if vc.StatisticsInterval < 0 {
+		return &ValidationError{
+			Field:   "StatisticsInterval",
+			Value:   vc.StatisticsInterval,
+			Message: "statistics interval must be non-negative",
+		}
+	}
+
+	if vc.BatchCandles && vc.FlushInterval <= 0 {
+		return &ValidationError{
+			Field:   "FlushInterval",
+			Value:   vc.FlushInterval,
+			Message: "flush interval must be positive when batching candles",
+		}
+	}
+
 	if vc.ReadTimeout <= 0 {
 		return &ValidationError{
 			Field:   "ReadTimeout",
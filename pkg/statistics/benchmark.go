@@ -0,0 +1,210 @@
+package statistics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// Benchmark は戦略の成績を同じ期間のバイアンドホールド（最初のローソク足で買って保有し続ける）と比較した結果です。
+type Benchmark struct {
+	BuyAndHoldReturn float64 // バイアンドホールドの総リターン（%）
+	StrategyReturn   float64 // 戦略の総リターン（%）
+	Alpha            float64 // 戦略の総リターンからバイアンドホールドの総リターンを引いた差（ポイント）
+	Correlation      float64 // ローソク足ごとの戦略とバイアンドホールドの資産変化率の相関係数（-1～1）
+}
+
+// BuyAndHoldEquity は最初のローソク足の終値で initialBalance 分を買い、各ローソク足の終値で評価した資産の推移を返します。
+// ローソク足がない場合、最初の終値が正でない場合、initialBalance が正でない場合は nil を返します。
+func BuyAndHoldEquity(candles []models.Candle, initialBalance float64) []float64 {
+	if len(candles) == 0 || initialBalance <= 0 || candles[0].Close <= 0 {
+		return nil
+	}
+
+	units := initialBalance / candles[0].Close
+	equity := make([]float64, len(candles))
+	for i, candle := range candles {
+		equity[i] = units * candle.Close
+	}
+	return equity
+}
+
+// BuyAndHoldReturn は最初のローソク足の終値で initialBalance 分を買い、最後のローソク足の終値まで保有した場合の総リターン（%）を返します。
+// 計算できない場合（BuyAndHoldEquity が nil の場合）は 0 を返します。
+func BuyAndHoldReturn(candles []models.Candle, initialBalance float64) float64 {
+	equity := BuyAndHoldEquity(candles, initialBalance)
+	if equity == nil {
+		return 0
+	}
+	return (equity[len(equity)-1] - initialBalance) / initialBalance * 100
+}
+
+// StrategyEquity は各ローソク足の時刻までに決済された取引の損益を initialBalance に加えた資産の推移を返します。
+// 決済ベースのため、保有中のポジションの含み損益は含みません。
+func StrategyEquity(trades []*models.Trade, candles []models.Candle, initialBalance float64) []float64 {
+	closed := closedByCloseTime(trades)
+
+	equity := make([]float64, len(candles))
+	balance := initialBalance
+	next := 0
+	for i, candle := range candles {
+		for next < len(closed) && !closed[next].CloseTime.After(candle.Timestamp) {
+			balance += closed[next].PnL
+			next++
+		}
+		equity[i] = balance
+	}
+	return equity
+}
+
+// CompareWithBenchmark は取引履歴から計算した戦略の成績を、同じローソク足のバイアンドホールドと比較します。
+// 戦略の総リターンは BacktestResult.TotalReturn と同じく全取引の損益の合計から計算します。
+func CompareWithBenchmark(trades []*models.Trade, candles []models.Candle, initialBalance float64) Benchmark {
+	closed := closedByCloseTime(trades)
+	accumulator := NewBenchmarkAccumulator(initialBalance)
+	for _, candle := range candles {
+		accumulator.Add(candle, closed)
+	}
+	accumulator.commit(closed)
+	return accumulator.Benchmark(trades)
+}
+
+// closedByCloseTime は決済済みの取引を決済時刻順に並べて返します。
+func closedByCloseTime(trades []*models.Trade) []*models.Trade {
+	closed := make([]*models.Trade, 0, len(trades))
+	for _, trade := range trades {
+		if trade.Status == models.TradeClosed {
+			closed = append(closed, trade)
+		}
+	}
+	sort.SliceStable(closed, func(i, j int) bool {
+		return closed[i].CloseTime.Before(closed[j].CloseTime)
+	})
+	return closed
+}
+
+// BenchmarkAccumulator はローソク足を1本ずつ受け取り、ローソク足を保持せずに CompareWithBenchmark と同じ比較を計算します。
+// 最初と直前の終値・資産と相関係数の途中結果だけを持つため、メモリ使用量はローソク足の本数に依存しません。
+type BenchmarkAccumulator struct {
+	initialBalance float64
+	bars           int
+	firstTime      time.Time
+	lastTime       time.Time
+	firstClose     float64
+	lastClose      float64
+
+	// 資産を確定したローソク足の本数。最後のローソク足は、その時刻に決済される取引が出揃うまで確定しない
+	committed    int
+	realized     float64 // 確定したローソク足の時刻までに決済された取引の損益の合計
+	next         int     // 次に確認する取引のインデックス
+	prevStrategy float64
+	prevHold     float64
+
+	// 資産の変化率の相関係数の途中結果（Welford 法）
+	returns   int
+	meanA     float64
+	meanB     float64
+	covariance float64
+	varianceA float64
+	varianceB float64
+}
+
+// NewBenchmarkAccumulator は initialBalance 分を運用した場合の比較を計算する BenchmarkAccumulator を作成します。
+func NewBenchmarkAccumulator(initialBalance float64) *BenchmarkAccumulator {
+	return &BenchmarkAccumulator{initialBalance: initialBalance}
+}
+
+// Add はローソク足を1本追加します。trades はその時点までの取引履歴で、決済時刻順に並んでいる必要があります
+// （Backtester.GetTradeHistory はこの順です）。直前のローソク足の資産は、その時刻までに決済された取引で確定します。
+func (a *BenchmarkAccumulator) Add(candle models.Candle, trades []*models.Trade) {
+	a.commit(trades)
+	if a.bars == 0 {
+		a.firstTime = candle.Timestamp
+		a.firstClose = candle.Close
+	}
+	a.bars++
+	a.lastTime = candle.Timestamp
+	a.lastClose = candle.Close
+}
+
+// Bars は追加したローソク足の本数を返します。
+func (a *BenchmarkAccumulator) Bars() int {
+	return a.bars
+}
+
+// Period は最初と最後のローソク足の時刻を返します。
+func (a *BenchmarkAccumulator) Period() (time.Time, time.Time) {
+	return a.firstTime, a.lastTime
+}
+
+// Benchmark は最後のローソク足の資産を trades（全取引履歴、決済時刻順）で確定し、バイアンドホールドとの比較を返します。
+func (a *BenchmarkAccumulator) Benchmark(trades []*models.Trade) Benchmark {
+	a.commit(trades)
+
+	var benchmark Benchmark
+	if a.holdValid() {
+		units := a.initialBalance / a.firstClose
+		benchmark.BuyAndHoldReturn = (units*a.lastClose - a.initialBalance) / a.initialBalance * 100
+	}
+	if a.initialBalance > 0 {
+		benchmark.StrategyReturn = NewCalculator(trades).CalculateTotalPnL() / a.initialBalance * 100
+	}
+	benchmark.Alpha = benchmark.StrategyReturn - benchmark.BuyAndHoldReturn
+
+	if a.holdValid() && a.returns >= 2 && a.varianceA != 0 && a.varianceB != 0 {
+		benchmark.Correlation = a.covariance / math.Sqrt(a.varianceA*a.varianceB)
+	}
+	return benchmark
+}
+
+// holdValid はバイアンドホールドの資産を計算できるか（BuyAndHoldEquity が nil でないか）を返します。
+func (a *BenchmarkAccumulator) holdValid() bool {
+	return a.bars > 0 && a.initialBalance > 0 && a.firstClose > 0
+}
+
+// commit は最後に追加したローソク足の資産を、その時刻までに決済された取引で確定し、直前からの変化率を相関係数に加えます。
+func (a *BenchmarkAccumulator) commit(trades []*models.Trade) {
+	if a.committed == a.bars {
+		return
+	}
+	for a.next < len(trades) && !trades[a.next].CloseTime.After(a.lastTime) {
+		if trades[a.next].Status == models.TradeClosed {
+			a.realized += trades[a.next].PnL
+		}
+		a.next++
+	}
+	a.committed++
+	if !a.holdValid() {
+		return
+	}
+
+	strategy := a.initialBalance + a.realized
+	hold := a.initialBalance / a.firstClose * a.lastClose
+	if a.committed > 1 {
+		a.addReturns(periodReturn(a.prevStrategy, strategy), periodReturn(a.prevHold, hold))
+	}
+	a.prevStrategy, a.prevHold = strategy, hold
+}
+
+// addReturns は戦略とバイアンドホールドの変化率の組を1つ加えます。
+func (a *BenchmarkAccumulator) addReturns(strategy, hold float64) {
+	a.returns++
+	n := float64(a.returns)
+	deltaA := strategy - a.meanA
+	a.meanA += deltaA / n
+	deltaB := hold - a.meanB
+	a.meanB += deltaB / n
+	a.covariance += deltaA * (hold - a.meanB)
+	a.varianceA += deltaA * (strategy - a.meanA)
+	a.varianceB += deltaB * (hold - a.meanB)
+}
+
+// periodReturn は資産が previous から current に変化した変化率を返します。previous が0の場合は0です。
+func periodReturn(previous, current float64) float64 {
+	if previous == 0 {
+		return 0
+	}
+	return (current - previous) / previous
+}
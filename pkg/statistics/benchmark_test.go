@@ -0,0 +1,256 @@
+package statistics
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// createBenchmarkCandles は1時間ごとの終値からローソク足を作成します。
+func createBenchmarkCandles(baseTime time.Time, closes ...float64) []models.Candle {
+	candles := make([]models.Candle, 0, len(closes))
+	for i, price := range closes {
+		candles = append(candles, *models.NewCandle(baseTime.Add(time.Duration(i)*time.Hour), price, price, price, price, 1000))
+	}
+	return candles
+}
+
+// BuyAndHoldReturn テスト
+func TestBuyAndHoldReturn(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	
+	t.Run("should return percentage change from first to last close", func(t *testing.T) {
+		candles := createBenchmarkCandles(baseTime, 100, 110, 90, 120)
+		if got := BuyAndHoldReturn(candles, 10000); math.Abs(got-20.0) > 1e-9 {
+			t.Errorf("Expected 20%%, got %f", got)
+		}
+		
+		equity := BuyAndHoldEquity(candles, 10000)
+		expected := []float64{10000, 11000, 9000, 12000}
+		for i := range expected {
+			if math.Abs(equity[i]-expected[i]) > 1e-9 {
+				t.Errorf("Expected equity[%d] %f, got %f", i, expected[i], equity[i])
+			}
+		}
+	})
+	
+	t.Run("should return zero when it cannot be calculated", func(t *testing.T) {
+		if got := BuyAndHoldReturn(nil, 10000); got != 0 {
+			t.Errorf("Expected 0 for no candles, got %f", got)
+		}
+		if got := BuyAndHoldReturn(createBenchmarkCandles(baseTime, 100, 110), 0); got != 0 {
+			t.Errorf("Expected 0 for zero balance, got %f", got)
+		}
+		if got := BuyAndHoldReturn(createBenchmarkCandles(baseTime, 0, 110), 10000); got != 0 {
+			t.Errorf("Expected 0 for zero first close, got %f", got)
+		}
+	})
+}
+
+// CompareWithBenchmark テスト
+func TestCompareWithBenchmark(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := createBenchmarkCandles(baseTime, 100, 110, 105, 120)
+	
+	t.Run("should calculate alpha against buy and hold", func(t *testing.T) {
+		// 上昇相場で 5% しか稼げなかった戦略はバイアンドホールドに 15 ポイント劣る
+		trades := []*models.Trade{createTrade("bench-1", 500.0, baseTime)}
+		benchmark := CompareWithBenchmark(trades, candles, 10000)
+		
+		if math.Abs(benchmark.BuyAndHoldReturn-20.0) > 1e-9 {
+			t.Errorf("Expected buy and hold return 20%%, got %f", benchmark.BuyAndHoldReturn)
+		}
+		if math.Abs(benchmark.StrategyReturn-5.0) > 1e-9 {
+			t.Errorf("Expected strategy return 5%%, got %f", benchmark.StrategyReturn)
+		}
+		if math.Abs(benchmark.Alpha+15.0) > 1e-9 {
+			t.Errorf("Expected alpha -15, got %f", benchmark.Alpha)
+		}
+	})
+	
+	t.Run("should correlate bar returns", func(t *testing.T) {
+		// 相場と同じ向きに損益が出る戦略は正、逆向きなら負の相関になる
+		units := 100.0
+		following := []*models.Trade{
+			createTrade("follow-1", 10*units, baseTime),
+			createTrade("follow-2", -5*units, baseTime.Add(time.Hour)),
+			createTrade("follow-3", 15*units, baseTime.Add(2*time.Hour)),
+		}
+		if got := CompareWithBenchmark(following, candles, 10000).Correlation; got < 0.9 {
+			t.Errorf("Expected strong positive correlation, got %f", got)
+		}
+		
+		opposing := []*models.Trade{
+			createTrade("oppose-1", -10*units, baseTime),
+			createTrade("oppose-2", 5*units, baseTime.Add(time.Hour)),
+			createTrade("oppose-3", -15*units, baseTime.Add(2*time.Hour)),
+		}
+		if got := CompareWithBenchmark(opposing, candles, 10000).Correlation; got > -0.9 {
+			t.Errorf("Expected strong negative correlation, got %f", got)
+		}
+		
+		// 取引がなく資産が変化しない場合は相関を定義できないため 0
+		if got := CompareWithBenchmark(nil, candles, 10000).Correlation; got != 0 {
+			t.Errorf("Expected zero correlation without trades, got %f", got)
+		}
+	})
+	
+	t.Run("should build realized equity at candle times", func(t *testing.T) {
+		trades := []*models.Trade{
+			createTrade("equity-2", -50.0, baseTime.Add(time.Hour)),
+			createTrade("equity-1", 100.0, baseTime),
+		}
+		equity := StrategyEquity(trades, candles, 1000)
+		expected := []float64{1000, 1100, 1050, 1050}
+		for i := range expected {
+			if math.Abs(equity[i]-expected[i]) > 1e-9 {
+				t.Errorf("Expected equity[%d] %f, got %f", i, expected[i], equity[i])
+			}
+		}
+	})
+}
+
+// BenchmarkAccumulator テスト
+func TestBenchmarkAccumulator(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := createBenchmarkCandles(baseTime, 100, 110, 105, 120, 118)
+	
+	// 決済時刻は各取引の OpenTime の1時間後（ローソク足の時刻と一致する）
+	trades := []*models.Trade{
+		createTrade("acc-1", 300.0, baseTime),
+		createTrade("acc-2", -100.0, baseTime.Add(time.Hour)),
+		createTrade("acc-3", 700.0, baseTime.Add(2*time.Hour)),
+		createTrade("acc-4", -200.0, baseTime.Add(3*time.Hour)), // 最後のローソク足の時刻に決済
+	}
+	expected := CompareWithBenchmark(trades, candles, 10000)
+	
+	t.Run("should match the correlation of the full equity curves", func(t *testing.T) {
+		// 資産の推移全体から2パスで計算した相関係数と一致する
+		strategy := StrategyEquity(trades, candles, 10000)
+		hold := BuyAndHoldEquity(candles, 10000)
+		var a, b []float64
+		for i := 1; i < len(candles); i++ {
+			a = append(a, (strategy[i]-strategy[i-1])/strategy[i-1])
+			b = append(b, (hold[i]-hold[i-1])/hold[i-1])
+		}
+		meanA, meanB := meanOf(a), meanOf(b)
+		var covariance, varianceA, varianceB float64
+		for i := range a {
+			covariance += (a[i] - meanA) * (b[i] - meanB)
+			varianceA += (a[i] - meanA) * (a[i] - meanA)
+			varianceB += (b[i] - meanB) * (b[i] - meanB)
+		}
+		want := covariance / math.Sqrt(varianceA*varianceB)
+		if math.Abs(expected.Correlation-want) > 1e-12 {
+			t.Errorf("Expected correlation %f, got %f", want, expected.Correlation)
+		}
+	})
+	
+	t.Run("should match CompareWithBenchmark when trades close during the run", func(t *testing.T) {
+		// ローソク足を追加する時点では、その時刻より前に決済された取引だけが履歴にある
+		accumulator := NewBenchmarkAccumulator(10000)
+		history := []*models.Trade{}
+		for _, candle := range candles {
+			for _, trade := range trades[len(history):] {
+				if trade.CloseTime.Before(candle.Timestamp) {
+					history = append(history, trade)
+				}
+			}
+			accumulator.Add(candle, history)
+		}
+		
+		// 最後のローソク足の時刻に決済された取引は Benchmark に渡す全履歴で反映される
+		got := accumulator.Benchmark(trades)
+		if math.Abs(got.BuyAndHoldReturn-expected.BuyAndHoldReturn) > 1e-12 || math.Abs(got.StrategyReturn-expected.StrategyReturn) > 1e-12 {
+			t.Errorf("Expected returns %+v, got %+v", expected, got)
+		}
+		if math.Abs(got.Correlation-expected.Correlation) > 1e-12 || expected.Correlation == 0 {
+			t.Errorf("Expected correlation %f, got %f", expected.Correlation, got.Correlation)
+		}
+		
+		// 複数回呼び出しても同じ結果になる
+		if again := accumulator.Benchmark(trades); again != got {
+			t.Errorf("Expected repeated Benchmark to return %+v, got %+v", got, again)
+		}
+	})
+	
+	t.Run("should report bars and period", func(t *testing.T) {
+		accumulator := NewBenchmarkAccumulator(10000)
+		for _, candle := range candles {
+			accumulator.Add(candle, nil)
+		}
+		first, last := accumulator.Period()
+		if accumulator.Bars() != 5 || !first.Equal(baseTime) || !last.Equal(baseTime.Add(4*time.Hour)) {
+			t.Errorf("Expected 5 bars from %v to %v, got %d from %v to %v", baseTime, baseTime.Add(4*time.Hour), accumulator.Bars(), first, last)
+		}
+	})
+	
+	t.Run("should return zero values without candles", func(t *testing.T) {
+		if got := NewBenchmarkAccumulator(10000).Benchmark(nil); got != (Benchmark{}) {
+			t.Errorf("Expected zero benchmark, got %+v", got)
+		}
+	})
+}
+
+// Report SetBenchmark テスト
+func TestReport_Benchmark(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := createBenchmarkCandles(baseTime, 100, 110, 105, 120)
+	report := NewReport([]*models.Trade{createTrade("bench-1", 500.0, baseTime)}, 10000)
+	
+	// 設定しない場合は掲載しない
+	if strings.Contains(report.GenerateTextReport(), "ベンチマーク比較") {
+		t.Error("Expected no benchmark section without candles")
+	}
+	if strings.Contains(report.GenerateJSONReport(), "benchmark") {
+		t.Error("Expected no benchmark in JSON without candles")
+	}
+	
+	report.SetBenchmark(candles)
+	text := report.GenerateTextReport()
+	for _, expected := range []string{"【ベンチマーク比較】", "バイアンドホールド・リターン: 20.00%", "戦略リターン: 5.00%", "アルファ: -15.00%", "相関係数: "} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected text report to contain %q", expected)
+		}
+	}
+	
+	var parsed JSONReport
+	if err := json.Unmarshal([]byte(report.GenerateJSONReport()), &parsed); err != nil {
+		t.Fatalf("Failed to parse JSON report: %v", err)
+	}
+	if parsed.Benchmark == nil {
+		t.Fatal("Expected benchmark in JSON report")
+	}
+	if math.Abs(float64(parsed.Benchmark.Alpha)+15.0) > 1e-9 {
+		t.Errorf("Expected alpha -15 in JSON, got %f", float64(parsed.Benchmark.Alpha))
+	}
+	
+	if !strings.Contains(report.GenerateHTMLReport(), "ベンチマーク比較") {
+		t.Error("Expected benchmark table in HTML report")
+	}
+	
+	// 空のローソク足で解除できる
+	report.SetBenchmark(nil)
+	if strings.Contains(report.GenerateTextReport(), "ベンチマーク比較") {
+		t.Error("Expected benchmark section to be removed")
+	}
+	
+	// 計算済みの比較も掲載でき、nil で解除できる
+	accumulator := NewBenchmarkAccumulator(10000)
+	for _, candle := range candles {
+		accumulator.Add(candle, nil)
+	}
+	benchmark := accumulator.Benchmark([]*models.Trade{createTrade("bench-1", 500.0, baseTime)})
+	report.SetBenchmarkResult(&benchmark)
+	if !strings.Contains(report.GenerateTextReport(), "アルファ: -15.00%") {
+		t.Error("Expected benchmark result in text report")
+	}
+	report.SetBenchmarkResult(nil)
+	if strings.Contains(report.GenerateTextReport(), "ベンチマーク比較") {
+		t.Error("Expected benchmark result to be removed")
+	}
+}
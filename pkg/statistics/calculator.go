@@ -2,14 +2,26 @@ package statistics
 
 import (
 	"math"
+	"sort"
 	"time"
 
 	"github.com/RuiHirano/fx-backtesting/pkg/models"
 )
 
+// ReturnMode はリスク調整指標の計算に使用するリターンの種類を表します。
+type ReturnMode int
+
+const (
+	ReturnModePnL    ReturnMode = iota // 取引ごとの損益額
+	ReturnModeEquity                   // 取引時点の資産に対する損益率
+)
+
 // Calculator は統計計算機能を提供します。
 type Calculator struct {
-	trades []*models.Trade
+	trades         []*models.Trade
+	returnMode     ReturnMode
+	initialBalance float64
+	calendar       *models.TradingCalendar
 }
 
 // NewCalculator は新しいCalculatorを作成します。
@@ -27,6 +39,65 @@ func NewCalculator(trades []*models.Trade) *Calculator {
 	}
 }
 
+// NewEquityReturnCalculator は資産ベースのリターンでシャープレシオ・ソルティノレシオを計算するCalculatorを作成します。
+// 各取引のリターンは初期残高と直前までの累積損益から求めた資産額に対する損益率です。
+func NewEquityReturnCalculator(trades []*models.Trade, initialBalance float64) *Calculator {
+	calculator := NewCalculator(trades)
+	calculator.returnMode = ReturnModeEquity
+	calculator.initialBalance = initialBalance
+	return calculator
+}
+
+// SetCalendar は保有期間・取引期間・年率換算に使う取引カレンダーを設定します。
+// 設定すると週末・休場日の時間を除いて期間を計算します。nil の場合は暦どおりの時間を使います。
+func (c *Calculator) SetCalendar(calendar *models.TradingCalendar) {
+	c.calendar = calendar
+}
+
+// holdingPeriod は取引の保有期間を返します。取引カレンダーが設定されている場合は取引日の時間のみを数えます。
+func (c *Calculator) holdingPeriod(trade *models.Trade) time.Duration {
+	if c.calendar == nil {
+		return trade.Duration
+	}
+	return c.calendar.TradingDuration(trade.OpenTime, trade.CloseTime)
+}
+
+// GetReturnMode は現在のリターン計算モードを返します。
+func (c *Calculator) GetReturnMode() ReturnMode {
+	return c.returnMode
+}
+
+// tradeReturns はリターン計算モードに応じた取引ごとのリターンを返します。
+func (c *Calculator) tradeReturns() []float64 {
+	returns := make([]float64, len(c.trades))
+	equity := c.initialBalance
+	for i, trade := range c.trades {
+		switch c.returnMode {
+		case ReturnModeEquity:
+			// 資産が0以下の場合は損益率を定義できないため0とする
+			if equity > 0 {
+				returns[i] = trade.PnL / equity
+			}
+			equity += trade.PnL
+		default:
+			returns[i] = trade.PnL
+		}
+	}
+	return returns
+}
+
+// meanOf は値の平均を返します。
+func meanOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
 // GetTrades は取引履歴を取得します。
 func (c *Calculator) GetTrades() []*models.Trade {
 	return c.trades
@@ -107,6 +178,46 @@ func (c *Calculator) CalculateAverageLoss() float64 {
 	return totalLoss / float64(lossCount)
 }
 
+// CalculateAverageWinPips は勝ち取引の平均利益をpips単位で計算します。
+// pipサイズが設定されていない取引は対象外です。
+func (c *Calculator) CalculateAverageWinPips() float64 {
+	var totalPips float64
+	var count int
+	
+	for _, trade := range c.trades {
+		if trade.PipSize > 0 && trade.IsWinning() {
+			totalPips += trade.PnLPips()
+			count++
+		}
+	}
+	
+	if count == 0 {
+		return 0.0
+	}
+	
+	return totalPips / float64(count)
+}
+
+// CalculateAverageLossPips は負け取引の平均損失をpips単位で計算します（CalculateAverageLoss と同じく正の値）。
+// pipサイズが設定されていない取引は対象外です。
+func (c *Calculator) CalculateAverageLossPips() float64 {
+	var totalPips float64
+	var count int
+	
+	for _, trade := range c.trades {
+		if trade.PipSize > 0 && trade.IsLosing() {
+			totalPips += -trade.PnLPips() // 絶対値として計算
+			count++
+		}
+	}
+	
+	if count == 0 {
+		return 0.0
+	}
+	
+	return totalPips / float64(count)
+}
+
 // CalculateMaxProfit は最大利益を計算します。
 func (c *Calculator) CalculateMaxProfit() float64 {
 	if len(c.trades) == 0 {
@@ -139,6 +250,84 @@ func (c *Calculator) CalculateMaxLoss() float64 {
 	return maxLoss
 }
 
+// GetBestTrade は損益が最大の取引を返します。取引がない場合はnilを返します。
+func (c *Calculator) GetBestTrade() *models.Trade {
+	var best *models.Trade
+	for _, trade := range c.trades {
+		if best == nil || trade.PnL > best.PnL {
+			best = trade
+		}
+	}
+	
+	return best
+}
+
+// GetWorstTrade は損益が最小の取引を返します。取引がない場合はnilを返します。
+func (c *Calculator) GetWorstTrade() *models.Trade {
+	var worst *models.Trade
+	for _, trade := range c.trades {
+		if worst == nil || trade.PnL < worst.PnL {
+			worst = trade
+		}
+	}
+	
+	return worst
+}
+
+// CalculateTopContributors は損益の絶対値が大きい順に、総損益への寄与が大きいn件の取引を返します。
+// 利益・損失の両方向を対象とし、絶対値が同じ場合は元の取引順を維持します。
+func (c *Calculator) CalculateTopContributors(n int) []*models.Trade {
+	if n <= 0 {
+		return []*models.Trade{}
+	}
+	
+	ranked := make([]*models.Trade, len(c.trades))
+	copy(ranked, c.trades)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return math.Abs(ranked[i].PnL) > math.Abs(ranked[j].PnL)
+	})
+	
+	if n < len(ranked) {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+// CalculatePnLShare は取引損益の総損益に対する割合（%）を計算します。総損益が0の場合は0を返します。
+func (c *Calculator) CalculatePnLShare(trade *models.Trade) float64 {
+	totalPnL := c.CalculateTotalPnL()
+	if trade == nil || totalPnL == 0 {
+		return 0.0
+	}
+	return trade.PnL / totalPnL * 100
+}
+
+// CalculateBySymbol は取引をシンボルごとに分割し、シンボル別のメトリクスセットを計算します。
+func (c *Calculator) CalculateBySymbol() map[string]*MetricsSet {
+	result := make(map[string]*MetricsSet)
+	for symbol, calculator := range c.symbolCalculators() {
+		result[symbol] = GenerateMetricsFromCalculator(calculator)
+	}
+	
+	return result
+}
+
+// symbolCalculators はシンボルごとの取引からCalculatorを作成します。
+func (c *Calculator) symbolCalculators() map[string]*Calculator {
+	grouped := make(map[string][]*models.Trade)
+	for _, trade := range c.trades {
+		grouped[trade.Symbol] = append(grouped[trade.Symbol], trade)
+	}
+	
+	calculators := make(map[string]*Calculator, len(grouped))
+	for symbol, trades := range grouped {
+		calculators[symbol] = NewCalculator(trades)
+		calculators[symbol].calendar = c.calendar
+	}
+	
+	return calculators
+}
+
 // CalculateTotalTrades は取引回数を計算します。
 func (c *Calculator) CalculateTotalTrades() int {
 	return len(c.trades)
@@ -172,6 +361,66 @@ func (c *Calculator) CalculateMaxDrawdown() float64 {
 	return maxDrawdown
 }
 
+// DrawdownPeriod は累積損益が直前の高値を下回ってから回復するまでのドローダウン区間を表します。
+type DrawdownPeriod struct {
+	StartTime    time.Time     `json:"start_time"`    // 高値を付けた時刻（最初の取引前が高値の場合はそのエントリー時刻）
+	TroughTime   time.Time     `json:"trough_time"`   // 最も深く下落した取引の決済時刻
+	RecoveryTime time.Time     `json:"recovery_time"` // 高値を回復した取引の決済時刻（未回復の場合はゼロ値）
+	Depth        float64       `json:"depth"`         // 高値からの最大下落額
+	Duration     time.Duration `json:"duration"`      // 開始から回復まで（未回復の場合は最後の取引の決済時刻まで）の期間
+	Recovered    bool          `json:"recovered"`
+}
+
+// DrawdownPeriods は累積損益のエクイティカーブから、下落額が threshold 以上のドローダウン区間を時系列順に列挙します。
+// 最大ドローダウンと同じく取引順の累積損益で判定し、threshold が0以下の場合は全ての区間を返します。
+func (c *Calculator) DrawdownPeriods(threshold float64) []DrawdownPeriod {
+	periods := make([]DrawdownPeriod, 0)
+	if len(c.trades) == 0 {
+		return periods
+	}
+	
+	var cumulativePnL, peak float64
+	peakTime := c.trades[0].OpenTime
+	var current *DrawdownPeriod
+	
+	for _, trade := range c.trades {
+		cumulativePnL += trade.PnL
+		
+		// 高値の回復・更新
+		if cumulativePnL >= peak {
+			if current != nil {
+				current.RecoveryTime = trade.CloseTime
+				current.Duration = trade.CloseTime.Sub(current.StartTime)
+				current.Recovered = true
+				if current.Depth >= threshold {
+					periods = append(periods, *current)
+				}
+				current = nil
+			}
+			peak = cumulativePnL
+			peakTime = trade.CloseTime
+			continue
+		}
+		
+		// ドローダウン中
+		if current == nil {
+			current = &DrawdownPeriod{StartTime: peakTime}
+		}
+		if drawdown := peak - cumulativePnL; drawdown > current.Depth {
+			current.Depth = drawdown
+			current.TroughTime = trade.CloseTime
+		}
+	}
+	
+	// 未回復のまま終わった区間
+	if current != nil && current.Depth >= threshold {
+		current.Duration = c.trades[len(c.trades)-1].CloseTime.Sub(current.StartTime)
+		periods = append(periods, *current)
+	}
+	
+	return periods
+}
+
 // CalculateSharpeRatio はシャープレシオを計算します。
 func (c *Calculator) CalculateSharpeRatio() float64 {
 	if len(c.trades) == 0 {
@@ -179,13 +428,10 @@ func (c *Calculator) CalculateSharpeRatio() float64 {
 	}
 	
 	// リターンの計算
-	returns := make([]float64, len(c.trades))
-	for i, trade := range c.trades {
-		returns[i] = trade.PnL
-	}
+	returns := c.tradeReturns()
 	
 	// 平均リターン
-	meanReturn := c.CalculateTotalPnL() / float64(len(c.trades))
+	meanReturn := meanOf(returns)
 	
 	// 標準偏差
 	stdDev := c.calculateStandardDeviation(returns, meanReturn)
@@ -198,6 +444,23 @@ func (c *Calculator) CalculateSharpeRatio() float64 {
 	return meanReturn / stdDev
 }
 
+// CalculateAnnualizedSharpeRatio は取引ごとのシャープレシオを1年あたりの取引数の平方根で年率換算します。
+// 1年あたりの取引数は TradingSpan を取引カレンダーの1年あたりの取引日数（未設定の場合は365日）で割った年数から求めます。
+// 取引が2件未満または取引期間が0の場合は0を返します。
+func (c *Calculator) CalculateAnnualizedSharpeRatio() float64 {
+	if len(c.trades) < 2 {
+		return 0.0
+	}
+	
+	years := c.TradingSpan().Hours() / 24.0 / c.calendar.TradingDaysPerYear()
+	if years <= 0 {
+		return 0.0
+	}
+	
+	tradesPerYear := float64(len(c.trades)) / years
+	return c.CalculateSharpeRatio() * math.Sqrt(tradesPerYear)
+}
+
 // CalculateSortinoRatio はソルティノレシオを計算します。
 func (c *Calculator) CalculateSortinoRatio() float64 {
 	if len(c.trades) == 0 {
@@ -205,10 +468,11 @@ func (c *Calculator) CalculateSortinoRatio() float64 {
 	}
 	
 	// 負のリターンのみを使用して下方偏差を計算
+	returns := c.tradeReturns()
 	var negativeReturns []float64
-	for _, trade := range c.trades {
-		if trade.PnL < 0 {
-			negativeReturns = append(negativeReturns, trade.PnL)
+	for _, r := range returns {
+		if r < 0 {
+			negativeReturns = append(negativeReturns, r)
 		}
 	}
 	
@@ -217,7 +481,7 @@ func (c *Calculator) CalculateSortinoRatio() float64 {
 	}
 	
 	// 平均リターン
-	meanReturn := c.CalculateTotalPnL() / float64(len(c.trades))
+	meanReturn := meanOf(returns)
 	
 	// 下方偏差
 	downwardDev := c.calculateStandardDeviation(negativeReturns, 0)
@@ -229,6 +493,23 @@ func (c *Calculator) CalculateSortinoRatio() float64 {
 	return meanReturn / downwardDev
 }
 
+// CalculateSQN はシステム品質指数（Van Tharp の SQN = √N × 平均リターン / リターンの標準偏差）を計算します。
+// 取引数の異なる戦略を1つのスコアで比較できます。取引が2件未満または標準偏差が0の場合は0を返します。
+func (c *Calculator) CalculateSQN() float64 {
+	if len(c.trades) < 2 {
+		return 0.0
+	}
+	
+	returns := c.tradeReturns()
+	meanReturn := meanOf(returns)
+	stdDev := c.calculateStandardDeviation(returns, meanReturn)
+	if stdDev == 0 {
+		return 0.0
+	}
+	
+	return math.Sqrt(float64(len(returns))) * meanReturn / stdDev
+}
+
 // CalculateReturnRiskRatio はリターン・リスク比を計算します。
 func (c *Calculator) CalculateReturnRiskRatio() float64 {
 	if len(c.trades) == 0 {
@@ -280,6 +561,61 @@ func (c *Calculator) CalculateProfitFactor() float64 {
 	return grossProfit / grossLoss
 }
 
+// CostAnalysis は取引コストの内訳と、コスト控除前後の成績を表します。
+// 取引の PnL はコスト控除後の損益で、コスト控除前の損益は PnL に取引コスト（Trade.TotalCost）を足し戻した値です。
+// スワップは Trade.Swap の合計で、スワップを計上していない取引では0になります。
+type CostAnalysis struct {
+	SpreadCost   float64 `json:"spread_cost"`
+	SlippageCost float64 `json:"slippage_cost"`
+	Commission   float64 `json:"commission"`
+	Swap         float64 `json:"swap"`
+	TotalCost    float64 `json:"total_cost"`
+
+	// コスト控除前（gross）と控除後（net）の成績（勝率は%）
+	GrossPnL          float64 `json:"gross_pnl"`
+	NetPnL            float64 `json:"net_pnl"`
+	GrossProfitFactor float64 `json:"gross_profit_factor"`
+	NetProfitFactor   float64 `json:"net_profit_factor"`
+	GrossWinRate      float64 `json:"gross_win_rate"`
+	NetWinRate        float64 `json:"net_win_rate"`
+
+	// コスト控除前の総損益に対するコストの割合（%）。100%以上ではコストで利益が消えている。
+	// コスト控除前の総損益が0以下の場合は NaN
+	CostPercentOfGrossPnL float64 `json:"cost_percent_of_gross_pnl"`
+}
+
+// CalculateCostAnalysis は取引コストを種類ごとに合計し、コスト控除前後の総損益・プロフィットファクター・勝率を計算します。
+func (c *Calculator) CalculateCostAnalysis() CostAnalysis {
+	analysis := CostAnalysis{}
+	gross := make([]*models.Trade, 0, len(c.trades))
+	for _, trade := range c.trades {
+		analysis.SpreadCost += trade.SpreadCost
+		analysis.SlippageCost += trade.SlippageCost
+		analysis.Commission += trade.Commission
+		analysis.Swap += trade.Swap
+		
+		grossTrade := *trade
+		grossTrade.PnL = trade.PnL + trade.TotalCost()
+		gross = append(gross, &grossTrade)
+	}
+	analysis.TotalCost = analysis.SpreadCost + analysis.SlippageCost + analysis.Commission + analysis.Swap
+	
+	grossCalculator := NewCalculator(gross)
+	analysis.GrossPnL = grossCalculator.CalculateTotalPnL()
+	analysis.NetPnL = c.CalculateTotalPnL()
+	analysis.GrossProfitFactor = grossCalculator.CalculateProfitFactor()
+	analysis.NetProfitFactor = c.CalculateProfitFactor()
+	analysis.GrossWinRate = grossCalculator.CalculateWinRate() * 100
+	analysis.NetWinRate = c.CalculateWinRate() * 100
+	
+	analysis.CostPercentOfGrossPnL = math.NaN()
+	if analysis.GrossPnL > 0 {
+		analysis.CostPercentOfGrossPnL = analysis.TotalCost / analysis.GrossPnL * 100
+	}
+	
+	return analysis
+}
+
 // CalculateExpectedValue は期待値を計算します。
 func (c *Calculator) CalculateExpectedValue() float64 {
 	if len(c.trades) == 0 {
@@ -289,6 +625,52 @@ func (c *Calculator) CalculateExpectedValue() float64 {
 	return c.CalculateTotalPnL() / float64(len(c.trades))
 }
 
+// TradingSpan は最初の取引のエントリーから最後の取引の決済までの期間を返します。
+// 取引カレンダーが設定されている場合は週末・休場日の時間を除きます。
+func (c *Calculator) TradingSpan() time.Duration {
+	if len(c.trades) == 0 {
+		return 0
+	}
+	
+	start, end := c.trades[0].OpenTime, c.trades[0].CloseTime
+	for _, trade := range c.trades[1:] {
+		if trade.OpenTime.Before(start) {
+			start = trade.OpenTime
+		}
+		if trade.CloseTime.After(end) {
+			end = trade.CloseTime
+		}
+	}
+	
+	return c.calendar.TradingDuration(start, end)
+}
+
+// CalculateExpectancyPerDay は期間1日あたりの期待値（総損益 / 日数）を計算します。
+// 取引頻度の異なる戦略を公平に比較するため、取引数ではなく時間で正規化します。
+// period にはバックテストの実行期間を渡し、0以下の場合は TradingSpan を使用します。
+func (c *Calculator) CalculateExpectancyPerDay(period time.Duration) float64 {
+	if period <= 0 {
+		period = c.TradingSpan()
+	}
+	
+	days := period.Hours() / 24.0
+	if days <= 0 {
+		return 0.0
+	}
+	
+	return c.CalculateTotalPnL() / days
+}
+
+// CalculateExpectancyPerBar はローソク足1本あたりの期待値（総損益 / バー数）を計算します。
+// bars が0以下の場合は0を返します。
+func (c *Calculator) CalculateExpectancyPerBar(bars int) float64 {
+	if bars <= 0 {
+		return 0.0
+	}
+	
+	return c.CalculateTotalPnL() / float64(bars)
+}
+
 // CalculateStandardDeviation は標準偏差を計算します。
 func (c *Calculator) CalculateStandardDeviation() float64 {
 	if len(c.trades) == 0 {
@@ -304,7 +686,67 @@ func (c *Calculator) CalculateStandardDeviation() float64 {
 	return c.calculateStandardDeviation(returns, mean)
 }
 
-// CalculateAverageHoldingPeriod は平均保有期間を計算します。
+// DefaultVaRConfidence はメトリクスセットで使用するVaRの信頼水準です。
+const DefaultVaRConfidence = 0.95
+
+// CalculateValueAtRisk は取引損益の分布からヒストリカルVaRを計算します。
+// confidenceは0より大きく1未満の信頼水準で、損失額を正の値として返します。
+func (c *Calculator) CalculateValueAtRisk(confidence float64) float64 {
+	if len(c.trades) == 0 || confidence <= 0 || confidence >= 1 {
+		return 0.0
+	}
+	
+	threshold := c.returnQuantile(1 - confidence)
+	if threshold >= 0 {
+		return 0.0 // 指定水準で損失が発生しない
+	}
+	return -threshold
+}
+
+// CalculateExpectedShortfall はVaRを超える損失の平均（期待ショートフォール）を計算します。
+func (c *Calculator) CalculateExpectedShortfall(confidence float64) float64 {
+	valueAtRisk := c.CalculateValueAtRisk(confidence)
+	if valueAtRisk == 0 {
+		return 0.0
+	}
+	
+	var tailLoss float64
+	tailCount := 0
+	for _, trade := range c.trades {
+		if -trade.PnL >= valueAtRisk {
+			tailLoss += -trade.PnL
+			tailCount++
+		}
+	}
+	
+	// 補間によりVaRを超える取引がない場合はVaR自体を返す
+	if tailCount == 0 {
+		return valueAtRisk
+	}
+	return tailLoss / float64(tailCount)
+}
+
+// returnQuantile は取引損益を昇順に並べ、線形補間で分位点を計算します。
+func (c *Calculator) returnQuantile(q float64) float64 {
+	returns := make([]float64, len(c.trades))
+	for i, trade := range c.trades {
+		returns[i] = trade.PnL
+	}
+	sort.Float64s(returns)
+	
+	if len(returns) == 1 {
+		return returns[0]
+	}
+	
+	position := q * float64(len(returns)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	weight := position - float64(lower)
+	
+	return returns[lower]*(1-weight) + returns[upper]*weight
+}
+
+// CalculateAverageHoldingPeriod は平均保有期間を計算します。取引カレンダーが設定されている場合は週末・休場日を除きます。
 func (c *Calculator) CalculateAverageHoldingPeriod() time.Duration {
 	if len(c.trades) == 0 {
 		return 0
@@ -312,12 +754,70 @@ func (c *Calculator) CalculateAverageHoldingPeriod() time.Duration {
 	
 	var totalDuration time.Duration
 	for _, trade := range c.trades {
-		totalDuration += trade.Duration
+		totalDuration += c.holdingPeriod(trade)
 	}
 	
 	return totalDuration / time.Duration(len(c.trades))
 }
 
+// HoldingPeriodStats は保有期間の分布を表します。
+type HoldingPeriodStats struct {
+	Min               time.Duration `json:"min"`
+	Max               time.Duration `json:"max"`
+	Median            time.Duration `json:"median"`
+	StandardDeviation time.Duration `json:"standard_deviation"`
+
+	// 保有期間帯ごとの取引数
+	UnderOneHour      int `json:"under_one_hour"`        // 1時間未満
+	OneToFourHours    int `json:"one_to_four_hours"`     // 1時間以上4時間未満
+	FourHoursToOneDay int `json:"four_hours_to_one_day"` // 4時間以上1日未満
+	OverOneDay        int `json:"over_one_day"`          // 1日以上
+}
+
+// CalculateHoldingPeriodStats は保有期間の最小・最大・中央値・標準偏差と期間帯別の取引数を計算します。
+func (c *Calculator) CalculateHoldingPeriodStats() HoldingPeriodStats {
+	stats := HoldingPeriodStats{}
+	if len(c.trades) == 0 {
+		return stats
+	}
+	
+	durations := make([]time.Duration, len(c.trades))
+	hours := make([]float64, len(c.trades))
+	for i, trade := range c.trades {
+		duration := c.holdingPeriod(trade)
+		durations[i] = duration
+		hours[i] = duration.Hours()
+		
+		switch {
+		case duration < time.Hour:
+			stats.UnderOneHour++
+		case duration < 4*time.Hour:
+			stats.OneToFourHours++
+		case duration < 24*time.Hour:
+			stats.FourHoursToOneDay++
+		default:
+			stats.OverOneDay++
+		}
+	}
+	
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	stats.Min = durations[0]
+	stats.Max = durations[len(durations)-1]
+	
+	middle := len(durations) / 2
+	if len(durations)%2 == 0 {
+		stats.Median = (durations[middle-1] + durations[middle]) / 2
+	} else {
+		stats.Median = durations[middle]
+	}
+	
+	meanHours := c.CalculateAverageHoldingPeriod().Hours()
+	stdDevHours := c.calculateStandardDeviation(hours, meanHours)
+	stats.StandardDeviation = time.Duration(stdDevHours * float64(time.Hour))
+	
+	return stats
+}
+
 // CalculateMaxConsecutiveWins は最大連勝数を計算します。
 func (c *Calculator) CalculateMaxConsecutiveWins() int {
 	if len(c.trades) == 0 {
@@ -364,7 +864,59 @@ func (c *Calculator) CalculateMaxConsecutiveLosses() int {
 	return maxLosses
 }
 
+// StreakType は連勝・連敗の種別を表します。
+type StreakType string
+
+const (
+	StreakWin  StreakType = "win"  // 連勝
+	StreakLoss StreakType = "loss" // 連敗
+)
+
+// Streak は同じ結果が連続した取引の区間を表します。
+type Streak struct {
+	Type      StreakType `json:"type"`
+	Length    int        `json:"length"`
+	PnL       float64    `json:"pnl"`
+	StartTime time.Time  `json:"start_time"` // 最初の取引のエントリー時刻
+	EndTime   time.Time  `json:"end_time"`   // 最後の取引の決済時刻
+}
+
+// CalculateStreaks は全ての連勝・連敗区間を取引順に列挙します。
+// 損益0の取引はどちらにも含めず、区間の区切りとして扱います。
+func (c *Calculator) CalculateStreaks() []Streak {
+	streaks := make([]Streak, 0)
+	var current *Streak
+	
+	for _, trade := range c.trades {
+		var streakType StreakType
+		switch {
+		case trade.IsWinning():
+			streakType = StreakWin
+		case trade.IsLosing():
+			streakType = StreakLoss
+		default:
+			current = nil
+			continue
+		}
+		
+		if current == nil || current.Type != streakType {
+			streaks = append(streaks, Streak{
+				Type:      streakType,
+				StartTime: trade.OpenTime,
+			})
+			current = &streaks[len(streaks)-1]
+		}
+		
+		current.Length++
+		current.PnL += trade.PnL
+		current.EndTime = trade.CloseTime
+	}
+	
+	return streaks
+}
+
 // CalculateTradingFrequency は取引頻度を計算します（1日あたりの取引数）。
+// 取引カレンダーが設定されている場合は取引日のみを日数に数えます。
 func (c *Calculator) CalculateTradingFrequency() float64 {
 	if len(c.trades) < 2 {
 		return 0.0
@@ -374,7 +926,7 @@ func (c *Calculator) CalculateTradingFrequency() float64 {
 	firstTrade := c.trades[0]
 	lastTrade := c.trades[len(c.trades)-1]
 	
-	duration := lastTrade.OpenTime.Sub(firstTrade.OpenTime)
+	duration := c.calendar.TradingDuration(firstTrade.OpenTime, lastTrade.OpenTime)
 	if duration <= 0 {
 		return 0.0
 	}
@@ -402,6 +954,151 @@ func (c *Calculator) CalculateRiskRewardRatio() float64 {
 	return avgProfit / avgLoss
 }
 
+// positionEvent はポジションのオープン・クローズを時系列で表します。
+type positionEvent struct {
+	time  time.Time
+	delta int
+}
+
+// positionTimeline は取引のオープン・クローズ時刻を時系列順のイベントに変換します。
+// 同時刻ではクローズを先に処理し、入れ替えを重複として数えないようにします。
+func (c *Calculator) positionTimeline() []positionEvent {
+	events := make([]positionEvent, 0, len(c.trades)*2)
+	for _, trade := range c.trades {
+		events = append(events, positionEvent{time: trade.OpenTime, delta: 1})
+		events = append(events, positionEvent{time: trade.CloseTime, delta: -1})
+	}
+	
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].time.Equal(events[j].time) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].time.Before(events[j].time)
+	})
+	
+	return events
+}
+
+// CalculateMaxConcurrentPositions は同時に保有していたポジション数の最大値を計算します。
+func (c *Calculator) CalculateMaxConcurrentPositions() int {
+	current, maxConcurrent := 0, 0
+	for _, event := range c.positionTimeline() {
+		current += event.delta
+		if current > maxConcurrent {
+			maxConcurrent = current
+		}
+	}
+	
+	return maxConcurrent
+}
+
+// CalculateAverageConcurrentPositions は最初のオープンから最後のクローズまでの
+// 時間加重平均の同時保有ポジション数を計算します。
+func (c *Calculator) CalculateAverageConcurrentPositions() float64 {
+	events := c.positionTimeline()
+	if len(events) == 0 {
+		return 0.0
+	}
+	
+	totalSpan := events[len(events)-1].time.Sub(events[0].time)
+	if totalSpan <= 0 {
+		return 0.0
+	}
+	
+	var weighted float64
+	current := 0
+	for i, event := range events {
+		if i > 0 {
+			weighted += float64(current) * event.time.Sub(events[i-1].time).Seconds()
+		}
+		current += event.delta
+	}
+	
+	return weighted / totalSpan.Seconds()
+}
+
+// CalculateKellyFraction はケリー基準による最適な資金投入比率を計算します。
+// 勝率W、平均利益/平均損失の比Rから W - (1-W)/R を求め、優位性がない場合は0を返します。
+func (c *Calculator) CalculateKellyFraction() float64 {
+	if len(c.trades) == 0 {
+		return 0.0
+	}
+	
+	winRate := c.CalculateWinRate()
+	payoffRatio := c.CalculateRiskRewardRatio()
+	if payoffRatio == 0 {
+		return 0.0
+	}
+	
+	// 損失取引がない場合はR=∞となり、比率は勝率に一致する
+	kelly := winRate
+	if !math.IsInf(payoffRatio, 1) {
+		kelly = winRate - (1-winRate)/payoffRatio
+	}
+	
+	if kelly < 0 {
+		return 0.0
+	}
+	return kelly
+}
+
+// CalculateHalfKellyFraction はケリー比率の半分（ハーフケリー）を計算します。
+func (c *Calculator) CalculateHalfKellyFraction() float64 {
+	return c.CalculateKellyFraction() / 2
+}
+
+// RollingSharpe は直近 window 件の取引ごとのシャープレシオを計算します。
+// 戻り値は取引と同じ長さで、i 番目は i-window+1 ～ i 番目の取引から計算した値です。
+// 取引が window 件に満たない先頭の要素は NaN、window が0以下の場合は nil を返します。
+func (c *Calculator) RollingSharpe(window int) []float64 {
+	return c.rolling(window, func(returns []float64) float64 {
+		mean := meanOf(returns)
+		stdDev := c.calculateStandardDeviation(returns, mean)
+		if stdDev == 0 {
+			return 0.0
+		}
+		return mean / stdDev
+	})
+}
+
+// RollingReturn は直近 window 件の取引ごとのリターンを計算します。
+// 損益モードでは window 件の損益の合計、資産モードでは window 件の損益率を複利で合成した値です。
+// 戻り値の長さと先頭の NaN は RollingSharpe と同じです。
+func (c *Calculator) RollingReturn(window int) []float64 {
+	return c.rolling(window, func(returns []float64) float64 {
+		if c.returnMode == ReturnModeEquity {
+			growth := 1.0
+			for _, r := range returns {
+				growth *= 1 + r
+			}
+			return growth - 1
+		}
+		var sum float64
+		for _, r := range returns {
+			sum += r
+		}
+		return sum
+	})
+}
+
+// rolling は取引ごとのリターンを window 件ずつずらしながら metric を適用した系列を返します。
+func (c *Calculator) rolling(window int, metric func(returns []float64) float64) []float64 {
+	if window <= 0 {
+		return nil
+	}
+	
+	returns := c.tradeReturns()
+	series := make([]float64, len(returns))
+	for i := range returns {
+		if i+1 < window {
+			series[i] = math.NaN()
+			continue
+		}
+		series[i] = metric(returns[i+1-window : i+1])
+	}
+	return series
+}
+
 // calculateStandardDeviation は標準偏差を計算するヘルパー関数です。
 func (c *Calculator) calculateStandardDeviation(values []float64, mean float64) float64 {
 	if len(values) <= 1 {
@@ -1,6 +1,8 @@
 package statistics
 
 import (
+	"fmt"
+	"math"
 	"testing"
 	"time"
 
@@ -200,6 +202,791 @@ func TestCalculator_TradingMetrics(t *testing.T) {
 	}
 }
 
+// Calculator ベスト/ワースト取引テスト
+func TestCalculator_BestAndWorstTrade(t *testing.T) {
+	trades := createTestTrades()
+	calculator := NewCalculator(trades)
+	
+	// 最大損益の取引 (trade-3: 200.0)
+	best := calculator.GetBestTrade()
+	if best == nil {
+		t.Fatal("Expected best trade to be returned")
+	}
+	if best != trades[2] {
+		t.Errorf("Expected best trade trade-3, got %s", best.ID)
+	}
+	if best.PnL != calculator.CalculateMaxProfit() {
+		t.Errorf("Expected best trade PnL %f, got %f", calculator.CalculateMaxProfit(), best.PnL)
+	}
+	
+	// 最小損益の取引 (trade-2: -100.0)
+	worst := calculator.GetWorstTrade()
+	if worst == nil {
+		t.Fatal("Expected worst trade to be returned")
+	}
+	if worst != trades[1] {
+		t.Errorf("Expected worst trade trade-2, got %s", worst.ID)
+	}
+	if -worst.PnL != calculator.CalculateMaxLoss() {
+		t.Errorf("Expected worst trade PnL %f, got %f", -calculator.CalculateMaxLoss(), worst.PnL)
+	}
+	
+	// 取引がない場合はnil
+	emptyCalculator := NewCalculator([]*models.Trade{})
+	if emptyCalculator.GetBestTrade() != nil || emptyCalculator.GetWorstTrade() != nil {
+		t.Error("Expected nil best/worst trade for empty trades")
+	}
+}
+
+// Calculator シンボル別統計テスト
+func TestCalculator_CalculateBySymbol(t *testing.T) {
+	baseTime := time.Now()
+	jpyWin := createTrade("trade-3", 300.0, baseTime.Add(2*time.Hour))
+	jpyWin.Symbol = "USDJPY"
+	trades := []*models.Trade{
+		createTrade("trade-1", 100.0, baseTime),
+		createTrade("trade-2", -50.0, baseTime.Add(time.Hour)),
+		jpyWin,
+	}
+	
+	calculator := NewCalculator(trades)
+	bySymbol := calculator.CalculateBySymbol()
+	
+	if len(bySymbol) != 2 {
+		t.Fatalf("Expected 2 symbols, got %d", len(bySymbol))
+	}
+	
+	eurusd := bySymbol["EURUSD"]
+	if eurusd == nil {
+		t.Fatal("Expected EURUSD metrics")
+	}
+	if eurusd.GetMetric(MetricTotalTrades).Value != 2 {
+		t.Errorf("Expected 2 EURUSD trades, got %v", eurusd.GetMetric(MetricTotalTrades).Value)
+	}
+	if eurusd.GetMetric(MetricTotalPnL).Value != 50.0 {
+		t.Errorf("Expected EURUSD PnL 50.0, got %v", eurusd.GetMetric(MetricTotalPnL).Value)
+	}
+	
+	usdjpy := bySymbol["USDJPY"]
+	if usdjpy == nil {
+		t.Fatal("Expected USDJPY metrics")
+	}
+	if usdjpy.GetMetric(MetricTotalPnL).Value != 300.0 {
+		t.Errorf("Expected USDJPY PnL 300.0, got %v", usdjpy.GetMetric(MetricTotalPnL).Value)
+	}
+	if usdjpy.GetMetric(MetricWinRate).Value != 100.0 {
+		t.Errorf("Expected USDJPY win rate 100, got %v", usdjpy.GetMetric(MetricWinRate).Value)
+	}
+	
+	// 取引がない場合は空のマップ
+	if len(NewCalculator([]*models.Trade{}).CalculateBySymbol()) != 0 {
+		t.Error("Expected empty breakdown for empty trades")
+	}
+}
+
+// Calculator VaR・期待ショートフォールテスト
+func TestCalculator_ValueAtRisk(t *testing.T) {
+	// 損益の昇順: -100, -75, -60, 80, 125, 150, 200
+	calculator := NewCalculator(createTestTrades())
+	
+	// 95%: 5%分位点は -100 と -75 の間を0.3で補間した -92.5
+	valueAtRisk := calculator.CalculateValueAtRisk(0.95)
+	if math.Abs(valueAtRisk-92.5) > 1e-9 {
+		t.Errorf("Expected VaR 92.5 at 95%%, got %f", valueAtRisk)
+	}
+	expectedShortfall := calculator.CalculateExpectedShortfall(0.95)
+	if math.Abs(expectedShortfall-100.0) > 1e-9 {
+		t.Errorf("Expected ES 100.0 at 95%%, got %f", expectedShortfall)
+	}
+	
+	// 80%: 20%分位点は -72、VaRを超える損失は -100 と -75
+	valueAtRisk = calculator.CalculateValueAtRisk(0.80)
+	if math.Abs(valueAtRisk-72.0) > 1e-9 {
+		t.Errorf("Expected VaR 72.0 at 80%%, got %f", valueAtRisk)
+	}
+	expectedShortfall = calculator.CalculateExpectedShortfall(0.80)
+	if math.Abs(expectedShortfall-87.5) > 1e-9 {
+		t.Errorf("Expected ES 87.5 at 80%%, got %f", expectedShortfall)
+	}
+	
+	// 少数サンプル: 1取引のみ
+	single := NewCalculator([]*models.Trade{createTrade("trade-1", -50.0, time.Now())})
+	if single.CalculateValueAtRisk(0.95) != 50.0 {
+		t.Errorf("Expected VaR 50.0 for single losing trade, got %f", single.CalculateValueAtRisk(0.95))
+	}
+	if single.CalculateExpectedShortfall(0.95) != 50.0 {
+		t.Errorf("Expected ES 50.0 for single losing trade, got %f", single.CalculateExpectedShortfall(0.95))
+	}
+	
+	// 勝ち取引のみ・不正な信頼水準・取引なしは0
+	winning := NewCalculator([]*models.Trade{createTrade("trade-1", 100.0, time.Now())})
+	if winning.CalculateValueAtRisk(0.95) != 0.0 || winning.CalculateExpectedShortfall(0.95) != 0.0 {
+		t.Error("Expected zero VaR/ES for winning-only trades")
+	}
+	if calculator.CalculateValueAtRisk(1.5) != 0.0 || calculator.CalculateValueAtRisk(0) != 0.0 {
+		t.Error("Expected zero VaR for invalid confidence")
+	}
+	if NewCalculator([]*models.Trade{}).CalculateValueAtRisk(0.95) != 0.0 {
+		t.Error("Expected zero VaR for empty trades")
+	}
+}
+
+// Calculator ケリー基準テスト
+func TestCalculator_KellyFraction(t *testing.T) {
+	calculator := NewCalculator(createTestTrades())
+	
+	// 勝率4/7、平均利益138.75、平均損失78.33
+	winRate := 4.0 / 7.0
+	payoffRatio := 138.75 / (235.0 / 3.0)
+	expected := winRate - (1-winRate)/payoffRatio
+	
+	kelly := calculator.CalculateKellyFraction()
+	if math.Abs(kelly-expected) > 1e-9 {
+		t.Errorf("Expected Kelly fraction %f, got %f", expected, kelly)
+	}
+	if math.Abs(calculator.CalculateHalfKellyFraction()-expected/2) > 1e-9 {
+		t.Errorf("Expected half Kelly fraction %f, got %f", expected/2, calculator.CalculateHalfKellyFraction())
+	}
+	
+	// 優位性がない場合は0
+	baseTime := time.Now()
+	losing := NewCalculator([]*models.Trade{
+		createTrade("trade-1", 50.0, baseTime),
+		createTrade("trade-2", -100.0, baseTime.Add(time.Hour)),
+		createTrade("trade-3", -100.0, baseTime.Add(2*time.Hour)),
+	})
+	if losing.CalculateKellyFraction() != 0.0 {
+		t.Errorf("Expected zero Kelly fraction without edge, got %f", losing.CalculateKellyFraction())
+	}
+	
+	// 損失取引がない場合は勝率と一致
+	winning := NewCalculator([]*models.Trade{createTrade("trade-1", 100.0, baseTime)})
+	if winning.CalculateKellyFraction() != 1.0 {
+		t.Errorf("Expected Kelly fraction 1.0 for winning-only trades, got %f", winning.CalculateKellyFraction())
+	}
+	
+	// 取引なし
+	if NewCalculator([]*models.Trade{}).CalculateKellyFraction() != 0.0 {
+		t.Error("Expected zero Kelly fraction for empty trades")
+	}
+}
+
+// Calculator SQN テスト
+func TestCalculator_SQN(t *testing.T) {
+	baseTime := time.Now()
+	
+	// 損益 1, 2, 3: 平均2、標準偏差1
+	calculator := NewCalculator([]*models.Trade{
+		createTrade("trade-1", 1.0, baseTime),
+		createTrade("trade-2", 2.0, baseTime.Add(time.Hour)),
+		createTrade("trade-3", 3.0, baseTime.Add(2*time.Hour)),
+	})
+	if got := calculator.CalculateSQN(); math.Abs(got-math.Sqrt(3)*2) > 1e-9 {
+		t.Errorf("Expected SQN %f, got %f", math.Sqrt(3)*2, got)
+	}
+	
+	// シャープレシオに取引数の平方根を掛けた値と一致
+	testCalculator := NewCalculator(createTestTrades())
+	expected := math.Sqrt(7) * testCalculator.CalculateSharpeRatio()
+	if got := testCalculator.CalculateSQN(); math.Abs(got-expected) > 1e-9 {
+		t.Errorf("Expected SQN %f, got %f", expected, got)
+	}
+	
+	// 取引1件、標準偏差0、取引なしの場合は0
+	single := NewCalculator([]*models.Trade{createTrade("trade-1", 100.0, baseTime)})
+	flat := NewCalculator([]*models.Trade{
+		createTrade("trade-1", 100.0, baseTime),
+		createTrade("trade-2", 100.0, baseTime.Add(time.Hour)),
+	})
+	for name, c := range map[string]*Calculator{"single": single, "flat": flat, "empty": NewCalculator([]*models.Trade{})} {
+		if got := c.CalculateSQN(); got != 0.0 {
+			t.Errorf("Expected zero SQN for %s trades, got %f", name, got)
+		}
+	}
+	
+	// メトリクスとして公開
+	metric := GenerateMetricsFromCalculator(testCalculator).GetMetric(MetricSQN)
+	if metric == nil || metric.Name != "SQN" || metric.Value != expected {
+		t.Errorf("Expected SQN metric with value %f, got %+v", expected, metric)
+	}
+}
+
+// Calculator pips単位の平均損益テスト
+func TestCalculator_PipStats(t *testing.T) {
+	// createTrade の取引は損益1.0あたり1pips（EURUSD、数量10000）
+	trades := createTestTrades()
+	for _, trade := range trades {
+		trade.PipSize = 0.0001
+	}
+	calculator := NewCalculator(trades)
+	if got := calculator.CalculateAverageWinPips(); math.Abs(got-138.75) > 1e-6 {
+		t.Errorf("Expected average win 138.75 pips, got %f", got)
+	}
+	if got := calculator.CalculateAverageLossPips(); math.Abs(got-235.0/3.0) > 1e-6 {
+		t.Errorf("Expected average loss %f pips, got %f", 235.0/3.0, got)
+	}
+	
+	// pipサイズのない取引は対象外
+	baseTime := time.Now()
+	usdjpy := &models.Trade{
+		ID:         "trade-jpy",
+		Symbol:     "USDJPY",
+		Side:       models.Sell,
+		Size:       1.0,
+		EntryPrice: 150.00,
+		ExitPrice:  149.60,
+		PnL:        400.0,
+		PipSize:    0.01,
+		Status:     models.TradeClosed,
+		OpenTime:   baseTime,
+		CloseTime:  baseTime.Add(time.Hour),
+	}
+	mixed := NewCalculator([]*models.Trade{usdjpy, createTrade("trade-raw", 500.0, baseTime)})
+	if got := mixed.CalculateAverageWinPips(); math.Abs(got-40.0) > 1e-6 {
+		t.Errorf("Expected average win 40 pips, got %f", got)
+	}
+	
+	// 該当する取引がない場合は0
+	if got := NewCalculator(createTestTrades()).CalculateAverageLossPips(); got != 0.0 {
+		t.Errorf("Expected zero pips without pip size, got %f", got)
+	}
+}
+
+// Calculator 時間あたり期待値テスト
+func TestCalculator_ExpectancyPerTime(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 各取引は1時間保有、最初のエントリーから最後の決済まで48時間
+	calculator := NewCalculator([]*models.Trade{
+		createTrade("trade-1", 300.0, baseTime),
+		createTrade("trade-2", -100.0, baseTime.Add(24*time.Hour)),
+		createTrade("trade-3", 100.0, baseTime.Add(47*time.Hour)),
+	})
+	
+	if span := calculator.TradingSpan(); span != 48*time.Hour {
+		t.Errorf("Expected trading span 48h, got %v", span)
+	}
+	
+	// 実行期間を指定しない場合は取引期間で正規化
+	if got := calculator.CalculateExpectancyPerDay(0); math.Abs(got-150.0) > 1e-9 {
+		t.Errorf("Expected expectancy per day 150, got %f", got)
+	}
+	// 実行期間が長ければ1日あたりの期待値は小さくなる
+	if got := calculator.CalculateExpectancyPerDay(4 * 24 * time.Hour); math.Abs(got-75.0) > 1e-9 {
+		t.Errorf("Expected expectancy per day 75, got %f", got)
+	}
+	
+	if got := calculator.CalculateExpectancyPerBar(100); math.Abs(got-3.0) > 1e-9 {
+		t.Errorf("Expected expectancy per bar 3, got %f", got)
+	}
+	if calculator.CalculateExpectancyPerBar(0) != 0.0 {
+		t.Error("Expected zero expectancy per bar without bars")
+	}
+	
+	// 1取引あたりの期待値が低くても高頻度の戦略は1日あたりで上回る
+	rare := NewCalculator([]*models.Trade{createTrade("rare-1", 500.0, baseTime)})
+	frequent := NewCalculator(createTestTrades())
+	if rare.CalculateExpectedValue() <= frequent.CalculateExpectedValue() {
+		t.Fatal("Expected rare strategy to have higher per-trade expectancy")
+	}
+	period := 10 * 24 * time.Hour
+	if rare.CalculateExpectancyPerDay(period) >= NewCalculator(append(createTestTrades(), createTestTrades()...)).CalculateExpectancyPerDay(period) {
+		t.Error("Expected frequent strategy to have higher expectancy per day over the same period")
+	}
+	
+	// 取引なし
+	empty := NewCalculator([]*models.Trade{})
+	if empty.TradingSpan() != 0 || empty.CalculateExpectancyPerDay(0) != 0.0 {
+		t.Error("Expected zero span and expectancy for empty trades")
+	}
+}
+
+// Calculator ドローダウン期間テスト
+func TestCalculator_DrawdownPeriods(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 累積損益: 100, 50, 20, 120, -80, -30
+	calculator := NewCalculator([]*models.Trade{
+		createTrade("trade-1", 100.0, baseTime),
+		createTrade("trade-2", -50.0, baseTime.Add(time.Hour)),
+		createTrade("trade-3", -30.0, baseTime.Add(2*time.Hour)),
+		createTrade("trade-4", 100.0, baseTime.Add(3*time.Hour)),
+		createTrade("trade-5", -200.0, baseTime.Add(4*time.Hour)),
+		createTrade("trade-6", 50.0, baseTime.Add(5*time.Hour)),
+	})
+	
+	periods := calculator.DrawdownPeriods(0)
+	if len(periods) != 2 {
+		t.Fatalf("Expected 2 drawdown periods, got %d", len(periods))
+	}
+	
+	// 回復した区間
+	first := periods[0]
+	if first.Depth != 80.0 || !first.Recovered {
+		t.Errorf("Expected recovered drawdown of 80, got %+v", first)
+	}
+	if !first.StartTime.Equal(baseTime.Add(time.Hour)) || !first.TroughTime.Equal(baseTime.Add(3*time.Hour)) || !first.RecoveryTime.Equal(baseTime.Add(4*time.Hour)) {
+		t.Errorf("Unexpected times for first period: %+v", first)
+	}
+	if first.Duration != 3*time.Hour {
+		t.Errorf("Expected duration 3h, got %v", first.Duration)
+	}
+	
+	// 未回復の区間は最後の取引の決済時刻までを期間とする
+	second := periods[1]
+	if second.Depth != 200.0 || second.Recovered || !second.RecoveryTime.IsZero() {
+		t.Errorf("Expected unrecovered drawdown of 200, got %+v", second)
+	}
+	if !second.TroughTime.Equal(baseTime.Add(5*time.Hour)) || second.Duration != 2*time.Hour {
+		t.Errorf("Unexpected trough or duration for second period: %+v", second)
+	}
+	
+	// しきい値未満の区間は除外
+	periods = calculator.DrawdownPeriods(100.0)
+	if len(periods) != 1 || periods[0].Depth != 200.0 {
+		t.Errorf("Expected only the 200 drawdown above threshold, got %+v", periods)
+	}
+	
+	// 最初の取引から損失の場合はエントリー時刻を開始とする
+	losing := NewCalculator([]*models.Trade{
+		createTrade("trade-1", -10.0, baseTime),
+		createTrade("trade-2", 10.0, baseTime.Add(time.Hour)),
+	})
+	periods = losing.DrawdownPeriods(0)
+	if len(periods) != 1 || !periods[0].StartTime.Equal(baseTime) || !periods[0].Recovered {
+		t.Errorf("Expected one recovered period starting at the first entry, got %+v", periods)
+	}
+	
+	// 取引なし
+	if len(NewCalculator([]*models.Trade{}).DrawdownPeriods(0)) != 0 {
+		t.Error("Expected no drawdown periods for empty trades")
+	}
+}
+
+// Calculator ローリング指標テスト
+func TestCalculator_Rolling(t *testing.T) {
+	baseTime := time.Now()
+	trades := []*models.Trade{
+		createTrade("trade-1", 100.0, baseTime),
+		createTrade("trade-2", -50.0, baseTime.Add(time.Hour)),
+		createTrade("trade-3", 100.0, baseTime.Add(2*time.Hour)),
+		createTrade("trade-4", 100.0, baseTime.Add(3*time.Hour)),
+	}
+	calculator := NewCalculator(trades)
+	
+	t.Run("rolling return", func(t *testing.T) {
+		series := calculator.RollingReturn(2)
+		if len(series) != len(trades) {
+			t.Fatalf("Expected %d values aligned to trades, got %d", len(trades), len(series))
+		}
+		if !math.IsNaN(series[0]) {
+			t.Errorf("Expected NaN before the window is filled, got %f", series[0])
+		}
+		expected := []float64{50.0, 50.0, 200.0}
+		for i, value := range expected {
+			if math.Abs(series[i+1]-value) > 1e-9 {
+				t.Errorf("Expected rolling return %f at %d, got %f", value, i+1, series[i+1])
+			}
+		}
+	})
+	
+	t.Run("rolling sharpe", func(t *testing.T) {
+		series := calculator.RollingSharpe(3)
+		if !math.IsNaN(series[0]) || !math.IsNaN(series[1]) {
+			t.Errorf("Expected NaN before the window is filled, got %v", series[:2])
+		}
+		// 最初の窓は全体と同じ計算になる
+		expected := NewCalculator(trades[:3]).CalculateSharpeRatio()
+		if math.Abs(series[2]-expected) > 1e-9 {
+			t.Errorf("Expected rolling sharpe %f, got %f", expected, series[2])
+		}
+		// 2番目の窓は -50, 100, 100
+		expected = NewCalculator(trades[1:]).CalculateSharpeRatio()
+		if math.Abs(series[3]-expected) > 1e-9 {
+			t.Errorf("Expected rolling sharpe %f, got %f", expected, series[3])
+		}
+		
+		// 標準偏差が0の窓は0
+		flat := NewCalculator([]*models.Trade{
+			createTrade("trade-1", 100.0, baseTime),
+			createTrade("trade-2", 100.0, baseTime.Add(time.Hour)),
+		})
+		if got := flat.RollingSharpe(2)[1]; got != 0.0 {
+			t.Errorf("Expected zero sharpe for constant returns, got %f", got)
+		}
+	})
+	
+	t.Run("equity mode compounds returns", func(t *testing.T) {
+		equity := NewEquityReturnCalculator(trades[:2], 1000.0)
+		// +10% のあと 1100 に対して -50
+		expected := 1.1*(1-50.0/1100.0) - 1
+		if got := equity.RollingReturn(2)[1]; math.Abs(got-expected) > 1e-9 {
+			t.Errorf("Expected compounded return %f, got %f", expected, got)
+		}
+	})
+	
+	t.Run("invalid window", func(t *testing.T) {
+		if calculator.RollingSharpe(0) != nil || calculator.RollingReturn(-1) != nil {
+			t.Error("Expected nil for non-positive window")
+		}
+		if series := calculator.RollingReturn(10); len(series) != len(trades) || !math.IsNaN(series[len(series)-1]) {
+			t.Errorf("Expected all NaN when window exceeds trades, got %v", series)
+		}
+	})
+}
+
+// Calculator 同時保有ポジションテスト
+func TestCalculator_ConcurrentPositions(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newTrade := func(id string, openOffset, closeOffset time.Duration) *models.Trade {
+		trade := createTrade(id, 10.0, baseTime.Add(openOffset))
+		trade.CloseTime = baseTime.Add(closeOffset)
+		trade.Duration = closeOffset - openOffset
+		return trade
+	}
+	
+	// 0-4h, 1-3h, 2-5h が重なり、5h-6hは入れ替え（同時刻クローズ→オープン）
+	trades := []*models.Trade{
+		newTrade("trade-1", 0, 4*time.Hour),
+		newTrade("trade-2", time.Hour, 3*time.Hour),
+		newTrade("trade-3", 2*time.Hour, 5*time.Hour),
+		newTrade("trade-4", 5*time.Hour, 6*time.Hour),
+	}
+	calculator := NewCalculator(trades)
+	
+	if calculator.CalculateMaxConcurrentPositions() != 3 {
+		t.Errorf("Expected max concurrent positions 3, got %d", calculator.CalculateMaxConcurrentPositions())
+	}
+	
+	// 保有時間の合計(4+2+3+1=10h) / 全期間(6h)
+	average := calculator.CalculateAverageConcurrentPositions()
+	if math.Abs(average-10.0/6.0) > 1e-9 {
+		t.Errorf("Expected average concurrent positions %f, got %f", 10.0/6.0, average)
+	}
+	
+	// 取引なし
+	empty := NewCalculator([]*models.Trade{})
+	if empty.CalculateMaxConcurrentPositions() != 0 || empty.CalculateAverageConcurrentPositions() != 0.0 {
+		t.Error("Expected zero concurrency for empty trades")
+	}
+}
+
+// Calculator 保有期間分布テスト
+func TestCalculator_HoldingPeriodStats(t *testing.T) {
+	baseTime := time.Now()
+	durations := []time.Duration{2 * time.Hour, 30 * time.Minute, 48 * time.Hour, 3 * time.Hour, 10 * time.Hour}
+	trades := make([]*models.Trade, len(durations))
+	for i, duration := range durations {
+		trades[i] = createTrade(fmt.Sprintf("trade-%d", i+1), 10.0, baseTime.Add(time.Duration(i)*time.Hour))
+		trades[i].Duration = duration
+	}
+	
+	stats := NewCalculator(trades).CalculateHoldingPeriodStats()
+	
+	if stats.Min != 30*time.Minute {
+		t.Errorf("Expected min 30m, got %v", stats.Min)
+	}
+	if stats.Max != 48*time.Hour {
+		t.Errorf("Expected max 48h, got %v", stats.Max)
+	}
+	if stats.Median != 3*time.Hour {
+		t.Errorf("Expected median 3h, got %v", stats.Median)
+	}
+	
+	// 標本標準偏差（時間単位）
+	hours := []float64{2, 0.5, 48, 3, 10}
+	mean := 63.5 / 5
+	var sum float64
+	for _, h := range hours {
+		sum += (h - mean) * (h - mean)
+	}
+	expectedStdDev := math.Sqrt(sum / 4)
+	if math.Abs(stats.StandardDeviation.Hours()-expectedStdDev) > 1e-6 {
+		t.Errorf("Expected standard deviation %f hours, got %f", expectedStdDev, stats.StandardDeviation.Hours())
+	}
+	
+	// 期間帯別の取引数
+	if stats.UnderOneHour != 1 || stats.OneToFourHours != 2 || stats.FourHoursToOneDay != 1 || stats.OverOneDay != 1 {
+		t.Errorf("Unexpected buckets: %+v", stats)
+	}
+	
+	// 偶数個の場合は中央2値の平均
+	evenStats := NewCalculator(trades[:4]).CalculateHoldingPeriodStats()
+	if evenStats.Median != (2*time.Hour+3*time.Hour)/2 {
+		t.Errorf("Expected median 2.5h for even count, got %v", evenStats.Median)
+	}
+	
+	// 取引なし
+	if NewCalculator([]*models.Trade{}).CalculateHoldingPeriodStats() != (HoldingPeriodStats{}) {
+		t.Error("Expected zero holding period stats for empty trades")
+	}
+}
+
+// Calculator コスト分析テスト
+func TestCalculator_CostAnalysis(t *testing.T) {
+	baseTime := time.Now()
+	// コスト控除後は1勝2敗、控除前は2勝1敗
+	winner := createTrade("trade-1", 30.0, baseTime)
+	winner.SpreadCost, winner.SlippageCost, winner.Commission = 2.0, 1.0, 2.0
+	costLoser := createTrade("trade-2", -2.0, baseTime.Add(time.Hour))
+	costLoser.SpreadCost, costLoser.Commission, costLoser.Swap = 2.0, 2.0, 1.0
+	loser := createTrade("trade-3", -20.0, baseTime.Add(2*time.Hour))
+	loser.SpreadCost = 2.0
+	
+	costs := NewCalculator([]*models.Trade{winner, costLoser, loser}).CalculateCostAnalysis()
+	
+	if costs.SpreadCost != 6.0 || costs.SlippageCost != 1.0 || costs.Commission != 4.0 || costs.Swap != 1.0 || costs.TotalCost != 12.0 {
+		t.Errorf("Unexpected cost breakdown: %+v", costs)
+	}
+	// スワップを含めたコスト合計は BacktestResult の TotalCost と一致する
+	result := models.NewBacktestResult(10000.0)
+	for _, trade := range []*models.Trade{winner, costLoser, loser} {
+		result.AddTrade(*trade)
+	}
+	if math.Abs(costs.TotalCost-result.TotalCost) > 1e-9 {
+		t.Errorf("Expected total cost %f to match BacktestResult %f", costs.TotalCost, result.TotalCost)
+	}
+	if costs.GrossPnL != 20.0 || costs.NetPnL != 8.0 {
+		t.Errorf("Expected gross 20 and net 8, got %f / %f", costs.GrossPnL, costs.NetPnL)
+	}
+	if math.Abs(costs.GrossProfitFactor-38.0/18.0) > 1e-9 || math.Abs(costs.NetProfitFactor-30.0/22.0) > 1e-9 {
+		t.Errorf("Unexpected profit factors: gross %f, net %f", costs.GrossProfitFactor, costs.NetProfitFactor)
+	}
+	if math.Abs(costs.GrossWinRate-200.0/3) > 1e-9 || math.Abs(costs.NetWinRate-100.0/3) > 1e-9 {
+		t.Errorf("Unexpected win rates: gross %f, net %f", costs.GrossWinRate, costs.NetWinRate)
+	}
+	if math.Abs(costs.CostPercentOfGrossPnL-60.0) > 1e-9 {
+		t.Errorf("Expected cost to be 60%% of gross PnL, got %f", costs.CostPercentOfGrossPnL)
+	}
+	
+	// 元の取引の損益は変更されない
+	if winner.PnL != 30.0 {
+		t.Errorf("Expected original PnL to be unchanged, got %f", winner.PnL)
+	}
+	
+	// コスト控除前の総損益が0以下の場合は割合を計算しない
+	if costs := NewCalculator([]*models.Trade{loser}).CalculateCostAnalysis(); !math.IsNaN(costs.CostPercentOfGrossPnL) {
+		t.Errorf("Expected NaN cost percent for losing gross PnL, got %f", costs.CostPercentOfGrossPnL)
+	}
+	if costs := NewCalculator([]*models.Trade{}).CalculateCostAnalysis(); costs.TotalCost != 0 || !math.IsNaN(costs.CostPercentOfGrossPnL) {
+		t.Errorf("Expected zero costs for empty trades, got %+v", costs)
+	}
+}
+
+// Calculator 取引カレンダーテスト
+func TestCalculator_Calendar(t *testing.T) {
+	friday := time.Date(2024, 1, 5, 20, 0, 0, 0, time.UTC)
+	monday := time.Date(2024, 1, 8, 4, 0, 0, 0, time.UTC)
+	weekendTrade := createTrade("trade-1", 100.0, friday)
+	weekendTrade.CloseTime = monday
+	weekendTrade.Duration = monday.Sub(friday)
+	trades := []*models.Trade{
+		weekendTrade,
+		createTrade("trade-2", -40.0, monday),
+		createTrade("trade-3", 60.0, monday.Add(time.Hour)),
+	}
+	
+	calculator := NewCalculator(trades)
+	if got := calculator.CalculateAverageHoldingPeriod(); got != 58*time.Hour/3 {
+		t.Errorf("Expected average holding period 19h20m without calendar, got %v", got)
+	}
+	if got := calculator.TradingSpan(); got != 58*time.Hour {
+		t.Errorf("Expected trading span 58h without calendar, got %v", got)
+	}
+	
+	// 週末の48時間を除く
+	calculator.SetCalendar(&models.TradingCalendar{SkipWeekends: true})
+	if got := calculator.CalculateAverageHoldingPeriod(); got != 10*time.Hour/3 {
+		t.Errorf("Expected average holding period 3h20m with calendar, got %v", got)
+	}
+	if got := calculator.CalculateHoldingPeriodStats().Max; got != 8*time.Hour {
+		t.Errorf("Expected max holding period 8h with calendar, got %v", got)
+	}
+	if got := calculator.TradingSpan(); got != 10*time.Hour {
+		t.Errorf("Expected trading span 10h with calendar, got %v", got)
+	}
+	
+	// 年率換算は 260取引日/年 を使う
+	years := 10.0 / 24.0 / 260.0
+	expected := calculator.CalculateSharpeRatio() * math.Sqrt(3.0/years)
+	if got := calculator.CalculateAnnualizedSharpeRatio(); math.Abs(got-expected) > 1e-9 {
+		t.Errorf("Expected annualized sharpe %f, got %f", expected, got)
+	}
+	
+	// 取引が2件未満の場合は0
+	if got := NewCalculator(trades[:1]).CalculateAnnualizedSharpeRatio(); got != 0 {
+		t.Errorf("Expected 0 annualized sharpe for single trade, got %f", got)
+	}
+}
+
+// Calculator 資産ベースリターンテスト
+func TestCalculator_EquityReturns(t *testing.T) {
+	// 同じ損益額でも資産が増えるにつれて損益率は小さくなる
+	baseTime := time.Now()
+	trades := []*models.Trade{
+		createTrade("trade-1", 1000.0, baseTime),
+		createTrade("trade-2", 1000.0, baseTime.Add(time.Hour)),
+		createTrade("trade-3", -500.0, baseTime.Add(2*time.Hour)),
+		createTrade("trade-4", 1000.0, baseTime.Add(3*time.Hour)),
+		createTrade("trade-5", -350.0, baseTime.Add(4*time.Hour)),
+	}
+	
+	pnlCalculator := NewCalculator(trades)
+	equityCalculator := NewEquityReturnCalculator(trades, 1000.0)
+	
+	if pnlCalculator.GetReturnMode() != ReturnModePnL || equityCalculator.GetReturnMode() != ReturnModeEquity {
+		t.Error("Unexpected return modes")
+	}
+	
+	// 資産: 1000 → 2000 → 3000 → 2500 → 3500 → 3150
+	returns := []float64{1.0, 0.5, -500.0 / 3000.0, 0.4, -0.1}
+	var mean float64
+	for _, r := range returns {
+		mean += r / float64(len(returns))
+	}
+	var sum float64
+	for _, r := range returns {
+		sum += (r - mean) * (r - mean)
+	}
+	expectedSharpe := mean / math.Sqrt(sum/float64(len(returns)-1))
+	
+	equitySharpe := equityCalculator.CalculateSharpeRatio()
+	if math.Abs(equitySharpe-expectedSharpe) > 1e-9 {
+		t.Errorf("Expected equity-based Sharpe %f, got %f", expectedSharpe, equitySharpe)
+	}
+	
+	pnlSharpe := pnlCalculator.CalculateSharpeRatio()
+	if math.Abs(pnlSharpe-equitySharpe) < 1e-6 {
+		t.Errorf("Expected equity-based Sharpe to differ from PnL-based Sharpe, both %f", pnlSharpe)
+	}
+	
+	// ソルティノレシオも損益率から計算される（下方偏差は0を基準とした標本標準偏差）
+	downwardDev := math.Sqrt(returns[2]*returns[2] + returns[4]*returns[4])
+	expectedSortino := mean / downwardDev
+	if math.Abs(equityCalculator.CalculateSortinoRatio()-expectedSortino) > 1e-9 {
+		t.Errorf("Expected equity-based Sortino %f, got %f", expectedSortino, equityCalculator.CalculateSortinoRatio())
+	}
+}
+
+// Calculator 連勝・連敗区間テスト
+func TestCalculator_Streaks(t *testing.T) {
+	t.Run("should enumerate winning and losing runs in order", func(t *testing.T) {
+		baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		trades := []*models.Trade{
+			createTrade("trade-1", 100.0, baseTime),
+			createTrade("trade-2", 50.0, baseTime.Add(time.Hour)),
+			createTrade("trade-3", -30.0, baseTime.Add(2*time.Hour)),
+			createTrade("trade-4", -20.0, baseTime.Add(3*time.Hour)),
+			createTrade("trade-5", -10.0, baseTime.Add(4*time.Hour)),
+			createTrade("trade-6", 80.0, baseTime.Add(5*time.Hour)),
+		}
+		calculator := NewCalculator(trades)
+		
+		streaks := calculator.CalculateStreaks()
+		expected := []Streak{
+			{Type: StreakWin, Length: 2, PnL: 150.0, StartTime: baseTime, EndTime: baseTime.Add(2 * time.Hour)},
+			{Type: StreakLoss, Length: 3, PnL: -60.0, StartTime: baseTime.Add(2 * time.Hour), EndTime: baseTime.Add(5 * time.Hour)},
+			{Type: StreakWin, Length: 1, PnL: 80.0, StartTime: baseTime.Add(5 * time.Hour), EndTime: baseTime.Add(6 * time.Hour)},
+		}
+		
+		if len(streaks) != len(expected) {
+			t.Fatalf("Expected %d streaks, got %d", len(expected), len(streaks))
+		}
+		for i, want := range expected {
+			got := streaks[i]
+			if got.Type != want.Type || got.Length != want.Length {
+				t.Errorf("Streak %d: expected %s x%d, got %s x%d", i, want.Type, want.Length, got.Type, got.Length)
+			}
+			if math.Abs(got.PnL-want.PnL) > 1e-9 {
+				t.Errorf("Streak %d: expected PnL %f, got %f", i, want.PnL, got.PnL)
+			}
+			if !got.StartTime.Equal(want.StartTime) || !got.EndTime.Equal(want.EndTime) {
+				t.Errorf("Streak %d: expected %v ～ %v, got %v ～ %v", i, want.StartTime, want.EndTime, got.StartTime, got.EndTime)
+			}
+		}
+	})
+	
+	t.Run("should split runs at breakeven trades", func(t *testing.T) {
+		baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		trades := []*models.Trade{
+			createTrade("trade-1", 100.0, baseTime),
+			createTrade("trade-2", 0.0, baseTime.Add(time.Hour)),
+			createTrade("trade-3", 100.0, baseTime.Add(2*time.Hour)),
+		}
+		
+		streaks := NewCalculator(trades).CalculateStreaks()
+		if len(streaks) != 2 {
+			t.Fatalf("Expected 2 streaks, got %d", len(streaks))
+		}
+		if streaks[0].Length != 1 || streaks[1].Length != 1 {
+			t.Errorf("Expected two single-trade streaks, got %d and %d", streaks[0].Length, streaks[1].Length)
+		}
+	})
+	
+	t.Run("should return empty list without trades", func(t *testing.T) {
+		streaks := NewCalculator([]*models.Trade{}).CalculateStreaks()
+		if streaks == nil || len(streaks) != 0 {
+			t.Errorf("Expected empty streak list, got %v", streaks)
+		}
+	})
+}
+
+// Calculator 損益寄与上位取引テスト
+func TestCalculator_TopContributors(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []*models.Trade{
+		createTrade("trade-1", 100.0, baseTime),
+		createTrade("trade-2", -250.0, baseTime.Add(time.Hour)),
+		createTrade("trade-3", 400.0, baseTime.Add(2*time.Hour)),
+		createTrade("trade-4", 50.0, baseTime.Add(3*time.Hour)),
+	}
+	calculator := NewCalculator(trades)
+	
+	t.Run("should rank trades by absolute PnL", func(t *testing.T) {
+		top := calculator.CalculateTopContributors(3)
+		if len(top) != 3 {
+			t.Fatalf("Expected 3 contributors, got %d", len(top))
+		}
+		
+		expectedIDs := []string{"trade-3", "trade-2", "trade-1"}
+		for i, id := range expectedIDs {
+			if top[i].ID != id {
+				t.Errorf("Expected contributor %d to be %s, got %s", i, id, top[i].ID)
+			}
+		}
+		
+		// 総損益300に対して400の寄与率は133.33%
+		expectedShare := 400.0 / 300.0 * 100
+		if math.Abs(calculator.CalculatePnLShare(top[0])-expectedShare) > 1e-9 {
+			t.Errorf("Expected share %f, got %f", expectedShare, calculator.CalculatePnLShare(top[0]))
+		}
+	})
+	
+	t.Run("should handle n larger than trade count and non-positive n", func(t *testing.T) {
+		if len(calculator.CalculateTopContributors(10)) != 4 {
+			t.Error("Expected all trades when n exceeds trade count")
+		}
+		if len(calculator.CalculateTopContributors(0)) != 0 {
+			t.Error("Expected no contributors for n = 0")
+		}
+	})
+	
+	t.Run("should not reorder original trades", func(t *testing.T) {
+		calculator.CalculateTopContributors(4)
+		if calculator.GetTrades()[0].ID != "trade-1" {
+			t.Error("Expected original trade order to be preserved")
+		}
+	})
+	
+	t.Run("should return zero share when total PnL is zero", func(t *testing.T) {
+		flat := NewCalculator([]*models.Trade{
+			createTrade("trade-1", 100.0, baseTime),
+			createTrade("trade-2", -100.0, baseTime.Add(time.Hour)),
+		})
+		if flat.CalculatePnLShare(flat.GetTrades()[0]) != 0.0 {
+			t.Error("Expected zero share when total PnL is zero")
+		}
+	})
+}
+
 // Calculator エラーハンドリングテスト
 func TestCalculator_ErrorHandling(t *testing.T) {
 	// 空の取引履歴テスト
@@ -237,6 +1024,7 @@ func TestCalculator_ErrorHandling(t *testing.T) {
 func createTrade(id string, pnl float64, timestamp time.Time) *models.Trade {
 	return &models.Trade{
 		ID:        id,
+		Symbol:    "EURUSD",
 		Side:      models.Buy,
 		Size:      10000.0,
 		EntryPrice: 1.1000,
@@ -39,6 +39,21 @@ const (
 	MetricTradingFrequency
 	MetricRiskRewardRatio
 	MetricExpectedValue
+	
+	// 損益分布ベースのリスクメトリクス
+	MetricValueAtRisk
+	MetricExpectedShortfall
+	
+	// ポジションサイジングメトリクス
+	MetricKellyFraction
+	MetricHalfKellyFraction
+	
+	// ポジション同時保有メトリクス
+	MetricMaxConcurrentPositions
+	MetricAverageConcurrentPositions
+	
+	// 戦略品質メトリクス
+	MetricSQN
 )
 
 // String はMetricTypeの文字列表現を返します。
@@ -92,6 +107,20 @@ func (mt MetricType) String() string {
 		return "RiskRewardRatio"
 	case MetricExpectedValue:
 		return "ExpectedValue"
+	case MetricValueAtRisk:
+		return "ValueAtRisk"
+	case MetricExpectedShortfall:
+		return "ExpectedShortfall"
+	case MetricKellyFraction:
+		return "KellyFraction"
+	case MetricHalfKellyFraction:
+		return "HalfKellyFraction"
+	case MetricMaxConcurrentPositions:
+		return "MaxConcurrentPositions"
+	case MetricAverageConcurrentPositions:
+		return "AverageConcurrentPositions"
+	case MetricSQN:
+		return "SQN"
 	default:
 		return "Unknown"
 	}
@@ -159,6 +188,8 @@ func GenerateMetricsFromCalculator(calculator *Calculator) *MetricsSet {
 	metrics.AddMetric(MetricCalmarRatio, calculator.CalculateCalmarRatio(), "ratio", "Return to max drawdown ratio")
 	metrics.AddMetric(MetricProfitFactor, calculator.CalculateProfitFactor(), "ratio", "Gross profit to gross loss ratio")
 	metrics.AddMetric(MetricStandardDeviation, calculator.CalculateStandardDeviation(), "USD", "Standard deviation of returns")
+	metrics.AddMetric(MetricValueAtRisk, calculator.CalculateValueAtRisk(DefaultVaRConfidence), "USD", "Historical value at risk (95%)")
+	metrics.AddMetric(MetricExpectedShortfall, calculator.CalculateExpectedShortfall(DefaultVaRConfidence), "USD", "Average loss beyond value at risk (95%)")
 	
 	// 取引パフォーマンスメトリクス
 	metrics.AddMetric(MetricMaxConsecutiveWins, calculator.CalculateMaxConsecutiveWins(), "count", "Maximum consecutive winning trades")
@@ -167,6 +198,11 @@ func GenerateMetricsFromCalculator(calculator *Calculator) *MetricsSet {
 	metrics.AddMetric(MetricTradingFrequency, calculator.CalculateTradingFrequency(), "trades/day", "Trading frequency per day")
 	metrics.AddMetric(MetricRiskRewardRatio, calculator.CalculateRiskRewardRatio(), "ratio", "Average win to average loss ratio")
 	metrics.AddMetric(MetricExpectedValue, calculator.CalculateExpectedValue(), "USD", "Expected value per trade")
+	metrics.AddMetric(MetricKellyFraction, calculator.CalculateKellyFraction()*100, "%", "Kelly criterion fraction of capital to risk")
+	metrics.AddMetric(MetricHalfKellyFraction, calculator.CalculateHalfKellyFraction()*100, "%", "Half of the Kelly criterion fraction")
+	metrics.AddMetric(MetricMaxConcurrentPositions, calculator.CalculateMaxConcurrentPositions(), "count", "Maximum number of simultaneously open positions")
+	metrics.AddMetric(MetricAverageConcurrentPositions, calculator.CalculateAverageConcurrentPositions(), "count", "Time-weighted average number of open positions")
+	metrics.AddMetric(MetricSQN, calculator.CalculateSQN(), "score", "System quality number (sqrt(N) x mean / stddev of trade returns)")
 	
 	return metrics
 }
@@ -204,6 +240,8 @@ func (ms *MetricsSet) GetRiskMetrics() map[string]*Metric {
 		MetricSortinoRatio,
 		MetricCalmarRatio,
 		MetricStandardDeviation,
+		MetricValueAtRisk,
+		MetricExpectedShortfall,
 	}
 	
 	for _, metricType := range riskTypes {
@@ -226,6 +264,11 @@ func (ms *MetricsSet) GetTradingMetrics() map[string]*Metric {
 		MetricTradingFrequency,
 		MetricRiskRewardRatio,
 		MetricExpectedValue,
+		MetricKellyFraction,
+		MetricHalfKellyFraction,
+		MetricMaxConcurrentPositions,
+		MetricAverageConcurrentPositions,
+		MetricSQN,
 	}
 	
 	for _, metricType := range tradingTypes {
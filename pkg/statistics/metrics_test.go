@@ -203,6 +203,8 @@ func TestMetricsSet_GetRiskMetrics(t *testing.T) {
 		"SortinoRatio",
 		"CalmarRatio",
 		"StandardDeviation",
+		"ValueAtRisk",
+		"ExpectedShortfall",
 	}
 	
 	for _, metricName := range expectedRiskMetrics {
@@ -236,6 +238,11 @@ func TestMetricsSet_GetTradingMetrics(t *testing.T) {
 		"TradingFrequency",
 		"RiskRewardRatio",
 		"ExpectedValue",
+		"KellyFraction",
+		"HalfKellyFraction",
+		"MaxConcurrentPositions",
+		"AverageConcurrentPositions",
+		"SQN",
 	}
 	
 	for _, metricName := range expectedTradingMetrics {
@@ -262,6 +269,13 @@ func TestMetricType_String(t *testing.T) {
 		{MetricProfitFactor, "ProfitFactor"},
 		{MetricTotalTrades, "TotalTrades"},
 		{MetricAverageHoldingPeriod, "AverageHoldingPeriod"},
+		{MetricValueAtRisk, "ValueAtRisk"},
+		{MetricExpectedShortfall, "ExpectedShortfall"},
+		{MetricKellyFraction, "KellyFraction"},
+		{MetricHalfKellyFraction, "HalfKellyFraction"},
+		{MetricMaxConcurrentPositions, "MaxConcurrentPositions"},
+		{MetricAverageConcurrentPositions, "AverageConcurrentPositions"},
+		{MetricSQN, "SQN"},
 	}
 	
 	for _, tc := range testCases {
@@ -1,8 +1,13 @@
 package statistics
 
 import (
+	"encoding/json"
 	"fmt"
+	"html"
+	"math"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/RuiHirano/fx-backtesting/pkg/models"
 )
@@ -14,12 +19,43 @@ const (
 	FormatText ReportFormat = iota
 	FormatJSON
 	FormatCSV
+	FormatHTML
+	FormatJSONLines
 )
 
+// ParseReportFormat は文字列からReportFormatに変換します。
+func ParseReportFormat(s string) (ReportFormat, error) {
+	switch strings.ToLower(s) {
+	case "text", "txt":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	case "csv":
+		return FormatCSV, nil
+	case "html":
+		return FormatHTML, nil
+	case "jsonl", "ndjson":
+		return FormatJSONLines, nil
+	default:
+		return FormatText, fmt.Errorf("invalid report format: %s", s)
+	}
+}
+
+// DefaultTopContributors はレポートに掲載する損益寄与上位取引のデフォルト件数です。
+const DefaultTopContributors = 5
+
+// DefaultTopDrawdowns はレポートに掲載する深いドローダウン区間のデフォルト件数です。
+const DefaultTopDrawdowns = 5
+
 // Report はバックテスト結果のレポート生成機能を提供します。
 type Report struct {
-	calculator *Calculator
-	result     *models.BacktestResult
+	calculator      *Calculator
+	result          *models.BacktestResult
+	topContributors int
+	topDrawdowns    int
+	benchmark       *Benchmark
+	runPeriod       time.Duration
+	runBars         int
 }
 
 // NewReport は新しいReportを作成します。
@@ -36,10 +72,83 @@ func NewReport(trades []*models.Trade, initialBalance float64) *Report {
 	// 高度な統計指標を設定
 	result.MaxDrawdown = calculator.CalculateMaxDrawdown()
 	result.SharpeRatio = calculator.CalculateSharpeRatio()
+	result.ProfitFactor = calculator.CalculateProfitFactor()
 	
 	return &Report{
-		calculator: calculator,
-		result:     result,
+		calculator:      calculator,
+		result:          result,
+		topContributors: DefaultTopContributors,
+		topDrawdowns:    DefaultTopDrawdowns,
+	}
+}
+
+// SetTopContributorsCount はレポートに掲載する損益寄与上位取引の件数を設定します。0以下の場合は掲載しません。
+func (r *Report) SetTopContributorsCount(n int) {
+	r.topContributors = n
+}
+
+// SetTopDrawdownsCount はレポートに掲載するドローダウン区間の件数を設定します。0以下の場合は掲載しません。
+func (r *Report) SetTopDrawdownsCount(n int) {
+	r.topDrawdowns = n
+}
+
+// SetRunPeriod はバックテストの実行期間と処理したローソク足の本数を設定し、時間あたりの期待値の計算に使用します。
+// 設定しない場合、1日あたりの期待値は取引期間から計算し、1バーあたりの期待値は0になります。
+func (r *Report) SetRunPeriod(period time.Duration, bars int) {
+	r.runPeriod = period
+	r.runBars = bars
+}
+
+// SetCalendar は保有期間・取引頻度・年率シャープレシオの計算に使う取引カレンダーを設定します。
+// nil の場合は週末・休場日も取引時間として扱います。
+func (r *Report) SetCalendar(calendar *models.TradingCalendar) {
+	r.calculator.SetCalendar(calendar)
+}
+
+// SetBenchmark はバックテストに使ったローソク足を設定し、同じ期間のバイアンドホールドとの比較（リターン・アルファ・相関係数）を
+// テキスト・JSON・HTML レポートに掲載します。ローソク足が空の場合は比較を掲載しません。
+func (r *Report) SetBenchmark(candles []models.Candle) {
+	if len(candles) == 0 {
+		r.benchmark = nil
+		return
+	}
+	benchmark := CompareWithBenchmark(r.calculator.GetTrades(), candles, r.result.InitialBalance)
+	r.benchmark = &benchmark
+}
+
+// SetBenchmarkResult は計算済みのバイアンドホールドとの比較（BenchmarkAccumulator.Benchmark など）を掲載します。
+// ローソク足を保持せずに比較する場合に SetBenchmark の代わりに使います。nil の場合は比較を掲載しません。
+func (r *Report) SetBenchmarkResult(benchmark *Benchmark) {
+	r.benchmark = benchmark
+}
+
+// benchmarkRows はバイアンドホールドとの比較の表示行を生成します。
+func (r *Report) benchmarkRows() [][2]string {
+	return [][2]string{
+		{"バイアンドホールド・リターン", fmt.Sprintf("%.2f%%", r.benchmark.BuyAndHoldReturn)},
+		{"戦略リターン", fmt.Sprintf("%.2f%%", r.benchmark.StrategyReturn)},
+		{"アルファ", fmt.Sprintf("%+.2f%%", r.benchmark.Alpha)},
+		{"相関係数", fmt.Sprintf("%.4f", r.benchmark.Correlation)},
+	}
+}
+
+// costAnalysisRows はコスト分析の表示行を返します。コスト控除前後の成績を並べて表示します。
+func (r *Report) costAnalysisRows() [][2]string {
+	costs := r.calculator.CalculateCostAnalysis()
+	costPercent := "算出不可（コスト控除前の総損益が0以下）"
+	if !math.IsNaN(costs.CostPercentOfGrossPnL) {
+		costPercent = fmt.Sprintf("%.2f%%", costs.CostPercentOfGrossPnL)
+	}
+	return [][2]string{
+		{"スプレッド", fmt.Sprintf("%.2f", costs.SpreadCost)},
+		{"スリッページ", fmt.Sprintf("%.2f", costs.SlippageCost)},
+		{"手数料", fmt.Sprintf("%.2f", costs.Commission)},
+		{"スワップ", fmt.Sprintf("%.2f", costs.Swap)},
+		{"コスト合計", fmt.Sprintf("%.2f", costs.TotalCost)},
+		{"総損益（コスト控除前 / 控除後）", fmt.Sprintf("%.2f / %.2f", costs.GrossPnL, costs.NetPnL)},
+		{"プロフィットファクター（コスト控除前 / 控除後）", fmt.Sprintf("%.4f / %.4f", costs.GrossProfitFactor, costs.NetProfitFactor)},
+		{"勝率（コスト控除前 / 控除後）", fmt.Sprintf("%.2f%% / %.2f%%", costs.GrossWinRate, costs.NetWinRate)},
+		{"コスト控除前の総損益に対するコスト", costPercent},
 	}
 }
 
@@ -63,12 +172,29 @@ func (r *Report) GenerateTextReport() string {
 	sb.WriteString("【損益情報】\n")
 	sb.WriteString(fmt.Sprintf("総損益: %.2f\n", r.result.TotalPnL))
 	sb.WriteString(fmt.Sprintf("総リターン: %.2f%%\n", r.result.TotalReturn))
+	sb.WriteString(fmt.Sprintf("総コスト: %.2f\n", r.result.TotalCost))
 	sb.WriteString(fmt.Sprintf("総利益: %.2f\n", r.result.GrossProfit))
 	sb.WriteString(fmt.Sprintf("総損失: %.2f\n", r.result.GrossLoss))
 	sb.WriteString(fmt.Sprintf("最大利益: %.2f\n", r.result.LargestWin))
 	sb.WriteString(fmt.Sprintf("最大損失: %.2f\n", r.result.LargestLoss))
 	sb.WriteString("\n")
 	
+	// コスト分析
+	sb.WriteString("【コスト分析】\n")
+	for _, row := range r.costAnalysisRows() {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", row[0], row[1]))
+	}
+	sb.WriteString("\n")
+	
+	// ベンチマーク比較
+	if r.benchmark != nil {
+		sb.WriteString("【ベンチマーク比較】\n")
+		for _, row := range r.benchmarkRows() {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", row[0], row[1]))
+		}
+		sb.WriteString("\n")
+	}
+	
 	// 取引統計
 	sb.WriteString("【取引統計】\n")
 	sb.WriteString(fmt.Sprintf("総取引数: %d\n", r.result.TotalTrades))
@@ -77,17 +203,27 @@ func (r *Report) GenerateTextReport() string {
 	sb.WriteString(fmt.Sprintf("勝率: %.2f%%\n", r.result.WinRate))
 	sb.WriteString(fmt.Sprintf("平均利益: %.2f\n", r.result.AverageWin))
 	sb.WriteString(fmt.Sprintf("平均損失: %.2f\n", r.result.AverageLoss))
+	sb.WriteString(fmt.Sprintf("平均利益（pips）: %.1f\n", r.calculator.CalculateAverageWinPips()))
+	sb.WriteString(fmt.Sprintf("平均損失（pips）: %.1f\n", r.calculator.CalculateAverageLossPips()))
 	sb.WriteString("\n")
 	
 	// リスク指標
 	sb.WriteString("【リスク指標】\n")
 	sb.WriteString(fmt.Sprintf("最大ドローダウン: %.2f\n", r.result.MaxDrawdown))
 	sb.WriteString(fmt.Sprintf("シャープレシオ: %.4f\n", r.result.SharpeRatio))
+	sb.WriteString(fmt.Sprintf("年率シャープレシオ: %.4f\n", r.calculator.CalculateAnnualizedSharpeRatio()))
 	sb.WriteString(fmt.Sprintf("プロフィットファクター: %.4f\n", r.result.ProfitFactor))
 	sb.WriteString(fmt.Sprintf("ソルティノレシオ: %.4f\n", r.calculator.CalculateSortinoRatio()))
 	sb.WriteString(fmt.Sprintf("カルマーレシオ: %.4f\n", r.calculator.CalculateCalmarRatio()))
 	sb.WriteString("\n")
 	
+	// ドローダウン期間
+	sb.WriteString("【ドローダウン期間】\n")
+	for _, row := range r.drawdownRows() {
+		sb.WriteString(fmt.Sprintf("%s %s\n", row[0], row[1]))
+	}
+	sb.WriteString("\n")
+	
 	// 取引パフォーマンス
 	sb.WriteString("【取引パフォーマンス】\n")
 	avgHolding := r.calculator.CalculateAverageHoldingPeriod()
@@ -96,65 +232,520 @@ func (r *Report) GenerateTextReport() string {
 	sb.WriteString(fmt.Sprintf("最大連敗: %d\n", r.calculator.CalculateMaxConsecutiveLosses()))
 	sb.WriteString(fmt.Sprintf("取引頻度: %.2f取引/日\n", r.calculator.CalculateTradingFrequency()))
 	sb.WriteString(fmt.Sprintf("リスクリワード比: %.4f\n", r.calculator.CalculateRiskRewardRatio()))
+	sb.WriteString(fmt.Sprintf("期待値（1取引あたり）: %.2f\n", r.calculator.CalculateExpectedValue()))
+	sb.WriteString(fmt.Sprintf("期待値（1日あたり）: %.2f\n", r.calculator.CalculateExpectancyPerDay(r.runPeriod)))
+	sb.WriteString(fmt.Sprintf("期待値（1バーあたり）: %.4f\n", r.calculator.CalculateExpectancyPerBar(r.runBars)))
+	holding := r.calculator.CalculateHoldingPeriodStats()
+	sb.WriteString(fmt.Sprintf("保有期間（最小/中央値/最大）: %.2f / %.2f / %.2f時間\n",
+		holding.Min.Hours(), holding.Median.Hours(), holding.Max.Hours()))
+	sb.WriteString(fmt.Sprintf("保有期間の標準偏差: %.2f時間\n", holding.StandardDeviation.Hours()))
+	sb.WriteString(fmt.Sprintf("保有期間分布: 1時間未満 %d / 1-4時間 %d / 4時間-1日 %d / 1日以上 %d\n",
+		holding.UnderOneHour, holding.OneToFourHours, holding.FourHoursToOneDay, holding.OverOneDay))
+	sb.WriteString("\n")
+	
+	// 連勝・連敗
+	sb.WriteString("【連勝・連敗】\n")
+	for _, line := range r.streakSummaryRows() {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", line[0], line[1]))
+	}
+	sb.WriteString("\n")
+	
+	// 損益寄与上位取引
+	sb.WriteString("【損益寄与上位取引】\n")
+	for _, row := range r.topContributorRows() {
+		sb.WriteString(fmt.Sprintf("%s %s\n", row[0], row[1]))
+	}
+	sb.WriteString("\n")
+	
+	// ベスト/ワースト取引
+	sb.WriteString("【ベスト/ワースト取引】\n")
+	sb.WriteString(fmt.Sprintf("ベスト取引: %s\n", formatSpotlightTrade(r.calculator.GetBestTrade())))
+	sb.WriteString(fmt.Sprintf("ワースト取引: %s\n", formatSpotlightTrade(r.calculator.GetWorstTrade())))
+	sb.WriteString("\n")
+	
+	// シンボル別統計
+	sb.WriteString("【シンボル別統計】\n")
+	for _, summary := range r.symbolSummaries() {
+		sb.WriteString(fmt.Sprintf("%s: 取引数 %d 総損益 %.2f 勝率 %.2f%% PF %.4f 最大DD %.2f\n",
+			displaySymbol(summary.Symbol),
+			summary.TotalTrades,
+			float64(summary.TotalPnL),
+			float64(summary.WinRate),
+			float64(summary.ProfitFactor),
+			float64(summary.MaxDrawdown)))
+	}
 	sb.WriteString("\n")
 	
 	return sb.String()
 }
 
+// formatSpotlightTrade はベスト/ワースト取引の1行表示を生成します。
+func formatSpotlightTrade(trade *models.Trade) string {
+	if trade == nil {
+		return "なし"
+	}
+	return fmt.Sprintf("%s %s サイズ %.2f 損益 %.2f (%s ～ %s)",
+		trade.ID,
+		trade.Side.String(),
+		trade.Size,
+		trade.PnL,
+		trade.OpenTime.Format("2006-01-02 15:04:05"),
+		trade.CloseTime.Format("2006-01-02 15:04:05"))
+}
+
+// topContributorRows は損益寄与上位取引の表示行を生成します。
+func (r *Report) topContributorRows() [][2]string {
+	contributors := r.calculator.CalculateTopContributors(r.topContributors)
+	
+	rows := make([][2]string, 0, len(contributors))
+	for i, trade := range contributors {
+		rows = append(rows, [2]string{
+			fmt.Sprintf("%d.", i+1),
+			fmt.Sprintf("%s %s %s 損益 %.2f (寄与率 %.2f%%)",
+				trade.ID,
+				displaySymbol(trade.Symbol),
+				trade.Side.String(),
+				trade.PnL,
+				r.calculator.CalculatePnLShare(trade)),
+		})
+	}
+	
+	return rows
+}
+
+// topDrawdownPeriods は下落額の大きい順にドローダウン区間を返します。
+func (r *Report) topDrawdownPeriods() []DrawdownPeriod {
+	if r.topDrawdowns <= 0 {
+		return []DrawdownPeriod{}
+	}
+	
+	periods := r.calculator.DrawdownPeriods(0)
+	sort.SliceStable(periods, func(i, j int) bool {
+		return periods[i].Depth > periods[j].Depth
+	})
+	if len(periods) > r.topDrawdowns {
+		periods = periods[:r.topDrawdowns]
+	}
+	
+	return periods
+}
+
+// drawdownRows はドローダウン区間の表示行を生成します。
+func (r *Report) drawdownRows() [][2]string {
+	periods := r.topDrawdownPeriods()
+	
+	rows := make([][2]string, 0, len(periods))
+	for i, period := range periods {
+		recovery := "未回復"
+		if period.Recovered {
+			recovery = period.RecoveryTime.Format("2006-01-02 15:04:05")
+		}
+		rows = append(rows, [2]string{
+			fmt.Sprintf("%d.", i+1),
+			fmt.Sprintf("下落 %.2f 期間 %.2f時間 (開始 %s 底 %s 回復 %s)",
+				period.Depth,
+				period.Duration.Hours(),
+				period.StartTime.Format("2006-01-02 15:04:05"),
+				period.TroughTime.Format("2006-01-02 15:04:05"),
+				recovery),
+		})
+	}
+	
+	return rows
+}
+
+// streakSummaryRows は連勝・連敗区間の要約行を生成します。
+func (r *Report) streakSummaryRows() [][2]string {
+	streaks := r.calculator.CalculateStreaks()
+	
+	rows := make([][2]string, 0, 4)
+	for _, streakType := range []StreakType{StreakWin, StreakLoss} {
+		count := 0
+		totalLength := 0
+		var longest *Streak
+		for i := range streaks {
+			if streaks[i].Type != streakType {
+				continue
+			}
+			count++
+			totalLength += streaks[i].Length
+			if longest == nil || streaks[i].Length > longest.Length {
+				longest = &streaks[i]
+			}
+		}
+		
+		label := "連勝"
+		if streakType == StreakLoss {
+			label = "連敗"
+		}
+		
+		averageLength := 0.0
+		if count > 0 {
+			averageLength = float64(totalLength) / float64(count)
+		}
+		rows = append(rows,
+			[2]string{label + "区間", fmt.Sprintf("%d回 平均 %.2f取引", count, averageLength)},
+			[2]string{"最長" + label, formatStreak(longest)},
+		)
+	}
+	
+	return rows
+}
+
+// formatStreak は連勝・連敗区間の1行表示を生成します。
+func formatStreak(streak *Streak) string {
+	if streak == nil {
+		return "なし"
+	}
+	return fmt.Sprintf("%d取引 損益 %.2f (%s ～ %s)",
+		streak.Length,
+		streak.PnL,
+		streak.StartTime.Format("2006-01-02 15:04:05"),
+		streak.EndTime.Format("2006-01-02 15:04:05"))
+}
+
 // GenerateJSONReport はJSON形式のレポートを生成します。
 func (r *Report) GenerateJSONReport() string {
-	// 基本的なJSON形式のレポート
-	return fmt.Sprintf(`{
-  "summary": {
-    "initial_balance": %.2f,
-    "final_balance": %.2f,
-    "total_pnl": %.2f,
-    "total_return": %.2f,
-    "total_trades": %d,
-    "win_rate": %.2f,
-    "profit_factor": %.4f,
-    "max_drawdown": %.2f,
-    "sharpe_ratio": %.4f
-  },
-  "detailed_metrics": {
-    "gross_profit": %.2f,
-    "gross_loss": %.2f,
-    "largest_win": %.2f,
-    "largest_loss": %.2f,
-    "average_win": %.2f,
-    "average_loss": %.2f,
-    "max_consecutive_wins": %d,
-    "max_consecutive_losses": %d,
-    "sortino_ratio": %.4f,
-    "calmar_ratio": %.4f,
-    "risk_reward_ratio": %.4f,
-    "trading_frequency": %.2f,
-    "average_holding_hours": %.2f
-  }
-}`,
-		r.result.InitialBalance,
-		r.result.FinalBalance,
-		r.result.TotalPnL,
-		r.result.TotalReturn,
-		r.result.TotalTrades,
-		r.result.WinRate,
-		r.result.ProfitFactor,
-		r.result.MaxDrawdown,
-		r.result.SharpeRatio,
-		r.result.GrossProfit,
-		r.result.GrossLoss,
-		r.result.LargestWin,
-		r.result.LargestLoss,
-		r.result.AverageWin,
-		r.result.AverageLoss,
-		r.calculator.CalculateMaxConsecutiveWins(),
-		r.calculator.CalculateMaxConsecutiveLosses(),
-		r.calculator.CalculateSortinoRatio(),
-		r.calculator.CalculateCalmarRatio(),
-		r.calculator.CalculateRiskRewardRatio(),
-		r.calculator.CalculateTradingFrequency(),
-		r.calculator.CalculateAverageHoldingPeriod().Hours(),
-	)
+	data, err := json.MarshalIndent(r.buildJSONReport(), "", "  ")
+	if err != nil {
+		errorReport, _ := json.Marshal(map[string]string{"error": err.Error()})
+		return string(errorReport)
+	}
+	return string(data)
+}
+
+// JSONReport はJSON形式レポートの構造を表します。
+// 取引（trades・best_trade・worst_trade・top_contributors）は models.Trade の MarshalJSON の形式で出力します。
+type JSONReport struct {
+	Summary         JSONSummary         `json:"summary"`
+	Benchmark       *JSONBenchmark      `json:"benchmark,omitempty"`
+	CostAnalysis    JSONCostAnalysis    `json:"cost_analysis"`
+	DetailedMetrics JSONDetailedMetrics `json:"detailed_metrics"`
+	BySymbol        []JSONSymbolSummary `json:"by_symbol"`
+	BestTrade       *models.Trade       `json:"best_trade"`
+	WorstTrade      *models.Trade       `json:"worst_trade"`
+	Streaks         []Streak            `json:"streaks"`
+	TopContributors []JSONContributor   `json:"top_contributors"`
+	DrawdownPeriods []JSONDrawdown      `json:"drawdown_periods"`
+	Trades          []*models.Trade     `json:"trades"`
+}
+
+// JSONSummary はJSONレポートのサマリーを表します。
+type JSONSummary struct {
+	InitialBalance        JSONFloat `json:"initial_balance"`
+	FinalBalance          JSONFloat `json:"final_balance"`
+	TotalPnL              JSONFloat `json:"total_pnl"`
+	TotalReturn           JSONFloat `json:"total_return"`
+	TotalCost             JSONFloat `json:"total_cost"`
+	TotalTrades           int       `json:"total_trades"`
+	WinRate               JSONFloat `json:"win_rate"`
+	ProfitFactor          JSONFloat `json:"profit_factor"`
+	MaxDrawdown           JSONFloat `json:"max_drawdown"`
+	SharpeRatio           JSONFloat `json:"sharpe_ratio"`
+	AnnualizedSharpeRatio JSONFloat `json:"annualized_sharpe_ratio"`
+}
+
+// JSONBenchmark はJSONレポートのバイアンドホールドとの比較を表します。
+type JSONBenchmark struct {
+	BuyAndHoldReturn JSONFloat `json:"buy_and_hold_return"`
+	StrategyReturn   JSONFloat `json:"strategy_return"`
+	Alpha            JSONFloat `json:"alpha"`
+	Correlation      JSONFloat `json:"correlation"`
+}
+
+// JSONCostAnalysis はJSONレポートのコスト分析を表します。
+// cost_percent_of_gross_pnl はコスト控除前の総損益が0以下の場合 null になります。
+type JSONCostAnalysis struct {
+	SpreadCost            JSONFloat `json:"spread_cost"`
+	SlippageCost          JSONFloat `json:"slippage_cost"`
+	Commission            JSONFloat `json:"commission"`
+	Swap                  JSONFloat `json:"swap"`
+	TotalCost             JSONFloat `json:"total_cost"`
+	GrossPnL              JSONFloat `json:"gross_pnl"`
+	NetPnL                JSONFloat `json:"net_pnl"`
+	GrossProfitFactor     JSONFloat `json:"gross_profit_factor"`
+	NetProfitFactor       JSONFloat `json:"net_profit_factor"`
+	GrossWinRate          JSONFloat `json:"gross_win_rate"`
+	NetWinRate            JSONFloat `json:"net_win_rate"`
+	CostPercentOfGrossPnL JSONFloat `json:"cost_percent_of_gross_pnl"`
+}
+
+// JSONDetailedMetrics はJSONレポートの詳細指標を表します。
+type JSONDetailedMetrics struct {
+	GrossProfit          JSONFloat         `json:"gross_profit"`
+	GrossLoss            JSONFloat         `json:"gross_loss"`
+	LargestWin           JSONFloat         `json:"largest_win"`
+	LargestLoss          JSONFloat         `json:"largest_loss"`
+	AverageWin           JSONFloat         `json:"average_win"`
+	AverageLoss          JSONFloat         `json:"average_loss"`
+	AverageWinPips       JSONFloat         `json:"average_win_pips"`
+	AverageLossPips      JSONFloat         `json:"average_loss_pips"`
+	MaxConsecutiveWins   int               `json:"max_consecutive_wins"`
+	MaxConsecutiveLosses int               `json:"max_consecutive_losses"`
+	SortinoRatio         JSONFloat         `json:"sortino_ratio"`
+	CalmarRatio          JSONFloat         `json:"calmar_ratio"`
+	RiskRewardRatio      JSONFloat         `json:"risk_reward_ratio"`
+	TradingFrequency     JSONFloat         `json:"trading_frequency"`
+	ExpectancyPerTrade   JSONFloat         `json:"expectancy_per_trade"`
+	ExpectancyPerDay     JSONFloat         `json:"expectancy_per_day"`
+	ExpectancyPerBar     JSONFloat         `json:"expectancy_per_bar"`
+	AverageHoldingHours  JSONFloat         `json:"average_holding_hours"`
+	HoldingPeriod        JSONHoldingPeriod `json:"holding_period"`
+}
+
+// JSONHoldingPeriod はJSONレポートの保有期間分布を表します。
+type JSONHoldingPeriod struct {
+	MinHours               float64 `json:"min_hours"`
+	MedianHours            float64 `json:"median_hours"`
+	MaxHours               float64 `json:"max_hours"`
+	StandardDeviationHours float64 `json:"standard_deviation_hours"`
+	UnderOneHour           int     `json:"under_one_hour"`
+	OneToFourHours         int     `json:"one_to_four_hours"`
+	FourHoursToOneDay      int     `json:"four_hours_to_one_day"`
+	OverOneDay             int     `json:"over_one_day"`
+}
+
+// JSONSymbolSummary はシンボル別の統計サマリーを表します。
+type JSONSymbolSummary struct {
+	Symbol       string    `json:"symbol"`
+	TotalTrades  int       `json:"total_trades"`
+	TotalPnL     JSONFloat `json:"total_pnl"`
+	WinRate      JSONFloat `json:"win_rate"`
+	ProfitFactor JSONFloat `json:"profit_factor"`
+	MaxDrawdown  JSONFloat `json:"max_drawdown"`
+}
+
+// JSONFloat はNaNや無限大をnullとして出力する浮動小数点数です。
+type JSONFloat float64
+
+// MarshalJSON はJSONFloatをJSONに変換します。NaNと無限大はnullになります。
+func (f JSONFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(v)
+}
+
+// JSONContributor はJSONレポートの損益寄与上位取引を表します。
+// 取引の MarshalJSON の出力に share を加えた1つのオブジェクトとして出力します。
+type JSONContributor struct {
+	models.Trade
+	Share JSONFloat `json:"share"` // 総損益に対する割合（%）
+}
+
+// MarshalJSON は取引のJSONオブジェクトの末尾に share を追加します。
+func (c JSONContributor) MarshalJSON() ([]byte, error) {
+	trade, err := json.Marshal(c.Trade)
+	if err != nil {
+		return nil, err
+	}
+	share, err := json.Marshal(c.Share)
+	if err != nil {
+		return nil, err
+	}
+	
+	data := append(trade[:len(trade)-1], `,"share":`...)
+	data = append(data, share...)
+	return append(data, '}'), nil
+}
+
+// UnmarshalJSON は MarshalJSON の形式のJSONから取引と share を復元します。
+func (c *JSONContributor) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &c.Trade); err != nil {
+		return err
+	}
+	var v struct {
+		Share JSONFloat `json:"share"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	c.Share = v.Share
+	return nil
+}
+
+// JSONDrawdown はJSONレポートのドローダウン区間を表します。
+type JSONDrawdown struct {
+	StartTime     time.Time  `json:"start_time"`
+	TroughTime    time.Time  `json:"trough_time"`
+	RecoveryTime  *time.Time `json:"recovery_time"` // 未回復の場合は null
+	Depth         JSONFloat  `json:"depth"`
+	DurationHours JSONFloat  `json:"duration_hours"`
+	Recovered     bool       `json:"recovered"`
+}
+
+// buildJSONReport はJSON出力用の構造体を組み立てます。
+func (r *Report) buildJSONReport() JSONReport {
+	// 取引がない場合も null ではなく空配列として出力する
+	trades := append(make([]*models.Trade, 0, len(r.calculator.trades)), r.calculator.trades...)
+
+	holding := r.calculator.CalculateHoldingPeriodStats()
+	costs := r.calculator.CalculateCostAnalysis()
+	var benchmark *JSONBenchmark
+	if r.benchmark != nil {
+		benchmark = &JSONBenchmark{
+			BuyAndHoldReturn: JSONFloat(r.benchmark.BuyAndHoldReturn),
+			StrategyReturn:   JSONFloat(r.benchmark.StrategyReturn),
+			Alpha:            JSONFloat(r.benchmark.Alpha),
+			Correlation:      JSONFloat(r.benchmark.Correlation),
+		}
+	}
+	return JSONReport{
+		Summary: JSONSummary{
+			InitialBalance:        JSONFloat(r.result.InitialBalance),
+			FinalBalance:          JSONFloat(r.result.FinalBalance),
+			TotalPnL:              JSONFloat(r.result.TotalPnL),
+			TotalReturn:           JSONFloat(r.result.TotalReturn),
+			TotalCost:             JSONFloat(r.result.TotalCost),
+			TotalTrades:           r.result.TotalTrades,
+			WinRate:               JSONFloat(r.result.WinRate),
+			ProfitFactor:          JSONFloat(r.result.ProfitFactor),
+			MaxDrawdown:           JSONFloat(r.result.MaxDrawdown),
+			SharpeRatio:           JSONFloat(r.result.SharpeRatio),
+			AnnualizedSharpeRatio: JSONFloat(r.calculator.CalculateAnnualizedSharpeRatio()),
+		},
+		Benchmark: benchmark,
+		CostAnalysis: JSONCostAnalysis{
+			SpreadCost:            JSONFloat(costs.SpreadCost),
+			SlippageCost:          JSONFloat(costs.SlippageCost),
+			Commission:            JSONFloat(costs.Commission),
+			Swap:                  JSONFloat(costs.Swap),
+			TotalCost:             JSONFloat(costs.TotalCost),
+			GrossPnL:              JSONFloat(costs.GrossPnL),
+			NetPnL:                JSONFloat(costs.NetPnL),
+			GrossProfitFactor:     JSONFloat(costs.GrossProfitFactor),
+			NetProfitFactor:       JSONFloat(costs.NetProfitFactor),
+			GrossWinRate:          JSONFloat(costs.GrossWinRate),
+			NetWinRate:            JSONFloat(costs.NetWinRate),
+			CostPercentOfGrossPnL: JSONFloat(costs.CostPercentOfGrossPnL),
+		},
+		DetailedMetrics: JSONDetailedMetrics{
+			GrossProfit:          JSONFloat(r.result.GrossProfit),
+			GrossLoss:            JSONFloat(r.result.GrossLoss),
+			LargestWin:           JSONFloat(r.result.LargestWin),
+			LargestLoss:          JSONFloat(r.result.LargestLoss),
+			AverageWin:           JSONFloat(r.result.AverageWin),
+			AverageLoss:          JSONFloat(r.result.AverageLoss),
+			AverageWinPips:       JSONFloat(r.calculator.CalculateAverageWinPips()),
+			AverageLossPips:      JSONFloat(r.calculator.CalculateAverageLossPips()),
+			MaxConsecutiveWins:   r.calculator.CalculateMaxConsecutiveWins(),
+			MaxConsecutiveLosses: r.calculator.CalculateMaxConsecutiveLosses(),
+			SortinoRatio:         JSONFloat(r.calculator.CalculateSortinoRatio()),
+			CalmarRatio:          JSONFloat(r.calculator.CalculateCalmarRatio()),
+			RiskRewardRatio:      JSONFloat(r.calculator.CalculateRiskRewardRatio()),
+			TradingFrequency:     JSONFloat(r.calculator.CalculateTradingFrequency()),
+			ExpectancyPerTrade:   JSONFloat(r.calculator.CalculateExpectedValue()),
+			ExpectancyPerDay:     JSONFloat(r.calculator.CalculateExpectancyPerDay(r.runPeriod)),
+			ExpectancyPerBar:     JSONFloat(r.calculator.CalculateExpectancyPerBar(r.runBars)),
+			AverageHoldingHours:  JSONFloat(r.calculator.CalculateAverageHoldingPeriod().Hours()),
+			HoldingPeriod: JSONHoldingPeriod{
+				MinHours:               holding.Min.Hours(),
+				MedianHours:            holding.Median.Hours(),
+				MaxHours:               holding.Max.Hours(),
+				StandardDeviationHours: holding.StandardDeviation.Hours(),
+				UnderOneHour:           holding.UnderOneHour,
+				OneToFourHours:         holding.OneToFourHours,
+				FourHoursToOneDay:      holding.FourHoursToOneDay,
+				OverOneDay:             holding.OverOneDay,
+			},
+		},
+		BySymbol:        r.symbolSummaries(),
+		BestTrade:       r.calculator.GetBestTrade(),
+		WorstTrade:      r.calculator.GetWorstTrade(),
+		Streaks:         r.calculator.CalculateStreaks(),
+		TopContributors: r.topContributorsJSON(),
+		DrawdownPeriods: r.drawdownsJSON(),
+		Trades:          trades,
+	}
+}
+
+// topContributorsJSON は損益寄与上位取引をJSON出力用に変換します。
+func (r *Report) topContributorsJSON() []JSONContributor {
+	contributors := r.calculator.CalculateTopContributors(r.topContributors)
+	
+	result := make([]JSONContributor, 0, len(contributors))
+	for _, trade := range contributors {
+		result = append(result, JSONContributor{
+			Trade: *trade,
+			Share: JSONFloat(r.calculator.CalculatePnLShare(trade)),
+		})
+	}
+	
+	return result
+}
+
+// drawdownsJSON はドローダウン区間をJSON出力用に変換します。
+func (r *Report) drawdownsJSON() []JSONDrawdown {
+	periods := r.topDrawdownPeriods()
+	
+	result := make([]JSONDrawdown, 0, len(periods))
+	for _, period := range periods {
+		drawdown := JSONDrawdown{
+			StartTime:     period.StartTime,
+			TroughTime:    period.TroughTime,
+			Depth:         JSONFloat(period.Depth),
+			DurationHours: JSONFloat(period.Duration.Hours()),
+			Recovered:     period.Recovered,
+		}
+		if period.Recovered {
+			recoveryTime := period.RecoveryTime
+			drawdown.RecoveryTime = &recoveryTime
+		}
+		result = append(result, drawdown)
+	}
+	
+	return result
+}
+
+// symbolSummaries はシンボル別の統計サマリーをシンボル順で返します。
+func (r *Report) symbolSummaries() []JSONSymbolSummary {
+	calculators := r.calculator.symbolCalculators()
+	symbols := make([]string, 0, len(calculators))
+	for symbol := range calculators {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	
+	summaries := make([]JSONSymbolSummary, 0, len(symbols))
+	for _, symbol := range symbols {
+		calculator := calculators[symbol]
+		summaries = append(summaries, JSONSymbolSummary{
+			Symbol:       symbol,
+			TotalTrades:  calculator.CalculateTotalTrades(),
+			TotalPnL:     JSONFloat(calculator.CalculateTotalPnL()),
+			WinRate:      JSONFloat(calculator.CalculateWinRate() * 100),
+			ProfitFactor: JSONFloat(calculator.CalculateProfitFactor()),
+			MaxDrawdown:  JSONFloat(calculator.CalculateMaxDrawdown()),
+		})
+	}
+	
+	return summaries
+}
+
+// displaySymbol はレポート表示用のシンボル名を返します。空の場合は「不明」とします。
+func displaySymbol(symbol string) string {
+	if symbol == "" {
+		return "不明"
+	}
+	return symbol
+}
+
+// GenerateJSONLinesReport は取引履歴を1行1取引のJSON Lines（NDJSON）形式で生成します。
+// 各行は models.Trade の MarshalJSON の形式で改行で終わり、取引がない場合は空文字列を返します。
+func (r *Report) GenerateJSONLinesReport() string {
+	var sb strings.Builder
+	encoder := json.NewEncoder(&sb)
+	
+	for _, trade := range r.calculator.GetTrades() {
+		if err := encoder.Encode(trade); err != nil {
+			errorLine, _ := json.Marshal(map[string]string{"error": err.Error(), "id": trade.ID})
+			sb.Write(errorLine)
+			sb.WriteString("\n")
+		}
+	}
+	
+	return sb.String()
 }
 
 // GenerateCSVReport はCSV形式の取引履歴レポートを生成します。
@@ -174,6 +765,223 @@ func (r *Report) GenerateCSVReport() string {
 	return sb.String()
 }
 
+// GenerateHTMLReport は外部依存のない単一ファイルのHTMLレポートを生成します。
+// エクイティカーブはインラインSVGで埋め込まれます。
+func (r *Report) GenerateHTMLReport() string {
+	var sb strings.Builder
+	
+	sb.WriteString("<!DOCTYPE html>\n<html lang=\"ja\">\n<head>\n<meta charset=\"utf-8\">\n")
+	sb.WriteString("<title>バックテスト結果レポート</title>\n")
+	sb.WriteString("<style>\n")
+	sb.WriteString("body{font-family:sans-serif;margin:2em;color:#222;background:#fafafa}\n")
+	sb.WriteString("h1{border-bottom:2px solid #2c7be5;padding-bottom:.3em}\n")
+	sb.WriteString("h2{margin-top:1.5em;color:#2c7be5}\n")
+	sb.WriteString("table{border-collapse:collapse;margin:.5em 0;background:#fff}\n")
+	sb.WriteString("th,td{border:1px solid #ddd;padding:.4em .8em;text-align:right}\n")
+	sb.WriteString("th{background:#f0f4fa;text-align:left}\n")
+	sb.WriteString(".pos{color:#1a7f37}.neg{color:#cf222e}\n")
+	sb.WriteString("</style>\n</head>\n<body>\n")
+	sb.WriteString("<h1>バックテスト結果レポート</h1>\n")
+	
+	// エクイティカーブ
+	sb.WriteString("<h2>エクイティカーブ</h2>\n")
+	sb.WriteString(r.equityCurveSVG(800, 300))
+	
+	// 基本情報
+	writeHTMLTable(&sb, "基本情報", [][2]string{
+		{"期間", fmt.Sprintf("%s ～ %s",
+			r.result.StartTime.Format("2006-01-02 15:04:05"),
+			r.result.EndTime.Format("2006-01-02 15:04:05"))},
+		{"初期残高", fmt.Sprintf("%.2f", r.result.InitialBalance)},
+		{"最終残高", fmt.Sprintf("%.2f", r.result.FinalBalance)},
+	})
+	
+	// 損益情報
+	writeHTMLTable(&sb, "損益情報", [][2]string{
+		{"総損益", fmt.Sprintf("%.2f", r.result.TotalPnL)},
+		{"総リターン", fmt.Sprintf("%.2f%%", r.result.TotalReturn)},
+		{"総コスト", fmt.Sprintf("%.2f", r.result.TotalCost)},
+		{"総利益", fmt.Sprintf("%.2f", r.result.GrossProfit)},
+		{"総損失", fmt.Sprintf("%.2f", r.result.GrossLoss)},
+		{"最大利益", fmt.Sprintf("%.2f", r.result.LargestWin)},
+		{"最大損失", fmt.Sprintf("%.2f", r.result.LargestLoss)},
+	})
+	
+	// コスト分析
+	writeHTMLTable(&sb, "コスト分析", r.costAnalysisRows())
+	
+	// ベンチマーク比較
+	if r.benchmark != nil {
+		writeHTMLTable(&sb, "ベンチマーク比較", r.benchmarkRows())
+	}
+	
+	// 取引統計
+	writeHTMLTable(&sb, "取引統計", [][2]string{
+		{"総取引数", fmt.Sprintf("%d", r.result.TotalTrades)},
+		{"勝ち取引", fmt.Sprintf("%d", r.result.WinningTrades)},
+		{"負け取引", fmt.Sprintf("%d", r.result.LosingTrades)},
+		{"勝率", fmt.Sprintf("%.2f%%", r.result.WinRate)},
+		{"平均利益", fmt.Sprintf("%.2f", r.result.AverageWin)},
+		{"平均損失", fmt.Sprintf("%.2f", r.result.AverageLoss)},
+		{"平均利益（pips）", fmt.Sprintf("%.1f", r.calculator.CalculateAverageWinPips())},
+		{"平均損失（pips）", fmt.Sprintf("%.1f", r.calculator.CalculateAverageLossPips())},
+	})
+	
+	// リスク指標
+	writeHTMLTable(&sb, "リスク指標", [][2]string{
+		{"最大ドローダウン", fmt.Sprintf("%.2f", r.result.MaxDrawdown)},
+		{"シャープレシオ", fmt.Sprintf("%.4f", r.result.SharpeRatio)},
+		{"年率シャープレシオ", fmt.Sprintf("%.4f", r.calculator.CalculateAnnualizedSharpeRatio())},
+		{"プロフィットファクター", fmt.Sprintf("%.4f", r.result.ProfitFactor)},
+		{"ソルティノレシオ", fmt.Sprintf("%.4f", r.calculator.CalculateSortinoRatio())},
+		{"カルマーレシオ", fmt.Sprintf("%.4f", r.calculator.CalculateCalmarRatio())},
+	})
+	
+	// ドローダウン期間
+	writeHTMLTable(&sb, "ドローダウン期間", r.drawdownRows())
+	
+	// 取引パフォーマンス
+	holding := r.calculator.CalculateHoldingPeriodStats()
+	writeHTMLTable(&sb, "取引パフォーマンス", [][2]string{
+		{"平均保有期間", fmt.Sprintf("%.2f時間", r.calculator.CalculateAverageHoldingPeriod().Hours())},
+		{"最大連勝", fmt.Sprintf("%d", r.calculator.CalculateMaxConsecutiveWins())},
+		{"最大連敗", fmt.Sprintf("%d", r.calculator.CalculateMaxConsecutiveLosses())},
+		{"取引頻度", fmt.Sprintf("%.2f取引/日", r.calculator.CalculateTradingFrequency())},
+		{"リスクリワード比", fmt.Sprintf("%.4f", r.calculator.CalculateRiskRewardRatio())},
+		{"期待値（1取引あたり）", fmt.Sprintf("%.2f", r.calculator.CalculateExpectedValue())},
+		{"期待値（1日あたり）", fmt.Sprintf("%.2f", r.calculator.CalculateExpectancyPerDay(r.runPeriod))},
+		{"期待値（1バーあたり）", fmt.Sprintf("%.4f", r.calculator.CalculateExpectancyPerBar(r.runBars))},
+		{"保有期間（最小/中央値/最大）", fmt.Sprintf("%.2f / %.2f / %.2f時間", holding.Min.Hours(), holding.Median.Hours(), holding.Max.Hours())},
+		{"保有期間の標準偏差", fmt.Sprintf("%.2f時間", holding.StandardDeviation.Hours())},
+		{"保有期間分布", fmt.Sprintf("1時間未満 %d / 1-4時間 %d / 4時間-1日 %d / 1日以上 %d",
+			holding.UnderOneHour, holding.OneToFourHours, holding.FourHoursToOneDay, holding.OverOneDay)},
+	})
+	
+	// 連勝・連敗
+	writeHTMLTable(&sb, "連勝・連敗", r.streakSummaryRows())
+	
+	// 損益寄与上位取引
+	writeHTMLTable(&sb, "損益寄与上位取引", r.topContributorRows())
+	
+	// ベスト/ワースト取引
+	writeHTMLTable(&sb, "ベスト/ワースト取引", [][2]string{
+		{"ベスト取引", formatSpotlightTrade(r.calculator.GetBestTrade())},
+		{"ワースト取引", formatSpotlightTrade(r.calculator.GetWorstTrade())},
+	})
+	
+	// シンボル別統計
+	symbolRows := make([][2]string, 0)
+	for _, summary := range r.symbolSummaries() {
+		symbolRows = append(symbolRows, [2]string{
+			displaySymbol(summary.Symbol),
+			fmt.Sprintf("取引数 %d / 総損益 %.2f / 勝率 %.2f%% / PF %.4f / 最大DD %.2f",
+				summary.TotalTrades,
+				float64(summary.TotalPnL),
+				float64(summary.WinRate),
+				float64(summary.ProfitFactor),
+				float64(summary.MaxDrawdown)),
+		})
+	}
+	writeHTMLTable(&sb, "シンボル別統計", symbolRows)
+	
+	// 取引履歴
+	sb.WriteString("<h2>取引履歴</h2>\n<table>\n")
+	sb.WriteString("<tr><th>ID</th><th>Side</th><th>Size</th><th>EntryPrice</th><th>ExitPrice</th><th>PnL</th><th>OpenTime</th><th>CloseTime</th></tr>\n")
+	for _, trade := range r.calculator.GetTrades() {
+		class := "pos"
+		if trade.IsLosing() {
+			class = "neg"
+		}
+		sb.WriteString(fmt.Sprintf(
+			"<tr><td>%s</td><td>%s</td><td>%.2f</td><td>%.5f</td><td>%.5f</td><td class=\"%s\">%.2f</td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(trade.ID),
+			trade.Side.String(),
+			trade.Size,
+			trade.EntryPrice,
+			trade.ExitPrice,
+			class,
+			trade.PnL,
+			trade.OpenTime.Format("2006-01-02 15:04:05"),
+			trade.CloseTime.Format("2006-01-02 15:04:05"),
+		))
+	}
+	sb.WriteString("</table>\n")
+	
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
+
+// writeHTMLTable は項目名と値のペアをHTMLテーブルとして書き出します。
+func writeHTMLTable(sb *strings.Builder, title string, rows [][2]string) {
+	sb.WriteString(fmt.Sprintf("<h2>%s</h2>\n<table>\n", html.EscapeString(title)))
+	for _, row := range rows {
+		sb.WriteString(fmt.Sprintf("<tr><th>%s</th><td>%s</td></tr>\n",
+			html.EscapeString(row[0]), html.EscapeString(row[1])))
+	}
+	sb.WriteString("</table>\n")
+}
+
+// equityCurve は初期残高と各取引の損益から残高推移を計算します。
+func (r *Report) equityCurve() []float64 {
+	trades := r.calculator.GetTrades()
+	curve := make([]float64, 0, len(trades)+1)
+	
+	equity := r.result.InitialBalance
+	curve = append(curve, equity)
+	for _, trade := range trades {
+		equity += trade.PnL
+		curve = append(curve, equity)
+	}
+	
+	return curve
+}
+
+// equityCurveSVG はエクイティカーブをインラインSVGとして描画します。
+func (r *Report) equityCurveSVG(width, height int) string {
+	curve := r.equityCurve()
+	
+	minEquity, maxEquity := curve[0], curve[0]
+	for _, equity := range curve {
+		if equity < minEquity {
+			minEquity = equity
+		}
+		if equity > maxEquity {
+			maxEquity = equity
+		}
+	}
+	
+	// 値幅がない場合は中央に水平線を描く
+	valueRange := maxEquity - minEquity
+	if valueRange == 0 {
+		valueRange = 1
+		minEquity -= 0.5
+	}
+	
+	padding := 10.0
+	plotWidth := float64(width) - 2*padding
+	plotHeight := float64(height) - 2*padding
+	
+	points := make([]string, 0, len(curve))
+	for i, equity := range curve {
+		x := padding
+		if len(curve) > 1 {
+			x += plotWidth * float64(i) / float64(len(curve)-1)
+		}
+		y := padding + plotHeight*(1-(equity-minEquity)/valueRange)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		width, height, width, height))
+	sb.WriteString(fmt.Sprintf("<rect x=\"0\" y=\"0\" width=\"%d\" height=\"%d\" fill=\"#fff\" stroke=\"#ddd\"/>\n", width, height))
+	sb.WriteString(fmt.Sprintf("<polyline fill=\"none\" stroke=\"#2c7be5\" stroke-width=\"2\" points=\"%s\"/>\n",
+		strings.Join(points, " ")))
+	sb.WriteString("</svg>\n")
+	
+	return sb.String()
+}
+
 // GenerateReport は指定されたフォーマットでレポートを生成します。
 func (r *Report) GenerateReport(format ReportFormat) string {
 	switch format {
@@ -181,6 +989,10 @@ func (r *Report) GenerateReport(format ReportFormat) string {
 		return r.GenerateJSONReport()
 	case FormatCSV:
 		return r.GenerateCSVReport()
+	case FormatHTML:
+		return r.GenerateHTMLReport()
+	case FormatJSONLines:
+		return r.GenerateJSONLinesReport()
 	default:
 		return r.GenerateTextReport()
 	}
@@ -190,6 +1002,7 @@ func (r *Report) GenerateReport(format ReportFormat) string {
 func (r *Report) GetSummaryMetrics() map[string]interface{} {
 	return map[string]interface{}{
 		"total_return":         r.result.TotalReturn,
+		"total_cost":           r.result.TotalCost,
 		"total_trades":         r.result.TotalTrades,
 		"win_rate":             r.result.WinRate,
 		"profit_factor":        r.result.ProfitFactor,
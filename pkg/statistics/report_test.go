@@ -1,8 +1,12 @@
 package statistics
 
 import (
+	"bytes"
+	"encoding/json"
+	"math"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/RuiHirano/fx-backtesting/pkg/models"
 )
@@ -58,9 +62,14 @@ func TestReport_GenerateTextReport(t *testing.T) {
 		"初期残高",
 		"最終残高",
 		"総損益",
+		"総コスト: 0.00",
 		"勝率",
 		"シャープレシオ",
+		"年率シャープレシオ",
 		"最大ドローダウン",
+		"ベスト取引: trade-3",
+		"ワースト取引: trade-2",
+		"保有期間分布: 1時間未満 0 / 1-4時間 7 / 4時間-1日 0 / 1日以上 0",
 	}
 	
 	for _, element := range requiredElements {
@@ -92,6 +101,7 @@ func TestReport_GenerateJSONReport(t *testing.T) {
 		"win_rate",
 		"profit_factor",
 		"sharpe_ratio",
+		"annualized_sharpe_ratio",
 		"max_drawdown",
 	}
 	
@@ -107,6 +117,406 @@ func TestReport_GenerateJSONReport(t *testing.T) {
 	}
 }
 
+// Report GenerateJSONReport 取引一覧テスト
+func TestReport_GenerateJSONReport_Trades(t *testing.T) {
+	trades := createTestTrades()
+	report := NewReport(trades, 10000.0)
+
+	var parsed JSONReport
+	if err := json.Unmarshal([]byte(report.GenerateJSONReport()), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+
+	if len(parsed.Trades) != len(trades) {
+		t.Fatalf("Expected %d trades, got %d", len(trades), len(parsed.Trades))
+	}
+
+	first := parsed.Trades[0]
+	if first.ID != "trade-1" {
+		t.Errorf("Expected first trade ID trade-1, got %s", first.ID)
+	}
+	if first.Symbol != "EURUSD" {
+		t.Errorf("Expected symbol EURUSD, got %s", first.Symbol)
+	}
+	if first.Side != models.Buy {
+		t.Errorf("Expected side Buy, got %s", first.Side)
+	}
+	if first.PnL != 150.0 {
+		t.Errorf("Expected PnL 150.0, got %f", first.PnL)
+	}
+	if first.Duration != time.Hour {
+		t.Errorf("Expected duration 1 hour, got %v", first.Duration)
+	}
+	if !first.OpenTime.Equal(trades[0].OpenTime) {
+		t.Errorf("Expected open time %v, got %v", trades[0].OpenTime, first.OpenTime)
+	}
+	if first.Status != models.TradeClosed {
+		t.Errorf("Expected status Closed, got %v", first.Status)
+	}
+
+	// 取引は models.Trade の MarshalJSON と同じ形で、コスト・pip・換算のフィールドも含む
+	detailed := createTrade("trade-detail", 50.0, trades[0].OpenTime)
+	detailed.SlippageCost = 1.5
+	detailed.Commission = 2.0
+	detailed.Swap = 0.5
+	detailed.PipSize = 0.0001
+	detailed.ContractSize = 100000
+	detailed.ConversionRate = 1.25
+	expected, err := json.Marshal(detailed)
+	if err != nil {
+		t.Fatalf("Expected trade to marshal, got %v", err)
+	}
+	var raw struct {
+		Trades          []json.RawMessage `json:"trades"`
+		BestTrade       json.RawMessage   `json:"best_trade"`
+		TopContributors []map[string]any  `json:"top_contributors"`
+	}
+	if err := json.Unmarshal([]byte(NewReport([]*models.Trade{detailed}, 10000.0).GenerateJSONReport()), &raw); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	for _, got := range []json.RawMessage{raw.Trades[0], raw.BestTrade} {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, got); err != nil {
+			t.Fatalf("Expected valid trade JSON, got %v", err)
+		}
+		if compact.String() != string(expected) {
+			t.Errorf("Expected trade JSON %s, got %s", expected, compact.String())
+		}
+	}
+	for _, key := range []string{"status", "slippage_cost", "commission", "swap", "pip_size", "pnl_pips", "contract_size", "conversion_rate", "share"} {
+		if _, ok := raw.TopContributors[0][key]; !ok {
+			t.Errorf("Expected top contributor to contain %s", key)
+		}
+	}
+
+	// ベスト/ワースト取引
+	if parsed.BestTrade == nil || parsed.BestTrade.ID != "trade-3" {
+		t.Errorf("Expected best trade trade-3, got %+v", parsed.BestTrade)
+	}
+	if parsed.WorstTrade == nil || parsed.WorstTrade.ID != "trade-2" {
+		t.Errorf("Expected worst trade trade-2, got %+v", parsed.WorstTrade)
+	}
+
+	// 取引がない場合も空配列として出力される
+	emptyReport := NewReport([]*models.Trade{}, 10000.0)
+	if !strings.Contains(emptyReport.GenerateJSONReport(), `"trades": []`) {
+		t.Error("Expected empty trades array in JSON report")
+	}
+}
+
+// Report GenerateJSONReport 無限大・NaNテスト
+func TestReport_GenerateJSONReport_NonFiniteValues(t *testing.T) {
+	// 勝ち取引のみの場合、プロフィットファクターは無限大になる
+	baseTime := time.Now()
+	trades := []*models.Trade{
+		createTrade("trade-1", 100.0, baseTime),
+		createTrade("trade-2", 50.0, baseTime.Add(time.Hour)),
+	}
+	report := NewReport(trades, 10000.0)
+
+	var parsed struct {
+		Summary map[string]interface{} `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(report.GenerateJSONReport()), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON for winning-only trades, got error: %v", err)
+	}
+
+	summary := parsed.Summary
+	if value, ok := summary["profit_factor"]; !ok || value != nil {
+		t.Errorf("Expected profit_factor to be null, got %v", value)
+	}
+	if summary["total_pnl"] != 150.0 {
+		t.Errorf("Expected total_pnl 150.0, got %v", summary["total_pnl"])
+	}
+
+	// JSONFloat単体の変換
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1.5, "1.5"},
+		{math.Inf(1), "null"},
+		{math.Inf(-1), "null"},
+		{math.NaN(), "null"},
+	}
+	for _, test := range tests {
+		data, err := json.Marshal(JSONFloat(test.value))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(data) != test.expected {
+			t.Errorf("Expected %s for %v, got %s", test.expected, test.value, string(data))
+		}
+	}
+}
+
+// Report シンボル別統計テスト
+func TestReport_SymbolBreakdown(t *testing.T) {
+	baseTime := time.Now()
+	jpyTrade := createTrade("trade-2", -40.0, baseTime.Add(time.Hour))
+	jpyTrade.Symbol = "USDJPY"
+	trades := []*models.Trade{
+		createTrade("trade-1", 120.0, baseTime),
+		jpyTrade,
+	}
+	report := NewReport(trades, 10000.0)
+
+	textReport := report.GenerateTextReport()
+	for _, element := range []string{"シンボル別統計", "EURUSD: 取引数 1 総損益 120.00", "USDJPY: 取引数 1 総損益 -40.00"} {
+		if !strings.Contains(textReport, element) {
+			t.Errorf("Text report missing element: %s", element)
+		}
+	}
+
+	var parsed JSONReport
+	if err := json.Unmarshal([]byte(report.GenerateJSONReport()), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	if len(parsed.BySymbol) != 2 {
+		t.Fatalf("Expected 2 symbol summaries, got %d", len(parsed.BySymbol))
+	}
+	if parsed.BySymbol[0].Symbol != "EURUSD" || parsed.BySymbol[1].Symbol != "USDJPY" {
+		t.Errorf("Expected symbols sorted as EURUSD, USDJPY, got %s, %s", parsed.BySymbol[0].Symbol, parsed.BySymbol[1].Symbol)
+	}
+	if parsed.BySymbol[1].TotalPnL != -40.0 {
+		t.Errorf("Expected USDJPY PnL -40.0, got %f", parsed.BySymbol[1].TotalPnL)
+	}
+
+	if !strings.Contains(report.GenerateHTMLReport(), "シンボル別統計") {
+		t.Error("Expected HTML report to include symbol breakdown")
+	}
+}
+
+// Report 連勝・連敗セクション テスト
+func TestReport_Streaks(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []*models.Trade{
+		createTrade("trade-1", 100.0, baseTime),
+		createTrade("trade-2", 50.0, baseTime.Add(time.Hour)),
+		createTrade("trade-3", -30.0, baseTime.Add(2*time.Hour)),
+	}
+	report := NewReport(trades, 10000.0)
+
+	textReport := report.GenerateTextReport()
+	for _, element := range []string{"【連勝・連敗】", "連勝区間: 1回 平均 2.00取引", "最長連勝: 2取引 損益 150.00", "最長連敗: 1取引 損益 -30.00"} {
+		if !strings.Contains(textReport, element) {
+			t.Errorf("Text report missing element: %s", element)
+		}
+	}
+
+	var parsed JSONReport
+	if err := json.Unmarshal([]byte(report.GenerateJSONReport()), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	if len(parsed.Streaks) != 2 || parsed.Streaks[0].Type != StreakWin || parsed.Streaks[1].Type != StreakLoss {
+		t.Errorf("Expected win then loss streaks in JSON, got %+v", parsed.Streaks)
+	}
+}
+
+// Report 損益寄与上位取引セクション テスト
+func TestReport_TopContributors(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []*models.Trade{
+		createTrade("trade-1", 100.0, baseTime),
+		createTrade("trade-2", 300.0, baseTime.Add(time.Hour)),
+		createTrade("trade-3", -200.0, baseTime.Add(2*time.Hour)),
+	}
+	report := NewReport(trades, 10000.0)
+	report.SetTopContributorsCount(2)
+
+	textReport := report.GenerateTextReport()
+	for _, element := range []string{"【損益寄与上位取引】", "1. trade-2 EURUSD Buy 損益 300.00 (寄与率 150.00%)", "2. trade-3 EURUSD Buy 損益 -200.00 (寄与率 -100.00%)"} {
+		if !strings.Contains(textReport, element) {
+			t.Errorf("Text report missing element: %s", element)
+		}
+	}
+	if strings.Contains(textReport, "3. trade-1") {
+		t.Error("Expected only 2 contributors in text report")
+	}
+
+	var parsed JSONReport
+	if err := json.Unmarshal([]byte(report.GenerateJSONReport()), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	if len(parsed.TopContributors) != 2 {
+		t.Fatalf("Expected 2 contributors in JSON, got %d", len(parsed.TopContributors))
+	}
+	if parsed.TopContributors[0].ID != "trade-2" || parsed.TopContributors[0].Share != 150.0 {
+		t.Errorf("Expected trade-2 with share 150, got %s with %f", parsed.TopContributors[0].ID, float64(parsed.TopContributors[0].Share))
+	}
+
+	if !strings.Contains(report.GenerateHTMLReport(), "損益寄与上位取引") {
+		t.Error("Expected HTML report to include top contributors")
+	}
+}
+
+// Report 時間あたり期待値テスト
+func TestReport_Expectancy(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []*models.Trade{
+		createTrade("trade-1", 300.0, baseTime),
+		createTrade("trade-2", -100.0, baseTime.Add(23*time.Hour)),
+	}
+	report := NewReport(trades, 10000.0)
+
+	// 実行期間を設定しない場合は取引期間（1日）で正規化
+	textReport := report.GenerateTextReport()
+	for _, element := range []string{"期待値（1取引あたり）: 100.00", "期待値（1日あたり）: 200.00", "期待値（1バーあたり）: 0.0000"} {
+		if !strings.Contains(textReport, element) {
+			t.Errorf("Text report missing element: %s", element)
+		}
+	}
+
+	report.SetRunPeriod(4*24*time.Hour, 400)
+	textReport = report.GenerateTextReport()
+	for _, element := range []string{"期待値（1日あたり）: 50.00", "期待値（1バーあたり）: 0.5000"} {
+		if !strings.Contains(textReport, element) {
+			t.Errorf("Text report missing element: %s", element)
+		}
+	}
+
+	var parsed JSONReport
+	if err := json.Unmarshal([]byte(report.GenerateJSONReport()), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	metrics := parsed.DetailedMetrics
+	if metrics.ExpectancyPerTrade != 100.0 || metrics.ExpectancyPerDay != 50.0 || metrics.ExpectancyPerBar != 0.5 {
+		t.Errorf("Unexpected expectancy in JSON: %+v", metrics)
+	}
+
+	if !strings.Contains(report.GenerateHTMLReport(), "期待値（1日あたり）") {
+		t.Error("Expected HTML report to include expectancy per day")
+	}
+}
+
+// Report pips単位の平均損益テスト
+func TestReport_Pips(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []*models.Trade{
+		createTrade("trade-1", 30.0, baseTime),
+		createTrade("trade-2", -10.0, baseTime.Add(time.Hour)),
+	}
+	for _, trade := range trades {
+		trade.PipSize = 0.0001
+	}
+	report := NewReport(trades, 10000.0)
+
+	textReport := report.GenerateTextReport()
+	for _, element := range []string{"平均利益（pips）: 30.0", "平均損失（pips）: 10.0"} {
+		if !strings.Contains(textReport, element) {
+			t.Errorf("Text report missing element: %s", element)
+		}
+	}
+
+	var parsed JSONReport
+	if err := json.Unmarshal([]byte(report.GenerateJSONReport()), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	metrics := parsed.DetailedMetrics
+	if math.Abs(float64(metrics.AverageWinPips)-30.0) > 1e-6 || math.Abs(float64(metrics.AverageLossPips)-10.0) > 1e-6 {
+		t.Errorf("Unexpected pips in JSON: win %v, loss %v", metrics.AverageWinPips, metrics.AverageLossPips)
+	}
+
+	if !strings.Contains(report.GenerateHTMLReport(), "平均利益（pips）") {
+		t.Error("Expected HTML report to include average win in pips")
+	}
+}
+
+func TestReport_CostAnalysis(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	winner := createTrade("trade-1", 30.0, baseTime)
+	winner.SpreadCost, winner.Commission = 3.0, 2.0
+	loser := createTrade("trade-2", -10.0, baseTime.Add(time.Hour))
+	loser.SpreadCost, loser.Swap = 3.0, 2.0
+	report := NewReport([]*models.Trade{winner, loser}, 10000.0)
+
+	textReport := report.GenerateTextReport()
+	for _, element := range []string{
+		"【コスト分析】",
+		"手数料: 2.00",
+		"スワップ: 2.00",
+		"コスト合計: 10.00",
+		"総損益（コスト控除前 / 控除後）: 30.00 / 20.00",
+		"コスト控除前の総損益に対するコスト: 33.33%",
+	} {
+		if !strings.Contains(textReport, element) {
+			t.Errorf("Text report missing element: %s", element)
+		}
+	}
+
+	var parsed JSONReport
+	if err := json.Unmarshal([]byte(report.GenerateJSONReport()), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	costs := parsed.CostAnalysis
+	if costs.SpreadCost != 6.0 || costs.Commission != 2.0 || costs.Swap != 2.0 || costs.TotalCost != 10.0 {
+		t.Errorf("Unexpected cost breakdown in JSON: %+v", costs)
+	}
+	if costs.GrossPnL != 30.0 || costs.NetPnL != 20.0 {
+		t.Errorf("Expected gross 30 and net 20 in JSON, got %v / %v", costs.GrossPnL, costs.NetPnL)
+	}
+
+	if !strings.Contains(report.GenerateHTMLReport(), "<h2>コスト分析</h2>") {
+		t.Error("Expected HTML report to include cost analysis section")
+	}
+
+	// コスト控除前でも負けている場合は割合を出さない
+	losing := NewReport([]*models.Trade{loser}, 10000.0)
+	if !strings.Contains(losing.GenerateTextReport(), "算出不可") {
+		t.Error("Expected text report to mark cost percent as unavailable")
+	}
+	if !strings.Contains(losing.GenerateJSONReport(), `"cost_percent_of_gross_pnl": null`) {
+		t.Error("Expected null cost percent in JSON report")
+	}
+}
+
+// Report ドローダウン期間テスト
+func TestReport_DrawdownPeriods(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []*models.Trade{
+		createTrade("trade-1", 100.0, baseTime),
+		createTrade("trade-2", -80.0, baseTime.Add(time.Hour)),
+		createTrade("trade-3", 100.0, baseTime.Add(2*time.Hour)),
+		createTrade("trade-4", -200.0, baseTime.Add(3*time.Hour)),
+	}
+	report := NewReport(trades, 10000.0)
+	report.SetTopDrawdownsCount(1)
+
+	textReport := report.GenerateTextReport()
+	for _, element := range []string{"【ドローダウン期間】", "1. 下落 200.00 期間 1.00時間 (開始 2024-01-01 03:00:00 底 2024-01-01 04:00:00 回復 未回復)"} {
+		if !strings.Contains(textReport, element) {
+			t.Errorf("Text report missing element: %s", element)
+		}
+	}
+	if strings.Contains(textReport, "2. 下落") {
+		t.Error("Expected only 1 drawdown period in text report")
+	}
+
+	var parsed JSONReport
+	if err := json.Unmarshal([]byte(report.GenerateJSONReport()), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	if len(parsed.DrawdownPeriods) != 1 {
+		t.Fatalf("Expected 1 drawdown period in JSON, got %d", len(parsed.DrawdownPeriods))
+	}
+	if parsed.DrawdownPeriods[0].Depth != 200.0 || parsed.DrawdownPeriods[0].RecoveryTime != nil {
+		t.Errorf("Expected unrecovered drawdown of 200, got %+v", parsed.DrawdownPeriods[0])
+	}
+
+	// 回復した区間は回復時刻を出力する
+	report.SetTopDrawdownsCount(2)
+	if err := json.Unmarshal([]byte(report.GenerateJSONReport()), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	if len(parsed.DrawdownPeriods) != 2 || parsed.DrawdownPeriods[1].RecoveryTime == nil || !parsed.DrawdownPeriods[1].RecoveryTime.Equal(baseTime.Add(3*time.Hour)) {
+		t.Errorf("Expected recovered drawdown with recovery time, got %+v", parsed.DrawdownPeriods)
+	}
+
+	if !strings.Contains(report.GenerateHTMLReport(), "ドローダウン期間") {
+		t.Error("Expected HTML report to include drawdown periods")
+	}
+}
+
 // Report GenerateCSVReport テスト
 func TestReport_GenerateCSVReport(t *testing.T) {
 	trades := createTestTrades()
@@ -141,6 +551,142 @@ func TestReport_GenerateCSVReport(t *testing.T) {
 	}
 }
 
+// Report GenerateJSONLinesReport テスト
+func TestReport_GenerateJSONLinesReport(t *testing.T) {
+	trades := createTestTrades()
+	report := NewReport(trades, 10000.0)
+	
+	jsonlReport := report.GenerateJSONLinesReport()
+	if !strings.HasSuffix(jsonlReport, "\n") {
+		t.Error("Expected JSON Lines report to end with newline")
+	}
+	
+	lines := strings.Split(strings.TrimSuffix(jsonlReport, "\n"), "\n")
+	if len(lines) != len(trades) {
+		t.Fatalf("Expected %d lines, got %d", len(trades), len(lines))
+	}
+	
+	// 各行が独立したJSONオブジェクトで、全フィールドを含むこと
+	for i, line := range lines {
+		var trade map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &trade); err != nil {
+			t.Fatalf("Expected line %d to be valid JSON, got %v", i+1, err)
+		}
+		for _, key := range []string{"id", "symbol", "side", "size", "entry_price", "exit_price", "pnl", "status", "open_time", "close_time", "duration_hours", "spread_cost"} {
+			if _, ok := trade[key]; !ok {
+				t.Errorf("Expected line %d to contain %s", i+1, key)
+			}
+		}
+		if trade["id"] != trades[i].ID {
+			t.Errorf("Expected line %d id %s, got %v", i+1, trades[i].ID, trade["id"])
+		}
+	}
+	
+	// 各行は models.Trade の MarshalJSON と同じ形で、コスト・pip・換算のフィールドも含む
+	detailed := createTrade("trade-detail", 50.0, trades[0].OpenTime)
+	detailed.SlippageCost = 1.5
+	detailed.Commission = 2.0
+	detailed.Swap = 0.5
+	detailed.PipSize = 0.0001
+	detailed.ContractSize = 100000
+	detailed.ConversionRate = 1.25
+	expected, err := json.Marshal(detailed)
+	if err != nil {
+		t.Fatalf("Expected trade to marshal, got %v", err)
+	}
+	if line := NewReport([]*models.Trade{detailed}, 10000.0).GenerateJSONLinesReport(); line != string(expected)+"\n" {
+		t.Errorf("Expected line %s, got %s", expected, line)
+	}
+	for _, key := range []string{"slippage_cost", "commission", "swap", "pip_size", "pnl_pips", "contract_size", "conversion_rate"} {
+		if !strings.Contains(string(expected), `"`+key+`"`) {
+			t.Errorf("Expected trade JSON to contain %s", key)
+		}
+	}
+	
+	// 改行や引用符を含む値もエスケープされ1行に収まること
+	escaped := NewReport([]*models.Trade{createTrade("a\n\"b\"", 10.0, trades[0].OpenTime)}, 10000.0).GenerateJSONLinesReport()
+	if strings.Count(escaped, "\n") != 1 {
+		t.Errorf("Expected escaped trade on a single line, got %q", escaped)
+	}
+	var trade models.Trade
+	if err := json.Unmarshal([]byte(escaped), &trade); err != nil || trade.ID != "a\n\"b\"" {
+		t.Errorf("Expected escaped ID to round-trip, got %q (%v)", trade.ID, err)
+	}
+	
+	// 取引がない場合は空
+	if empty := NewReport(nil, 10000.0).GenerateJSONLinesReport(); empty != "" {
+		t.Errorf("Expected empty report, got %q", empty)
+	}
+}
+
+// Report GenerateHTMLReport テスト
+func TestReport_GenerateHTMLReport(t *testing.T) {
+	trades := createTestTrades()
+	report := NewReport(trades, 10000.0)
+	
+	htmlReport := report.GenerateHTMLReport()
+	
+	if !strings.HasPrefix(htmlReport, "<!DOCTYPE html>") {
+		t.Error("Expected HTML report to start with doctype")
+	}
+	
+	requiredElements := []string{
+		"<svg",
+		"<polyline",
+		"<style>",
+		"基本情報",
+		"リスク指標",
+		"取引履歴",
+		"trade-1",
+	}
+	
+	for _, element := range requiredElements {
+		if !strings.Contains(htmlReport, element) {
+			t.Errorf("HTML report missing required element: %s", element)
+		}
+	}
+	
+	// 外部リソースに依存していないこと
+	if strings.Contains(htmlReport, "<script src") || strings.Contains(htmlReport, "<link") {
+		t.Error("Expected HTML report to be self-contained")
+	}
+	
+	// 取引IDがエスケープされること
+	escaped := NewReport([]*models.Trade{createTrade("<b>x</b>", 10.0, trades[0].OpenTime)}, 10000.0)
+	if strings.Contains(escaped.GenerateHTMLReport(), "<b>x</b>") {
+		t.Error("Expected trade ID to be HTML-escaped")
+	}
+}
+
+// ParseReportFormat テスト
+func TestParseReportFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ReportFormat
+	}{
+		{"text", FormatText},
+		{"json", FormatJSON},
+		{"csv", FormatCSV},
+		{"HTML", FormatHTML},
+		{"jsonl", FormatJSONLines},
+		{"ndjson", FormatJSONLines},
+	}
+	
+	for _, test := range tests {
+		format, err := ParseReportFormat(test.input)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", test.input, err)
+		}
+		if format != test.expected {
+			t.Errorf("Expected %v for %s, got %v", test.expected, test.input, format)
+		}
+	}
+	
+	if _, err := ParseReportFormat("pdf"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
 // Report GenerateReport（フォーマット指定）テスト
 func TestReport_GenerateReport(t *testing.T) {
 	trades := createTestTrades()
@@ -163,6 +709,18 @@ func TestReport_GenerateReport(t *testing.T) {
 	if !strings.Contains(csvReport, "ID,Symbol,Side") {
 		t.Error("Expected CSV format report")
 	}
+	
+	// HTML形式
+	htmlReport := report.GenerateReport(FormatHTML)
+	if !strings.Contains(htmlReport, "<html") {
+		t.Error("Expected HTML format report")
+	}
+	
+	// JSON Lines形式
+	jsonlReport := report.GenerateReport(FormatJSONLines)
+	if !strings.HasPrefix(jsonlReport, `{"id":`) {
+		t.Error("Expected JSON Lines format report")
+	}
 }
 
 // Report GetSummaryMetrics テスト
@@ -0,0 +1,68 @@
+package strategy
+
+import (
+	"errors"
+	"math"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// ATR はWilderの平滑化による平均真の値幅（Average True Range）です。
+// 最初の period 本の真の値幅の単純平均で初期化し、以降は Wilder の平滑化で更新します。
+// 最初のローソク足は前の終値が無いため、高値 - 安値を真の値幅とします。
+type ATR struct {
+	period    int
+	count     int
+	prevClose float64
+	sum       float64
+	value     float64
+}
+
+// NewATR は新しいATRを作成します。
+func NewATR(period int) (*ATR, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return &ATR{period: period}, nil
+}
+
+// Add はローソク足を追加してATRを更新します。
+func (a *ATR) Add(candle models.Candle) {
+	trueRange := candle.High - candle.Low
+	if a.count > 0 {
+		trueRange = math.Max(trueRange, math.Max(math.Abs(candle.High-a.prevClose), math.Abs(candle.Low-a.prevClose)))
+	}
+	a.prevClose = candle.Close
+	a.count++
+	
+	period := float64(a.period)
+	if a.count <= a.period {
+		a.sum += trueRange
+		if a.count == a.period {
+			a.value = a.sum / period
+		}
+		return
+	}
+	a.value = (a.value*(period-1) + trueRange) / period
+}
+
+// IsReady は period 本のローソク足が揃い、ATRが計算できるかを返します。
+func (a *ATR) IsReady() bool {
+	return a.count >= a.period
+}
+
+// Value は現在のATRを返します。計算できない場合は0を返します。
+func (a *ATR) Value() float64 {
+	if !a.IsReady() {
+		return 0
+	}
+	return a.value
+}
+
+// Reset はATRを初期状態に戻します。
+func (a *ATR) Reset() {
+	a.count = 0
+	a.prevClose = 0
+	a.sum = 0
+	a.value = 0
+}
@@ -0,0 +1,52 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+func TestATR(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	candle := func(i int, high, low, close float64) models.Candle {
+		return *models.NewCandle(baseTime.Add(time.Duration(i)*time.Minute), close, high, low, close, 1000)
+	}
+	
+	t.Run("should use true range and Wilder smoothing", func(t *testing.T) {
+		atr, err := NewATR(2)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		// 最初の真の値幅は高値 - 安値 = 2
+		atr.Add(candle(0, 11, 9, 10))
+		if atr.IsReady() || atr.Value() != 0 {
+			t.Error("Expected ATR not to be ready before period candles")
+		}
+		
+		// 前の終値10からのギャップ: max(13-12, |13-10|, |12-10|) = 3 → (2+3)/2
+		atr.Add(candle(1, 13, 12, 12.5))
+		if !atr.IsReady() || atr.Value() != 2.5 {
+			t.Errorf("Value() = %f, want 2.5", atr.Value())
+		}
+		
+		// 真の値幅 max(12.5-11, |12.5-12.5|, |11-12.5|) = 1.5 → (2.5×1+1.5)/2
+		atr.Add(candle(2, 12.5, 11, 11))
+		if math.Abs(atr.Value()-2.0) > 1e-12 {
+			t.Errorf("Value() = %f, want 2", atr.Value())
+		}
+		
+		atr.Reset()
+		if atr.IsReady() || atr.Value() != 0 {
+			t.Error("Expected ATR to be cleared after Reset")
+		}
+	})
+	
+	t.Run("should reject non-positive period", func(t *testing.T) {
+		if _, err := NewATR(0); err == nil {
+			t.Error("Expected error for zero period")
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package strategy
+
+import (
+	"errors"
+	"math"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+)
+
+// BollingerBands は単純移動平均 ± k × 標準偏差のボリンジャーバンドです。
+// 標準偏差は直近 period 個の価格の母標準偏差です。
+type BollingerBands struct {
+	period     int
+	multiplier float64
+	prices     []float64
+	middle     float64
+	stdDev     float64
+}
+
+// NewBollingerBands は新しいBollingerBandsを作成します。一般的な設定は 20/2 です。
+func NewBollingerBands(period int, multiplier float64) (*BollingerBands, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	if multiplier <= 0 {
+		return nil, errors.New("multiplier must be positive")
+	}
+	return &BollingerBands{
+		period:     period,
+		multiplier: multiplier,
+		prices:     make([]float64, 0, period),
+	}, nil
+}
+
+// Add は価格を追加してバンドを更新します。
+func (b *BollingerBands) Add(price float64) {
+	b.prices = append(b.prices, price)
+	if len(b.prices) > b.period {
+		b.prices = b.prices[1:]
+	}
+	if !b.IsReady() {
+		return
+	}
+	
+	b.middle = average(b.prices)
+	variance := 0.0
+	for _, p := range b.prices {
+		variance += (p - b.middle) * (p - b.middle)
+	}
+	b.stdDev = math.Sqrt(variance / float64(len(b.prices)))
+}
+
+// IsReady は period 個の価格が揃い、バンドが計算できるかを返します。
+func (b *BollingerBands) IsReady() bool {
+	return len(b.prices) >= b.period
+}
+
+// Middle は中心線（単純移動平均）を返します。計算できない場合は0を返します。
+func (b *BollingerBands) Middle() float64 {
+	if !b.IsReady() {
+		return 0
+	}
+	return b.middle
+}
+
+// Upper は上側バンドを返します。計算できない場合は0を返します。
+func (b *BollingerBands) Upper() float64 {
+	if !b.IsReady() {
+		return 0
+	}
+	return b.middle + b.multiplier*b.stdDev
+}
+
+// Lower は下側バンドを返します。計算できない場合は0を返します。
+func (b *BollingerBands) Lower() float64 {
+	if !b.IsReady() {
+		return 0
+	}
+	return b.middle - b.multiplier*b.stdDev
+}
+
+// Width はバンド幅 (上側 - 下側) / 中心線 を返します。ボラティリティの目安として使えます。
+// 計算できない場合は0を返します。
+func (b *BollingerBands) Width() float64 {
+	if !b.IsReady() || b.middle == 0 {
+		return 0
+	}
+	return (b.Upper() - b.Lower()) / b.middle
+}
+
+// Reset はバンドを初期状態に戻します。
+func (b *BollingerBands) Reset() {
+	b.prices = b.prices[:0]
+	b.middle = 0
+	b.stdDev = 0
+}
+
+// BollingerStrategy はボリンジャーバンドによる逆張り戦略です。
+// 終値が下側バンドを下回ったら買いエントリーし、中心線以上に戻ったら保有ポジションを全て決済します。
+type BollingerStrategy struct {
+	executor
+	bands *BollingerBands
+}
+
+// NewBollingerStrategy は新しいBollingerStrategyを作成します。
+func NewBollingerStrategy(symbol string, period int, multiplier, size float64) (*BollingerStrategy, error) {
+	bands, err := NewBollingerBands(period, multiplier)
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, errors.New("size must be positive")
+	}
+	
+	return &BollingerStrategy{
+		executor: executor{symbol: symbol, size: size},
+		bands:    bands,
+	}, nil
+}
+
+// Evaluate は現在の終値でバンドを更新し、シグナルを返します。
+func (s *BollingerStrategy) Evaluate(bt *backtester.Backtester) (Signal, error) {
+	price := bt.GetCurrentPrice()
+	s.bands.Add(price)
+	if !s.bands.IsReady() {
+		return SignalHold, nil
+	}
+	
+	bt.PublishIndicator("bb_upper", s.bands.Upper())
+	bt.PublishIndicator("bb_middle", s.bands.Middle())
+	bt.PublishIndicator("bb_lower", s.bands.Lower())
+	
+	switch {
+	case price < s.bands.Lower():
+		return SignalBuy, nil
+	case price >= s.bands.Middle():
+		return SignalSell, nil
+	}
+	return SignalHold, nil
+}
+
+// OnBar はシグナルに応じて売買します。
+func (s *BollingerStrategy) OnBar(bt *backtester.Backtester) error {
+	signal, err := s.Evaluate(bt)
+	if err != nil {
+		return err
+	}
+	return s.execute(bt, signal)
+}
+
+// Reset はバンドの状態を初期化します。
+func (s *BollingerStrategy) Reset() {
+	s.executor.reset()
+	s.bands.Reset()
+}
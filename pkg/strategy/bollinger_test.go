@@ -0,0 +1,75 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBollingerBands(t *testing.T) {
+	t.Run("should compute bands from population standard deviation", func(t *testing.T) {
+		bands, err := NewBollingerBands(3, 2)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		bands.Add(1)
+		bands.Add(2)
+		if bands.IsReady() || bands.Upper() != 0 {
+			t.Error("Expected bands not to be ready before period prices")
+		}
+		
+		// [1, 2, 3] の平均2・母標準偏差 sqrt(2/3)
+		bands.Add(3)
+		stdDev := math.Sqrt(2.0 / 3.0)
+		if bands.Middle() != 2 {
+			t.Errorf("Middle() = %f, want 2", bands.Middle())
+		}
+		if math.Abs(bands.Upper()-(2+2*stdDev)) > 1e-12 || math.Abs(bands.Lower()-(2-2*stdDev)) > 1e-12 {
+			t.Errorf("Upper() = %f, Lower() = %f", bands.Upper(), bands.Lower())
+		}
+		if math.Abs(bands.Width()-2*stdDev) > 1e-12 {
+			t.Errorf("Width() = %f, want %f", bands.Width(), 2*stdDev)
+		}
+		
+		// 窓がずれて [2, 3, 4] になる
+		bands.Add(4)
+		if bands.Middle() != 3 {
+			t.Errorf("Middle() = %f, want 3", bands.Middle())
+		}
+		
+		bands.Reset()
+		if bands.IsReady() || bands.Middle() != 0 {
+			t.Error("Expected bands to be cleared after Reset")
+		}
+	})
+	
+	t.Run("should reject invalid parameters", func(t *testing.T) {
+		if _, err := NewBollingerBands(0, 2); err == nil {
+			t.Error("Expected error for zero period")
+		}
+		if _, err := NewBollingerBands(20, 0); err == nil {
+			t.Error("Expected error for zero multiplier")
+		}
+	})
+}
+
+func TestBollingerStrategy_Run(t *testing.T) {
+	bt := newTestBacktester(t, []float64{1.10, 1.10, 1.10, 1.07, 1.08, 1.09, 1.09})
+	strategy, err := NewBollingerStrategy("EURUSD", 3, 1, 10000.0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	
+	result, err := bt.Run(strategy)
+	if err != nil {
+		t.Fatalf("Unexpected error from Run: %v", err)
+	}
+	
+	if result.TotalTrades != 1 {
+		t.Fatalf("TotalTrades = %d, want 1", result.TotalTrades)
+	}
+	// 下側バンドを下回った 1.07 で買い、中心線 1.08 以上に戻った 1.09 で決済
+	if math.Abs(result.FinalBalance-10200.0) > 1e-6 {
+		t.Errorf("FinalBalance = %f, want 10200", result.FinalBalance)
+	}
+}
@@ -0,0 +1,117 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+)
+
+// SignalStrategy は売買を行わずにシグナルだけを返す戦略です。
+// CompositeStrategy で組み合わせる戦略はこのインターフェースを実装します。
+type SignalStrategy interface {
+	// Evaluate は現在のバーでインジケーターを更新し、シグナルを返します。
+	Evaluate(bt *backtester.Backtester) (Signal, error)
+	// Reset はインジケーターの状態を初期化します。
+	Reset()
+}
+
+// AggregationPolicy は複数のシグナルをまとめる方法です。
+type AggregationPolicy int
+
+const (
+	// AggregateAllAgree は全ての戦略が同じシグナルを出した場合のみ、そのシグナルを採用します。
+	AggregateAllAgree AggregationPolicy = iota
+	// AggregateMajority は過半数の戦略が出したシグナルを採用します。
+	AggregateMajority
+	// AggregateFirstToFire は並び順で最初に Hold 以外を出した戦略のシグナルを採用します。
+	AggregateFirstToFire
+)
+
+// CompositeStrategy は複数の戦略のシグナルを集約して売買する戦略です。
+// 全ての子戦略を毎バー評価してから集約するため、採用されなかった戦略のインジケーターも更新されます。
+type CompositeStrategy struct {
+	executor
+	policy     AggregationPolicy
+	strategies []SignalStrategy
+}
+
+// NewCompositeStrategy は新しいCompositeStrategyを作成します。
+func NewCompositeStrategy(symbol string, policy AggregationPolicy, strategies []SignalStrategy, size float64) (*CompositeStrategy, error) {
+	if len(strategies) == 0 {
+		return nil, errors.New("at least one strategy is required")
+	}
+	if policy < AggregateAllAgree || policy > AggregateFirstToFire {
+		return nil, fmt.Errorf("unknown aggregation policy: %d", policy)
+	}
+	if size <= 0 {
+		return nil, errors.New("size must be positive")
+	}
+	
+	return &CompositeStrategy{
+		executor:   executor{symbol: symbol, size: size},
+		policy:     policy,
+		strategies: append([]SignalStrategy(nil), strategies...),
+	}, nil
+}
+
+// Evaluate は全ての子戦略を評価し、集約したシグナルを返します。
+func (s *CompositeStrategy) Evaluate(bt *backtester.Backtester) (Signal, error) {
+	signals := make([]Signal, 0, len(s.strategies))
+	for i, strategy := range s.strategies {
+		signal, err := strategy.Evaluate(bt)
+		if err != nil {
+			return SignalHold, fmt.Errorf("strategy %d: %w", i, err)
+		}
+		signals = append(signals, signal)
+	}
+	return aggregateSignals(s.policy, signals), nil
+}
+
+// OnBar は集約したシグナルに応じて売買します。
+func (s *CompositeStrategy) OnBar(bt *backtester.Backtester) error {
+	signal, err := s.Evaluate(bt)
+	if err != nil {
+		return err
+	}
+	return s.execute(bt, signal)
+}
+
+// Reset は全ての子戦略の状態を初期化します。
+func (s *CompositeStrategy) Reset() {
+	s.executor.reset()
+	for _, strategy := range s.strategies {
+		strategy.Reset()
+	}
+}
+
+// aggregateSignals はポリシーに従ってシグナルを集約します。
+func aggregateSignals(policy AggregationPolicy, signals []Signal) Signal {
+	switch policy {
+	case AggregateAllAgree:
+		first := signals[0]
+		for _, signal := range signals[1:] {
+			if signal != first {
+				return SignalHold
+			}
+		}
+		return first
+	case AggregateMajority:
+		counts := make(map[Signal]int)
+		for _, signal := range signals {
+			counts[signal]++
+		}
+		for _, signal := range []Signal{SignalBuy, SignalSell} {
+			if counts[signal]*2 > len(signals) {
+				return signal
+			}
+		}
+	case AggregateFirstToFire:
+		for _, signal := range signals {
+			if signal != SignalHold {
+				return signal
+			}
+		}
+	}
+	return SignalHold
+}
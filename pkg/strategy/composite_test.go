@@ -0,0 +1,128 @@
+package strategy
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+)
+
+// 組み込みの戦略は全て SignalStrategy として組み合わせられる
+var (
+	_ SignalStrategy = (*MovingAverageCross)(nil)
+	_ SignalStrategy = (*RSIStrategy)(nil)
+	_ SignalStrategy = (*MACDStrategy)(nil)
+	_ SignalStrategy = (*BollingerStrategy)(nil)
+	_ SignalStrategy = (*CompositeStrategy)(nil)
+)
+
+// scriptedStrategy は決められた順にシグナルを返すテスト用の戦略
+type scriptedStrategy struct {
+	signals []Signal
+	calls   int
+	resets  int
+	err     error
+}
+
+func (s *scriptedStrategy) Evaluate(bt *backtester.Backtester) (Signal, error) {
+	if s.err != nil {
+		return SignalHold, s.err
+	}
+	signal := SignalHold
+	if s.calls < len(s.signals) {
+		signal = s.signals[s.calls]
+	}
+	s.calls++
+	return signal, nil
+}
+
+func (s *scriptedStrategy) Reset() {
+	s.resets++
+	s.calls = 0
+}
+
+func TestAggregateSignals(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  AggregationPolicy
+		signals []Signal
+		want    Signal
+	}{
+		{name: "all agree on buy", policy: AggregateAllAgree, signals: []Signal{SignalBuy, SignalBuy, SignalBuy}, want: SignalBuy},
+		{name: "all agree with dissent", policy: AggregateAllAgree, signals: []Signal{SignalBuy, SignalBuy, SignalHold}, want: SignalHold},
+		{name: "majority buy", policy: AggregateMajority, signals: []Signal{SignalBuy, SignalHold, SignalBuy}, want: SignalBuy},
+		{name: "majority sell", policy: AggregateMajority, signals: []Signal{SignalSell, SignalSell, SignalBuy}, want: SignalSell},
+		{name: "no majority on tie", policy: AggregateMajority, signals: []Signal{SignalBuy, SignalSell}, want: SignalHold},
+		{name: "first to fire skips hold", policy: AggregateFirstToFire, signals: []Signal{SignalHold, SignalSell, SignalBuy}, want: SignalSell},
+		{name: "first to fire all hold", policy: AggregateFirstToFire, signals: []Signal{SignalHold, SignalHold}, want: SignalHold},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := aggregateSignals(tt.policy, tt.signals); got != tt.want {
+				t.Errorf("aggregateSignals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCompositeStrategy(t *testing.T) {
+	child := &scriptedStrategy{}
+	
+	if _, err := NewCompositeStrategy("EURUSD", AggregateMajority, nil, 1000.0); err == nil {
+		t.Error("Expected error for empty strategies")
+	}
+	if _, err := NewCompositeStrategy("EURUSD", AggregationPolicy(99), []SignalStrategy{child}, 1000.0); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+	if _, err := NewCompositeStrategy("EURUSD", AggregateMajority, []SignalStrategy{child}, 0); err == nil {
+		t.Error("Expected error for non-positive size")
+	}
+}
+
+func TestCompositeStrategy_Run(t *testing.T) {
+	t.Run("should trade only when majority agrees", func(t *testing.T) {
+		first := &scriptedStrategy{signals: []Signal{SignalBuy, SignalBuy, SignalHold, SignalSell}}
+		second := &scriptedStrategy{signals: []Signal{SignalHold, SignalBuy, SignalSell, SignalSell}}
+		third := &scriptedStrategy{signals: []Signal{SignalSell, SignalHold, SignalSell, SignalHold}}
+		composite, err := NewCompositeStrategy("EURUSD", AggregateMajority, []SignalStrategy{first, second, third}, 10000.0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		bt := newTestBacktester(t, []float64{1.10, 1.11, 1.12, 1.13, 1.14})
+		result, err := bt.Run(composite)
+		if err != nil {
+			t.Fatalf("Unexpected error from Run: %v", err)
+		}
+		
+		// 全ての子戦略が毎バー評価される
+		for i, child := range []*scriptedStrategy{first, second, third} {
+			if child.calls != 5 {
+				t.Errorf("strategy %d evaluated %d times, want 5", i, child.calls)
+			}
+		}
+		// 2本目 1.11 で買い（2/3）、3本目 1.12 で決済（2/3）
+		if result.TotalTrades != 1 || math.Abs(result.FinalBalance-10100.0) > 1e-6 {
+			t.Errorf("Got %d trades and balance %f, want 1 and 10100", result.TotalTrades, result.FinalBalance)
+		}
+		
+		composite.Reset()
+		for i, child := range []*scriptedStrategy{first, second, third} {
+			if child.resets != 1 {
+				t.Errorf("strategy %d reset %d times, want 1", i, child.resets)
+			}
+		}
+	})
+	
+	t.Run("should propagate child errors", func(t *testing.T) {
+		failing := &scriptedStrategy{err: errors.New("boom")}
+		composite, _ := NewCompositeStrategy("EURUSD", AggregateFirstToFire, []SignalStrategy{&scriptedStrategy{}, failing}, 10000.0)
+		
+		bt := newTestBacktester(t, []float64{1.10, 1.11})
+		if _, err := bt.Run(composite); err == nil || !errors.Is(err, failing.err) {
+			t.Errorf("Expected child error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package strategy
+
+import (
+	"errors"
+)
+
+// EMA は指数移動平均（Exponential Moving Average）です。
+// 最初の period 個の価格の単純平均で初期化し、以降は平滑化係数 2/(period+1) で更新します。
+type EMA struct {
+	period int
+	alpha  float64
+	count  int
+	sum    float64
+	value  float64
+}
+
+// NewEMA は新しいEMAを作成します。
+func NewEMA(period int) (*EMA, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return &EMA{
+		period: period,
+		alpha:  2.0 / float64(period+1),
+	}, nil
+}
+
+// Add は価格を追加してEMAを更新します。
+func (e *EMA) Add(price float64) {
+	e.count++
+	if e.count <= e.period {
+		e.sum += price
+		if e.count == e.period {
+			e.value = e.sum / float64(e.period)
+		}
+		return
+	}
+	e.value += e.alpha * (price - e.value)
+}
+
+// IsReady は period 個の価格が揃い、EMAが計算できるかを返します。
+func (e *EMA) IsReady() bool {
+	return e.count >= e.period
+}
+
+// Value は現在のEMAを返します。計算できない場合は0を返します。
+func (e *EMA) Value() float64 {
+	if !e.IsReady() {
+		return 0
+	}
+	return e.value
+}
+
+// Reset はEMAを初期状態に戻します。
+func (e *EMA) Reset() {
+	e.count = 0
+	e.sum = 0
+	e.value = 0
+}
@@ -0,0 +1,61 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEMA(t *testing.T) {
+	t.Run("should seed from SMA and apply smoothing factor", func(t *testing.T) {
+		ema, err := NewEMA(3)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		ema.Add(1)
+		ema.Add(2)
+		if ema.IsReady() || ema.Value() != 0 {
+			t.Error("Expected EMA not to be ready before period prices")
+		}
+		
+		// 最初の3個の単純平均 (1+2+3)/3 = 2
+		ema.Add(3)
+		if !ema.IsReady() || ema.Value() != 2 {
+			t.Errorf("Value() = %f, want 2", ema.Value())
+		}
+		
+		// 係数 2/(3+1) = 0.5 → 2 + 0.5×(6-2) = 4
+		ema.Add(6)
+		if math.Abs(ema.Value()-4) > 1e-12 {
+			t.Errorf("Value() = %f, want 4", ema.Value())
+		}
+		
+		// 4 + 0.5×(2-4) = 3
+		ema.Add(2)
+		if math.Abs(ema.Value()-3) > 1e-12 {
+			t.Errorf("Value() = %f, want 3", ema.Value())
+		}
+	})
+	
+	t.Run("should reset to initial state", func(t *testing.T) {
+		ema, _ := NewEMA(2)
+		ema.Add(1)
+		ema.Add(3)
+		ema.Reset()
+		if ema.IsReady() {
+			t.Error("Expected EMA not to be ready after Reset")
+		}
+		
+		ema.Add(5)
+		ema.Add(7)
+		if ema.Value() != 6 {
+			t.Errorf("Value() = %f, want 6", ema.Value())
+		}
+	})
+	
+	t.Run("should reject non-positive period", func(t *testing.T) {
+		if _, err := NewEMA(0); err == nil {
+			t.Error("Expected error for zero period")
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package strategy
+
+import (
+	"errors"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+)
+
+// Protection はエントリー時に設定するストップロス・テイクプロフィットの距離です。
+// 距離はエントリー時の価格からの値幅で、0は保護価格を設定しないことを表します。
+type Protection struct {
+	StopLoss   float64
+	TakeProfit float64
+	// ATRPeriod が0より大きい場合、StopLoss・TakeProfit をこの期間のATRの倍数として扱います。
+	ATRPeriod int
+}
+
+// Pyramiding は保有中の買いシグナルでポジションを積み増す設定です。
+type Pyramiding struct {
+	// MaxUnits は同時に保有するポジション数の上限です。1以下は積み増ししないことを表します。
+	MaxUnits int
+	// Step は直前のエントリー価格から有利な方向にこの値幅以上動いた場合にのみ積み増すことを表します。
+	Step float64
+}
+
+// executor はシグナルを注文に変換する、各戦略に共通の実行部です。
+type executor struct {
+	symbol         string
+	size           float64
+	protection     Protection
+	atr            *ATR
+	pyramiding     Pyramiding
+	lastEntryPrice float64
+}
+
+// SetProtection はエントリー時に設定するストップロス・テイクプロフィットを設定します。
+func (e *executor) SetProtection(protection Protection) error {
+	if protection.StopLoss < 0 || protection.TakeProfit < 0 {
+		return errors.New("protection distances must not be negative")
+	}
+	if protection.ATRPeriod < 0 {
+		return errors.New("ATR period must not be negative")
+	}
+	
+	e.protection = protection
+	e.atr = nil
+	if protection.ATRPeriod > 0 {
+		atr, err := NewATR(protection.ATRPeriod)
+		if err != nil {
+			return err
+		}
+		e.atr = atr
+	}
+	return nil
+}
+
+// SetPyramiding はポジションの積み増しを設定します。
+func (e *executor) SetPyramiding(pyramiding Pyramiding) error {
+	if pyramiding.Step < 0 {
+		return errors.New("pyramiding step must not be negative")
+	}
+	e.pyramiding = pyramiding
+	return nil
+}
+
+// canEnter は買いシグナルでエントリーできるかを返します。
+// ポジションが無い場合は常にエントリーし、保有中は積み増しの上限と値幅を満たす場合のみ積み増します。
+func (e *executor) canEnter(units int, price float64) bool {
+	if units == 0 {
+		return true
+	}
+	return units < e.pyramiding.MaxUnits && price-e.lastEntryPrice >= e.pyramiding.Step
+}
+
+// execute はシグナルに従って売買します。
+// 買いはポジションが無い場合か積み増しできる場合のみ、決済はポジションがある場合のみ行います。
+// ATRの倍数で保護価格を設定する場合、ATRが計算できるまでは買いエントリーしません。
+func (e *executor) execute(bt *backtester.Backtester, signal Signal) error {
+	if e.atr != nil {
+		if candles := bt.GetRecentCandles(1); len(candles) > 0 {
+			e.atr.Add(*candles[0])
+		}
+	}
+	
+	units := len(bt.GetPositions())
+	switch {
+	case signal == SignalBuy && e.canEnter(units, bt.GetCurrentPrice()):
+		unit := 1.0
+		if e.atr != nil {
+			if !e.atr.IsReady() {
+				return nil
+			}
+			unit = e.atr.Value()
+		}
+		
+		price := bt.GetCurrentPrice()
+		stopLoss, takeProfit := 0.0, 0.0
+		if e.protection.StopLoss > 0 {
+			stopLoss = price - e.protection.StopLoss*unit
+		}
+		if e.protection.TakeProfit > 0 {
+			takeProfit = price + e.protection.TakeProfit*unit
+		}
+		if err := bt.BuyWithProtection(e.symbol, e.size, stopLoss, takeProfit); err != nil {
+			return err
+		}
+		e.lastEntryPrice = price
+		return nil
+	case signal == SignalSell && units > 0:
+		return bt.CloseAllPositions()
+	}
+	return nil
+}
+
+// reset はATRと積み増しの状態を初期化します。
+func (e *executor) reset() {
+	e.lastEntryPrice = 0
+	if e.atr != nil {
+		e.atr.Reset()
+	}
+}
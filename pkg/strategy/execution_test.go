@@ -0,0 +1,132 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+func TestExecutor_Protection(t *testing.T) {
+	newStrategy := func(t *testing.T, signals []Signal, protection Protection) *CompositeStrategy {
+		t.Helper()
+		composite, err := NewCompositeStrategy("EURUSD", AggregateFirstToFire, []SignalStrategy{&scriptedStrategy{signals: signals}}, 10000.0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := composite.SetProtection(protection); err != nil {
+			t.Fatalf("Unexpected error from SetProtection: %v", err)
+		}
+		return composite
+	}
+	
+	t.Run("should close at take profit set by price distance", func(t *testing.T) {
+		// 1.10 で買い、SL 1.08・TP 1.13 → 1.14 でテイクプロフィット
+		strategy := newStrategy(t, []Signal{SignalBuy, SignalBuy}, Protection{StopLoss: 0.02, TakeProfit: 0.03})
+		bt := newTestBacktester(t, []float64{1.10, 1.11, 1.14, 1.15})
+		
+		result, err := bt.Run(strategy)
+		if err != nil {
+			t.Fatalf("Unexpected error from Run: %v", err)
+		}
+		if result.TotalTrades != 1 || math.Abs(result.FinalBalance-10400.0) > 1e-6 {
+			t.Errorf("Got %d trades and balance %f, want 1 and 10400", result.TotalTrades, result.FinalBalance)
+		}
+	})
+	
+	t.Run("should scale stop loss by ATR and wait until ATR is ready", func(t *testing.T) {
+		// ATR(2) は2本目で0.01 → 1.12 で買い SL 1.11、1.10 で損切り
+		// 続けて ATR 0.015 → 1.10 で買い SL 1.085、1.06 で損切り
+		strategy := newStrategy(t, []Signal{SignalBuy, SignalBuy, SignalBuy}, Protection{StopLoss: 1, ATRPeriod: 2})
+		bt := newTestBacktester(t, []float64{1.10, 1.12, 1.10, 1.06})
+		
+		result, err := bt.Run(strategy)
+		if err != nil {
+			t.Fatalf("Unexpected error from Run: %v", err)
+		}
+		if result.TotalTrades != 2 || math.Abs(result.FinalBalance-9400.0) > 1e-6 {
+			t.Errorf("Got %d trades and balance %f, want 2 and 9400", result.TotalTrades, result.FinalBalance)
+		}
+	})
+	
+	t.Run("should not set protective prices for zero distances", func(t *testing.T) {
+		strategy := newStrategy(t, []Signal{SignalBuy}, Protection{})
+		bt := newTestBacktester(t, []float64{1.10, 1.00})
+		
+		if err := strategy.OnBar(bt); err != nil {
+			t.Fatalf("Unexpected error from OnBar: %v", err)
+		}
+		positions := bt.GetPositions()
+		if len(positions) != 1 || positions[0].StopLoss != 0 || positions[0].TakeProfit != 0 {
+			t.Fatalf("Expected one unprotected position, got %+v", positions)
+		}
+		bt.Forward()
+		if len(bt.GetPositions()) != 1 {
+			t.Error("Expected unprotected position to stay open")
+		}
+	})
+	
+	t.Run("should reject negative distances", func(t *testing.T) {
+		strategy, _ := NewRSIStrategy("EURUSD", 14, 30, 70, 1000.0)
+		if err := strategy.SetProtection(Protection{StopLoss: -1}); err == nil {
+			t.Error("Expected error for negative stop loss")
+		}
+		if err := strategy.SetProtection(Protection{StopLoss: 1, ATRPeriod: -1}); err == nil {
+			t.Error("Expected error for negative ATR period")
+		}
+	})
+}
+
+func TestExecutor_Pyramiding(t *testing.T) {
+	t.Run("should add units after favorable step up to max units", func(t *testing.T) {
+		composite, _ := NewCompositeStrategy("EURUSD", AggregateFirstToFire, []SignalStrategy{
+			&scriptedStrategy{signals: []Signal{SignalBuy, SignalBuy, SignalBuy, SignalBuy, SignalBuy}},
+		}, 10000.0)
+		if err := composite.SetPyramiding(Pyramiding{MaxUnits: 3, Step: 0.01}); err != nil {
+			t.Fatalf("Unexpected error from SetPyramiding: %v", err)
+		}
+		bt := newTestBacktester(t, []float64{1.10, 1.12, 1.125, 1.14, 1.16})
+		
+		// 1.10 で買い、1.12 で積み増し、1.125 は値幅不足、1.14 で積み増し
+		for i := 0; i < 4; i++ {
+			if err := composite.OnBar(bt); err != nil {
+				t.Fatalf("Unexpected error from OnBar: %v", err)
+			}
+			bt.Forward()
+		}
+		price, size := bt.GetAveragePrice("EURUSD", models.Buy)
+		if len(bt.GetPositions()) != 3 || math.Abs(price-1.12) > 1e-9 || size != 30000.0 {
+			t.Fatalf("Got %d positions, average %f, size %f; want 3, 1.12, 30000", len(bt.GetPositions()), price, size)
+		}
+		
+		// 1.16 は上限に達しているため積み増さない
+		result, err := bt.Run(composite)
+		if err != nil {
+			t.Fatalf("Unexpected error from Run: %v", err)
+		}
+		if result.TotalTrades != 3 || math.Abs(result.FinalBalance-11200.0) > 1e-6 {
+			t.Errorf("Got %d trades and balance %f, want 3 and 11200", result.TotalTrades, result.FinalBalance)
+		}
+	})
+	
+	t.Run("should keep single entry by default", func(t *testing.T) {
+		strategy := &scriptedStrategy{signals: []Signal{SignalBuy, SignalBuy, SignalBuy}}
+		composite, _ := NewCompositeStrategy("EURUSD", AggregateFirstToFire, []SignalStrategy{strategy}, 10000.0)
+		bt := newTestBacktester(t, []float64{1.10, 1.12, 1.14})
+		
+		for i := 0; i < 3; i++ {
+			composite.OnBar(bt)
+			bt.Forward()
+		}
+		if len(bt.GetPositions()) != 1 {
+			t.Errorf("Expected 1 position, got %d", len(bt.GetPositions()))
+		}
+	})
+	
+	t.Run("should reject negative step", func(t *testing.T) {
+		strategy, _ := NewRSIStrategy("EURUSD", 14, 30, 70, 1000.0)
+		if err := strategy.SetPyramiding(Pyramiding{MaxUnits: 2, Step: -0.01}); err == nil {
+			t.Error("Expected error for negative step")
+		}
+	})
+}
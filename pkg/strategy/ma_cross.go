@@ -0,0 +1,87 @@
+package strategy
+
+import (
+	"errors"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+)
+
+// MovingAverageCross は短期・長期の単純移動平均のクロスで売買する戦略です。
+// 短期MAが長期MAを上回ったら買いエントリーし、下回ったら保有ポジションを全て決済します。
+type MovingAverageCross struct {
+	executor
+	fastPeriod int
+	slowPeriod int
+	prices     []float64
+}
+
+// NewMovingAverageCross は新しいMovingAverageCrossを作成します。
+func NewMovingAverageCross(symbol string, size float64, fastPeriod, slowPeriod int) (*MovingAverageCross, error) {
+	if fastPeriod <= 0 || slowPeriod <= 0 {
+		return nil, errors.New("periods must be positive")
+	}
+	if fastPeriod >= slowPeriod {
+		return nil, errors.New("fast period must be shorter than slow period")
+	}
+	if size <= 0 {
+		return nil, errors.New("size must be positive")
+	}
+	
+	return &MovingAverageCross{
+		executor:   executor{symbol: symbol, size: size},
+		fastPeriod: fastPeriod,
+		slowPeriod: slowPeriod,
+		prices:     make([]float64, 0, slowPeriod),
+	}, nil
+}
+
+// Evaluate は現在の終値で移動平均を更新し、短期MAと長期MAの大小に応じたシグナルを返します。
+func (s *MovingAverageCross) Evaluate(bt *backtester.Backtester) (Signal, error) {
+	s.prices = append(s.prices, bt.GetCurrentPrice())
+	if len(s.prices) > s.slowPeriod {
+		s.prices = s.prices[1:]
+	}
+	
+	// 長期MAが計算できるまでは何もしない
+	if len(s.prices) < s.slowPeriod {
+		return SignalHold, nil
+	}
+	
+	fast := average(s.prices[len(s.prices)-s.fastPeriod:])
+	slow := average(s.prices)
+	
+	bt.PublishIndicator("fast_ma", fast)
+	bt.PublishIndicator("slow_ma", slow)
+	
+	switch {
+	case fast > slow:
+		return SignalBuy, nil
+	case fast < slow:
+		return SignalSell, nil
+	}
+	return SignalHold, nil
+}
+
+// OnBar はシグナルに応じて売買します。
+func (s *MovingAverageCross) OnBar(bt *backtester.Backtester) error {
+	signal, err := s.Evaluate(bt)
+	if err != nil {
+		return err
+	}
+	return s.execute(bt, signal)
+}
+
+// Reset は価格の履歴を初期化します。
+func (s *MovingAverageCross) Reset() {
+	s.executor.reset()
+	s.prices = s.prices[:0]
+}
+
+// average は価格の単純平均を返します。
+func average(prices []float64) float64 {
+	sum := 0.0
+	for _, price := range prices {
+		sum += price
+	}
+	return sum / float64(len(prices))
+}
@@ -0,0 +1,35 @@
+package strategy
+
+import (
+	"testing"
+)
+
+func TestNewMovingAverageCross(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       float64
+		fastPeriod int
+		slowPeriod int
+		wantErr    bool
+	}{
+		{name: "valid periods", size: 1000.0, fastPeriod: 5, slowPeriod: 20, wantErr: false},
+		{name: "non-positive period", size: 1000.0, fastPeriod: 0, slowPeriod: 20, wantErr: true},
+		{name: "fast not shorter than slow", size: 1000.0, fastPeriod: 20, slowPeriod: 20, wantErr: true},
+		{name: "non-positive size", size: 0, fastPeriod: 5, slowPeriod: 20, wantErr: true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMovingAverageCross("EURUSD", tt.size, tt.fastPeriod, tt.slowPeriod)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewMovingAverageCross() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAverage(t *testing.T) {
+	if got := average([]float64{1.0, 2.0, 3.0, 6.0}); got != 3.0 {
+		t.Errorf("average() = %f, want 3.0", got)
+	}
+}
@@ -0,0 +1,143 @@
+package strategy
+
+import (
+	"errors"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+)
+
+// MACD は短期・長期EMAの差（MACDライン）と、そのEMA（シグナルライン）を計算するインジケーターです。
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+}
+
+// NewMACD は新しいMACDを作成します。一般的な設定は 12/26/9 です。
+func NewMACD(fastPeriod, slowPeriod, signalPeriod int) (*MACD, error) {
+	if fastPeriod >= slowPeriod {
+		return nil, errors.New("fast period must be shorter than slow period")
+	}
+	fast, err := NewEMA(fastPeriod)
+	if err != nil {
+		return nil, err
+	}
+	slow, err := NewEMA(slowPeriod)
+	if err != nil {
+		return nil, err
+	}
+	signal, err := NewEMA(signalPeriod)
+	if err != nil {
+		return nil, err
+	}
+	return &MACD{fast: fast, slow: slow, signal: signal}, nil
+}
+
+// Add は価格を追加してMACDを更新します。
+// シグナルラインには長期EMAが計算できるようになってからのMACDラインを渡します。
+func (m *MACD) Add(price float64) {
+	m.fast.Add(price)
+	m.slow.Add(price)
+	if m.slow.IsReady() {
+		m.signal.Add(m.fast.Value() - m.slow.Value())
+	}
+}
+
+// IsReady はシグナルラインまで計算できるかを返します。
+func (m *MACD) IsReady() bool {
+	return m.signal.IsReady()
+}
+
+// Value はMACDライン（短期EMA - 長期EMA）を返します。計算できない場合は0を返します。
+func (m *MACD) Value() float64 {
+	if !m.slow.IsReady() {
+		return 0
+	}
+	return m.fast.Value() - m.slow.Value()
+}
+
+// Signal はシグナルラインを返します。計算できない場合は0を返します。
+func (m *MACD) Signal() float64 {
+	return m.signal.Value()
+}
+
+// Histogram はMACDラインとシグナルラインの差を返します。計算できない場合は0を返します。
+func (m *MACD) Histogram() float64 {
+	if !m.IsReady() {
+		return 0
+	}
+	return m.Value() - m.Signal()
+}
+
+// Reset はMACDを初期状態に戻します。
+func (m *MACD) Reset() {
+	m.fast.Reset()
+	m.slow.Reset()
+	m.signal.Reset()
+}
+
+// MACDStrategy はMACDラインとシグナルラインのクロスで売買する戦略です。
+// MACDラインがシグナルラインを上抜けたら買いエントリーし、下抜けたら保有ポジションを全て決済します。
+type MACDStrategy struct {
+	executor
+	macd          *MACD
+	prevHistogram float64
+	hasPrev       bool
+}
+
+// NewMACDStrategy は新しいMACDStrategyを作成します。
+func NewMACDStrategy(symbol string, fastPeriod, slowPeriod, signalPeriod int, size float64) (*MACDStrategy, error) {
+	macd, err := NewMACD(fastPeriod, slowPeriod, signalPeriod)
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, errors.New("size must be positive")
+	}
+	
+	return &MACDStrategy{
+		executor: executor{symbol: symbol, size: size},
+		macd:     macd,
+	}, nil
+}
+
+// Evaluate は現在の終値でMACDを更新し、クロスに応じたシグナルを返します。
+func (s *MACDStrategy) Evaluate(bt *backtester.Backtester) (Signal, error) {
+	s.macd.Add(bt.GetCurrentPrice())
+	if !s.macd.IsReady() {
+		return SignalHold, nil
+	}
+	
+	histogram := s.macd.Histogram()
+	prev, hasPrev := s.prevHistogram, s.hasPrev
+	s.prevHistogram, s.hasPrev = histogram, true
+	
+	// 最初に計算できたバーはクロスの判定に使わない
+	if !hasPrev {
+		return SignalHold, nil
+	}
+	switch {
+	case prev <= 0 && histogram > 0:
+		return SignalBuy, nil
+	case prev >= 0 && histogram < 0:
+		return SignalSell, nil
+	}
+	return SignalHold, nil
+}
+
+// OnBar はシグナルに応じて売買します。
+func (s *MACDStrategy) OnBar(bt *backtester.Backtester) error {
+	signal, err := s.Evaluate(bt)
+	if err != nil {
+		return err
+	}
+	return s.execute(bt, signal)
+}
+
+// Reset はMACDとクロス判定の状態を初期化します。
+func (s *MACDStrategy) Reset() {
+	s.executor.reset()
+	s.macd.Reset()
+	s.prevHistogram = 0
+	s.hasPrev = false
+}
@@ -0,0 +1,73 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMACD(t *testing.T) {
+	t.Run("should compute MACD, signal and histogram", func(t *testing.T) {
+		macd, err := NewMACD(2, 3, 2)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		for _, price := range []float64{1, 2, 3} {
+			macd.Add(price)
+		}
+		if macd.IsReady() {
+			t.Error("Expected MACD not to be ready before signal period")
+		}
+		
+		// 短期EMA 3.5・長期EMA 3 → MACD 0.5、シグナルは [0.5, 0.5] の平均
+		macd.Add(4)
+		if !macd.IsReady() {
+			t.Fatal("Expected MACD to be ready")
+		}
+		if math.Abs(macd.Value()-0.5) > 1e-12 || math.Abs(macd.Signal()-0.5) > 1e-12 || math.Abs(macd.Histogram()) > 1e-12 {
+			t.Errorf("Got MACD=%f signal=%f histogram=%f, want 0.5/0.5/0", macd.Value(), macd.Signal(), macd.Histogram())
+		}
+		
+		// 短期EMA 2.5・長期EMA 2.5 → MACD 0、シグナル 0.5 + 2/3×(0-0.5)
+		macd.Add(2)
+		wantSignal := 0.5 - 1.0/3
+		if math.Abs(macd.Value()) > 1e-12 || math.Abs(macd.Signal()-wantSignal) > 1e-12 || math.Abs(macd.Histogram()+wantSignal) > 1e-12 {
+			t.Errorf("Got MACD=%f signal=%f histogram=%f", macd.Value(), macd.Signal(), macd.Histogram())
+		}
+		
+		macd.Reset()
+		if macd.IsReady() || macd.Value() != 0 || macd.Histogram() != 0 {
+			t.Error("Expected MACD to be cleared after Reset")
+		}
+	})
+	
+	t.Run("should reject invalid periods", func(t *testing.T) {
+		if _, err := NewMACD(26, 12, 9); err == nil {
+			t.Error("Expected error when fast period is not shorter than slow period")
+		}
+		if _, err := NewMACD(12, 26, 0); err == nil {
+			t.Error("Expected error for zero signal period")
+		}
+	})
+}
+
+func TestMACDStrategy_Run(t *testing.T) {
+	bt := newTestBacktester(t, []float64{1.10, 1.09, 1.08, 1.07, 1.08, 1.10, 1.12, 1.11, 1.09, 1.07})
+	strategy, err := NewMACDStrategy("EURUSD", 2, 3, 2, 10000.0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	
+	result, err := bt.Run(strategy)
+	if err != nil {
+		t.Fatalf("Unexpected error from Run: %v", err)
+	}
+	
+	if result.TotalTrades != 1 {
+		t.Fatalf("TotalTrades = %d, want 1", result.TotalTrades)
+	}
+	// ヒストグラムが正に転じた 1.08 で買い、負に転じた 1.11 で決済
+	if math.Abs(result.FinalBalance-10300.0) > 1e-6 {
+		t.Errorf("FinalBalance = %f, want 10300", result.FinalBalance)
+	}
+}
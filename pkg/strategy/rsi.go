@@ -0,0 +1,148 @@
+package strategy
+
+import (
+	"errors"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+)
+
+// RSI はWilderの平滑化による相対力指数（Relative Strength Index）です。
+// 最初の period 個の値動きの単純平均で初期化し、以降は Wilder の平滑化で更新します。
+type RSI struct {
+	period    int
+	count     int
+	prevPrice float64
+	avgGain   float64
+	avgLoss   float64
+}
+
+// NewRSI は新しいRSIを作成します。
+func NewRSI(period int) (*RSI, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return &RSI{period: period}, nil
+}
+
+// Add は価格を追加してRSIを更新します。
+func (r *RSI) Add(price float64) {
+	if r.count == 0 {
+		r.prevPrice = price
+		r.count++
+		return
+	}
+	
+	change := price - r.prevPrice
+	r.prevPrice = price
+	gain, loss := 0.0, 0.0
+	if change > 0 {
+		gain = change
+	} else {
+		loss = -change
+	}
+	
+	period := float64(r.period)
+	if r.count <= r.period {
+		// 初期期間は値動きを合計し、period 個揃った時点で平均にする
+		r.avgGain += gain
+		r.avgLoss += loss
+		if r.count == r.period {
+			r.avgGain /= period
+			r.avgLoss /= period
+		}
+	} else {
+		r.avgGain = (r.avgGain*(period-1) + gain) / period
+		r.avgLoss = (r.avgLoss*(period-1) + loss) / period
+	}
+	r.count++
+}
+
+// IsReady は period 個の値動きが揃い、RSIが計算できるかを返します。
+func (r *RSI) IsReady() bool {
+	return r.count > r.period
+}
+
+// Value は現在のRSI（0〜100）を返します。計算できない場合は0を返します。
+func (r *RSI) Value() float64 {
+	if !r.IsReady() {
+		return 0
+	}
+	if r.avgLoss == 0 {
+		if r.avgGain == 0 {
+			return 50
+		}
+		return 100
+	}
+	rs := r.avgGain / r.avgLoss
+	return 100 - 100/(1+rs)
+}
+
+// Reset はRSIを初期状態に戻します。
+func (r *RSI) Reset() {
+	r.count = 0
+	r.prevPrice = 0
+	r.avgGain = 0
+	r.avgLoss = 0
+}
+
+// RSIStrategy はRSIで売買する戦略です。
+// RSIが売られすぎの閾値を下回ったら買いエントリーし、買われすぎの閾値を上回ったら保有ポジションを全て決済します。
+type RSIStrategy struct {
+	executor
+	oversold   float64
+	overbought float64
+	rsi        *RSI
+}
+
+// NewRSIStrategy は新しいRSIStrategyを作成します。
+func NewRSIStrategy(symbol string, period int, oversold, overbought, size float64) (*RSIStrategy, error) {
+	rsi, err := NewRSI(period)
+	if err != nil {
+		return nil, err
+	}
+	if oversold <= 0 || overbought >= 100 || oversold >= overbought {
+		return nil, errors.New("thresholds must satisfy 0 < oversold < overbought < 100")
+	}
+	if size <= 0 {
+		return nil, errors.New("size must be positive")
+	}
+	
+	return &RSIStrategy{
+		executor:   executor{symbol: symbol, size: size},
+		oversold:   oversold,
+		overbought: overbought,
+		rsi:        rsi,
+	}, nil
+}
+
+// Evaluate は現在の終値でRSIを更新し、シグナルを返します。
+func (s *RSIStrategy) Evaluate(bt *backtester.Backtester) (Signal, error) {
+	s.rsi.Add(bt.GetCurrentPrice())
+	if !s.rsi.IsReady() {
+		return SignalHold, nil
+	}
+	
+	value := s.rsi.Value()
+	switch {
+	case value < s.oversold:
+		return SignalBuy, nil
+	case value > s.overbought:
+		return SignalSell, nil
+	}
+	return SignalHold, nil
+}
+
+// OnBar はシグナルに応じて売買します。
+func (s *RSIStrategy) OnBar(bt *backtester.Backtester) error {
+	signal, err := s.Evaluate(bt)
+	if err != nil {
+		return err
+	}
+	return s.execute(bt, signal)
+}
+
+// Reset はRSIの状態を初期化します。
+func (s *RSIStrategy) Reset() {
+	s.executor.reset()
+	s.rsi.Reset()
+}
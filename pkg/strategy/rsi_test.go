@@ -0,0 +1,134 @@
+package strategy
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/RuiHirano/fx-backtesting/pkg/backtester"
+	"github.com/RuiHirano/fx-backtesting/pkg/data"
+	"github.com/RuiHirano/fx-backtesting/pkg/models"
+)
+
+// candlesFromCloses は 2024-01-01 09:00 から1分ごとの、終値のみ（始値・高値・安値も同値）のローソク足を作成する
+func candlesFromCloses(closes []float64) []models.Candle {
+	baseTime := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 0, len(closes))
+	for i, price := range closes {
+		candles = append(candles, *models.NewCandle(baseTime.Add(time.Duration(i)*time.Minute), price, price, price, price, 1000))
+	}
+	return candles
+}
+
+// newTestBacktester は終値のみのローソク足でバックテスターを作成・初期化する
+func newTestBacktester(t *testing.T, closes []float64) *backtester.Backtester {
+	t.Helper()
+	config := backtester.Config{
+		Broker: backtester.BrokerConfig{InitialBalance: 10000.0},
+	}
+	bt, err := backtester.NewBacktesterWithProvider(config, data.NewInMemoryProvider(candlesFromCloses(closes)))
+	if err != nil {
+		t.Fatalf("Failed to create backtester: %v", err)
+	}
+	if err := bt.Initialize(context.Background()); err != nil {
+		t.Fatalf("Failed to initialize backtester: %v", err)
+	}
+	return bt
+}
+
+func TestRSI(t *testing.T) {
+	t.Run("should use Wilder smoothing", func(t *testing.T) {
+		rsi, err := NewRSI(2)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		rsi.Add(10)
+		rsi.Add(11)
+		if rsi.IsReady() {
+			t.Error("Expected RSI not to be ready before period changes")
+		}
+		
+		// 値動き +1, -1 → 平均上昇0.5・平均下落0.5
+		rsi.Add(10)
+		if !rsi.IsReady() || rsi.Value() != 50 {
+			t.Errorf("Value() = %f, want 50", rsi.Value())
+		}
+		
+		// 値動き +2 → 平均上昇1.25・平均下落0.25 → RS=5
+		rsi.Add(12)
+		if math.Abs(rsi.Value()-(100-100.0/6)) > 1e-9 {
+			t.Errorf("Value() = %f, want %f", rsi.Value(), 100-100.0/6)
+		}
+	})
+	
+	t.Run("should handle one-sided moves and reset", func(t *testing.T) {
+		rsi, _ := NewRSI(3)
+		for _, price := range []float64{1, 2, 3, 4} {
+			rsi.Add(price)
+		}
+		if rsi.Value() != 100 {
+			t.Errorf("Value() = %f, want 100", rsi.Value())
+		}
+		
+		rsi.Reset()
+		if rsi.IsReady() || rsi.Value() != 0 {
+			t.Error("Expected RSI to be cleared after Reset")
+		}
+	})
+	
+	t.Run("should reject non-positive period", func(t *testing.T) {
+		if _, err := NewRSI(0); err == nil {
+			t.Error("Expected error for zero period")
+		}
+	})
+}
+
+func TestNewRSIStrategy(t *testing.T) {
+	tests := []struct {
+		name       string
+		period     int
+		oversold   float64
+		overbought float64
+		size       float64
+		wantErr    bool
+	}{
+		{name: "valid parameters", period: 14, oversold: 30, overbought: 70, size: 1000.0, wantErr: false},
+		{name: "non-positive period", period: 0, oversold: 30, overbought: 70, size: 1000.0, wantErr: true},
+		{name: "oversold above overbought", period: 14, oversold: 70, overbought: 30, size: 1000.0, wantErr: true},
+		{name: "threshold out of range", period: 14, oversold: 30, overbought: 100, size: 1000.0, wantErr: true},
+		{name: "non-positive size", period: 14, oversold: 30, overbought: 70, size: 0, wantErr: true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRSIStrategy("EURUSD", tt.period, tt.oversold, tt.overbought, tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRSIStrategy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRSIStrategy_Run(t *testing.T) {
+	// 2連続の下落でRSI=0（買い）、その後の上昇でRSI=75（決済）
+	bt := newTestBacktester(t, []float64{1.10, 1.09, 1.08, 1.09, 1.10, 1.11})
+	strategy, err := NewRSIStrategy("EURUSD", 2, 30, 70, 10000.0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	
+	result, err := bt.Run(strategy)
+	if err != nil {
+		t.Fatalf("Unexpected error from Run: %v", err)
+	}
+	
+	if result.TotalTrades != 1 {
+		t.Fatalf("TotalTrades = %d, want 1", result.TotalTrades)
+	}
+	// 1.08 で買い、1.10 で決済
+	if math.Abs(result.FinalBalance-10200.0) > 1e-6 {
+		t.Errorf("FinalBalance = %f, want 10200", result.FinalBalance)
+	}
+}
@@ -0,0 +1,25 @@
+package strategy
+
+// Signal は戦略が出す売買シグナルです。
+type Signal int
+
+const (
+	// SignalHold は何もしないことを表します。
+	SignalHold Signal = iota
+	// SignalBuy は買いエントリーを表します。
+	SignalBuy
+	// SignalSell は保有ポジションの決済を表します。
+	SignalSell
+)
+
+// String はシグナルの文字列表現を返します。
+func (s Signal) String() string {
+	switch s {
+	case SignalBuy:
+		return "buy"
+	case SignalSell:
+		return "sell"
+	default:
+		return "hold"
+	}
+}
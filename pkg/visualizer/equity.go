@@ -0,0 +1,65 @@
+package visualizer
+
+import "time"
+
+// EquityPoint はエクイティカーブ上の1点を表す
+type EquityPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Equity    float64   `json:"equity"`
+}
+
+// DownsampleEquity はエクイティ系列を最大 target 点に間引く。
+// 先頭・末尾を残し、間の系列を等分したバケットごとに最小値と最大値の点を時系列順に残すため、
+// 系列全体の最小値・最大値は必ず保持される。target が0以下、または系列が target 点以下の場合はそのままコピーを返す。
+func DownsampleEquity(points []EquityPoint, target int) []EquityPoint {
+	if target <= 0 || len(points) <= target {
+		result := make([]EquityPoint, len(points))
+		copy(result, points)
+		return result
+	}
+
+	if target < 4 {
+		// バケットを作れないため先頭と末尾のみ残す
+		if target == 1 {
+			return []EquityPoint{points[len(points)-1]}
+		}
+		return []EquityPoint{points[0], points[len(points)-1]}
+	}
+
+	inner := points[1 : len(points)-1]
+	buckets := (target - 2) / 2
+
+	result := make([]EquityPoint, 0, target)
+	result = append(result, points[0])
+
+	for b := 0; b < buckets; b++ {
+		start := b * len(inner) / buckets
+		end := (b + 1) * len(inner) / buckets
+		if start >= end {
+			continue
+		}
+
+		minIdx, maxIdx := start, start
+		for i := start + 1; i < end; i++ {
+			if inner[i].Equity < inner[minIdx].Equity {
+				minIdx = i
+			}
+			if inner[i].Equity > inner[maxIdx].Equity {
+				maxIdx = i
+			}
+		}
+
+		// 時系列順を保つ
+		first, second := minIdx, maxIdx
+		if first > second {
+			first, second = second, first
+		}
+		result = append(result, inner[first])
+		if second != first {
+			result = append(result, inner[second])
+		}
+	}
+
+	result = append(result, points[len(points)-1])
+	return result
+}
@@ -2,9 +2,14 @@ package visualizer
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,6 +28,9 @@ type Visualizer interface {
 	OnCandleUpdate(candle *models.Candle) error
 	OnTradeEvent(trade *models.Trade) error
 	OnStatisticsUpdate(stats *models.Statistics) error
+	OnEquityUpdate(timestamp time.Time, equity float64) error
+	OnIndicatorUpdate(name string, time time.Time, value float64) error
+	OnOrderFilled(order *models.Order, position *models.Position) error
 	OnBacktestStateChange(state models.BacktestState) error
 
 	// フロントエンドからのコマンド処理
@@ -39,22 +47,66 @@ type Visualizer interface {
 	// バックテスト制御
 	SetBacktestController(controller models.BacktestController)
 	GetBacktestController() models.BacktestController
+
+	// 接続時の履歴送信
+	SetHistoryProvider(provider HistoryProvider)
+
+	// /status エンドポイントの進捗
+	SetStatusProvider(provider StatusProvider)
+
+	// 複数バックテストのセッション
+	Session(id string) Visualizer
+	SessionIDs() []string
+}
+
+// HistoryProvider は接続時に送信する直近のローソク足を提供するインターフェース
+type HistoryProvider interface {
+	GetRecentCandles(count int) []*models.Candle
+}
+
+// StatusProvider は /status エンドポイントで返すバックテストの進捗を提供するインターフェース
+// WebSocket を保持せずに HTTP のポーリングで進捗や停滞を確認するために使う
+type StatusProvider interface {
+	GetStatus() models.BacktestStatus
 }
 
 // ControlCommand はフロントエンドからの制御コマンドを表す
+// SessionID は操作対象のセッション。空の場合は購読中のセッション（未購読ならデフォルトセッション）
 type ControlCommand struct {
 	Type      string                 `json:"type"`
 	Data      map[string]interface{} `json:"data"`
 	ClientID  string                 `json:"client_id"`
 	Timestamp time.Time              `json:"timestamp"`
+	SessionID string                 `json:"session_id,omitempty"`
 }
 
 // Message は WebSocket で送信されるメッセージの基本構造
+// SessionID は送信元のセッション。デフォルトセッションのメッセージでは省略される
 type Message struct {
 	Type      string      `json:"type"`
 	Data      interface{} `json:"data"`
 	Timestamp time.Time   `json:"timestamp"`
 	ClientID  string      `json:"client_id,omitempty"`
+	SessionID string      `json:"session_id,omitempty"`
+}
+
+// SessionInfo は /sessions エンドポイントで返すセッションの情報
+type SessionInfo struct {
+	ID     string                 `json:"session_id"`
+	Status *models.BacktestStatus `json:"status,omitempty"`
+}
+
+// OrderFilled は保留注文の約定を表す。ペーパーモードでは Position は nil
+type OrderFilled struct {
+	Order    *models.Order    `json:"order"`
+	Position *models.Position `json:"position,omitempty"`
+}
+
+// IndicatorUpdate はチャートに重ねて描画するインジケーターの1点を表す
+type IndicatorUpdate struct {
+	Name  string    `json:"name"`
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
 }
 
 // Config は Visualizer の設定を管理
@@ -67,6 +119,23 @@ type Config struct {
 	ClientTimeout     time.Duration `json:"client_timeout"`
 	BufferSize        int           `json:"buffer_size"`
 	LogLevel          string        `json:"log_level"`
+	HistorySize       int           `json:"history_size"`
+	AllowedOrigins    []string      `json:"allowed_origins"`
+	CertFile          string        `json:"cert_file"`
+	KeyFile           string        `json:"key_file"`
+	StaticDir         string        `json:"static_dir"`
+	EnableCompression bool          `json:"enable_compression"`
+	BatchInterval     time.Duration `json:"batch_interval"`
+	BatchSize         int           `json:"batch_size"`
+	EquityPoints      int           `json:"equity_points"`
+	ReplayBufferSize  int           `json:"replay_buffer_size"`
+	// Logger は診断ログの出力先。nil の場合は LogLevel に従って標準エラー出力に出力する
+	Logger *slog.Logger `json:"-"`
+}
+
+// TLSEnabled は証明書または秘密鍵が設定され、TLS (wss) での配信が要求されているかを返す
+func (c *Config) TLSEnabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
 }
 
 // DefaultConfig はデフォルトの設定を返す
@@ -80,43 +149,84 @@ func DefaultConfig() *Config {
 		ClientTimeout:     90 * time.Second,
 		BufferSize:        1024,
 		LogLevel:          "info",
+		HistorySize:       200,
+		AllowedOrigins:    []string{"*"},
+		EquityPoints:      500,
+		ReplayBufferSize:  1000,
 	}
 }
 
 // visualizerImpl は Visualizer インターフェースの実装
 type visualizerImpl struct {
-	config       *Config
-	server       *http.Server
-	upgrader     websocket.Upgrader
-	clients      map[string]*Client
-	clientsMutex sync.RWMutex
-	isRunning    bool
-	runningMutex sync.RWMutex
-	ctx          context.Context
-	cancel       context.CancelFunc
-	hub          *Hub
+	config             *Config
+	server             *http.Server
+	upgrader           websocket.Upgrader
+	clients            map[string]*Client
+	clientsMutex       sync.RWMutex
+	isRunning          bool
+	runningMutex       sync.RWMutex
+	ctx                context.Context
+	cancel             context.CancelFunc
+	hub                *Hub
 	backtestController models.BacktestController
+	historyProvider    HistoryProvider
+	statusProvider     StatusProvider
+	// コントローラーとプロバイダーは HTTP・WebSocket のゴルーチンからも参照される
+	providersMutex     sync.RWMutex
+	latestStatistics   []byte
+	statisticsMutex    sync.RWMutex
+	equityHistory      []EquityPoint
+	equityMutex        sync.Mutex
+	pendingCandles     []*models.Candle
+	batchMutex         sync.Mutex
+	logger             *slog.Logger
+	// Hub・バッチ送信・クライアント回収のゴルーチン。Stop で終了を待つ
+	workers sync.WaitGroup
+	// Session で作成したセッションは root を参照し、サーバーと Hub を共有する
+	sessionID     string
+	root          *visualizerImpl
+	sessions      map[string]*visualizerImpl
+	sessionsMutex sync.RWMutex
 }
 
 // Client は WebSocket クライアントを表す
 type Client struct {
-	id           string
-	conn         *websocket.Conn
-	send         chan []byte
-	hub          *Hub
-	lastActivity time.Time
-	isActive     bool
-	mutex        sync.RWMutex
+	id                string
+	conn              *websocket.Conn
+	send              chan []byte
+	hub               *Hub
+	lastActivity      time.Time
+	isActive          bool
+	mutex             sync.RWMutex
+	heartbeatInterval time.Duration
+	since             time.Time
+	sendMutex         sync.Mutex
+	sendClosed        bool
+	// subscribed が true の場合は session のメッセージだけを受信する
+	session    string
+	subscribed bool
 }
 
 // Hub は複数のクライアントを管理する
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan outboundMessage
 	register   chan *Client
 	unregister chan *Client
 	mutex      sync.RWMutex
 	visualizer Visualizer
+	replay     []outboundMessage
+	replaySize int
+	logger     *slog.Logger
+	// Visualizer の停止で閉じられる。以降 Hub への送信はブロックせずに破棄される
+	done <-chan struct{}
+}
+
+// outboundMessage は送信済みメッセージとその時刻・送信元セッション。再接続時のリプレイに使う
+type outboundMessage struct {
+	timestamp time.Time
+	data      []byte
+	session   string
 }
 
 // NewVisualizer は新しい Visualizer インスタンスを作成
@@ -127,25 +237,32 @@ func NewVisualizer(config *Config) Visualizer {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	
+	logger := config.Logger
+	if logger == nil {
+		logger = models.NewLogger(os.Stderr, config.LogLevel)
+	}
+	
 	hub := &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
+		broadcast:  make(chan outboundMessage),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		logger:     logger,
+		done:       ctx.Done(),
 	}
 
 	vizImpl := &visualizerImpl{
 		config:   config,
 		clients:  make(map[string]*Client),
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // 開発環境用: 本番環境では適切な Origin チェックを実装
-			},
-		},
 		ctx:    ctx,
 		cancel: cancel,
 		hub:    hub,
 		backtestController: nil, // 外部から設定される
+		logger: logger,
+		sessions: make(map[string]*visualizerImpl),
+	}
+	vizImpl.upgrader = websocket.Upgrader{
+		CheckOrigin: vizImpl.checkOrigin,
 	}
 	
 	// Hubにvisualizerへの参照を設定
@@ -155,47 +272,122 @@ func NewVisualizer(config *Config) Visualizer {
 }
 
 // Start は Visualizer を開始
+// セッションはサーバーを共有するため何もしない（Visualizer が開始されていない場合はエラー）
 func (v *visualizerImpl) Start(ctx context.Context, port int) error {
+	if v.root != nil {
+		if !v.root.IsRunning() {
+			return fmt.Errorf("visualizer is not running; start it before session %s", v.sessionID)
+		}
+		return nil
+	}
+
 	v.runningMutex.Lock()
 	defer v.runningMutex.Unlock()
 
 	if v.isRunning {
 		return fmt.Errorf("visualizer is already running")
 	}
+	if v.ctx.Err() != nil {
+		return fmt.Errorf("visualizer has been stopped; create a new visualizer to start again")
+	}
 
 	if port > 0 {
 		v.config.Port = port
 	}
 
+	// 静的ファイルのディレクトリも起動前に確認する
+	if v.config.StaticDir != "" {
+		info, err := os.Stat(v.config.StaticDir)
+		if err != nil {
+			return fmt.Errorf("failed to access static dir: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("static dir %s is not a directory", v.config.StaticDir)
+		}
+	}
+
+	// TLS 証明書は起動前に読み込み、設定ミスを Start のエラーとして返す
+	var tlsConfig *tls.Config
+	if v.config.TLSEnabled() {
+		if v.config.CertFile == "" || v.config.KeyFile == "" {
+			return fmt.Errorf("both cert file and key file are required for TLS")
+		}
+		cert, err := tls.LoadX509KeyPair(v.config.CertFile, v.config.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	// permessage-deflate はクライアントが対応している場合のみ有効になる
+	v.upgrader.EnableCompression = v.config.EnableCompression
+
 	// Hub を開始
-	go v.hub.run()
+	v.hub.replaySize = v.config.ReplayBufferSize
+	v.goWorker(v.hub.run)
+
+	// ローソク足のバッチ送信を開始
+	if v.config.BatchInterval > 0 {
+		v.goWorker(v.flushCandlesPeriodically)
+	}
+
+	// タイムアウトしたクライアントの回収を開始
+	if v.config.ClientTimeout > 0 {
+		v.goWorker(v.reapIdleClients)
+	}
 
 	// HTTP サーバーを設定
 	mux := http.NewServeMux()
 	mux.HandleFunc("/ws", v.handleWebSocket)
 	mux.HandleFunc("/health", v.handleHealth)
+	mux.HandleFunc("/statistics", v.handleStatistics)
+	mux.HandleFunc("/status", v.handleStatus)
+	mux.HandleFunc("/sessions", v.handleSessions)
+	// フロントエンドのビルド成果物を同じサーバーから配信
+	if v.config.StaticDir != "" {
+		mux.Handle("/", http.FileServer(http.Dir(v.config.StaticDir)))
+	}
 
 	v.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", v.config.Port),
 		Handler:      mux,
 		ReadTimeout:  v.config.ReadTimeout,
 		WriteTimeout: v.config.WriteTimeout,
+		TLSConfig:    tlsConfig,
 	}
 
 	v.isRunning = true
 
 	go func() {
-		if err := v.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			fmt.Printf("Server error: %v\n", err)
+		var err error
+		if tlsConfig != nil {
+			// 証明書は TLSConfig に読み込み済み
+			err = v.server.ListenAndServeTLS("", "")
+		} else {
+			err = v.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			v.logger.Error("visualizer server error", "error", err)
 		}
 	}()
 
-	fmt.Printf("Visualizer started on port %d\n", v.config.Port)
+	scheme := "ws"
+	if tlsConfig != nil {
+		scheme = "wss"
+	}
+	v.logger.Info("visualizer started", "port", v.config.Port, "scheme", scheme)
 	return nil
 }
 
 // Stop は Visualizer を停止
+// セッションの場合は溜まっているローソク足を送信して登録を解除するだけで、サーバーは停止しない
 func (v *visualizerImpl) Stop() error {
+	if v.root != nil {
+		v.flushCandles()
+		v.root.removeSession(v.sessionID)
+		return nil
+	}
+
 	v.runningMutex.Lock()
 	defer v.runningMutex.Unlock()
 
@@ -203,12 +395,22 @@ func (v *visualizerImpl) Stop() error {
 		return nil
 	}
 
+	// Hub を止める前に、全セッションで溜まっているローソク足を送信する
+	for _, session := range v.allSessions() {
+		if err := session.flushCandles(); err != nil {
+			session.logger.Error("failed to flush candle batch", "error", err)
+		}
+	}
+
 	v.cancel()
 
-	// 全てのクライアント接続を閉じる
+	// Hub などのゴルーチンの終了を待ち、送信済みのメッセージが送信キューに積まれた状態にする
+	v.workers.Wait()
+
+	// 全てのクライアントの送信キューを閉じる。writePump は残りのメッセージを書き出してから接続を閉じる
 	v.clientsMutex.Lock()
 	for _, client := range v.clients {
-		client.conn.Close()
+		client.closeSend()
 	}
 	v.clientsMutex.Unlock()
 
@@ -220,12 +422,24 @@ func (v *visualizerImpl) Stop() error {
 	}
 
 	v.isRunning = false
-	fmt.Println("Visualizer stopped")
+	v.logger.Info("visualizer stopped")
 	return nil
 }
 
-// IsRunning は Visualizer の実行状態を返す
+// goWorker は Stop で終了を待つゴルーチンを開始する
+func (v *visualizerImpl) goWorker(fn func()) {
+	v.workers.Add(1)
+	go func() {
+		defer v.workers.Done()
+		fn()
+	}()
+}
+
+// IsRunning は Visualizer の実行状態を返す。セッションは共有しているサーバーの状態を返す
 func (v *visualizerImpl) IsRunning() bool {
+	if v.root != nil {
+		return v.root.IsRunning()
+	}
 	v.runningMutex.RLock()
 	defer v.runningMutex.RUnlock()
 	return v.isRunning
@@ -233,6 +447,19 @@ func (v *visualizerImpl) IsRunning() bool {
 
 // OnCandleUpdate はローソク足データの更新を処理
 func (v *visualizerImpl) OnCandleUpdate(candle *models.Candle) error {
+	// バッチモードでは溜めておき、BatchInterval ごと（または BatchSize 到達時）に candle_batch として送信
+	if v.config.BatchInterval > 0 {
+		v.batchMutex.Lock()
+		v.pendingCandles = append(v.pendingCandles, candle)
+		full := v.config.BatchSize > 0 && len(v.pendingCandles) >= v.config.BatchSize
+		v.batchMutex.Unlock()
+
+		if full {
+			return v.flushCandles()
+		}
+		return nil
+	}
+
 	message := Message{
 		Type:      "candle_update",
 		Data:      candle,
@@ -242,6 +469,46 @@ func (v *visualizerImpl) OnCandleUpdate(candle *models.Candle) error {
 	return v.BroadcastMessage(message)
 }
 
+// flushCandles は溜まっているローソク足を candle_batch メッセージとして送信
+func (v *visualizerImpl) flushCandles() error {
+	v.batchMutex.Lock()
+	candles := v.pendingCandles
+	v.pendingCandles = nil
+	v.batchMutex.Unlock()
+
+	if len(candles) == 0 {
+		return nil
+	}
+
+	message := Message{
+		Type:      "candle_batch",
+		Data:      candles,
+		Timestamp: time.Now(),
+	}
+
+	return v.BroadcastMessage(message)
+}
+
+// flushCandlesPeriodically は BatchInterval ごとにローソク足のバッチを送信
+// 停止時に残っているローソク足は Stop が送信する
+func (v *visualizerImpl) flushCandlesPeriodically() {
+	ticker := time.NewTicker(v.config.BatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-v.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, session := range v.allSessions() {
+				if err := session.flushCandles(); err != nil {
+					session.logger.Error("failed to flush candle batch", "error", err)
+				}
+			}
+		}
+	}
+}
+
 // OnTradeEvent は取引イベントを処理
 func (v *visualizerImpl) OnTradeEvent(trade *models.Trade) error {
 	message := Message{
@@ -253,8 +520,30 @@ func (v *visualizerImpl) OnTradeEvent(trade *models.Trade) error {
 	return v.BroadcastMessage(message)
 }
 
+// OnOrderFilled は保留注文の約定を order_filled として配信
+func (v *visualizerImpl) OnOrderFilled(order *models.Order, position *models.Position) error {
+	message := Message{
+		Type:      "order_filled",
+		Data:      OrderFilled{Order: order, Position: position},
+		Timestamp: time.Now(),
+	}
+
+	return v.BroadcastMessage(message)
+}
+
 // OnStatisticsUpdate は統計情報の更新を処理
 func (v *visualizerImpl) OnStatisticsUpdate(stats *models.Statistics) error {
+	// REST エンドポイント用に最新のスナップショットを保持
+	if stats != nil {
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return fmt.Errorf("failed to marshal statistics: %w", err)
+		}
+		v.statisticsMutex.Lock()
+		v.latestStatistics = data
+		v.statisticsMutex.Unlock()
+	}
+
 	message := Message{
 		Type:      "statistics_update",
 		Data:      stats,
@@ -264,8 +553,60 @@ func (v *visualizerImpl) OnStatisticsUpdate(stats *models.Statistics) error {
 	return v.BroadcastMessage(message)
 }
 
+// OnEquityUpdate はエクイティを記録し、EquityPoints 点に間引いた系列を equity_update として配信。
+// EquityPoints が0以下の場合は何もしない
+func (v *visualizerImpl) OnEquityUpdate(timestamp time.Time, equity float64) error {
+	target := v.config.EquityPoints
+	if target <= 0 {
+		return nil
+	}
+
+	v.equityMutex.Lock()
+	v.equityHistory = append(v.equityHistory, EquityPoint{Timestamp: timestamp, Equity: equity})
+	// 保持する点数が増え続けないよう、目標の2倍を超えたら間引いておく（極値は保持される）
+	if len(v.equityHistory) > 2*target {
+		v.equityHistory = DownsampleEquity(v.equityHistory, target)
+	}
+	series := DownsampleEquity(v.equityHistory, target)
+	v.equityMutex.Unlock()
+
+	message := Message{
+		Type:      "equity_update",
+		Data:      series,
+		Timestamp: time.Now(),
+	}
+
+	return v.BroadcastMessage(message)
+}
+
+// OnIndicatorUpdate はストラテジーのインジケーター値を indicator_update として配信
+func (v *visualizerImpl) OnIndicatorUpdate(name string, timestamp time.Time, value float64) error {
+	if name == "" {
+		return fmt.Errorf("indicator name is required")
+	}
+
+	message := Message{
+		Type: "indicator_update",
+		Data: IndicatorUpdate{
+			Name:  name,
+			Time:  timestamp,
+			Value: value,
+		},
+		Timestamp: time.Now(),
+	}
+
+	return v.BroadcastMessage(message)
+}
+
 // OnBacktestStateChange はバックテストの状態変更を処理
 func (v *visualizerImpl) OnBacktestStateChange(state models.BacktestState) error {
+	// リセット後は Idle が通知されるため、エクイティ系列も最初からやり直す
+	if state == models.BacktestStateIdle {
+		v.equityMutex.Lock()
+		v.equityHistory = nil
+		v.equityMutex.Unlock()
+	}
+
 	message := Message{
 		Type:      "backtest_state",
 		Data:      state,
@@ -276,136 +617,476 @@ func (v *visualizerImpl) OnBacktestStateChange(state models.BacktestState) error
 }
 
 // OnControlCommand はフロントエンドからの制御コマンドを処理
+// cmd.SessionID が指定されている場合は、そのセッションのコントローラーに振り分ける
 func (v *visualizerImpl) OnControlCommand(cmd *ControlCommand) error {
-	fmt.Printf("Processing control command: %s from client %s\n", cmd.Type, cmd.ClientID)
+	target := v
+	if cmd.SessionID != "" && cmd.SessionID != v.sessionID {
+		target = v.rootVisualizer().lookupSession(cmd.SessionID)
+		if target == nil {
+			return fmt.Errorf("unknown session: %s", cmd.SessionID)
+		}
+	}
+	target.logger.Debug("processing control command", "type", cmd.Type, "client", cmd.ClientID)
 	
 	switch cmd.Type {
 	case "play":
-		return v.handlePlayCommand(cmd)
+		return target.handlePlayCommand(cmd)
 	case "pause":
-		return v.handlePauseCommand(cmd)
+		return target.handlePauseCommand(cmd)
 	case "speed_change":
-		return v.handleSpeedChangeCommand(cmd)
+		return target.handleSpeedChangeCommand(cmd)
+	case "step":
+		return target.handleStepCommand(cmd)
+	case "reset":
+		return target.handleResetCommand(cmd)
 	default:
 		return fmt.Errorf("unknown control command type: %s", cmd.Type)
 	}
 }
 
+// Session は id のセッションを返す。登録されていない場合は作成して登録する。空文字の場合はデフォルトセッション（この Visualizer 自身）を返す
+// セッションはサーバーとクライアント接続を共有し、コントローラー・プロバイダー・統計情報・エクイティ系列を個別に持つ
+// セッションから配信するメッセージには session_id が付き、そのセッションを購読しているクライアントと未購読のクライアントに届く
+func (v *visualizerImpl) Session(id string) Visualizer {
+	root := v.rootVisualizer()
+	if id == "" {
+		return root
+	}
+
+	root.sessionsMutex.Lock()
+	defer root.sessionsMutex.Unlock()
+	if session, ok := root.sessions[id]; ok {
+		return session
+	}
+	session := &visualizerImpl{
+		config:    root.config,
+		ctx:       root.ctx,
+		hub:       root.hub,
+		logger:    root.logger.With("session", id),
+		sessionID: id,
+		root:      root,
+	}
+	root.sessions[id] = session
+	return session
+}
+
+// SessionIDs は登録されているセッションの ID をソートして返す。デフォルトセッションは含まない
+func (v *visualizerImpl) SessionIDs() []string {
+	root := v.rootVisualizer()
+	root.sessionsMutex.RLock()
+	defer root.sessionsMutex.RUnlock()
+
+	ids := make([]string, 0, len(root.sessions))
+	for id := range root.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// rootVisualizer はサーバーを保持する Visualizer を返す。セッションでなければ自身
+func (v *visualizerImpl) rootVisualizer() *visualizerImpl {
+	if v.root != nil {
+		return v.root
+	}
+	return v
+}
+
+// lookupSession は id のセッションを返す。空文字はデフォルトセッション、未登録の場合は nil
+func (v *visualizerImpl) lookupSession(id string) *visualizerImpl {
+	if id == "" {
+		return v
+	}
+	v.sessionsMutex.RLock()
+	defer v.sessionsMutex.RUnlock()
+	return v.sessions[id]
+}
+
+// allSessions はデフォルトセッションと登録されている全てのセッションを返す
+func (v *visualizerImpl) allSessions() []*visualizerImpl {
+	v.sessionsMutex.RLock()
+	defer v.sessionsMutex.RUnlock()
+
+	sessions := []*visualizerImpl{v}
+	for _, id := range v.sortedSessionIDs() {
+		sessions = append(sessions, v.sessions[id])
+	}
+	return sessions
+}
+
+// sortedSessionIDs はセッション ID をソートして返す。sessionsMutex を保持して呼び出す
+func (v *visualizerImpl) sortedSessionIDs() []string {
+	ids := make([]string, 0, len(v.sessions))
+	for id := range v.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// removeSession はセッションの登録を解除する
+func (v *visualizerImpl) removeSession(id string) {
+	v.sessionsMutex.Lock()
+	delete(v.sessions, id)
+	v.sessionsMutex.Unlock()
+}
+
+// SetHistoryProvider は接続時の履歴送信に使うプロバイダーを設定
+func (v *visualizerImpl) SetHistoryProvider(provider HistoryProvider) {
+	v.providersMutex.Lock()
+	v.historyProvider = provider
+	v.providersMutex.Unlock()
+}
+
+// getHistoryProvider は接続時の履歴送信に使うプロバイダーを返す
+func (v *visualizerImpl) getHistoryProvider() HistoryProvider {
+	v.providersMutex.RLock()
+	defer v.providersMutex.RUnlock()
+	return v.historyProvider
+}
+
+// SetStatusProvider は /status エンドポイントで返す進捗のプロバイダーを設定
+func (v *visualizerImpl) SetStatusProvider(provider StatusProvider) {
+	v.providersMutex.Lock()
+	v.statusProvider = provider
+	v.providersMutex.Unlock()
+}
+
+// getStatusProvider は /status エンドポイントで返す進捗のプロバイダーを返す
+func (v *visualizerImpl) getStatusProvider() StatusProvider {
+	v.providersMutex.RLock()
+	defer v.providersMutex.RUnlock()
+	return v.statusProvider
+}
+
 // SetBacktestController はバックテストコントローラーを設定
 func (v *visualizerImpl) SetBacktestController(controller models.BacktestController) {
+	v.providersMutex.Lock()
 	v.backtestController = controller
+	v.providersMutex.Unlock()
 }
 
 // GetBacktestController はバックテストコントローラーを取得
 func (v *visualizerImpl) GetBacktestController() models.BacktestController {
+	v.providersMutex.RLock()
+	defer v.providersMutex.RUnlock()
 	return v.backtestController
 }
 
 // handlePlayCommand はプレイコマンドを処理
 func (v *visualizerImpl) handlePlayCommand(cmd *ControlCommand) error {
-	fmt.Printf("Handling play command from %s\n", cmd.ClientID)
+	v.logger.Debug("handling play command", "client", cmd.ClientID)
 	
 	speed := 1.0
 	if speedData, ok := cmd.Data["speed"].(float64); ok {
 		speed = speedData
 	}
 	
-	if v.backtestController != nil {
-		return v.backtestController.Play(speed)
+	if controller := v.GetBacktestController(); controller != nil {
+		return controller.Play(speed)
 	}
 	
-	fmt.Printf("Backtest controller not set\n")
+	v.logger.Warn("backtest controller not set", "command", cmd.Type)
 	return nil
 }
 
 // handlePauseCommand は一時停止コマンドを処理
 func (v *visualizerImpl) handlePauseCommand(cmd *ControlCommand) error {
-	fmt.Printf("Handling pause command from %s\n", cmd.ClientID)
+	v.logger.Debug("handling pause command", "client", cmd.ClientID)
+	
+	if controller := v.GetBacktestController(); controller != nil {
+		return controller.Pause()
+	}
 	
-	if v.backtestController != nil {
-		return v.backtestController.Pause()
+	v.logger.Warn("backtest controller not set", "command", cmd.Type)
+	return nil
+}
+
+// handleStepCommand はステップ実行コマンドを処理（data.count で進めるステップ数を指定、省略時は1）
+func (v *visualizerImpl) handleStepCommand(cmd *ControlCommand) error {
+	v.logger.Debug("handling step command", "client", cmd.ClientID)
+	
+	count := 1
+	if countData, ok := cmd.Data["count"].(float64); ok {
+		if countData < 1 {
+			return fmt.Errorf("invalid step count: %v", countData)
+		}
+		count = int(countData)
 	}
 	
-	fmt.Printf("Backtest controller not set\n")
+	if controller := v.GetBacktestController(); controller != nil {
+		return controller.Step(count)
+	}
+	
+	v.logger.Warn("backtest controller not set", "command", cmd.Type)
+	return nil
+}
+
+// handleResetCommand はリセットコマンドを処理
+func (v *visualizerImpl) handleResetCommand(cmd *ControlCommand) error {
+	v.logger.Debug("handling reset command", "client", cmd.ClientID)
+	
+	if controller := v.GetBacktestController(); controller != nil {
+		return controller.Reset()
+	}
+	
+	v.logger.Warn("backtest controller not set", "command", cmd.Type)
 	return nil
 }
 
 // handleSpeedChangeCommand は速度変更コマンドを処理
 func (v *visualizerImpl) handleSpeedChangeCommand(cmd *ControlCommand) error {
-	fmt.Printf("Handling speed change command from %s\n", cmd.ClientID)
+	v.logger.Debug("handling speed change command", "client", cmd.ClientID)
 	
 	if speedData, ok := cmd.Data["speed"].(float64); ok {
-		fmt.Printf("New speed: %f\n", speedData)
+		v.logger.Debug("new speed", "speed", speedData)
 		
-		if v.backtestController != nil {
-			return v.backtestController.SetSpeed(speedData)
+		if controller := v.GetBacktestController(); controller != nil {
+			return controller.SetSpeed(speedData)
 		}
 	}
 	
-	fmt.Printf("Backtest controller not set or invalid speed data\n")
+	v.logger.Warn("backtest controller not set or invalid speed data", "command", cmd.Type)
 	return nil
 }
 
-// GetConnectionCount は接続数を返す
+// GetConnectionCount は接続数を返す。セッションは共有している全ての接続数を返す
 func (v *visualizerImpl) GetConnectionCount() int {
-	v.clientsMutex.RLock()
-	defer v.clientsMutex.RUnlock()
-	return len(v.clients)
+	root := v.rootVisualizer()
+	root.clientsMutex.RLock()
+	defer root.clientsMutex.RUnlock()
+	return len(root.clients)
 }
 
-// BroadcastMessage は全てのクライアントにメッセージを送信
+// BroadcastMessage はセッションを購読している全てのクライアントにメッセージを送信
+// セッションから送信する Message には session_id を付ける
 func (v *visualizerImpl) BroadcastMessage(message interface{}) error {
+	// クライアントが受信した timestamp と比較できるよう、メッセージの時刻でリプレイバッファに記録
+	timestamp := time.Now()
+	session := v.sessionID
+	if msg, ok := message.(Message); ok {
+		if msg.SessionID == "" {
+			msg.SessionID = v.sessionID
+			message = msg
+		}
+		session = msg.SessionID
+		if !msg.Timestamp.IsZero() {
+			timestamp = msg.Timestamp
+		}
+	}
+
 	data, err := json.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	v.hub.broadcast <- data
-	return nil
+	select {
+	case v.hub.broadcast <- outboundMessage{timestamp: timestamp, data: data, session: session}:
+		return nil
+	case <-v.hub.done:
+		return fmt.Errorf("visualizer is stopped")
+	}
 }
 
-// SetConfig は設定を更新
+// SetConfig は設定を更新。セッションは Visualizer と設定を共有する
 func (v *visualizerImpl) SetConfig(config *Config) error {
-	if v.IsRunning() {
+	root := v.rootVisualizer()
+	if root.IsRunning() {
 		return fmt.Errorf("cannot change config while running")
 	}
-	v.config = config
+	root.config = config
+	root.sessionsMutex.RLock()
+	for _, session := range root.sessions {
+		session.config = config
+	}
+	root.sessionsMutex.RUnlock()
 	return nil
 }
 
 // GetConfig は現在の設定を返す
 func (v *visualizerImpl) GetConfig() *Config {
-	return v.config
+	return v.rootVisualizer().config
 }
 
 // handleWebSocket は WebSocket 接続を処理
 func (v *visualizerImpl) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// 接続数の上限チェック
+	if v.isAtCapacity() {
+		http.Error(w, "too many connections", http.StatusServiceUnavailable)
+		return
+	}
+
+	// 再接続時は最後に受信したメッセージの timestamp 以降だけをリプレイする
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			http.Error(w, "invalid since parameter", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	// session を指定した場合はそのセッションのメッセージだけを受信する（空文字はデフォルトセッション）
+	session, subscribed := "", r.URL.Query().Has("session")
+	if subscribed {
+		session = r.URL.Query().Get("session")
+	}
+
 	conn, err := v.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		fmt.Printf("WebSocket upgrade error: %v\n", err)
+		v.logger.Error("websocket upgrade failed", "error", err)
 		return
 	}
 
 	client := &Client{
-		id:           fmt.Sprintf("client_%d", time.Now().UnixNano()),
-		conn:         conn,
-		send:         make(chan []byte, v.config.BufferSize),
-		hub:          v.hub,
-		lastActivity: time.Now(),
-		isActive:     true,
+		id:                fmt.Sprintf("client_%d", time.Now().UnixNano()),
+		conn:              conn,
+		send:              make(chan []byte, v.config.BufferSize),
+		hub:               v.hub,
+		lastActivity:      time.Now(),
+		isActive:          true,
+		heartbeatInterval: v.config.HeartbeatInterval,
+		since:             since,
+		session:           session,
+		subscribed:        subscribed,
 	}
 
 	v.clientsMutex.Lock()
+	// アップグレード中に他の接続で上限に達した場合は理由付きで切断
+	if v.config.MaxClients > 0 && len(v.clients) >= v.config.MaxClients {
+		v.clientsMutex.Unlock()
+		closeMessage := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too many connections")
+		conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+		conn.Close()
+		return
+	}
 	v.clients[client.id] = client
 	v.clientsMutex.Unlock()
 
-	client.hub.register <- client
+	// ライブ更新より先に届くよう、登録前に履歴をキューに積む
+	v.sendHistory(client)
+
+	select {
+	case client.hub.register <- client:
+	case <-client.hub.done:
+		v.removeClient(client)
+		conn.Close()
+		return
+	}
 
 	// クライアントの読み書きを開始
 	go client.writePump()
 	go client.readPump()
 
-	fmt.Printf("Client %s connected\n", client.id)
+	v.logger.Info("client connected", "client", client.id)
+}
+
+// sendHistory はクライアントが購読しているセッション（未購読の場合は全てのセッション）の履歴を送信
+func (v *visualizerImpl) sendHistory(client *Client) {
+	session, subscribed := client.subscription()
+	if !subscribed {
+		for _, target := range v.allSessions() {
+			target.sendSessionHistory(client)
+		}
+		return
+	}
+	if target := v.lookupSession(session); target != nil {
+		target.sendSessionHistory(client)
+	}
+}
+
+// sendSessionHistory はセッションの直近のローソク足を history メッセージとしてクライアントに送信
+func (v *visualizerImpl) sendSessionHistory(client *Client) {
+	provider := v.getHistoryProvider()
+	if provider == nil || v.config.HistorySize <= 0 {
+		return
+	}
+
+	candles := provider.GetRecentCandles(v.config.HistorySize)
+	if len(candles) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(Message{
+		Type:      "history",
+		Data:      candles,
+		Timestamp: time.Now(),
+		ClientID:  client.id,
+		SessionID: v.sessionID,
+	})
+	if err != nil {
+		v.logger.Error("failed to marshal history", "client", client.id, "error", err)
+		return
+	}
+
+	if !client.enqueue(data) {
+		v.logger.Warn("send buffer full, dropping history", "client", client.id)
+	}
+}
+
+// checkOrigin はリクエストの Origin ヘッダーを AllowedOrigins と照合。"*" または未設定の場合は全て許可
+func (v *visualizerImpl) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	// ブラウザ以外のクライアントは Origin を送らない
+	if origin == "" {
+		return true
+	}
+
+	allowed := v.config.AllowedOrigins
+	if len(allowed) == 0 {
+		return true
+	}
+
+	origin = strings.TrimSuffix(origin, "/")
+	for _, candidate := range allowed {
+		if candidate == "*" || strings.EqualFold(strings.TrimSuffix(candidate, "/"), origin) {
+			return true
+		}
+	}
+
+	v.logger.Warn("rejected websocket connection", "origin", origin)
+	return false
+}
+
+// isAtCapacity は接続数が MaxClients に達しているかを返す
+func (v *visualizerImpl) isAtCapacity() bool {
+	return v.config.MaxClients > 0 && v.GetConnectionCount() >= v.config.MaxClients
+}
+
+// removeClient は切断されたクライアントを接続一覧から削除
+func (v *visualizerImpl) removeClient(client *Client) {
+	v.clientsMutex.Lock()
+	delete(v.clients, client.id)
+	v.clientsMutex.Unlock()
+}
+
+// reapIdleClients は最終アクティビティから ClientTimeout を超えたクライアントを切断
+func (v *visualizerImpl) reapIdleClients() {
+	ticker := time.NewTicker(v.config.ClientTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-v.ctx.Done():
+			return
+		case <-ticker.C:
+			v.clientsMutex.RLock()
+			idle := make([]*Client, 0)
+			for _, client := range v.clients {
+				if client.idleTime() > v.config.ClientTimeout {
+					idle = append(idle, client)
+				}
+			}
+			v.clientsMutex.RUnlock()
+
+			// 接続を閉じると readPump が終了し、登録解除される
+			for _, client := range idle {
+				v.logger.Info("client timed out", "client", client.id)
+				client.conn.Close()
+			}
+		}
+	}
 }
 
 // handleHealth はヘルスチェックエンドポイント
@@ -420,57 +1101,240 @@ func (v *visualizerImpl) handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(status)
 }
 
+// handleStatistics は最新の統計情報スナップショットを返すエンドポイント。session クエリでセッションを指定する
+func (v *visualizerImpl) handleStatistics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	session, ok := v.sessionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	session.statisticsMutex.RLock()
+	data := session.latestStatistics
+	session.statisticsMutex.RUnlock()
+
+	if data == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "statistics not available"})
+		return
+	}
+	w.Write(data)
+}
+
+// handleStatus はバックテストの進捗（状態・シミュレーション時刻・完了率・残高・有効証拠金・保有ポジション数）を返すエンドポイント
+// session クエリでセッションを指定する。プロバイダーが設定されていない場合は 404 を返す
+func (v *visualizerImpl) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	session, ok := v.sessionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	provider := session.getStatusProvider()
+	if provider == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "status not available"})
+		return
+	}
+	json.NewEncoder(w).Encode(provider.GetStatus())
+}
+
+// handleSessions は登録されているセッションの一覧を、進捗のプロバイダーがあれば進捗と合わせて返すエンドポイント
+func (v *visualizerImpl) handleSessions(w http.ResponseWriter, r *http.Request) {
+	sessions := v.allSessions()[1:]
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		info := SessionInfo{ID: session.sessionID}
+		if provider := session.getStatusProvider(); provider != nil {
+			status := provider.GetStatus()
+			info.Status = &status
+		}
+		infos = append(infos, info)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
+
+// sessionFromRequest は session クエリで指定されたセッション（省略時はデフォルトセッション）を返す
+// 登録されていない場合は 404 を書き込んで false を返す
+func (v *visualizerImpl) sessionFromRequest(w http.ResponseWriter, r *http.Request) (*visualizerImpl, bool) {
+	id := r.URL.Query().Get("session")
+	session := v.lookupSession(id)
+	if session == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unknown session: " + id})
+		return nil, false
+	}
+	return session, true
+}
+
 // Hub の実行ループ
 func (h *Hub) run() {
 	for {
 		select {
+		case <-h.done:
+			return
+
 		case client := <-h.register:
+			// 登録と同じループ内でリプレイするため、ライブ更新との間に欠落や順序の入れ替わりは起きない
+			h.replayTo(client)
 			h.mutex.Lock()
 			h.clients[client] = true
 			h.mutex.Unlock()
 
 		case client := <-h.unregister:
 			h.mutex.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-			}
+			delete(h.clients, client)
 			h.mutex.Unlock()
+			client.closeSend()
 
 		case message := <-h.broadcast:
+			h.record(message)
+
+			// 送信バッファが詰まったクライアントは、走査の後でまとめて切り離す
+			var slow []*Client
 			h.mutex.RLock()
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
+				if !client.accepts(message.session) {
+					continue
+				}
+				if !client.enqueue(message.data) {
+					slow = append(slow, client)
 				}
 			}
 			h.mutex.RUnlock()
+
+			if len(slow) > 0 {
+				h.mutex.Lock()
+				for _, client := range slow {
+					delete(h.clients, client)
+				}
+				h.mutex.Unlock()
+				for _, client := range slow {
+					h.logger.Warn("send buffer full, disconnecting slow client", "client", client.id)
+					client.closeSend()
+				}
+			}
+		}
+	}
+}
+
+// record はメッセージをリプレイバッファに追加し、replaySize を超えた古いものを捨てる
+func (h *Hub) record(message outboundMessage) {
+	if h.replaySize <= 0 {
+		return
+	}
+	if len(h.replay) >= h.replaySize {
+		h.replay = append(h.replay[:0], h.replay[len(h.replay)-h.replaySize+1:]...)
+	}
+	h.replay = append(h.replay, message)
+}
+
+// replayTo はクライアントの since より後のメッセージを送信キューに積む
+func (h *Hub) replayTo(client *Client) {
+	for _, message := range h.replay {
+		if !message.timestamp.After(client.since) || !client.accepts(message.session) {
+			continue
+		}
+		if !client.enqueue(message.data) {
+			h.logger.Warn("send buffer full, truncating replay", "client", client.id)
+			return
 		}
 	}
 }
 
+// enqueue は送信キューにメッセージを積む。バッファが満杯、または既に閉じられている場合は false を返す
+func (c *Client) enqueue(data []byte) bool {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	if c.sendClosed {
+		return false
+	}
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeSend は送信キューを閉じる。複数回呼び出しても安全で、閉じた後の enqueue は失敗する
+func (c *Client) closeSend() {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	if !c.sendClosed {
+		c.sendClosed = true
+		close(c.send)
+	}
+}
+
+// touch は最終アクティビティ時刻を更新
+func (c *Client) touch() {
+	c.mutex.Lock()
+	c.lastActivity = time.Now()
+	c.mutex.Unlock()
+}
+
+// subscribe は購読するセッションを設定する。subscribed が false の場合は全てのセッションを受信する
+func (c *Client) subscribe(session string, subscribed bool) {
+	c.mutex.Lock()
+	c.session = session
+	c.subscribed = subscribed
+	c.mutex.Unlock()
+}
+
+// subscription は購読しているセッションと、セッションを購読しているかを返す
+func (c *Client) subscription() (string, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.session, c.subscribed
+}
+
+// accepts は session から送信されたメッセージを受信するかを返す
+func (c *Client) accepts(session string) bool {
+	subscribedSession, subscribed := c.subscription()
+	return !subscribed || subscribedSession == session
+}
+
+// idleTime は最終アクティビティからの経過時間を返す
+func (c *Client) idleTime() time.Duration {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return time.Since(c.lastActivity)
+}
+
 // readPump はクライアントからのメッセージを処理
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.done:
+		}
+		if visualizer, ok := c.hub.visualizer.(*visualizerImpl); ok {
+			visualizer.removeClient(c)
+		}
 		c.conn.Close()
 	}()
 
+	// Pong の受信もアクティビティとして扱う
+	c.conn.SetPongHandler(func(string) error {
+		c.touch()
+		return nil
+	})
+
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				fmt.Printf("WebSocket error: %v\n", err)
+				c.hub.logger.Error("websocket error", "client", c.id, "error", err)
 			}
 			break
 		}
 
-		c.mutex.Lock()
-		c.lastActivity = time.Now()
-		c.mutex.Unlock()
+		c.touch()
 
 		// メッセージを処理
 		c.handleMessage(message)
@@ -481,14 +1345,14 @@ func (c *Client) readPump() {
 func (c *Client) handleMessage(message []byte) {
 	var controlCmd ControlCommand
 	if err := json.Unmarshal(message, &controlCmd); err != nil {
-		fmt.Printf("Error parsing control command from %s: %v\n", c.id, err)
+		c.hub.logger.Warn("failed to parse control command", "client", c.id, "error", err)
 		return
 	}
 
 	controlCmd.ClientID = c.id
 	controlCmd.Timestamp = time.Now()
 
-	fmt.Printf("Received control command from %s: %s\n", c.id, controlCmd.Type)
+	c.hub.logger.Debug("received control command", "client", c.id, "type", controlCmd.Type)
 
 	// コマンドを処理
 	switch controlCmd.Type {
@@ -501,29 +1365,34 @@ func (c *Client) handleMessage(message []byte) {
 			ClientID:  c.id,
 		}
 		if data, err := json.Marshal(response); err == nil {
-			select {
-			case c.send <- data:
-			default:
-				fmt.Printf("Failed to send pong to %s\n", c.id)
+			if !c.enqueue(data) {
+				c.hub.logger.Warn("failed to send pong", "client", c.id)
 			}
 		}
-	case "play", "pause", "speed_change":
+	case "play", "pause", "speed_change", "step", "reset":
 		// バックテスト制御コマンドを処理
 		c.handleBacktestControl(&controlCmd)
+	case "subscribe", "unsubscribe":
+		c.handleSubscription(&controlCmd)
 	default:
-		fmt.Printf("Unknown command type: %s\n", controlCmd.Type)
+		c.hub.logger.Warn("unknown command type", "client", c.id, "type", controlCmd.Type)
 	}
 }
 
 // handleBacktestControl はバックテスト制御コマンドを処理
 func (c *Client) handleBacktestControl(cmd *ControlCommand) {
+	// セッションを省略したコマンドは購読中のセッションに送る
+	if session, subscribed := c.subscription(); cmd.SessionID == "" && subscribed {
+		cmd.SessionID = session
+	}
+
 	// Visualizerを取得して、OnControlCommandを呼び出す
 	if visualizer, ok := c.hub.visualizer.(*visualizerImpl); ok {
 		if err := visualizer.OnControlCommand(cmd); err != nil {
-			fmt.Printf("Error handling control command: %v\n", err)
+			c.hub.logger.Error("failed to handle control command", "client", c.id, "error", err)
 		}
 	} else {
-		fmt.Printf("Unable to get visualizer instance\n")
+		c.hub.logger.Error("unable to get visualizer instance", "client", c.id)
 	}
 
 	// 確認メッセージを送信
@@ -532,19 +1401,50 @@ func (c *Client) handleBacktestControl(cmd *ControlCommand) {
 		Data:      map[string]interface{}{"command": cmd.Type, "status": "received"},
 		Timestamp: time.Now(),
 		ClientID:  c.id,
+		SessionID: cmd.SessionID,
 	}
 	if data, err := json.Marshal(response); err == nil {
-		select {
-		case c.send <- data:
-		default:
-			fmt.Printf("Failed to send control response to %s\n", c.id)
+		if !c.enqueue(data) {
+			c.hub.logger.Warn("failed to send control response", "client", c.id)
+		}
+	}
+}
+
+// handleSubscription は subscribe（session_id のセッションだけを受信）と unsubscribe（全てのセッションを受信）を処理
+// subscribe では購読したセッションの履歴を送信する
+func (c *Client) handleSubscription(cmd *ControlCommand) {
+	subscribed := cmd.Type == "subscribe"
+	if !subscribed {
+		cmd.SessionID = ""
+	}
+	c.subscribe(cmd.SessionID, subscribed)
+	c.hub.logger.Debug("subscription changed", "client", c.id, "session", cmd.SessionID, "subscribed", subscribed)
+
+	response := Message{
+		Type:      "control_response",
+		Data:      map[string]interface{}{"command": cmd.Type, "status": "received"},
+		Timestamp: time.Now(),
+		ClientID:  c.id,
+		SessionID: cmd.SessionID,
+	}
+	if data, err := json.Marshal(response); err == nil {
+		if !c.enqueue(data) {
+			c.hub.logger.Warn("failed to send control response", "client", c.id)
 		}
 	}
+
+	if visualizer, ok := c.hub.visualizer.(*visualizerImpl); ok && subscribed {
+		visualizer.sendHistory(c)
+	}
 }
 
 // writePump はクライアントへのメッセージを送信
 func (c *Client) writePump() {
-	ticker := time.NewTicker(54 * time.Second)
+	interval := c.heartbeatInterval
+	if interval <= 0 {
+		interval = DefaultConfig().HeartbeatInterval
+	}
+	ticker := time.NewTicker(interval)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
@@ -1,10 +1,22 @@
 package visualizer
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -112,6 +124,37 @@ func TestVisualizerLifecycle(t *testing.T) {
 		
 		visualizer.Stop()
 	})
+
+	t.Run("should not block or restart after stop", func(t *testing.T) {
+		visualizer := NewVisualizer(nil)
+		
+		ctx := context.Background()
+		if err := visualizer.Start(ctx, 8106); err != nil {
+			t.Fatalf("Expected no error on start, got %v", err)
+		}
+		if err := visualizer.Stop(); err != nil {
+			t.Fatalf("Expected no error on stop, got %v", err)
+		}
+		
+		// 停止後の送信は Hub を待たずにエラーを返す
+		done := make(chan error, 1)
+		go func() {
+			done <- visualizer.BroadcastMessage(Message{Type: "test"})
+		}()
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Error("Expected error when broadcasting after stop")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("BroadcastMessage blocked after stop")
+		}
+		
+		if err := visualizer.Start(ctx, 8106); err == nil {
+			visualizer.Stop()
+			t.Error("Expected error when starting after stop")
+		}
+	})
 }
 
 // TestWebSocketConnection は WebSocket 接続をテスト
@@ -297,6 +340,7 @@ func TestOnTradeEvent(t *testing.T) {
 		// トレードデータを作成  
 		trade := &models.Trade{
 			ID:         "trade_1",
+			Symbol:     "USDJPY",
 			Side:       models.Buy,
 			Size:       1000,
 			EntryPrice: 150.0,
@@ -339,12 +383,63 @@ func TestOnTradeEvent(t *testing.T) {
 			t.Errorf("Expected symbol 'USDJPY', got '%v'", tradeData["symbol"])
 		}
 		
-		if tradeData["side"] != float64(models.Buy) {
+		if tradeData["side"] != "Buy" {
 			t.Errorf("Expected side 'Buy', got '%v'", tradeData["side"])
 		}
 	})
 }
 
+// TestOnOrderFilled は保留注文の約定通知をテスト
+func TestOnOrderFilled(t *testing.T) {
+	visualizer := NewVisualizer(nil)
+	if err := visualizer.Start(context.Background(), 8107); err != nil {
+		t.Fatalf("Failed to start visualizer: %v", err)
+	}
+	defer visualizer.Stop()
+	time.Sleep(100 * time.Millisecond)
+	
+	u := url.URL{Scheme: "ws", Host: "localhost:8107", Path: "/ws"}
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to websocket: %v", err)
+	}
+	defer conn.Close()
+	time.Sleep(100 * time.Millisecond)
+	
+	order := models.NewLimitOrder("limit_1", "USDJPY", models.Buy, 1000, 150.0)
+	order.Execute(150.0)
+	position := &models.Position{ID: "pos-limit_1", Symbol: "USDJPY", Side: models.Buy, Size: 1000, EntryPrice: 150.0}
+	if err := visualizer.OnOrderFilled(order, position); err != nil {
+		t.Fatalf("Failed to send order fill: %v", err)
+	}
+	
+	conn.SetReadDeadline(time.Now().Add(1 * time.Second))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	
+	var received struct {
+		Type string      `json:"type"`
+		Data OrderFilled `json:"data"`
+	}
+	if err := json.Unmarshal(message, &received); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	
+	if received.Type != "order_filled" {
+		t.Errorf("Expected message type 'order_filled', got '%s'", received.Type)
+	}
+	if received.Data.Order == nil || received.Data.Order.ID != "limit_1" || received.Data.Order.Status != models.Executed {
+		t.Errorf("Expected executed order 'limit_1', got %+v", received.Data.Order)
+	} else if received.Data.Order.ExecutedPrice != 150.0 {
+		t.Errorf("Expected executed price 150.0, got %v", received.Data.Order.ExecutedPrice)
+	}
+	if received.Data.Position == nil || received.Data.Position.ID != "pos-limit_1" {
+		t.Errorf("Expected position 'pos-limit_1', got %+v", received.Data.Position)
+	}
+}
+
 // TestHealthEndpoint はヘルスチェックエンドポイントをテスト
 func TestHealthEndpoint(t *testing.T) {
 	t.Run("should return health status", func(t *testing.T) {
@@ -386,6 +481,82 @@ func TestHealthEndpoint(t *testing.T) {
 	})
 }
 
+// staticStatus は固定の進捗を返すテスト用の StatusProvider
+type staticStatus models.BacktestStatus
+
+func (s staticStatus) GetStatus() models.BacktestStatus {
+	return models.BacktestStatus(s)
+}
+
+// TestStatusEndpoint は /status エンドポイントをテスト
+func TestStatusEndpoint(t *testing.T) {
+	t.Run("should serve backtest progress from provider", func(t *testing.T) {
+		visualizer := NewVisualizer(nil)
+		
+		ctx := context.Background()
+		if err := visualizer.Start(ctx, 8108); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		// プロバイダーが設定されていない場合は 404
+		resp, err := http.Get("http://localhost:8108/status")
+		if err != nil {
+			t.Fatalf("Failed to request status: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404 without provider, got %d", resp.StatusCode)
+		}
+		
+		visualizer.SetStatusProvider(staticStatus{
+			State:           "Running",
+			CurrentTime:     time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC),
+			Bar:             25,
+			TotalBars:       100,
+			PercentComplete: 25.0,
+			Balance:         9900.0,
+			Equity:          10050.0,
+			OpenPositions:   2,
+		})
+		
+		resp, err = http.Get("http://localhost:8108/status")
+		if err != nil {
+			t.Fatalf("Failed to request status: %v", err)
+		}
+		defer resp.Body.Close()
+		
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if contentType := resp.Header.Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Expected JSON content type, got %s", contentType)
+		}
+		
+		var decoded map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+			t.Fatalf("Failed to decode status: %v", err)
+		}
+		expected := map[string]interface{}{
+			"state":            "Running",
+			"current_time":     "2024-01-01T09:30:00Z",
+			"bar":              25.0,
+			"total_bars":       100.0,
+			"percent_complete": 25.0,
+			"balance":          9900.0,
+			"equity":           10050.0,
+			"open_positions":   2.0,
+		}
+		for key, want := range expected {
+			if decoded[key] != want {
+				t.Errorf("Expected %s to be %v, got %v", key, want, decoded[key])
+			}
+		}
+	})
+}
+
 // TestConfigManagement は設定管理をテスト
 func TestConfigManagement(t *testing.T) {
 	t.Run("should not allow config change while running", func(t *testing.T) {
@@ -433,4 +604,1183 @@ func TestConfigManagement(t *testing.T) {
 			t.Errorf("Expected log level to be 'debug', got '%s'", config.LogLevel)
 		}
 	})
-}
\ No newline at end of file
+}
+
+// TestMaxClients は接続数の上限をテスト
+func TestMaxClients(t *testing.T) {
+	t.Run("should refuse connections beyond MaxClients", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxClients = 2
+		visualizer := NewVisualizer(config)
+		
+		ctx := context.Background()
+		if err := visualizer.Start(ctx, 8089); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		u := url.URL{Scheme: "ws", Host: "localhost:8089", Path: "/ws"}
+		conns := make([]*websocket.Conn, 0, config.MaxClients)
+		for i := 0; i < config.MaxClients; i++ {
+			conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+			if err != nil {
+				t.Fatalf("Failed to connect client %d: %v", i, err)
+			}
+			conns = append(conns, conn)
+		}
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		// 上限を超える接続は 503 で拒否される
+		_, resp, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		if err == nil {
+			t.Fatal("Expected connection beyond MaxClients to be refused")
+		}
+		if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %v", resp)
+		}
+		
+		if visualizer.GetConnectionCount() != config.MaxClients {
+			t.Errorf("Expected %d connections, got %d", config.MaxClients, visualizer.GetConnectionCount())
+		}
+		
+		// 切断すると再び接続できる
+		conns[0].Close()
+		time.Sleep(100 * time.Millisecond)
+		
+		conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		if err != nil {
+			t.Errorf("Expected connection after disconnect to succeed: %v", err)
+		} else {
+			conn.Close()
+		}
+		
+		for _, c := range conns[1:] {
+			c.Close()
+		}
+	})
+}
+
+// TestClientHeartbeat はハートビートとアイドルタイムアウトをテスト
+func TestClientHeartbeat(t *testing.T) {
+	t.Run("should disconnect idle clients after ClientTimeout", func(t *testing.T) {
+		config := DefaultConfig()
+		config.HeartbeatInterval = time.Hour
+		config.ClientTimeout = 200 * time.Millisecond
+		visualizer := NewVisualizer(config)
+		
+		ctx := context.Background()
+		if err := visualizer.Start(ctx, 8090); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		u := url.URL{Scheme: "ws", Host: "localhost:8090", Path: "/ws"}
+		conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close()
+		
+		time.Sleep(50 * time.Millisecond)
+		if visualizer.GetConnectionCount() != 1 {
+			t.Fatalf("Expected 1 connection, got %d", visualizer.GetConnectionCount())
+		}
+		
+		// Ping が送られないため、タイムアウト後に切断される
+		time.Sleep(500 * time.Millisecond)
+		if visualizer.GetConnectionCount() != 0 {
+			t.Errorf("Expected idle client to be reaped, got %d connections", visualizer.GetConnectionCount())
+		}
+	})
+	
+	t.Run("should keep clients that answer pings", func(t *testing.T) {
+		config := DefaultConfig()
+		config.HeartbeatInterval = 50 * time.Millisecond
+		config.ClientTimeout = 200 * time.Millisecond
+		visualizer := NewVisualizer(config)
+		
+		ctx := context.Background()
+		if err := visualizer.Start(ctx, 8091); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		u := url.URL{Scheme: "ws", Host: "localhost:8091", Path: "/ws"}
+		conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close()
+		
+		// 読み込みを続けることで Ping に Pong が自動応答される
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		
+		time.Sleep(500 * time.Millisecond)
+		if visualizer.GetConnectionCount() != 1 {
+			t.Errorf("Expected client answering pings to stay connected, got %d connections", visualizer.GetConnectionCount())
+		}
+	})
+}
+
+// TestCandleBatching はローソク足のバッチ送信をテスト
+func TestCandleBatching(t *testing.T) {
+	candles := candlesFromCloses([]float64{150.0, 151.0, 152.0, 153.0, 154.0})
+	
+	readBatch := func(t *testing.T, conn *websocket.Conn) []models.Candle {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var message struct {
+			Type string          `json:"type"`
+			Data []models.Candle `json:"data"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("Failed to read batch message: %v", err)
+		}
+		if message.Type != "candle_batch" {
+			t.Fatalf("Expected candle_batch message, got %s", message.Type)
+		}
+		return message.Data
+	}
+	
+	config := DefaultConfig()
+	config.BatchInterval = 200 * time.Millisecond
+	config.BatchSize = 3
+	visualizer := NewVisualizer(config)
+	
+	ctx := context.Background()
+	if err := visualizer.Start(ctx, 8102); err != nil {
+		t.Fatalf("Failed to start visualizer: %v", err)
+	}
+	defer visualizer.Stop()
+	
+	time.Sleep(100 * time.Millisecond)
+	
+	u := url.URL{Scheme: "ws", Host: "localhost:8102", Path: "/ws"}
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	
+	time.Sleep(100 * time.Millisecond)
+	
+	t.Run("should flush immediately when BatchSize is reached", func(t *testing.T) {
+		for _, candle := range candles[:3] {
+			if err := visualizer.OnCandleUpdate(candle); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		batch := readBatch(t, conn)
+		if len(batch) != 3 || batch[2].Close != 152.0 {
+			t.Errorf("Expected 3 candles ending at 152, got %+v", batch)
+		}
+	})
+	
+	t.Run("should flush remaining candles at BatchInterval", func(t *testing.T) {
+		for _, candle := range candles[3:] {
+			if err := visualizer.OnCandleUpdate(candle); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		batch := readBatch(t, conn)
+		if len(batch) != 2 || batch[0].Close != 153.0 {
+			t.Errorf("Expected 2 candles starting at 153, got %+v", batch)
+		}
+	})
+}
+
+// TestCandleBatching_FlushOnStop は停止時に溜まっているローソク足が全セッション分送信されることをテスト
+func TestCandleBatching_FlushOnStop(t *testing.T) {
+	candles := candlesFromCloses([]float64{150.0, 151.0, 152.0})
+	
+	config := DefaultConfig()
+	config.BatchInterval = time.Hour
+	config.BatchSize = 100
+	visualizer := NewVisualizer(config)
+	
+	if err := visualizer.Start(context.Background(), 8112); err != nil {
+		t.Fatalf("Failed to start visualizer: %v", err)
+	}
+	defer visualizer.Stop()
+	
+	time.Sleep(100 * time.Millisecond)
+	
+	u := url.URL{Scheme: "ws", Host: "localhost:8112", Path: "/ws"}
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	
+	time.Sleep(100 * time.Millisecond)
+	
+	// バッチサイズにもバッチ間隔にも達しないまま停止する
+	for _, candle := range candles[:2] {
+		if err := visualizer.OnCandleUpdate(candle); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := visualizer.Session("a").OnCandleUpdate(candles[2]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := visualizer.Stop(); err != nil {
+		t.Fatalf("Failed to stop visualizer: %v", err)
+	}
+	
+	// デフォルトセッション、セッション a の順に送信される
+	expected := []struct {
+		session string
+		count   int
+	}{{"", 2}, {"a", 1}}
+	for _, want := range expected {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var message struct {
+			Type      string          `json:"type"`
+			SessionID string          `json:"session_id"`
+			Data      []models.Candle `json:"data"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("Expected candle batch for session %q before close, got %v", want.session, err)
+		}
+		if message.Type != "candle_batch" || message.SessionID != want.session || len(message.Data) != want.count {
+			t.Errorf("Expected %d candles for session %q, got %s %q with %d candles",
+				want.count, want.session, message.Type, message.SessionID, len(message.Data))
+		}
+	}
+	
+	// 送信後に接続が閉じられる
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Error("Expected connection to be closed after stop")
+	}
+}
+
+// TestCompression は permessage-deflate の有効化をテスト
+func TestCompression(t *testing.T) {
+	config := DefaultConfig()
+	config.EnableCompression = true
+	visualizer := NewVisualizer(config)
+	
+	ctx := context.Background()
+	if err := visualizer.Start(ctx, 8103); err != nil {
+		t.Fatalf("Failed to start visualizer: %v", err)
+	}
+	defer visualizer.Stop()
+	
+	time.Sleep(100 * time.Millisecond)
+	
+	dialer := websocket.Dialer{EnableCompression: true}
+	u := url.URL{Scheme: "ws", Host: "localhost:8103", Path: "/ws"}
+	conn, resp, err := dialer.Dial(u.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	
+	if !strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate") {
+		t.Errorf("Expected permessage-deflate to be negotiated, got %q", resp.Header.Get("Sec-WebSocket-Extensions"))
+	}
+	
+	time.Sleep(100 * time.Millisecond)
+	
+	// 圧縮されたメッセージも通常通り受信できる
+	if err := visualizer.OnCandleUpdate(models.NewCandle(time.Now(), 150.0, 150.0, 150.0, 150.0, 1000)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message Message
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("Failed to read compressed message: %v", err)
+	}
+	if message.Type != "candle_update" {
+		t.Errorf("Expected candle_update message, got %s", message.Type)
+	}
+}
+
+// TestHubSlowClient は送信バッファが詰まったクライアントの切り離しをテスト
+func TestHubSlowClient(t *testing.T) {
+	hub := &Hub{
+		clients:    make(map[*Client]bool),
+		broadcast:  make(chan outboundMessage),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		logger:     models.NopLogger(),
+	}
+	go hub.run()
+	
+	fast := &Client{id: "fast", send: make(chan []byte, 10), hub: hub}
+	slow := &Client{id: "slow", send: make(chan []byte, 1), hub: hub}
+	hub.register <- fast
+	hub.register <- slow
+	
+	for i := 0; i < 3; i++ {
+		hub.broadcast <- outboundMessage{timestamp: time.Now(), data: []byte(fmt.Sprintf("message-%d", i))}
+	}
+	// 直前のブロードキャストの処理完了を待つ
+	hub.register <- &Client{id: "sync", send: make(chan []byte, 1), hub: hub}
+	
+	t.Run("should keep delivering to other clients", func(t *testing.T) {
+		if len(fast.send) != 3 {
+			t.Errorf("Expected fast client to receive 3 messages, got %d", len(fast.send))
+		}
+		hub.mutex.RLock()
+		_, fastRegistered := hub.clients[fast]
+		_, slowRegistered := hub.clients[slow]
+		hub.mutex.RUnlock()
+		if !fastRegistered || slowRegistered {
+			t.Errorf("Expected only slow client to be removed (fast=%v, slow=%v)", fastRegistered, slowRegistered)
+		}
+	})
+	
+	t.Run("should close slow client's queue exactly once", func(t *testing.T) {
+		// readPump 終了時の登録解除と重なっても二重 close しない
+		hub.unregister <- slow
+		hub.register <- &Client{id: "sync2", send: make(chan []byte, 1), hub: hub}
+		
+		if slow.enqueue([]byte("late pong")) {
+			t.Error("Expected enqueue to fail after the queue is closed")
+		}
+		
+		<-slow.send
+		if _, ok := <-slow.send; ok {
+			t.Error("Expected slow client's queue to be closed")
+		}
+	})
+}
+
+// TestReplayBuffer は再接続時のメッセージリプレイをテスト
+func TestReplayBuffer(t *testing.T) {
+	config := DefaultConfig()
+	config.ReplayBufferSize = 3
+	visualizer := NewVisualizer(config)
+	
+	ctx := context.Background()
+	if err := visualizer.Start(ctx, 8104); err != nil {
+		t.Fatalf("Failed to start visualizer: %v", err)
+	}
+	defer visualizer.Stop()
+	
+	time.Sleep(100 * time.Millisecond)
+	
+	// 接続中のクライアントがいない間に5本配信
+	for _, candle := range candlesFromCloses([]float64{150.0, 151.0, 152.0, 153.0, 154.0}) {
+		if err := visualizer.OnCandleUpdate(candle); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	
+	type candleMessage struct {
+		Type      string        `json:"type"`
+		Data      models.Candle `json:"data"`
+		Timestamp string        `json:"timestamp"`
+	}
+	
+	readCandles := func(t *testing.T, rawQuery string, count int) []candleMessage {
+		t.Helper()
+		u := url.URL{Scheme: "ws", Host: "localhost:8104", Path: "/ws", RawQuery: rawQuery}
+		conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close()
+		
+		messages := make([]candleMessage, 0, count)
+		for i := 0; i < count; i++ {
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			var message candleMessage
+			if err := conn.ReadJSON(&message); err != nil {
+				t.Fatalf("Failed to read replayed message %d: %v", i, err)
+			}
+			messages = append(messages, message)
+		}
+		
+		// それ以上はリプレイされない
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		var extra candleMessage
+		if err := conn.ReadJSON(&extra); err == nil {
+			t.Errorf("Expected no more replayed messages, got %+v", extra)
+		}
+		return messages
+	}
+	
+	var replayed []candleMessage
+	t.Run("should replay last ReplayBufferSize messages on connect", func(t *testing.T) {
+		replayed = readCandles(t, "", 3)
+		for i, message := range replayed {
+			if message.Type != "candle_update" || message.Data.Close != 152.0+float64(i) {
+				t.Errorf("Unexpected replayed message %d: %+v", i, message)
+			}
+		}
+	})
+	
+	t.Run("should replay only messages after since", func(t *testing.T) {
+		if len(replayed) != 3 {
+			t.Skip("previous subtest failed")
+		}
+		messages := readCandles(t, "since="+url.QueryEscape(replayed[1].Timestamp), 1)
+		if messages[0].Data.Close != 154.0 {
+			t.Errorf("Expected only the last candle, got %+v", messages[0])
+		}
+	})
+	
+	t.Run("should reject invalid since", func(t *testing.T) {
+		u := url.URL{Scheme: "ws", Host: "localhost:8104", Path: "/ws", RawQuery: "since=yesterday"}
+		_, resp, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		if err == nil {
+			t.Fatal("Expected dial to fail for invalid since")
+		}
+		if resp == nil || resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %v", resp)
+		}
+	})
+}
+
+// TestStaticDir は StaticDir からのフロントエンド配信をテスト
+func TestStaticDir(t *testing.T) {
+	t.Run("should serve static files on / alongside API endpoints", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>dashboard</html>"), 0644); err != nil {
+			t.Fatalf("Failed to write index.html: %v", err)
+		}
+		
+		config := DefaultConfig()
+		config.StaticDir = dir
+		visualizer := NewVisualizer(config)
+		
+		ctx := context.Background()
+		if err := visualizer.Start(ctx, 8100); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		resp, err := http.Get("http://localhost:8100/")
+		if err != nil {
+			t.Fatalf("Failed to request index: %v", err)
+		}
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body[:n]), "dashboard") {
+			t.Errorf("Expected index.html to be served, got %d %q", resp.StatusCode, string(body[:n]))
+		}
+		
+		resp, err = http.Get("http://localhost:8100/health")
+		if err != nil {
+			t.Fatalf("Failed to request health: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected health endpoint to keep working, got %d", resp.StatusCode)
+		}
+	})
+	
+	t.Run("should not serve / when StaticDir is empty", func(t *testing.T) {
+		visualizer := NewVisualizer(DefaultConfig())
+		
+		ctx := context.Background()
+		if err := visualizer.Start(ctx, 8101); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		resp, err := http.Get("http://localhost:8101/")
+		if err != nil {
+			t.Fatalf("Failed to request index: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", resp.StatusCode)
+		}
+	})
+	
+	t.Run("should fail to start with missing directory", func(t *testing.T) {
+		config := DefaultConfig()
+		config.StaticDir = filepath.Join(t.TempDir(), "missing")
+		if err := NewVisualizer(config).Start(context.Background(), 8101); err == nil {
+			t.Error("Expected error for missing static dir")
+		}
+	})
+}
+
+// TestStatisticsEndpoint は /statistics エンドポイントをテスト
+func TestStatisticsEndpoint(t *testing.T) {
+	t.Run("should serve latest statistics snapshot", func(t *testing.T) {
+		config := DefaultConfig()
+		visualizer := NewVisualizer(config)
+		
+		ctx := context.Background()
+		if err := visualizer.Start(ctx, 8092); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		// 統計情報がまだない場合は 404
+		resp, err := http.Get("http://localhost:8092/statistics")
+		if err != nil {
+			t.Fatalf("Failed to request statistics: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404 before any update, got %d", resp.StatusCode)
+		}
+		
+		stats := models.NewStatistics(10000.0)
+		stats.AddTrade(500.0)
+		stats.UpdateBalance(10500.0)
+		if err := visualizer.OnStatisticsUpdate(stats); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		resp, err = http.Get("http://localhost:8092/statistics")
+		if err != nil {
+			t.Fatalf("Failed to request statistics: %v", err)
+		}
+		defer resp.Body.Close()
+		
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		
+		var decoded map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+			t.Fatalf("Failed to decode statistics: %v", err)
+		}
+		if decoded["current_balance"] != 10500.0 {
+			t.Errorf("Expected balance 10500, got %v", decoded["current_balance"])
+		}
+		if decoded["win_rate"] != 100.0 {
+			t.Errorf("Expected win rate 100, got %v", decoded["win_rate"])
+		}
+	})
+}
+
+// stubHistoryProvider はテスト用の履歴プロバイダー
+type stubHistoryProvider struct {
+	candles []*models.Candle
+}
+
+func (p *stubHistoryProvider) GetRecentCandles(count int) []*models.Candle {
+	if count < len(p.candles) {
+		return p.candles[len(p.candles)-count:]
+	}
+	return p.candles
+}
+
+// TestHistoryOnConnect は接続時の履歴送信をテスト
+func TestHistoryOnConnect(t *testing.T) {
+	t.Run("should send last HistorySize candles as history message on connect", func(t *testing.T) {
+		config := DefaultConfig()
+		config.HistorySize = 3
+		visualizer := NewVisualizer(config)
+		
+		provider := &stubHistoryProvider{candles: candlesFromCloses([]float64{150.0, 151.0, 152.0, 153.0, 154.0})}
+		visualizer.SetHistoryProvider(provider)
+		
+		ctx := context.Background()
+		if err := visualizer.Start(ctx, 8093); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		u := url.URL{Scheme: "ws", Host: "localhost:8093", Path: "/ws"}
+		conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close()
+		
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var message struct {
+			Type string          `json:"type"`
+			Data []models.Candle `json:"data"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("Failed to read history message: %v", err)
+		}
+		
+		if message.Type != "history" {
+			t.Fatalf("Expected history message, got %s", message.Type)
+		}
+		if len(message.Data) != 3 {
+			t.Fatalf("Expected 3 candles, got %d", len(message.Data))
+		}
+		if message.Data[2].Close != 154.0 {
+			t.Errorf("Expected last candle close 154, got %f", message.Data[2].Close)
+		}
+	})
+}
+
+// TestDownsampleEquity はエクイティ系列の間引きをテスト
+func TestDownsampleEquity(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := make([]EquityPoint, 10000)
+	for i := range points {
+		// ギザギザの系列に、途中で1回ずつ大きな谷と山を入れる
+		equity := 10000.0 + float64(i%7) - float64(i%3)
+		switch i {
+		case 2345:
+			equity = 9000.0
+		case 7777:
+			equity = 12000.0
+		}
+		points[i] = EquityPoint{Timestamp: baseTime.Add(time.Duration(i) * time.Minute), Equity: equity}
+	}
+	
+	t.Run("should reduce long series to target count keeping extremes", func(t *testing.T) {
+		result := DownsampleEquity(points, 100)
+		if len(result) != 100 {
+			t.Fatalf("Expected 100 points, got %d", len(result))
+		}
+		if !result[0].Timestamp.Equal(points[0].Timestamp) || !result[99].Timestamp.Equal(points[9999].Timestamp) {
+			t.Error("Expected first and last points to be kept")
+		}
+		
+		hasMin, hasMax := false, false
+		for i, p := range result {
+			if p.Equity == 9000.0 {
+				hasMin = true
+			}
+			if p.Equity == 12000.0 {
+				hasMax = true
+			}
+			if i > 0 && !p.Timestamp.After(result[i-1].Timestamp) {
+				t.Fatalf("Expected ascending timestamps at %d", i)
+			}
+		}
+		if !hasMin || !hasMax {
+			t.Errorf("Expected min and max to be retained (min=%v, max=%v)", hasMin, hasMax)
+		}
+	})
+	
+	t.Run("should return short series unchanged", func(t *testing.T) {
+		result := DownsampleEquity(points[:50], 100)
+		if len(result) != 50 {
+			t.Errorf("Expected 50 points, got %d", len(result))
+		}
+		if len(DownsampleEquity(points, 0)) != len(points) {
+			t.Error("Expected non-positive target to disable downsampling")
+		}
+	})
+}
+
+// TestEquityUpdate は equity_update メッセージの配信をテスト
+func TestEquityUpdate(t *testing.T) {
+	config := DefaultConfig()
+	config.EquityPoints = 10
+	visualizer := NewVisualizer(config)
+	
+	ctx := context.Background()
+	if err := visualizer.Start(ctx, 8098); err != nil {
+		t.Fatalf("Failed to start visualizer: %v", err)
+	}
+	defer visualizer.Stop()
+	
+	time.Sleep(100 * time.Millisecond)
+	
+	u := url.URL{Scheme: "ws", Host: "localhost:8098", Path: "/ws"}
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	
+	time.Sleep(100 * time.Millisecond)
+	
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 50; i++ {
+		if err := visualizer.OnEquityUpdate(baseTime.Add(time.Duration(i)*time.Minute), 10000.0+float64(i)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	
+	var last struct {
+		Type string        `json:"type"`
+		Data []EquityPoint `json:"data"`
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for i := 0; i < 50; i++ {
+		if err := conn.ReadJSON(&last); err != nil {
+			t.Fatalf("Failed to read equity message %d: %v", i, err)
+		}
+		if last.Type != "equity_update" {
+			t.Fatalf("Expected equity_update message, got %s", last.Type)
+		}
+		if len(last.Data) > 10 {
+			t.Fatalf("Expected at most 10 points, got %d", len(last.Data))
+		}
+	}
+	
+	if last.Data[len(last.Data)-1].Equity != 10049.0 {
+		t.Errorf("Expected last equity 10049, got %f", last.Data[len(last.Data)-1].Equity)
+	}
+}
+
+// TestIndicatorUpdate は indicator_update メッセージの配信をテスト
+func TestIndicatorUpdate(t *testing.T) {
+	visualizer := NewVisualizer(DefaultConfig())
+	
+	t.Run("should reject empty indicator name", func(t *testing.T) {
+		if err := visualizer.OnIndicatorUpdate("", time.Now(), 1.0); err == nil {
+			t.Error("Expected error for empty indicator name")
+		}
+	})
+	
+	t.Run("should broadcast indicator value aligned with candle time", func(t *testing.T) {
+		ctx := context.Background()
+		if err := visualizer.Start(ctx, 8099); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		u := url.URL{Scheme: "ws", Host: "localhost:8099", Path: "/ws"}
+		conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		candleTime := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+		if err := visualizer.OnIndicatorUpdate("slow_ma", candleTime, 150.25); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var message struct {
+			Type string          `json:"type"`
+			Data IndicatorUpdate `json:"data"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("Failed to read indicator message: %v", err)
+		}
+		
+		if message.Type != "indicator_update" {
+			t.Fatalf("Expected indicator_update message, got %s", message.Type)
+		}
+		if message.Data.Name != "slow_ma" || message.Data.Value != 150.25 || !message.Data.Time.Equal(candleTime) {
+			t.Errorf("Unexpected indicator data: %+v", message.Data)
+		}
+	})
+}
+
+// TestAllowedOrigins は Origin の許可リストをテスト
+func TestAllowedOrigins(t *testing.T) {
+	config := DefaultConfig()
+	config.AllowedOrigins = []string{"https://viz.example.com"}
+	visualizer := NewVisualizer(config)
+	
+	ctx := context.Background()
+	if err := visualizer.Start(ctx, 8094); err != nil {
+		t.Fatalf("Failed to start visualizer: %v", err)
+	}
+	defer visualizer.Stop()
+	
+	time.Sleep(100 * time.Millisecond)
+	
+	u := url.URL{Scheme: "ws", Host: "localhost:8094", Path: "/ws"}
+	
+	t.Run("should accept allowed origin", func(t *testing.T) {
+		header := http.Header{"Origin": []string{"https://viz.example.com"}}
+		conn, _, err := websocket.DefaultDialer.Dial(u.String(), header)
+		if err != nil {
+			t.Fatalf("Expected allowed origin to connect: %v", err)
+		}
+		conn.Close()
+	})
+	
+	t.Run("should reject unknown origin", func(t *testing.T) {
+		header := http.Header{"Origin": []string{"https://evil.example.com"}}
+		_, resp, err := websocket.DefaultDialer.Dial(u.String(), header)
+		if err == nil {
+			t.Fatal("Expected unknown origin to be rejected")
+		}
+		if resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %v", resp)
+		}
+	})
+	
+	t.Run("should accept any origin with wildcard", func(t *testing.T) {
+		wildcard := DefaultConfig()
+		v := NewVisualizer(wildcard)
+		if err := v.Start(ctx, 8095); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer v.Stop()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		wildcardURL := url.URL{Scheme: "ws", Host: "localhost:8095", Path: "/ws"}
+		header := http.Header{"Origin": []string{"https://anything.example.com"}}
+		conn, _, err := websocket.DefaultDialer.Dial(wildcardURL.String(), header)
+		if err != nil {
+			t.Fatalf("Expected wildcard to accept any origin: %v", err)
+		}
+		conn.Close()
+	})
+}
+
+// candlesFromCloses は 2024-01-01 00:00 から1分ごとの、終値のみ（始値・高値・安値も同値）のローソク足を作成する
+func candlesFromCloses(closes []float64) []*models.Candle {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]*models.Candle, 0, len(closes))
+	for i, price := range closes {
+		candles = append(candles, models.NewCandle(baseTime.Add(time.Duration(i)*time.Minute), price, price, price, price, 1000))
+	}
+	return candles
+}
+
+// writeSelfSignedCert はテスト用の自己署名証明書と秘密鍵を書き出す
+func writeSelfSignedCert(t *testing.T) (string, string) {
+	t.Helper()
+	
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// TestTLS は TLS (wss) での配信をテスト
+func TestTLS(t *testing.T) {
+	t.Run("should serve wss when cert and key are configured", func(t *testing.T) {
+		certFile, keyFile := writeSelfSignedCert(t)
+		config := DefaultConfig()
+		config.CertFile = certFile
+		config.KeyFile = keyFile
+		visualizer := NewVisualizer(config)
+		
+		ctx := context.Background()
+		if err := visualizer.Start(ctx, 8096); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		
+		time.Sleep(100 * time.Millisecond)
+		
+		dialer := websocket.Dialer{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+		u := url.URL{Scheme: "wss", Host: "localhost:8096", Path: "/ws"}
+		conn, _, err := dialer.Dial(u.String(), nil)
+		if err != nil {
+			t.Fatalf("Failed to connect over wss: %v", err)
+		}
+		conn.Close()
+		
+		// 平文の ws では接続できない
+		plain := url.URL{Scheme: "ws", Host: "localhost:8096", Path: "/ws"}
+		if conn, _, err := websocket.DefaultDialer.Dial(plain.String(), nil); err == nil {
+			conn.Close()
+			t.Error("Expected plain ws connection to fail on TLS server")
+		}
+	})
+	
+	t.Run("should fail to start with incomplete or invalid TLS config", func(t *testing.T) {
+		config := DefaultConfig()
+		config.CertFile = "cert.pem"
+		if err := NewVisualizer(config).Start(context.Background(), 8097); err == nil {
+			t.Error("Expected error when key file is missing")
+		}
+		
+		config = DefaultConfig()
+		config.CertFile = "not_exists_cert.pem"
+		config.KeyFile = "not_exists_key.pem"
+		if err := NewVisualizer(config).Start(context.Background(), 8097); err == nil {
+			t.Error("Expected error when certificate files do not exist")
+		}
+	})
+}
+
+// stubController はテスト用のバックテストコントローラー
+type stubController struct {
+	stepCounts []int
+	resets     int
+}
+
+func (c *stubController) Play(speed float64) error     { return nil }
+func (c *stubController) Pause() error                 { return nil }
+func (c *stubController) SetSpeed(speed float64) error { return nil }
+func (c *stubController) IsRunning() bool              { return false }
+func (c *stubController) GetState() models.BacktestControlState {
+	return models.BacktestControlState{}
+}
+
+func (c *stubController) Reset() error {
+	c.resets++
+	return nil
+}
+
+func (c *stubController) Step(count int) error {
+	c.stepCounts = append(c.stepCounts, count)
+	return nil
+}
+
+// TestStepCommand はステップ実行コマンドをテスト
+func TestResetCommand(t *testing.T) {
+	visualizer := NewVisualizer(DefaultConfig())
+	controller := &stubController{}
+	visualizer.SetBacktestController(controller)
+	
+	cmd := &ControlCommand{Type: "reset", Data: map[string]interface{}{}}
+	if err := visualizer.OnControlCommand(cmd); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if controller.resets != 1 {
+		t.Errorf("Expected 1 reset, got %d", controller.resets)
+	}
+}
+
+func TestStepCommand(t *testing.T) {
+	visualizer := NewVisualizer(DefaultConfig())
+	controller := &stubController{}
+	visualizer.SetBacktestController(controller)
+	
+	t.Run("should default step count to 1", func(t *testing.T) {
+		cmd := &ControlCommand{Type: "step", Data: map[string]interface{}{}}
+		if err := visualizer.OnControlCommand(cmd); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+	
+	t.Run("should pass optional step count", func(t *testing.T) {
+		cmd := &ControlCommand{Type: "step", Data: map[string]interface{}{"count": 5.0}}
+		if err := visualizer.OnControlCommand(cmd); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+	
+	t.Run("should reject invalid step count", func(t *testing.T) {
+		cmd := &ControlCommand{Type: "step", Data: map[string]interface{}{"count": 0.0}}
+		if err := visualizer.OnControlCommand(cmd); err == nil {
+			t.Error("Expected error for invalid step count")
+		}
+	})
+	
+	if len(controller.stepCounts) != 2 || controller.stepCounts[0] != 1 || controller.stepCounts[1] != 5 {
+		t.Errorf("Expected step counts [1 5], got %v", controller.stepCounts)
+	}
+}
+
+// TestLogger は診断ログが Config.Logger に出力されることをテスト
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	config := DefaultConfig()
+	config.Logger = models.NewLogger(&buf, "debug")
+	visualizer := NewVisualizer(config)
+	
+	cmd := &ControlCommand{Type: "pause", ClientID: "client-1", Data: map[string]interface{}{}}
+	if err := visualizer.OnControlCommand(cmd); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	
+	output := buf.String()
+	if !strings.Contains(output, "handling pause command") || !strings.Contains(output, "client=client-1") {
+		t.Errorf("Expected debug log for pause command, got %q", output)
+	}
+	if !strings.Contains(output, "level=WARN") || !strings.Contains(output, "backtest controller not set") {
+		t.Errorf("Expected warning for missing controller, got %q", output)
+	}
+}
+
+// TestSessions は複数セッションへの制御コマンドの振り分けと、クライアントのセッション購読をテスト
+func TestSessions(t *testing.T) {
+	t.Run("should route control commands by session", func(t *testing.T) {
+		visualizer := NewVisualizer(DefaultConfig())
+		defaultController, controllerA, controllerB := &stubController{}, &stubController{}, &stubController{}
+		visualizer.SetBacktestController(defaultController)
+		visualizer.Session("a").SetBacktestController(controllerA)
+		visualizer.Session("b").SetBacktestController(controllerB)
+		
+		if visualizer.Session("a") != visualizer.Session("a") || visualizer.Session("") != visualizer {
+			t.Error("Expected Session to return the registered session and the visualizer itself for empty id")
+		}
+		if ids := visualizer.SessionIDs(); len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+			t.Errorf("Expected sessions [a b], got %v", ids)
+		}
+		
+		if err := visualizer.OnControlCommand(&ControlCommand{Type: "reset", SessionID: "b"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := visualizer.Session("a").OnControlCommand(&ControlCommand{Type: "step", Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if controllerB.resets != 1 || controllerA.resets != 0 || defaultController.resets != 0 {
+			t.Errorf("Expected reset only on session b, got default=%d a=%d b=%d", defaultController.resets, controllerA.resets, controllerB.resets)
+		}
+		if len(controllerA.stepCounts) != 1 || len(defaultController.stepCounts) != 0 {
+			t.Errorf("Expected step on session a, got a=%v default=%v", controllerA.stepCounts, defaultController.stepCounts)
+		}
+		if err := visualizer.OnControlCommand(&ControlCommand{Type: "reset", SessionID: "missing"}); err == nil {
+			t.Error("Expected error for unknown session")
+		}
+		
+		// セッションの Stop は登録を解除するだけ
+		if err := visualizer.Session("a").Stop(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ids := visualizer.SessionIDs(); len(ids) != 1 || ids[0] != "b" {
+			t.Errorf("Expected sessions [b] after stop, got %v", ids)
+		}
+	})
+	
+	t.Run("should deliver messages to subscribed clients", func(t *testing.T) {
+		visualizer := NewVisualizer(DefaultConfig())
+		if err := visualizer.Start(context.Background(), 8109); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		sessionA, sessionB := visualizer.Session("a"), visualizer.Session("b")
+		if !sessionA.IsRunning() || sessionA.Start(context.Background(), 0) != nil {
+			t.Error("Expected session to share the running server")
+		}
+		time.Sleep(100 * time.Millisecond)
+		
+		dial := func(rawQuery string) *websocket.Conn {
+			u := url.URL{Scheme: "ws", Host: "localhost:8109", Path: "/ws", RawQuery: rawQuery}
+			conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+			if err != nil {
+				t.Fatalf("Failed to connect to websocket: %v", err)
+			}
+			return conn
+		}
+		read := func(conn *websocket.Conn) Message {
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("Failed to read message: %v", err)
+			}
+			var message Message
+			if err := json.Unmarshal(data, &message); err != nil {
+				t.Fatalf("Failed to unmarshal message: %v", err)
+			}
+			return message
+		}
+		
+		subscriber := dial("session=a")
+		defer subscriber.Close()
+		monitor := dial("")
+		defer monitor.Close()
+		time.Sleep(100 * time.Millisecond)
+		
+		sessionB.OnBacktestStateChange(models.BacktestStateRunning)
+		sessionA.OnBacktestStateChange(models.BacktestStatePaused)
+		
+		if message := read(subscriber); message.SessionID != "a" || message.Type != "backtest_state" {
+			t.Errorf("Expected only session a message for subscriber, got %+v", message)
+		}
+		if first, second := read(monitor), read(monitor); first.SessionID != "b" || second.SessionID != "a" {
+			t.Errorf("Expected messages of all sessions in order, got %q and %q", first.SessionID, second.SessionID)
+		}
+		
+		// subscribe コマンドで購読するセッションを切り替える
+		if err := monitor.WriteJSON(map[string]interface{}{"type": "subscribe", "session_id": "b"}); err != nil {
+			t.Fatalf("Failed to send subscribe: %v", err)
+		}
+		if response := read(monitor); response.Type != "control_response" || response.SessionID != "b" {
+			t.Errorf("Expected control response for session b, got %+v", response)
+		}
+		sessionA.OnBacktestStateChange(models.BacktestStateRunning)
+		sessionB.OnBacktestStateChange(models.BacktestStateCompleted)
+		if message := read(monitor); message.SessionID != "b" {
+			t.Errorf("Expected session b message after subscribe, got %+v", message)
+		}
+		if message := read(subscriber); message.SessionID != "a" {
+			t.Errorf("Expected session a message for subscriber, got %+v", message)
+		}
+	})
+	
+	t.Run("should serve status per session", func(t *testing.T) {
+		visualizer := NewVisualizer(DefaultConfig())
+		if err := visualizer.Start(context.Background(), 8110); err != nil {
+			t.Fatalf("Failed to start visualizer: %v", err)
+		}
+		defer visualizer.Stop()
+		visualizer.Session("a").SetStatusProvider(staticStatus{State: "Running", Bar: 10})
+		visualizer.Session("b")
+		time.Sleep(100 * time.Millisecond)
+		
+		resp, err := http.Get("http://localhost:8110/status?session=a")
+		if err != nil {
+			t.Fatalf("Failed to request status: %v", err)
+		}
+		var status models.BacktestStatus
+		json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || status.Bar != 10 {
+			t.Errorf("Expected status of session a, got %d %+v", resp.StatusCode, status)
+		}
+		
+		resp, err = http.Get("http://localhost:8110/status?session=missing")
+		if err != nil {
+			t.Fatalf("Failed to request status: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404 for unknown session, got %d", resp.StatusCode)
+		}
+		
+		resp, err = http.Get("http://localhost:8110/sessions")
+		if err != nil {
+			t.Fatalf("Failed to request sessions: %v", err)
+		}
+		defer resp.Body.Close()
+		var sessions []SessionInfo
+		if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
+			t.Fatalf("Failed to decode sessions: %v", err)
+		}
+		if len(sessions) != 2 || sessions[0].ID != "a" || sessions[0].Status == nil || sessions[0].Status.State != "Running" || sessions[1].Status != nil {
+			t.Errorf("Expected sessions a (with status) and b, got %+v", sessions)
+		}
+	})
+}